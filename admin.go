@@ -0,0 +1,300 @@
+package lumen
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	traceotlp "github.com/kolosys/lumen/trace/otlpconv"
+)
+
+// AdminOptions configures AdminHandler.
+type AdminOptions struct {
+	// Registry is served at /metrics. Defaults to metrics.DefaultRegistry.
+	Registry *metrics.Registry
+
+	// Loggers are the loggers whose level /loglevel reads and sets, by
+	// name. Defaults to logs.Default as "root".
+	Loggers map[string]*logs.Logger
+
+	// RecentSpans is dumped at /traces/recent. Without it the endpoint
+	// responds 404.
+	RecentSpans *RecentSpans
+
+	// FlightRecorder is dumped at /flightrecorder. Without it the endpoint
+	// responds 404.
+	FlightRecorder *FlightRecorder
+
+	// ConfigMap reports its status at /configmap. Without it the endpoint
+	// responds 404.
+	ConfigMap *ConfigMapWatcher
+
+	// HealthChecks are run by /healthz; any error makes it respond 503.
+	HealthChecks map[string]func(ctx context.Context) error
+
+	// HealthTimeout bounds each /healthz request. Defaults to 5s.
+	HealthTimeout time.Duration
+
+	// BuildInfo adds entries to /buildinfo, e.g. the service version.
+	BuildInfo map[string]string
+
+	// BearerToken, when set, requires an "Authorization: Bearer" header
+	// with this token.
+	BearerToken string
+
+	// Authorize, if set, decides whether a request may proceed, replacing
+	// the BearerToken check.
+	Authorize func(r *http.Request) bool
+
+	// AuthorizeHealth also applies authorization to /healthz, which is
+	// otherwise open so orchestrator probes need no credentials.
+	AuthorizeHealth bool
+}
+
+func (o *AdminOptions) applyDefaults() {
+	if o.Registry == nil {
+		o.Registry = metrics.DefaultRegistry()
+	}
+	if o.Loggers == nil {
+		o.Loggers = map[string]*logs.Logger{"root": logs.Default()}
+	}
+	if o.HealthTimeout <= 0 {
+		o.HealthTimeout = 5 * time.Second
+	}
+}
+
+// AdminHandler returns a mux serving a service's operational endpoints:
+//
+//	/metrics        Prometheus metrics
+//	/loglevel       GET the level of each logger; POST logger=<name>&level=<level> to change one
+//	/traces/recent  recently ended spans as OTLP/JSON
+//	/flightrecorder GET the flight recorder dump; POST to write one to its Dir or Output
+//	/configmap      the revision of the watched ConfigMap last applied
+//	/healthz        health check results
+//	/buildinfo      Go build and module information
+//
+// Mount it on an internal port or behind authentication; see
+// AdminOptions.BearerToken and AdminOptions.Authorize.
+func AdminHandler(opts *AdminOptions) http.Handler {
+	return newAdminHandler(opts, nil)
+}
+
+func newAdminHandler(opts *AdminOptions, obs *Observability) http.Handler {
+	if opts == nil {
+		opts = &AdminOptions{}
+	}
+	o := *opts
+	o.applyDefaults()
+
+	a := &admin{opts: o, obs: obs}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", a.authorized(metrics.HTTPHandler(o.Registry)))
+	mux.Handle("/loglevel", a.authorized(http.HandlerFunc(a.logLevel)))
+	mux.Handle("/traces/recent", a.authorized(http.HandlerFunc(a.recentTraces)))
+	mux.Handle("/flightrecorder", a.authorized(http.HandlerFunc(a.flightRecorder)))
+	mux.Handle("/configmap", a.authorized(http.HandlerFunc(a.configMap)))
+	mux.Handle("/buildinfo", a.authorized(http.HandlerFunc(a.buildInfo)))
+	if o.AuthorizeHealth {
+		mux.Handle("/healthz", a.authorized(http.HandlerFunc(a.health)))
+	} else {
+		mux.HandleFunc("/healthz", a.health)
+	}
+	return mux
+}
+
+// AdminHandler returns AdminHandler for the configured telemetry: its
+// registry, logger (as "root"), recent spans, flight recorder and service
+// build info.
+// Fields set in opts take precedence. Levels set for its logger through
+// /loglevel are applied as Reload applies them, and recorded in
+// CurrentConfig.
+func (o *Observability) AdminHandler(opts *AdminOptions) http.Handler {
+	var ao AdminOptions
+	if opts != nil {
+		ao = *opts
+	}
+	if ao.Registry == nil {
+		ao.Registry = o.Metrics
+	}
+	if ao.Loggers == nil {
+		ao.Loggers = map[string]*logs.Logger{"root": o.Logger}
+	}
+	if ao.RecentSpans == nil {
+		ao.RecentSpans = o.RecentSpans
+	}
+	if ao.FlightRecorder == nil {
+		ao.FlightRecorder = o.FlightRecorder
+	}
+
+	info := map[string]string{"service": o.Config.ServiceName, "instance": o.Resource.InstanceID()}
+	if o.Config.ServiceVersion != "" {
+		info["version"] = o.Config.ServiceVersion
+	}
+	if o.Config.Environment != "" {
+		info["environment"] = o.Config.Environment
+	}
+	for k, v := range ao.BuildInfo {
+		info[k] = v
+	}
+	ao.BuildInfo = info
+	return newAdminHandler(&ao, o)
+}
+
+type admin struct {
+	opts AdminOptions
+	obs  *Observability
+}
+
+func (a *admin) authorized(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok := true
+		switch {
+		case a.opts.Authorize != nil:
+			ok = a.opts.Authorize(r)
+		case a.opts.BearerToken != "":
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			ok = found && subtle.ConstantTimeCompare([]byte(token), []byte(a.opts.BearerToken)) == 1
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *admin) logLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost, http.MethodPut:
+		name, level := r.FormValue("logger"), r.FormValue("level")
+		if name == "" && len(a.opts.Loggers) == 1 {
+			for n := range a.opts.Loggers {
+				name = n
+			}
+		}
+		logger, ok := a.opts.Loggers[name]
+		if !ok {
+			http.Error(w, "unknown logger "+name, http.StatusNotFound)
+			return
+		}
+		lvl, err := parseLevel(level)
+		if err != nil || level == "" {
+			http.Error(w, "invalid level "+level, http.StatusBadRequest)
+			return
+		}
+		if a.obs != nil && logger == a.obs.Logger {
+			a.obs.setLogLevel(lvl)
+		} else {
+			logger.SetLevel(lvl)
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	levels := make(map[string]string, len(a.opts.Loggers))
+	for name, logger := range a.opts.Loggers {
+		if a.obs != nil && logger == a.obs.Logger {
+			levels[name] = a.obs.logLevel().String()
+			continue
+		}
+		levels[name] = logger.GetLevel().String()
+	}
+	writeJSON(w, http.StatusOK, levels)
+}
+
+func (a *admin) recentTraces(w http.ResponseWriter, r *http.Request) {
+	if a.opts.RecentSpans == nil {
+		http.Error(w, "recent spans are not recorded", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, traceotlp.FromSnapshots(a.opts.RecentSpans.Spans()))
+}
+
+func (a *admin) flightRecorder(w http.ResponseWriter, r *http.Request) {
+	fr := a.opts.FlightRecorder
+	if fr == nil {
+		http.Error(w, "flight recorder is not enabled", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		fr.WriteDump(w, "admin request")
+	case http.MethodPost:
+		path, err := fr.Dump("admin request")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"path": path})
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *admin) configMap(w http.ResponseWriter, r *http.Request) {
+	if a.opts.ConfigMap == nil {
+		http.Error(w, "no configmap is watched", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, a.opts.ConfigMap.Status())
+}
+
+func (a *admin) health(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), a.opts.HealthTimeout)
+	defer cancel()
+
+	status := http.StatusOK
+	results := make(map[string]string, len(a.opts.HealthChecks))
+	for name, check := range a.opts.HealthChecks {
+		if err := check(ctx); err != nil {
+			results[name] = err.Error()
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		results[name] = "ok"
+	}
+	writeJSON(w, status, map[string]any{
+		"status": strings.ToLower(http.StatusText(status)),
+		"checks": results,
+	})
+}
+
+func (a *admin) buildInfo(w http.ResponseWriter, r *http.Request) {
+	info := map[string]any{"go_version": runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info["path"] = bi.Path
+		info["module"] = bi.Main.Path
+		info["module_version"] = bi.Main.Version
+		settings := make(map[string]string)
+		for _, s := range bi.Settings {
+			if strings.HasPrefix(s.Key, "vcs.") || slices.Contains([]string{"GOOS", "GOARCH", "CGO_ENABLED"}, s.Key) {
+				settings[s.Key] = s.Value
+			}
+		}
+		info["settings"] = settings
+	}
+	for k, v := range a.opts.BuildInfo {
+		info[k] = v
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,251 @@
+// Package audit records structured audit events: who (actor) did what
+// (action) to which resource (target), with what result and why. Events
+// are validated, chained by hash so that edits, deletions and reordering
+// can be detected, and appended to a dedicated Sink separate from the
+// application logs. Each recorded event is also logged at info level and
+// counted in a metric.
+//
+// Basic usage:
+//
+//	sink, err := audit.OpenFileSink("/var/log/app/audit.jsonl")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	auditor, err := audit.New(&audit.Options{Sink: sink, Key: hmacKey})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer auditor.Close()
+//
+//	auditor.Record(ctx, audit.Event{
+//		Actor:  "user:42",
+//		Action: "invoice.delete",
+//		Target: "invoice:981",
+//		Result: audit.ResultDenied,
+//		Reason: "missing role billing-admin",
+//	})
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+)
+
+// Results of an audited action.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	ResultDenied  = "denied"
+)
+
+var (
+	ErrMissingField  = errors.New("audit: missing required field")
+	ErrInvalidResult = errors.New("audit: invalid result")
+	ErrClosed        = errors.New("audit: auditor closed")
+)
+
+// Event is one audited action.
+type Event struct {
+	// Seq numbers the events in a chain, starting at 1. Set by Record.
+	Seq uint64 `json:"seq"`
+
+	// Time defaults to the time of Record.
+	Time time.Time `json:"time"`
+
+	// Actor is who performed the action, e.g. "user:42" or "svc:billing".
+	Actor string `json:"actor"`
+
+	// Action is what was done, e.g. "invoice.delete".
+	Action string `json:"action"`
+
+	// Target is what it was done to, e.g. "invoice:981".
+	Target string `json:"target"`
+
+	// Result is ResultSuccess, ResultFailure or ResultDenied.
+	Result string `json:"result"`
+
+	// Reason explains the result. It is required unless the action
+	// succeeded.
+	Reason string `json:"reason,omitempty"`
+
+	// Attributes hold further details, e.g. the client IP.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// TraceID and SpanID link the event to the active span. Set by Record.
+	TraceID string `json:"trace_id,omitempty"`
+	SpanID  string `json:"span_id,omitempty"`
+
+	// PrevHash is the Hash of the previous event in the chain, empty for
+	// the first. Set by Record.
+	PrevHash string `json:"prev_hash"`
+
+	// Hash covers every other field, including PrevHash. Set by Record.
+	Hash string `json:"hash"`
+}
+
+// Validate reports missing required fields and unknown results.
+func (e *Event) Validate() error {
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"actor", e.Actor},
+		{"action", e.Action},
+		{"target", e.Target},
+		{"result", e.Result},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if e.Result != "" && e.Result != ResultSuccess && strings.TrimSpace(e.Reason) == "" {
+		missing = append(missing, "reason")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingField, strings.Join(missing, ", "))
+	}
+	switch e.Result {
+	case ResultSuccess, ResultFailure, ResultDenied:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidResult, e.Result)
+	}
+}
+
+// Options configures an Auditor.
+type Options struct {
+	// Sink stores the events. Required.
+	Sink Sink
+
+	// Key, if set, makes the chain an HMAC-SHA256 chain, so that an
+	// attacker who can rewrite the sink cannot recompute valid hashes
+	// without it. Verify needs the same key.
+	Key []byte
+
+	// Logger receives an info entry for each event. Defaults to
+	// logs.Default().
+	Logger *logs.Logger
+
+	// DisableLog turns the info entries off.
+	DisableLog bool
+
+	// Registry holds the audit_events_total counter, labeled by action
+	// and result. Defaults to metrics.DefaultRegistry().
+	Registry *metrics.Registry
+
+	// DisableMetrics turns the counter off.
+	DisableMetrics bool
+}
+
+func (o *Options) applyDefaults() {
+	if o.Logger == nil {
+		o.Logger = logs.Default()
+	}
+	if o.Registry == nil {
+		o.Registry = metrics.DefaultRegistry()
+	}
+}
+
+// Auditor validates, chains and stores events. It is safe for concurrent
+// use; events are chained in the order Record is called.
+type Auditor struct {
+	opts    Options
+	counter *metrics.Counter
+
+	mu     sync.Mutex
+	seq    uint64
+	last   string
+	closed bool
+}
+
+// New creates an Auditor writing to opts.Sink. If the sink already holds
+// events (see ChainHead), the chain continues from the last one.
+func New(opts *Options) (*Auditor, error) {
+	if opts == nil || opts.Sink == nil {
+		return nil, fmt.Errorf("%w: sink", ErrMissingField)
+	}
+	o := *opts
+	o.applyDefaults()
+
+	a := &Auditor{opts: o}
+	if head, ok := o.Sink.(ChainHead); ok {
+		last, found, err := head.Last()
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			a.seq, a.last = last.Seq, last.Hash
+		}
+	}
+	if !o.DisableMetrics {
+		a.counter = o.Registry.Counter("audit_events_total", "Audit events recorded.", "action", "result")
+	}
+	return a, nil
+}
+
+// Record validates e, links it to the active span in ctx and to the
+// previous event, and appends it to the sink. The event is only logged
+// and counted once the sink has accepted it; if the sink fails, the
+// chain is not advanced and the error is returned.
+func (a *Auditor) Record(ctx context.Context, e Event) (Event, error) {
+	if err := e.Validate(); err != nil {
+		return e, err
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	if span := trace.SpanFromContext(ctx); span != nil && span.TraceID().IsValid() {
+		e.TraceID, e.SpanID = span.TraceID().String(), span.SpanID().String()
+	}
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return e, ErrClosed
+	}
+	e.Seq = a.seq + 1
+	e.PrevHash = a.last
+	e.Hash = e.digest(a.opts.Key)
+	if err := a.opts.Sink.Append(&e); err != nil {
+		a.mu.Unlock()
+		return e, fmt.Errorf("audit: append: %w", err)
+	}
+	a.seq, a.last = e.Seq, e.Hash
+	a.mu.Unlock()
+
+	if !a.opts.DisableLog {
+		fields := []logs.Field{
+			logs.String("audit.actor", e.Actor),
+			logs.String("audit.action", e.Action),
+			logs.String("audit.target", e.Target),
+			logs.String("audit.result", e.Result),
+			logs.Uint64("audit.seq", e.Seq),
+		}
+		if e.Reason != "" {
+			fields = append(fields, logs.String("audit.reason", e.Reason))
+		}
+		a.opts.Logger.InfoContext(ctx, "audit event", fields...)
+	}
+	if a.counter != nil {
+		a.counter.Inc(e.Action, e.Result)
+	}
+	return e, nil
+}
+
+// Close closes the sink. Later Record calls return ErrClosed.
+func (a *Auditor) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+	return a.opts.Sink.Close()
+}
@@ -0,0 +1,108 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/lumen/audit"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+)
+
+func TestRecordChainsAndVerifies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	key := []byte("secret")
+	var out bytes.Buffer
+	registry := metrics.NewRegistry(nil)
+	logger := logs.New(&logs.Options{Output: &out, Formatter: &logs.JSONFormatter{}})
+
+	record := func(events ...Event) {
+		sink, err := OpenFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		auditor, err := New(&Options{Sink: sink, Key: key, Logger: logger, Registry: registry})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range events {
+			if _, err := auditor.Record(context.Background(), e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := auditor.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record(
+		Event{Actor: "user:1", Action: "invoice.create", Target: "invoice:1", Result: ResultSuccess},
+		Event{Actor: "user:2", Action: "invoice.delete", Target: "invoice:1", Result: ResultDenied, Reason: "not owner"},
+	)
+	// A new auditor continues the chain stored in the file.
+	record(Event{Actor: "user:1", Action: "invoice.delete", Target: "invoice:1", Result: ResultSuccess})
+
+	events, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[2].Seq != 3 || events[2].PrevHash != events[1].Hash {
+		t.Fatalf("unexpected chain: %+v", events)
+	}
+	if err := Verify(events, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(events, []byte("other")); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("expected ErrChainBroken with the wrong key, got %v", err)
+	}
+
+	tampered := append([]Event(nil), events...)
+	tampered[1].Result, tampered[1].Reason = ResultSuccess, ""
+	if err := Verify(tampered, key); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("expected ErrChainBroken for an edited event, got %v", err)
+	}
+	if err := Verify([]Event{events[0], events[2]}, key); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("expected ErrChainBroken for a removed event, got %v", err)
+	}
+
+	if got := strings.Count(out.String(), `"audit event"`); got != 3 {
+		t.Errorf("expected 3 audit log entries, got %d: %s", got, out.String())
+	}
+	counter := registry.Counter("audit_events_total", "", "action", "result")
+	if v := counter.Value("invoice.delete", ResultDenied); v != 1 {
+		t.Errorf("expected 1 denied delete, got %v", v)
+	}
+}
+
+func TestRecordValidates(t *testing.T) {
+	sink := &MemorySink{}
+	auditor, err := New(&Options{Sink: sink, DisableLog: true, DisableMetrics: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		event Event
+		want  error
+	}{
+		{Event{Action: "login", Target: "session", Result: ResultSuccess}, ErrMissingField},
+		{Event{Actor: "user:1", Action: "login", Target: "session", Result: ResultFailure}, ErrMissingField},
+		{Event{Actor: "user:1", Action: "login", Target: "session", Result: "maybe", Reason: "?"}, ErrInvalidResult},
+	}
+	for _, tt := range tests {
+		if _, err := auditor.Record(context.Background(), tt.event); !errors.Is(err, tt.want) {
+			t.Errorf("Record(%+v) = %v, want %v", tt.event, err, tt.want)
+		}
+	}
+	if len(sink.Events()) != 0 {
+		t.Errorf("invalid events were stored: %+v", sink.Events())
+	}
+
+	auditor.Close()
+	if _, err := auditor.Record(context.Background(), Event{Actor: "a", Action: "b", Target: "c", Result: ResultSuccess}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrChainBroken is returned by Verify when events were altered, removed
+// or reordered.
+var ErrChainBroken = errors.New("audit: chain broken")
+
+// digest hashes the event's JSON encoding without its Hash. With a key
+// the hash is an HMAC.
+func (e *Event) digest(key []byte) string {
+	c := *e
+	c.Hash = ""
+	data, _ := json.Marshal(&c)
+
+	if len(key) > 0 {
+		m := hmac.New(sha256.New, key)
+		m.Write(data)
+		return hex.EncodeToString(m.Sum(nil))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that events form an unbroken chain: each hash matches the
+// event's contents and each event links to the one before it with the
+// next sequence number. Pass the Key the events were recorded with. The
+// first event may continue an earlier chain, so a truncated prefix is not
+// detected; compare the first event's Seq and PrevHash against a saved
+// checkpoint for that.
+func Verify(events []Event, key []byte) error {
+	for i := range events {
+		e := &events[i]
+		if e.digest(key) != e.Hash {
+			return fmt.Errorf("%w: event %d (seq %d) does not match its hash", ErrChainBroken, i, e.Seq)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := &events[i-1]
+		if e.PrevHash != prev.Hash || e.Seq != prev.Seq+1 {
+			return fmt.Errorf("%w: event %d (seq %d) does not follow seq %d", ErrChainBroken, i, e.Seq, prev.Seq)
+		}
+	}
+	return nil
+}
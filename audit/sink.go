@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Sink stores audit events. Sinks only append; they never modify or
+// remove stored events. Append is called with events in chain order and
+// must persist the event before returning.
+type Sink interface {
+	Append(e *Event) error
+	Close() error
+}
+
+// ChainHead is implemented by sinks that can return their last stored
+// event, letting a new Auditor continue the chain after a restart.
+type ChainHead interface {
+	Last() (e Event, ok bool, err error)
+}
+
+// FileSink appends events as JSON lines to a file, syncing after each
+// event.
+type FileSink struct {
+	mu   sync.Mutex
+	f    *os.File
+	last *Event
+}
+
+// OpenFileSink opens or creates the file at path for appending. The
+// file's last event becomes the chain head.
+func OpenFileSink(path string) (*FileSink, error) {
+	events, err := ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileSink{f: f}
+	if len(events) > 0 {
+		s.last = &events[len(events)-1]
+	}
+	return s, nil
+}
+
+// Append implements Sink.
+func (s *FileSink) Append(e *Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := s.f.Sync(); err != nil {
+		return err
+	}
+	c := *e
+	s.last = &c
+	return nil
+}
+
+// Last implements ChainHead.
+func (s *FileSink) Last() (Event, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil {
+		return Event{}, false, nil
+	}
+	return *s.last, true, nil
+}
+
+// Close implements Sink.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// ReadFile reads the events written by a FileSink, e.g. to Verify them.
+func ReadFile(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return events, fmt.Errorf("audit: %s:%d: %w", path, line, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// MemorySink keeps events in memory, e.g. for tests.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Append implements Sink.
+func (s *MemorySink) Append(e *Event) error {
+	s.mu.Lock()
+	s.events = append(s.events, *e)
+	s.mu.Unlock()
+	return nil
+}
+
+// Last implements ChainHead.
+func (s *MemorySink) Last() (Event, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == 0 {
+		return Event{}, false, nil
+	}
+	return s.events[len(s.events)-1], true, nil
+}
+
+// Events returns the stored events.
+func (s *MemorySink) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.events)
+}
+
+// Close implements Sink.
+func (s *MemorySink) Close() error { return nil }
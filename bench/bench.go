@@ -0,0 +1,52 @@
+// Package bench holds end-to-end benchmarks of lumen's hot paths and the
+// allocation budgets that guard them. It is a separate module so the
+// benchmarks can exercise every package together without adding to the
+// main module.
+//
+// Run the benchmarks with:
+//
+//	cd bench && go test -bench . -benchmem
+//
+// The budgets in TestAllocBudgets fail when a change makes a hot path
+// allocate more than it does today. Lower a budget when an optimization
+// lands; raise one only with a reason in the commit.
+package bench
+
+import (
+	"context"
+	"io"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+)
+
+// NewLogger returns a logger writing JSON to io.Discard with a hook that
+// sees every entry, the common production shape.
+func NewLogger() *logs.Logger {
+	return logs.New(&logs.Options{
+		Output:    io.Discard,
+		Formatter: &logs.JSONFormatter{},
+		Hooks:     []logs.Hook{logs.NewFuncHook(func(*logs.Entry) {})},
+	})
+}
+
+// NewTracer returns a tracer that exports synchronously to exporter.
+func NewTracer(exporter trace.Exporter) *trace.Tracer {
+	return trace.New(&trace.Options{ServiceName: "bench", Exporter: exporter})
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *metrics.Registry {
+	return metrics.NewRegistry(nil)
+}
+
+// Request performs the telemetry of one instrumented request: a span, a
+// context log entry and a counter and histogram observation.
+func Request(ctx context.Context, tracer *trace.Tracer, logger *logs.Logger, requests *metrics.Counter, latency *metrics.Histogram) {
+	ctx, span := tracer.Start(ctx, "GET /orders/{id}")
+	logger.InfoContext(ctx, "order loaded", logs.String("order_id", "o-123"), logs.Int("items", 3))
+	requests.Inc("GET", "200")
+	latency.Observe(0.042, "GET")
+	span.End()
+}
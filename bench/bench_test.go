@@ -0,0 +1,182 @@
+package bench_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/bench"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+)
+
+var errBench = errors.New("connection refused")
+
+func logEntry(l *logs.Logger) {
+	l.Info("request handled",
+		logs.String("method", "GET"),
+		logs.String("path", "/orders/123"),
+		logs.Int("status", 200),
+		logs.Duration("elapsed", 42*time.Millisecond),
+	)
+}
+
+type order struct {
+	ID    string
+	Items int
+	Total float64
+}
+
+func (o *order) MarshalLog(enc logs.FieldEncoder) {
+	enc.AddString("id", o.ID)
+	enc.AddInt("items", o.Items)
+	enc.AddFloat64("total", o.Total)
+}
+
+var benchOrder = &order{ID: "ord_123", Items: 3, Total: 59.97}
+
+func logObject(l *logs.Logger) {
+	l.Info("order placed", logs.Object("order", benchOrder))
+}
+
+func BenchmarkLogJSONWithHook(b *testing.B) {
+	l := NewLogger()
+	b.ReportAllocs()
+	for b.Loop() {
+		logEntry(l)
+	}
+}
+
+func BenchmarkLogError(b *testing.B) {
+	l := NewLogger()
+	b.ReportAllocs()
+	for b.Loop() {
+		l.Error("query failed", logs.Err(errBench), logs.String("table", "orders"))
+	}
+}
+
+func BenchmarkLogObject(b *testing.B) {
+	l := NewLogger()
+	b.ReportAllocs()
+	for b.Loop() {
+		logObject(l)
+	}
+}
+
+func BenchmarkLogDisabled(b *testing.B) {
+	l := NewLogger()
+	b.ReportAllocs()
+	for b.Loop() {
+		l.Debug("not logged", logs.String("key", "value"))
+	}
+}
+
+func BenchmarkLogWith(b *testing.B) {
+	l := NewLogger().With(logs.String("service", "checkout"), logs.String("version", "1.2.3"))
+	b.ReportAllocs()
+	for b.Loop() {
+		logEntry(l)
+	}
+}
+
+func BenchmarkSpanStartEnd(b *testing.B) {
+	tracer := NewTracer(trace.NopExporter{})
+	defer tracer.Close()
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		_, span := tracer.Start(ctx, "op")
+		span.End()
+	}
+}
+
+func BenchmarkSpanWithAttributes(b *testing.B) {
+	tracer := NewTracer(trace.NopExporter{})
+	defer tracer.Close()
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		_, span := tracer.Start(ctx, "op")
+		span.SetAttribute("http.route", "/orders/{id}")
+		span.SetAttribute("http.response.status_code", 200)
+		span.AddEvent("cache miss")
+		span.End()
+	}
+}
+
+func BenchmarkSpanExportJSON(b *testing.B) {
+	tracer := NewTracer(trace.NewWriterExporter(io.Discard))
+	defer tracer.Close()
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		_, span := tracer.Start(ctx, "op")
+		span.SetAttribute("http.route", "/orders/{id}")
+		span.End()
+	}
+}
+
+func BenchmarkCounterInc(b *testing.B) {
+	c := NewRegistry().Counter("requests_total", "")
+	b.ReportAllocs()
+	for b.Loop() {
+		c.Inc()
+	}
+}
+
+func BenchmarkCounterIncLabels(b *testing.B) {
+	c := NewRegistry().Counter("requests_total", "", "method", "status")
+	b.ReportAllocs()
+	for b.Loop() {
+		c.Inc("GET", "200")
+	}
+}
+
+func BenchmarkHistogramObserve(b *testing.B) {
+	h := NewRegistry().Histogram("latency_seconds", "", nil, "method")
+	b.ReportAllocs()
+	for b.Loop() {
+		h.Observe(0.042, "GET")
+	}
+}
+
+func BenchmarkHistogramObserveParallel(b *testing.B) {
+	h := NewRegistry().Histogram("latency_seconds", "", nil, "method")
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			h.Observe(0.042, "GET")
+		}
+	})
+}
+
+func BenchmarkRequest(b *testing.B) {
+	tracer := NewTracer(trace.NopExporter{})
+	defer tracer.Close()
+	reg := NewRegistry()
+	requests := reg.Counter("requests_total", "", "method", "status")
+	latency := reg.Histogram("latency_seconds", "", nil, "method")
+	logger := NewLogger()
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		Request(ctx, tracer, logger, requests, latency)
+	}
+}
+
+func BenchmarkPrometheusExposition(b *testing.B) {
+	reg := NewRegistry()
+	c := reg.Counter("requests_total", "Requests.", "method", "status")
+	h := reg.Histogram("latency_seconds", "Latency.", nil, "method")
+	for _, m := range []string{"GET", "POST", "PUT", "DELETE"} {
+		c.Inc(m, "200")
+		h.Observe(0.1, m)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		metrics.WriteFamilies(io.Discard, reg.Gather())
+	}
+}
@@ -0,0 +1,60 @@
+package bench_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	. "github.com/kolosys/lumen/bench"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/trace"
+)
+
+// TestAllocBudgets fails when a hot path allocates more per operation
+// than its budget.
+func TestAllocBudgets(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not meaningful under the race detector")
+	}
+	ctx := context.Background()
+	logger := NewLogger()
+	child := logger.With(logs.String("service", "checkout"))
+	nop := NewTracer(trace.NopExporter{})
+	defer nop.Close()
+	exporting := NewTracer(trace.NewWriterExporter(io.Discard))
+	defer exporting.Close()
+	reg := NewRegistry()
+	counter := reg.Counter("requests_total", "")
+	labeled := reg.Counter("labeled_requests_total", "", "method", "status")
+	latency := reg.Histogram("latency_seconds", "", nil, "method")
+
+	budgets := []struct {
+		name   string
+		budget float64
+		fn     func()
+	}{
+		{"log JSON with hook", 4, func() { logEntry(logger) }},
+		{"log object", 2, func() { logObject(logger) }},
+		{"log with fields", 5, func() { logEntry(child) }},
+		{"log disabled level", 0, func() { logger.Debug("not logged", logs.String("key", "value")) }},
+		{"span start end", 3, func() {
+			_, span := nop.Start(ctx, "op")
+			span.End()
+		}},
+		{"span export JSON", 4, func() {
+			_, span := exporting.Start(ctx, "op")
+			span.SetAttribute("http.route", "/orders/{id}")
+			span.End()
+		}},
+		{"counter inc", 0, func() { counter.Inc() }},
+		{"counter inc labels", 11, func() { labeled.Inc("GET", "200") }},
+		{"histogram observe", 4, func() { latency.Observe(0.042, "GET") }},
+		{"request", 21, func() { Request(ctx, nop, logger, labeled, latency) }},
+	}
+	for _, b := range budgets {
+		b.fn() // warm pools and create series
+		if got := testing.AllocsPerRun(200, b.fn); got > b.budget {
+			t.Errorf("%s: %v allocs/op, budget %v", b.name, got, b.budget)
+		}
+	}
+}
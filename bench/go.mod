@@ -0,0 +1,7 @@
+module github.com/kolosys/lumen/bench
+
+go 1.24
+
+require github.com/kolosys/lumen v0.0.0
+
+replace github.com/kolosys/lumen => ../
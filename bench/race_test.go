@@ -0,0 +1,6 @@
+//go:build race
+
+package bench_test
+
+// The race detector adds allocations, so budgets are not checked under it.
+const raceEnabled = true
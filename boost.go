@@ -0,0 +1,122 @@
+package lumen
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/trace"
+)
+
+// BoostRule is the sampling.rule attribute of spans sampled by a boost.
+const BoostRule = "boost"
+
+// defaultObs is the Observability most recently returned by Setup, used
+// by the package-level Boost.
+var defaultObs atomic.Pointer[Observability]
+
+// Boost calls Boost on the Observability most recently returned by
+// Setup. It does nothing, and returns a no-op stop function, before Setup.
+func Boost(ctx context.Context, d time.Duration, scope ...trace.Attribute) (stop func()) {
+	o := defaultObs.Load()
+	if o == nil {
+		return func() {}
+	}
+	return o.Boost(ctx, d, scope...)
+}
+
+// boostState is an active boost.
+type boostState struct {
+	scope  []trace.Attribute
+	traces sync.Map // trace.TraceID -> struct{}, traces sampled by a scoped boost
+}
+
+// matches reports whether a span started with attrs is in scope.
+func (b *boostState) matches(attrs []trace.Attribute) bool {
+	for _, want := range b.scope {
+		found := false
+		for _, a := range attrs {
+			if a.Key == want.Key && fmt.Sprint(a.Value) == fmt.Sprint(want.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// covers reports whether the trace is boosted.
+func (b *boostState) covers(id trace.TraceID) bool {
+	if len(b.scope) == 0 {
+		return true
+	}
+	_, ok := b.traces.Load(id)
+	return ok
+}
+
+// Boost temporarily turns up all three signals for troubleshooting: for
+// d, or until ctx is done or stop is called, whichever comes first, every
+// trace is sampled, request loggers log at Debug, and context log entries
+// are also added as events to their span (unless correlation is
+// disabled). Afterwards, sampling and levels return to what they were.
+//
+// With scope, the boost only applies to traces whose spans start with
+// all of the given attributes, e.g. semconv.URLPath("/checkout") for
+// requests through Middleware, and the logger's own level is unchanged.
+// Without scope, the logger is also set to Debug.
+//
+// A new boost replaces the one in progress.
+func (o *Observability) Boost(ctx context.Context, d time.Duration, scope ...trace.Attribute) (stop func()) {
+	b := &boostState{scope: scope}
+	o.sampler.boost.Store(b)
+
+	prevLevel := o.Logger.GetLevel()
+	if len(scope) == 0 && prevLevel < logs.DebugLevel {
+		o.Logger.SetLevel(logs.DebugLevel)
+	}
+	// With Logs.FollowTraceSampling the logger is already at Debug and
+	// the log sampler holds the configured level instead.
+	var prevThreshold logs.Level
+	if o.logSampler != nil {
+		prevThreshold = o.logSampler.Threshold()
+		if len(scope) == 0 && prevThreshold < logs.DebugLevel {
+			o.logSampler.SetThreshold(logs.DebugLevel)
+		}
+	}
+	o.Logger.Info("lumen boost started", logs.Duration("duration", d), logs.Int("scope", len(scope)))
+
+	ctx, cancel := context.WithTimeout(ctx, d)
+	var once sync.Once
+	end := func() {
+		once.Do(func() {
+			cancel()
+			if !o.sampler.boost.CompareAndSwap(b, nil) {
+				return // replaced by a newer boost
+			}
+			if len(scope) == 0 && o.Logger.GetLevel() == logs.DebugLevel && prevLevel < logs.DebugLevel {
+				o.Logger.SetLevel(prevLevel)
+			}
+			if len(scope) == 0 && o.logSampler != nil && o.logSampler.Threshold() == logs.DebugLevel && prevThreshold < logs.DebugLevel {
+				o.logSampler.SetThreshold(prevThreshold)
+			}
+			o.Logger.Info("lumen boost ended")
+		})
+	}
+	go func() {
+		<-ctx.Done()
+		end()
+	}()
+	return end
+}
+
+// boosted reports whether the trace is covered by an active boost.
+func (o *Observability) boosted(id trace.TraceID) bool {
+	b := o.sampler.boost.Load()
+	return b != nil && b.covers(id)
+}
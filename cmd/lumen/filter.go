@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolosys/lumen/expr"
+	"github.com/kolosys/lumen/logs"
+)
+
+// filter decides which records are printed.
+type filter struct {
+	level    logs.Level
+	loggers  []string
+	since    time.Time
+	until    time.Time
+	matchers []matcher
+	exprs    []*expr.Expr
+}
+
+// match reports whether r passes every condition of f.
+func (f *filter) match(r *record) bool {
+	if r.entry.Level > f.level {
+		return false
+	}
+	if len(f.loggers) > 0 && !f.matchLogger(r.logger) {
+		return false
+	}
+	if !f.since.IsZero() && (r.entry.Time.IsZero() || r.entry.Time.Before(f.since)) {
+		return false
+	}
+	if !f.until.IsZero() && (r.entry.Time.IsZero() || r.entry.Time.After(f.until)) {
+		return false
+	}
+	for _, m := range f.matchers {
+		if !m.match(r) {
+			return false
+		}
+	}
+	for _, e := range f.exprs {
+		if !e.MatchEntry(&r.entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLogger matches name against the logger patterns. A pattern matches
+// the logger itself and, as for named loggers, its children.
+func (f *filter) matchLogger(name string) bool {
+	for _, pattern := range f.loggers {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if strings.HasPrefix(name, pattern+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// matcher is a field condition such as status>=500 or path~^/api.
+type matcher struct {
+	key   string
+	op    string
+	value string
+	num   float64
+	isNum bool
+	re    *regexp.Regexp
+}
+
+// Operators in the order they are looked for, longest first so that >=
+// is not read as >.
+var operators = []string{"!=", ">=", "<=", "!~", "=", ">", "<", "~"}
+
+// parseMatcher parses key<op>value. Comparisons with >, >=, < and <=
+// are numeric; ~ and !~ match a regular expression.
+func parseMatcher(expr string) (matcher, error) {
+	for i := 0; i < len(expr); i++ {
+		for _, op := range operators {
+			if !strings.HasPrefix(expr[i:], op) {
+				continue
+			}
+			m := matcher{
+				key:   strings.TrimSpace(expr[:i]),
+				op:    op,
+				value: strings.TrimSpace(expr[i+len(op):]),
+			}
+			if m.key == "" {
+				return m, fmt.Errorf("invalid expression %q: missing field name", expr)
+			}
+			switch op {
+			case "~", "!~":
+				re, err := regexp.Compile(m.value)
+				if err != nil {
+					return m, fmt.Errorf("invalid expression %q: %w", expr, err)
+				}
+				m.re = re
+			case ">", ">=", "<", "<=":
+				num, err := strconv.ParseFloat(m.value, 64)
+				if err != nil {
+					return m, fmt.Errorf("invalid expression %q: %q is not a number", expr, m.value)
+				}
+				m.num, m.isNum = num, true
+			default:
+				m.num, m.isNum = parseNumber(m.value)
+			}
+			return m, nil
+		}
+	}
+	return matcher{}, fmt.Errorf("invalid expression %q: expected key=value, key!=value, key>n, key~regexp or similar", expr)
+}
+
+func (m matcher) match(r *record) bool {
+	value, ok := m.lookup(r)
+	if !ok {
+		// A missing field only satisfies negated conditions.
+		return m.op == "!=" || m.op == "!~"
+	}
+	switch m.op {
+	case "=", "!=":
+		eq := value == m.value
+		if n, isNum := parseNumber(value); isNum && m.isNum {
+			eq = n == m.num
+		}
+		return eq == (m.op == "=")
+	case "~":
+		return m.re.MatchString(value)
+	case "!~":
+		return !m.re.MatchString(value)
+	}
+	n, isNum := parseNumber(value)
+	if !isNum {
+		return false
+	}
+	switch m.op {
+	case ">":
+		return n > m.num
+	case ">=":
+		return n >= m.num
+	case "<":
+		return n < m.num
+	default:
+		return n <= m.num
+	}
+}
+
+// lookup returns the value of m.key: a field, or msg, logger or caller.
+func (m matcher) lookup(r *record) (string, bool) {
+	for _, f := range r.entry.Fields {
+		if f.Key == m.key {
+			return f.StringValue(), true
+		}
+	}
+	switch m.key {
+	case "msg", "message":
+		return r.entry.Message, true
+	case "logger":
+		return r.logger, r.logger != ""
+	case "caller":
+		return r.entry.Caller, r.entry.Caller != ""
+	}
+	return "", false
+}
+
+func parseNumber(s string) (float64, bool) {
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
+
+// parseLevel parses a -level flag, rejecting unknown names.
+func parseLevel(s string) (logs.Level, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	for _, l := range logs.AllLevels() {
+		if l.String() == name {
+			return l, nil
+		}
+	}
+	switch name {
+	case "err", "warning":
+		return logs.ParseLevel(name), nil
+	}
+	return 0, fmt.Errorf("unknown level %q", s)
+}
+
+// parseTimeFlag parses a -since or -until flag: an RFC 3339 time, a
+// date, or a duration before now such as 15m.
+func parseTimeFlag(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: want RFC 3339, a date or a duration", s)
+}
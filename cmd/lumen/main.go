@@ -0,0 +1,251 @@
+// Command lumen reads lumen log streams and prints them for people.
+//
+// It reads NDJSON, as written by logs.JSONFormatter, and logfmt from files
+// or standard input, filters the entries, and prints them with
+// logs.PrettyFormatter. Lines that are not log entries are printed as they
+// are unless a filter is set.
+//
+// Usage:
+//
+//	lumen [flags] [file ...]
+//
+// Examples:
+//
+//	kubectl logs -f deploy/checkout | lumen -level warn
+//	lumen -f -logger 'gateway.*' -where 'status>=500' /var/log/app.json
+//	lumen -since 15m -where 'path~^/api/' -where 'user_id=42' app.log
+//	lumen -filter 'level >= warn || fields.duration > 2s' app.log
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kolosys/lumen/expr"
+	"github.com/kolosys/lumen/logs"
+)
+
+// stringsFlag collects a repeatable flag.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string     { return strings.Join(*s, ",") }
+func (s *stringsFlag) Set(v string) error { *s = append(*s, v); return nil }
+
+// config is the parsed command line.
+type config struct {
+	filter    filter
+	formatter logs.Formatter
+	follow    bool
+	poll      time.Duration
+	filtered  bool
+	files     []string
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "lumen:", err)
+		os.Exit(2)
+	}
+	if err := run(cfg, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "lumen:", err)
+		os.Exit(1)
+	}
+}
+
+func parseFlags(args []string, stderr io.Writer) (*config, error) {
+	fs := flag.NewFlagSet("lumen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: lumen [flags] [file ...]")
+		fmt.Fprintln(stderr, "Reads NDJSON or logfmt logs from the files, or stdin, and pretty-prints them.")
+		fs.PrintDefaults()
+	}
+
+	var (
+		level   = fs.String("level", "trace", "minimum `level` to print")
+		since   = fs.String("since", "", "only entries at or after `time` (RFC 3339, date, or duration ago like 15m)")
+		until   = fs.String("until", "", "only entries at or before `time`")
+		format  = fs.String("format", "pretty", "output `format`: pretty, text or json")
+		noColor = fs.Bool("no-color", false, "print plain text without ANSI colors")
+		caller  = fs.Bool("caller", false, "show the caller of each entry")
+		follow  = fs.Bool("f", false, "keep reading the files as they grow")
+		loggers stringsFlag
+		where   stringsFlag
+		filters stringsFlag
+	)
+	fs.Var(&loggers, "logger", "only loggers matching `pattern` and their children (repeatable)")
+	fs.Var(&where, "where", "only entries whose field matches `expr`: key=v, key!=v, key>n, key>=n, key<n, key<=n, key~re, key!~re (repeatable)")
+	fs.Var(&filters, "filter", "only entries matching the lumen `expression`, e.g. 'level >= error && fields.status >= 500' (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := &config{follow: *follow, poll: 250 * time.Millisecond, files: fs.Args()}
+	var err error
+	if cfg.filter.level, err = parseLevel(*level); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if cfg.filter.since, err = parseTimeFlag(*since, now); err != nil {
+		return nil, err
+	}
+	if cfg.filter.until, err = parseTimeFlag(*until, now); err != nil {
+		return nil, err
+	}
+	cfg.filter.loggers = loggers
+	for _, expr := range where {
+		m, err := parseMatcher(expr)
+		if err != nil {
+			return nil, err
+		}
+		cfg.filter.matchers = append(cfg.filter.matchers, m)
+	}
+	for _, src := range filters {
+		e, err := expr.Compile(src)
+		if err != nil {
+			return nil, err
+		}
+		cfg.filter.exprs = append(cfg.filter.exprs, e)
+	}
+	cfg.filtered = *level != "trace" || *since != "" || *until != "" || len(loggers) > 0 || len(where) > 0 || len(filters) > 0
+
+	switch {
+	case *format == "json":
+		cfg.formatter = &logs.JSONFormatter{}
+	case *format == "text" || *noColor:
+		cfg.formatter = &logs.TextFormatter{DisableColors: *noColor, FullTimestamp: true}
+	case *format == "pretty":
+		cfg.formatter = &logs.PrettyFormatter{ShowTimestamp: true, ShowCaller: *caller}
+	default:
+		return nil, fmt.Errorf("unknown format %q", *format)
+	}
+	if cfg.follow && len(cfg.files) == 0 {
+		return nil, errors.New("-f needs at least one file")
+	}
+	return cfg, nil
+}
+
+// run prints the inputs named by cfg to out.
+func run(cfg *config, stdin io.Reader, out io.Writer) error {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	if len(cfg.files) == 0 {
+		return process(cfg, bufio.NewReader(stdin), w)
+	}
+	for _, name := range cfg.files {
+		if name == "-" {
+			if err := process(cfg, bufio.NewReader(stdin), w); err != nil {
+				return err
+			}
+			continue
+		}
+		if cfg.follow {
+			return follow(cfg, name, w)
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = process(cfg, bufio.NewReader(f), w)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// process prints every line of r.
+func process(cfg *config, r *bufio.Reader, w *bufio.Writer) error {
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if werr := printLine(cfg, line, w); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// follow prints name and then the lines appended to it, starting over if
+// the file is truncated, as on rotation by copy-truncate.
+func follow(cfg *config, name string, w *bufio.Writer) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var partial []byte
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		offset += int64(len(line))
+		partial = append(partial, line...)
+		if err == nil {
+			if werr := printLine(cfg, partial, w); werr != nil {
+				return werr
+			}
+			partial = partial[:0]
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		time.Sleep(cfg.poll)
+		if info, err := f.Stat(); err == nil && info.Size() < offset {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			offset, partial = 0, partial[:0]
+		}
+		r.Reset(f)
+	}
+}
+
+// printLine prints one input line if it is a log entry passing the filter,
+// or as it is if it is not a log entry and no filter is set.
+func printLine(cfg *config, line []byte, w *bufio.Writer) error {
+	rec, err := parseLine(line)
+	if err != nil {
+		if cfg.filtered || len(strings.TrimSpace(string(line))) == 0 {
+			return nil
+		}
+		w.Write(line)
+		if line[len(line)-1] != '\n' {
+			w.WriteByte('\n')
+		}
+		return nil
+	}
+	if !cfg.filter.match(rec) {
+		return nil
+	}
+	out, err := cfg.formatter.Format(&rec.entry)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolosys/lumen/expr"
+	"github.com/kolosys/lumen/logs"
+)
+
+func TestParseLine(t *testing.T) {
+	rec, err := parseLine([]byte(`{"time":"2024-05-01T12:00:00Z","level":"warn","logger":"db","msg":"slow query","took_ms":1500,"ratio":0.5,"tags":["a"],"ok":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.entry.Level != logs.WarnLevel || rec.entry.Message != "slow query" || rec.logger != "db" {
+		t.Errorf("entry = %+v, logger %q", rec.entry, rec.logger)
+	}
+	var keys []string
+	for _, f := range rec.entry.Fields {
+		keys = append(keys, f.Key+"="+f.StringValue())
+	}
+	if got := strings.Join(keys, " "); got != `_logger=db took_ms=1500 ratio=0.5 tags=["a"] ok=true` {
+		t.Errorf("fields = %s", got)
+	}
+
+	rec, err = parseLine([]byte(`ts=1714564800.5 level=error msg="disk \"sda\" full" free=0 retry`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.entry.Message != `disk "sda" full` || rec.entry.Level != logs.ErrorLevel {
+		t.Errorf("logfmt entry = %+v", rec.entry)
+	}
+	if want := time.Unix(1714564800, 5e8); !rec.entry.Time.Equal(want) {
+		t.Errorf("time = %v, want %v", rec.entry.Time, want)
+	}
+	if len(rec.entry.Fields) != 2 || rec.entry.Fields[1].Type != logs.FieldTypeBool {
+		t.Errorf("logfmt fields = %+v", rec.entry.Fields)
+	}
+
+	for _, line := range []string{"", "panic: runtime error", `{"a":1}`, "{not json"} {
+		if _, err := parseLine([]byte(line)); err == nil {
+			t.Errorf("parseLine(%q) succeeded", line)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rec := &record{
+		logger: "gateway.http",
+		entry: logs.Entry{
+			Level:   logs.ErrorLevel,
+			Time:    base,
+			Message: "request failed",
+			Fields:  []logs.Field{logs.Int("status", 502), logs.String("path", "/api/orders")},
+		},
+	}
+	tests := []struct {
+		name string
+		f    filter
+		want bool
+	}{
+		{"no conditions", filter{level: logs.TraceLevel}, true},
+		{"level passes", filter{level: logs.WarnLevel}, true},
+		{"level fails", filter{level: logs.FatalLevel}, false},
+		{"logger glob", filter{level: logs.TraceLevel, loggers: []string{"gateway.*"}}, true},
+		{"logger parent", filter{level: logs.TraceLevel, loggers: []string{"gateway"}}, true},
+		{"logger other", filter{level: logs.TraceLevel, loggers: []string{"db"}}, false},
+		{"since", filter{level: logs.TraceLevel, since: base.Add(-time.Minute)}, true},
+		{"since after", filter{level: logs.TraceLevel, since: base.Add(time.Minute)}, false},
+		{"until before", filter{level: logs.TraceLevel, until: base.Add(-time.Minute)}, false},
+		{"expression", filter{level: logs.TraceLevel, exprs: []*expr.Expr{expr.MustCompile(`level >= error && fields.status >= 500`)}}, true},
+		{"expression fails", filter{level: logs.TraceLevel, exprs: []*expr.Expr{expr.MustCompile(`fields.path !~ "^/api/"`)}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.f.match(rec); got != tt.want {
+			t.Errorf("%s: match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	exprs := map[string]bool{
+		"status>=500":         true,
+		"status<500":          false,
+		"status=502":          true,
+		"status=502.0":        true,
+		"status!=502":         false,
+		"path~^/api/":         true,
+		"path!~orders":        false,
+		"msg~failed":          true,
+		"user=42":             false,
+		"user!=42":            true,
+		"logger=gateway.http": true,
+	}
+	for expr, want := range exprs {
+		m, err := parseMatcher(expr)
+		if err != nil {
+			t.Fatalf("parseMatcher(%q): %v", expr, err)
+		}
+		if got := m.match(rec); got != want {
+			t.Errorf("%s: match = %v, want %v", expr, got, want)
+		}
+	}
+	for _, expr := range []string{"status", "=5", "status>abc", "path~("} {
+		if _, err := parseMatcher(expr); err == nil {
+			t.Errorf("parseMatcher(%q) succeeded", expr)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	input := strings.Join([]string{
+		`{"time":"2024-05-01T12:00:00Z","level":"info","logger":"gateway","msg":"ok","status":200}`,
+		`{"time":"2024-05-01T12:00:01Z","level":"error","logger":"gateway","msg":"failed","status":503}`,
+		`goroutine 1 [running]:`,
+		`time=2024-05-01T12:00:02Z level=error msg=boom status=500`,
+	}, "\n")
+
+	var errOut bytes.Buffer
+	cfg, err := parseFlags([]string{"-format", "json", "-level", "warn", "-where", "status>=500", "-logger", "gateway"}, &errOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := run(cfg, strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"time":"2024-05-01T12:00:01Z","level":"error","logger":"gateway","msg":"failed","status":503}` + "\n"
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	cfg, _ = parseFlags([]string{"-no-color"}, &errOut)
+	out.Reset()
+	if err := run(cfg, strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 4 || !strings.Contains(out.String(), "goroutine 1 [running]:") {
+		t.Errorf("unfiltered output:\n%s", out.String())
+	}
+
+	for _, args := range [][]string{{"-level", "loud"}, {"-format", "xml"}, {"-since", "yesterday"}, {"-f"}} {
+		if _, err := parseFlags(args, &errOut); err == nil {
+			t.Errorf("parseFlags(%q) succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kolosys/lumen/logs"
+)
+
+var errNotLog = errors.New("not a log line")
+
+// Keys recognized for the entry's own attributes. The first of each list
+// is what JSONFormatter writes; the rest cover other common producers.
+var (
+	timeKeys    = []string{"time", "ts", "timestamp", "@timestamp"}
+	levelKeys   = []string{"level", "lvl", "severity"}
+	messageKeys = []string{"msg", "message"}
+	loggerKeys  = []string{"logger", "_logger"}
+	callerKeys  = []string{"caller", "source"}
+	stackKeys   = []string{"stack", "stacktrace"}
+)
+
+// record is a parsed log line.
+type record struct {
+	entry  logs.Entry
+	logger string
+}
+
+// parseLine parses an NDJSON or logfmt line.
+func parseLine(line []byte) (*record, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil, errNotLog
+	}
+	var (
+		pairs []pair
+		err   error
+	)
+	if line[0] == '{' {
+		pairs, err = parseJSON(line)
+	} else {
+		pairs, err = parseLogfmt(line)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newRecord(pairs)
+}
+
+// pair is one key/value of a line, in line order.
+type pair struct {
+	key   string
+	field logs.Field
+}
+
+func newRecord(pairs []pair) (*record, error) {
+	r := &record{entry: logs.Entry{Level: logs.InfoLevel}}
+	found := false
+	for _, p := range pairs {
+		switch {
+		case contains(timeKeys, p.key) && r.entry.Time.IsZero():
+			r.entry.Time = parseTime(p.field)
+		case contains(levelKeys, p.key):
+			r.entry.Level = logs.ParseLevel(p.field.StringValue())
+		case contains(messageKeys, p.key):
+			r.entry.Message = p.field.StringValue()
+			found = true
+		case contains(loggerKeys, p.key) && r.logger == "":
+			r.logger = p.field.StringValue()
+		case contains(callerKeys, p.key) && p.field.Type == logs.FieldTypeString:
+			r.entry.Caller = p.field.String
+		case contains(stackKeys, p.key):
+			r.entry.Stack = p.field.StringValue()
+		default:
+			r.entry.Fields = append(r.entry.Fields, p.field)
+		}
+	}
+	if !found && r.entry.Time.IsZero() {
+		return nil, errNotLog
+	}
+	if r.logger != "" {
+		// Formatters print the logger name from the _logger field.
+		r.entry.Fields = append([]logs.Field{logs.String("_logger", r.logger)}, r.entry.Fields...)
+	}
+	return r, nil
+}
+
+// parseJSON parses a JSON object, keeping its keys in order.
+func parseJSON(line []byte) ([]pair, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errNotLog
+	}
+	var pairs []pair
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair{key: key, field: jsonField(key, raw)})
+	}
+	return pairs, nil
+}
+
+func jsonField(key string, raw json.RawMessage) logs.Field {
+	switch raw[0] {
+	case '"':
+		var s string
+		json.Unmarshal(raw, &s)
+		return logs.String(key, s)
+	case 't', 'f':
+		return logs.Bool(key, raw[0] == 't')
+	case 'n':
+		return logs.Any(key, nil)
+	case '{', '[':
+		return logs.Bytes(key, raw)
+	}
+	return numberField(key, string(raw))
+}
+
+// numberField returns an int or float field for s, or a string field if s
+// is not a number.
+func numberField(key, s string) logs.Field {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return logs.Int64(key, n)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return logs.Float64(key, f)
+	}
+	return logs.String(key, s)
+}
+
+// parseLogfmt parses key=value pairs separated by spaces. Values may be
+// double-quoted with Go escapes; a bare key is a true boolean.
+func parseLogfmt(line []byte) ([]pair, error) {
+	s := string(line)
+	var pairs []pair
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			return pairs, nil
+		}
+		end := strings.IndexAny(s, "= \t")
+		if end == 0 {
+			return nil, errNotLog
+		}
+		if end < 0 || s[end] != '=' {
+			if end < 0 {
+				end = len(s)
+			}
+			pairs = append(pairs, pair{key: s[:end], field: logs.Bool(s[:end], true)})
+			s = s[end:]
+			continue
+		}
+		key := s[:end]
+		s = s[end+1:]
+
+		var value string
+		quoted := strings.HasPrefix(s, `"`)
+		if quoted {
+			prefix, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, errNotLog
+			}
+			value, _ = strconv.Unquote(prefix)
+			s = s[len(prefix):]
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+
+		field := logs.String(key, value)
+		switch {
+		case quoted:
+		case value == "true" || value == "false":
+			field = logs.Bool(key, value == "true")
+		case value != "":
+			field = numberField(key, value)
+		}
+		pairs = append(pairs, pair{key: key, field: field})
+	}
+}
+
+// parseTime reads a timestamp as RFC 3339 or as Unix seconds, millis or
+// nanos, picked by magnitude.
+func parseTime(f logs.Field) time.Time {
+	switch f.Type {
+	case logs.FieldTypeString:
+		t, _ := time.Parse(time.RFC3339Nano, f.String)
+		return t
+	case logs.FieldTypeInt:
+		return unixTime(float64(f.Int))
+	case logs.FieldTypeFloat:
+		return unixTime(f.Float)
+	}
+	return time.Time{}
+}
+
+func unixTime(n float64) time.Time {
+	switch {
+	case n > 1e17:
+		return time.Unix(0, int64(n))
+	case n > 1e11:
+		return time.UnixMilli(int64(n))
+	default:
+		sec := int64(n)
+		return time.Unix(sec, int64((n-float64(sec))*1e9))
+	}
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
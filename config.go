@@ -0,0 +1,239 @@
+package lumen
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolosys/lumen/trace"
+)
+
+// Exporter names accepted in Config.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Config describes logs, metrics and tracing for one service. The json and
+// yaml tags let it be decoded from a configuration file; ApplyEnv layers
+// environment variables on top.
+type Config struct {
+	// ServiceName identifies the service in all three signals.
+	ServiceName string `json:"service_name" yaml:"service_name"`
+
+	// ServiceVersion is the deployed version, e.g. a release tag.
+	ServiceVersion string `json:"service_version" yaml:"service_version"`
+
+	// Environment is the deployment environment, e.g. "production".
+	Environment string `json:"environment" yaml:"environment"`
+
+	// InstanceID identifies this process among the service's instances.
+	// Defaults to the detected pod or host name.
+	InstanceID string `json:"instance_id" yaml:"instance_id"`
+
+	// ResourceAttributes are added to the service's resource, e.g.
+	// "team" or "k8s.cluster.name".
+	ResourceAttributes map[string]string `json:"resource_attributes" yaml:"resource_attributes"`
+
+	// DisableResourceDetection stops the resource from being filled in
+	// from the host and environment; see resource.Detect.
+	DisableResourceDetection bool `json:"disable_resource_detection" yaml:"disable_resource_detection"`
+
+	// OTLPEndpoint is the base URL of an OTLP/HTTP collector, e.g.
+	// "http://localhost:4318". It is used by the "otlp" exporters unless
+	// a signal sets its own endpoint.
+	OTLPEndpoint string `json:"otlp_endpoint" yaml:"otlp_endpoint"`
+
+	Logs    LogsConfig    `json:"logs" yaml:"logs"`
+	Metrics MetricsConfig `json:"metrics" yaml:"metrics"`
+	Trace   TraceConfig   `json:"trace" yaml:"trace"`
+
+	// FlightRecorder keeps recent telemetry for crash dumps; see
+	// FlightRecorder.
+	FlightRecorder FlightRecorderConfig `json:"flight_recorder" yaml:"flight_recorder"`
+
+	// DisableCorrelation turns off linking the signals through the active
+	// span: trace_id and span_id fields on context logs, trace exemplars
+	// on context observations, and a log.count attribute on spans.
+	DisableCorrelation bool `json:"disable_correlation" yaml:"disable_correlation"`
+
+	// SetDefaults installs the logger, registry and tracer as the package
+	// defaults of logs, metrics and trace.
+	SetDefaults bool `json:"set_defaults" yaml:"set_defaults"`
+}
+
+// LogsConfig configures the logger.
+type LogsConfig struct {
+	// Level is the minimum level: trace, debug, info, warn or error.
+	// Defaults to info.
+	Level string `json:"level" yaml:"level"`
+
+	// Format is text, json, pretty or datadog. Defaults to text.
+	Format string `json:"format" yaml:"format"`
+
+	// Output receives the log lines. Defaults to os.Stdout.
+	Output io.Writer `json:"-" yaml:"-"`
+
+	// AddCaller records the calling file and line.
+	AddCaller bool `json:"add_caller" yaml:"add_caller"`
+
+	// FollowTraceSampling keeps entries more verbose than Level, down to
+	// debug, for requests whose trace is sampled and drops them for the
+	// rest, so sampled traces come with their full debug logs. Entries
+	// must be logged with the request context, e.g. via DebugContext.
+	FollowTraceSampling bool `json:"follow_trace_sampling" yaml:"follow_trace_sampling"`
+}
+
+// MetricsConfig configures the metrics registry.
+type MetricsConfig struct {
+	// Prefix is prepended to every metric name.
+	Prefix string `json:"prefix" yaml:"prefix"`
+
+	// Exporter is none (scrape only) or otlp. Defaults to none.
+	Exporter string `json:"exporter" yaml:"exporter"`
+
+	// Endpoint overrides the OTLP metrics URL. Defaults to
+	// Config.OTLPEndpoint + "/v1/metrics".
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+
+	// PushInterval is how often the exporter runs. Defaults to 30s.
+	PushInterval time.Duration `json:"push_interval" yaml:"push_interval"`
+
+	// ListenAddr, if set, serves the Prometheus endpoint at /metrics on
+	// this address, e.g. ":9090".
+	ListenAddr string `json:"listen_addr" yaml:"listen_addr"`
+
+	// Drop lists expressions selecting series to leave out of collection
+	// and export, e.g. `name =~ "^go_gc_"`; see package expr.
+	Drop []string `json:"drop" yaml:"drop"`
+}
+
+// TraceConfig configures the tracer.
+type TraceConfig struct {
+	// Exporter is none, stdout or otlp. Defaults to none.
+	Exporter string `json:"exporter" yaml:"exporter"`
+
+	// Endpoint overrides the OTLP traces URL. Defaults to
+	// Config.OTLPEndpoint + "/v1/traces".
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+
+	// SpanExporter, if set, is used instead of Exporter, e.g. an
+	// in-memory exporter in tests.
+	SpanExporter trace.Exporter `json:"-" yaml:"-"`
+
+	// RecentSpans is how many ended spans to keep for
+	// Observability.RecentSpans and the admin handler. Defaults to 100;
+	// negative disables.
+	RecentSpans int `json:"recent_spans" yaml:"recent_spans"`
+
+	// SampleRatio is the fraction of root traces recorded; children follow
+	// their parent's decision. Zero samples everything, negative samples
+	// nothing.
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio"`
+
+	// Drop lists expressions selecting ended spans to discard before
+	// export, e.g. `name == "GET /health" && status != error`; see
+	// package expr.
+	Drop []string `json:"drop" yaml:"drop"`
+}
+
+// FlightRecorderConfig configures the flight recorder. Zero sizes use the
+// FlightRecorderOptions defaults.
+type FlightRecorderConfig struct {
+	// Enabled turns the flight recorder on.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Dir receives the dumps. Defaults to writing them to stderr.
+	Dir string `json:"dir" yaml:"dir"`
+
+	// Logs, Spans and MetricSnapshots size the ring buffers.
+	Logs            int `json:"logs" yaml:"logs"`
+	Spans           int `json:"spans" yaml:"spans"`
+	MetricSnapshots int `json:"metric_snapshots" yaml:"metric_snapshots"`
+
+	// MetricInterval is the time between metric snapshots.
+	MetricInterval time.Duration `json:"metric_interval" yaml:"metric_interval"`
+}
+
+// ConfigFromEnv returns a Config read from the environment; see ApplyEnv.
+func ConfigFromEnv() Config {
+	var c Config
+	c.ApplyEnv()
+	return c
+}
+
+// ApplyEnv overrides fields with the environment variables that are set:
+//
+//	LUMEN_SERVICE, OTEL_SERVICE_NAME     ServiceName
+//	LUMEN_VERSION, SERVICE_VERSION       ServiceVersion
+//	LUMEN_ENV, DEPLOYMENT_ENVIRONMENT    Environment
+//	LUMEN_OTLP_ENDPOINT,
+//	OTEL_EXPORTER_OTLP_ENDPOINT          OTLPEndpoint
+//	LUMEN_LOG_LEVEL                      Logs.Level
+//	LUMEN_LOG_FORMAT                     Logs.Format
+//	LUMEN_METRICS_EXPORTER               Metrics.Exporter
+//	LUMEN_METRICS_PUSH_INTERVAL          Metrics.PushInterval
+//	LUMEN_METRICS_ADDR                   Metrics.ListenAddr
+//	LUMEN_TRACE_EXPORTER                 Trace.Exporter
+//	LUMEN_TRACE_SAMPLE_RATIO,
+//	OTEL_TRACES_SAMPLER_ARG              Trace.SampleRatio
+//
+// Malformed durations and ratios are ignored.
+func (c *Config) ApplyEnv() {
+	setEnv(&c.ServiceName, "LUMEN_SERVICE", "OTEL_SERVICE_NAME")
+	setEnv(&c.ServiceVersion, "LUMEN_VERSION", "SERVICE_VERSION")
+	setEnv(&c.Environment, "LUMEN_ENV", "DEPLOYMENT_ENVIRONMENT")
+	setEnv(&c.OTLPEndpoint, "LUMEN_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT")
+	setEnv(&c.Logs.Level, "LUMEN_LOG_LEVEL")
+	setEnv(&c.Logs.Format, "LUMEN_LOG_FORMAT")
+	setEnv(&c.Metrics.Exporter, "LUMEN_METRICS_EXPORTER")
+	setEnv(&c.Metrics.ListenAddr, "LUMEN_METRICS_ADDR")
+	setEnv(&c.Trace.Exporter, "LUMEN_TRACE_EXPORTER")
+
+	var s string
+	if setEnv(&s, "LUMEN_METRICS_PUSH_INTERVAL") {
+		if d, err := time.ParseDuration(s); err == nil {
+			c.Metrics.PushInterval = d
+		}
+	}
+	if setEnv(&s, "LUMEN_TRACE_SAMPLE_RATIO", "OTEL_TRACES_SAMPLER_ARG") {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			c.Trace.SampleRatio = f
+		}
+	}
+}
+
+// setEnv stores the first non-empty variable among keys in dst.
+func setEnv(dst *string, keys ...string) bool {
+	for _, k := range keys {
+		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+			*dst = v
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) applyDefaults() {
+	if c.ServiceName == "" {
+		c.ServiceName = "unknown"
+	}
+	if c.Logs.Output == nil {
+		c.Logs.Output = os.Stdout
+	}
+	if c.Trace.RecentSpans == 0 {
+		c.Trace.RecentSpans = 100
+	}
+	if c.Metrics.PushInterval <= 0 {
+		c.Metrics.PushInterval = 30 * time.Second
+	}
+	if c.Metrics.Endpoint == "" && c.OTLPEndpoint != "" {
+		c.Metrics.Endpoint = strings.TrimSuffix(c.OTLPEndpoint, "/") + "/v1/metrics"
+	}
+	if c.Trace.Endpoint == "" && c.OTLPEndpoint != "" {
+		c.Trace.Endpoint = strings.TrimSuffix(c.OTLPEndpoint, "/") + "/v1/traces"
+	}
+}
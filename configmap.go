@@ -0,0 +1,276 @@
+package lumen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+	"github.com/kolosys/lumen/logs"
+)
+
+// ErrUnknownConfigKey is returned for a ConfigMap key under logs., trace.
+// or metrics. that WatchConfigMap does not know.
+var ErrUnknownConfigKey = errors.New("lumen: unknown config key")
+
+// configMapData is the symlink through which Kubernetes swaps the
+// contents of a mounted ConfigMap atomically. Its target names the
+// revision.
+const configMapData = "..data"
+
+// ConfigMapOptions configures WatchConfigMap.
+type ConfigMapOptions struct {
+	// Interval is how often the directory is checked for a new revision.
+	// Defaults to 5s; the kubelet itself syncs ConfigMaps about once a
+	// minute.
+	Interval time.Duration
+
+	// File is the key holding a whole config document, applied before the
+	// single-setting keys. Defaults to "lumen.json".
+	File string
+
+	// Decode parses File; see LoadConfigFile.
+	Decode func(data []byte, v any) error
+
+	// OnError is called when a revision cannot be read or applied. Errors
+	// are logged at error level by default.
+	OnError func(err error)
+}
+
+func (o *ConfigMapOptions) applyDefaults() {
+	if o.Interval <= 0 {
+		o.Interval = 5 * time.Second
+	}
+	if o.File == "" {
+		o.File = "lumen.json"
+	}
+	if o.Decode == nil {
+		o.Decode = json.Unmarshal
+	}
+}
+
+// ConfigMapStatus reports what a ConfigMapWatcher last applied.
+type ConfigMapStatus struct {
+	// Revision identifies the applied contents: the target of the ..data
+	// symlink Kubernetes maintains, or a hash of the files in a plain
+	// directory. Empty until a revision has been applied.
+	Revision string `json:"revision"`
+
+	// AppliedAt is when Revision was applied.
+	AppliedAt time.Time `json:"applied_at"`
+
+	// Error is why the latest revision, FailedRevision, was not applied.
+	// Both are cleared once a revision applies.
+	Error          string `json:"error,omitempty"`
+	FailedRevision string `json:"failed_revision,omitempty"`
+}
+
+// ConfigMapWatcher applies a mounted ConfigMap; see WatchConfigMap.
+type ConfigMapWatcher struct {
+	o    *Observability
+	dir  string
+	opts ConfigMapOptions
+
+	// seen is the last revision tried, touched only by the watch loop.
+	seen string
+
+	mu     sync.Mutex
+	status ConfigMapStatus
+
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// WatchConfigMap applies the ConfigMap mounted at dir now and whenever
+// Kubernetes updates it, until Stop is called.
+//
+// Each key is a file in dir. The File key, lumen.json by default, holds a
+// config document as read by LoadConfigFile. Single settings can be given
+// as keys named after their config path instead, which is convenient with
+// kubectl create configmap --from-literal:
+//
+//	logs.level          log level
+//	trace.sample_ratio  trace sample ratio
+//	trace.endpoint      OTLP trace endpoint
+//	metrics.endpoint    OTLP metrics endpoint
+//
+// Settings absent from the ConfigMap keep the values passed to Setup, so
+// removing a key reverts it. Each revision is applied with Reload, and so
+// is applied completely or not at all; settings that Reload cannot change
+// are ignored. Other keys are ignored too, except unknown ones under
+// logs., trace. or metrics., which fail the revision with
+// ErrUnknownConfigKey.
+func (o *Observability) WatchConfigMap(dir string, opts *ConfigMapOptions) *ConfigMapWatcher {
+	w := &ConfigMapWatcher{
+		o:       o,
+		dir:     dir,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if opts != nil {
+		w.opts = *opts
+	}
+	w.opts.applyDefaults()
+	if w.opts.OnError == nil {
+		w.opts.OnError = func(err error) {
+			o.Logger.Error("lumen configmap reload failed", logs.Err(err))
+		}
+	}
+
+	w.check()
+	go w.run()
+	return w
+}
+
+// Status returns the revision last applied and the latest failure.
+func (w *ConfigMapWatcher) Status() ConfigMapStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// Stop stops watching. The applied configuration stays in effect.
+func (w *ConfigMapWatcher) Stop() {
+	w.once.Do(func() {
+		close(w.done)
+		<-w.stopped
+	})
+}
+
+func (w *ConfigMapWatcher) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check applies the ConfigMap if its revision changed since the last
+// check.
+func (w *ConfigMapWatcher) check() {
+	rev, err := configMapRevision(w.dir)
+	if err == nil {
+		if rev == w.seen {
+			return
+		}
+		w.seen = rev
+		var cfg Config
+		if cfg, err = w.load(); err == nil {
+			err = w.o.Reload(cfg)
+		}
+	}
+
+	w.mu.Lock()
+	if err == nil {
+		w.status = ConfigMapStatus{Revision: rev, AppliedAt: time.Now()}
+		w.mu.Unlock()
+		return
+	}
+	repeated := w.status.Error == err.Error() && w.status.FailedRevision == rev
+	w.status.Error, w.status.FailedRevision = err.Error(), rev
+	w.mu.Unlock()
+
+	// An unreadable directory is retried on every tick; report it once.
+	if !repeated {
+		report.Error(report.ConfigReload, err)
+		w.opts.OnError(err)
+	}
+}
+
+// load builds the config of the current revision on top of the config
+// passed to Setup.
+func (w *ConfigMapWatcher) load() (Config, error) {
+	cfg := w.o.Config
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return cfg, err
+	}
+	if data, err := os.ReadFile(filepath.Join(w.dir, w.opts.File)); err == nil {
+		if err := w.opts.Decode(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("lumen: parse %s: %w", w.opts.File, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return cfg, err
+	}
+
+	for _, e := range entries {
+		key := e.Name()
+		if key == w.opts.File || strings.HasPrefix(key, "..") || e.IsDir() {
+			continue
+		}
+		if !strings.HasPrefix(key, "logs.") && !strings.HasPrefix(key, "trace.") && !strings.HasPrefix(key, "metrics.") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(w.dir, key))
+		if errors.Is(err, os.ErrNotExist) {
+			// A key removed in this revision, whose symlink the kubelet
+			// has not cleaned up yet.
+			continue
+		}
+		if err != nil {
+			return cfg, err
+		}
+		if err := setConfigKey(&cfg, key, strings.TrimSpace(string(data))); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, nil
+}
+
+func setConfigKey(cfg *Config, key, value string) error {
+	switch key {
+	case "logs.level":
+		cfg.Logs.Level = value
+	case "trace.sample_ratio":
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("lumen: %s: %w", key, err)
+		}
+		cfg.Trace.SampleRatio = ratio
+	case "trace.endpoint":
+		cfg.Trace.Endpoint = value
+	case "metrics.endpoint":
+		cfg.Metrics.Endpoint = value
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownConfigKey, key)
+	}
+	return nil
+}
+
+// configMapRevision identifies the contents of dir.
+func configMapRevision(dir string) (string, error) {
+	if target, err := os.Readlink(filepath.Join(dir, configMapData)); err == nil {
+		return target, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New64a()
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "..") || e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", e.Name(), len(data))
+		h.Write(data)
+	}
+	return strconv.FormatUint(h.Sum64(), 16), nil
+}
@@ -0,0 +1,183 @@
+package lumen
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+	"github.com/kolosys/lumen/trace/semconv"
+)
+
+// Telemetry bundles the request-scoped handles of all three signals.
+type Telemetry struct {
+	// Logger is the request's logger, carrying its trace and span IDs
+	// when correlation is on.
+	Logger *logs.Logger
+
+	// Span is the current span, or a no-op span if there is none.
+	Span *trace.Span
+
+	// Metrics records into the registry with the request's context.
+	Metrics *Recorder
+}
+
+type telemetryKey struct{}
+
+// ContextWithTelemetry returns a context carrying t.
+func ContextWithTelemetry(ctx context.Context, t *Telemetry) context.Context {
+	return context.WithValue(ctx, telemetryKey{}, t)
+}
+
+// FromContext returns the telemetry stored by Observability.Middleware or
+// ContextWithTelemetry. Without it, the handles fall back to the context's
+// logger and span and the default registry. The span is always current:
+// spans started below the middleware replace the request span.
+func FromContext(ctx context.Context) *Telemetry {
+	t, _ := ctx.Value(telemetryKey{}).(*Telemetry)
+	if t == nil {
+		t = &Telemetry{
+			Logger:  logs.LoggerFromContext(ctx),
+			Metrics: &Recorder{Registry: metrics.DefaultRegistry()},
+		}
+	}
+
+	span := trace.SpanFromContext(ctx)
+	if span == nil {
+		span = trace.NoopSpan()
+	}
+	out := *t
+	out.Span = span
+	out.Metrics = &Recorder{Registry: t.Metrics.Registry, ctx: ctx}
+	return &out
+}
+
+// Recorder records metrics by name, registering them on first use.
+// Observations carry the context's trace exemplar when the registry has
+// Options.ExemplarFromContext set. Labels are key/value pairs; a name
+// must be used with the same label keys each time, or the observation is
+// dropped.
+type Recorder struct {
+	Registry *metrics.Registry
+	ctx      context.Context
+}
+
+// Inc adds 1 to the counter name.
+func (r *Recorder) Inc(name string, labels ...string) {
+	r.Add(name, 1, labels...)
+}
+
+// Add adds delta to the counter name.
+func (r *Recorder) Add(name string, delta float64, labels ...string) {
+	keys, values := splitLabels(labels)
+	r.Registry.Counter(name, "", keys...).AddContext(r.context(), delta, values...)
+}
+
+// Set sets the gauge name.
+func (r *Recorder) Set(name string, value float64, labels ...string) {
+	keys, values := splitLabels(labels)
+	r.Registry.Gauge(name, "", keys...).Set(value, values...)
+}
+
+// Observe adds an observation to the histogram name, which uses the
+// registry's default buckets.
+func (r *Recorder) Observe(name string, value float64, labels ...string) {
+	keys, values := splitLabels(labels)
+	r.Registry.Histogram(name, "", nil, keys...).ObserveContext(r.context(), value, values...)
+}
+
+// Since observes the seconds elapsed since start in the histogram name.
+func (r *Recorder) Since(name string, start time.Time, labels ...string) {
+	r.Observe(name, time.Since(start).Seconds(), labels...)
+}
+
+func (r *Recorder) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+func splitLabels(pairs []string) (keys, values []string) {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		keys = append(keys, pairs[i])
+		values = append(values, pairs[i+1])
+	}
+	return keys, values
+}
+
+// Middleware returns HTTP middleware that starts a server span for each
+// request, continuing any propagated trace, and stores the request's
+// logger, span and metrics recorder for FromContext.
+func (o *Observability) Middleware(next http.Handler) http.Handler {
+	propagator := trace.DefaultPropagator()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagator.Extract(r.Context(), headerCarrier(r.Header))
+		ctx, span := o.Tracer.Start(ctx, r.Method+" "+r.URL.Path, trace.WithAttributes(
+			semconv.HTTPMethod(r.Method),
+			semconv.URLPath(r.URL.Path),
+		))
+		defer span.End()
+
+		// Entries logged with the context of a child span get its IDs
+		// instead: ContextFields replace fields under the same keys.
+		logger := o.Logger
+		if o.correlator != nil {
+			logger = logger.With(o.correlator.logFields(ctx)...)
+		}
+		if o.boosted(span.TraceID()) && logger.GetLevel() < logs.DebugLevel {
+			logger = logger.With()
+			logger.SetLevel(logs.DebugLevel)
+		}
+		ctx = logs.WithLogger(ctx, logger)
+		ctx = ContextWithTelemetry(ctx, &Telemetry{
+			Logger:  logger,
+			Metrics: &Recorder{Registry: o.Metrics},
+		})
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPStatusCode(sw.status))
+		if sw.status >= 500 {
+			span.SetStatus(trace.StatusError, strconv.Itoa(sw.status))
+		}
+	})
+}
+
+// headerCarrier adapts http.Header to trace.Carrier.
+type headerCarrier http.Header
+
+func (h headerCarrier) Get(key string) string { return http.Header(h).Get(key) }
+func (h headerCarrier) Set(key, value string) { http.Header(h).Set(key, value) }
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// statusWriter records the status code written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
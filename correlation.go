@@ -0,0 +1,104 @@
+package lumen
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+)
+
+// Correlation field, label and attribute keys.
+const (
+	TraceIDKey  = "trace_id"
+	SpanIDKey   = "span_id"
+	LogCountKey = "log.count"
+)
+
+// correlator links the three signals through the active span: log entries
+// logged with a context get its trace and span IDs, metric observations
+// made with a context get them as exemplars, and each sampled span is
+// annotated with the number of log entries emitted while it was active.
+// Spans of traces for which verbose reports true also get each entry as
+// an event.
+type correlator struct {
+	logCounts sync.Map // span ID -> *spanLogs, sampled spans with context logs
+	verbose   func(trace.TraceID) bool
+}
+
+// spanLogs tracks the context log entries of one span.
+type spanLogs struct {
+	count atomic.Int64
+	span  atomic.Pointer[trace.Span] // set when entries become events
+}
+
+// logFields is logs.Options.ContextFields.
+func (c *correlator) logFields(ctx context.Context) []logs.Field {
+	span := trace.SpanFromContext(ctx)
+	if span == nil || !span.TraceID().IsValid() {
+		return nil
+	}
+	spanID := span.SpanID().String()
+	if span.IsSampled() && span.EndTime().IsZero() {
+		v, _ := c.logCounts.LoadOrStore(spanID, new(spanLogs))
+		if c.verbose != nil && c.verbose(span.TraceID()) {
+			v.(*spanLogs).span.Store(span)
+		}
+	}
+	return []logs.Field{
+		logs.String(TraceIDKey, span.TraceID().String()),
+		logs.String(SpanIDKey, spanID),
+	}
+}
+
+// exemplar is metrics.Options.ExemplarFromContext. Only sampled spans are
+// linked, since unsampled traces cannot be looked up.
+func (c *correlator) exemplar(ctx context.Context) metrics.Labels {
+	span := trace.SpanFromContext(ctx)
+	if span == nil || !span.IsSampled() {
+		return metrics.Labels{}
+	}
+	return metrics.NewLabels(TraceIDKey, span.TraceID().String(), SpanIDKey, span.SpanID().String())
+}
+
+// Fire counts an emitted entry against its span, and adds it as a span
+// event if the span is verbose. Entries are only counted once written,
+// after level checks and sampling.
+func (c *correlator) Fire(e *logs.Entry) {
+	spanID := e.GetString(SpanIDKey)
+	if spanID == "" {
+		return
+	}
+	v, ok := c.logCounts.Load(spanID)
+	if !ok {
+		return
+	}
+	sl := v.(*spanLogs)
+	sl.count.Add(1)
+	if span := sl.span.Load(); span != nil {
+		attrs := make([]trace.Attribute, 0, len(e.Fields)+1)
+		attrs = append(attrs, trace.Attribute{Key: "log.level", Value: e.Level.String()})
+		for _, f := range e.Fields {
+			if f.Key != TraceIDKey && f.Key != SpanIDKey {
+				attrs = append(attrs, trace.Attribute{Key: f.Key, Value: f.Value()})
+			}
+		}
+		span.AddEvent(e.Message, attrs...)
+	}
+}
+
+// Levels implements logs.Hook for all levels.
+func (c *correlator) Levels() []logs.Level { return nil }
+
+// Process adds the log count to an ended span. It runs before any other
+// processor so every sampled span releases its counter.
+func (c *correlator) Process(span *trace.SpanSnapshot) *trace.SpanSnapshot {
+	var count int64
+	if v, ok := c.logCounts.LoadAndDelete(span.SpanID.String()); ok {
+		count = v.(*spanLogs).count.Load()
+	}
+	span.Attributes = append(span.Attributes, trace.Attribute{Key: LogCountKey, Value: count})
+	return span
+}
@@ -0,0 +1,416 @@
+// Package errtrack groups errors reported through logs and traces into
+// issues. Each error-level log entry and each span ended with an error
+// status is fingerprinted by its message template and top stack frame;
+// occurrences with the same fingerprint are counted together, with their
+// first and last times and a few recent samples.
+//
+// A Tracker is both a logs.Hook and a trace.Processor:
+//
+//	tracker := errtrack.New(&errtrack.Options{WebhookURL: "https://hooks.example.com/errors"})
+//	defer tracker.Close()
+//
+//	logger := logs.New(&logs.Options{AddStack: true, Hooks: []logs.Hook{tracker}})
+//	tracer := trace.New(&trace.Options{Processors: []trace.Processor{tracker}})
+//
+//	http.Handle("/errors", tracker.Handler())
+package errtrack
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/trace"
+)
+
+// Sources of an issue.
+const (
+	SourceLog  = "log"
+	SourceSpan = "span"
+)
+
+// ErrQueueFull is reported when a webhook notification is dropped because
+// earlier ones are still being delivered.
+var ErrQueueFull = errors.New("errtrack: webhook queue full")
+
+// Options configures a Tracker.
+type Options struct {
+	// Levels are the log levels tracked. Defaults to error, fatal and
+	// panic.
+	Levels []logs.Level
+
+	// MaxIssues bounds the number of fingerprints kept. Occurrences of
+	// new fingerprints beyond it are only counted in Report.Dropped.
+	// Default: 1000
+	MaxIssues int
+
+	// MaxSamples is the number of recent occurrences kept per issue.
+	// Default: 5
+	MaxSamples int
+
+	// OnNewIssue, if set, is called with each new issue. It runs on the
+	// logging or span-ending goroutine and must not block.
+	OnNewIssue func(issue Issue)
+
+	// WebhookURL, if set, receives a JSON POST of each new issue.
+	WebhookURL string
+
+	// WebhookHeaders are added to webhook requests.
+	WebhookHeaders map[string]string
+
+	// WebhookClient sends webhook requests. Defaults to an http.Client
+	// with a 10s timeout.
+	WebhookClient *http.Client
+
+	// OnWebhookError is called when a notification cannot be delivered.
+	OnWebhookError func(err error)
+}
+
+func (o *Options) applyDefaults() {
+	if len(o.Levels) == 0 {
+		o.Levels = []logs.Level{logs.PanicLevel, logs.FatalLevel, logs.ErrorLevel}
+	}
+	if o.MaxIssues <= 0 {
+		o.MaxIssues = 1000
+	}
+	if o.MaxSamples <= 0 {
+		o.MaxSamples = 5
+	}
+	if o.WebhookClient == nil {
+		o.WebhookClient = &http.Client{Timeout: 10 * time.Second}
+	}
+}
+
+// Issue is a group of errors sharing a fingerprint.
+type Issue struct {
+	Fingerprint string    `json:"fingerprint"`
+	Source      string    `json:"source"`
+	Template    string    `json:"template"`
+	Frame       string    `json:"frame,omitempty"`
+	Count       int64     `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Samples     []Sample  `json:"samples"`
+}
+
+// Sample is the context of one occurrence.
+type Sample struct {
+	Time    time.Time         `json:"time"`
+	Message string            `json:"message"`
+	TraceID string            `json:"trace_id,omitempty"`
+	SpanID  string            `json:"span_id,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	Stack   string            `json:"stack,omitempty"`
+}
+
+// Report is a snapshot of all issues.
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Issues      []Issue   `json:"issues"`
+
+	// Dropped counts occurrences not tracked because MaxIssues was
+	// reached.
+	Dropped int64 `json:"dropped"`
+}
+
+// Tracker aggregates errors into issues.
+type Tracker struct {
+	opts Options
+
+	mu      sync.Mutex
+	issues  map[string]*Issue
+	dropped int64
+
+	webhook chan Issue
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+// New creates a Tracker. If opts is nil, default options are used.
+func New(opts *Options) *Tracker {
+	if opts == nil {
+		opts = &Options{}
+	}
+	o := *opts
+	o.applyDefaults()
+
+	t := &Tracker{opts: o, issues: make(map[string]*Issue)}
+	if o.WebhookURL != "" {
+		t.webhook = make(chan Issue, 64)
+		t.wg.Add(1)
+		go t.deliver()
+	}
+	return t
+}
+
+// Levels implements logs.Hook.
+func (t *Tracker) Levels() []logs.Level { return t.opts.Levels }
+
+// Fire implements logs.Hook. The message template includes the entry's
+// error field, and the frame is taken from its stack (see
+// logs.Options.AddStack), falling back to the caller's file.
+func (t *Tracker) Fire(e *logs.Entry) {
+	msg := e.Message
+	if errMsg := e.GetString("error"); errMsg != "" {
+		msg += ": " + errMsg
+	}
+	frame := topFrame(e.Stack)
+	if frame == "" {
+		frame = callerFile(e.Caller)
+	}
+
+	s := Sample{
+		Time:    e.Time,
+		Message: msg,
+		TraceID: e.GetString("trace_id"),
+		SpanID:  e.GetString("span_id"),
+		Stack:   e.Stack,
+	}
+	if len(e.Fields) > 0 {
+		s.Fields = make(map[string]string, len(e.Fields))
+		for _, f := range e.Fields {
+			s.Fields[f.Key] = f.StringValue()
+		}
+	}
+	t.record(SourceLog, msg, frame, s)
+}
+
+// Process implements trace.Processor, tracking spans with an error
+// status. The span name stands in for the stack frame unless the span
+// recorded an exception.stacktrace.
+func (t *Tracker) Process(span *trace.SpanSnapshot) *trace.SpanSnapshot {
+	if span.Status != trace.StatusError {
+		return span
+	}
+	msg, stack := span.StatusMessage, ""
+	for _, ev := range span.Events {
+		if ev.Name != "exception" {
+			continue
+		}
+		for _, a := range ev.Attributes {
+			switch a.Key {
+			case "exception.message":
+				msg = fmt.Sprint(a.Value)
+			case "exception.stacktrace":
+				stack = fmt.Sprint(a.Value)
+			}
+		}
+	}
+	frame := topFrame(stack)
+	if frame == "" {
+		frame = span.Name
+	}
+
+	s := Sample{
+		Time:    span.EndTime,
+		Message: msg,
+		TraceID: span.TraceID.String(),
+		SpanID:  span.SpanID.String(),
+		Stack:   stack,
+	}
+	if len(span.Attributes) > 0 {
+		s.Fields = make(map[string]string, len(span.Attributes))
+		for _, a := range span.Attributes {
+			s.Fields[a.Key] = fmt.Sprint(a.Value)
+		}
+	}
+	t.record(SourceSpan, msg, frame, s)
+	return span
+}
+
+func (t *Tracker) record(source, msg, frame string, s Sample) {
+	if s.Time.IsZero() {
+		s.Time = time.Now()
+	}
+	template := Template(msg)
+	fp := Fingerprint(template, frame)
+
+	t.mu.Lock()
+	issue, ok := t.issues[fp]
+	if !ok {
+		if len(t.issues) >= t.opts.MaxIssues {
+			t.dropped++
+			t.mu.Unlock()
+			return
+		}
+		issue = &Issue{
+			Fingerprint: fp,
+			Source:      source,
+			Template:    template,
+			Frame:       frame,
+			FirstSeen:   s.Time,
+		}
+		t.issues[fp] = issue
+	}
+	issue.Count++
+	issue.LastSeen = s.Time
+	if len(issue.Samples) == t.opts.MaxSamples {
+		issue.Samples = append(issue.Samples[:0], issue.Samples[1:]...)
+	}
+	issue.Samples = append(issue.Samples, s)
+
+	var created Issue
+	if !ok {
+		created = issue.clone()
+	}
+	t.mu.Unlock()
+
+	if ok {
+		return
+	}
+	if t.opts.OnNewIssue != nil {
+		t.opts.OnNewIssue(created)
+	}
+	t.notify(created)
+}
+
+func (i *Issue) clone() Issue {
+	c := *i
+	c.Samples = slices.Clone(i.Samples)
+	return c
+}
+
+// Issues returns all issues, most frequent first.
+func (t *Tracker) Issues() []Issue {
+	t.mu.Lock()
+	out := make([]Issue, 0, len(t.issues))
+	for _, issue := range t.issues {
+		out = append(out, issue.clone())
+	}
+	t.mu.Unlock()
+
+	slices.SortFunc(out, func(a, b Issue) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return b.LastSeen.Compare(a.LastSeen)
+	})
+	return out
+}
+
+// Issue returns the issue with the given fingerprint.
+func (t *Tracker) Issue(fingerprint string) (Issue, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	issue, ok := t.issues[fingerprint]
+	if !ok {
+		return Issue{}, false
+	}
+	return issue.clone(), true
+}
+
+// Report returns all issues and the dropped count.
+func (t *Tracker) Report() Report {
+	issues := t.Issues()
+	t.mu.Lock()
+	dropped := t.dropped
+	t.mu.Unlock()
+	return Report{GeneratedAt: time.Now(), Issues: issues, Dropped: dropped}
+}
+
+// Reset forgets all issues, e.g. after they have been reviewed. Later
+// occurrences are reported as new.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	t.issues = make(map[string]*Issue)
+	t.dropped = 0
+	t.mu.Unlock()
+}
+
+// Handler serves the report as JSON. A "fingerprint" query parameter
+// selects a single issue.
+func (t *Tracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v any = t.Report()
+		if fp := r.URL.Query().Get("fingerprint"); fp != "" {
+			issue, ok := t.Issue(fp)
+			if !ok {
+				http.Error(w, "unknown fingerprint "+fp, http.StatusNotFound)
+				return
+			}
+			v = issue
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	})
+}
+
+// Close waits for pending webhook notifications to be delivered. Issues
+// first seen after Close are still tracked but not sent.
+func (t *Tracker) Close() error {
+	t.mu.Lock()
+	if t.closed || t.webhook == nil {
+		t.closed = true
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	close(t.webhook)
+	t.mu.Unlock()
+	t.wg.Wait()
+	return nil
+}
+
+func (t *Tracker) notify(issue Issue) {
+	t.mu.Lock()
+	if t.webhook == nil || t.closed {
+		t.mu.Unlock()
+		return
+	}
+	var err error
+	select {
+	case t.webhook <- issue:
+	default:
+		err = ErrQueueFull
+	}
+	t.mu.Unlock()
+	if err != nil {
+		t.webhookError(err)
+	}
+}
+
+func (t *Tracker) deliver() {
+	defer t.wg.Done()
+	for issue := range t.webhook {
+		if err := t.post(issue); err != nil {
+			t.webhookError(err)
+		}
+	}
+}
+
+func (t *Tracker) post(issue Issue) error {
+	body, err := json.Marshal(map[string]any{"event": "new_issue", "issue": issue})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.opts.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.opts.WebhookHeaders {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.opts.WebhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("errtrack: webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+func (t *Tracker) webhookError(err error) {
+	if t.opts.OnWebhookError != nil {
+		t.opts.OnWebhookError(err)
+	}
+}
@@ -0,0 +1,202 @@
+package errtrack_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/kolosys/lumen/errtrack"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/trace"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := map[string]string{
+		`user 42 not found`:                                  `user <n> not found`,
+		`open "/tmp/a.txt": no such file`:                    `open <str>: no such file`,
+		`order 3f2b1c9e-8a7d-4e6f-9b0a-1c2d3e4f5a6b expired`: `order <uuid> expired`,
+		`bad pointer 0xc000123abc after 1.5s`:                `bad pointer <hex> after <n>s`,
+		`commit deadbeef12 missing`:                          `commit <hex> missing`,
+	}
+	for in, want := range tests {
+		if got := Template(in); got != want {
+			t.Errorf("Template(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTrackerGroupsLogErrors(t *testing.T) {
+	var mu sync.Mutex
+	var posted []string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		posted = append(posted, string(body))
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	var created []Issue
+	tracker := New(&Options{
+		MaxSamples: 2,
+		WebhookURL: hook.URL,
+		OnNewIssue: func(issue Issue) { created = append(created, issue) },
+	})
+	logger := logs.New(&logs.Options{Output: io.Discard, AddStack: true, Hooks: []logs.Hook{tracker}})
+
+	for id := range 3 {
+		logger.Error("charge failed", logs.Err(fmt.Errorf("card %d declined", id)), logs.Int("attempt", id))
+	}
+	logger.Error("refund failed", logs.Err(fmt.Errorf("card 9 declined")))
+	logger.Warn("charge failed", logs.Err(fmt.Errorf("card 1 declined")))
+	if err := tracker.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	issues := tracker.Issues()
+	if len(issues) != 2 || len(created) != 2 {
+		t.Fatalf("expected 2 issues, got %+v", issues)
+	}
+	top := issues[0]
+	if top.Count != 3 || top.Template != "charge failed: card <n> declined" || top.Source != SourceLog {
+		t.Errorf("unexpected issue: %+v", top)
+	}
+	if top.Frame != "github.com/kolosys/lumen/errtrack_test.TestTrackerGroupsLogErrors" {
+		t.Errorf("expected the logging function as frame, got %q", top.Frame)
+	}
+	if len(top.Samples) != 2 || top.Samples[1].Fields["attempt"] != "2" || top.FirstSeen.After(top.LastSeen) {
+		t.Errorf("unexpected samples: %+v", top.Samples)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(posted) != 2 {
+		t.Fatalf("expected 2 webhook posts, got %d", len(posted))
+	}
+	var payload struct {
+		Event string `json:"event"`
+		Issue Issue  `json:"issue"`
+	}
+	if err := json.Unmarshal([]byte(posted[0]), &payload); err != nil || payload.Event != "new_issue" || payload.Issue.Count != 1 {
+		t.Errorf("unexpected webhook payload %s (%v)", posted[0], err)
+	}
+}
+
+func TestTrackerErrorSpans(t *testing.T) {
+	tracker := New(&Options{MaxIssues: 1})
+	tracer := trace.New(&trace.Options{Processors: []trace.Processor{tracker}})
+	defer tracer.Close()
+
+	var traceID string
+	for id := range 2 {
+		_, span := tracer.Start(context.Background(), "charge")
+		traceID = span.TraceID().String()
+		span.RecordError(fmt.Errorf("gateway timeout after %dms", 100+id))
+		span.End()
+	}
+	_, ok := tracer.Start(context.Background(), "ok")
+	ok.End()
+	_, other := tracer.Start(context.Background(), "refund")
+	other.SetStatus(trace.StatusError, "refund rejected")
+	other.End()
+
+	report := tracker.Report()
+	if len(report.Issues) != 1 || report.Dropped != 1 {
+		t.Fatalf("expected 1 issue and 1 dropped, got %+v", report)
+	}
+	issue := report.Issues[0]
+	if issue.Count != 2 || issue.Frame != "charge" || issue.Template != "gateway timeout after <n>ms" {
+		t.Errorf("unexpected issue: %+v", issue)
+	}
+	if issue.Samples[1].TraceID != traceID {
+		t.Errorf("expected the span's trace ID in the sample, got %+v", issue.Samples[1])
+	}
+
+	rec := httptest.NewRecorder()
+	tracker.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?fingerprint="+issue.Fingerprint, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestSentryHook(t *testing.T) {
+	var mu sync.Mutex
+	var events []map[string]any
+	var auth, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+		var ev map[string]any
+		if len(lines) != 3 || json.Unmarshal([]byte(lines[2]), &ev) != nil {
+			t.Errorf("bad envelope: %s", body)
+		}
+		mu.Lock()
+		auth, path = r.Header.Get("X-Sentry-Auth"), r.URL.Path
+		events = append(events, ev)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	hook, err := NewSentryHook(&SentryOptions{
+		DSN:         strings.Replace(srv.URL, "//", "//public@", 1) + "/42",
+		Environment: "test",
+		TagKeys:     []string{"tenant"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logs.New(&logs.Options{Output: io.Discard, AddStack: true, Hooks: []logs.Hook{hook}}).Named("billing")
+
+	logger.Error("user 42 not found", logs.Err(errors.New("no rows")), logs.String("tenant", "acme"), logs.Int("attempt", 2))
+	logger.Warn("not reported")
+	logger.Error("user 7 not found")
+	hook.Close()
+	logger.Error("after close")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if path != "/api/42/envelope/" || !strings.Contains(auth, "sentry_key=public") {
+		t.Errorf("path %s, auth %s", path, auth)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	ev := events[0]
+	if fmt.Sprint(ev["fingerprint"]) != fmt.Sprint(events[1]["fingerprint"]) || fmt.Sprint(ev["fingerprint"]) != "[user <n> not found]" {
+		t.Errorf("fingerprints %v, %v", ev["fingerprint"], events[1]["fingerprint"])
+	}
+	tags := ev["tags"].(map[string]any)
+	if ev["level"] != "error" || ev["environment"] != "test" || tags["tenant"] != "acme" || tags["logger"] != "billing" {
+		t.Errorf("event = %v", ev)
+	}
+	if extra := ev["extra"].(map[string]any); extra["attempt"] != 2.0 {
+		t.Errorf("extra = %v", extra)
+	}
+	exc := ev["exception"].(map[string]any)["values"].([]any)[0].(map[string]any)
+	if exc["type"] != "*errors.errorString" || exc["value"] != "no rows" {
+		t.Errorf("exception = %v", exc)
+	}
+	frames := exc["stacktrace"].(map[string]any)["frames"].([]any)
+	last := frames[len(frames)-1].(map[string]any)
+	for i := len(frames) - 1; i >= 0 && last["in_app"] != true; i-- {
+		last = frames[i].(map[string]any)
+	}
+	if last["function"] != "TestSentryHook" {
+		t.Errorf("innermost app frame = %v", last)
+	}
+}
+
+func TestSentryDSN(t *testing.T) {
+	for _, dsn := range []string{"", "https://sentry.io/42", "https://key@sentry.io/", "::"} {
+		if _, err := NewSentryHook(&SentryOptions{DSN: dsn}); !errors.Is(err, ErrInvalidDSN) {
+			t.Errorf("%q: %v", dsn, err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package errtrack
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var templateRules = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`"[^"]*"|'[^']*'`), "<str>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>"},
+	{regexp.MustCompile(`\b\d+(\.\d+)?`), "<n>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8,}\b`), "<hex>"},
+}
+
+// Template reduces an error message to its constant parts, replacing
+// quoted strings, UUIDs, hex values and numbers with placeholders, so that
+// "user 42 not found" and "user 7 not found" share one template.
+func Template(msg string) string {
+	for _, rule := range templateRules {
+		msg = rule.re.ReplaceAllString(msg, rule.repl)
+	}
+	return strings.TrimSpace(msg)
+}
+
+// Fingerprint identifies an issue by its message template and top stack
+// frame.
+func Fingerprint(template, frame string) string {
+	h := fnv.New64a()
+	h.Write([]byte(template))
+	h.Write([]byte{0})
+	h.Write([]byte(frame))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// frameSkip lists function prefixes that are never the origin of an
+// error: the runtime and the logger itself.
+var frameSkip = []string{"runtime.", "runtime/debug.", "github.com/kolosys/lumen/logs."}
+
+// topFrame returns the first function in a runtime.Stack trace outside the
+// runtime and logger, without its arguments.
+func topFrame(stack string) string {
+	for _, line := range strings.Split(stack, "\n") {
+		if line == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		fn := line
+		if i := strings.LastIndexByte(fn, '('); i > 0 {
+			fn = fn[:i]
+		}
+		skip := false
+		for _, p := range frameSkip {
+			if strings.HasPrefix(fn, p) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			return fn
+		}
+	}
+	return ""
+}
+
+// callerFile strips the line from a "file.go:123" caller so that
+// unrelated edits to the file do not change the fingerprint.
+func callerFile(caller string) string {
+	if caller == "" || caller == "unknown" {
+		return ""
+	}
+	if i := strings.LastIndexByte(caller, ':'); i > 0 {
+		caller = caller[:i]
+	}
+	return path.Base(caller)
+}
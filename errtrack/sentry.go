@@ -0,0 +1,388 @@
+package errtrack
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+	"github.com/kolosys/lumen/logs"
+)
+
+// ErrInvalidDSN is returned for a Sentry DSN that cannot be parsed.
+var ErrInvalidDSN = errors.New("errtrack: invalid sentry dsn")
+
+// SentryOptions configures a SentryHook.
+type SentryOptions struct {
+	// DSN is the project's client key URL,
+	// https://<key>@<host>/<project>. Required.
+	DSN string
+
+	// Environment, Release and ServerName are set on every event.
+	Environment string
+	Release     string
+	ServerName  string
+
+	// Levels are the log levels reported. Defaults to error, fatal and
+	// panic.
+	Levels []logs.Level
+
+	// TagKeys are the fields sent as tags, which Sentry indexes for
+	// search. Other fields are sent as extra data. The logger name is
+	// always a tag.
+	TagKeys []string
+
+	// QueueSize bounds the events waiting to be sent; events beyond it
+	// are dropped and reported to OnError. Default: 100
+	QueueSize int
+
+	// Client sends events. Defaults to an http.Client with a 10s timeout.
+	Client *http.Client
+
+	// OnError is called when an event is dropped or cannot be delivered.
+	OnError func(err error)
+}
+
+func (o *SentryOptions) applyDefaults() {
+	if len(o.Levels) == 0 {
+		o.Levels = []logs.Level{logs.PanicLevel, logs.FatalLevel, logs.ErrorLevel}
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = 100
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+}
+
+// SentryHook is a logs.Hook that sends entries to Sentry as events. The
+// entry's error field becomes the exception and its stack (see
+// logs.Options.AddStack) the stack trace; events are fingerprinted by the
+// message template, as issues are by a Tracker, so that "user 42 not
+// found" and "user 7 not found" are one Sentry issue. Events are sent in
+// the background; Close sends those still queued.
+//
+//	hook, err := errtrack.NewSentryHook(&errtrack.SentryOptions{
+//		DSN:         os.Getenv("SENTRY_DSN"),
+//		Environment: "production",
+//		TagKeys:     []string{"tenant"},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer hook.Close()
+//	logger := logs.New(&logs.Options{AddStack: true, Hooks: []logs.Hook{hook}})
+type SentryHook struct {
+	opts     SentryOptions
+	endpoint string
+	auth     string
+
+	mu     sync.Mutex
+	queue  chan *sentryEvent
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewSentryHook creates a SentryHook and starts its sender.
+func NewSentryHook(opts *SentryOptions) (*SentryHook, error) {
+	if opts == nil {
+		opts = &SentryOptions{}
+	}
+	o := *opts
+	o.applyDefaults()
+
+	endpoint, key, err := parseDSN(o.DSN)
+	if err != nil {
+		return nil, err
+	}
+	h := &SentryHook{
+		opts:     o,
+		endpoint: endpoint,
+		auth:     "Sentry sentry_version=7, sentry_client=lumen, sentry_key=" + key,
+		queue:    make(chan *sentryEvent, o.QueueSize),
+	}
+	h.wg.Add(1)
+	go h.send()
+	return h, nil
+}
+
+// parseDSN returns the envelope endpoint and public key of dsn.
+func parseDSN(dsn string) (endpoint, key string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidDSN, err)
+	}
+	project := u.Path[strings.LastIndexByte(u.Path, '/')+1:]
+	if u.User == nil || u.User.Username() == "" || u.Host == "" || project == "" {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidDSN, dsn)
+	}
+	prefix := strings.TrimSuffix(u.Path, project)
+	endpoint = fmt.Sprintf("%s://%s%sapi/%s/envelope/", u.Scheme, u.Host, prefix, project)
+	return endpoint, u.User.Username(), nil
+}
+
+// Levels implements logs.Hook.
+func (h *SentryHook) Levels() []logs.Level { return h.opts.Levels }
+
+// Fire implements logs.Hook.
+func (h *SentryHook) Fire(e *logs.Entry) {
+	ev := h.event(e)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	var err error
+	select {
+	case h.queue <- ev:
+	default:
+		err = ErrQueueFull
+	}
+	h.mu.Unlock()
+	if err != nil {
+		h.fail(err)
+	}
+}
+
+// Close sends the queued events and stops the sender. Entries fired
+// after Close are dropped.
+func (h *SentryHook) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	close(h.queue)
+	h.mu.Unlock()
+	h.wg.Wait()
+	return nil
+}
+
+func (h *SentryHook) send() {
+	defer h.wg.Done()
+	for ev := range h.queue {
+		if err := h.post(ev); err != nil {
+			h.fail(err)
+		}
+	}
+}
+
+func (h *SentryHook) fail(err error) {
+	report.Error(report.LogHook, err)
+	if h.opts.OnError != nil {
+		h.opts.OnError(err)
+	}
+}
+
+// post sends ev as a single-item envelope.
+func (h *SentryHook) post(ev *sentryEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "{\"event_id\":%q,\"sent_at\":%q}\n", ev.EventID, time.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&body, "{\"type\":\"event\",\"length\":%d}\n", len(payload))
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, h.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", h.auth)
+	resp, err := h.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("errtrack: sentry: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("errtrack: sentry responded %s", resp.Status)
+	}
+	return nil
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger,omitempty"`
+	Message     sentryMessage     `json:"message"`
+	Fingerprint []string          `json:"fingerprint"`
+	Exception   *sentryExceptions `json:"exception,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+	Contexts    map[string]any    `json:"contexts,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	SDK         map[string]string `json:"sdk"`
+}
+
+type sentryMessage struct {
+	Formatted string `json:"formatted"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+// event converts e, which may be reused once Fire returns.
+func (h *SentryHook) event(e *logs.Entry) *sentryEvent {
+	var id [16]byte
+	rand.Read(id[:])
+	t := e.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	ev := &sentryEvent{
+		EventID:     hex.EncodeToString(id[:]),
+		Timestamp:   t.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       sentryLevel(e.Level),
+		Message:     sentryMessage{Formatted: e.Message},
+		Fingerprint: []string{Template(e.Message)},
+		Environment: h.opts.Environment,
+		Release:     h.opts.Release,
+		ServerName:  h.opts.ServerName,
+		SDK:         map[string]string{"name": "lumen.errtrack"},
+	}
+
+	exc := sentryException{Type: "log entry", Value: e.Message}
+	hasErr := false
+	traceCtx := map[string]string{}
+	for _, f := range e.Fields {
+		switch {
+		case f.Key == "_logger":
+			ev.Logger = f.String
+			ev.tag("logger", f.String)
+		case f.Key == logs.TraceIDKey || f.Key == logs.SpanIDKey:
+			traceCtx[f.Key] = f.StringValue()
+		case f.Key == "error" && f.Type == logs.FieldTypeError:
+			hasErr = true
+			exc.Type = fmt.Sprintf("%T", f.Interface)
+			exc.Value = f.String
+		case slices.Contains(h.opts.TagKeys, f.Key):
+			ev.tag(f.Key, f.StringValue())
+		default:
+			if ev.Extra == nil {
+				ev.Extra = make(map[string]any)
+			}
+			ev.Extra[f.Key] = f.Value()
+		}
+	}
+	if e.Caller != "" {
+		if ev.Extra == nil {
+			ev.Extra = make(map[string]any)
+		}
+		ev.Extra["caller"] = e.Caller
+	}
+	if traceCtx[logs.TraceIDKey] != "" {
+		ev.Contexts = map[string]any{"trace": traceCtx}
+	}
+	if frames := parseStack(e.Stack); len(frames) > 0 || hasErr {
+		if len(frames) > 0 {
+			exc.Stacktrace = &sentryStacktrace{Frames: frames}
+		}
+		ev.Exception = &sentryExceptions{Values: []sentryException{exc}}
+	}
+	return ev
+}
+
+func (ev *sentryEvent) tag(key, value string) {
+	if ev.Tags == nil {
+		ev.Tags = make(map[string]string)
+	}
+	ev.Tags[key] = value
+}
+
+func sentryLevel(level logs.Level) string {
+	switch level {
+	case logs.PanicLevel, logs.FatalLevel:
+		return "fatal"
+	case logs.ErrorLevel:
+		return "error"
+	case logs.WarnLevel:
+		return "warning"
+	case logs.InfoLevel:
+		return "info"
+	default:
+		return "debug"
+	}
+}
+
+// parseStack converts a runtime.Stack trace to Sentry frames, outermost
+// call first as Sentry expects. Frames in the runtime and the logger are
+// marked as not in the application.
+func parseStack(stack string) []sentryFrame {
+	lines := strings.Split(stack, "\n")
+	var frames []sentryFrame
+	for i := 0; i+1 < len(lines); i++ {
+		fn := lines[i]
+		loc := lines[i+1]
+		if fn == "" || strings.HasPrefix(fn, "\t") || strings.HasPrefix(fn, "goroutine ") || !strings.HasPrefix(loc, "\t") {
+			continue
+		}
+		i++
+		if j := strings.LastIndexByte(fn, '('); j > 0 {
+			fn = fn[:j]
+		}
+		fn = strings.TrimPrefix(fn, "created by ")
+		file := strings.TrimSpace(loc)
+		if j := strings.IndexByte(file, ' '); j > 0 {
+			file = file[:j] // drop the +0x offset
+		}
+		frame := sentryFrame{Function: fn, AbsPath: file, InApp: true}
+		if j := strings.LastIndexByte(file, ':'); j > 0 {
+			frame.AbsPath = file[:j]
+			frame.Lineno, _ = strconv.Atoi(file[j+1:])
+		}
+		if j := strings.LastIndexByte(fn, '/'); j >= 0 {
+			if k := strings.IndexByte(fn[j:], '.'); k > 0 {
+				frame.Module, frame.Function = fn[:j+k], fn[j+k+1:]
+			}
+		} else if k := strings.IndexByte(fn, '.'); k > 0 {
+			frame.Module, frame.Function = fn[:k], fn[k+1:]
+		}
+		for _, p := range frameSkip {
+			if strings.HasPrefix(fn, p) {
+				frame.InApp = false
+			}
+		}
+		frames = append(frames, frame)
+	}
+	slices.Reverse(frames)
+	return frames
+}
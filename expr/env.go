@@ -0,0 +1,112 @@
+package expr
+
+import (
+	"strings"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+)
+
+// entryEnv resolves names against a log entry.
+type entryEnv struct{ e *logs.Entry }
+
+func (env entryEnv) Lookup(name string) (any, bool) {
+	switch name {
+	case "level":
+		return env.e.Level, true
+	case "msg", "message":
+		return env.e.Message, true
+	case "logger":
+		if f, ok := env.e.GetField("_logger"); ok {
+			return f.StringValue(), true
+		}
+		return nil, true
+	case "caller":
+		return unlessEmpty(env.e.Caller), true
+	case "stack":
+		return unlessEmpty(env.e.Stack), true
+	}
+	if key, ok := strings.CutPrefix(name, "fields."); ok {
+		// The last field with the key wins, as when the entry is decoded
+		// from JSON.
+		for i := len(env.e.Fields) - 1; i >= 0; i-- {
+			if f := env.e.Fields[i]; f.Key == key {
+				return f.Value(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// spanEnv resolves names against an ended span.
+type spanEnv struct{ s *trace.SpanSnapshot }
+
+func (env spanEnv) Lookup(name string) (any, bool) {
+	s := env.s
+	switch name {
+	case "name":
+		return s.Name, true
+	case "service":
+		return s.ServiceName, true
+	case "status":
+		return s.Status.String(), true
+	case "status_message":
+		return unlessEmpty(s.StatusMessage), true
+	case "duration":
+		return s.Duration(), true
+	case "trace_id":
+		return s.TraceID.String(), true
+	case "span_id":
+		return s.SpanID.String(), true
+	case "parent_id":
+		if !s.ParentID.IsValid() {
+			return nil, true
+		}
+		return s.ParentID.String(), true
+	case "sampled":
+		return s.Sampled, true
+	}
+	prefix, key, _ := strings.Cut(name, ".")
+	switch prefix {
+	case "attributes", "attrs":
+		for i := len(s.Attributes) - 1; i >= 0; i-- {
+			if a := s.Attributes[i]; a.Key == key {
+				return a.Value, true
+			}
+		}
+	case "resource":
+		if v := s.Resource.Get(key); v != "" {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// unlessEmpty returns s, or nil (unset) if it is empty.
+func unlessEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// seriesEnv resolves names against a metric series.
+type seriesEnv struct {
+	name   string
+	labels metrics.Labels
+}
+
+func (env seriesEnv) Lookup(name string) (any, bool) {
+	if name == "name" || name == metrics.MetricNameLabel {
+		return env.name, true
+	}
+	if key, ok := strings.CutPrefix(name, "labels."); ok {
+		for i, k := range env.labels.Keys() {
+			if k == key {
+				return env.labels.Values()[i], true
+			}
+		}
+	}
+	return nil, false
+}
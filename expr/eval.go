@@ -0,0 +1,274 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+)
+
+type kind uint8
+
+const (
+	kindMissing kind = iota
+	kindString
+	kindNumber
+	kindDuration
+	kindBool
+	kindLevel
+)
+
+// value is an evaluated operand. Numbers and durations (in nanoseconds)
+// are kept in n; levels are kept in n as a severity, higher being more
+// severe.
+type value struct {
+	kind kind
+	s    string
+	n    float64
+	b    bool
+}
+
+func stringValue(s string) value          { return value{kind: kindString, s: s} }
+func numberValue(f float64) value         { return value{kind: kindNumber, n: f} }
+func durationValue(d time.Duration) value { return value{kind: kindDuration, n: float64(d)} }
+func boolValue(b bool) value              { return value{kind: kindBool, b: b} }
+func levelValue(l logs.Level) value       { return value{kind: kindLevel, n: float64(logs.TraceLevel - l)} }
+
+// valueOf converts a value returned by an Env.
+func valueOf(v any) value {
+	switch v := v.(type) {
+	case nil:
+		return value{}
+	case value:
+		return v
+	case string:
+		return stringValue(v)
+	case bool:
+		return boolValue(v)
+	case int:
+		return numberValue(float64(v))
+	case int8:
+		return numberValue(float64(v))
+	case int16:
+		return numberValue(float64(v))
+	case int32:
+		return numberValue(float64(v))
+	case int64:
+		return numberValue(float64(v))
+	case uint:
+		return numberValue(float64(v))
+	case uint8:
+		return numberValue(float64(v))
+	case uint16:
+		return numberValue(float64(v))
+	case uint32:
+		return numberValue(float64(v))
+	case uint64:
+		return numberValue(float64(v))
+	case float32:
+		return numberValue(float64(v))
+	case float64:
+		return numberValue(v)
+	case time.Duration:
+		return durationValue(v)
+	case time.Time:
+		return stringValue(v.Format(time.RFC3339Nano))
+	case logs.Level:
+		return levelValue(v)
+	case error:
+		return stringValue(v.Error())
+	case fmt.Stringer:
+		return stringValue(v.String())
+	case []byte:
+		return stringValue(string(v))
+	default:
+		return stringValue(fmt.Sprint(v))
+	}
+}
+
+func (v value) truthy() bool {
+	switch v.kind {
+	case kindString:
+		return v.s != ""
+	case kindNumber, kindDuration:
+		return v.n != 0
+	case kindBool:
+		return v.b
+	case kindLevel:
+		return true
+	}
+	return false
+}
+
+// text returns v as a string for regular expression matching.
+func (v value) text() string {
+	switch v.kind {
+	case kindString:
+		return v.s
+	case kindNumber:
+		return strconv.FormatFloat(v.n, 'f', -1, 64)
+	case kindDuration:
+		return time.Duration(v.n).String()
+	case kindBool:
+		return strconv.FormatBool(v.b)
+	case kindLevel:
+		return (logs.TraceLevel - logs.Level(v.n)).String()
+	}
+	return ""
+}
+
+// as converts v to kind k, reporting whether that is possible.
+func (v value) as(k kind) (value, bool) {
+	if v.kind == k {
+		return v, true
+	}
+	if v.kind != kindString {
+		return value{}, false
+	}
+	s := strings.TrimSpace(v.s)
+	switch k {
+	case kindNumber:
+		f, err := strconv.ParseFloat(s, 64)
+		return numberValue(f), err == nil
+	case kindDuration:
+		d, err := time.ParseDuration(s)
+		return durationValue(d), err == nil
+	case kindBool:
+		b, err := strconv.ParseBool(s)
+		return boolValue(b), err == nil
+	case kindLevel:
+		l := logs.ParseLevel(s)
+		return levelValue(l), l != logs.InfoLevel || strings.EqualFold(s, "info")
+	}
+	return value{}, false
+}
+
+// compare returns -1, 0 or 1, or false if a and b cannot be compared.
+// Strings are converted to the kind of the other operand.
+func compare(a, b value) (int, bool) {
+	if a.kind == kindMissing || b.kind == kindMissing {
+		return 0, false
+	}
+	k := a.kind
+	if k == kindString {
+		k = b.kind
+	}
+	a, okA := a.as(k)
+	b, okB := b.as(k)
+	if !okA || !okB {
+		return 0, false
+	}
+	switch k {
+	case kindString:
+		return strings.Compare(a.s, b.s), true
+	case kindBool:
+		// Booleans are ordered false < true, as in SQL.
+		switch {
+		case a.b == b.b:
+			return 0, true
+		case b.b:
+			return -1, true
+		}
+		return 1, true
+	default:
+		if math.IsNaN(a.n) || math.IsNaN(b.n) {
+			return 0, false
+		}
+		switch {
+		case a.n < b.n:
+			return -1, true
+		case a.n > b.n:
+			return 1, true
+		}
+		return 0, true
+	}
+}
+
+type node interface {
+	eval(env Env) value
+}
+
+type literal struct{ v value }
+
+func (n literal) eval(Env) value { return n.v }
+
+// ref is a name resolved by the Env. A bare name (no dots or brackets)
+// that the Env does not know evaluates to itself as a string.
+type ref struct {
+	name string
+	bare bool
+}
+
+func (n ref) eval(env Env) value {
+	if v, ok := env.Lookup(n.name); ok {
+		return valueOf(v)
+	}
+	if n.bare {
+		return stringValue(n.name)
+	}
+	return value{}
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(env Env) value {
+	return boolValue(n.left.eval(env).truthy() && n.right.eval(env).truthy())
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(env Env) value {
+	return boolValue(n.left.eval(env).truthy() || n.right.eval(env).truthy())
+}
+
+type notNode struct{ n node }
+
+func (n notNode) eval(env Env) value {
+	return boolValue(!n.n.eval(env).truthy())
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(env Env) value {
+	c, ok := compare(n.left.eval(env), n.right.eval(env))
+	if !ok {
+		// Values that cannot be compared, including unset names, are
+		// unequal and unordered.
+		return boolValue(n.op == "!=")
+	}
+	switch n.op {
+	case "==":
+		return boolValue(c == 0)
+	case "!=":
+		return boolValue(c != 0)
+	case "<":
+		return boolValue(c < 0)
+	case "<=":
+		return boolValue(c <= 0)
+	case ">":
+		return boolValue(c > 0)
+	default:
+		return boolValue(c >= 0)
+	}
+}
+
+type matchNode struct {
+	left   node
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (n matchNode) eval(env Env) value {
+	v := n.left.eval(env)
+	if v.kind == kindMissing {
+		return boolValue(n.negate)
+	}
+	return boolValue(n.re.MatchString(v.text()) != n.negate)
+}
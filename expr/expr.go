@@ -0,0 +1,156 @@
+// Package expr compiles small filter expressions into predicates over log
+// entries, spans and metric series, so routing rules can live in
+// configuration instead of Go code.
+//
+// An expression compares names with literals and combines the results:
+//
+//	level >= error && fields.status >= 500 && logger =~ "gateway.*"
+//	name == "GET /health" || duration < 5ms
+//	name =~ "^go_" && labels.job != "api"
+//
+// The operators are ==, !=, <, <=, >, >=, =~ and !~ (regular expression
+// match, unanchored), combined with && (and), || (or), ! (not) and
+// parentheses. Literals are quoted strings, numbers, durations such as
+// 250ms or 1m30s, and true and false. A name on its own is true if it is
+// set to anything but false, zero or "".
+//
+// The names available depend on what is matched:
+//
+//	log entries   level, msg, logger, caller, stack, fields.<key>
+//	spans         name, service, status, status_message, duration,
+//	              trace_id, span_id, parent_id, sampled,
+//	              attributes.<key>, resource.<key>
+//	series        name, labels.<key>
+//
+// Keys containing characters other than letters, digits, _ and . are
+// written fields["x-request-id"]. A bare word (without dots) that is not
+// one of these names is a string, so levels and statuses need no quotes:
+// level >= warn, status == error. Levels compare by severity.
+//
+// A comparison with a name that is not set is false, except != which is
+// true. Numbers compare numerically with values that parse as numbers,
+// and durations compare with durations.
+//
+// Compiled expressions plug into the existing extension points:
+//
+//	e := expr.MustCompile(`level >= error && fields.status >= 500`)
+//	logger.AddHook(logs.NewFilterHook(alertHook, e.MatchEntry))
+//
+//	tracer := trace.New(&trace.Options{Processors: []trace.Processor{
+//		expr.DropSpans(expr.MustCompile(`name == "GET /health"`)),
+//	}})
+//
+//	metrics.RelabelRule{Action: metrics.RelabelDrop, Match: e.MatchSeries}
+//
+// Expr implements encoding.TextUnmarshaler, so it can be a field of a
+// configuration struct decoded from JSON or YAML.
+package expr
+
+import (
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+)
+
+// Expr is a compiled expression. It is safe for concurrent use.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Env resolves the names of an expression.
+type Env interface {
+	// Lookup returns the value of name, or false if name is unknown.
+	// A known name without a value returns nil and true, so that it is
+	// not mistaken for a bare word.
+	Lookup(name string) (any, bool)
+}
+
+// EnvFunc adapts a function to the Env interface.
+type EnvFunc func(name string) (any, bool)
+
+// Lookup implements Env.
+func (f EnvFunc) Lookup(name string) (any, bool) {
+	return f(name)
+}
+
+// Compile parses an expression.
+func Compile(src string) (*Expr, error) {
+	root, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// MustCompile is like Compile but panics on error. It is meant for
+// expressions written in the source.
+func MustCompile(src string) *Expr {
+	e, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (e *Expr) MarshalText() ([]byte, error) {
+	return []byte(e.src), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (e *Expr) UnmarshalText(text []byte) error {
+	compiled, err := Compile(string(text))
+	if err != nil {
+		return err
+	}
+	*e = *compiled
+	return nil
+}
+
+// Match evaluates the expression against env.
+func (e *Expr) Match(env Env) bool {
+	return e.root.eval(env).truthy()
+}
+
+// MatchEntry evaluates the expression against a log entry. It has the
+// signature of a logs.FilterHook filter.
+func (e *Expr) MatchEntry(entry *logs.Entry) bool {
+	return e.Match(entryEnv{entry})
+}
+
+// MatchSpan evaluates the expression against an ended span.
+func (e *Expr) MatchSpan(span *trace.SpanSnapshot) bool {
+	return e.Match(spanEnv{span})
+}
+
+// MatchSeries evaluates the expression against a metric series. It has
+// the signature of metrics.RelabelRule.Match.
+func (e *Expr) MatchSeries(name string, labels metrics.Labels) bool {
+	return e.Match(seriesEnv{name, labels})
+}
+
+// KeepSpans returns a processor that drops the spans e does not match.
+func KeepSpans(e *Expr) trace.Processor {
+	return trace.ProcessorFunc(func(span *trace.SpanSnapshot) *trace.SpanSnapshot {
+		if !e.MatchSpan(span) {
+			return nil
+		}
+		return span
+	})
+}
+
+// DropSpans returns a processor that drops the spans e matches.
+func DropSpans(e *Expr) trace.Processor {
+	return trace.ProcessorFunc(func(span *trace.SpanSnapshot) *trace.SpanSnapshot {
+		if e.MatchSpan(span) {
+			return nil
+		}
+		return span
+	})
+}
@@ -0,0 +1,182 @@
+package expr_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/expr"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/resource"
+	"github.com/kolosys/lumen/trace"
+)
+
+func TestMatchEntry(t *testing.T) {
+	entry := &logs.Entry{
+		Level:   logs.ErrorLevel,
+		Message: "upstream failed",
+		Fields: []logs.Field{
+			logs.String("_logger", "gateway.http"),
+			logs.Int("status", 502),
+			logs.String("code", "404"),
+			logs.Duration("elapsed", 1500*time.Millisecond),
+			logs.Bool("retried", true),
+			logs.String("x-request-id", "abc"),
+		},
+	}
+	tests := map[string]bool{
+		`level >= error && fields.status >= 500 && logger =~ "gateway.*"`: true,
+		`level >= warn`:                 true,
+		`level > error`:                 false,
+		`level == "ERROR"`:              true,
+		`level < fatal`:                 true,
+		`fields.status == 502`:          true,
+		`fields.status != 502`:          false,
+		`fields.code == 404`:            true,
+		`fields.code < 500`:             true,
+		`fields.elapsed > 1s`:           true,
+		`fields.elapsed >= 1m30s`:       false,
+		`fields.retried`:                true,
+		`fields.retried == false`:       false,
+		`!fields.retried`:               false,
+		`fields.missing`:                false,
+		`fields.missing == 1`:           false,
+		`fields.missing != 1`:           true,
+		`fields.missing !~ "x"`:         true,
+		`fields["x-request-id"] == abc`: true,
+		`msg =~ 'fail(ed|ure)'`:         true,
+		`msg !~ "^upstream"`:            false,
+		`msg == "upstream failed"`:      true,
+		`logger == gateway.http`:        false, // a dotted name is a lookup, not a word
+		`logger == "gateway.http"`:      true,
+		`not (level == info or level == debug) and fields.status > -1`:    true,
+		`fields.status >= 500 && (fields.code == 200 || fields.retried)`:  true,
+		`fields.status >= 500 && fields.code == 200 || fields.retried`:    true,
+		`fields.status >= 500 && !(fields.code == 404 || fields.retried)`: false,
+	}
+	for src, want := range tests {
+		e, err := Compile(src)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", src, err)
+			continue
+		}
+		if got := e.MatchEntry(entry); got != want {
+			t.Errorf("%s: got %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`level >=`,
+		`level = error`,
+		`(level == error`,
+		`msg =~ foo`,
+		`msg =~ "("`,
+		`"unterminated`,
+		`fields.`,
+		`fields[status]`,
+		`level == error extra`,
+		`5xs > 1`,
+		`a # b`,
+	} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("Compile(%q) succeeded", src)
+		} else if !strings.HasPrefix(err.Error(), "expr: ") {
+			t.Errorf("Compile(%q): error %q lacks prefix", src, err)
+		}
+	}
+}
+
+func TestFilterHook(t *testing.T) {
+	ring := logs.NewRingHook(10)
+	log := logs.New(&logs.Options{
+		Output: &bytes.Buffer{},
+		Hooks:  []logs.Hook{logs.NewFilterHook(ring, MustCompile(`level >= warn && fields.status >= 500`).MatchEntry)},
+	})
+	log.Warn("slow", logs.Int("status", 200))
+	log.Error("failed", logs.Int("status", 503))
+	log.Info("ok", logs.Int("status", 500))
+
+	if entries := ring.Entries(); len(entries) != 1 || entries[0].Message != "failed" {
+		t.Errorf("entries = %v", entries)
+	}
+}
+
+func TestSpanProcessors(t *testing.T) {
+	spans := trace.NewInMemoryExporter()
+	tracer := trace.New(&trace.Options{
+		ServiceName: "api",
+		Resource:    resource.New(&resource.Options{ServiceName: "api", Attributes: map[string]string{"region": "eu"}, DisableDetection: true}),
+		Exporter:    spans,
+		Processors: []trace.Processor{
+			DropSpans(MustCompile(`name == "GET /health" && status != error`)),
+			KeepSpans(MustCompile(`resource.region == eu && service == api`)),
+		},
+	})
+	defer tracer.Close()
+
+	for _, name := range []string{"GET /health", "GET /orders"} {
+		_, span := tracer.Start(t.Context(), name)
+		span.SetAttribute("http.status_code", 200)
+		span.End()
+	}
+	_, span := tracer.Start(t.Context(), "GET /health")
+	span.SetStatus(trace.StatusError, "db down")
+	span.End()
+
+	got := spans.Spans()
+	if len(got) != 2 || got[0].Name != "GET /orders" || got[1].Status != trace.StatusError {
+		t.Fatalf("exported %d spans", len(got))
+	}
+	e := MustCompile(`attributes.http.status_code == 200 && duration < 1m && !parent_id && sampled`)
+	if !e.MatchSpan(got[0]) {
+		t.Errorf("%s did not match %+v", e, got[0])
+	}
+}
+
+func TestRelabelMatch(t *testing.T) {
+	reg := metrics.NewRegistry(&metrics.Options{Relabel: []metrics.RelabelRule{
+		{Action: metrics.RelabelDrop, Match: MustCompile(`name =~ "^debug_" || labels.path == "/health"`).MatchSeries},
+	}})
+	reg.Counter("debug_calls_total", "Calls.").Inc()
+	reqs := reg.Counter("requests_total", "Requests.", "path")
+	reqs.Inc("/health")
+	reqs.Inc("/orders")
+
+	samples := reg.Collect()
+	if len(samples) != 1 || samples[0].Labels.Get("path") != "/orders" {
+		t.Errorf("samples = %+v", samples)
+	}
+}
+
+func TestUnmarshalText(t *testing.T) {
+	var cfg struct {
+		Route *Expr `json:"route"`
+	}
+	if err := json.Unmarshal([]byte(`{"route": "level >= error"}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Route.MatchEntry(&logs.Entry{Level: logs.FatalLevel}) || cfg.Route.String() != "level >= error" {
+		t.Errorf("route = %v", cfg.Route)
+	}
+	if err := json.Unmarshal([]byte(`{"route": "level >="}`), &cfg); err == nil {
+		t.Error("invalid expression accepted")
+	}
+}
+
+func TestEnvFunc(t *testing.T) {
+	env := EnvFunc(func(name string) (any, bool) {
+		if name == "count" {
+			return uint8(3), true
+		}
+		return nil, false
+	})
+	if !MustCompile(`count > 2 && count <= 3.0 && other == other`).Match(env) {
+		t.Error("no match")
+	}
+}
@@ -0,0 +1,340 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+)
+
+type token struct {
+	kind tokenKind
+	text string // identifier, operator or unquoted string
+	num  value  // number or duration literal
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lex splits src into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokLBracket, text: "[", pos: i})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokRBracket, text: "]", pos: i})
+			i++
+		case c == '"' || c == '\'' || c == '`':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, syntaxError(src, i, err.Error())
+			}
+			tokens = append(tokens, token{kind: tokString, text: s, pos: i})
+			i += n
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && isDigit(src[i+1]):
+			n := i
+			for n < len(src) && (isIdentByte(src[n]) || src[n] == '.' || strings.HasPrefix(src[n:], "µ")) {
+				n++
+			}
+			v, err := parseNumber(src[i:n])
+			if err != nil {
+				return nil, syntaxError(src, i, err.Error())
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[i:n], num: v, pos: i})
+			i = n
+		case isIdentByte(c):
+			n := i
+			for n < len(src) && (isIdentByte(src[n]) || src[n] == '.') {
+				n++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[i:n], pos: i})
+			i = n
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "-"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, syntaxError(src, i, fmt.Sprintf("unexpected %q", r))
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexString reads the quoted string at the start of s. Double-quoted
+// strings use Go escapes; single-quoted and backquoted strings are raw,
+// which suits regular expressions.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			if quote != '"' {
+				return s[1:i], i + 1, nil
+			}
+			unquoted, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return unquoted, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// parseNumber parses a number or, with a unit suffix, a duration.
+func parseNumber(s string) (value, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return numberValue(f), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return value{}, fmt.Errorf("invalid number %q", s)
+	}
+	return durationValue(d), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c)
+}
+
+func syntaxError(src string, pos int, msg string) error {
+	return fmt.Errorf("expr: %s at offset %d in %q", msg, pos, src)
+}
+
+// parser is a recursive descent parser over:
+//
+//	or      = and { ("||" | "or") and }
+//	and     = not { ("&&" | "and") not }
+//	not     = ("!" | "not") not | compare
+//	compare = operand [ op operand ]
+//	operand = "(" or ")" | literal | name
+//	name    = ident { "[" string "]" }
+type parser struct {
+	src    string
+	tokens []token
+	pos    int
+}
+
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the operators or
+// keywords in ops.
+func (p *parser) accept(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return syntaxError(p.src, t.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||", "or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&", "and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!", "not") {
+		n, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokOp {
+		return left, nil
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{op: t.text, left: left, right: right}, nil
+	case "=~", "!~":
+		p.next()
+		pattern := p.next()
+		if pattern.kind != tokString {
+			return nil, p.errorf(pattern, "%s needs a quoted regular expression, got %s", t.text, pattern)
+		}
+		re, err := regexp.Compile(pattern.text)
+		if err != nil {
+			return nil, p.errorf(pattern, "invalid regular expression: %v", err)
+		}
+		return matchNode{left: left, re: re, negate: t.text == "!~"}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.next(); end.kind != tokRParen {
+			return nil, p.errorf(end, "expected \")\", got %s", end)
+		}
+		return n, nil
+	case tokString:
+		return literal{stringValue(t.text)}, nil
+	case tokNumber:
+		return literal{t.num}, nil
+	case tokOp:
+		if t.text == "-" && p.peek().kind == tokNumber {
+			v := p.next().num
+			v.n = -v.n
+			return literal{v}, nil
+		}
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return literal{boolValue(t.text == "true")}, nil
+		case "and", "or", "not":
+			return nil, p.errorf(t, "unexpected %s", t)
+		}
+		name := t.text
+		bare := !strings.Contains(name, ".")
+		for p.peek().kind == tokLBracket {
+			p.next()
+			key := p.next()
+			if key.kind != tokString {
+				return nil, p.errorf(key, "expected a quoted key, got %s", key)
+			}
+			if end := p.next(); end.kind != tokRBracket {
+				return nil, p.errorf(end, "expected \"]\", got %s", end)
+			}
+			name += "." + key.text
+			bare = false
+		}
+		if strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+			return nil, p.errorf(t, "invalid name %q", name)
+		}
+		return ref{name: name, bare: bare}, nil
+	}
+	return nil, p.errorf(t, "unexpected %s", t)
+}
@@ -0,0 +1,288 @@
+package lumen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/resource"
+	"github.com/kolosys/lumen/trace"
+	traceotlp "github.com/kolosys/lumen/trace/otlpconv"
+)
+
+// FlightRecorderOptions configures a FlightRecorder.
+type FlightRecorderOptions struct {
+	// Logs is how many recent log entries to keep. Default: 1000.
+	Logs int
+
+	// Spans is how many recently ended spans to keep. Default: 200.
+	Spans int
+
+	// MetricSnapshots is how many registry snapshots to keep.
+	// Default: 6.
+	MetricSnapshots int
+
+	// MetricInterval is the time between registry snapshots once Start
+	// is called. Default: 10s.
+	MetricInterval time.Duration
+
+	// Registry is snapshotted every MetricInterval. Without it no metrics
+	// are recorded.
+	Registry *metrics.Registry
+
+	// Resource identifies the service in dumps.
+	Resource *resource.Resource
+
+	// Dir, if set, receives each dump as flight-<time>.json. Otherwise
+	// dumps are written to Output.
+	Dir string
+
+	// Output receives dumps when Dir is empty. Default: os.Stderr.
+	Output io.Writer
+}
+
+func (o *FlightRecorderOptions) applyDefaults() {
+	if o.Logs <= 0 {
+		o.Logs = 1000
+	}
+	if o.Spans <= 0 {
+		o.Spans = 200
+	}
+	if o.MetricSnapshots <= 0 {
+		o.MetricSnapshots = 6
+	}
+	if o.MetricInterval <= 0 {
+		o.MetricInterval = 10 * time.Second
+	}
+	if o.Output == nil {
+		o.Output = os.Stderr
+	}
+}
+
+// FlightRecorder keeps the recent past of all three signals in memory so
+// that a rare failure can be examined after the fact: the latest log
+// entries, including Debug entries that were never printed, the latest
+// spans, and periodic snapshots of the metric registry. It dumps them on
+// a Fatal log entry, on a panic recovered by Recover, or on demand, e.g.
+// from AdminHandler's /flightrecorder.
+//
+// Attach it to a logger with Logger.SetRecorder, to a tracer through
+// trace.Options.Processors, and call Start to begin metric snapshots.
+// Setup does all three when Config.FlightRecorder.Enabled is set.
+type FlightRecorder struct {
+	opts  FlightRecorderOptions
+	spans *RecentSpans
+
+	mu        sync.Mutex
+	entries   []logs.Entry
+	nextEntry int
+	snapshots []metricSnapshot
+	nextSnap  int
+	dumpMu    sync.Mutex
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+type metricSnapshot struct {
+	Time time.Time `json:"time"`
+	Text string    `json:"prometheus"`
+}
+
+// NewFlightRecorder creates a FlightRecorder.
+func NewFlightRecorder(opts *FlightRecorderOptions) *FlightRecorder {
+	var o FlightRecorderOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.applyDefaults()
+	return &FlightRecorder{
+		opts:      o,
+		spans:     NewRecentSpans(o.Spans),
+		entries:   make([]logs.Entry, 0, o.Logs),
+		snapshots: make([]metricSnapshot, 0, o.MetricSnapshots),
+	}
+}
+
+// Fire implements logs.Hook. It keeps a copy of the entry, and dumps the
+// recorder on a Fatal entry, since the process exits right after.
+func (f *FlightRecorder) Fire(e *logs.Entry) {
+	entry := *e
+	entry.Fields = slices.Clone(e.Fields)
+
+	f.mu.Lock()
+	if len(f.entries) < cap(f.entries) {
+		f.entries = append(f.entries, entry)
+	} else {
+		f.entries[f.nextEntry] = entry
+		f.nextEntry = (f.nextEntry + 1) % len(f.entries)
+	}
+	f.mu.Unlock()
+
+	if e.Level == logs.FatalLevel {
+		f.Dump("fatal: " + e.Message)
+	}
+}
+
+// Levels implements logs.Hook.
+func (f *FlightRecorder) Levels() []logs.Level { return nil }
+
+// Process implements trace.Processor.
+func (f *FlightRecorder) Process(span *trace.SpanSnapshot) *trace.SpanSnapshot {
+	return f.spans.Process(span)
+}
+
+// Start begins taking a registry snapshot every MetricInterval. It does
+// nothing without a registry or if already started.
+func (f *FlightRecorder) Start() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.opts.Registry == nil || f.stop != nil {
+		return
+	}
+	f.stop = make(chan struct{})
+	f.stopped = make(chan struct{})
+	go f.snapshotLoop(f.stop, f.stopped)
+}
+
+// Stop stops the registry snapshots.
+func (f *FlightRecorder) Stop() {
+	f.mu.Lock()
+	stop, stopped := f.stop, f.stopped
+	f.stop, f.stopped = nil, nil
+	f.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-stopped
+	}
+}
+
+func (f *FlightRecorder) snapshotLoop(stop, stopped chan struct{}) {
+	defer close(stopped)
+	ticker := time.NewTicker(f.opts.MetricInterval)
+	defer ticker.Stop()
+	for {
+		f.SnapshotMetrics()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SnapshotMetrics records the registry's current state now.
+func (f *FlightRecorder) SnapshotMetrics() {
+	if f.opts.Registry == nil {
+		return
+	}
+	var buf bytes.Buffer
+	if err := metrics.WriteFamilies(&buf, f.opts.Registry.Gather()); err != nil {
+		return
+	}
+	snap := metricSnapshot{Time: time.Now(), Text: buf.String()}
+
+	f.mu.Lock()
+	if len(f.snapshots) < cap(f.snapshots) {
+		f.snapshots = append(f.snapshots, snap)
+	} else {
+		f.snapshots[f.nextSnap] = snap
+		f.nextSnap = (f.nextSnap + 1) % len(f.snapshots)
+	}
+	f.mu.Unlock()
+}
+
+// flightDump is the JSON document written by WriteDump.
+type flightDump struct {
+	Reason   string            `json:"reason"`
+	Time     time.Time         `json:"time"`
+	Resource map[string]string `json:"resource,omitempty"`
+	Stack    string            `json:"stack,omitempty"`
+	Logs     []json.RawMessage `json:"logs"`
+	Spans    any               `json:"spans"`
+	Metrics  []metricSnapshot  `json:"metrics"`
+}
+
+// WriteDump writes everything recorded to w as one JSON document: log
+// entries as written by logs.JSONFormatter, spans as OTLP/JSON and metric
+// snapshots in Prometheus text format, each oldest first.
+func (f *FlightRecorder) WriteDump(w io.Writer, reason string) error {
+	return f.writeDump(w, reason, "")
+}
+
+func (f *FlightRecorder) writeDump(w io.Writer, reason, stack string) error {
+	f.mu.Lock()
+	entries := append(slices.Clone(f.entries[f.nextEntry:]), f.entries[:f.nextEntry]...)
+	snapshots := append(slices.Clone(f.snapshots[f.nextSnap:]), f.snapshots[:f.nextSnap]...)
+	f.mu.Unlock()
+
+	dump := flightDump{
+		Reason:   reason,
+		Time:     time.Now(),
+		Resource: f.opts.Resource.Map(),
+		Stack:    stack,
+		Logs:     make([]json.RawMessage, 0, len(entries)),
+		Spans:    traceotlp.FromSnapshots(f.spans.Spans()),
+		Metrics:  snapshots,
+	}
+	formatter := &logs.JSONFormatter{}
+	for i := range entries {
+		line, err := formatter.Format(&entries[i])
+		if err != nil {
+			continue
+		}
+		dump.Logs = append(dump.Logs, bytes.TrimSpace(line))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dump)
+}
+
+// Dump writes a dump to a new file in Options.Dir, or to Options.Output,
+// and returns the file's path, if any.
+func (f *FlightRecorder) Dump(reason string) (string, error) {
+	return f.dump(reason, "")
+}
+
+func (f *FlightRecorder) dump(reason, stack string) (string, error) {
+	f.dumpMu.Lock()
+	defer f.dumpMu.Unlock()
+
+	if f.opts.Dir == "" {
+		return "", f.writeDump(f.opts.Output, reason, stack)
+	}
+	if err := os.MkdirAll(f.opts.Dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(f.opts.Dir, "flight-"+time.Now().UTC().Format("20060102T150405.000000000Z")+".json")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+	err = f.writeDump(file, reason, stack)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return path, err
+}
+
+// Recover dumps the recorder if the calling goroutine is panicking, with
+// the panic's stack, and then panics again with the same value. Defer it
+// at the top of main and of long-lived goroutines:
+//
+//	defer obs.FlightRecorder.Recover()
+func (f *FlightRecorder) Recover() {
+	if p := recover(); p != nil {
+		f.dump(fmt.Sprintf("panic: %v", p), string(debug.Stack()))
+		panic(p)
+	}
+}
@@ -0,0 +1,312 @@
+// Package grpcx instruments gRPC servers and clients with logs, metrics
+// and tracing in a single interceptor per call kind. Every call gets a
+// span continuing the caller's trace, a log entry when it finishes and
+// the started, handled and handling-seconds metrics, all labeled with the
+// same service, method and status code:
+//
+//	server := grpc.NewServer(grpcx.ServerOptions(nil)...)
+//	conn, err := grpc.NewClient(target, append(grpcx.DialOptions(nil), creds)...)
+//
+// With lumen.Setup, pass grpcx.OptionsFrom(obs) to use its logger,
+// registry and tracer.
+package grpcx
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/kolosys/lumen"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+	"github.com/kolosys/lumen/trace/semconv"
+)
+
+// Call types, the values of the grpc_type label.
+const (
+	Unary        = "unary"
+	ClientStream = "client_stream"
+	ServerStream = "server_stream"
+	BidiStream   = "bidi_stream"
+)
+
+// Log field keys.
+const (
+	ServiceKey  = "grpc.service"
+	MethodKey   = "grpc.method"
+	TypeKey     = "grpc.type"
+	CodeKey     = "grpc.code"
+	DurationKey = "grpc.duration"
+)
+
+// Options configures the interceptors.
+type Options struct {
+	// Logger logs each finished call. Defaults to logs.Default(). Server
+	// handlers find it, with the call's service and method fields, via
+	// logs.LoggerFromContext.
+	Logger *logs.Logger
+
+	// Registry holds the call metrics. Defaults to
+	// metrics.DefaultRegistry().
+	Registry *metrics.Registry
+
+	// Tracer starts the call spans. Defaults to trace.Default().
+	Tracer *trace.Tracer
+
+	// Propagator carries the trace context in metadata. Defaults to
+	// trace.DefaultPropagator().
+	Propagator trace.Propagator
+
+	// Filter, if set, selects the calls to instrument by full method name,
+	// e.g. to skip "/grpc.health.v1.Health/Check".
+	Filter func(fullMethod string) bool
+
+	// CodeLevel chooses the level calls are logged at. Defaults to
+	// DefaultCodeLevel.
+	CodeLevel func(code codes.Code) logs.Level
+
+	// HistogramBuckets are the buckets of the handling-seconds histogram.
+	// Defaults to the registry's buckets.
+	HistogramBuckets []float64
+}
+
+func (o *Options) applyDefaults() {
+	if o.Logger == nil {
+		o.Logger = logs.Default()
+	}
+	if o.Registry == nil {
+		o.Registry = metrics.DefaultRegistry()
+	}
+	if o.Tracer == nil {
+		o.Tracer = trace.Default()
+	}
+	if o.Propagator == nil {
+		o.Propagator = trace.DefaultPropagator()
+	}
+	if o.CodeLevel == nil {
+		o.CodeLevel = DefaultCodeLevel
+	}
+}
+
+// OptionsFrom returns Options using the logger, registry and tracer
+// configured by lumen.Setup.
+func OptionsFrom(o *lumen.Observability) *Options {
+	return &Options{Logger: o.Logger, Registry: o.Metrics, Tracer: o.Tracer}
+}
+
+// DefaultCodeLevel logs successful calls and client errors at info,
+// codes that suggest an overloaded or misbehaving dependency at warn, and
+// server faults at error.
+func DefaultCodeLevel(code codes.Code) logs.Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound,
+		codes.AlreadyExists, codes.Unauthenticated:
+		return logs.InfoLevel
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return logs.WarnLevel
+	default:
+		return logs.ErrorLevel
+	}
+}
+
+// ServerOptions returns the options installing the server interceptors.
+func ServerOptions(opts *Options) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(opts)),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(opts)),
+	}
+}
+
+// DialOptions returns the options installing the client interceptors.
+func DialOptions(opts *Options) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(opts)),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor(opts)),
+	}
+}
+
+const (
+	serverSide = "server"
+	clientSide = "client"
+)
+
+// instrument holds the metrics of one side and starts its calls.
+type instrument struct {
+	opts    Options
+	side    string
+	started *metrics.Counter
+	handled *metrics.Counter
+	seconds *metrics.Histogram
+}
+
+func newInstrument(opts *Options, side string) *instrument {
+	if opts == nil {
+		opts = &Options{}
+	}
+	o := *opts
+	o.applyDefaults()
+
+	prefix := "grpc_" + side + "_"
+	return &instrument{
+		opts: o,
+		side: side,
+		started: o.Registry.Counter(prefix+"started_total",
+			"RPCs started on the "+side+".", "grpc_type", "grpc_service", "grpc_method"),
+		handled: o.Registry.Counter(prefix+"handled_total",
+			"RPCs completed on the "+side+", by status code.", "grpc_type", "grpc_service", "grpc_method", "grpc_code"),
+		seconds: o.Registry.Histogram(prefix+"handling_seconds",
+			"Duration of RPCs on the "+side+".", o.HistogramBuckets, "grpc_type", "grpc_service", "grpc_method"),
+	}
+}
+
+func (in *instrument) enabled(fullMethod string) bool {
+	return in.opts.Filter == nil || in.opts.Filter(fullMethod)
+}
+
+// call is one instrumented RPC.
+type call struct {
+	in      *instrument
+	ctx     context.Context
+	span    *trace.Span
+	logger  *logs.Logger
+	typ     string
+	service string
+	method  string
+	start   time.Time
+}
+
+// start begins a call: on the server it continues the trace from the
+// incoming metadata, on the client it adds the trace to the outgoing
+// metadata.
+func (in *instrument) start(ctx context.Context, fullMethod, typ string) (context.Context, *call) {
+	service, method := splitMethod(fullMethod)
+	if in.side == serverSide {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = in.opts.Propagator.Extract(ctx, metadataCarrier(md))
+		}
+	}
+
+	attrs := []trace.Attribute{
+		semconv.RPCSystem("grpc"),
+		semconv.RPCService(service),
+		semconv.RPCMethod(method),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil && in.side == serverSide {
+		attrs = append(attrs, semconv.ClientAddress(p.Addr.String()))
+	}
+	ctx, span := in.opts.Tracer.Start(ctx, strings.TrimPrefix(fullMethod, "/"), trace.WithAttributes(attrs...))
+
+	if in.side == clientSide {
+		md, ok := metadata.FromOutgoingContext(ctx)
+		if ok {
+			md = md.Copy()
+		} else {
+			md = metadata.MD{}
+		}
+		in.opts.Propagator.Inject(ctx, metadataCarrier(md))
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	logger := in.opts.Logger.With(
+		logs.String(ServiceKey, service),
+		logs.String(MethodKey, method),
+		logs.String(TypeKey, typ),
+	)
+	if in.side == serverSide {
+		ctx = logs.WithLogger(ctx, logger)
+	}
+
+	in.started.Inc(typ, service, method)
+	return ctx, &call{
+		in:      in,
+		ctx:     ctx,
+		span:    span,
+		logger:  logger,
+		typ:     typ,
+		service: service,
+		method:  method,
+		start:   time.Now(),
+	}
+}
+
+// end records the outcome of the call.
+func (c *call) end(err error) {
+	elapsed := time.Since(c.start)
+	code := status.Code(err)
+
+	c.span.SetAttributes(semconv.RPCGRPCStatusCode(int(code)))
+	if err != nil && (c.in.side == clientSide || isServerFault(code)) {
+		c.span.RecordError(err)
+	}
+	c.span.End()
+
+	c.in.handled.Inc(c.typ, c.service, c.method, code.String())
+	c.in.seconds.ObserveContext(c.ctx, elapsed.Seconds(), c.typ, c.service, c.method)
+
+	fields := []logs.Field{logs.String(CodeKey, code.String()), logs.Duration(DurationKey, elapsed)}
+	if err != nil {
+		fields = append(fields, logs.Err(err))
+	}
+	c.logger.LogContext(c.ctx, c.in.opts.CodeLevel(code), "grpc "+c.in.side+" call finished", fields...)
+}
+
+// isServerFault reports whether code marks a server span as failed; the
+// remaining codes describe the request rather than the server.
+func isServerFault(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// splitMethod splits "/pkg.Service/Method" into service and method.
+func splitMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndexByte(fullMethod, '/'); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+func streamType(clientStreams, serverStreams bool) string {
+	switch {
+	case clientStreams && serverStreams:
+		return BidiStream
+	case clientStreams:
+		return ClientStream
+	case serverStreams:
+		return ServerStream
+	default:
+		return Unary
+	}
+}
+
+// metadataCarrier adapts gRPC metadata to trace.Carrier.
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	if v := metadata.MD(m).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (m metadataCarrier) Set(key, value string) { metadata.MD(m).Set(key, value) }
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
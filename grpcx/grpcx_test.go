@@ -0,0 +1,87 @@
+package grpcx_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/kolosys/lumen/grpcx"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+)
+
+func TestInterceptors(t *testing.T) {
+	var out bytes.Buffer
+	spans := trace.NewInMemoryExporter()
+	opts := &grpcx.Options{
+		Logger:   logs.New(&logs.Options{Output: &out, Formatter: &logs.JSONFormatter{}}),
+		Registry: metrics.NewRegistry(nil),
+		Tracer:   trace.New(&trace.Options{Exporter: spans}),
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpcx.ServerOptions(opts)...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", append(grpcx.DialOptions(opts),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	handled := func(side, code string) float64 {
+		c := opts.Registry.Counter("grpc_"+side+"_handled_total", "", "grpc_type", "grpc_service", "grpc_method", "grpc_code")
+		return c.Value(grpcx.Unary, "grpc.health.v1.Health", "Check", code)
+	}
+	for _, side := range []string{"server", "client"} {
+		if handled(side, "OK") != 1 || handled(side, "NotFound") != 1 {
+			t.Errorf("unexpected %s counts: OK=%v NotFound=%v", side, handled(side, "OK"), handled(side, "NotFound"))
+		}
+	}
+
+	ended := spans.Spans()
+	if len(ended) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(ended))
+	}
+	// The server span ends first and continues the client's trace.
+	serverSpan, clientSpan := ended[0], ended[1]
+	if serverSpan.Name != "grpc.health.v1.Health/Check" || serverSpan.TraceID != clientSpan.TraceID ||
+		serverSpan.ParentID != clientSpan.SpanID {
+		t.Errorf("server span not linked to client span: %+v %+v", serverSpan, clientSpan)
+	}
+	// NotFound fails the client span but not the server span.
+	if ended[2].Status != trace.StatusUnset || ended[3].Status != trace.StatusError {
+		t.Errorf("unexpected statuses: server %v, client %v", ended[2].Status, ended[3].Status)
+	}
+
+	logged := out.String()
+	if strings.Count(logged, "grpc server call finished") != 2 || !strings.Contains(logged, `"grpc.code":"NotFound"`) ||
+		!strings.Contains(logged, `"grpc.method":"Check"`) {
+		t.Errorf("unexpected logs: %s", logged)
+	}
+}
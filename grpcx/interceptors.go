@@ -0,0 +1,113 @@
+package grpcx
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerInterceptor instruments unary calls on the server.
+func UnaryServerInterceptor(opts *Options) grpc.UnaryServerInterceptor {
+	in := newInstrument(opts, serverSide)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !in.enabled(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, c := in.start(ctx, info.FullMethod, Unary)
+		resp, err := handler(ctx, req)
+		c.end(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor instruments streaming calls on the server. The
+// call ends when the handler returns.
+func StreamServerInterceptor(opts *Options) grpc.StreamServerInterceptor {
+	in := newInstrument(opts, serverSide)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !in.enabled(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx, c := in.start(ss.Context(), info.FullMethod, streamType(info.IsClientStream, info.IsServerStream))
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		c.end(err)
+		return err
+	}
+}
+
+// serverStream replaces the stream's context with the instrumented one.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+// UnaryClientInterceptor instruments unary calls on the client.
+func UnaryClientInterceptor(opts *Options) grpc.UnaryClientInterceptor {
+	in := newInstrument(opts, clientSide)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if !in.enabled(method) {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		ctx, c := in.start(ctx, method, Unary)
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		c.end(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor instruments streaming calls on the client. The
+// call ends when RecvMsg returns the final status, or, for streams with a
+// single response, once that response is received.
+func StreamClientInterceptor(opts *Options) grpc.StreamClientInterceptor {
+	in := newInstrument(opts, clientSide)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if !in.enabled(method) {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+		ctx, c := in.start(ctx, method, streamType(desc.ClientStreams, desc.ServerStreams))
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			c.end(err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, call: c, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// clientStream ends its call when the stream completes.
+type clientStream struct {
+	grpc.ClientStream
+	call          *call
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.serverStreams:
+		s.finish(nil)
+	}
+	return err
+}
+
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.finish(err)
+	}
+	return md, err
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() { s.call.end(err) })
+}
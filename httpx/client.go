@@ -0,0 +1,108 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+	"github.com/kolosys/lumen/trace/semconv"
+)
+
+// transport instruments a RoundTripper.
+type transport struct {
+	base     http.RoundTripper
+	opts     Options
+	requests *metrics.Counter
+	duration *metrics.Histogram
+}
+
+// Transport wraps base, http.DefaultTransport if nil, so that each request
+// gets a client span whose context is sent in the request headers, an
+// entry in http_client_requests_total and
+// http_client_request_duration_seconds labeled by server address, and a
+// log entry. Failed round trips are counted with status "error".
+func Transport(base http.RoundTripper, opts *Options) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.StatusLevel == nil {
+		o.StatusLevel = clientStatusLevel
+	}
+	o.applyDefaults()
+
+	return &transport{
+		base: base,
+		opts: o,
+		requests: o.Registry.Counter("http_client_requests_total",
+			"HTTP requests sent.", MethodLabel, ServerLabel, StatusLabel),
+		duration: o.Registry.Histogram("http_client_request_duration_seconds",
+			"Duration of outgoing HTTP requests.", o.HistogramBuckets, MethodLabel, ServerLabel),
+	}
+}
+
+// clientStatusLevel logs successful outgoing requests at debug, since the
+// server side usually logs them too, and failed round trips at error.
+func clientStatusLevel(status int) logs.Level {
+	switch {
+	case status == 0:
+		return logs.ErrorLevel
+	case status < 400:
+		return logs.DebugLevel
+	default:
+		return DefaultStatusLevel(status)
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.opts.Filter != nil && !t.opts.Filter(r) {
+		return t.base.RoundTrip(r)
+	}
+
+	start := time.Now()
+	method, host := r.Method, r.URL.Host
+	ctx, span := t.opts.Tracer.Start(r.Context(), method, trace.WithAttributes(
+		semconv.HTTPMethod(method),
+		semconv.URLFull(r.URL.Redacted()),
+		semconv.ServerAddress(r.URL.Hostname()),
+	))
+
+	// RoundTrippers must not modify the caller's request.
+	req := r.Clone(ctx)
+	t.opts.Propagator.Inject(ctx, headerCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	code, status := 0, "error"
+	if err != nil {
+		span.RecordError(err)
+	} else {
+		code, status = resp.StatusCode, strconv.Itoa(resp.StatusCode)
+		spanStatus(span, code, true)
+	}
+	span.End()
+
+	t.requests.Inc(method, host, status)
+	t.duration.ObserveContext(ctx, elapsed.Seconds(), method, host)
+
+	fields := []logs.Field{
+		logs.String(semconv.HTTPMethodKey, method),
+		logs.String(semconv.URLFullKey, r.URL.Redacted()),
+		logs.Duration(DurationKey, elapsed),
+	}
+	if err != nil {
+		fields = append(fields, logs.Err(err))
+	} else {
+		fields = append(fields, logs.Int(semconv.HTTPStatusCodeKey, code))
+	}
+	t.opts.Logger.LogContext(ctx, t.opts.StatusLevel(code), "http client request", fields...)
+	return resp, err
+}
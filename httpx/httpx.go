@@ -0,0 +1,164 @@
+// Package httpx instruments net/http servers and clients with logs,
+// metrics and tracing together. Middleware wraps a handler and
+// Transport wraps a RoundTripper; both record a span, a log entry and RED
+// metrics (rate, errors and duration) for every request, using the
+// OpenTelemetry HTTP attribute names for span attributes and log fields
+// and their underscore forms for metric labels:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("GET /users/{id}", getUser)
+//	http.ListenAndServe(":8080", httpx.Middleware(mux, nil))
+//
+//	client := &http.Client{Transport: httpx.Transport(nil, nil)}
+//
+// Requests are labeled by route template, never by raw path, so metric
+// cardinality stays bounded; see Options.Route and SetRoute.
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+	"github.com/kolosys/lumen/trace/semconv"
+)
+
+// Metric label names, the semconv attribute keys with underscores.
+const (
+	MethodLabel = "http_request_method"
+	RouteLabel  = "http_route"
+	StatusLabel = "http_response_status_code"
+	ServerLabel = "server_address"
+)
+
+// Unmatched is the route of requests that no route was found for.
+const Unmatched = "unmatched"
+
+// Options configures Middleware and Transport.
+type Options struct {
+	// Logger logs each request. Defaults to logs.Default(). Handlers find
+	// it, with the request's method, via logs.LoggerFromContext.
+	Logger *logs.Logger
+
+	// Registry holds the RED metrics. Defaults to
+	// metrics.DefaultRegistry().
+	Registry *metrics.Registry
+
+	// Tracer starts the request spans. Defaults to trace.Default().
+	Tracer *trace.Tracer
+
+	// Propagator reads and writes the trace context headers. Defaults to
+	// trace.DefaultPropagator().
+	Propagator trace.Propagator
+
+	// Route returns the route template of a server request, e.g.
+	// "/users/{id}". It runs after the handler, so routers can record the
+	// matched route first. Defaults to the route set with SetRoute, then
+	// the http.ServeMux pattern, then Unmatched.
+	Route func(r *http.Request) string
+
+	// Filter, if set, selects the requests to instrument, e.g. to skip
+	// health checks.
+	Filter func(r *http.Request) bool
+
+	// StatusLevel chooses the level requests are logged at. Outgoing
+	// requests that failed without a response are passed 0. Defaults to
+	// DefaultStatusLevel for Middleware; Transport logs successful
+	// requests at debug and failed round trips at error.
+	StatusLevel func(status int) logs.Level
+
+	// DisableRecovery lets handler panics propagate instead of logging
+	// them and responding 500.
+	DisableRecovery bool
+
+	// HistogramBuckets are the buckets of the duration histograms.
+	// Defaults to the registry's buckets.
+	HistogramBuckets []float64
+}
+
+func (o *Options) applyDefaults() {
+	if o.Logger == nil {
+		o.Logger = logs.Default()
+	}
+	if o.Registry == nil {
+		o.Registry = metrics.DefaultRegistry()
+	}
+	if o.Tracer == nil {
+		o.Tracer = trace.Default()
+	}
+	if o.Propagator == nil {
+		o.Propagator = trace.DefaultPropagator()
+	}
+	if o.Route == nil {
+		o.Route = defaultRoute
+	}
+	if o.StatusLevel == nil {
+		o.StatusLevel = DefaultStatusLevel
+	}
+}
+
+// DefaultStatusLevel logs server errors at error, client errors at warn
+// and everything else at info.
+func DefaultStatusLevel(status int) logs.Level {
+	switch {
+	case status >= 500:
+		return logs.ErrorLevel
+	case status >= 400:
+		return logs.WarnLevel
+	default:
+		return logs.InfoLevel
+	}
+}
+
+type routeKey struct{}
+
+// SetRoute records the route template matched for r, for routers that do
+// not set http.Request.Pattern. It has no effect outside Middleware.
+func SetRoute(r *http.Request, route string) {
+	if p, ok := r.Context().Value(routeKey{}).(*string); ok {
+		*p = route
+	}
+}
+
+func withRouteHolder(ctx context.Context) (context.Context, *string) {
+	p := new(string)
+	return context.WithValue(ctx, routeKey{}, p), p
+}
+
+func defaultRoute(r *http.Request) string {
+	if p, ok := r.Context().Value(routeKey{}).(*string); ok && *p != "" {
+		return *p
+	}
+	if r.Pattern != "" {
+		// Patterns have the form "[METHOD ][HOST]/[PATH]".
+		if i := strings.IndexByte(r.Pattern, '/'); i >= 0 {
+			return r.Pattern[i:]
+		}
+	}
+	return Unmatched
+}
+
+// headerCarrier adapts http.Header to trace.Carrier.
+type headerCarrier http.Header
+
+func (h headerCarrier) Get(key string) string { return http.Header(h).Get(key) }
+func (h headerCarrier) Set(key, value string) { http.Header(h).Set(key, value) }
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// spanStatus records an HTTP status code on span. Server spans fail on
+// 5xx responses, client spans on any error response.
+func spanStatus(span *trace.Span, code int, client bool) {
+	span.SetAttributes(semconv.HTTPStatusCode(code))
+	if code >= 500 || (client && code >= 400) {
+		span.SetStatus(trace.StatusError, http.StatusText(code))
+	}
+}
@@ -0,0 +1,98 @@
+package httpx_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/lumen/httpx"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+)
+
+func TestMiddlewareAndTransport(t *testing.T) {
+	var out bytes.Buffer
+	spans := trace.NewInMemoryExporter()
+	opts := &Options{
+		Logger:   logs.New(&logs.Options{Output: &out, Formatter: &logs.JSONFormatter{}}),
+		Registry: metrics.NewRegistry(nil),
+		Tracer:   trace.New(&trace.Options{Exporter: spans}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logs.LoggerFromContext(r.Context()).Info("loading user")
+		io.WriteString(w, "ok")
+	})
+	mux.HandleFunc("/legacy/", func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r, "/legacy/{page}")
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("out of cheese")
+	})
+	server := httptest.NewServer(Middleware(mux, opts))
+	defer server.Close()
+
+	client := &http.Client{Transport: Transport(nil, opts)}
+	for _, path := range []string{"/users/1", "/users/2", "/legacy/a", "/boom"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	requests := opts.Registry.Counter("http_server_requests_total", "", MethodLabel, RouteLabel, StatusLabel)
+	for _, tt := range []struct {
+		route, status string
+		want          float64
+	}{
+		{"/users/{id}", "200", 2},
+		{"/legacy/{page}", "404", 1},
+		{"/boom", "500", 1},
+	} {
+		if got := requests.Value("GET", tt.route, tt.status); got != tt.want {
+			t.Errorf("requests{%s,%s} = %v, want %v", tt.route, tt.status, got, tt.want)
+		}
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	sent := opts.Registry.Counter("http_client_requests_total", "", MethodLabel, ServerLabel, StatusLabel)
+	if got := sent.Value("GET", host, "404"); got != 1 {
+		t.Errorf("expected 1 client 404, got %v", got)
+	}
+
+	ended := spans.Spans()
+	if len(ended) != 8 {
+		t.Fatalf("expected 8 spans, got %d", len(ended))
+	}
+	// Each server span ends before, and is a child of, its client span.
+	serverSpan, clientSpan := ended[0], ended[1]
+	if serverSpan.Name != "GET /users/{id}" || serverSpan.ParentID != clientSpan.SpanID || clientSpan.Name != "GET" {
+		t.Errorf("unexpected spans: %q (parent %s), %q (%s)", serverSpan.Name, serverSpan.ParentID, clientSpan.Name, clientSpan.SpanID)
+	}
+	if ended[4].Status != trace.StatusUnset || ended[5].Status != trace.StatusError {
+		t.Errorf("a 404 should fail only the client span, got %v and %v", ended[4].Status, ended[5].Status)
+	}
+	if ended[6].Status != trace.StatusError {
+		t.Errorf("expected the panicking request's span to fail")
+	}
+
+	logged := out.String()
+	for _, want := range []string{
+		`"msg":"loading user"`,
+		`"http.route":"/users/{id}"`,
+		`"panic":"out of cheese"`,
+	} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("expected %s in logs: %s", want, logged)
+		}
+	}
+	if n := strings.Count(logged, "http client request"); n != 2 {
+		t.Errorf("expected only failed client requests above debug, got %d: %s", n, logged)
+	}
+}
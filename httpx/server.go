@@ -0,0 +1,149 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+	"github.com/kolosys/lumen/trace/semconv"
+)
+
+// Log field keys not covered by semconv.
+const (
+	DurationKey = "http.duration"
+	PanicKey    = "panic"
+)
+
+// server holds the server metrics.
+type server struct {
+	opts     Options
+	requests *metrics.Counter
+	duration *metrics.Histogram
+	inFlight *metrics.Gauge
+}
+
+// Middleware instruments next. Each request gets a server span continuing
+// any propagated trace, an entry in http_server_requests_total and
+// http_server_request_duration_seconds, and a log entry once it
+// completes. Panics in next are recovered, logged with their stack and
+// answered with 500 unless Options.DisableRecovery is set.
+func Middleware(next http.Handler, opts *Options) http.Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+	o := *opts
+	o.applyDefaults()
+
+	s := &server{
+		opts: o,
+		requests: o.Registry.Counter("http_server_requests_total",
+			"HTTP requests handled.", MethodLabel, RouteLabel, StatusLabel),
+		duration: o.Registry.Histogram("http_server_request_duration_seconds",
+			"Duration of HTTP requests.", o.HistogramBuckets, MethodLabel, RouteLabel),
+		inFlight: o.Registry.Gauge("http_server_active_requests",
+			"HTTP requests being handled.", MethodLabel),
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.Filter != nil && !o.Filter(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		s.serve(next, w, r)
+	})
+}
+
+func (s *server) serve(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	method := r.Method
+	s.inFlight.Inc(method)
+	defer s.inFlight.Dec(method)
+
+	ctx := s.opts.Propagator.Extract(r.Context(), headerCarrier(r.Header))
+	ctx, span := s.opts.Tracer.Start(ctx, method, trace.WithAttributes(
+		semconv.HTTPMethod(method),
+		semconv.URLPath(r.URL.Path),
+		semconv.ClientAddress(r.RemoteAddr),
+		semconv.UserAgent(r.UserAgent()),
+	))
+	ctx, route := withRouteHolder(ctx)
+	logger := s.opts.Logger.With(logs.String(semconv.HTTPMethodKey, method))
+	ctx = logs.WithLogger(ctx, logger)
+
+	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+	req := r.WithContext(ctx)
+
+	var recovered any
+	var stack []byte
+	func() {
+		if !s.opts.DisableRecovery {
+			defer func() {
+				if recovered = recover(); recovered != nil {
+					if recovered == http.ErrAbortHandler {
+						panic(recovered)
+					}
+					stack = debug.Stack()
+					if !rw.wroteHeader {
+						http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					}
+					rw.status = http.StatusInternalServerError
+				}
+			}()
+		}
+		next.ServeHTTP(rw, req)
+	}()
+
+	elapsed := time.Since(start)
+	*route = s.opts.Route(req)
+	span.SetName(method + " " + *route)
+	span.SetAttributes(semconv.HTTPRoute(*route))
+	if recovered != nil {
+		span.RecordError(fmt.Errorf("panic: %v", recovered))
+	}
+	spanStatus(span, rw.status, false)
+	span.End()
+
+	status := strconv.Itoa(rw.status)
+	s.requests.Inc(method, *route, status)
+	s.duration.ObserveContext(ctx, elapsed.Seconds(), method, *route)
+
+	fields := []logs.Field{
+		logs.String(semconv.HTTPRouteKey, *route),
+		logs.String(semconv.URLPathKey, r.URL.Path),
+		logs.Int(semconv.HTTPStatusCodeKey, rw.status),
+		logs.Int64(semconv.HTTPResponseSizeKey, rw.bytes),
+		logs.Duration(DurationKey, elapsed),
+	}
+	if recovered != nil {
+		fields = append(fields, logs.String(PanicKey, fmt.Sprint(recovered)), logs.String("stack", string(stack)))
+	}
+	logger.LogContext(ctx, s.opts.StatusLevel(rw.status), "http request", fields...)
+}
+
+// responseWriter records the status and size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
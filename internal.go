@@ -0,0 +1,31 @@
+package lumen
+
+import "github.com/kolosys/lumen/internal/report"
+
+// Components passed to OnInternalError handlers.
+const (
+	ComponentLogs          = report.Logs          // formatting or writing a log entry
+	ComponentLogHook       = report.LogHook       // a WriterHook or FileHook failing
+	ComponentTraceExport   = report.TraceExport   // a span exporter returning an error
+	ComponentMetricsPush   = report.MetricsPush   // a metrics push giving up after retries
+	ComponentProfileUpload = report.ProfileUpload // a scheduled profile capture or upload
+	ComponentConfigReload  = report.ConfigReload  // WatchConfig failing to apply a file
+)
+
+// OnInternalError registers fn to be called when the telemetry pipeline
+// itself fails: a log entry that cannot be written, a hook that cannot
+// write, a span export or metrics push that fails, and so on. It applies
+// to every logger, tracer and registry in the process, whether or not
+// they were created by Setup, and returns a function that removes fn.
+//
+// fn runs synchronously on the failing path and may be called once per
+// entry while an output is broken, so it should be cheap. It must not log
+// through a logger that may be the one failing; writing to os.Stderr or
+// incrementing a counter is safe.
+//
+//	lumen.OnInternalError(func(component string, err error) {
+//		fmt.Fprintf(os.Stderr, "lumen %s: %v\n", component, err)
+//	})
+func OnInternalError(fn func(component string, err error)) (remove func()) {
+	return report.Subscribe(fn)
+}
@@ -0,0 +1,113 @@
+package enc_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/internal/enc"
+)
+
+func TestAppendJSONStringMatchesEncodingJSON(t *testing.T) {
+	inputs := []string{
+		"",
+		"plain",
+		`quote " and \ backslash`,
+		"ctl \x00\x01\x1f\n\r\t",
+		"<script>&amp;</script>",
+		"line para ",
+		"invalid \xff\xfe utf8",
+		"unicode héllo 世界 🎉",
+	}
+	for _, in := range inputs {
+		want, _ := json.Marshal(in)
+		if got := AppendJSONString(nil, in); string(got) != string(want) {
+			t.Errorf("AppendJSONString(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestAppendJSONFloatMatchesEncodingJSON(t *testing.T) {
+	for _, f := range []float64{0, 1, -1.5, 3.14, 1e20, 1e21, 1e-6, 1e-7, 123456789.125, -2.5e-9, math.MaxFloat64, math.SmallestNonzeroFloat64} {
+		want, _ := json.Marshal(f)
+		if got := AppendJSONFloat(nil, f); string(got) != string(want) {
+			t.Errorf("AppendJSONFloat(%v) = %s, want %s", f, got, want)
+		}
+	}
+	if got := AppendJSONFloat(nil, math.NaN()); string(got) != `"NaN"` {
+		t.Errorf("NaN = %s", got)
+	}
+	if got := AppendJSONFloat(nil, math.Inf(-1)); string(got) != `"-Inf"` {
+		t.Errorf("-Inf = %s", got)
+	}
+}
+
+func TestAppendJSONValue(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	cases := []struct {
+		v    Value
+		want string
+	}{
+		{String("a\"b"), `"a\"b"`},
+		{Int64(-42), `-42`},
+		{Uint64(math.MaxUint64), `18446744073709551615`},
+		{Float64(0.25), `0.25`},
+		{Bool(true), `true`},
+		{Bool(false), `false`},
+		{Time(ts), `"2024-05-01T12:00:00.0000005Z"`},
+		{Duration(1500 * time.Millisecond), `"1.5s"`},
+		{RawJSON([]byte(`{"a":1}`)), `{"a":1}`},
+		{Any([]string{"x", "y"}), `["x","y"]`},
+		{Any(nil), `null`},
+		{Any(math.Inf(1)), `"+Inf"`},
+		{Of(errors.New("boom")), `"boom"`},
+		{Of(int8(7)), `7`},
+		{Of(time.Second), `"1s"`},
+	}
+	for i, c := range cases {
+		if got := string(AppendJSONValue(nil, c.v)); got != c.want {
+			t.Errorf("case %d: got %s, want %s", i, got, c.want)
+		}
+	}
+}
+
+func TestPrometheusEscaping(t *testing.T) {
+	if got := EscapeLabelValue("plain"); got != "plain" {
+		t.Errorf("EscapeLabelValue(plain) = %q", got)
+	}
+	if got := EscapeLabelValue("a\\b\"c\nd"); got != `a\\b\"c\nd` {
+		t.Errorf("EscapeLabelValue = %q", got)
+	}
+	if got := EscapeHelp("a\\b\"c\nd"); got != `a\\b"c\nd` {
+		t.Errorf("EscapeHelp = %q", got)
+	}
+	if got := string(AppendLabelValue([]byte("x="), `"q"`)); got != `x=\"q\"` {
+		t.Errorf("AppendLabelValue = %q", got)
+	}
+}
+
+func TestEncodingDoesNotAllocate(t *testing.T) {
+	buf := make([]byte, 0, 512)
+	allocs := testing.AllocsPerRun(100, func() {
+		b := AppendJSONValue(buf[:0], String("hello <world>"))
+		b = AppendJSONValue(b, Int64(12345))
+		b = AppendJSONValue(b, Float64(1.5))
+		b = AppendJSONValue(b, Bool(true))
+		_ = AppendLabelValue(b, `a"b`)
+		_ = EscapeLabelValue("plain")
+	})
+	if allocs != 0 {
+		t.Errorf("allocs = %v, want 0", allocs)
+	}
+}
+
+func TestBufferPool(t *testing.T) {
+	buf := GetBuffer()
+	buf.WriteString("data")
+	PutBuffer(buf)
+	if got := GetBuffer(); got.Len() != 0 {
+		t.Errorf("pooled buffer not reset: %q", got.String())
+	}
+}
@@ -0,0 +1,128 @@
+package enc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const hex = "0123456789abcdef"
+
+// AppendJSONString appends s as a JSON string, escaped like
+// encoding/json: HTML characters and U+2028/U+2029 are escaped and invalid
+// UTF-8 is replaced with U+FFFD.
+func AppendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// AppendJSONFloat appends f formatted like encoding/json. NaN and the
+// infinities, which JSON cannot represent, are written as the strings
+// "NaN", "+Inf" and "-Inf".
+func AppendJSONFloat(dst []byte, f float64) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(dst, `"NaN"`...)
+	case math.IsInf(f, 1):
+		return append(dst, `"+Inf"`...)
+	case math.IsInf(f, -1):
+		return append(dst, `"-Inf"`...)
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9, as encoding/json does.
+		if n := len(dst); n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst
+}
+
+// AppendJSONValue appends v as JSON. Times are RFC 3339 strings and
+// durations their String form. KindAny values are marshaled with
+// encoding/json, falling back to their fmt.Sprint string if that fails.
+func AppendJSONValue(dst []byte, v Value) []byte {
+	switch v.kind {
+	case KindString:
+		return AppendJSONString(dst, v.str)
+	case KindInt64:
+		return strconv.AppendInt(dst, v.Int64(), 10)
+	case KindUint64:
+		return strconv.AppendUint(dst, v.num, 10)
+	case KindFloat64:
+		return AppendJSONFloat(dst, v.Float64())
+	case KindBool:
+		return strconv.AppendBool(dst, v.Bool())
+	case KindTime:
+		dst = append(dst, '"')
+		dst = v.Time().AppendFormat(dst, time.RFC3339Nano)
+		return append(dst, '"')
+	case KindDuration:
+		return AppendJSONString(dst, v.Duration().String())
+	case KindRawJSON:
+		return append(dst, v.any.([]byte)...)
+	}
+	if v.any == nil {
+		return append(dst, "null"...)
+	}
+	data, err := json.Marshal(v.any)
+	if err != nil {
+		return AppendJSONString(dst, fmt.Sprint(v.any))
+	}
+	return append(dst, data...)
+}
+
+// AppendJSONKey appends a JSON object key and colon.
+func AppendJSONKey(dst []byte, key string) []byte {
+	return append(AppendJSONString(dst, key), ':')
+}
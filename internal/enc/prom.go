@@ -0,0 +1,48 @@
+package enc
+
+import "strings"
+
+// AppendLabelValue appends s escaped for a Prometheus text-format label
+// value: backslash, double quote and newline are escaped.
+func AppendLabelValue(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			dst = append(dst, '\\', c)
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// EscapeLabelValue returns s escaped like AppendLabelValue. It returns s
+// itself, without allocating, when nothing needs escaping.
+func EscapeLabelValue(s string) string {
+	if !strings.ContainsAny(s, "\\\"\n") {
+		return s
+	}
+	return string(AppendLabelValue(make([]byte, 0, len(s)+8), s))
+}
+
+// EscapeHelp escapes s for a Prometheus text-format HELP line, where only
+// backslash and newline are escaped.
+func EscapeHelp(s string) string {
+	if !strings.ContainsAny(s, "\\\n") {
+		return s
+	}
+	b := make([]byte, 0, len(s)+8)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			b = append(b, '\\', '\\')
+		case '\n':
+			b = append(b, '\\', 'n')
+		default:
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
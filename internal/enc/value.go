@@ -0,0 +1,166 @@
+// Package enc is the encoding core shared by logs, trace and metrics: a
+// tagged-union Value for field and attribute values, append-style JSON and
+// Prometheus text encoders that do not allocate for the common kinds, and
+// a pool of buffers to encode into.
+package enc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Kind identifies the type held by a Value.
+type Kind uint8
+
+const (
+	// KindAny holds an arbitrary value, encoded with encoding/json.
+	KindAny Kind = iota
+	KindString
+	KindInt64
+	KindUint64
+	KindFloat64
+	KindBool
+	KindTime
+	KindDuration
+	// KindRawJSON holds bytes that are already valid JSON.
+	KindRawJSON
+)
+
+// Value is a tagged union of the values fields and attributes carry. The
+// scalar kinds are stored inline, so building a Value does not allocate.
+type Value struct {
+	kind Kind
+	num  uint64
+	str  string
+	any  any
+}
+
+// String returns a string Value.
+func String(s string) Value { return Value{kind: KindString, str: s} }
+
+// Int64 returns an integer Value.
+func Int64(n int64) Value { return Value{kind: KindInt64, num: uint64(n)} }
+
+// Uint64 returns an unsigned integer Value.
+func Uint64(n uint64) Value { return Value{kind: KindUint64, num: n} }
+
+// Float64 returns a floating-point Value.
+func Float64(f float64) Value { return Value{kind: KindFloat64, num: math.Float64bits(f)} }
+
+// Bool returns a boolean Value.
+func Bool(b bool) Value {
+	v := Value{kind: KindBool}
+	if b {
+		v.num = 1
+	}
+	return v
+}
+
+// Time returns a time Value, encoded in RFC 3339 format.
+func Time(t time.Time) Value { return Value{kind: KindTime, any: t} }
+
+// Duration returns a duration Value, encoded like time.Duration.String.
+func Duration(d time.Duration) Value { return Value{kind: KindDuration, num: uint64(d)} }
+
+// RawJSON returns a Value written verbatim. The caller guarantees b is
+// valid JSON.
+func RawJSON(b []byte) Value { return Value{kind: KindRawJSON, any: b} }
+
+// Any returns a KindAny Value for v, encoded with encoding/json.
+func Any(v any) Value { return Value{kind: KindAny, any: v} }
+
+// Of returns a Value for v, using the most specific kind for known types.
+// Errors and fmt.Stringers become their string form.
+func Of(v any) Value {
+	switch x := v.(type) {
+	case string:
+		return String(x)
+	case bool:
+		return Bool(x)
+	case int:
+		return Int64(int64(x))
+	case int8:
+		return Int64(int64(x))
+	case int16:
+		return Int64(int64(x))
+	case int32:
+		return Int64(int64(x))
+	case int64:
+		return Int64(x)
+	case uint:
+		return Uint64(uint64(x))
+	case uint8:
+		return Uint64(uint64(x))
+	case uint16:
+		return Uint64(uint64(x))
+	case uint32:
+		return Uint64(uint64(x))
+	case uint64:
+		return Uint64(x)
+	case float32:
+		return Float64(float64(x))
+	case float64:
+		return Float64(x)
+	case time.Time:
+		return Time(x)
+	case time.Duration:
+		return Duration(x)
+	case json.RawMessage:
+		return RawJSON(x)
+	case error:
+		return String(x.Error())
+	case fmt.Stringer:
+		return String(x.String())
+	default:
+		return Any(v)
+	}
+}
+
+// Kind returns the kind of v.
+func (v Value) Kind() Kind { return v.kind }
+
+// Str returns the string of a KindString Value.
+func (v Value) Str() string { return v.str }
+
+// Int64 returns the integer of a KindInt64 Value.
+func (v Value) Int64() int64 { return int64(v.num) }
+
+// Uint64 returns the integer of a KindUint64 Value.
+func (v Value) Uint64() uint64 { return v.num }
+
+// Float64 returns the number of a KindFloat64 Value.
+func (v Value) Float64() float64 { return math.Float64frombits(v.num) }
+
+// Bool returns the boolean of a KindBool Value.
+func (v Value) Bool() bool { return v.num == 1 }
+
+// Duration returns the duration of a KindDuration Value.
+func (v Value) Duration() time.Duration { return time.Duration(v.num) }
+
+// Time returns the time of a KindTime Value.
+func (v Value) Time() time.Time {
+	t, _ := v.any.(time.Time)
+	return t
+}
+
+// Interface returns the value held by v as a Go value.
+func (v Value) Interface() any {
+	switch v.kind {
+	case KindString:
+		return v.str
+	case KindInt64:
+		return v.Int64()
+	case KindUint64:
+		return v.num
+	case KindFloat64:
+		return v.Float64()
+	case KindBool:
+		return v.Bool()
+	case KindDuration:
+		return v.Duration()
+	default:
+		return v.any
+	}
+}
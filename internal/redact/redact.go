@@ -0,0 +1,51 @@
+// Package redact matches keys against the redaction patterns shared by
+// logs.Redactor and trace.RedactProcessor, so one PII policy reads the
+// same for log fields and span attributes.
+package redact
+
+import (
+	"path"
+	"strings"
+)
+
+// CredentialKeys returns key patterns covering common credentials.
+func CredentialKeys() []string {
+	return []string{
+		"*password*",
+		"*passwd*",
+		"*secret*",
+		"*token*",
+		"*authorization*",
+		"*cookie*",
+		"*api_key*",
+		"*apikey*",
+		"*private_key*",
+	}
+}
+
+// Patterns is a list of case-insensitive glob patterns with the syntax of
+// path.Match, e.g. "password", "*token*" or "http.request.header.*".
+type Patterns []string
+
+// Compile returns patterns ready for Index.
+func Compile(patterns []string) Patterns {
+	p := make(Patterns, len(patterns))
+	for i, pattern := range patterns {
+		p[i] = strings.ToLower(pattern)
+	}
+	return p
+}
+
+// Index returns the index of the first pattern matching key, or -1.
+func (p Patterns) Index(key string) int {
+	if len(p) == 0 || key == "" {
+		return -1
+	}
+	key = strings.ToLower(key)
+	for i, pattern := range p {
+		if ok, _ := path.Match(pattern, key); ok {
+			return i
+		}
+	}
+	return -1
+}
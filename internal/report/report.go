@@ -0,0 +1,72 @@
+// Package report carries errors from inside the telemetry pipeline, such
+// as failed log writes and span exports, to the handlers registered with
+// lumen.OnInternalError.
+package report
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Components passed to handlers.
+const (
+	Logs          = "logs"
+	LogHook       = "logs.hook"
+	TraceExport   = "trace.export"
+	MetricsPush   = "metrics.push"
+	ProfileUpload = "profile"
+	ConfigReload  = "config"
+)
+
+type handler struct {
+	fn func(component string, err error)
+}
+
+var (
+	mu       sync.Mutex
+	handlers atomic.Pointer[[]*handler]
+)
+
+// Subscribe registers fn and returns a function that removes it.
+func Subscribe(fn func(component string, err error)) (remove func()) {
+	h := &handler{fn: fn}
+	mu.Lock()
+	defer mu.Unlock()
+	var next []*handler
+	if cur := handlers.Load(); cur != nil {
+		next = append(next, *cur...)
+	}
+	next = append(next, h)
+	handlers.Store(&next)
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		cur := handlers.Load()
+		if cur == nil {
+			return
+		}
+		next := make([]*handler, 0, len(*cur))
+		for _, other := range *cur {
+			if other != h {
+				next = append(next, other)
+			}
+		}
+		handlers.Store(&next)
+	}
+}
+
+// Error passes err to every registered handler. It does nothing when err
+// is nil or no handler is registered.
+func Error(component string, err error) {
+	if err == nil {
+		return
+	}
+	hs := handlers.Load()
+	if hs == nil {
+		return
+	}
+	for _, h := range *hs {
+		h.fn(component, err)
+	}
+}
@@ -0,0 +1,93 @@
+// Package watch reloads configuration files when they change, for the
+// WatchConfig methods of lumen and logs.
+package watch
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+)
+
+// Options configures File.
+type Options struct {
+	// Interval is how often the file's modification time is checked.
+	Interval time.Duration
+
+	// SIGHUP also triggers a reload on SIGHUP.
+	SIGHUP bool
+
+	// Immediate applies the file once before File returns.
+	Immediate bool
+
+	// Reload reads and applies the file.
+	Reload func() error
+
+	// OnError is called with the errors of Reload, after they are
+	// reported to the internal error handlers.
+	OnError func(err error)
+}
+
+// File calls opts.Reload whenever the modification time of path changes,
+// and on SIGHUP if enabled, until the returned stop function is called.
+func File(path string, opts Options) (stop func()) {
+	var hup chan os.Signal
+	if opts.SIGHUP {
+		hup = make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+	}
+
+	reload := func() {
+		if err := opts.Reload(); err != nil {
+			report.Error(report.ConfigReload, err)
+			opts.OnError(err)
+		}
+	}
+
+	last := modTime(path)
+	if opts.Immediate {
+		reload()
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+				reload()
+			case <-ticker.C:
+				if m := modTime(path); !m.Equal(last) {
+					last = m
+					reload()
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if hup != nil {
+				signal.Stop(hup)
+			}
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+func modTime(path string) time.Time {
+	if fi, err := os.Stat(path); err == nil {
+		return fi.ModTime()
+	}
+	return time.Time{}
+}
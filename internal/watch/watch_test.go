@@ -0,0 +1,58 @@
+package watch_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/internal/watch"
+)
+
+func TestFileReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var reloads, failures atomic.Int32
+	fail := errors.New("bad config")
+	stop := File(path, Options{
+		Interval:  5 * time.Millisecond,
+		Immediate: true,
+		Reload: func() error {
+			if reloads.Add(1) == 2 {
+				return fail
+			}
+			return nil
+		},
+		OnError: func(err error) {
+			if errors.Is(err, fail) {
+				failures.Add(1)
+			}
+		},
+	})
+	if n := reloads.Load(); n != 1 {
+		t.Fatalf("Immediate: %d reloads before File returned, want 1", n)
+	}
+
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for reloads.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+	stop()
+
+	if n := reloads.Load(); n != 2 {
+		t.Errorf("%d reloads, want 2", n)
+	}
+	if n := failures.Load(); n != 1 {
+		t.Errorf("OnError called %d times, want 1", n)
+	}
+}
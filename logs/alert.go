@@ -0,0 +1,372 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kolosys/lumen/internal/report"
+)
+
+// AlertOptions configures an AlertHook.
+type AlertOptions struct {
+	// Levels are the levels posted. Defaults to error, fatal and panic;
+	// add WarnLevel to be alerted of warnings too.
+	Levels []Level
+
+	// Interval is the least time between posts. The first entry is posted
+	// at once; entries arriving within Interval of a post are coalesced
+	// into the next one, with repeats of a message counted rather than
+	// repeated. Default: 10s
+	Interval time.Duration
+
+	// MaxAlerts is the most distinct messages listed in one post; the
+	// rest are summed up in a single line. Default: 10, the most embeds
+	// Discord accepts in a message.
+	MaxAlerts int
+
+	// Username overrides the name the webhook posts as.
+	Username string
+
+	// Client sends requests. Defaults to an http.Client with a 10s
+	// timeout.
+	Client *http.Client
+
+	// OnError is called when a post fails.
+	OnError func(err error)
+}
+
+func (o *AlertOptions) applyDefaults() {
+	if len(o.Levels) == 0 {
+		o.Levels = []Level{ErrorLevel, FatalLevel, PanicLevel}
+	}
+	if o.Interval <= 0 {
+		o.Interval = 10 * time.Second
+	}
+	if o.MaxAlerts <= 0 || o.MaxAlerts > 10 {
+		o.MaxAlerts = 10
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+}
+
+// alert is an entry waiting to be posted, with the number of times its
+// message was logged since the last post.
+type alert struct {
+	level   Level
+	message string
+	logger  string
+	fields  [][2]string
+	time    time.Time
+	count   int
+}
+
+// alertFormat renders a post for a webhook service.
+type alertFormat func(opts *AlertOptions, alerts []*alert, more int) any
+
+// AlertHook posts entries to a Slack or Discord channel through an
+// incoming webhook. Posts are rate limited to one per Interval and the
+// entries in between are coalesced, so an error storm produces a post
+// listing each distinct message with its count rather than one post per
+// entry.
+//
+//	hook := logs.NewSlackHook(os.Getenv("SLACK_WEBHOOK_URL"), &logs.AlertOptions{
+//		Levels: []logs.Level{logs.WarnLevel, logs.ErrorLevel, logs.FatalLevel, logs.PanicLevel},
+//	})
+//	defer hook.Close()
+type AlertHook struct {
+	url    string
+	opts   AlertOptions
+	format alertFormat
+
+	mu      sync.Mutex
+	pending []*alert
+	index   map[string]*alert
+	more    int
+	closed  bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewSlackHook creates an AlertHook posting to a Slack incoming webhook.
+// Each message becomes an attachment colored by level, with the entry's
+// fields as attachment fields.
+func NewSlackHook(webhookURL string, opts *AlertOptions) *AlertHook {
+	return newAlertHook(webhookURL, opts, slackMessage)
+}
+
+// NewDiscordHook creates an AlertHook posting to a Discord webhook. Each
+// message becomes an embed colored by level, with the entry's fields as
+// embed fields.
+func NewDiscordHook(webhookURL string, opts *AlertOptions) *AlertHook {
+	return newAlertHook(webhookURL, opts, discordMessage)
+}
+
+func newAlertHook(url string, opts *AlertOptions, format alertFormat) *AlertHook {
+	h := &AlertHook{
+		url:    url,
+		format: format,
+		index:  make(map[string]*alert),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	h.opts.applyDefaults()
+	go h.run()
+	return h
+}
+
+// Fire implements Hook.
+func (h *AlertHook) Fire(entry *Entry) {
+	key := entry.Level.String() + "\x00" + entry.Message
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	if a, ok := h.index[key]; ok {
+		a.count++
+	} else if len(h.pending) >= h.opts.MaxAlerts {
+		h.more++
+	} else {
+		a := &alert{level: entry.Level, message: entry.Message, time: entry.Time, count: 1}
+		for _, f := range entry.Fields {
+			if f.Key == "_logger" {
+				a.logger = f.String
+				continue
+			}
+			a.fields = append(a.fields, [2]string{f.Key, f.StringValue()})
+		}
+		h.pending = append(h.pending, a)
+		h.index[key] = a
+	}
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Levels implements Hook.
+func (h *AlertHook) Levels() []Level {
+	return h.opts.Levels
+}
+
+// Close posts the entries waiting for the rate limit and stops the hook.
+func (h *AlertHook) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.mu.Unlock()
+
+	close(h.stop)
+	<-h.done
+	return nil
+}
+
+func (h *AlertHook) run() {
+	defer close(h.done)
+	var last time.Time
+	for {
+		select {
+		case <-h.wake:
+		case <-h.stop:
+			h.post()
+			return
+		}
+		if wait := time.Until(last.Add(h.opts.Interval)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-h.stop:
+				timer.Stop()
+				h.post()
+				return
+			}
+		}
+		h.post()
+		last = time.Now()
+	}
+}
+
+// post sends the pending alerts, if any.
+func (h *AlertHook) post() {
+	h.mu.Lock()
+	alerts, more := h.pending, h.more
+	h.pending, h.more = nil, 0
+	clear(h.index)
+	h.mu.Unlock()
+	if len(alerts) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(h.format(&h.opts, alerts, more))
+	if err == nil {
+		err = h.send(body)
+	}
+	if err != nil {
+		err = fmt.Errorf("logs: alert hook: %w", err)
+		report.Error(report.LogHook, err)
+		if h.opts.OnError != nil {
+			h.opts.OnError(err)
+		}
+	}
+}
+
+func (h *AlertHook) send(body []byte) error {
+	resp, err := h.opts.Client.Post(h.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// title returns the heading of an alert, with its count if repeated.
+func (a *alert) title() string {
+	title := fmt.Sprintf("[%s] %s", a.level, a.message)
+	if a.count > 1 {
+		title += fmt.Sprintf(" (×%d)", a.count)
+	}
+	return title
+}
+
+// alertSummary returns the plain text of a post, shown in notifications.
+func alertSummary(alerts []*alert, more int) string {
+	if len(alerts) == 1 && more == 0 {
+		return alerts[0].title()
+	}
+	n := more
+	for _, a := range alerts {
+		n += a.count
+	}
+	s := fmt.Sprintf("%d alerts", n)
+	if more > 0 {
+		s += fmt.Sprintf(", %d not listed", more)
+	}
+	return s
+}
+
+// alertColor returns the RGB color of a level.
+func alertColor(level Level) int {
+	switch level {
+	case PanicLevel, FatalLevel:
+		return 0x8b0000
+	case ErrorLevel:
+		return 0xe01e5a
+	case WarnLevel:
+		return 0xecb22e
+	default:
+		return 0x2eb67d
+	}
+}
+
+func slackMessage(opts *AlertOptions, alerts []*alert, more int) any {
+	type field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+	type attachment struct {
+		Fallback string  `json:"fallback"`
+		Color    string  `json:"color"`
+		Title    string  `json:"title"`
+		Fields   []field `json:"fields,omitempty"`
+		Footer   string  `json:"footer,omitempty"`
+		TS       int64   `json:"ts,omitempty"`
+	}
+	msg := struct {
+		Text        string       `json:"text"`
+		Username    string       `json:"username,omitempty"`
+		Attachments []attachment `json:"attachments"`
+	}{Text: alertSummary(alerts, more), Username: opts.Username}
+	for _, a := range alerts {
+		att := attachment{
+			Fallback: a.title(),
+			Color:    fmt.Sprintf("#%06x", alertColor(a.level)),
+			Title:    a.title(),
+			Footer:   a.logger,
+		}
+		if !a.time.IsZero() {
+			att.TS = a.time.Unix()
+		}
+		for _, f := range a.fields {
+			att.Fields = append(att.Fields, field{Title: f[0], Value: f[1], Short: len(f[1]) <= 40})
+		}
+		msg.Attachments = append(msg.Attachments, att)
+	}
+	return msg
+}
+
+func discordMessage(opts *AlertOptions, alerts []*alert, more int) any {
+	type field struct {
+		Name   string `json:"name"`
+		Value  string `json:"value"`
+		Inline bool   `json:"inline"`
+	}
+	type footer struct {
+		Text string `json:"text"`
+	}
+	type embed struct {
+		Title     string  `json:"title"`
+		Color     int     `json:"color"`
+		Fields    []field `json:"fields,omitempty"`
+		Footer    *footer `json:"footer,omitempty"`
+		Timestamp string  `json:"timestamp,omitempty"`
+	}
+	msg := struct {
+		Content  string  `json:"content"`
+		Username string  `json:"username,omitempty"`
+		Embeds   []embed `json:"embeds"`
+	}{Content: alertSummary(alerts, more), Username: opts.Username}
+	for _, a := range alerts {
+		e := embed{Title: truncate(a.title(), 256), Color: alertColor(a.level)}
+		if a.logger != "" {
+			e.Footer = &footer{Text: a.logger}
+		}
+		if !a.time.IsZero() {
+			e.Timestamp = a.time.Format(time.RFC3339)
+		}
+		// Discord rejects embeds with more than 25 fields or empty values.
+		for _, f := range a.fields {
+			if len(e.Fields) == 25 {
+				break
+			}
+			value := f[1]
+			if value == "" {
+				value = "\u200b"
+			}
+			e.Fields = append(e.Fields, field{Name: truncate(f[0], 256), Value: truncate(value, 1024), Inline: len(value) <= 40})
+		}
+		msg.Embeds = append(msg.Embeds, e)
+	}
+	return msg
+}
+
+// truncate shortens s to at most n bytes, ending it with an ellipsis.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	n -= len("…")
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
@@ -0,0 +1,82 @@
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kolosys/lumen/internal/enc"
+)
+
+// appendArray appends the slice held by a FieldTypeArray field: as a JSON
+// array if json is set, and otherwise as a compact, bracketed list of
+// unquoted values for text output, e.g. [1,2,3].
+func appendArray(dst []byte, v any, json bool) []byte {
+	switch s := v.(type) {
+	case []string:
+		return appendElems(dst, s, func(dst []byte, e string) []byte {
+			if json {
+				return enc.AppendJSONString(dst, e)
+			}
+			return append(dst, e...)
+		})
+	case []int:
+		return appendElems(dst, s, func(dst []byte, e int) []byte {
+			return strconv.AppendInt(dst, int64(e), 10)
+		})
+	case []int64:
+		return appendElems(dst, s, func(dst []byte, e int64) []byte {
+			return strconv.AppendInt(dst, e, 10)
+		})
+	case []float64:
+		return appendElems(dst, s, func(dst []byte, e float64) []byte {
+			if json {
+				return enc.AppendJSONFloat(dst, e)
+			}
+			return strconv.AppendFloat(dst, e, 'g', -1, 64)
+		})
+	case []bool:
+		return appendElems(dst, s, strconv.AppendBool)
+	case []time.Duration:
+		return appendElems(dst, s, func(dst []byte, e time.Duration) []byte {
+			if json {
+				return enc.AppendJSONValue(dst, enc.Duration(e))
+			}
+			return append(dst, e.String()...)
+		})
+	case []time.Time:
+		return appendElems(dst, s, func(dst []byte, e time.Time) []byte {
+			if json {
+				return enc.AppendJSONValue(dst, enc.Time(e))
+			}
+			return e.AppendFormat(dst, time.RFC3339Nano)
+		})
+	case []error:
+		return appendElems(dst, s, func(dst []byte, e error) []byte {
+			switch {
+			case e == nil && json:
+				return append(dst, "null"...)
+			case e == nil:
+				return append(dst, "<nil>"...)
+			case json:
+				return enc.AppendJSONString(dst, e.Error())
+			}
+			return append(dst, e.Error()...)
+		})
+	}
+	if json {
+		return enc.AppendJSONValue(dst, enc.Any(v))
+	}
+	return fmt.Append(dst, v)
+}
+
+func appendElems[T any](dst []byte, s []T, appendElem func([]byte, T) []byte) []byte {
+	dst = append(dst, '[')
+	for i, e := range s {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendElem(dst, e)
+	}
+	return append(dst, ']')
+}
@@ -130,6 +130,7 @@ func (b *Builder) Error(msg string) {
 // Fatal logs at fatal level and exits.
 func (b *Builder) Fatal(msg string) {
 	b.emit(FatalLevel, msg)
+	b.logger.exit()
 }
 
 // Panic logs at panic level and panics.
@@ -147,7 +148,7 @@ func (b *Builder) emit(level Level, msg string) {
 	if b.ctx != nil {
 		b.logger.logContext(b.ctx, level, msg, b.fields)
 	} else {
-		b.logger.log(level, msg, b.fields)
+		b.logger.log(context.Background(), level, msg, b.fields)
 	}
 }
 
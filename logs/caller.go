@@ -0,0 +1,95 @@
+package logs
+
+import (
+	"runtime"
+	"strings"
+)
+
+// FunctionKey is the field key set by Options.AddFunction.
+const FunctionKey = "func"
+
+// CallerFormat selects how much of the call site's path is recorded.
+type CallerFormat uint8
+
+const (
+	// CallerShort records the file name and line, e.g. "handler.go:42",
+	// and the function without its package path, e.g.
+	// "orders.(*Server).Create".
+	CallerShort CallerFormat = iota
+
+	// CallerPackage adds the file's directory, e.g.
+	// "orders/handler.go:42".
+	CallerPackage
+
+	// CallerFull records the full file path and the function with its
+	// full package path.
+	CallerFull
+)
+
+// pkgPrefix is the prefix of the names of this package's functions, which
+// are skipped to find the call site.
+var pkgPrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	slash := strings.LastIndexByte(name, '/')
+	return name[:slash+strings.IndexByte(name[slash:], '.')+1]
+}()
+
+// WithCallerSkip returns a logger recording the call site n frames further
+// up the stack, for libraries wrapping the logger in their own helpers:
+//
+//	func (c *Client) logf(format string, args ...any) {
+//		c.log.Infof(format, args...) // c.log = log.WithCallerSkip(1)
+//	}
+func (l *Logger) WithCallerSkip(n int) *Logger {
+	l = l.current()
+	child := l.clone()
+	child.callerSkip = max(child.callerSkip+n, 0)
+	return child
+}
+
+// caller returns the call site and calling function, formatted as set by
+// the logger's options: the first frame outside this package, skipping
+// callerSkip more.
+func (l *Logger) caller() (file, function string) {
+	var pcs [32]uintptr
+	n := runtime.Callers(3, pcs[:]) // skip Callers, caller and emit
+	frames := runtime.CallersFrames(pcs[:n])
+	skip := l.callerSkip
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, pkgPrefix) {
+			if skip == 0 {
+				return l.formatCaller(frame)
+			}
+			skip--
+		}
+		if !more {
+			return "unknown", ""
+		}
+	}
+}
+
+func (l *Logger) formatCaller(frame runtime.Frame) (file, function string) {
+	file, function = frame.File, frame.Function
+	if l.callerFmt != CallerFull {
+		file = trimPath(file, l.callerFmt == CallerPackage)
+		if slash := strings.LastIndexByte(function, '/'); slash >= 0 {
+			function = function[slash+1:]
+		}
+	}
+	buf := make([]byte, 0, len(file)+12)
+	buf = append(buf, file...)
+	buf = append(buf, ':')
+	buf = appendInt(buf, frame.Line)
+	return string(buf), function
+}
+
+// trimPath returns the file name of path, with its directory if dir is set.
+func trimPath(path string, dir bool) string {
+	i := strings.LastIndexByte(path, '/')
+	if dir && i > 0 {
+		i = strings.LastIndexByte(path[:i], '/')
+	}
+	return path[i+1:]
+}
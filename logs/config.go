@@ -0,0 +1,297 @@
+package logs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownFormat is returned for a Config.Format that is not one of
+	// the built-in formatters.
+	ErrUnknownFormat = errors.New("logs: unknown format")
+
+	// ErrUnknownHook is returned for a Config.Hooks name that was not
+	// registered with RegisterHook.
+	ErrUnknownHook = errors.New("logs: unknown hook")
+
+	// ErrInvalidLevel is returned for a Config.Level that is not a level
+	// name.
+	ErrInvalidLevel = errors.New("logs: invalid level")
+)
+
+// Config is a declarative logger configuration, for services that
+// configure logging from a file or the environment rather than code. It
+// can be decoded from JSON, or from YAML with a decoder of choice.
+//
+//	{"level": "debug", "format": "json", "output": "/var/log/app.log",
+//	 "sampling": {"rate": 100, "window": 1000000000}, "hooks": ["sentry"]}
+type Config struct {
+	// Level is the minimum level: trace, debug, info, warn, error, fatal
+	// or panic. Defaults to info.
+	Level string `json:"level" yaml:"level"`
+
+	// Format is text, json, pretty or datadog. Defaults to text.
+	Format string `json:"format" yaml:"format"`
+
+	// Output is stdout, stderr, split (warnings and above to stderr, the
+	// rest to stdout), discard, or the path of a file to append to.
+	// Defaults to stdout.
+	Output string `json:"output" yaml:"output"`
+
+	// Name names the logger, as Logger.Named.
+	Name string `json:"name" yaml:"name"`
+
+	// Fields are added to every entry.
+	Fields map[string]string `json:"fields" yaml:"fields"`
+
+	// AddCaller records the calling file and line.
+	AddCaller bool `json:"add_caller" yaml:"add_caller"`
+
+	// AddStack records stack traces for error and above.
+	AddStack bool `json:"add_stack" yaml:"add_stack"`
+
+	// Sampling, if set, limits how often each message is logged.
+	Sampling *SamplingConfig `json:"sampling" yaml:"sampling"`
+
+	// AsyncBufferSize enables asynchronous logging; see
+	// Options.AsyncBufferSize.
+	AsyncBufferSize int `json:"async_buffer_size" yaml:"async_buffer_size"`
+
+	// Hooks are the names of hooks registered with RegisterHook to add to
+	// the logger.
+	Hooks []string `json:"hooks" yaml:"hooks"`
+}
+
+// SamplingConfig configures a RateSampler.
+type SamplingConfig struct {
+	// Rate is the number of entries per message logged in each Window.
+	Rate int `json:"rate" yaml:"rate"`
+
+	// Window is the sampling window. Defaults to 1s.
+	Window time.Duration `json:"window" yaml:"window"`
+
+	// Burst is the initial allowance. Defaults to Rate.
+	Burst int `json:"burst" yaml:"burst"`
+}
+
+var hookRegistry struct {
+	mu    sync.RWMutex
+	hooks map[string]Hook
+}
+
+// RegisterHook makes hook available to Config.Hooks under name. A hook
+// registered under a name already in use replaces the previous one.
+func RegisterHook(name string, hook Hook) {
+	hookRegistry.mu.Lock()
+	defer hookRegistry.mu.Unlock()
+	if hookRegistry.hooks == nil {
+		hookRegistry.hooks = make(map[string]Hook)
+	}
+	hookRegistry.hooks[name] = hook
+}
+
+// LoadConfig reads a JSON Config from the file at path.
+func LoadConfig(path string) (Config, error) {
+	var c Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("logs: %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ConfigFromEnv returns a Config read from the environment; see ApplyEnv.
+func ConfigFromEnv() Config {
+	var c Config
+	c.ApplyEnv()
+	return c
+}
+
+// ApplyEnv overrides fields with the environment variables that are set:
+//
+//	LUMEN_LOG_LEVEL         Level
+//	LUMEN_LOG_FORMAT        Format
+//	LUMEN_LOG_OUTPUT        Output
+//	LUMEN_LOG_NAME          Name
+//	LUMEN_LOG_CALLER        AddCaller
+//	LUMEN_LOG_STACK         AddStack
+//	LUMEN_LOG_SAMPLE_RATE   Sampling.Rate, per second
+//	LUMEN_LOG_ASYNC         AsyncBufferSize
+//	LUMEN_LOG_HOOKS         Hooks, comma-separated
+//
+// Malformed booleans and numbers are ignored.
+func (c *Config) ApplyEnv() {
+	setEnvString(&c.Level, "LUMEN_LOG_LEVEL")
+	setEnvString(&c.Format, "LUMEN_LOG_FORMAT")
+	setEnvString(&c.Output, "LUMEN_LOG_OUTPUT")
+	setEnvString(&c.Name, "LUMEN_LOG_NAME")
+
+	var s string
+	if setEnvString(&s, "LUMEN_LOG_CALLER") {
+		if b, err := strconv.ParseBool(s); err == nil {
+			c.AddCaller = b
+		}
+	}
+	if setEnvString(&s, "LUMEN_LOG_STACK") {
+		if b, err := strconv.ParseBool(s); err == nil {
+			c.AddStack = b
+		}
+	}
+	if setEnvString(&s, "LUMEN_LOG_SAMPLE_RATE") {
+		if n, err := strconv.Atoi(s); err == nil {
+			c.Sampling = &SamplingConfig{Rate: n, Window: time.Second}
+		}
+	}
+	if setEnvString(&s, "LUMEN_LOG_ASYNC") {
+		if n, err := strconv.Atoi(s); err == nil {
+			c.AsyncBufferSize = n
+		}
+	}
+	if setEnvString(&s, "LUMEN_LOG_HOOKS") {
+		c.Hooks = c.Hooks[:0]
+		for _, name := range strings.Split(s, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				c.Hooks = append(c.Hooks, name)
+			}
+		}
+	}
+}
+
+// setEnvString stores the variable key in dst if it is set.
+func setEnvString(dst *string, key string) bool {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		*dst = v
+		return true
+	}
+	return false
+}
+
+// NewFromEnv creates a Logger configured by the environment. If
+// LUMEN_LOG_CONFIG names a JSON file, it is loaded first and the other
+// variables override it; see Config.ApplyEnv.
+func NewFromEnv() (*Logger, error) {
+	var c Config
+	if path := strings.TrimSpace(os.Getenv("LUMEN_LOG_CONFIG")); path != "" {
+		var err error
+		if c, err = LoadConfig(path); err != nil {
+			return nil, err
+		}
+	}
+	c.ApplyEnv()
+	return NewFromConfig(c)
+}
+
+// NewFromConfig creates a Logger from cfg. A file named by cfg.Output is
+// opened for appending and stays open for the life of the process.
+func NewFromConfig(cfg Config) (*Logger, error) {
+	opts := &Options{
+		AddCaller:       cfg.AddCaller,
+		AddStack:        cfg.AddStack,
+		AsyncBufferSize: cfg.AsyncBufferSize,
+	}
+
+	level, err := parseConfigLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Formatter, err = newFormatter(cfg.Format); err != nil {
+		return nil, err
+	}
+
+	if s := cfg.Sampling; s != nil && s.Rate > 0 {
+		window := s.Window
+		if window <= 0 {
+			window = time.Second
+		}
+		sampler := NewRateSampler(s.Rate, window)
+		if s.Burst > 0 {
+			sampler.WithBurst(s.Burst)
+		}
+		opts.Sampler = sampler
+	}
+
+	hookRegistry.mu.RLock()
+	for _, name := range cfg.Hooks {
+		hook, ok := hookRegistry.hooks[name]
+		if !ok {
+			hookRegistry.mu.RUnlock()
+			return nil, fmt.Errorf("%w: %q", ErrUnknownHook, name)
+		}
+		opts.Hooks = append(opts.Hooks, hook)
+	}
+	hookRegistry.mu.RUnlock()
+
+	for _, k := range slices.Sorted(maps.Keys(cfg.Fields)) {
+		opts.Fields = append(opts.Fields, String(k, cfg.Fields[k]))
+	}
+
+	// Opened last, so that a file is not left open by a config error.
+	if opts.Output, err = openOutput(cfg.Output); err != nil {
+		return nil, err
+	}
+
+	logger := New(opts)
+	logger.family.outputName = cfg.Output
+	// Set after New, which cannot tell PanicLevel from an unset level.
+	logger.SetLevel(level)
+	if cfg.Name != "" {
+		logger = logger.Named(cfg.Name)
+	}
+	return logger, nil
+}
+
+// parseConfigLevel parses a level name, rejecting unknown ones rather
+// than falling back to info as ParseLevel does.
+func parseConfigLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return InfoLevel, nil
+	case "panic", "fatal", "error", "warn", "warning", "info", "debug", "trace":
+		return ParseLevel(s), nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalidLevel, s)
+}
+
+// openOutput returns the writer named by output.
+func openOutput(output string) (io.Writer, error) {
+	switch strings.ToLower(output) {
+	case "", "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	case "split":
+		return NewLevelSplitWriter(), nil
+	case "discard":
+		return io.Discard, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// closeOutput closes w if openOutput opened it as a file for output.
+func closeOutput(w io.Writer, output string) error {
+	switch strings.ToLower(output) {
+	case "", "stdout", "stderr", "split", "discard":
+		return nil
+	}
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
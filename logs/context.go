@@ -2,6 +2,8 @@ package logs
 
 import (
 	"context"
+
+	"github.com/kolosys/lumen/trace"
 )
 
 // contextKey is the type for context keys.
@@ -10,6 +12,8 @@ type contextKey int
 const (
 	fieldsKey contextKey = iota
 	loggerKey
+	samplingKey
+	goroutineLabelKey
 )
 
 // WithFields adds fields to the context that will be included in all logs.
@@ -49,6 +53,23 @@ func LoggerFromContext(ctx context.Context) *Logger {
 	return defaultLogger
 }
 
+// WithSamplingDecision records in the context whether the request it
+// belongs to is sampled, e.g. by its trace. DecisionSampler keeps or drops
+// verbose entries logged with the context accordingly.
+func WithSamplingDecision(ctx context.Context, sampled bool) context.Context {
+	return context.WithValue(ctx, samplingKey, sampled)
+}
+
+// SamplingDecision returns the decision recorded by WithSamplingDecision,
+// or false for ok if there is none.
+func SamplingDecision(ctx context.Context) (sampled, ok bool) {
+	if ctx == nil {
+		return false, false
+	}
+	sampled, ok = ctx.Value(samplingKey).(bool)
+	return sampled, ok
+}
+
 // CtxTrace logs at trace level using the logger from context.
 func CtxTrace(ctx context.Context, msg string, fields ...Field) {
 	LoggerFromContext(ctx).TraceContext(ctx, msg, fields...)
@@ -90,6 +111,9 @@ func WithTraceID(ctx context.Context, traceID string) context.Context {
 	return WithContextFields(ctx, String(TraceIDKey, traceID))
 }
 
+// SpanID is a common field key for span IDs.
+const SpanIDKey = "span_id"
+
 // UserID is a common field key for user IDs.
 const UserIDKey = "user_id"
 
@@ -97,3 +121,21 @@ const UserIDKey = "user_id"
 func WithUserID(ctx context.Context, userID string) context.Context {
 	return WithContextFields(ctx, String(UserIDKey, userID))
 }
+
+// appendSpanFields appends the trace and span IDs of the span in ctx to
+// fields, unless fields already has a trace ID, e.g. from WithTraceID.
+func appendSpanFields(ctx context.Context, fields []Field) []Field {
+	span := trace.SpanFromContext(ctx)
+	if span == nil || !span.TraceID().IsValid() {
+		return fields
+	}
+	for _, f := range fields {
+		if f.Key == TraceIDKey {
+			return fields
+		}
+	}
+	return append(fields[:len(fields):len(fields)],
+		String(TraceIDKey, span.TraceID().String()),
+		String(SpanIDKey, span.SpanID().String()),
+	)
+}
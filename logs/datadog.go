@@ -0,0 +1,132 @@
+package logs
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kolosys/lumen/internal/enc"
+)
+
+// DatadogFormatter formats entries as JSON with the attribute names
+// Datadog's log pipeline recognizes without remapping: status,
+// logger.name, error.message, error.kind and error.stack. The trace_id
+// and span_id fields added by trace correlation become dd.trace_id and
+// dd.span_id, converted to the unsigned decimal form Datadog uses to join
+// logs to traces; a 128-bit trace ID contributes its lower 64 bits.
+type DatadogFormatter struct {
+	// Service, Env and Version, if set, are added as dd.service, dd.env
+	// and dd.version, which Datadog uses together with the trace ID to
+	// link an entry to its trace.
+	Service string
+	Env     string
+	Version string
+
+	// DisableTimestamp disables timestamp output, leaving Datadog to use
+	// the intake time.
+	DisableTimestamp bool
+}
+
+// datadogStatus maps levels to Datadog statuses.
+func datadogStatus(level Level) string {
+	switch level {
+	case PanicLevel:
+		return "emergency"
+	case FatalLevel:
+		return "critical"
+	case TraceLevel:
+		return "debug"
+	default:
+		return level.String()
+	}
+}
+
+// Format formats an entry as Datadog JSON.
+func (f *DatadogFormatter) Format(entry *Entry) ([]byte, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.WriteByte('{')
+	buf.Write(enc.AppendJSONKey(buf.AvailableBuffer(), "status"))
+	writeDatadogString(buf, datadogStatus(entry.Level))
+	if !f.DisableTimestamp {
+		writeDatadogKey(buf, "timestamp")
+		buf.WriteByte('"')
+		buf.Write(entry.Time.AppendFormat(buf.AvailableBuffer(), time.RFC3339Nano))
+		buf.WriteByte('"')
+	}
+	writeDatadogKey(buf, "message")
+	writeDatadogString(buf, entry.Message)
+
+	for _, attr := range [...]struct{ key, value string }{
+		{"dd.service", f.Service},
+		{"dd.env", f.Env},
+		{"dd.version", f.Version},
+		{"logger.method_name", entry.Caller},
+		{"error.stack", entry.Stack},
+	} {
+		if attr.value != "" {
+			writeDatadogKey(buf, attr.key)
+			writeDatadogString(buf, attr.value)
+		}
+	}
+
+	// Datadog reads dotted keys as nested attributes.
+	for _, field := range flattenFields(entry.Fields) {
+		switch {
+		case field.Key == "_logger":
+			writeDatadogKey(buf, "logger.name")
+			writeDatadogString(buf, field.String)
+			continue
+		case field.Key == TraceIDKey || field.Key == SpanIDKey:
+			if id, ok := datadogID(field); ok {
+				writeDatadogKey(buf, "dd."+field.Key)
+				writeDatadogString(buf, id)
+				continue
+			}
+		case field.Key == "error" && field.Type == FieldTypeError:
+			writeDatadogKey(buf, "error.message")
+			writeDatadogString(buf, field.String)
+			writeDatadogKey(buf, "error.kind")
+			writeDatadogString(buf, fmt.Sprintf("%T", field.Interface))
+			continue
+		}
+		writeDatadogKey(buf, field.Key)
+		buf.Write(field.appendJSON(buf.AvailableBuffer()))
+	}
+
+	buf.WriteString("}\n")
+	result := make([]byte, buf.Len())
+	copy(result, buf.Bytes())
+	return result, nil
+}
+
+// datadogID converts a hex trace or span ID field to decimal, using the
+// lower 64 bits of 128-bit trace IDs.
+func datadogID(field Field) (string, bool) {
+	if field.Type != FieldTypeString {
+		return "", false
+	}
+	hex := field.String
+	if len(hex) == 32 {
+		hex = hex[16:]
+	}
+	if len(hex) != 16 {
+		return "", false
+	}
+	id, err := strconv.ParseUint(hex, 16, 64)
+	if err != nil {
+		return "", false
+	}
+	return strconv.FormatUint(id, 10), true
+}
+
+func writeDatadogKey(buf *bytes.Buffer, key string) {
+	buf.WriteByte(',')
+	buf.Write(enc.AppendJSONKey(buf.AvailableBuffer(), key))
+}
+
+func writeDatadogString(buf *bytes.Buffer, s string) {
+	buf.Write(enc.AppendJSONString(buf.AvailableBuffer(), s))
+}
@@ -12,6 +12,10 @@ type Entry struct {
 	Fields  []Field
 	Caller  string
 	Stack   string
+
+	// Frames holds the stack trace as frames, if it was captured by the
+	// logger; Stack holds the same trace as text.
+	Frames []StackFrame
 }
 
 // HasField returns true if the entry has a field with the given key.
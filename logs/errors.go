@@ -1,9 +1,12 @@
 package logs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"runtime"
+	"slices"
+	"strings"
 )
 
 // ErrorBuilder provides a fluent API for logging errors.
@@ -58,7 +61,7 @@ func (b *ErrorBuilder) Trace(msg string) {
 		return
 	}
 	b.fields = append(b.fields, Err(b.err))
-	b.logger.log(TraceLevel, msg, b.fields)
+	b.logger.log(context.Background(), TraceLevel, msg, b.fields)
 }
 
 // Debug logs at debug level if error is not nil.
@@ -67,7 +70,7 @@ func (b *ErrorBuilder) Debug(msg string) {
 		return
 	}
 	b.fields = append(b.fields, Err(b.err))
-	b.logger.log(DebugLevel, msg, b.fields)
+	b.logger.log(context.Background(), DebugLevel, msg, b.fields)
 }
 
 // Info logs at info level if error is not nil.
@@ -76,7 +79,7 @@ func (b *ErrorBuilder) Info(msg string) {
 		return
 	}
 	b.fields = append(b.fields, Err(b.err))
-	b.logger.log(InfoLevel, msg, b.fields)
+	b.logger.log(context.Background(), InfoLevel, msg, b.fields)
 }
 
 // Warn logs at warn level if error is not nil.
@@ -85,7 +88,7 @@ func (b *ErrorBuilder) Warn(msg string) {
 		return
 	}
 	b.fields = append(b.fields, Err(b.err))
-	b.logger.log(WarnLevel, msg, b.fields)
+	b.logger.log(context.Background(), WarnLevel, msg, b.fields)
 }
 
 // Error logs at error level if error is not nil.
@@ -94,7 +97,7 @@ func (b *ErrorBuilder) Error(msg string) {
 		return
 	}
 	b.fields = append(b.fields, Err(b.err))
-	b.logger.log(ErrorLevel, msg, b.fields)
+	b.logger.log(context.Background(), ErrorLevel, msg, b.fields)
 }
 
 // Fatal logs at fatal level if error is not nil and exits.
@@ -103,7 +106,8 @@ func (b *ErrorBuilder) Fatal(msg string) {
 		return
 	}
 	b.fields = append(b.fields, Err(b.err))
-	b.logger.log(FatalLevel, msg, b.fields)
+	b.logger.log(context.Background(), FatalLevel, msg, b.fields)
+	b.logger.exit()
 }
 
 // WrapErr wraps an error with additional context and logs it.
@@ -122,7 +126,7 @@ func (l *Logger) WrapErr(err error, msg string, fields ...Field) error {
 	allFields := make([]Field, 0, len(fields)+1)
 	allFields = append(allFields, Err(err))
 	allFields = append(allFields, fields...)
-	l.log(ErrorLevel, msg, allFields)
+	l.log(context.Background(), ErrorLevel, msg, allFields)
 
 	return wrapped
 }
@@ -138,7 +142,7 @@ func (l *Logger) WrapErrLevel(level Level, err error, msg string, fields ...Fiel
 	allFields := make([]Field, 0, len(fields)+1)
 	allFields = append(allFields, Err(err))
 	allFields = append(allFields, fields...)
-	l.log(level, msg, allFields)
+	l.log(context.Background(), level, msg, allFields)
 
 	return wrapped
 }
@@ -154,7 +158,7 @@ func (l *Logger) LogErr(err error, msg string, fields ...Field) {
 	allFields := make([]Field, 0, len(fields)+1)
 	allFields = append(allFields, Err(err))
 	allFields = append(allFields, fields...)
-	l.log(ErrorLevel, msg, allFields)
+	l.log(context.Background(), ErrorLevel, msg, allFields)
 }
 
 // ErrChain creates a field that unwraps the error chain.
@@ -211,7 +215,7 @@ func ErrWithStack(err error) Field {
 //	db := log.Must(sql.Open("postgres", dsn))
 func Must[T any](l *Logger, val T, err error) T {
 	if err != nil {
-		l.log(PanicLevel, "fatal error", []Field{Err(err)})
+		l.log(context.Background(), PanicLevel, "fatal error", []Field{Err(err)})
 		panic(err)
 	}
 	return val
@@ -223,7 +227,7 @@ func (l *Logger) MustErr(err error, msg string, fields ...Field) {
 		allFields := make([]Field, 0, len(fields)+1)
 		allFields = append(allFields, Err(err))
 		allFields = append(allFields, fields...)
-		l.log(PanicLevel, msg, allFields)
+		l.log(context.Background(), PanicLevel, msg, allFields)
 		panic(err)
 	}
 }
@@ -241,6 +245,143 @@ func (l *Logger) CheckErr(err error, msg string, fields ...Field) bool {
 	allFields := make([]Field, 0, len(fields)+1)
 	allFields = append(allFields, Err(err))
 	allFields = append(allFields, fields...)
-	l.log(ErrorLevel, msg, allFields)
+	l.log(context.Background(), ErrorLevel, msg, allFields)
 	return true
 }
+
+// ErrorEncoder writes the value of an error field as an object. stack is
+// the entry's stack trace if AddStack recorded one, and empty otherwise.
+type ErrorEncoder func(enc FieldEncoder, err error, stack string)
+
+// RichErrors is an ErrorEncoder writing errors as objects that error
+// analytics can group by type:
+//
+//	"error": {"message": "read config: open app.yaml: no such file or directory",
+//	  "type": "*fmt.wrapError",
+//	  "causes": ["open app.yaml: no such file or directory", "no such file or directory"],
+//	  "cause_types": ["*fs.PathError", "syscall.Errno"],
+//	  "stack": ["main.load /app/main.go:42", ...]}
+//
+// Causes are the errors found with errors.Unwrap, depth first for joined
+// errors. The stack is the one carried by the first error in the chain
+// with a Callers() []uintptr method, as errors from
+// github.com/go-errors/errors have, or else the entry's stack.
+func RichErrors(enc FieldEncoder, err error, stack string) {
+	enc.AddString("message", err.Error())
+	enc.AddString("type", fmt.Sprintf("%T", err))
+
+	var causes, types []string
+	var pcs []uintptr
+	var walk func(error)
+	walk = func(e error) {
+		if c, ok := e.(interface{ Callers() []uintptr }); ok && pcs == nil {
+			pcs = c.Callers()
+		}
+		var wrapped []error
+		switch u := e.(type) {
+		case interface{ Unwrap() error }:
+			if next := u.Unwrap(); next != nil {
+				wrapped = []error{next}
+			}
+		case interface{ Unwrap() []error }:
+			wrapped = u.Unwrap()
+		}
+		for _, next := range wrapped {
+			if next == nil {
+				continue
+			}
+			causes = append(causes, next.Error())
+			types = append(types, fmt.Sprintf("%T", next))
+			walk(next)
+		}
+	}
+	walk(err)
+	if len(causes) > 0 {
+		enc.AddField(Strings("causes", causes))
+		enc.AddField(Strings("cause_types", types))
+	}
+
+	if frames := stackFrames(pcs, stack); len(frames) > 0 {
+		enc.AddField(Strings("stack", frames))
+	}
+}
+
+// stackFrames returns the frames of pcs as "function file:line", or if
+// pcs is empty, those of stack, a trace in the format of runtime.Stack.
+func stackFrames(pcs []uintptr, stack string) []string {
+	var out []string
+	if len(pcs) > 0 {
+		frames := runtime.CallersFrames(pcs)
+		for {
+			frame, more := frames.Next()
+			out = append(out, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+			if !more {
+				return out
+			}
+		}
+	}
+	// runtime.Stack writes a goroutine header, then a function line and
+	// a tab-indented "file:line +0x.." line for each frame. Stacks the
+	// logger captured have no header.
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "goroutine ") {
+		lines = lines[1:]
+	}
+	for i := 0; i+1 < len(lines); i += 2 {
+		fn := lines[i]
+		if in := strings.Index(fn, " in goroutine "); in > 0 {
+			fn = fn[:in]
+		} else if paren := strings.LastIndexByte(fn, '('); paren > 0 && strings.HasSuffix(fn, ")") {
+			fn = fn[:paren]
+		}
+		loc := strings.TrimSpace(lines[i+1])
+		if sp := strings.IndexByte(loc, ' '); sp > 0 {
+			loc = loc[:sp]
+		}
+		out = append(out, fn+" "+loc)
+	}
+	return out
+}
+
+// errorObject is the value of an error field encoded by an ErrorEncoder.
+type errorObject struct {
+	err    error
+	stack  string
+	encode ErrorEncoder
+}
+
+// MarshalLog implements LogMarshaler.
+func (o errorObject) MarshalLog(enc FieldEncoder) {
+	o.encode(enc, o.err, o.stack)
+}
+
+// encodeErrors returns e with its error fields replaced by objects written
+// by encode. The first takes the entry's stack, which is then left out of
+// the entry itself. e is returned as is if it has no error fields.
+func encodeErrors(e *Entry, encode ErrorEncoder) *Entry {
+	var out *Entry
+	stack := e.Stack
+	for i, f := range e.Fields {
+		err, ok := f.Interface.(error)
+		if f.Type != FieldTypeError || !ok {
+			continue
+		}
+		if out == nil {
+			out = new(Entry)
+			*out = *e
+			out.Fields = slices.Clone(e.Fields)
+			out.Stack = ""
+			out.Frames = nil
+		}
+		out.Fields[i] = Field{
+			Key:       f.Key,
+			Type:      FieldTypeObject,
+			Interface: errorObject{err: err, stack: stack, encode: encode},
+		}
+		stack = ""
+	}
+	if out == nil {
+		return e
+	}
+	return out
+}
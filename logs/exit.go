@@ -0,0 +1,60 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/kolosys/lumen/internal/report"
+)
+
+var exitHandlers struct {
+	mu       sync.Mutex
+	handlers []func()
+}
+
+// RegisterExitHandler adds a function run when a Fatal call exits the
+// process, e.g. to flush hooks or close files. Handlers run in the order
+// they were registered; one that panics is reported and the rest still
+// run.
+//
+//	logs.RegisterExitHandler(func() { httpHook.Close() })
+func RegisterExitHandler(handler func()) {
+	exitHandlers.mu.Lock()
+	defer exitHandlers.mu.Unlock()
+	exitHandlers.handlers = append(exitHandlers.handlers, handler)
+}
+
+// runExitHandlers runs the handlers registered with RegisterExitHandler.
+func runExitHandlers() {
+	exitHandlers.mu.Lock()
+	handlers := exitHandlers.handlers
+	exitHandlers.mu.Unlock()
+	for _, h := range handlers {
+		runExitHandler(h)
+	}
+}
+
+func runExitHandler(handler func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			report.Error(report.Logs, fmt.Errorf("logs: exit handler panicked: %v", r))
+		}
+	}()
+	handler()
+}
+
+// exit ends a Fatal call: it flushes asynchronous logging, runs the exit
+// handlers and calls the logger's exit function.
+func (l *Logger) exit() {
+	l = l.current()
+	if l.async {
+		l.Close()
+	}
+	runExitHandlers()
+	if l.exitFunc != nil {
+		l.exitFunc(1)
+		return
+	}
+	os.Exit(1)
+}
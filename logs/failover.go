@@ -0,0 +1,195 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+)
+
+// ErrWriteTimeout is returned by a FailoverWriter for a write that did not
+// complete within FailoverOptions.Timeout, or to a writer still blocked in
+// such a write.
+var ErrWriteTimeout = errors.New("logs: write timed out")
+
+// FailoverOptions configures a FailoverWriter.
+type FailoverOptions struct {
+	// Timeout is how long a write may block before the writer is treated
+	// as failed. Default: 2s; negative disables it, writing directly on
+	// the caller's goroutine.
+	Timeout time.Duration
+
+	// ProbeInterval is how often, while failed over, a write is first
+	// tried on the primary again, to return to it once it recovers.
+	// Default: 10s
+	ProbeInterval time.Duration
+
+	// OnFailover is called when the writer in use changes, with the
+	// indexes of the old and new writers (0 is the primary) and the error
+	// that caused it, nil when returning to the primary.
+	OnFailover func(from, to int, err error)
+}
+
+func (o *FailoverOptions) applyDefaults() {
+	if o.Timeout == 0 {
+		o.Timeout = 2 * time.Second
+	}
+	if o.ProbeInterval <= 0 {
+		o.ProbeInterval = 10 * time.Second
+	}
+}
+
+// FailoverWriter writes to a primary output and falls back to secondary
+// writers, such as a local file, when the primary returns an error or
+// blocks past the timeout. While failed over it probes the primary every
+// ProbeInterval and returns to it once a write succeeds.
+//
+//	w := logs.NewFailoverWriter(conn, []io.Writer{localFile}, nil)
+//	logs.New(&logs.Options{Output: w})
+//
+// Writes are serialized, so entries are never interleaved.
+type FailoverWriter struct {
+	opts    FailoverOptions
+	targets []*failoverTarget
+
+	mu        sync.Mutex
+	active    int
+	lastErr   error
+	nextProbe time.Time
+}
+
+// failoverTarget writes to w on its own goroutine, so that a blocked write
+// can be abandoned. busy is set while a write that timed out is still in
+// progress; the target is skipped until it completes.
+type failoverTarget struct {
+	w       io.Writer
+	reqs    chan []byte
+	results chan error
+	busy    bool
+}
+
+// NewFailoverWriter creates a FailoverWriter writing to primary, and to
+// the fallbacks in order when the writers before them fail.
+func NewFailoverWriter(primary io.Writer, fallbacks []io.Writer, opts *FailoverOptions) *FailoverWriter {
+	w := &FailoverWriter{}
+	if opts != nil {
+		w.opts = *opts
+	}
+	w.opts.applyDefaults()
+	for _, out := range append([]io.Writer{primary}, fallbacks...) {
+		t := &failoverTarget{w: out}
+		if w.opts.Timeout > 0 {
+			t.reqs = make(chan []byte)
+			t.results = make(chan error, 1)
+			go t.run()
+		}
+		w.targets = append(w.targets, t)
+	}
+	return w
+}
+
+// Write implements io.Writer. It fails only if every writer fails,
+// returning the last error.
+func (w *FailoverWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.active > 0 && !time.Now().Before(w.nextProbe) {
+		if err := w.write(0, p); err == nil {
+			w.switchTo(0, nil)
+			return len(p), nil
+		}
+		w.nextProbe = time.Now().Add(w.opts.ProbeInterval)
+	}
+
+	var err error
+	for i := w.active; i < len(w.targets); i++ {
+		if err = w.write(i, p); err == nil {
+			if i != w.active {
+				w.switchTo(i, w.lastErr)
+			}
+			return len(p), nil
+		}
+		w.lastErr = err
+	}
+	return 0, err
+}
+
+// Active returns the index of the writer in use: 0 for the primary, 1 for
+// the first fallback and so on.
+func (w *FailoverWriter) Active() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.active
+}
+
+// Close stops the goroutines writing to the writers. It does not close
+// the writers. Later writes fail with os.ErrClosed.
+func (w *FailoverWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, t := range w.targets {
+		if t.reqs != nil {
+			close(t.reqs)
+			t.reqs = nil
+		}
+	}
+	return nil
+}
+
+func (w *FailoverWriter) switchTo(i int, err error) {
+	from := w.active
+	w.active = i
+	if i > 0 {
+		w.nextProbe = time.Now().Add(w.opts.ProbeInterval)
+		report.Error(report.Logs, fmt.Errorf("logs: failing over from writer %d to %d: %w", from, i, err))
+	}
+	if w.opts.OnFailover != nil {
+		w.opts.OnFailover(from, i, err)
+	}
+}
+
+// write writes p to the target at i, waiting at most Timeout.
+func (w *FailoverWriter) write(i int, p []byte) error {
+	t := w.targets[i]
+	if t.reqs == nil {
+		if w.opts.Timeout > 0 {
+			return os.ErrClosed
+		}
+		_, err := t.w.Write(p)
+		return err
+	}
+	if t.busy {
+		select {
+		case <-t.results:
+			t.busy = false
+		default:
+			return ErrWriteTimeout
+		}
+	}
+
+	// The write may outlive the call, so it gets its own copy of p.
+	buf := make([]byte, len(p))
+	copy(buf, p)
+	t.reqs <- buf
+	timer := time.NewTimer(w.opts.Timeout)
+	defer timer.Stop()
+	select {
+	case err := <-t.results:
+		return err
+	case <-timer.C:
+		t.busy = true
+		return ErrWriteTimeout
+	}
+}
+
+func (t *failoverTarget) run() {
+	for p := range t.reqs {
+		_, err := t.w.Write(p)
+		t.results <- err
+	}
+}
@@ -3,7 +3,12 @@ package logs
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/kolosys/lumen/internal/enc"
 )
 
 // FieldType represents the type of a field value.
@@ -34,6 +39,12 @@ const (
 	FieldTypeStringer
 	// FieldTypeBytes is a []byte field.
 	FieldTypeBytes
+	// FieldTypeObject is a LogMarshaler field.
+	FieldTypeObject
+	// FieldTypeNamespace opens a namespace; see Namespace.
+	FieldTypeNamespace
+	// FieldTypeArray is a slice field, such as Ints or Strings.
+	FieldTypeArray
 )
 
 // Field represents a structured log field.
@@ -54,7 +65,42 @@ func String(key, value string) Field {
 
 // Strings creates a string slice field.
 func Strings(key string, values []string) Field {
-	return Field{Key: key, Type: FieldTypeAny, Interface: values}
+	return Field{Key: key, Type: FieldTypeArray, Interface: values}
+}
+
+// Ints creates an int slice field.
+func Ints(key string, values []int) Field {
+	return Field{Key: key, Type: FieldTypeArray, Interface: values}
+}
+
+// Int64s creates an int64 slice field.
+func Int64s(key string, values []int64) Field {
+	return Field{Key: key, Type: FieldTypeArray, Interface: values}
+}
+
+// Float64s creates a float64 slice field.
+func Float64s(key string, values []float64) Field {
+	return Field{Key: key, Type: FieldTypeArray, Interface: values}
+}
+
+// Bools creates a bool slice field.
+func Bools(key string, values []bool) Field {
+	return Field{Key: key, Type: FieldTypeArray, Interface: values}
+}
+
+// Durations creates a time.Duration slice field.
+func Durations(key string, values []time.Duration) Field {
+	return Field{Key: key, Type: FieldTypeArray, Interface: values}
+}
+
+// Times creates a time.Time slice field.
+func Times(key string, values []time.Time) Field {
+	return Field{Key: key, Type: FieldTypeArray, Interface: values}
+}
+
+// Errs creates a field from a slice of errors, logged as their messages.
+func Errs(key string, errs []error) Field {
+	return Field{Key: key, Type: FieldTypeArray, Interface: errs}
 }
 
 // Stringer creates a field from a fmt.Stringer.
@@ -194,12 +240,16 @@ func Any(key string, value any) Field {
 		return Time(key, v)
 	case time.Duration:
 		return Duration(key, v)
+	case LogMarshaler:
+		return Field{Key: key, Type: FieldTypeObject, Interface: v}
 	case error:
 		return NamedErr(key, v)
 	case fmt.Stringer:
 		return Stringer(key, v)
 	case []byte:
 		return Bytes(key, v)
+	case []string, []int, []int64, []float64, []bool, []time.Duration, []time.Time, []error:
+		return Field{Key: key, Type: FieldTypeArray, Interface: v}
 	default:
 		return Field{Key: key, Type: FieldTypeAny, Interface: v}
 	}
@@ -221,12 +271,91 @@ func JSON(key string, value any) Field {
 
 // Stack creates a field containing a stack trace.
 func Stack(key string) Field {
-	return String(key, getStack())
+	o := StackOptions{}
+	o.applyDefaults()
+	return String(key, formatStack(captureStack(&o)))
 }
 
-// Namespace creates a namespace field for grouping.
+// Namespace creates a field that nests the fields after it, up to the end
+// of the entry, under key: a nested object in JSON output, and keys
+// prefixed with "key." in text output.
+//
+//	log := logger.With(logs.Namespace("http"))
+//	log.Info("served", logs.String("method", "GET")) // {"http":{"method":"GET"}}
 func Namespace(key string) Field {
-	return Field{Key: key, Type: FieldTypeUnknown, String: "namespace"}
+	return Field{Key: key, Type: FieldTypeNamespace}
+}
+
+// Group creates a field nesting fields under key: a nested object in JSON
+// output, and keys prefixed with "key." in text output.
+//
+//	logs.Group("http", logs.String("method", "GET"), logs.Int("status", 200))
+func Group(key string, fields ...Field) Field {
+	return Field{Key: key, Type: FieldTypeObject, Interface: groupFields(fields)}
+}
+
+// Dict creates a field nesting fields under key like Group, with the
+// fields sorted by key so that the output does not depend on the order
+// they were built in.
+func Dict(key string, fields ...Field) Field {
+	sorted := slices.Clone(fields)
+	slices.SortStableFunc(sorted, func(a, b Field) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return Group(key, sorted...)
+}
+
+// Map creates a field nesting the entries of m under key, sorted by key,
+// with each value encoded as by Any. Nested map[string]any values are
+// nested in turn.
+func Map(key string, m map[string]any) Field {
+	fields := make([]Field, 0, len(m))
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		if sub, ok := m[k].(map[string]any); ok {
+			fields = append(fields, Map(k, sub))
+		} else {
+			fields = append(fields, Any(k, m[k]))
+		}
+	}
+	return Group(key, fields...)
+}
+
+// groupFields is the value of a Group field.
+type groupFields []Field
+
+// MarshalLog implements LogMarshaler.
+func (g groupFields) MarshalLog(enc FieldEncoder) {
+	for _, f := range g {
+		enc.AddField(f)
+	}
+}
+
+// flattenFields returns fields with groups and namespaces replaced by
+// dotted keys, for formatters without nesting. fields is returned as is
+// if it has neither.
+func flattenFields(fields []Field) []Field {
+	for _, f := range fields {
+		if _, ok := f.Interface.(groupFields); ok || f.Type == FieldTypeNamespace {
+			return appendFlattened(make([]Field, 0, len(fields)), fields, "")
+		}
+	}
+	return fields
+}
+
+func appendFlattened(dst, fields []Field, prefix string) []Field {
+	for _, f := range fields {
+		if f.Type == FieldTypeNamespace {
+			prefix += f.Key + "."
+			continue
+		}
+		if g, ok := f.Interface.(groupFields); ok {
+			dst = appendFlattened(dst, g, prefix+f.Key+".")
+			continue
+		}
+		f.Key = prefix + f.Key
+		dst = append(dst, f)
+	}
+	return dst
 }
 
 // Value returns the field value as an interface{}.
@@ -258,11 +387,87 @@ func (f Field) Value() any {
 		return f.Interface
 	case FieldTypeBytes:
 		return f.Interface
+	case FieldTypeObject:
+		if m, ok := f.Interface.(LogMarshaler); ok {
+			return objectMap(m)
+		}
+		return f.Interface
+	case FieldTypeArray:
+		if errs, ok := f.Interface.([]error); ok {
+			msgs := make([]string, len(errs))
+			for i, err := range errs {
+				if err != nil {
+					msgs[i] = err.Error()
+				}
+			}
+			return msgs
+		}
+		return f.Interface
 	default:
 		return f.Interface
 	}
 }
 
+// encValue returns the field value in the shared encoding representation.
+func (f Field) encValue() enc.Value {
+	switch f.Type {
+	case FieldTypeString, FieldTypeError:
+		return enc.String(f.String)
+	case FieldTypeInt:
+		return enc.Int64(f.Int)
+	case FieldTypeUint:
+		return enc.Uint64(f.Uint)
+	case FieldTypeFloat:
+		return enc.Float64(f.Float)
+	case FieldTypeBool:
+		return enc.Bool(f.Int == 1)
+	case FieldTypeTime:
+		if t, ok := f.Interface.(time.Time); ok {
+			return enc.Time(t)
+		}
+		return enc.Int64(f.Int)
+	case FieldTypeDuration:
+		return enc.Duration(time.Duration(f.Int))
+	case FieldTypeStringer:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			return enc.String(s.String())
+		}
+		return enc.Any(nil)
+	case FieldTypeBytes:
+		b, ok := f.Interface.([]byte)
+		if !ok {
+			return enc.Any(nil)
+		}
+		if json.Valid(b) {
+			return enc.RawJSON(b)
+		}
+		return enc.String(string(b))
+	case FieldTypeObject:
+		if m, ok := f.Interface.(LogMarshaler); ok {
+			return enc.RawJSON(appendObjectJSON(nil, m))
+		}
+		return enc.Any(f.Interface)
+	case FieldTypeArray:
+		return enc.RawJSON(appendArray(nil, f.Interface, true))
+	default:
+		return enc.Any(f.Interface)
+	}
+}
+
+// appendJSON appends the field value as JSON. LogMarshaler values are
+// encoded straight into dst.
+func (f Field) appendJSON(dst []byte) []byte {
+	switch f.Type {
+	case FieldTypeObject:
+		if m, ok := f.Interface.(LogMarshaler); ok {
+			return appendObjectJSON(dst, m)
+		}
+	case FieldTypeArray:
+		return appendArray(dst, f.Interface, true)
+	}
+	return enc.AppendJSONValue(dst, f.encValue())
+}
+
 // StringValue returns the field value as a string.
 func (f Field) StringValue() string {
 	switch f.Type {
@@ -298,6 +503,13 @@ func (f Field) StringValue() string {
 			return string(b)
 		}
 		return fmt.Sprintf("%v", f.Interface)
+	case FieldTypeObject:
+		if m, ok := f.Interface.(LogMarshaler); ok {
+			return string(appendObjectJSON(nil, m))
+		}
+		return fmt.Sprintf("%v", f.Interface)
+	case FieldTypeArray:
+		return string(appendArray(nil, f.Interface, false))
 	default:
 		if f.Interface == nil {
 			return "null"
@@ -2,10 +2,10 @@ package logs
 
 import (
 	"bytes"
-	"encoding/json"
 	"strconv"
-	"sync"
 	"time"
+
+	"github.com/kolosys/lumen/internal/enc"
 )
 
 // Formatter formats log entries.
@@ -13,26 +13,9 @@ type Formatter interface {
 	Format(entry *Entry) ([]byte, error)
 }
 
-// bufferPool is a pool of byte buffers for formatting.
-var bufferPool = sync.Pool{
-	New: func() any {
-		return bytes.NewBuffer(make([]byte, 0, 256))
-	},
-}
+func getBuffer() *bytes.Buffer { return enc.GetBuffer() }
 
-func getBuffer() *bytes.Buffer {
-	buf := bufferPool.Get().(*bytes.Buffer)
-	buf.Reset()
-	return buf
-}
-
-func putBuffer(buf *bytes.Buffer) {
-	if buf.Cap() > 64*1024 {
-		// Don't pool buffers larger than 64KB
-		return
-	}
-	bufferPool.Put(buf)
-}
+func putBuffer(buf *bytes.Buffer) { enc.PutBuffer(buf) }
 
 // TextFormatter formats logs as text.
 type TextFormatter struct {
@@ -145,7 +128,7 @@ func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 	buf.WriteString(entry.Message)
 
 	// Fields (use filtered fields without _logger)
-	for _, field := range filteredFields {
+	for _, field := range flattenFields(filteredFields) {
 		buf.WriteString(fieldSep)
 
 		if !f.DisableColors {
@@ -282,57 +265,61 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 
 	// Timestamp
 	if !f.DisableTimestamp {
+		buf.Write(enc.AppendJSONKey(buf.AvailableBuffer(), timestampKey))
 		buf.WriteByte('"')
-		buf.WriteString(timestampKey)
-		buf.WriteString(`":"`)
-		buf.WriteString(entry.Time.Format(timestampFormat))
+		buf.Write(entry.Time.AppendFormat(buf.AvailableBuffer(), timestampFormat))
 		buf.WriteString(`",`)
 	}
 
 	// Level
-	buf.WriteByte('"')
-	buf.WriteString(levelKey)
-	buf.WriteString(`":"`)
-	buf.WriteString(entry.Level.String())
-	buf.WriteString(`",`)
+	buf.Write(enc.AppendJSONKey(buf.AvailableBuffer(), levelKey))
+	f.writeJSONString(buf, entry.Level.String())
 
 	// Logger name (if present)
 	if loggerName != "" {
-		buf.WriteString(`"logger":"`)
-		buf.WriteString(loggerName)
-		buf.WriteString(`",`)
+		f.writeJSONKey(buf, "logger")
+		f.writeJSONString(buf, loggerName)
 	}
 
 	// Message
-	buf.WriteByte('"')
-	buf.WriteString(messageKey)
-	buf.WriteString(`":`)
+	f.writeJSONKey(buf, messageKey)
 	f.writeJSONString(buf, entry.Message)
 
 	// Caller
 	if entry.Caller != "" {
-		buf.WriteString(`,"`)
-		buf.WriteString(callerKey)
-		buf.WriteString(`":"`)
-		buf.WriteString(entry.Caller)
-		buf.WriteByte('"')
+		f.writeJSONKey(buf, callerKey)
+		f.writeJSONString(buf, entry.Caller)
 	}
 
-	// Stack
-	if entry.Stack != "" {
-		buf.WriteString(`,"`)
-		buf.WriteString(stackKey)
-		buf.WriteString(`":`)
+	// Stack, as frames if the logger captured it
+	if len(entry.Frames) > 0 {
+		f.writeJSONKey(buf, stackKey)
+		f.writeJSONFrames(buf, entry.Frames)
+	} else if entry.Stack != "" {
+		f.writeJSONKey(buf, stackKey)
 		f.writeJSONString(buf, entry.Stack)
 	}
 
-	// Fields (filtered, without _logger)
+	// Fields (filtered, without _logger). A namespace opens an object
+	// holding the rest of the fields.
+	open, first := 0, false
 	for _, field := range filteredFields {
-		buf.WriteString(`,"`)
-		buf.WriteString(field.Key)
-		buf.WriteString(`":`)
+		if first {
+			buf.Write(enc.AppendJSONKey(buf.AvailableBuffer(), field.Key))
+		} else {
+			f.writeJSONKey(buf, field.Key)
+		}
+		first = field.Type == FieldTypeNamespace
+		if first {
+			buf.WriteByte('{')
+			open++
+			continue
+		}
 		f.writeJSONValue(buf, field)
 	}
+	for ; open > 0; open-- {
+		buf.WriteByte('}')
+	}
 
 	buf.WriteByte('}')
 	buf.WriteByte('\n')
@@ -344,70 +331,36 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 
 // writeJSONString writes a JSON-encoded string.
 func (f *JSONFormatter) writeJSONString(buf *bytes.Buffer, s string) {
-	data, _ := json.Marshal(s)
-	buf.Write(data)
+	buf.Write(enc.AppendJSONString(buf.AvailableBuffer(), s))
+}
+
+// writeJSONKey writes a separator-prefixed JSON object key.
+func (f *JSONFormatter) writeJSONKey(buf *bytes.Buffer, key string) {
+	buf.WriteByte(',')
+	buf.Write(enc.AppendJSONKey(buf.AvailableBuffer(), key))
+}
+
+// writeJSONFrames writes stack frames as an array of objects.
+func (f *JSONFormatter) writeJSONFrames(buf *bytes.Buffer, frames []StackFrame) {
+	buf.WriteByte('[')
+	for i, frame := range frames {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`{"func":`)
+		f.writeJSONString(buf, frame.Function)
+		buf.WriteString(`,"file":`)
+		f.writeJSONString(buf, frame.File)
+		buf.WriteString(`,"line":`)
+		buf.Write(appendInt(buf.AvailableBuffer(), frame.Line))
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
 }
 
 // writeJSONValue writes a JSON-encoded field value.
 func (f *JSONFormatter) writeJSONValue(buf *bytes.Buffer, field Field) {
-	switch field.Type {
-	case FieldTypeString:
-		f.writeJSONString(buf, field.String)
-	case FieldTypeInt:
-		buf.WriteString(strconv.FormatInt(field.Int, 10))
-	case FieldTypeUint:
-		buf.WriteString(strconv.FormatUint(field.Uint, 10))
-	case FieldTypeFloat:
-		buf.WriteString(strconv.FormatFloat(field.Float, 'g', -1, 64))
-	case FieldTypeBool:
-		if field.Int == 1 {
-			buf.WriteString("true")
-		} else {
-			buf.WriteString("false")
-		}
-	case FieldTypeTime:
-		if t, ok := field.Interface.(time.Time); ok {
-			buf.WriteByte('"')
-			buf.WriteString(t.Format(time.RFC3339Nano))
-			buf.WriteByte('"')
-		} else {
-			buf.WriteString(strconv.FormatInt(field.Int, 10))
-		}
-	case FieldTypeDuration:
-		buf.WriteByte('"')
-		buf.WriteString(time.Duration(field.Int).String())
-		buf.WriteByte('"')
-	case FieldTypeError:
-		f.writeJSONString(buf, field.String)
-	case FieldTypeStringer:
-		if s, ok := field.Interface.(interface{ String() string }); ok {
-			f.writeJSONString(buf, s.String())
-		} else {
-			buf.WriteString("null")
-		}
-	case FieldTypeBytes:
-		if b, ok := field.Interface.([]byte); ok {
-			// Check if it's already valid JSON
-			if json.Valid(b) {
-				buf.Write(b)
-			} else {
-				f.writeJSONString(buf, string(b))
-			}
-		} else {
-			buf.WriteString("null")
-		}
-	default:
-		if field.Interface == nil {
-			buf.WriteString("null")
-		} else {
-			data, err := json.Marshal(field.Interface)
-			if err != nil {
-				f.writeJSONString(buf, field.StringValue())
-			} else {
-				buf.Write(data)
-			}
-		}
-	}
+	buf.Write(field.appendJSON(buf.AvailableBuffer()))
 }
 
 // PrettyFormatter formats logs with colors and alignment for development.
@@ -473,7 +426,7 @@ func (f *PrettyFormatter) Format(entry *Entry) ([]byte, error) {
 	// Fields (filtered, without _logger)
 	if len(filteredFields) > 0 {
 		buf.WriteString(" \033[90m│\033[0m")
-		for _, field := range filteredFields {
+		for _, field := range flattenFields(filteredFields) {
 			buf.WriteByte(' ')
 			buf.WriteString("\033[36m") // Cyan
 			buf.WriteString(field.Key)
@@ -0,0 +1,47 @@
+package logs
+
+import (
+	"context"
+	"runtime"
+)
+
+// GoroutineKey is the field key set by Options.AddGoroutineID.
+const GoroutineKey = "goroutine"
+
+// GoroutineLabel returns a context whose entries are tagged with label in
+// place of the goroutine ID, when Options.AddGoroutineID is set. Labels
+// name workers more readably than IDs, and stay the same when a worker's
+// job moves to another goroutine:
+//
+//	ctx = logs.GoroutineLabel(ctx, fmt.Sprintf("shard-%d", i))
+//	log.InfoContext(ctx, "rebalanced") // ... goroutine=shard-3
+func GoroutineLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, goroutineLabelKey, label)
+}
+
+// goroutineField returns the goroutine field for an entry logged with ctx.
+func goroutineField(ctx context.Context) Field {
+	if ctx != nil {
+		if label, ok := ctx.Value(goroutineLabelKey).(string); ok {
+			return String(GoroutineKey, label)
+		}
+	}
+	return Uint64(GoroutineKey, goroutineID())
+}
+
+// goroutineID returns the ID of the calling goroutine, parsed from the
+// "goroutine N [status]:" header of its stack trace.
+func goroutineID() uint64 {
+	var buf [32]byte
+	n := runtime.Stack(buf[:], false)
+	const prefix = len("goroutine ")
+	var id uint64
+	for i := prefix; i < n; i++ {
+		c := buf[i]
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+	}
+	return id
+}
@@ -0,0 +1,303 @@
+// Package grpclog logs gRPC calls and puts a per-call logger in their
+// context, without needing tracing or metrics. It is to gRPC what httplog
+// is to net/http; grpcx instruments all three signals once a service has
+// enabled them.
+//
+//	server := grpc.NewServer(grpclog.ServerOptions(nil)...)
+//	conn, err := grpc.NewClient(target, append(grpclog.DialOptions(nil), creds)...)
+//
+// Each finished call is logged with its service, method, status code,
+// duration and peer address, at a level chosen by its status code.
+// Handlers log with the call's fields through logs.LoggerFromContext or
+// logs.CtxInfo and friends.
+package grpclog
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/trace/semconv"
+)
+
+// Call types, the values of the TypeKey field.
+const (
+	Unary        = "unary"
+	ClientStream = "client_stream"
+	ServerStream = "server_stream"
+	BidiStream   = "bidi_stream"
+)
+
+// Log field keys. The peer's address is logged under client.address on
+// the server and server.address on the client.
+const (
+	ServiceKey  = "grpc.service"
+	MethodKey   = "grpc.method"
+	TypeKey     = "grpc.type"
+	CodeKey     = "grpc.code"
+	DurationKey = "grpc.duration"
+)
+
+// Options configures the interceptors.
+type Options struct {
+	// Logger logs each finished call. Defaults to logs.Default(). The
+	// call's logger, with its service, method, type and, on the server,
+	// peer fields, is stored in the call context.
+	Logger *logs.Logger
+
+	// Filter, if set, selects the calls to log by full method name, e.g.
+	// to skip "/grpc.health.v1.Health/Check".
+	Filter func(fullMethod string) bool
+
+	// CodeLevel chooses the level calls are logged at. Defaults to
+	// DefaultCodeLevel.
+	CodeLevel func(code codes.Code) logs.Level
+
+	// DisableCallLog stops the entry logged for each finished call,
+	// leaving only the context setup.
+	DisableCallLog bool
+}
+
+func (o *Options) applyDefaults() {
+	if o.Logger == nil {
+		o.Logger = logs.Default()
+	}
+	if o.CodeLevel == nil {
+		o.CodeLevel = DefaultCodeLevel
+	}
+}
+
+// DefaultCodeLevel logs successful calls and client errors at info,
+// codes that suggest an overloaded or misbehaving dependency at warn, and
+// server faults at error.
+func DefaultCodeLevel(code codes.Code) logs.Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound,
+		codes.AlreadyExists, codes.Unauthenticated:
+		return logs.InfoLevel
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return logs.WarnLevel
+	default:
+		return logs.ErrorLevel
+	}
+}
+
+// ServerOptions returns the options installing the server interceptors.
+func ServerOptions(opts *Options) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(opts)),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(opts)),
+	}
+}
+
+// DialOptions returns the options installing the client interceptors.
+func DialOptions(opts *Options) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(opts)),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor(opts)),
+	}
+}
+
+// UnaryServerInterceptor logs unary calls on the server.
+func UnaryServerInterceptor(opts *Options) grpc.UnaryServerInterceptor {
+	in := newInterceptor(opts, serverSide)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !in.enabled(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, c := in.start(ctx, info.FullMethod, Unary)
+		resp, err := handler(ctx, req)
+		c.end(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor logs streaming calls on the server. The call
+// ends when the handler returns.
+func StreamServerInterceptor(opts *Options) grpc.StreamServerInterceptor {
+	in := newInterceptor(opts, serverSide)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !in.enabled(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx, c := in.start(ss.Context(), info.FullMethod, streamType(info.IsClientStream, info.IsServerStream))
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		c.end(err)
+		return err
+	}
+}
+
+// serverStream replaces the stream's context with one holding the call
+// logger.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+// UnaryClientInterceptor logs unary calls on the client.
+func UnaryClientInterceptor(opts *Options) grpc.UnaryClientInterceptor {
+	in := newInterceptor(opts, clientSide)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if !in.enabled(method) {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		ctx, c := in.start(ctx, method, Unary)
+		err := invoker(ctx, method, req, reply, cc, append(callOpts, grpc.Peer(&c.peer))...)
+		c.end(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor logs streaming calls on the client. The call
+// ends when RecvMsg returns the final status, or, for streams with a
+// single response, once that response is received.
+func StreamClientInterceptor(opts *Options) grpc.StreamClientInterceptor {
+	in := newInterceptor(opts, clientSide)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if !in.enabled(method) {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+		ctx, c := in.start(ctx, method, streamType(desc.ClientStreams, desc.ServerStreams))
+		cs, err := streamer(ctx, desc, cc, method, append(callOpts, grpc.Peer(&c.peer))...)
+		if err != nil {
+			c.end(err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, call: c, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// clientStream ends its call when the stream completes.
+type clientStream struct {
+	grpc.ClientStream
+	call          *call
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.serverStreams:
+		s.finish(nil)
+	}
+	return err
+}
+
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.finish(err)
+	}
+	return md, err
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() { s.call.end(err) })
+}
+
+const (
+	serverSide = "server"
+	clientSide = "client"
+)
+
+// interceptor holds the options of one side and starts its calls.
+type interceptor struct {
+	opts Options
+	side string
+}
+
+func newInterceptor(opts *Options, side string) *interceptor {
+	in := &interceptor{side: side}
+	if opts != nil {
+		in.opts = *opts
+	}
+	in.opts.applyDefaults()
+	return in
+}
+
+func (in *interceptor) enabled(fullMethod string) bool {
+	return in.opts.Filter == nil || in.opts.Filter(fullMethod)
+}
+
+// call is one logged RPC.
+type call struct {
+	in     *interceptor
+	ctx    context.Context
+	logger *logs.Logger
+	start  time.Time
+	// peer is filled in by grpc.Peer on the client.
+	peer peer.Peer
+}
+
+// start begins a call, storing its logger in the returned context.
+func (in *interceptor) start(ctx context.Context, fullMethod, typ string) (context.Context, *call) {
+	service, method := splitMethod(fullMethod)
+	fields := []logs.Field{
+		logs.String(ServiceKey, service),
+		logs.String(MethodKey, method),
+		logs.String(TypeKey, typ),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil && in.side == serverSide {
+		fields = append(fields, logs.String(semconv.ClientAddressKey, p.Addr.String()))
+	}
+	logger := in.opts.Logger.With(fields...)
+	ctx = logs.WithLogger(ctx, logger)
+	return ctx, &call{in: in, ctx: ctx, logger: logger, start: time.Now()}
+}
+
+// end logs the outcome of the call.
+func (c *call) end(err error) {
+	if c.in.opts.DisableCallLog {
+		return
+	}
+	elapsed := time.Since(c.start)
+	code := status.Code(err)
+
+	fields := []logs.Field{logs.String(CodeKey, code.String()), logs.Duration(DurationKey, elapsed)}
+	if c.peer.Addr != nil {
+		fields = append(fields, logs.String(semconv.ServerAddressKey, c.peer.Addr.String()))
+	}
+	if err != nil {
+		fields = append(fields, logs.Err(err))
+	}
+	c.logger.LogContext(c.ctx, c.in.opts.CodeLevel(code), "grpc "+c.in.side+" call finished", fields...)
+}
+
+// splitMethod splits "/pkg.Service/Method" into service and method.
+func splitMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndexByte(fullMethod, '/'); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+func streamType(clientStreams, serverStreams bool) string {
+	switch {
+	case clientStreams && serverStreams:
+		return BidiStream
+	case clientStreams:
+		return ClientStream
+	case serverStreams:
+		return ServerStream
+	default:
+		return Unary
+	}
+}
@@ -0,0 +1,98 @@
+package grpclog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/logs/grpclog"
+)
+
+func TestInterceptors(t *testing.T) {
+	var out bytes.Buffer
+	opts := &grpclog.Options{
+		Logger: logs.New(&logs.Options{Output: &out, Formatter: &logs.JSONFormatter{}}),
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(append(grpclog.ServerOptions(opts),
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			logs.CtxInfo(ctx, "handling")
+			return handler(ctx, req)
+		}),
+	)...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", append(grpclog.DialOptions(opts),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		entries = append(entries, m)
+	}
+	// Per call: the handler's entry, then the server's, then the client's.
+	if len(entries) != 6 {
+		t.Fatalf("expected 6 entries, got %d:\n%s", len(entries), out.String())
+	}
+	handling, serverCall, clientCall := entries[0], entries[1], entries[2]
+	if handling["msg"] != "handling" || handling[grpclog.MethodKey] != "Check" || handling["client.address"] != "bufconn" {
+		t.Errorf("handler entry lacks the call fields: %v", handling)
+	}
+	if serverCall["msg"] != "grpc server call finished" || serverCall[grpclog.ServiceKey] != "grpc.health.v1.Health" ||
+		serverCall[grpclog.CodeKey] != "OK" || serverCall[grpclog.DurationKey] == nil {
+		t.Errorf("unexpected server entry: %v", serverCall)
+	}
+	if clientCall["msg"] != "grpc client call finished" || clientCall["server.address"] != "bufconn" {
+		t.Errorf("unexpected client entry: %v", clientCall)
+	}
+	if nf := entries[5]; nf[grpclog.CodeKey] != "NotFound" || nf["level"] != "info" || nf["error"] == nil {
+		t.Errorf("unexpected NotFound entry: %v", nf)
+	}
+}
+
+func TestDefaultCodeLevel(t *testing.T) {
+	for code, want := range map[codes.Code]logs.Level{
+		codes.OK:               logs.InfoLevel,
+		codes.NotFound:         logs.InfoLevel,
+		codes.DeadlineExceeded: logs.WarnLevel,
+		codes.Internal:         logs.ErrorLevel,
+		codes.Unavailable:      logs.ErrorLevel,
+	} {
+		if got := grpclog.DefaultCodeLevel(code); got != want {
+			t.Errorf("DefaultCodeLevel(%v) = %v, want %v", code, got, want)
+		}
+	}
+}
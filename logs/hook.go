@@ -1,9 +1,13 @@
 package logs
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
 	"sync"
+
+	"github.com/kolosys/lumen/internal/report"
 )
 
 // Hook is called when a log entry is written.
@@ -16,6 +20,61 @@ type Hook interface {
 	Levels() []Level
 }
 
+// ContextHook is a Hook that also receives the context of the entry, so
+// it can read deadlines, tenant IDs or the active span rather than only
+// the fields. The logger calls FireContext instead of Fire, with the
+// context passed to the *Context logging methods, or
+// context.Background() for the others.
+type ContextHook interface {
+	Hook
+
+	// FireContext is called for each log entry in place of Fire.
+	FireContext(ctx context.Context, entry *Entry)
+}
+
+// WriteHook is a hook run on the formatted bytes of each entry, just
+// before they are written, e.g. to checksum, mirror or encrypt them
+// without formatting the entry again. It returns the bytes to write to
+// output: data itself, a replacement such as its encryption, or nil to
+// skip writing the entry. data is only valid during the call; it must be
+// copied to be kept.
+type WriteHook interface {
+	OnWrite(entry *Entry, data []byte, output io.Writer) []byte
+}
+
+// WriteHookFunc adapts a function to a WriteHook.
+type WriteHookFunc func(entry *Entry, data []byte, output io.Writer) []byte
+
+// OnWrite calls f(entry, data, output).
+func (f WriteHookFunc) OnWrite(entry *Entry, data []byte, output io.Writer) []byte {
+	return f(entry, data, output)
+}
+
+// NewMirrorHook returns a WriteHook that also writes every formatted
+// entry to w, in the logger's format. Write errors are reported rather
+// than stopping the entry reaching the output.
+func NewMirrorHook(w io.Writer) WriteHook {
+	var mu sync.Mutex
+	return WriteHookFunc(func(_ *Entry, data []byte, _ io.Writer) []byte {
+		mu.Lock()
+		_, err := w.Write(data)
+		mu.Unlock()
+		if err != nil {
+			report.Error(report.LogHook, fmt.Errorf("logs: mirror hook: %w", err))
+		}
+		return data
+	})
+}
+
+// fireHook fires hook with ctx if it is a ContextHook.
+func fireHook(ctx context.Context, hook Hook, entry *Entry) {
+	if ch, ok := hook.(ContextHook); ok {
+		ch.FireContext(ctx, entry)
+	} else {
+		hook.Fire(entry)
+	}
+}
+
 // LevelHook fires only for specific levels.
 type LevelHook struct {
 	hook   Hook
@@ -36,8 +95,13 @@ func NewLevelHook(hook Hook, levels ...Level) *LevelHook {
 
 // Fire implements Hook.
 func (h *LevelHook) Fire(entry *Entry) {
+	h.FireContext(context.Background(), entry)
+}
+
+// FireContext implements ContextHook, passing ctx on to the wrapped hook.
+func (h *LevelHook) FireContext(ctx context.Context, entry *Entry) {
 	if h.levels[entry.Level] {
-		h.hook.Fire(entry)
+		fireHook(ctx, h.hook, entry)
 	}
 }
 
@@ -71,11 +135,15 @@ func NewWriterHook(w io.Writer, formatter Formatter, levels ...Level) *WriterHoo
 func (h *WriterHook) Fire(entry *Entry) {
 	data, err := h.formatter.Format(entry)
 	if err != nil {
+		report.Error(report.LogHook, fmt.Errorf("logs: hook format entry: %w", err))
 		return
 	}
 	h.mu.Lock()
-	h.writer.Write(data)
+	_, err = h.writer.Write(data)
 	h.mu.Unlock()
+	if err != nil {
+		report.Error(report.LogHook, fmt.Errorf("logs: hook write entry: %w", err))
+	}
 }
 
 // Levels implements Hook.
@@ -158,7 +226,58 @@ func (h *ErrorHook) Clear() {
 	h.mu.Unlock()
 }
 
+// RingHook keeps the most recent entries in a fixed-size ring, e.g. for
+// display in a local UI or inclusion in a bug report.
+type RingHook struct {
+	entries []Entry
+	next    int
+	full    bool
+	levels  []Level
+	mu      sync.Mutex
+}
+
+// NewRingHook creates a hook that keeps the last size entries (minimum 1)
+// at the given levels, or at all levels if none are given.
+func NewRingHook(size int, levels ...Level) *RingHook {
+	return &RingHook{entries: make([]Entry, max(size, 1)), levels: levels}
+}
+
+// Fire implements Hook. The entry's fields are copied, since the entry
+// is reused once hooks return.
+func (h *RingHook) Fire(entry *Entry) {
+	e := *entry
+	e.Fields = append([]Field(nil), entry.Fields...)
+
+	h.mu.Lock()
+	h.entries[h.next] = e
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+	h.mu.Unlock()
+}
+
+// Levels implements Hook.
+func (h *RingHook) Levels() []Level {
+	return h.levels
+}
+
+// Entries returns the retained entries, oldest first.
+func (h *RingHook) Entries() []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.full {
+		return append([]Entry(nil), h.entries[:h.next]...)
+	}
+	out := make([]Entry, 0, len(h.entries))
+	out = append(out, h.entries[h.next:]...)
+	return append(out, h.entries[:h.next]...)
+}
+
 // MetricsHook tracks log counts by level.
+//
+// Deprecated: Use NewMetricsRegistryHook, which records counts in a
+// metrics.Registry where they can be exported.
 type MetricsHook struct {
 	counts map[Level]uint64
 	mu     sync.RWMutex
@@ -227,8 +346,13 @@ func NewFilterHook(hook Hook, filter func(*Entry) bool) *FilterHook {
 
 // Fire implements Hook.
 func (h *FilterHook) Fire(entry *Entry) {
+	h.FireContext(context.Background(), entry)
+}
+
+// FireContext implements ContextHook, passing ctx on to the wrapped hook.
+func (h *FilterHook) FireContext(ctx context.Context, entry *Entry) {
 	if h.filter(entry) {
-		h.hook.Fire(entry)
+		fireHook(ctx, h.hook, entry)
 	}
 }
 
@@ -260,3 +384,39 @@ func (h *FuncHook) Fire(entry *Entry) {
 func (h *FuncHook) Levels() []Level {
 	return h.levels
 }
+
+// ContextFuncHook wraps a function receiving the entry's context as a
+// hook.
+type ContextFuncHook struct {
+	fn     func(context.Context, *Entry)
+	levels []Level
+}
+
+// NewContextFuncHook creates a ContextHook from a function.
+//
+//	logs.NewContextFuncHook(func(ctx context.Context, e *logs.Entry) {
+//		if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+//			perTenant.Inc(tenant, e.Level.String())
+//		}
+//	})
+func NewContextFuncHook(fn func(context.Context, *Entry), levels ...Level) *ContextFuncHook {
+	return &ContextFuncHook{
+		fn:     fn,
+		levels: levels,
+	}
+}
+
+// Fire implements Hook.
+func (h *ContextFuncHook) Fire(entry *Entry) {
+	h.fn(context.Background(), entry)
+}
+
+// FireContext implements ContextHook.
+func (h *ContextFuncHook) FireContext(ctx context.Context, entry *Entry) {
+	h.fn(ctx, entry)
+}
+
+// Levels implements Hook.
+func (h *ContextFuncHook) Levels() []Level {
+	return h.levels
+}
@@ -0,0 +1,306 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+)
+
+// ErrHTTPHookQueueFull is reported when an entry is dropped because the
+// queue of an HTTPHook is full.
+var ErrHTTPHookQueueFull = errors.New("logs: http hook queue full")
+
+// HTTPHookOptions configures an HTTPHook.
+type HTTPHookOptions struct {
+	// Formatter formats each entry; a batch is the formatted entries
+	// concatenated. Defaults to a JSONFormatter, making batches NDJSON.
+	Formatter Formatter
+
+	// ContentType is the Content-Type of requests. Defaults to
+	// application/x-ndjson.
+	ContentType string
+
+	// Headers are added to every request, e.g. an API key.
+	Headers map[string]string
+
+	// Levels are the levels sent. Defaults to all.
+	Levels []Level
+
+	// BatchSize is the number of entries sent per request. Default: 100
+	BatchSize int
+
+	// FlushInterval is the longest an entry waits for its batch to fill.
+	// Default: 5s
+	FlushInterval time.Duration
+
+	// QueueSize bounds the entries waiting to be sent. Entries beyond it
+	// are dropped and counted in Dropped. Default: 1000
+	QueueSize int
+
+	// Retries is the number of retries after a failed request (default 3;
+	// negative disables retrying). Requests failing with a 4xx status
+	// other than 429 are not retried.
+	Retries int
+
+	// Backoff is the delay before the first retry, doubled for each
+	// further retry with full jitter. Defaults to 500ms.
+	Backoff time.Duration
+
+	// MaxBackoff caps the retry delay. Defaults to 30s.
+	MaxBackoff time.Duration
+
+	// Client sends requests. Defaults to an http.Client with a 10s
+	// timeout.
+	Client *http.Client
+
+	// OnError is called when entries are dropped: with
+	// ErrHTTPHookQueueFull when the queue is full, or with the last error
+	// when a batch could not be delivered.
+	OnError func(err error)
+}
+
+func (o *HTTPHookOptions) applyDefaults() {
+	if o.Formatter == nil {
+		o.Formatter = &JSONFormatter{}
+	}
+	if o.ContentType == "" {
+		o.ContentType = "application/x-ndjson"
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = 5 * time.Second
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = 1000
+	}
+	if o.Retries == 0 {
+		o.Retries = 3
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = 500 * time.Millisecond
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 30 * time.Second
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+}
+
+// HTTPHook ships entries to an HTTP endpoint in batches, for log
+// collectors and webhooks without a dedicated hook. Entries are formatted
+// when fired and queued; a background sender POSTs a batch when it is
+// full or FlushInterval has passed, retrying failures with exponential
+// backoff. Close sends what is still queued.
+//
+//	hook := logs.NewHTTPHook("https://logs.example.com/ingest", &logs.HTTPHookOptions{
+//		Headers: map[string]string{"Authorization": "Bearer " + token},
+//	})
+//	defer hook.Close()
+type HTTPHook struct {
+	url  string
+	opts HTTPHookOptions
+
+	mu      sync.Mutex
+	queue   chan []byte
+	flushes chan chan struct{}
+	closed  bool
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// NewHTTPHook creates an HTTPHook posting to url and starts its sender.
+func NewHTTPHook(url string, opts *HTTPHookOptions) *HTTPHook {
+	h := &HTTPHook{url: url}
+	if opts != nil {
+		h.opts = *opts
+	}
+	h.opts.applyDefaults()
+	h.queue = make(chan []byte, h.opts.QueueSize)
+	h.flushes = make(chan chan struct{})
+	h.done = make(chan struct{})
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	go h.run()
+	return h
+}
+
+// Fire implements Hook.
+func (h *HTTPHook) Fire(entry *Entry) {
+	data, err := h.opts.Formatter.Format(entry)
+	if err != nil {
+		h.drop(1, fmt.Errorf("logs: http hook format entry: %w", err))
+		return
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	select {
+	case h.queue <- data:
+		h.mu.Unlock()
+	default:
+		h.mu.Unlock()
+		h.drop(1, ErrHTTPHookQueueFull)
+	}
+}
+
+// Levels implements Hook.
+func (h *HTTPHook) Levels() []Level {
+	return h.opts.Levels
+}
+
+// Dropped returns the number of entries dropped because the queue was
+// full or their batch could not be delivered.
+func (h *HTTPHook) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// Flush sends the queued entries and waits for them to be delivered or
+// dropped.
+func (h *HTTPHook) Flush() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.mu.Unlock()
+	ack := make(chan struct{})
+	select {
+	case h.flushes <- ack:
+		<-ack
+	case <-h.done:
+	}
+}
+
+// Close sends the queued entries, without retrying failures, and stops
+// the sender. Entries fired after Close are dropped.
+func (h *HTTPHook) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	close(h.queue)
+	h.mu.Unlock()
+
+	h.cancel()
+	<-h.done
+	return nil
+}
+
+func (h *HTTPHook) run() {
+	defer close(h.done)
+	ticker := time.NewTicker(h.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var batch [][]byte
+	send := func() {
+		if len(batch) > 0 {
+			h.send(batch)
+			batch = batch[:0]
+		}
+	}
+	for {
+		select {
+		case data, ok := <-h.queue:
+			if !ok {
+				send()
+				return
+			}
+			batch = append(batch, data)
+			if len(batch) >= h.opts.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-h.flushes:
+			for drained := false; !drained; {
+				select {
+				case data, ok := <-h.queue:
+					if !ok {
+						drained = true
+						break
+					}
+					batch = append(batch, data)
+					if len(batch) >= h.opts.BatchSize {
+						send()
+					}
+				default:
+					drained = true
+				}
+			}
+			send()
+			close(ack)
+		}
+	}
+}
+
+// send posts a batch, retrying failures with exponential backoff and full
+// jitter until the retries are used up or the hook is closed.
+func (h *HTTPHook) send(batch [][]byte) {
+	body := bytes.Join(batch, nil)
+	backoff := h.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := h.post(body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= h.opts.Retries || h.ctx.Err() != nil {
+			h.drop(len(batch), err)
+			return
+		}
+
+		delay := time.Duration(rand.Int64N(int64(backoff) + 1))
+		select {
+		case <-h.ctx.Done():
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, h.opts.MaxBackoff)
+	}
+}
+
+// post sends one request and reports whether a failure may be retried.
+func (h *HTTPHook) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", h.opts.ContentType)
+	for k, v := range h.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := h.opts.Client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("logs: http hook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("logs: http hook: endpoint responded %s", resp.Status)
+	}
+	return false, nil
+}
+
+func (h *HTTPHook) drop(n int, err error) {
+	h.dropped.Add(uint64(n))
+	report.Error(report.LogHook, err)
+	if h.opts.OnError != nil {
+		h.opts.OnError(err)
+	}
+}
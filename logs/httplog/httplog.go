@@ -0,0 +1,151 @@
+// Package httplog logs net/http requests and puts a request logger in
+// their context, without needing tracing or metrics. It suits services
+// that have not enabled tracing yet; httpx instruments all three signals
+// once they have.
+//
+//	handler = httplog.Middleware(mux, &httplog.Options{ParseTraceparent: true})
+//
+// With ParseTraceparent, the W3C traceparent header of incoming requests
+// is parsed and its trace and parent span IDs added to every entry logged
+// with the request context, so the logs of a service without a tracer
+// still line up with the traces of its callers.
+package httplog
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/trace"
+	"github.com/kolosys/lumen/trace/semconv"
+)
+
+// DurationKey is the log field holding the time taken to serve a request.
+const DurationKey = "http.duration"
+
+// Options configures Middleware.
+type Options struct {
+	// Logger logs each request. Defaults to logs.Default(). Handlers find
+	// it via logs.LoggerFromContext.
+	Logger *logs.Logger
+
+	// ParseTraceparent adds the trace_id and span_id of an incoming
+	// traceparent header to the request context's log fields, unless the
+	// request already has an active span. The parsed trace context and
+	// its sampled flag are also stored in the context, for a tracer or a
+	// logs.DecisionSampler further down to pick up.
+	ParseTraceparent bool
+
+	// Filter, if set, selects the requests to handle, e.g. to skip health
+	// checks.
+	Filter func(r *http.Request) bool
+
+	// StatusLevel chooses the level requests are logged at. Defaults to
+	// error for 5xx, warn for 4xx and info otherwise.
+	StatusLevel func(status int) logs.Level
+
+	// DisableAccessLog stops the entry logged for each completed request,
+	// leaving only the context setup.
+	DisableAccessLog bool
+}
+
+func (o *Options) applyDefaults() {
+	if o.Logger == nil {
+		o.Logger = logs.Default()
+	}
+	if o.StatusLevel == nil {
+		o.StatusLevel = statusLevel
+	}
+}
+
+func statusLevel(status int) logs.Level {
+	switch {
+	case status >= 500:
+		return logs.ErrorLevel
+	case status >= 400:
+		return logs.WarnLevel
+	default:
+		return logs.InfoLevel
+	}
+}
+
+// Middleware logs the requests served by next.
+func Middleware(next http.Handler, opts *Options) http.Handler {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	o.applyDefaults()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.Filter != nil && !o.Filter(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		ctx := r.Context()
+		if o.ParseTraceparent && trace.SpanFromContext(ctx) == nil {
+			ctx = withTraceparent(ctx, r.Header)
+		}
+		logger := o.Logger.With(logs.String(semconv.HTTPMethodKey, r.Method))
+		ctx = logs.WithLogger(ctx, logger)
+
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		if o.DisableAccessLog {
+			return
+		}
+		logger.LogContext(ctx, o.StatusLevel(rw.status), "http request",
+			logs.String(semconv.URLPathKey, r.URL.Path),
+			logs.Int(semconv.HTTPStatusCodeKey, rw.status),
+			logs.Int64(semconv.HTTPResponseSizeKey, rw.bytes),
+			logs.Duration(DurationKey, time.Since(start)),
+		)
+	})
+}
+
+// withTraceparent adds the trace context of a valid traceparent header to
+// ctx. Invalid headers are ignored.
+func withTraceparent(ctx context.Context, h http.Header) context.Context {
+	header := h.Get(trace.W3CTraceparentHeader)
+	if header == "" {
+		return ctx
+	}
+	tc, err := trace.ParseW3CTraceparent(header)
+	if err != nil {
+		return ctx
+	}
+	tc.TraceState = h.Get(trace.W3CTracestateHeader)
+	ctx = trace.ContextWithTraceContext(ctx, tc)
+	ctx = logs.WithSamplingDecision(ctx, tc.IsSampled())
+	return logs.WithContextFields(ctx,
+		logs.String(logs.TraceIDKey, tc.TraceID.String()),
+		logs.String(logs.SpanIDKey, tc.SpanID.String()),
+	)
+}
+
+// responseWriter records the status and size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
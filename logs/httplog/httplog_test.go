@@ -0,0 +1,87 @@
+package httplog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kolosys/lumen/logs"
+	. "github.com/kolosys/lumen/logs/httplog"
+	"github.com/kolosys/lumen/trace"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestTraceparentCorrelation(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(&logs.Options{Output: &buf, Formatter: &logs.JSONFormatter{}})
+	var tc *trace.TraceContext
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc = trace.TraceContextFromContext(r.Context())
+		logs.CtxInfo(r.Context(), "handling")
+		http.Error(w, "missing", http.StatusNotFound)
+	}), &Options{Logger: logger, ParseTraceparent: true})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+	req.Header.Set("traceparent", traceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if tc == nil || !tc.IsSampled() {
+		t.Fatalf("trace context not stored: %+v", tc)
+	}
+	entries := decode(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if e["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || e["span_id"] != "00f067aa0ba902b7" {
+			t.Errorf("entry not correlated: %v", e)
+		}
+		if e["http.request.method"] != "GET" {
+			t.Errorf("entry lacks the method: %v", e)
+		}
+	}
+	access := entries[1]
+	if access["msg"] != "http request" || access["level"] != "warn" ||
+		access["http.response.status_code"] != 404.0 || access["url.path"] != "/orders/7" {
+		t.Errorf("access entry = %v", access)
+	}
+}
+
+func TestWithoutTraceparent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(&logs.Options{Output: &buf, Formatter: &logs.JSONFormatter{}})
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}), &Options{Logger: logger})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", traceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-garbage")
+	Middleware(http.NotFoundHandler(), &Options{Logger: logger, ParseTraceparent: true}).ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, e := range decode(t, &buf) {
+		if _, ok := e["trace_id"]; ok {
+			t.Errorf("unexpected trace_id: %v", e)
+		}
+	}
+}
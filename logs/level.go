@@ -11,7 +11,7 @@ type Level int
 const (
 	// PanicLevel logs and then panics.
 	PanicLevel Level = iota
-	// FatalLevel logs and then calls os.Exit(1).
+	// FatalLevel logs and then calls os.Exit(1), or Options.ExitFunc.
 	FatalLevel
 	// ErrorLevel is for errors that should be noted.
 	ErrorLevel
@@ -0,0 +1,12 @@
+module github.com/kolosys/lumen/logs/logrusadapter
+
+go 1.24
+
+require (
+	github.com/kolosys/lumen v0.0.0
+	github.com/sirupsen/logrus v1.9.3
+)
+
+require golang.org/x/sys v0.33.0 // indirect
+
+replace github.com/kolosys/lumen => ../../
@@ -0,0 +1,94 @@
+// Package logrusadapter forwards logrus entries into a lumen Logger, so
+// that a codebase can move to lumen before its call sites are rewritten:
+//
+//	logrus.SetOutput(io.Discard) // lumen writes the entries instead
+//	logrus.AddHook(logrusadapter.NewHook(logger))
+//
+// Entries keep their message, level, fields and context; the logrus
+// formatter and output are no longer involved.
+package logrusadapter
+
+import (
+	"context"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kolosys/lumen/logs"
+)
+
+// Hook is a logrus.Hook logging each entry to a lumen Logger.
+type Hook struct {
+	logger *logs.Logger
+	levels []logrus.Level
+}
+
+// NewHook creates a Hook forwarding entries at the given levels, or at all
+// levels if none are given, to logger. Entries below the logger's level
+// are dropped by it as usual.
+func NewHook(logger *logs.Logger, levels ...logrus.Level) *Hook {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+	return &Hook{logger: logger, levels: levels}
+}
+
+// Levels implements logrus.Hook.
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire implements logrus.Hook. Panic and fatal entries are logged
+// without panicking or exiting, which logrus does itself.
+func (h *Hook) Fire(e *logrus.Entry) error {
+	level := Level(e.Level)
+	if !h.logger.IsEnabled(level) {
+		return nil
+	}
+	ctx := e.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	h.logger.LogContext(ctx, level, e.Message, Fields(e.Data)...)
+	return nil
+}
+
+// Level returns the lumen level of a logrus level.
+func Level(l logrus.Level) logs.Level {
+	switch l {
+	case logrus.PanicLevel:
+		return logs.PanicLevel
+	case logrus.FatalLevel:
+		return logs.FatalLevel
+	case logrus.ErrorLevel:
+		return logs.ErrorLevel
+	case logrus.WarnLevel:
+		return logs.WarnLevel
+	case logrus.InfoLevel:
+		return logs.InfoLevel
+	case logrus.DebugLevel:
+		return logs.DebugLevel
+	default:
+		return logs.TraceLevel
+	}
+}
+
+// Fields converts logrus fields, sorted by key since logrus keeps them in
+// a map. Errors become error fields.
+func Fields(data logrus.Fields) []logs.Field {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	fields := make([]logs.Field, len(keys))
+	for i, k := range keys {
+		if err, ok := data[k].(error); ok {
+			fields[i] = logs.NamedErr(k, err)
+		} else {
+			fields[i] = logs.Any(k, data[k])
+		}
+	}
+	return fields
+}
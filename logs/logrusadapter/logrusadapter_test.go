@@ -0,0 +1,54 @@
+package logrusadapter_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/logs/logrusadapter"
+)
+
+func TestHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(&logs.Options{Output: &buf, Formatter: &logs.JSONFormatter{}})
+
+	lr := logrus.New()
+	lr.SetOutput(io.Discard)
+	lr.SetLevel(logrus.TraceLevel)
+	lr.AddHook(logrusadapter.NewHook(logger))
+
+	ctx := logs.WithContextFields(context.Background(), logs.String("request_id", "r-1"))
+	lr.WithContext(ctx).WithFields(logrus.Fields{
+		"user":  "ann",
+		"count": 3,
+	}).WithError(errors.New("boom")).Warn("save failed")
+	lr.Debug("below the logger's level")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if got["msg"] != "save failed" || got["level"] != "warn" || got["user"] != "ann" ||
+		got["count"] != float64(3) || got["error"] != "boom" || got["request_id"] != "r-1" {
+		t.Errorf("unexpected entry: %v", got)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for l, want := range map[logrus.Level]logs.Level{
+		logrus.PanicLevel: logs.PanicLevel,
+		logrus.ErrorLevel: logs.ErrorLevel,
+		logrus.InfoLevel:  logs.InfoLevel,
+		logrus.TraceLevel: logs.TraceLevel,
+	} {
+		if got := logrusadapter.Level(l); got != want {
+			t.Errorf("Level(%v) = %v, want %v", l, got, want)
+		}
+	}
+}
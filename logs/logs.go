@@ -6,7 +6,8 @@
 //   - Type-safe field builders
 //   - Multiple output formats (text, JSON, pretty)
 //   - Named logger instances with automatic prefix display
-//   - Per-instance log level configuration
+//   - Per-instance log level configuration, and per-name levels set at
+//     runtime with SetLevelFor
 //   - Sampling for high-volume logs
 //   - Async logging option
 //   - Hook system for extensibility
@@ -34,31 +35,65 @@ package logs
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
-	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+	"github.com/kolosys/lumen/resource"
 )
 
 // Logger is the main logging interface.
 type Logger struct {
-	output      io.Writer
-	level       atomic.Int32
-	formatter   Formatter
-	hooks       []Hook
-	fields      []Field
-	callerDepth int
-	addCaller   bool
-	addStack    bool
-	async       bool
-	asyncCh     chan *Entry
-	asyncWg     sync.WaitGroup
-	mu          sync.RWMutex
-	entryPool   *sync.Pool
-	closed      atomic.Bool
-	sampler     Sampler
+	output     io.Writer
+	level      atomic.Int32
+	formatter  Formatter
+	hooks      []Hook
+	fields     []Field
+	callerSkip int
+	callerFmt  CallerFormat
+	addFunc    bool
+	addCaller  bool
+	stack      *StackOptions
+	exitFunc   func(code int)
+	root       *Logger
+	async      bool
+	asyncCh    chan *Entry
+	asyncWg    sync.WaitGroup
+	mu         sync.RWMutex
+	entryPool  *sync.Pool
+	closed     atomic.Bool
+	sampler    Sampler
+	ctxFields  func(ctx context.Context) []Field
+	redactor   *Redactor
+	processors []Processor
+	writeHooks []WriteHook
+	traceCorr  bool
+	errEncoder ErrorEncoder
+	addGoID    bool
+
+	// name is the logger's name, kept for resolving its level in the
+	// registry; levelCache caches the result (see effectiveLevel).
+	name       string
+	levelCache atomic.Uint64
+
+	// family is shared by the loggers derived from one New; familyGen is
+	// the generation of its settings l has adopted (see Reconfigure).
+	family    *family
+	familyGen atomic.Uint64
+
+	// recorder receives entries down to recordLevel-1, below the
+	// logger's own level; recordLevel is 0 when there is no recorder.
+	recorder    Hook
+	recordLevel atomic.Int32
+
+	// follow is set on loggers returned by GetNamed, which act through
+	// a child of the current default logger (see current).
+	follow *follower
 }
 
 // Options configures a Logger.
@@ -80,13 +115,33 @@ type Options struct {
 	AddCaller bool
 
 	// CallerDepth sets the caller stack depth.
-	// Default is 2.
+	// Default is 2, the code calling the logger. The call site is found
+	// past the logger's own frames, such as those of Builder or Infof;
+	// each level above 2 skips one more frame, as WithCallerSkip does.
 	CallerDepth int
 
+	// CallerFormat selects how much of the file path AddCaller records.
+	// Default is CallerShort.
+	CallerFormat CallerFormat
+
+	// AddFunction records the calling function, as the func field, when
+	// AddCaller is set. Its package path is trimmed like the file's.
+	AddFunction bool
+
 	// AddStack enables stack traces for error and above.
 	// Default is false.
 	AddStack bool
 
+	// ExitFunc is called by Fatal and the other fatal-level methods after
+	// logging, in place of os.Exit, e.g. to test fatal paths. Exit
+	// handlers run before it.
+	// Default is os.Exit.
+	ExitFunc func(code int)
+
+	// Stack configures stack traces, and enables them even if AddStack is
+	// not set. See StackOptions.
+	Stack *StackOptions
+
 	// AsyncBufferSize enables asynchronous logging with the specified buffer size.
 	// If 0, synchronous logging is used.
 	// If > 0, async logging is enabled with the specified buffer size.
@@ -99,8 +154,51 @@ type Options struct {
 	// Fields are default fields to include in all log entries.
 	Fields []Field
 
+	// Resource adds the service's identity (service, version, env and
+	// service_instance) to all log entries. Fields with the same keys win.
+	Resource *resource.Resource
+
+	// AddGoroutineID adds the ID of the logging goroutine, as the
+	// goroutine field, to all log entries, or for entries logged with a
+	// context, the label set with GoroutineLabel if there is one.
+	AddGoroutineID bool
+
+	// IncludeHostInfo adds the hostname and process ID, as the host and
+	// pid fields, to all log entries. They are looked up once, in New.
+	// Fields with the same keys win.
+	IncludeHostInfo bool
+
 	// Sampler is used for rate limiting logs.
 	Sampler Sampler
+
+	// ContextFields, if set, returns fields to add to entries logged with
+	// a context, e.g. the trace and span IDs of the active span. Like
+	// call-site fields, they replace fields added by With under the same
+	// keys, so the context's current values win.
+	ContextFields func(ctx context.Context) []Field
+
+	// WithTraceCorrelation adds the trace_id and span_id of the active
+	// trace.Span to entries logged with a context, e.g. via InfoContext,
+	// linking them to the span.
+	WithTraceCorrelation bool
+
+	// Processors transform or drop entries before they reach hooks, the
+	// recorder or the formatter. See Processor.
+	Processors []Processor
+
+	// Redactor, if set, masks sensitive data in every entry before it
+	// reaches hooks, the recorder or the formatter. It runs after the
+	// Processors, so fields they add are redacted too.
+	Redactor *Redactor
+
+	// WriteHooks run on the formatted bytes of each entry before they are
+	// written, in order. See WriteHook.
+	WriteHooks []WriteHook
+
+	// ErrorEncoder, if set, writes error fields as objects rather than
+	// their message, e.g. RichErrors. It applies to the output only;
+	// hooks still see the error fields.
+	ErrorEncoder ErrorEncoder
 }
 
 // applyDefaults applies default values to nil or zero-valued options.
@@ -119,6 +217,61 @@ func (o *Options) applyDefaults() {
 	// We'll handle this in the New function
 }
 
+// defaultFields prepends the identity of opts.Resource and, if
+// opts.IncludeHostInfo is set, the host and pid to opts.Fields.
+func defaultFields(opts *Options) []Field {
+	fields := opts.Fields
+	if opts.Resource == nil && !opts.IncludeHostInfo {
+		return fields
+	}
+	out := make([]Field, 0, len(fields)+6)
+	add := func(f Field) {
+		if !slices.ContainsFunc(fields, func(g Field) bool { return g.Key == f.Key }) {
+			out = append(out, f)
+		}
+	}
+	if opts.Resource != nil {
+		for _, kv := range opts.Resource.Identity() {
+			add(String(kv.Key, kv.Value))
+		}
+	}
+	if opts.IncludeHostInfo {
+		if host, err := os.Hostname(); err == nil {
+			add(String(HostKey, host))
+		}
+		add(Int(PIDKey, os.Getpid()))
+	}
+	return append(out, fields...)
+}
+
+// Keys of the fields added by Options.IncludeHostInfo.
+const (
+	HostKey = "host"
+	PIDKey  = "pid"
+)
+
+// WithServiceInfo returns service, version and env fields, under the keys
+// Options.Resource uses, for Options.Fields or Logger.With when there is
+// no Resource. Empty values are left out.
+//
+//	logs.New(&logs.Options{
+//		IncludeHostInfo: true,
+//		Fields:          logs.WithServiceInfo("checkout", "1.4.2", "prod"),
+//	})
+func WithServiceInfo(name, version, env string) []Field {
+	var fields []Field
+	for _, kv := range [...][2]string{
+		{resource.ServiceLabel, name},
+		{resource.VersionLabel, version},
+		{resource.EnvironmentLabel, env},
+	} {
+		if kv[1] != "" {
+			fields = append(fields, String(kv[0], kv[1]))
+		}
+	}
+	return fields
+}
+
 // New creates a new Logger with the provided options.
 // If opts is nil, default options will be used.
 //
@@ -135,14 +288,24 @@ func New(opts *Options) *Logger {
 	opts.applyDefaults()
 
 	l := &Logger{
-		output:      opts.Output,
-		formatter:   opts.Formatter,
-		callerDepth: opts.CallerDepth,
-		addCaller:   opts.AddCaller,
-		addStack:    opts.AddStack,
-		hooks:       opts.Hooks,
-		fields:      opts.Fields,
-		sampler:     opts.Sampler,
+		output:     opts.Output,
+		formatter:  opts.Formatter,
+		callerSkip: max(opts.CallerDepth-2, 0),
+		callerFmt:  opts.CallerFormat,
+		addFunc:    opts.AddFunction,
+		addCaller:  opts.AddCaller,
+		stack:      stackOptions(opts),
+		exitFunc:   opts.ExitFunc,
+		hooks:      opts.Hooks,
+		fields:     defaultFields(opts),
+		sampler:    opts.Sampler,
+		ctxFields:  opts.ContextFields,
+		redactor:   opts.Redactor,
+		processors: opts.Processors,
+		writeHooks: opts.WriteHooks,
+		traceCorr:  opts.WithTraceCorrelation,
+		errEncoder: opts.ErrorEncoder,
+		addGoID:    opts.AddGoroutineID,
 		entryPool: &sync.Pool{
 			New: func() any {
 				return &Entry{
@@ -152,6 +315,10 @@ func New(opts *Options) *Logger {
 		},
 	}
 
+	l.name = l.getName()
+	l.family = &family{output: l.output, formatter: l.formatter}
+	l.root = l
+
 	// Set level (default to InfoLevel if not specified)
 	if opts.Level == 0 {
 		l.level.Store(int32(InfoLevel))
@@ -184,18 +351,23 @@ func NewNamed(name string) *Logger {
 	return New(nil).Named(name)
 }
 
-// SetLevel sets the minimum log level.
+// SetLevel sets the minimum log level. A level set for the logger's name
+// with SetLevelFor takes precedence.
 func (l *Logger) SetLevel(level Level) {
+	l = l.current()
 	l.level.Store(int32(level))
 }
 
-// GetLevel returns the current log level.
+// GetLevel returns the current log level, which is the level set for the
+// logger's name with SetLevelFor if any applies.
 func (l *Logger) GetLevel() Level {
-	return Level(l.level.Load())
+	l = l.current()
+	return l.effectiveLevel()
 }
 
 // SetOutput sets the output writer.
 func (l *Logger) SetOutput(w io.Writer) {
+	l = l.current()
 	l.mu.Lock()
 	l.output = w
 	l.mu.Unlock()
@@ -203,6 +375,7 @@ func (l *Logger) SetOutput(w io.Writer) {
 
 // SetFormatter sets the formatter.
 func (l *Logger) SetFormatter(f Formatter) {
+	l = l.current()
 	l.mu.Lock()
 	l.formatter = f
 	l.mu.Unlock()
@@ -210,34 +383,108 @@ func (l *Logger) SetFormatter(f Formatter) {
 
 // AddHook adds a hook to the logger.
 func (l *Logger) AddHook(hook Hook) {
+	l = l.current()
 	l.mu.Lock()
 	l.hooks = append(l.hooks, hook)
 	l.mu.Unlock()
 }
 
+// AddProcessor appends a processor to the logger's chain. Loggers
+// created from l afterwards inherit it.
+func (l *Logger) AddProcessor(p Processor) {
+	l = l.current()
+	l.mu.Lock()
+	l.processors = append(l.processors[:len(l.processors):len(l.processors)], p)
+	l.mu.Unlock()
+}
+
+// AddWriteHook appends a hook run on the formatted bytes of each entry.
+// Loggers created from l afterwards inherit it.
+func (l *Logger) AddWriteHook(h WriteHook) {
+	l = l.current()
+	l.mu.Lock()
+	l.writeHooks = append(l.writeHooks[:len(l.writeHooks):len(l.writeHooks)], h)
+	l.mu.Unlock()
+}
+
+// SetRedactor sets the Redactor applied to entries, or removes it if r
+// is nil. Loggers created from l afterwards inherit it.
+func (l *Logger) SetRedactor(r *Redactor) {
+	l = l.current()
+	l.mu.Lock()
+	l.redactor = r
+	l.mu.Unlock()
+}
+
+// SetRecorder sets a hook that receives every entry at level or above,
+// even those below the logger's level, which reach no other hook or the
+// output. It is meant for keeping recent verbose entries in memory, e.g.
+// Debug entries for a crash dump, without printing them. Entries are
+// passed to the recorder before sampling. Loggers created from l
+// afterwards inherit the recorder. Pass a nil hook to remove it.
+func (l *Logger) SetRecorder(hook Hook, level Level) {
+	l = l.current()
+	l.mu.Lock()
+	l.recorder = hook
+	l.mu.Unlock()
+	if hook == nil {
+		l.recordLevel.Store(0)
+	} else {
+		l.recordLevel.Store(int32(level) + 1)
+	}
+}
+
+// recording reports whether entries at level go to the recorder.
+func (l *Logger) recording(level Level) bool {
+	return int32(level) < l.recordLevel.Load()
+}
+
 // With creates a child logger with additional fields.
 func (l *Logger) With(fields ...Field) *Logger {
+	l = l.current()
 	child := &Logger{
-		output:      l.output,
-		formatter:   l.formatter,
-		hooks:       l.hooks,
-		callerDepth: l.callerDepth,
-		addCaller:   l.addCaller,
-		addStack:    l.addStack,
-		async:       l.async,
-		asyncCh:     l.asyncCh,
-		entryPool:   l.entryPool,
-		sampler:     l.sampler,
-		fields:      make([]Field, 0, len(l.fields)+len(fields)),
+		output:     l.output,
+		formatter:  l.formatter,
+		hooks:      l.hooks,
+		callerSkip: l.callerSkip,
+		callerFmt:  l.callerFmt,
+		addFunc:    l.addFunc,
+		addCaller:  l.addCaller,
+		stack:      l.stack,
+		exitFunc:   l.exitFunc,
+		async:      l.async,
+		asyncCh:    l.asyncCh,
+		entryPool:  l.entryPool,
+		sampler:    l.sampler,
+		ctxFields:  l.ctxFields,
+		redactor:   l.redactor,
+		processors: l.processors,
+		writeHooks: l.writeHooks,
+		traceCorr:  l.traceCorr,
+		errEncoder: l.errEncoder,
+		addGoID:    l.addGoID,
+		name:       l.name,
+		family:     l.family,
+		root:       l.root,
+		fields:     make([]Field, 0, len(l.fields)+len(fields)),
 	}
 	child.level.Store(l.level.Load())
+	child.familyGen.Store(l.familyGen.Load())
+	child.recorder = l.recorder
+	child.recordLevel.Store(l.recordLevel.Load())
 	child.fields = append(child.fields, l.fields...)
 	child.fields = append(child.fields, fields...)
 	return child
 }
 
-// Close closes the logger and flushes any pending async logs.
+// Close closes the logger and flushes any pending async logs. Loggers
+// derived with With or Named share the logger's pipeline, so closing one
+// closes the logger they were derived from.
 func (l *Logger) Close() error {
+	l = l.current()
+	if l.root != nil && l.root != l {
+		return l.root.Close()
+	}
 	if l.closed.CompareAndSwap(false, true) {
 		if l.async && l.asyncCh != nil {
 			close(l.asyncCh)
@@ -263,6 +510,7 @@ func (l *Logger) getEntry() *Entry {
 	e.Fields = e.Fields[:0]
 	e.Caller = ""
 	e.Stack = ""
+	e.Frames = nil
 	return e
 }
 
@@ -271,18 +519,32 @@ func (l *Logger) releaseEntry(e *Entry) {
 	e.Message = ""
 	e.Caller = ""
 	e.Stack = ""
+	e.Frames = nil
 	e.Fields = e.Fields[:0]
 	l.entryPool.Put(e)
 }
 
 // log logs a message at the given level.
-func (l *Logger) log(level Level, msg string, fields []Field) {
-	if Level(l.level.Load()) < level {
+func (l *Logger) log(ctx context.Context, level Level, msg string, fields []Field) {
+	l = l.current()
+	if l.effectiveLevel() < level {
+		if l.recording(level) {
+			l.record(level, msg, fields)
+		}
 		return
 	}
+	l.emit(ctx, level, msg, fields)
+}
 
+// emit samples, builds and writes an entry regardless of the logger's
+// level. It must be called directly by log or another function called
+// directly by the public logging method, for the caller to be right.
+func (l *Logger) emit(ctx context.Context, level Level, msg string, fields []Field) {
 	// Check sampler
-	if l.sampler != nil && !l.sampler.Sample(level, msg) {
+	if l.sampler != nil && !sample(l.sampler, ctx, level, msg) {
+		if l.recording(level) {
+			l.record(level, msg, fields)
+		}
 		return
 	}
 
@@ -290,124 +552,229 @@ func (l *Logger) log(level Level, msg string, fields []Field) {
 	e.Level = level
 	e.Message = msg
 
-	// Add default fields
-	e.Fields = append(e.Fields, l.fields...)
-	// Add call-site fields
-	e.Fields = append(e.Fields, fields...)
+	// Add default fields, then call-site fields, which replace them
+	e.Fields = appendFields(e.Fields, l.fields, fields)
+
+	if l.addGoID {
+		e.Fields = append(e.Fields, goroutineField(ctx))
+	}
 
 	// Add caller info
 	if l.addCaller {
-		e.Caller = getCaller(l.callerDepth + 1)
+		var fn string
+		e.Caller, fn = l.caller()
+		if l.addFunc && fn != "" {
+			e.Fields = append(e.Fields, String(FunctionKey, fn))
+		}
 	}
 
 	// Add stack trace for errors
-	if l.addStack && level <= ErrorLevel {
-		e.Stack = getStack()
+	if l.stack != nil && level <= l.stack.Level {
+		e.Frames = captureStack(l.stack)
+		e.Stack = formatStack(e.Frames)
 	}
 
-	// Run hooks
 	l.mu.RLock()
+	// Processors may drop the entry or replace it; e stays the pooled one.
+	entry := l.process(e)
+	if entry == nil {
+		l.mu.RUnlock()
+		l.releaseEntry(e)
+		return
+	}
+
+	// Run hooks
 	for _, hook := range l.hooks {
 		levels := hook.Levels()
 		if len(levels) == 0 {
 			// Fire for all levels
-			hook.Fire(e)
+			fireHook(ctx, hook, entry)
 		} else {
 			// Check if level matches
 			for _, lvl := range levels {
-				if lvl == level {
-					hook.Fire(e)
+				if lvl == entry.Level {
+					fireHook(ctx, hook, entry)
 					break
 				}
 			}
 		}
 	}
+	if l.recorder != nil && l.recording(entry.Level) {
+		l.recorder.Fire(entry)
+	}
 	l.mu.RUnlock()
 
-	if l.async && l.asyncCh != nil && !l.closed.Load() {
+	if l.async && l.asyncCh != nil && !l.root.closed.Load() {
 		// Clone entry for async processing
 		clone := l.getEntry()
-		*clone = *e
-		clone.Fields = make([]Field, len(e.Fields))
-		copy(clone.Fields, e.Fields)
+		*clone = *entry
+		clone.Fields = make([]Field, len(entry.Fields))
+		copy(clone.Fields, entry.Fields)
 
 		select {
 		case l.asyncCh <- clone:
 		default:
 			// Channel full, write synchronously
-			l.writeEntry(e)
+			l.writeEntry(entry)
 		}
 		l.releaseEntry(e)
 	} else {
-		l.writeEntry(e)
+		l.writeEntry(entry)
 		l.releaseEntry(e)
 	}
 }
 
+// record passes an entry that is not otherwise logged to the recorder.
+func (l *Logger) record(level Level, msg string, fields []Field) {
+	e := l.getEntry()
+	e.Level = level
+	e.Message = msg
+	e.Fields = appendFields(e.Fields, l.fields, fields)
+
+	l.mu.RLock()
+	if l.recorder != nil {
+		if entry := l.process(e); entry != nil {
+			l.recorder.Fire(entry)
+		}
+	}
+	l.mu.RUnlock()
+	l.releaseEntry(e)
+}
+
+// appendFields appends the logger's fields, except those whose keys are
+// set again at the call site, followed by the call-site fields. Only
+// fields at the same namespace level replace each other: the logger's
+// fields after its last namespace, and call-site fields before their
+// first.
+func appendFields(dst, defaults, fields []Field) []Field {
+	if len(fields) == 0 {
+		return append(dst, defaults...)
+	}
+	overrides := fields
+	if i := slices.IndexFunc(fields, isNamespace); i >= 0 {
+		overrides = fields[:i]
+	}
+	scope := 0
+	for i, d := range defaults {
+		if isNamespace(d) {
+			scope = i + 1
+		}
+	}
+	dst = append(dst, defaults[:scope]...)
+	for _, d := range defaults[scope:] {
+		if !slices.ContainsFunc(overrides, func(f Field) bool { return f.Key == d.Key }) {
+			dst = append(dst, d)
+		}
+	}
+	return append(dst, fields...)
+}
+
+func isNamespace(f Field) bool { return f.Type == FieldTypeNamespace }
+
 // logContext logs with context.
 func (l *Logger) logContext(ctx context.Context, level Level, msg string, fields []Field) {
+	l = l.current()
+	if l.effectiveLevel() < level && !l.recording(level) {
+		return
+	}
+
+	ctxFields := FieldsFromContext(ctx)
+	if l.ctxFields != nil {
+		if extra := l.ctxFields(ctx); len(extra) > 0 {
+			ctxFields = append(ctxFields[:len(ctxFields):len(ctxFields)], extra...)
+		}
+	}
+	if l.traceCorr {
+		ctxFields = appendSpanFields(ctx, ctxFields)
+	}
+
 	// Check if context has logger fields
-	if ctxFields := FieldsFromContext(ctx); len(ctxFields) > 0 {
+	if len(ctxFields) > 0 {
 		allFields := make([]Field, 0, len(ctxFields)+len(fields))
 		allFields = append(allFields, ctxFields...)
 		allFields = append(allFields, fields...)
-		l.log(level, msg, allFields)
+		l.log(ctx, level, msg, allFields)
 		return
 	}
-	l.log(level, msg, fields)
+	l.log(ctx, level, msg, fields)
 }
 
 // writeEntry formats and writes the entry.
 func (l *Logger) writeEntry(e *Entry) {
+	l.sync()
 	l.mu.RLock()
-	output := l.output
 	formatter := l.formatter
+	writeHooks := l.writeHooks
 	l.mu.RUnlock()
 
+	if l.errEncoder != nil {
+		e = encodeErrors(e, l.errEncoder)
+	}
+
 	data, err := formatter.Format(e)
 	if err != nil {
+		report.Error(report.Logs, fmt.Errorf("logs: format entry: %w", err))
 		return
 	}
-	output.Write(data)
+
+	// The output is read under the family's write lock, which
+	// Reconfigure waits on before closing an output it replaced.
+	l.family.writes.RLock()
+	l.sync()
+	l.mu.RLock()
+	output := l.output
+	l.mu.RUnlock()
+	for _, h := range writeHooks {
+		if data = h.OnWrite(e, data, output); data == nil {
+			l.family.writes.RUnlock()
+			return
+		}
+	}
+	if lw, ok := output.(LevelWriter); ok {
+		_, err = lw.WriteLevel(e.Level, data)
+	} else {
+		_, err = output.Write(data)
+	}
+	l.family.writes.RUnlock()
+	if err != nil {
+		report.Error(report.Logs, fmt.Errorf("logs: write entry: %w", err))
+	}
 }
 
 // Trace logs at trace level.
 func (l *Logger) Trace(msg string, fields ...Field) {
-	l.log(TraceLevel, msg, fields)
+	l.log(context.Background(), TraceLevel, msg, fields)
 }
 
 // Debug logs at debug level.
 func (l *Logger) Debug(msg string, fields ...Field) {
-	l.log(DebugLevel, msg, fields)
+	l.log(context.Background(), DebugLevel, msg, fields)
 }
 
 // Info logs at info level.
 func (l *Logger) Info(msg string, fields ...Field) {
-	l.log(InfoLevel, msg, fields)
+	l.log(context.Background(), InfoLevel, msg, fields)
 }
 
 // Warn logs at warn level.
 func (l *Logger) Warn(msg string, fields ...Field) {
-	l.log(WarnLevel, msg, fields)
+	l.log(context.Background(), WarnLevel, msg, fields)
 }
 
 // Error logs at error level.
 func (l *Logger) Error(msg string, fields ...Field) {
-	l.log(ErrorLevel, msg, fields)
+	l.log(context.Background(), ErrorLevel, msg, fields)
 }
 
 // Fatal logs at fatal level and exits.
 func (l *Logger) Fatal(msg string, fields ...Field) {
-	l.log(FatalLevel, msg, fields)
-	if l.async {
-		l.Close()
-	}
-	os.Exit(1)
+	l.log(context.Background(), FatalLevel, msg, fields)
+	l.exit()
 }
 
 // Panic logs at panic level and panics.
 func (l *Logger) Panic(msg string, fields ...Field) {
-	l.log(PanicLevel, msg, fields)
+	l.log(context.Background(), PanicLevel, msg, fields)
 	panic(msg)
 }
 
@@ -438,7 +805,7 @@ func (l *Logger) ErrorContext(ctx context.Context, msg string, fields ...Field)
 
 // Log logs at a specific level.
 func (l *Logger) Log(level Level, msg string, fields ...Field) {
-	l.log(level, msg, fields)
+	l.log(context.Background(), level, msg, fields)
 }
 
 // LogContext logs at a specific level with context.
@@ -448,37 +815,8 @@ func (l *Logger) LogContext(ctx context.Context, level Level, msg string, fields
 
 // IsEnabled returns true if the given level is enabled.
 func (l *Logger) IsEnabled(level Level) bool {
-	return Level(l.level.Load()) >= level
-}
-
-// getCaller returns the caller's file and line.
-func getCaller(skip int) string {
-	_, file, line, ok := runtime.Caller(skip + 1)
-	if !ok {
-		return "unknown"
-	}
-
-	// Get just the filename
-	short := file
-	for i := len(file) - 1; i > 0; i-- {
-		if file[i] == '/' {
-			short = file[i+1:]
-			break
-		}
-	}
-
-	buf := make([]byte, 0, len(short)+12)
-	buf = append(buf, short...)
-	buf = append(buf, ':')
-	buf = appendInt(buf, line)
-	return string(buf)
-}
-
-// getStack returns a stack trace.
-func getStack() string {
-	buf := make([]byte, 4096)
-	n := runtime.Stack(buf, false)
-	return string(buf[:n])
+	l = l.current()
+	return l.effectiveLevel() >= level
 }
 
 // appendInt appends an int to a byte slice.
@@ -506,6 +844,7 @@ var defaultLogger = New(nil)
 // SetDefault sets the default logger.
 func SetDefault(l *Logger) {
 	defaultLogger = l
+	defaultGen.Add(1)
 }
 
 // Default returns the default logger.
@@ -2,16 +2,28 @@ package logs_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	. "github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
 )
 
 func TestNewLogger(t *testing.T) {
@@ -275,6 +287,29 @@ func TestContextLogging(t *testing.T) {
 	}
 }
 
+func TestCallSiteFieldsReplaceLoggerFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(&Options{
+		Output:    buf,
+		Formatter: &JSONFormatter{DisableTimestamp: true},
+		ContextFields: func(ctx context.Context) []Field {
+			return []Field{String("span_id", "child")}
+		},
+	}).With(String("span_id", "request"), String("user", "ann"))
+
+	log.InfoContext(context.Background(), "from context")
+	log.Info("call site", String("user", "bob"))
+	log.With(Namespace("db")).Info("namespaced", String("user", "carl"))
+
+	want := `{"level":"info","msg":"from context","user":"ann","span_id":"child"}
+{"level":"info","msg":"call site","span_id":"request","user":"bob"}
+{"level":"info","msg":"namespaced","span_id":"request","user":"ann","db":{"user":"carl"}}
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
 func TestLoggerFromContext(t *testing.T) {
 	buf := &bytes.Buffer{}
 	log := New(&Options{
@@ -517,6 +552,38 @@ func TestIsEnabled(t *testing.T) {
 	}
 }
 
+func TestRecorderSeesEntriesBelowLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(&Options{
+		Output:    buf,
+		Level:     InfoLevel,
+		Formatter: &TextFormatter{DisableTimestamp: true, DisableColors: true},
+	})
+	var recorded []string
+	log.SetRecorder(NewFuncHook(func(e *Entry) {
+		recorded = append(recorded, e.Level.String()+":"+e.Message)
+	}), DebugLevel)
+
+	child := log.Named("db")
+	log.Trace("too verbose")
+	log.Debug("cache miss")
+	child.DebugContext(context.Background(), "query plan")
+	log.Info("started")
+
+	if got := strings.Join(recorded, ","); got != "debug:cache miss,debug:query plan,info:started" {
+		t.Errorf("recorded = %s", got)
+	}
+	if strings.Contains(buf.String(), "cache miss") || !strings.Contains(buf.String(), "started") {
+		t.Errorf("output = %q", buf.String())
+	}
+
+	log.SetRecorder(nil, DebugLevel)
+	log.Debug("dropped")
+	if len(recorded) != 3 {
+		t.Errorf("recorder still called after removal: %v", recorded)
+	}
+}
+
 func TestDefaultLogger(t *testing.T) {
 	buf := &bytes.Buffer{}
 	log := New(&Options{
@@ -1185,3 +1252,1936 @@ func BenchmarkPrintf(b *testing.B) {
 		log.Infof("user %s with id %d", "john", 123)
 	}
 }
+
+func TestRingHook(t *testing.T) {
+	ring := NewRingHook(2)
+
+	log := New(&Options{
+		Output: &bytes.Buffer{},
+		Hooks:  []Hook{ring},
+	})
+
+	log.Info("one")
+	log.Info("two", String("k", "v"))
+	log.Info("three")
+
+	entries := ring.Entries()
+	if len(entries) != 2 || entries[0].Message != "two" || entries[1].Message != "three" {
+		t.Fatalf("entries = %v", entries)
+	}
+	if v, ok := entries[0].GetField("k"); !ok || v.StringValue() != "v" {
+		t.Errorf("field not retained: %v", entries[0].Fields)
+	}
+}
+
+func TestDecisionSampler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(&Options{
+		Output:    buf,
+		Level:     DebugLevel,
+		Formatter: &TextFormatter{DisableTimestamp: true, DisableColors: true},
+		Sampler:   NewCompositeSampler(NewDecisionSampler(InfoLevel, nil)),
+	})
+
+	sampled := WithSamplingDecision(context.Background(), true)
+	unsampled := WithSamplingDecision(context.Background(), false)
+	log.DebugContext(sampled, "sampled debug")
+	log.DebugContext(unsampled, "unsampled debug")
+	log.Debug("undecided debug")
+	log.InfoContext(unsampled, "unsampled info")
+
+	out := buf.String()
+	for msg, want := range map[string]bool{
+		"sampled debug":   true,
+		"unsampled debug": false,
+		"undecided debug": false,
+		"unsampled info":  true,
+	} {
+		if strings.Contains(out, msg) != want {
+			t.Errorf("%q logged = %v, want %v:\n%s", msg, !want, want, out)
+		}
+	}
+}
+
+func TestRotatingFileWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	old := filepath.Join(dir, "app-2020-01-01T00-00-00.000.log")
+	unrelated := filepath.Join(dir, "app-notes.log")
+	for _, p := range []string{old, unrelated} {
+		if err := os.WriteFile(p, []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := NewRotatingFileWriter(path, &RotatingFileOptions{MaxSize: 100, MaxBackups: 2, MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := New(&Options{Output: w, Formatter: &JSONFormatter{DisableTimestamp: true}})
+	for i := range 12 {
+		log.Info("entry", Int("i", i))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("late\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("write after close: %v", err)
+	}
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("backup older than MaxAge was kept")
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Error("unrelated file removed")
+	}
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-20*.log"))
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %v", backups)
+	}
+	var lines []string
+	for _, p := range append(backups, path) {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 100 {
+			t.Errorf("%s has %d bytes", p, len(data))
+		}
+		lines = append(lines, strings.Split(strings.TrimSpace(string(data)), "\n")...)
+	}
+	// Glob sorts backups oldest first, so the newest entries are last.
+	if last := lines[len(lines)-1]; !strings.Contains(last, `"i":11`) {
+		t.Errorf("last entry = %s", last)
+	}
+}
+
+func TestTimedRotatingWriter(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	expired := filepath.Join(dir, "app-2020-01-01.log")
+	yesterday := filepath.Join(dir, "app-"+now.AddDate(0, 0, -1).Format("2006-01-02")+".log")
+	unrelated := filepath.Join(dir, "app-notes.log")
+	for _, p := range []string{expired, yesterday, unrelated} {
+		if err := os.WriteFile(p, []byte("left by an earlier run\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := NewTimedRotatingWriter(filepath.Join(dir, "app.log"), &TimedRotatingOptions{
+		MaxAge:   30 * 24 * time.Hour,
+		Compress: true,
+		UTC:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := w.Name()
+	if want := filepath.Join(dir, "app-"+now.Format("2006-01-02")+".log"); today != want {
+		t.Errorf("Name() = %s, want %s", today, want)
+	}
+	New(&Options{Output: w, Formatter: &JSONFormatter{}}).Info("today")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(today); !strings.Contains(string(data), `"msg":"today"`) {
+		t.Errorf("today's file = %q", data)
+	}
+	for _, p := range []string{expired, yesterday} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s was kept uncompressed", p)
+		}
+	}
+	if _, err := os.Stat(expired + ".gz"); !os.IsNotExist(err) {
+		t.Error("file past the retention window was compressed instead of removed")
+	}
+	f, err := os.Open(yesterday + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != "left by an earlier run\n" {
+		t.Errorf("decompressed %q", data)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Error("unrelated file removed")
+	}
+}
+
+func TestSyslogHook(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	hook, err := NewSyslogHook("udp", pc.LocalAddr().String(), FacilityLocal0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close()
+	hook.SetAppName("checkout")
+	log := New(&Options{Output: io.Discard, Hooks: []Hook{hook}})
+
+	read := func() string {
+		t.Helper()
+		buf := make([]byte, 2048)
+		pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf[:n])
+	}
+
+	log.Error("upstream failed", Int("status", 503), String("note", `say "hi"]`))
+	msg := read()
+	if !strings.HasPrefix(msg, "<131>1 ") || !strings.Contains(msg, " checkout ") {
+		t.Errorf("header: %s", msg)
+	}
+	if !strings.HasSuffix(msg, `[lumen@32473 status="503" note="say \"hi\"\]"] upstream failed`) {
+		t.Errorf("structured data: %s", msg)
+	}
+
+	hook.SetFormat(SyslogRFC3164)
+	log.Info("started", String("addr", ":8080"))
+	if msg := read(); !strings.HasPrefix(msg, "<134>") || !strings.HasSuffix(msg, "checkout["+fmt.Sprint(os.Getpid())+"]: started addr=:8080") {
+		t.Errorf("rfc 3164: %s", msg)
+	}
+}
+
+func TestSyslogHookReconnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 16)
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 2048)
+			n, _ := conn.Read(buf)
+			received <- fmt.Sprintf("conn %d: %s", i, buf[:n])
+			if i == 0 {
+				conn.Close() // the daemon restarts
+				continue
+			}
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+
+	hook, err := NewSyslogHook("tcp", ln.Addr().String(), FacilityDaemon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close()
+	log := New(&Options{Output: io.Discard, Hooks: []Hook{hook}})
+
+	log.Info("first")
+	if got := <-received; !strings.HasPrefix(got, "conn 0: ") || !strings.HasSuffix(got, " first") {
+		t.Fatalf("first message: %q", got)
+	}
+	// Writes to the closed connection may succeed until the reset arrives.
+	deadline := time.After(5 * time.Second)
+	for {
+		log.Info("again")
+		select {
+		case got := <-received:
+			if !strings.HasPrefix(got, "conn 1: ") {
+				t.Fatalf("unexpected %q", got)
+			}
+			return
+		case <-deadline:
+			t.Fatal("hook did not reconnect")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestDatadogFormatter(t *testing.T) {
+	f := &DatadogFormatter{Service: "checkout", Env: "prod", Version: "1.2.3"}
+	data, err := f.Format(&Entry{
+		Level:   FatalLevel,
+		Time:    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Message: "query failed",
+		Stack:   "goroutine 1 [running]:",
+		Fields: []Field{
+			String("_logger", "db"),
+			String(TraceIDKey, "4bf92f3577b34da6a3ce929d0e0e4736"),
+			String(SpanIDKey, "00f067aa0ba902b7"),
+			Err(errors.New("timeout")),
+			Int("rows", 0),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("%v: %s", err, data)
+	}
+	want := map[string]any{
+		"status":        "critical",
+		"timestamp":     "2024-05-01T10:00:00Z",
+		"message":       "query failed",
+		"logger.name":   "db",
+		"dd.trace_id":   "11803532876627986230",
+		"dd.span_id":    "67667974448284343",
+		"dd.service":    "checkout",
+		"dd.env":        "prod",
+		"dd.version":    "1.2.3",
+		"error.message": "timeout",
+		"error.kind":    "*errors.errorString",
+		"error.stack":   "goroutine 1 [running]:",
+		"rows":          0.0,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected keys: %v", got)
+	}
+
+	data, _ = f.Format(&Entry{Level: TraceLevel, Fields: []Field{String(TraceIDKey, "not-hex")}})
+	if !strings.Contains(string(data), `"status":"debug"`) || !strings.Contains(string(data), `"trace_id":"not-hex"`) {
+		t.Errorf("unconvertible id: %s", data)
+	}
+}
+
+func TestHTTPHook(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	var failures int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("X-Api-Key") != "secret" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("headers: %v", r.Header)
+		}
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		batches = append(batches, strings.Split(strings.TrimSpace(string(body)), "\n"))
+	}))
+	defer srv.Close()
+
+	hook := NewHTTPHook(srv.URL, &HTTPHookOptions{
+		Headers:       map[string]string{"X-Api-Key": "secret"},
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+		Backoff:       time.Millisecond,
+	})
+	logger := New(&Options{Output: io.Discard, Hooks: []Hook{hook}})
+	for i := range 7 {
+		logger.Info("entry", Int("n", i))
+	}
+	hook.Flush()
+
+	mu.Lock()
+	if len(batches) != 3 || len(batches[0]) != 3 || len(batches[1]) != 3 || len(batches[2]) != 1 {
+		t.Fatalf("batches: %q", batches)
+	}
+	if !strings.Contains(batches[2][0], `"n":6`) {
+		t.Errorf("last entry: %s", batches[2][0])
+	}
+	batches, failures = nil, 1
+	mu.Unlock()
+
+	// Close sends what is queued but does not retry.
+	logger.Info("unsent")
+	if err := hook.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if hook.Dropped() != 1 || len(batches) != 0 {
+		t.Errorf("dropped = %d, batches = %q", hook.Dropped(), batches)
+	}
+}
+
+func TestHTTPHookRetries(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	status := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK, http.StatusBadRequest}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(status[0])
+		status = status[1:]
+	}))
+	defer srv.Close()
+
+	var errs []error
+	hook := NewHTTPHook(srv.URL, &HTTPHookOptions{
+		Formatter: &TextFormatter{DisableTimestamp: true},
+		Backoff:   time.Millisecond,
+		OnError:   func(err error) { errs = append(errs, err) },
+	})
+	defer hook.Close()
+	logger := New(&Options{Output: io.Discard, Hooks: []Hook{hook}})
+
+	logger.Warn("flaky")
+	hook.Flush()
+	if len(bodies) != 3 || hook.Dropped() != 0 || len(errs) != 0 {
+		t.Fatalf("bodies = %q, dropped = %d, errs = %v", bodies, hook.Dropped(), errs)
+	}
+
+	// Client errors are not retried.
+	logger.Warn("rejected")
+	hook.Flush()
+	if len(bodies) != 4 || hook.Dropped() != 1 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "400") {
+		t.Errorf("bodies = %q, dropped = %d, errs = %v", bodies, hook.Dropped(), errs)
+	}
+}
+
+func TestHTTPHookQueueFull(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var errs []error
+	hook := NewHTTPHook(srv.URL, &HTTPHookOptions{
+		BatchSize: 1,
+		QueueSize: 2,
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+	logger := New(&Options{Output: io.Discard, Hooks: []Hook{hook}})
+
+	logger.Info("sending")
+	<-started
+	for range 5 {
+		logger.Info("queued")
+	}
+	close(release)
+	hook.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hook.Dropped() != 3 || len(errs) != 3 || !errors.Is(errs[0], ErrHTTPHookQueueFull) {
+		t.Errorf("dropped = %d, errs = %v", hook.Dropped(), errs)
+	}
+}
+
+func TestAlertHook(t *testing.T) {
+	posts := make(chan map[string]any, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		posts <- msg
+	}))
+	defer srv.Close()
+
+	next := func() map[string]any {
+		select {
+		case msg := <-posts:
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatal("no post")
+			return nil
+		}
+	}
+
+	hook := NewSlackHook(srv.URL, &AlertOptions{Interval: 200 * time.Millisecond})
+	logger := New(&Options{Output: io.Discard, Hooks: []Hook{hook}}).Named("payments")
+
+	logger.Warn("ignored")
+	logger.Error("charge failed", String("order", "o-1"))
+	msg := next()
+	if msg["text"] != "[error] charge failed" {
+		t.Errorf("text = %v", msg["text"])
+	}
+	att := msg["attachments"].([]any)[0].(map[string]any)
+	if att["footer"] != "payments" || att["color"] != "#e01e5a" {
+		t.Errorf("attachment = %v", att)
+	}
+	if f := att["fields"].([]any)[0].(map[string]any); f["title"] != "order" || f["value"] != "o-1" {
+		t.Errorf("field = %v", f)
+	}
+
+	// An error storm within the interval is coalesced into one post.
+	for range 5 {
+		logger.Error("charge failed", String("order", "o-2"))
+	}
+	logger.Error("refund failed")
+	msg = next()
+	if msg["text"] != "6 alerts" {
+		t.Errorf("text = %v", msg["text"])
+	}
+	atts := msg["attachments"].([]any)
+	if len(atts) != 2 || atts[0].(map[string]any)["title"] != "[error] charge failed (×5)" {
+		t.Errorf("attachments = %v", atts)
+	}
+
+	hook.Fire(&Entry{Level: FatalLevel, Message: "unreachable"})
+	hook.Close()
+	if msg := next(); msg["attachments"].([]any)[0].(map[string]any)["color"] != "#8b0000" {
+		t.Errorf("close did not post pending alert: %v", msg)
+	}
+	select {
+	case msg := <-posts:
+		t.Errorf("unexpected post: %v", msg)
+	default:
+	}
+}
+
+func TestDiscordHook(t *testing.T) {
+	posts := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]any
+		json.NewDecoder(r.Body).Decode(&msg)
+		posts <- msg
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var errs []error
+	hook := NewDiscordHook(srv.URL, &AlertOptions{
+		Levels:    []Level{WarnLevel},
+		MaxAlerts: 1,
+		Username:  "lumen",
+		OnError:   func(err error) { errs = append(errs, err) },
+	})
+	hook.Fire(&Entry{Level: WarnLevel, Message: "disk at 91%", Fields: []Field{String("mount", "")}})
+	hook.Fire(&Entry{Level: WarnLevel, Message: "disk at 92%"})
+	hook.Close()
+
+	msg := <-posts
+	embed := msg["embeds"].([]any)[0].(map[string]any)
+	field := embed["fields"].([]any)[0].(map[string]any)
+	if msg["username"] != "lumen" || embed["title"] != "[warn] disk at 91%" || field["value"] != "\u200b" {
+		t.Errorf("message = %v", msg)
+	}
+	if msg["content"] != "2 alerts, 1 not listed" || len(errs) != 0 {
+		t.Errorf("content = %v, errs = %v", msg["content"], errs)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	var buf bytes.Buffer
+	ring := NewRingHook(10)
+	redactor := NewRedactor("password", "*token*").
+		ScrubCreditCards().
+		ScrubEmails().
+		WithFunc(func(f Field) Field {
+			if f.Key == "user" {
+				return String(f.Key, "u-***")
+			}
+			return f
+		})
+	logger := New(&Options{
+		Output:    &buf,
+		Formatter: &JSONFormatter{DisableTimestamp: true},
+		Hooks:     []Hook{ring},
+		Fields:    []Field{String("API_Token", "abc")},
+		Redactor:  redactor,
+	}).Named("billing")
+
+	logger.Info("charged jane@example.com",
+		String("password", "hunter2"),
+		String("card", "4111 1111 1111 1111"),
+		String("order", "1234567890123456"),
+		Err(errors.New("declined for 4111-1111-1111-1111")),
+		Any("contact", map[string]string{"email": "bob@example.org"}),
+		String("user", "u-42"),
+		Int("amount", 1299),
+	)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("%v: %s", err, buf.Bytes())
+	}
+	want := map[string]any{
+		"msg":       "charged [EMAIL]",
+		"API_Token": RedactedValue,
+		"password":  RedactedValue,
+		"card":      "[CARD ****1111]",
+		"order":     "1234567890123456",
+		"error":     "declined for [CARD ****1111]",
+		"contact":   "map[email:[EMAIL]]",
+		"user":      "u-***",
+		"amount":    1299.0,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+	if s := buf.String(); strings.Contains(s, "hunter2") || strings.Contains(s, "@example") {
+		t.Errorf("unredacted output: %s", s)
+	}
+
+	// Hooks see the redacted entry.
+	entries := ring.Entries()
+	if len(entries) != 1 || entries[0].GetString("password") != RedactedValue {
+		t.Errorf("hook entries: %+v", entries)
+	}
+
+	buf.Reset()
+	logger.SetRedactor(nil)
+	logger.Info("plain", String("password", "hunter2"))
+	if !strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("redactor not removed: %s", buf.String())
+	}
+}
+
+func TestProcessors(t *testing.T) {
+	var buf bytes.Buffer
+	ring := NewRingHook(10, ErrorLevel)
+	var order []string
+	logger := New(&Options{
+		Output:    &buf,
+		Formatter: &JSONFormatter{DisableTimestamp: true},
+		Hooks:     []Hook{ring},
+		Processors: []Processor{
+			ProcessorFunc(func(e *Entry) *Entry {
+				order = append(order, "drop")
+				if e.Message == "health check" {
+					return nil
+				}
+				return e
+			}),
+			ProcessorFunc(func(e *Entry) *Entry {
+				order = append(order, "enrich")
+				e.Fields = append(e.Fields, String("region", "eu-west-1"), String("session_token", "t0k"))
+				return e
+			}),
+		},
+		Redactor: NewRedactor(),
+	})
+
+	logger.Info("health check")
+	if buf.Len() != 0 || len(order) != 1 {
+		t.Fatalf("dropped entry logged: %q, order %v", buf.String(), order)
+	}
+
+	// Rewriting the level changes which hooks fire.
+	logger.AddProcessor(ProcessorFunc(func(e *Entry) *Entry {
+		order = append(order, "escalate")
+		if e.Message == "disk full" {
+			e.Level = ErrorLevel
+		}
+		return e
+	}))
+	logger.Warn("disk full")
+	if want := []string{"drop", "drop", "enrich", "escalate"}; fmt.Sprint(order) != fmt.Sprint(want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"level":"error"`) || !strings.Contains(out, `"region":"eu-west-1"`) ||
+		!strings.Contains(out, `"session_token":"[REDACTED]"`) {
+		t.Errorf("output: %s", out)
+	}
+	if entries := ring.Entries(); len(entries) != 1 || entries[0].GetString("session_token") != RedactedValue {
+		t.Errorf("hook entries: %+v", entries)
+	}
+
+	// A processor may return an entry of its own.
+	buf.Reset()
+	replaced := New(&Options{
+		Output:    &buf,
+		Formatter: &JSONFormatter{DisableTimestamp: true},
+		Processors: []Processor{ProcessorFunc(func(e *Entry) *Entry {
+			return &Entry{Level: e.Level, Time: e.Time, Message: "sampled: " + e.Message}
+		})},
+	})
+	replaced.With(String("k", "v")).Info("event")
+	if !strings.Contains(buf.String(), `"msg":"sampled: event"`) || strings.Contains(buf.String(), `"k"`) {
+		t.Errorf("replaced entry: %s", buf.String())
+	}
+}
+
+func TestSetLevelFor(t *testing.T) {
+	t.Cleanup(ResetLevels)
+	var buf bytes.Buffer
+	root := New(&Options{Output: &buf, Formatter: &TextFormatter{DisableTimestamp: true}})
+	gateway := root.Named("gateway")
+	shard0 := gateway.Named("shard.0")
+	conn := shard0.Named("conn").With(String("peer", "a"))
+	billing := root.Named("billing")
+
+	SetLevelFor("gateway.shard.*", DebugLevel)
+	if shard0.GetLevel() != DebugLevel || conn.GetLevel() != DebugLevel {
+		t.Errorf("shard levels = %v, %v", shard0.GetLevel(), conn.GetLevel())
+	}
+	if gateway.GetLevel() != InfoLevel || billing.GetLevel() != InfoLevel {
+		t.Errorf("unmatched levels = %v, %v", gateway.GetLevel(), billing.GetLevel())
+	}
+	conn.Debug("handshake")
+	if !strings.Contains(buf.String(), "handshake") {
+		t.Errorf("debug entry not logged: %q", buf.String())
+	}
+
+	// The closest matching prefix wins, and loggers created later follow
+	// the rules.
+	SetLevelFor("gateway", ErrorLevel)
+	shard1 := gateway.Named("shard.1")
+	if gateway.GetLevel() != ErrorLevel || shard1.GetLevel() != DebugLevel || NewNamed("gateway.http").GetLevel() != ErrorLevel {
+		t.Errorf("levels = %v, %v", gateway.GetLevel(), shard1.GetLevel())
+	}
+
+	// Rules take precedence over the logger's own level.
+	shard0.SetLevel(WarnLevel)
+	if shard0.GetLevel() != DebugLevel {
+		t.Errorf("shard0 level = %v", shard0.GetLevel())
+	}
+	if lvl, ok := LevelFor("gateway.shard.7.conn"); !ok || lvl != DebugLevel {
+		t.Errorf("LevelFor = %v, %v", lvl, ok)
+	}
+	if rules := LevelRules(); len(rules) != 2 || rules["gateway"] != ErrorLevel {
+		t.Errorf("rules = %v", rules)
+	}
+
+	ClearLevelFor("gateway.shard.*")
+	if shard0.GetLevel() != ErrorLevel {
+		t.Errorf("after clear, shard0 level = %v", shard0.GetLevel())
+	}
+	ResetLevels()
+	if shard0.GetLevel() != WarnLevel || gateway.GetLevel() != InfoLevel {
+		t.Errorf("after reset, levels = %v, %v", shard0.GetLevel(), gateway.GetLevel())
+	}
+	if _, ok := LevelFor("gateway"); ok {
+		t.Error("LevelFor after reset")
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	ring := NewRingHook(10)
+	RegisterHook("test-ring", ring)
+
+	var cfg Config
+	err := json.Unmarshal([]byte(`{
+		"level": "debug",
+		"format": "json",
+		"output": "discard",
+		"name": "api",
+		"fields": {"region": "eu", "az": "b"},
+		"sampling": {"rate": 2},
+		"hooks": ["test-ring"]
+	}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		logger.Debug("polled")
+	}
+	entries := ring.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2 (sampled)", len(entries))
+	}
+	if e := entries[0]; e.GetString("_logger") != "api" || e.GetString("region") != "eu" || e.GetString("az") != "b" {
+		t.Errorf("entry fields: %+v", e.Fields)
+	}
+
+	for _, bad := range []struct {
+		cfg  Config
+		want error
+	}{
+		{Config{Level: "verbose"}, ErrInvalidLevel},
+		{Config{Format: "xml"}, ErrUnknownFormat},
+		{Config{Hooks: []string{"missing"}}, ErrUnknownHook},
+	} {
+		if _, err := NewFromConfig(bad.cfg); !errors.Is(err, bad.want) {
+			t.Errorf("%+v: err = %v, want %v", bad.cfg, err, bad.want)
+		}
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logging.json")
+	if err := os.WriteFile(path, []byte(`{"level": "error", "format": "text", "add_caller": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "logs", "app.log")
+	t.Setenv("LUMEN_LOG_CONFIG", path)
+	t.Setenv("LUMEN_LOG_FORMAT", "json")
+	t.Setenv("LUMEN_LOG_OUTPUT", out)
+	t.Setenv("LUMEN_LOG_CALLER", "false")
+
+	logger, err := NewFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Warn("dropped")
+	logger.Error("kept")
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); strings.Count(s, "\n") != 1 || !strings.Contains(s, `"msg":"kept"`) || strings.Contains(s, "caller") {
+		t.Errorf("output: %s", s)
+	}
+
+	t.Setenv("LUMEN_LOG_CONFIG", filepath.Join(dir, "missing.json"))
+	if _, err := NewFromEnv(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing config: err = %v", err)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	dev := DevelopmentOptions()
+	if dev.Level != DebugLevel || !dev.AddCaller || dev.Output != os.Stderr {
+		t.Errorf("development options: %+v", dev)
+	}
+	if _, ok := dev.Formatter.(*PrettyFormatter); !ok {
+		t.Errorf("development formatter = %T", dev.Formatter)
+	}
+	if NewDevelopment().GetLevel() != DebugLevel {
+		t.Error("development level")
+	}
+
+	var buf bytes.Buffer
+	opts := ProductionOptions()
+	opts.Output = &buf
+	prod := New(opts)
+	for range 150 {
+		prod.Info("tick")
+	}
+	for range 150 {
+		prod.Error("failed")
+	}
+	prod.Debug("hidden")
+	prod.Close()
+	out := buf.String()
+	if n := strings.Count(out, `"msg":"tick"`); n != 100 {
+		t.Errorf("logged %d of 150 sampled info entries, want 100", n)
+	}
+	if n := strings.Count(out, `"msg":"failed"`); n != 150 {
+		t.Errorf("logged %d of 150 error entries, want all", n)
+	}
+	if strings.Contains(out, "hidden") || !json.Valid([]byte(strings.SplitN(out, "\n", 2)[0])) {
+		t.Errorf("output: %.200s", out)
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	var before bytes.Buffer
+	logger := New(&Options{Output: &before, AsyncBufferSize: 64})
+	child := logger.Named("db").With(String("pool", "main"))
+
+	for i := range 20 {
+		child.Info("queued", Int("n", i))
+	}
+	after := filepath.Join(t.TempDir(), "app.log")
+	if err := logger.Reconfigure(Config{Level: "debug", Format: "json", Output: after}); err != nil {
+		t.Fatal(err)
+	}
+	child.Debug("reconfigured")
+	logger.Close()
+
+	data, err := os.ReadFile(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No entry is lost across the swap, and the child logger created
+	// before it adopts the new settings.
+	if n := strings.Count(before.String(), "queued") + strings.Count(string(data), "queued"); n != 20 {
+		t.Errorf("%d of 20 queued entries written", n)
+	}
+	if !strings.Contains(string(data), `"msg":"reconfigured"`) || !strings.Contains(string(data), `"logger":"db"`) {
+		t.Errorf("file output: %s", data)
+	}
+
+	if err := logger.Reconfigure(Config{Level: "loud"}); !errors.Is(err, ErrInvalidLevel) {
+		t.Errorf("invalid level: err = %v", err)
+	}
+	if err := logger.Reconfigure(Config{Format: "xml"}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("unknown format: err = %v", err)
+	}
+	if child.GetLevel() != DebugLevel {
+		t.Errorf("failed reconfigure changed level to %v", child.GetLevel())
+	}
+}
+
+func TestReconfigureClosesReplacedFile(t *testing.T) {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("needs /proc/self/fd")
+	}
+	// openFiles counts this process's descriptors open on path.
+	openFiles := func(path string) int {
+		entries, _ := os.ReadDir("/proc/self/fd")
+		n := 0
+		for _, e := range entries {
+			if target, err := os.Readlink(filepath.Join("/proc/self/fd", e.Name())); err == nil && target == path {
+				n++
+			}
+		}
+		return n
+	}
+
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+	logger, err := NewFromConfig(Config{Format: "json", Output: first, AsyncBufferSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 20 {
+		logger.Info("queued", Int("n", i))
+	}
+	if err := logger.Reconfigure(Config{Format: "json", Output: second}); err != nil {
+		t.Fatal(err)
+	}
+	if n := openFiles(first); n != 0 {
+		t.Errorf("replaced output still has %d open descriptors", n)
+	}
+	if n := openFiles(second); n != 1 {
+		t.Errorf("new output has %d open descriptors, want 1", n)
+	}
+	logger.Info("after")
+	logger.Close()
+
+	a, _ := os.ReadFile(first)
+	b, _ := os.ReadFile(second)
+	if n := strings.Count(string(a), "queued") + strings.Count(string(b), "queued"); n != 20 {
+		t.Errorf("%d of 20 queued entries written", n)
+	}
+	if !strings.Contains(string(b), `"msg":"after"`) {
+		t.Errorf("second output: %s", b)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logging.json")
+	write := func(content string, mtime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, mtime, mtime)
+	}
+	write(`{"level": "warn", "output": "discard"}`, time.Now().Add(-time.Hour))
+
+	var mu sync.Mutex
+	var errs []error
+	logger := New(&Options{Output: io.Discard})
+	stop := logger.WatchConfig(path, &WatchOptions{
+		Interval:      5 * time.Millisecond,
+		DisableSIGHUP: true,
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+	defer stop()
+
+	child := logger.Named("api")
+	if child.GetLevel() != WarnLevel {
+		t.Fatalf("level = %v, want the file's level at start", child.GetLevel())
+	}
+
+	write(`{"level": "debug", "output": "discard"}`, time.Now())
+	waitFor(t, func() bool { return child.GetLevel() == DebugLevel })
+
+	write(`{"level": "debug", "format": "yaml"}`, time.Now().Add(time.Minute))
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) == 1 && errors.Is(errs[0], ErrUnknownFormat)
+	})
+	if child.GetLevel() != DebugLevel {
+		t.Errorf("level = %v after a failed reload", child.GetLevel())
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLevelSplitWriter(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	w := NewLevelSplitWriter()
+	w.Stdout, w.Stderr = &stdout, &stderr
+	logger := New(&Options{Output: w, Level: DebugLevel, Formatter: &TextFormatter{DisableTimestamp: true, DisableColors: true}})
+
+	logger.Debug("cache miss")
+	logger.Info("started")
+	logger.Warn("slow query")
+	logger.Error("failed")
+
+	if s := stdout.String(); !strings.Contains(s, "cache miss") || !strings.Contains(s, "started") || strings.Contains(s, "slow") {
+		t.Errorf("stdout: %q", s)
+	}
+	if s := stderr.String(); !strings.Contains(s, "slow query") || !strings.Contains(s, "failed") || strings.Contains(s, "started") {
+		t.Errorf("stderr: %q", s)
+	}
+
+	w.Threshold = ErrorLevel
+	stdout.Reset()
+	logger.Warn("degraded")
+	if !strings.Contains(stdout.String(), "degraded") {
+		t.Errorf("warning with error threshold went to stderr")
+	}
+}
+
+// flakyWriter fails or blocks on demand.
+type flakyWriter struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	err   error
+	block chan struct{}
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	err, block := w.err, w.block
+	w.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *flakyWriter) set(err error, block chan struct{}) {
+	w.mu.Lock()
+	w.err, w.block = err, block
+	w.mu.Unlock()
+}
+
+func (w *flakyWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestFailoverWriter(t *testing.T) {
+	primary, fallback := &flakyWriter{}, &flakyWriter{}
+	var switches []string
+	w := NewFailoverWriter(primary, []io.Writer{fallback}, &FailoverOptions{
+		Timeout:       50 * time.Millisecond,
+		ProbeInterval: 20 * time.Millisecond,
+		OnFailover: func(from, to int, err error) {
+			switches = append(switches, fmt.Sprintf("%d->%d %v", from, to, err))
+		},
+	})
+	defer w.Close()
+
+	w.Write([]byte("a\n"))
+	primary.set(errors.New("connection reset"), nil)
+	w.Write([]byte("b\n"))
+	w.Write([]byte("c\n"))
+	if w.Active() != 1 || primary.String() != "a\n" || fallback.String() != "b\nc\n" {
+		t.Fatalf("active = %d, primary %q, fallback %q", w.Active(), primary.String(), fallback.String())
+	}
+
+	// The primary is probed again after ProbeInterval.
+	primary.set(nil, nil)
+	time.Sleep(30 * time.Millisecond)
+	w.Write([]byte("d\n"))
+	if w.Active() != 0 || primary.String() != "a\nd\n" {
+		t.Errorf("active = %d, primary %q", w.Active(), primary.String())
+	}
+
+	// A blocked primary times out, and is skipped while still blocked.
+	block := make(chan struct{})
+	primary.set(nil, block)
+	start := time.Now()
+	w.Write([]byte("e\n"))
+	w.Write([]byte("f\n"))
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("writes took %v", elapsed)
+	}
+	if fallback.String() != "b\nc\ne\nf\n" {
+		t.Errorf("fallback %q", fallback.String())
+	}
+	close(block)
+
+	want := []string{"0->1 connection reset", "1->0 <nil>", "0->1 " + ErrWriteTimeout.Error()}
+	if fmt.Sprint(switches) != fmt.Sprint(want) {
+		t.Errorf("switches = %q, want %q", switches, want)
+	}
+
+	fallback.set(errors.New("disk full"), nil)
+	primary.set(errors.New("down"), nil)
+	time.Sleep(30 * time.Millisecond)
+	if _, err := w.Write([]byte("g\n")); err == nil || err.Error() != "disk full" {
+		t.Errorf("all writers failing: err = %v", err)
+	}
+}
+
+func TestRingBufferHook(t *testing.T) {
+	var dumped bytes.Buffer
+	ring := NewRingBufferHook(&RingBufferOptions{Size: 3, DumpTo: &dumped})
+	logger := New(&Options{Output: io.Discard, Level: InfoLevel})
+	logger.SetRecorder(ring, TraceLevel)
+
+	logger.Debug("connecting")
+	logger.Trace("dial tcp")
+	logger.Info("retrying")
+	logger.Debug("dial tcp again")
+	if dumped.Len() != 0 {
+		t.Fatalf("dumped before an error: %s", dumped.String())
+	}
+
+	var manual bytes.Buffer
+	if err := ring.Dump(&manual); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(manual.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[0], "dial tcp") {
+		t.Errorf("manual dump: %q", lines)
+	}
+
+	logger.Error("connect failed")
+	lines := strings.Split(strings.TrimSpace(dumped.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"msg":"retrying"`) || !strings.Contains(lines[2], `"msg":"connect failed"`) {
+		t.Errorf("error dump: %q", lines)
+	}
+	if len(ring.Entries()) != 0 {
+		t.Errorf("buffer not cleared after dump: %d entries", len(ring.Entries()))
+	}
+
+	logger.Debug("after")
+	rec := httptest.NewRecorder()
+	ring.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/logs", nil))
+	if rec.Header().Get("Content-Type") != "application/x-ndjson" || !strings.Contains(rec.Body.String(), `"msg":"after"`) {
+		t.Errorf("handler: %s %q", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
+
+func TestTraceCorrelation(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&Options{
+		Output:               &buf,
+		Formatter:            &JSONFormatter{DisableTimestamp: true},
+		WithTraceCorrelation: true,
+	}).Named("api")
+
+	ctx, span := trace.New(nil).Start(context.Background(), "GET /orders")
+	defer span.End()
+
+	logger.InfoContext(ctx, "listing orders")
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got[TraceIDKey] != span.TraceID().String() || got[SpanIDKey] != span.SpanID().String() {
+		t.Errorf("entry = %v, want span %s/%s", got, span.TraceID(), span.SpanID())
+	}
+
+	// Explicit IDs win, and entries without a span are left alone.
+	buf.Reset()
+	logger.InfoContext(WithTraceID(ctx, "explicit"), "overridden")
+	logger.InfoContext(context.Background(), "no span")
+	logger.Info("no context")
+	out := buf.String()
+	if strings.Count(out, TraceIDKey) != 1 || !strings.Contains(out, `"trace_id":"explicit"`) || strings.Contains(out, SpanIDKey) {
+		t.Errorf("output: %s", out)
+	}
+
+	buf.Reset()
+	New(&Options{Output: &buf}).InfoContext(ctx, "not correlated")
+	if strings.Contains(buf.String(), TraceIDKey) {
+		t.Errorf("correlated without the option: %s", buf.String())
+	}
+}
+
+func TestGetNamed(t *testing.T) {
+	shard := GetNamed("test.gateway.shard.0")
+	if GetNamed("test.gateway.shard.0") != shard {
+		t.Fatal("GetNamed returned a new logger for the same name")
+	}
+	if shard.GetName() != "test.gateway.shard.0" {
+		t.Errorf("name = %q", shard.GetName())
+	}
+	shard.SetLevel(TraceLevel)
+	if GetNamed("test.gateway.shard.0").GetLevel() != TraceLevel {
+		t.Error("level set on the logger was not kept")
+	}
+
+	GetNamed("test.gateway.http")
+	var names []string
+	for name := range NamedLoggers() {
+		if strings.HasPrefix(name, "test.gateway.") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	if want := []string{"test.gateway.http", "test.gateway.shard.0"}; !slices.Equal(names, want) {
+		t.Errorf("names = %q, want %q", names, want)
+	}
+
+	var wg sync.WaitGroup
+	loggers := make([]*Logger, 8)
+	for i := range loggers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loggers[i] = GetNamed("test.concurrent")
+		}()
+	}
+	wg.Wait()
+	for _, l := range loggers {
+		if l != loggers[0] {
+			t.Fatal("concurrent GetNamed calls returned different loggers")
+		}
+	}
+}
+
+func TestGetNamedFollowsSetDefault(t *testing.T) {
+	prev := Default()
+	defer SetDefault(prev)
+
+	// Fetched before the default is set, as a package-level var would be.
+	log := GetNamed("test.follow")
+
+	var buf bytes.Buffer
+	SetDefault(New(&Options{
+		Output:    &buf,
+		Formatter: &JSONFormatter{DisableTimestamp: true},
+		Level:     DebugLevel,
+		Fields:    []Field{String("service", "checkout")},
+	}))
+	log.Debug("after SetDefault")
+	log.Named("child").Info("derived")
+
+	want := `{"level":"debug","logger":"test.follow","msg":"after SetDefault","service":"checkout"}
+{"level":"info","logger":"test.follow.child","msg":"derived","service":"checkout"}
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if !log.IsEnabled(DebugLevel) || log.GetName() != "test.follow" {
+		t.Errorf("level = %v, name = %q", log.GetLevel(), log.GetName())
+	}
+
+	log.SetLevel(WarnLevel)
+	if log.IsEnabled(InfoLevel) {
+		t.Error("level set on the logger was not applied")
+	}
+}
+
+func TestVerbose(t *testing.T) {
+	defer SetVerbosity(GetVerbosity())
+	var buf bytes.Buffer
+	logger := New(&Options{Output: &buf, Formatter: &JSONFormatter{DisableTimestamp: true}})
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(VerbosityFlag(), "v", "log verbosity")
+	if err := fs.Parse([]string{"-v=3"}); err != nil {
+		t.Fatal(err)
+	}
+	if GetVerbosity() != 3 {
+		t.Fatalf("verbosity = %d", GetVerbosity())
+	}
+
+	logger.V(0).Info("v0")
+	logger.V(2).Info("v2", String("pod", "web-0"))
+	logger.V(3).Infof("v%d", 3)
+	logger.V(4).Info("v4")
+	if logger.V(4).Enabled() || !logger.V(3).Enabled() {
+		t.Error("Enabled does not follow the verbosity")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines: %s", len(lines), buf.String())
+	}
+	var v2 map[string]any
+	json.Unmarshal([]byte(lines[1]), &v2)
+	// Verbose entries bypass the logger's info level.
+	if v2["level"] != "debug" || v2[VerbosityKey] != 2.0 || v2["pod"] != "web-0" {
+		t.Errorf("v2 entry = %v", v2)
+	}
+	if strings.Contains(lines[0], `"v"`) || !strings.Contains(lines[0], `"level":"info"`) {
+		t.Errorf("v0 entry = %s", lines[0])
+	}
+
+	buf.Reset()
+	SetVerbosity(9)
+	logger.V(5).Info("deep")
+	if !strings.Contains(buf.String(), `"level":"trace"`) {
+		t.Errorf("v5 entry = %s", buf.String())
+	}
+	if err := fs.Parse([]string{"-v=high"}); err == nil {
+		t.Error("invalid verbosity accepted")
+	}
+}
+
+type testUser struct {
+	ID       string
+	Age      int
+	Password string
+	Address  *testAddress
+}
+
+func (u testUser) MarshalLog(enc FieldEncoder) {
+	enc.AddString("id", u.ID)
+	enc.AddInt("age", u.Age)
+	enc.AddObject("address", u.Address)
+	enc.AddField(Err(errors.New("locked")))
+}
+
+type testAddress struct{ City string }
+
+func (a *testAddress) MarshalLog(enc FieldEncoder) {
+	enc.AddString("city", a.City)
+}
+
+func TestLogMarshaler(t *testing.T) {
+	user := testUser{ID: "u1", Age: 42, Password: "hunter2", Address: &testAddress{City: "Oslo"}}
+	const want = `{"id":"u1","age":42,"address":{"city":"Oslo"},"error":"locked"}`
+
+	var buf bytes.Buffer
+	logger := New(&Options{Output: &buf, Formatter: &JSONFormatter{DisableTimestamp: true}})
+	logger.Info("signed up", Object("user", user), Any("user2", user))
+	if !strings.Contains(buf.String(), `"user":`+want+`,"user2":`+want) {
+		t.Errorf("JSON = %s", buf.String())
+	}
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Error("unmarshaled field logged")
+	}
+
+	field := Object("user", user)
+	if field.Type != FieldTypeObject || field.StringValue() != want {
+		t.Errorf("field = %v %q", field.Type, field.StringValue())
+	}
+	value, _ := field.Value().(map[string]any)
+	if value["age"] != int64(42) || value["address"].(map[string]any)["city"] != "Oslo" {
+		t.Errorf("Value = %v", field.Value())
+	}
+
+	var empty LogMarshalerFunc = func(FieldEncoder) {}
+	if got := Any("empty", empty).StringValue(); got != "{}" {
+		t.Errorf("empty object = %q", got)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&Options{
+		Output:    &buf,
+		Formatter: &JSONFormatter{DisableTimestamp: true},
+		Redactor:  NewRedactor("token"),
+	})
+	logger.With(String("service", "api"), Namespace("req")).Info("served",
+		Group("http", String("method", "GET"), Int("status", 200), String("token", "t0ps3cret")),
+		Namespace("db"),
+		Int("rows", 3),
+	)
+	want := `"service":"api","req":{"http":{"method":"GET","status":200,"token":"[REDACTED]"},"db":{"rows":3}}}`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("JSON = %s, want %s", buf.String(), want)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	logger.SetFormatter(&TextFormatter{DisableTimestamp: true, DisableColors: true})
+	logger.Info("served", String("id", "1"), Group("http", String("method", "GET"), Group("tls", String("version", "1.3"))), Namespace("db"), Int("rows", 3))
+	if got := buf.String(); !strings.Contains(got, "id=1 http.method=GET http.tls.version=1.3 db.rows=3") {
+		t.Errorf("text = %q", got)
+	}
+
+	if value, _ := Group("http", Int("status", 200)).Value().(map[string]any); value["status"] != int64(200) {
+		t.Errorf("Value = %v", value)
+	}
+}
+
+func TestSliceFields(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fields := []Field{
+		Ints("ids", []int{1, 2, 3}),
+		Int64s("offsets", []int64{-1, 1 << 40}),
+		Float64s("ratios", []float64{0.5, 2}),
+		Bools("flags", []bool{true, false}),
+		Durations("waits", []time.Duration{time.Second, 15 * time.Millisecond}),
+		Times("at", []time.Time{ts}),
+		Errs("errors", []error{errors.New("timeout"), nil}),
+		Strings("tags", []string{"a", "b"}),
+		Any("empty", []int(nil)),
+	}
+
+	var buf bytes.Buffer
+	logger := New(&Options{Output: &buf, Formatter: &JSONFormatter{DisableTimestamp: true}})
+	logger.Info("batch", fields...)
+	want := `"ids":[1,2,3],"offsets":[-1,1099511627776],"ratios":[0.5,2],"flags":[true,false],` +
+		`"waits":["1s","15ms"],"at":["2024-03-01T12:00:00Z"],"errors":["timeout",null],"tags":["a","b"],"empty":[]`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("JSON = %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetFormatter(&TextFormatter{DisableTimestamp: true, DisableColors: true})
+	logger.Info("batch", fields...)
+	want = `ids=[1,2,3] offsets=[-1,1099511627776] ratios=[0.5,2] flags=[true,false] ` +
+		`waits=[1s,15ms] at=[2024-03-01T12:00:00Z] errors=[timeout,<nil>] tags=[a,b] empty=[]`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("text = %s", buf.String())
+	}
+
+	if got := Errs("errors", []error{errors.New("timeout")}).Value(); !slices.Equal(got.([]string), []string{"timeout"}) {
+		t.Errorf("Value = %v", got)
+	}
+}
+
+func TestMapAndDict(t *testing.T) {
+	m := map[string]any{
+		"zone":   "eu-1",
+		"count":  3,
+		"labels": map[string]any{"tier": "gold", "app": "api"},
+	}
+	var buf bytes.Buffer
+	logger := New(&Options{Output: &buf, Formatter: &JSONFormatter{DisableTimestamp: true}})
+	for range 3 {
+		logger.Info("state", Map("m", m), Dict("d", String("b", "2"), Int("a", 1)))
+	}
+	want := `"m":{"count":3,"labels":{"app":"api","tier":"gold"},"zone":"eu-1"},"d":{"a":1,"b":"2"}}`
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.HasSuffix(line, want) {
+			t.Errorf("JSON = %s", line)
+		}
+	}
+
+	buf.Reset()
+	logger.SetFormatter(&TextFormatter{DisableTimestamp: true, DisableColors: true})
+	logger.Info("state", Map("m", m))
+	if got := buf.String(); !strings.Contains(got, "m.count=3 m.labels.app=api m.labels.tier=gold m.zone=eu-1") {
+		t.Errorf("text = %q", got)
+	}
+}
+
+type callersError struct{ pcs []uintptr }
+
+func (e *callersError) Error() string      { return "disk full" }
+func (e *callersError) Callers() []uintptr { return e.pcs }
+
+func TestRichErrors(t *testing.T) {
+	var buf bytes.Buffer
+	hook := NewRingHook(10)
+	logger := New(&Options{
+		Output:       &buf,
+		Formatter:    &JSONFormatter{DisableTimestamp: true},
+		ErrorEncoder: RichErrors,
+		AddStack:     true,
+		Hooks:        []Hook{hook},
+	})
+
+	cause := &os.PathError{Op: "open", Path: "app.yaml", Err: os.ErrNotExist}
+	logger.Error("load failed", Err(fmt.Errorf("read config: %w", cause)))
+
+	var entry struct {
+		Stack string `json:"stack"`
+		Error struct {
+			Message    string   `json:"message"`
+			Type       string   `json:"type"`
+			Causes     []string `json:"causes"`
+			CauseTypes []string `json:"cause_types"`
+			Stack      []string `json:"stack"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	e := entry.Error
+	if e.Message != "read config: open app.yaml: file does not exist" || e.Type != "*fmt.wrapError" {
+		t.Errorf("error = %+v", e)
+	}
+	if !slices.Equal(e.CauseTypes, []string{"*fs.PathError", "*errors.errorString"}) || len(e.Causes) != 2 {
+		t.Errorf("causes = %v %v", e.Causes, e.CauseTypes)
+	}
+	if !slices.ContainsFunc(e.Stack, func(f string) bool { return strings.HasPrefix(f, "github.com/kolosys/lumen/logs_test.TestRichErrors ") }) || entry.Stack != "" {
+		t.Errorf("stack = %q, entry stack = %q", e.Stack, entry.Stack)
+	}
+	// Hooks still see the error field.
+	if entries := hook.Entries(); len(entries) != 1 || entries[0].Fields[0].Type != FieldTypeError {
+		t.Errorf("hook entries = %v", entries)
+	}
+
+	buf.Reset()
+	pcs := make([]uintptr, 8)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+	logger.Warn("flush failed", Err(errors.Join(&callersError{pcs: pcs}, io.ErrShortWrite)))
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Error.Stack) == 0 || !strings.Contains(entry.Error.Stack[0], "TestRichErrors") {
+		t.Errorf("carried stack = %q", entry.Error.Stack)
+	}
+	if !slices.Equal(entry.Error.Causes, []string{"disk full", "short write"}) {
+		t.Errorf("joined causes = %v", entry.Error.Causes)
+	}
+}
+
+func TestHostAndServiceInfo(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&Options{
+		Output:          &buf,
+		Formatter:       &JSONFormatter{DisableTimestamp: true},
+		IncludeHostInfo: true,
+		Fields:          append(WithServiceInfo("checkout", "1.4.2", ""), String("host", "web-1")),
+	})
+	logger.Info("started")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["service"] != "checkout" || entry["version"] != "1.4.2" || entry["pid"] != float64(os.Getpid()) {
+		t.Errorf("entry = %v", entry)
+	}
+	if _, ok := entry["env"]; ok {
+		t.Error("empty env added")
+	}
+	// Fields win over the looked-up host.
+	if entry["host"] != "web-1" || strings.Count(buf.String(), `"host"`) != 1 {
+		t.Errorf("host = %s", buf.String())
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	var buf safeBuffer
+	logger := New(&Options{Output: &buf, Formatter: &JSONFormatter{DisableTimestamp: true}, AddGoroutineID: true})
+
+	var wg sync.WaitGroup
+	for i := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("work")
+			logger.With(String("n", "x")).InfoContext(GoroutineLabel(context.Background(), fmt.Sprintf("worker-%d", i)), "labeled")
+		}()
+	}
+	wg.Wait()
+
+	ids := map[float64]bool{}
+	labels := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		switch g := entry[GoroutineKey].(type) {
+		case float64:
+			ids[g] = true
+		case string:
+			labels[g] = true
+		}
+	}
+	if len(ids) != 3 || ids[0] || len(labels) != 3 || !labels["worker-2"] {
+		t.Errorf("ids = %v, labels = %v", ids, labels)
+	}
+}
+
+func logVia(l *Logger, msg string) {
+	l.Info(msg)
+}
+
+func TestCallerSite(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&Options{Output: &buf, Formatter: &JSONFormatter{DisableTimestamp: true}, AddCaller: true, AddFunction: true})
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("direct")
+	logger.InfoContext(context.Background(), "context")
+	logger.Infof("printf %d", 1)
+	logger.IfErr(errors.New("x")).Error("error builder")
+	logger.Build().Str("k", "v").Info("builder")
+	logger.V(0).Info("verbose")
+	logVia(logger.WithCallerSkip(1), "skipped")
+	logVia(logger, "not skipped")
+
+	want := fmt.Sprintf("logs_test.go:%d", line+1)
+	var entries []map[string]any
+	for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		json.Unmarshal([]byte(l), &entry)
+		entries = append(entries, entry)
+	}
+	for i, entry := range entries[:6] {
+		if want := fmt.Sprintf("logs_test.go:%d", line+1+i); entry["caller"] != want {
+			t.Errorf("%s: caller = %v, want %s", entry["msg"], entry["caller"], want)
+		}
+		if entry[FunctionKey] != "logs_test.TestCallerSite" {
+			t.Errorf("%s: func = %v", entry["msg"], entry[FunctionKey])
+		}
+	}
+	if want = fmt.Sprintf("logs_test.go:%d", line+7); entries[6]["caller"] != want {
+		t.Errorf("WithCallerSkip caller = %v, want %s", entries[6]["caller"], want)
+	}
+	if entries[7][FunctionKey] != "logs_test.logVia" {
+		t.Errorf("wrapper func = %v", entries[7][FunctionKey])
+	}
+
+	buf.Reset()
+	New(&Options{Output: &buf, Formatter: &JSONFormatter{}, AddCaller: true, CallerFormat: CallerPackage}).Info("pkg")
+	if !strings.Contains(buf.String(), `"caller":"logs/logs_test.go:`) {
+		t.Errorf("CallerPackage = %s", buf.String())
+	}
+}
+
+func TestStackOptions(t *testing.T) {
+	var buf bytes.Buffer
+	hook := NewRingHook(10)
+	logger := New(&Options{
+		Output:    &buf,
+		Formatter: &JSONFormatter{DisableTimestamp: true},
+		Stack:     &StackOptions{Level: WarnLevel, MaxFrames: 2},
+		Hooks:     []Hook{hook},
+	})
+	logger.Info("no stack")
+	logger.Warn("with stack")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if strings.Contains(lines[0], `"stack":`) {
+		t.Errorf("info entry has a stack: %s", lines[0])
+	}
+	var entry struct {
+		Stack []StackFrame `json:"stack"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("%v: %s", err, lines[1])
+	}
+	if len(entry.Stack) != 2 || entry.Stack[0].Function != "github.com/kolosys/lumen/logs_test.TestStackOptions" ||
+		!strings.HasSuffix(entry.Stack[0].File, "logs_test.go") || entry.Stack[0].Line == 0 {
+		t.Errorf("stack = %+v", entry.Stack)
+	}
+
+	// Hooks get the same trace as text, in the layout of runtime.Stack.
+	text := hook.Entries()[1].Stack
+	if !strings.HasPrefix(text, "github.com/kolosys/lumen/logs_test.TestStackOptions(...)\n\t") || strings.Count(text, "\n") != 4 {
+		t.Errorf("text stack = %q", text)
+	}
+
+	buf.Reset()
+	logger = New(&Options{
+		Output:    &buf,
+		Formatter: &JSONFormatter{DisableTimestamp: true},
+		Stack:     &StackOptions{SkipPrefixes: []string{"github.com/kolosys/lumen/", "testing.", "runtime."}},
+	})
+	logger.Error("all skipped")
+	if strings.Contains(buf.String(), `"stack":`) {
+		t.Errorf("skipped frames logged: %s", buf.String())
+	}
+}
+
+func TestExitHandlers(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	record := func(s string) {
+		mu.Lock()
+		calls = append(calls, s)
+		mu.Unlock()
+	}
+	RegisterExitHandler(func() { record("first") })
+	RegisterExitHandler(func() { panic("broken handler") })
+	RegisterExitHandler(func() { record("last") })
+
+	var buf bytes.Buffer
+	logger := New(&Options{
+		Output:          &buf,
+		Formatter:       &JSONFormatter{DisableTimestamp: true},
+		AsyncBufferSize: 16,
+		ExitFunc:        func(code int) { record(fmt.Sprint("exit ", code)) },
+	})
+	logger.With(String("k", "v")).Fatal("fatal")
+	want := []string{"first", "last", "exit 1"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	// Async entries are flushed before the handlers run.
+	if !strings.Contains(buf.String(), `"msg":"fatal"`) {
+		t.Errorf("output = %q", buf.String())
+	}
+
+	calls = nil
+	logger = New(&Options{Output: io.Discard, ExitFunc: func(code int) { record("exit") }})
+	logger.Fatalf("fatal %d", 1)
+	logger.Build().Fatal("builder")
+	logger.IfErr(errors.New("x")).Fatal("error builder")
+	if n := strings.Count(strings.Join(calls, ","), "exit"); n != 3 {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestCloseChild(t *testing.T) {
+	var buf safeBuffer
+	logger := New(&Options{Output: &buf, AsyncBufferSize: 16})
+	child := logger.With(String("k", "v"))
+	child.Info("before")
+	if err := child.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "before") {
+		t.Errorf("child Close did not flush: %q", buf.String())
+	}
+	// Written synchronously once closed, rather than sent on the closed
+	// channel.
+	child.Info("after")
+	logger.Info("after")
+	logger.Close()
+}
+
+func TestContextHook(t *testing.T) {
+	type tenantKey struct{}
+	var tenants []string
+	var deadlines int
+	hook := NewContextFuncHook(func(ctx context.Context, e *Entry) {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		tenants = append(tenants, tenant)
+		if _, ok := ctx.Deadline(); ok {
+			deadlines++
+		}
+	}, WarnLevel)
+	logger := New(&Options{
+		Output: io.Discard,
+		Hooks: []Hook{
+			hook,
+			// Wrappers pass the context on.
+			NewFilterHook(NewLevelHook(hook, ErrorLevel), func(*Entry) bool { return true }),
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), tenantKey{}, "acme"), time.Minute)
+	defer cancel()
+	logger.WarnContext(ctx, "slow")
+	logger.With(String("k", "v")).ErrorContext(ctx, "failed")
+	logger.Warn("no context")
+
+	// The error entry reaches only the wrapped hook.
+	if !slices.Equal(tenants, []string{"acme", "acme", ""}) || deadlines != 2 {
+		t.Errorf("tenants = %q, deadlines = %d", tenants, deadlines)
+	}
+}
+
+func TestWriteHooks(t *testing.T) {
+	var out, mirror bytes.Buffer
+	var sums []uint32
+	logger := New(&Options{
+		Output:    &out,
+		Formatter: &JSONFormatter{DisableTimestamp: true},
+		WriteHooks: []WriteHook{
+			WriteHookFunc(func(e *Entry, data []byte, output io.Writer) []byte {
+				if output != &out {
+					t.Errorf("output = %v", output)
+				}
+				if e.Message == "secret" {
+					return nil
+				}
+				return data
+			}),
+			NewMirrorHook(&mirror),
+		},
+	})
+	logger.AddWriteHook(WriteHookFunc(func(_ *Entry, data []byte, _ io.Writer) []byte {
+		var sum uint32
+		for _, b := range data {
+			sum = sum*31 + uint32(b)
+		}
+		sums = append(sums, sum)
+		return bytes.ToUpper(data)
+	}))
+
+	logger.Info("hello")
+	logger.With(String("k", "v")).Info("secret")
+
+	if out.String() != "{\"LEVEL\":\"INFO\",\"MSG\":\"HELLO\"}\n" {
+		t.Errorf("output = %q", out.String())
+	}
+	if mirror.String() != "{\"level\":\"info\",\"msg\":\"hello\"}\n" {
+		t.Errorf("mirror = %q", mirror.String())
+	}
+	if len(sums) != 1 {
+		t.Errorf("checksums = %v", sums)
+	}
+}
+
+func TestMetricsRegistryHook(t *testing.T) {
+	var out bytes.Buffer
+	reg := metrics.NewRegistry(nil)
+	logger := New(&Options{
+		Output:     &out,
+		Formatter:  &JSONFormatter{DisableTimestamp: true},
+		WriteHooks: []WriteHook{NewMetricsRegistryHook(reg)},
+	})
+
+	db := logger.Named("db")
+	db.Info("connected")
+	db.Info("query")
+	db.Error("timeout")
+	logger.Info("ready")
+
+	entries := reg.Counter(LogEntriesMetric, "", "level", "logger")
+	for _, tt := range []struct {
+		level, logger string
+		want          float64
+	}{{"info", "db", 2}, {"error", "db", 1}, {"info", "", 1}} {
+		if got := entries.Value(tt.level, tt.logger); got != tt.want {
+			t.Errorf("entries{%s,%s} = %v, want %v", tt.level, tt.logger, got, tt.want)
+		}
+	}
+
+	var sum float64
+	for _, s := range reg.Collect() {
+		if s.Name == LogEntrySizeMetric+"_sum" {
+			sum += s.Value
+		}
+	}
+	if sum != float64(out.Len()) {
+		t.Errorf("size sum = %v, want %d", sum, out.Len())
+	}
+}
+
+func TestThresholdHook(t *testing.T) {
+	var out safeBuffer
+	logger := New(&Options{Output: &out, Formatter: &JSONFormatter{DisableTimestamp: true}})
+	events := make(chan ThresholdEvent, 4)
+	hook := NewThresholdHook(&ThresholdOptions{
+		Threshold: 3,
+		Recover:   1,
+		Window:    100 * time.Millisecond,
+		Logger:    logger,
+		OnChange:  func(ev ThresholdEvent) { events <- ev },
+	})
+	defer hook.Close()
+	logger.AddHook(hook)
+
+	logger.Warn("not counted")
+	logger.Error("one")
+	logger.Error("two")
+	if hook.Firing() {
+		t.Fatal("firing below threshold")
+	}
+	logger.Error("three")
+	logger.Error("four")
+
+	ev := <-events
+	if !ev.Firing || ev.Count != 3 || !hook.Firing() {
+		t.Errorf("event = %+v, firing = %v", ev, hook.Firing())
+	}
+	ev = <-events
+	if ev.Firing || ev.Count > 1 || hook.Firing() {
+		t.Errorf("recovery event = %+v, firing = %v", ev, hook.Firing())
+	}
+	waitFor(t, func() bool { return strings.Contains(out.String(), "error rate recovered") })
+	if s := out.String(); strings.Count(s, `"alert":"error_rate"`) != 2 || !strings.Contains(s, `"severity":"critical"`) {
+		t.Errorf("output = %s", s)
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event %+v", ev)
+	default:
+	}
+}
+
+func TestLoggingTransport(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if calls++; calls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/echo":
+			io.Copy(w, r.Body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ring := NewRingHook(10)
+	logger := New(&Options{Output: io.Discard, Level: DebugLevel})
+	logger.AddHook(ring)
+	client := &http.Client{Transport: NewLoggingTransport(nil, logger).
+		WithRetries(2, time.Millisecond).
+		WithBodies(64, NewRedactor("token").ScrubEmails())}
+
+	resp, err := client.Get(server.URL + "/flaky")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("flaky: %v %v", resp, err)
+	}
+	resp.Body.Close()
+
+	body := `{"token":"abc123","email":"ann@example.com","name":"ann"}`
+	resp, err = client.Post(server.URL+"/echo", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoed, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(echoed) != body {
+		t.Errorf("response body = %q, want it untouched", echoed)
+	}
+
+	resp, err = client.Post(server.URL+"/echo", "text/plain", strings.NewReader(strings.Repeat("x", 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	resp, err = client.Get(server.URL + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	entries := ring.Entries()
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(entries))
+	}
+	if e := entries[0]; e.Level != ErrorLevel || e.GetString("http.response.status_code") != "503" {
+		t.Errorf("first attempt = %v %v", e.Level, e.Fields)
+	}
+	if e := entries[1]; e.Level != DebugLevel || e.GetString(HTTPResendCountKey) != "1" ||
+		e.GetString("url.full") != server.URL+"/flaky" {
+		t.Errorf("retry = %v %v", e.Level, e.Fields)
+	}
+	for _, key := range []string{HTTPRequestBodyKey, HTTPResponseBodyKey} {
+		logged := entries[2].GetString(key)
+		if strings.Contains(logged, "abc123") || strings.Contains(logged, "ann@example.com") || !strings.Contains(logged, `"name":"ann"`) {
+			t.Errorf("%s = %s", key, logged)
+		}
+	}
+	if logged := entries[3].GetString(HTTPRequestBodyKey); logged != strings.Repeat("x", 64)+"…" {
+		t.Errorf("truncated body = %q", logged)
+	}
+	if e := entries[4]; e.Level != WarnLevel {
+		t.Errorf("404 logged at %v", e.Level)
+	}
+}
@@ -0,0 +1,173 @@
+package logs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/internal/enc"
+)
+
+// LogMarshaler is implemented by types that encode themselves into log
+// entries. Object and Any use it instead of reflection or encoding/json,
+// so a type controls exactly which of its fields are logged, and encoding
+// it into a JSON entry does not allocate:
+//
+//	func (u User) MarshalLog(enc logs.FieldEncoder) {
+//		enc.AddString("id", u.ID)
+//		enc.AddString("plan", u.Plan)
+//		enc.AddTime("created", u.Created)
+//		// u.Email and u.PasswordHash are left out
+//	}
+//
+//	log.Info("signed up", logs.Object("user", user))
+type LogMarshaler interface {
+	MarshalLog(enc FieldEncoder)
+}
+
+// LogMarshalerFunc adapts a function to a LogMarshaler.
+type LogMarshalerFunc func(enc FieldEncoder)
+
+// MarshalLog calls f(enc).
+func (f LogMarshalerFunc) MarshalLog(enc FieldEncoder) { f(enc) }
+
+// FieldEncoder receives the fields of a LogMarshaler, in the order they
+// are to be written.
+type FieldEncoder interface {
+	AddString(key, value string)
+	AddInt(key string, value int)
+	AddInt64(key string, value int64)
+	AddUint64(key string, value uint64)
+	AddFloat64(key string, value float64)
+	AddBool(key string, value bool)
+	AddTime(key string, value time.Time)
+	AddDuration(key string, value time.Duration)
+	// AddObject adds a nested object.
+	AddObject(key string, value LogMarshaler)
+	// AddField adds a field of any other type, e.g. logs.Err(err).
+	AddField(field Field)
+}
+
+// jsonObjectEncoder is a FieldEncoder appending a JSON object to buf.
+type jsonObjectEncoder struct {
+	buf   []byte
+	empty bool
+}
+
+var jsonObjectEncoderPool = sync.Pool{
+	New: func() any { return new(jsonObjectEncoder) },
+}
+
+// appendObjectJSON appends m to dst as a JSON object.
+func appendObjectJSON(dst []byte, m LogMarshaler) []byte {
+	e := jsonObjectEncoderPool.Get().(*jsonObjectEncoder)
+	e.buf = dst
+	e.appendObject(m)
+	dst = e.buf
+	e.buf = nil
+	jsonObjectEncoderPool.Put(e)
+	return dst
+}
+
+func (e *jsonObjectEncoder) appendObject(m LogMarshaler) {
+	if m == nil {
+		e.buf = append(e.buf, "null"...)
+		return
+	}
+	e.buf = append(e.buf, '{')
+	e.empty = true
+	m.MarshalLog(e)
+	e.buf = append(e.buf, '}')
+	e.empty = false
+}
+
+func (e *jsonObjectEncoder) key(key string) {
+	if !e.empty {
+		e.buf = append(e.buf, ',')
+	}
+	e.empty = false
+	e.buf = enc.AppendJSONKey(e.buf, key)
+}
+
+func (e *jsonObjectEncoder) AddString(key, value string) {
+	e.key(key)
+	e.buf = enc.AppendJSONString(e.buf, value)
+}
+
+func (e *jsonObjectEncoder) AddInt(key string, value int) {
+	e.AddInt64(key, int64(value))
+}
+
+func (e *jsonObjectEncoder) AddInt64(key string, value int64) {
+	e.key(key)
+	e.buf = enc.AppendJSONValue(e.buf, enc.Int64(value))
+}
+
+func (e *jsonObjectEncoder) AddUint64(key string, value uint64) {
+	e.key(key)
+	e.buf = enc.AppendJSONValue(e.buf, enc.Uint64(value))
+}
+
+func (e *jsonObjectEncoder) AddFloat64(key string, value float64) {
+	e.key(key)
+	e.buf = enc.AppendJSONFloat(e.buf, value)
+}
+
+func (e *jsonObjectEncoder) AddBool(key string, value bool) {
+	e.key(key)
+	e.buf = enc.AppendJSONValue(e.buf, enc.Bool(value))
+}
+
+func (e *jsonObjectEncoder) AddTime(key string, value time.Time) {
+	e.key(key)
+	e.buf = enc.AppendJSONValue(e.buf, enc.Time(value))
+}
+
+func (e *jsonObjectEncoder) AddDuration(key string, value time.Duration) {
+	e.key(key)
+	e.buf = enc.AppendJSONValue(e.buf, enc.Duration(value))
+}
+
+func (e *jsonObjectEncoder) AddObject(key string, value LogMarshaler) {
+	e.key(key)
+	e.appendObject(value)
+}
+
+func (e *jsonObjectEncoder) AddField(field Field) {
+	e.key(field.Key)
+	if m, ok := field.Interface.(LogMarshaler); ok && field.Type == FieldTypeObject {
+		e.appendObject(m)
+		return
+	}
+	e.buf = enc.AppendJSONValue(e.buf, field.encValue())
+}
+
+// mapObjectEncoder is a FieldEncoder collecting the fields into a map, for
+// Field.Value.
+type mapObjectEncoder map[string]any
+
+func (m mapObjectEncoder) AddString(key, value string)          { m[key] = value }
+func (m mapObjectEncoder) AddInt(key string, value int)         { m[key] = int64(value) }
+func (m mapObjectEncoder) AddInt64(key string, value int64)     { m[key] = value }
+func (m mapObjectEncoder) AddUint64(key string, value uint64)   { m[key] = value }
+func (m mapObjectEncoder) AddFloat64(key string, value float64) { m[key] = value }
+func (m mapObjectEncoder) AddBool(key string, value bool)       { m[key] = value }
+func (m mapObjectEncoder) AddTime(key string, value time.Time)  { m[key] = value }
+func (m mapObjectEncoder) AddField(field Field)                 { m[field.Key] = field.Value() }
+
+func (m mapObjectEncoder) AddDuration(key string, value time.Duration) {
+	m[key] = value
+}
+
+func (m mapObjectEncoder) AddObject(key string, value LogMarshaler) {
+	m[key] = objectMap(value)
+}
+
+// objectMap returns the fields of v as a map.
+func objectMap(v LogMarshaler) map[string]any {
+	if v == nil {
+		return nil
+	}
+	m := make(mapObjectEncoder)
+	v.MarshalLog(m)
+	return m
+}
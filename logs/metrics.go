@@ -0,0 +1,52 @@
+package logs
+
+import (
+	"io"
+
+	"github.com/kolosys/lumen/metrics"
+)
+
+// Names of the metrics recorded by a MetricsRegistryHook.
+const (
+	LogEntriesMetric   = "lumen_log_entries_total"
+	LogEntrySizeMetric = "lumen_log_entry_size_bytes"
+)
+
+// MetricsRegistryHook counts log entries and records their formatted
+// sizes in a metrics.Registry, labeled by level and logger name:
+//
+//	lumen_log_entries_total{level="error",logger="db"} 3
+//	lumen_log_entry_size_bytes_bucket{level="error",logger="db",le="256"} 2
+//
+// It is a WriteHook, so that it sees the bytes each entry is written as;
+// entries a previous write hook drops are not counted.
+type MetricsRegistryHook struct {
+	entries *metrics.Counter
+	sizes   *metrics.Histogram
+}
+
+// NewMetricsRegistryHook creates a hook recording log metrics in reg, or
+// in metrics.DefaultRegistry() if reg is nil. Entry sizes use the
+// PresetSize buckets.
+//
+//	logs.New(&logs.Options{WriteHooks: []logs.WriteHook{
+//		logs.NewMetricsRegistryHook(reg),
+//	}})
+func NewMetricsRegistryHook(reg *metrics.Registry) *MetricsRegistryHook {
+	if reg == nil {
+		reg = metrics.DefaultRegistry()
+	}
+	return &MetricsRegistryHook{
+		entries: reg.Counter(LogEntriesMetric, "Log entries written, by level and logger.", "level", "logger"),
+		sizes: reg.Histogram(LogEntrySizeMetric, "Size of formatted log entries in bytes.",
+			metrics.PresetBuckets(metrics.PresetSize), "level", "logger"),
+	}
+}
+
+// OnWrite implements WriteHook. It returns data unchanged.
+func (h *MetricsRegistryHook) OnWrite(entry *Entry, data []byte, _ io.Writer) []byte {
+	level, logger := entry.Level.String(), entry.GetString(loggerNameKey)
+	h.entries.Inc(level, logger)
+	h.sizes.Observe(float64(len(data)), level, logger)
+	return data
+}
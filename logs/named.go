@@ -11,6 +11,7 @@ import (
 //	authLog := userLog.Named("auth")        // [users.auth]
 //	log.Info("action")                      // [users.auth] action
 func (l *Logger) Named(name string) *Logger {
+	l = l.current()
 	child := l.clone()
 
 	// Build the full name
@@ -45,6 +46,7 @@ func (l *Logger) setName(name string) {
 
 	// Add new name field at the beginning
 	l.fields = append([]Field{{Key: loggerNameKey, Type: FieldTypeString, String: name}}, newFields...)
+	l.name = name
 }
 
 // loggerNameKey is the field key for logger names.
@@ -53,19 +55,35 @@ const loggerNameKey = "_logger"
 // clone creates a shallow copy of the logger.
 func (l *Logger) clone() *Logger {
 	child := &Logger{
-		output:      l.output,
-		formatter:   l.formatter,
-		hooks:       l.hooks,
-		callerDepth: l.callerDepth,
-		addCaller:   l.addCaller,
-		addStack:    l.addStack,
-		async:       l.async,
-		asyncCh:     l.asyncCh,
-		entryPool:   l.entryPool,
-		sampler:     l.sampler,
-		fields:      make([]Field, len(l.fields)),
+		output:     l.output,
+		formatter:  l.formatter,
+		hooks:      l.hooks,
+		callerSkip: l.callerSkip,
+		callerFmt:  l.callerFmt,
+		addFunc:    l.addFunc,
+		addCaller:  l.addCaller,
+		stack:      l.stack,
+		exitFunc:   l.exitFunc,
+		async:      l.async,
+		asyncCh:    l.asyncCh,
+		entryPool:  l.entryPool,
+		sampler:    l.sampler,
+		ctxFields:  l.ctxFields,
+		redactor:   l.redactor,
+		processors: l.processors,
+		writeHooks: l.writeHooks,
+		traceCorr:  l.traceCorr,
+		errEncoder: l.errEncoder,
+		addGoID:    l.addGoID,
+		name:       l.name,
+		family:     l.family,
+		root:       l.root,
+		fields:     make([]Field, len(l.fields)),
 	}
 	child.level.Store(l.level.Load())
+	child.familyGen.Store(l.familyGen.Load())
+	child.recorder = l.recorder
+	child.recordLevel.Store(l.recordLevel.Load())
 	copy(child.fields, l.fields)
 	return child
 }
@@ -0,0 +1,50 @@
+package logs
+
+import (
+	"os"
+	"time"
+)
+
+// DevelopmentOptions returns the options NewDevelopment uses, for
+// adjusting before calling New.
+func DevelopmentOptions() *Options {
+	return &Options{
+		Output:    os.Stderr,
+		Level:     DebugLevel,
+		Formatter: &PrettyFormatter{ShowCaller: true, ShowTimestamp: true},
+		AddCaller: true,
+		AddStack:  true,
+	}
+}
+
+// NewDevelopment creates a logger for local development: colored, human
+// readable output on stderr from debug level, with callers and, for
+// errors, stack traces.
+func NewDevelopment() *Logger {
+	return New(DevelopmentOptions())
+}
+
+// ProductionOptions returns the options NewProduction uses, for adjusting
+// before calling New.
+func ProductionOptions() *Options {
+	rate := NewRateSampler(100, time.Second)
+	return &Options{
+		Output:    os.Stdout,
+		Level:     InfoLevel,
+		Formatter: &JSONFormatter{},
+		// Each message is logged at most 100 times a second at info and
+		// below; warnings and errors are never dropped.
+		Sampler: NewLevelSampler(nil).
+			WithLevel(InfoLevel, rate).
+			WithLevel(DebugLevel, rate).
+			WithLevel(TraceLevel, rate),
+		AsyncBufferSize: 4096,
+	}
+}
+
+// NewProduction creates a logger for production: JSON on stdout from info
+// level, sampled and written asynchronously. Close it before the process
+// exits to flush buffered entries.
+func NewProduction() *Logger {
+	return New(ProductionOptions())
+}
@@ -12,68 +12,69 @@ import (
 // Tracef logs a formatted message at trace level.
 func (l *Logger) Tracef(format string, args ...any) {
 	if l.IsEnabled(TraceLevel) {
-		l.log(TraceLevel, fmt.Sprintf(format, args...), nil)
+		l.log(context.Background(), TraceLevel, fmt.Sprintf(format, args...), nil)
 	}
 }
 
 // Debugf logs a formatted message at debug level.
 func (l *Logger) Debugf(format string, args ...any) {
 	if l.IsEnabled(DebugLevel) {
-		l.log(DebugLevel, fmt.Sprintf(format, args...), nil)
+		l.log(context.Background(), DebugLevel, fmt.Sprintf(format, args...), nil)
 	}
 }
 
 // Infof logs a formatted message at info level.
 func (l *Logger) Infof(format string, args ...any) {
 	if l.IsEnabled(InfoLevel) {
-		l.log(InfoLevel, fmt.Sprintf(format, args...), nil)
+		l.log(context.Background(), InfoLevel, fmt.Sprintf(format, args...), nil)
 	}
 }
 
 // Warnf logs a formatted message at warn level.
 func (l *Logger) Warnf(format string, args ...any) {
 	if l.IsEnabled(WarnLevel) {
-		l.log(WarnLevel, fmt.Sprintf(format, args...), nil)
+		l.log(context.Background(), WarnLevel, fmt.Sprintf(format, args...), nil)
 	}
 }
 
 // Errorf logs a formatted message at error level.
 func (l *Logger) Errorf(format string, args ...any) {
 	if l.IsEnabled(ErrorLevel) {
-		l.log(ErrorLevel, fmt.Sprintf(format, args...), nil)
+		l.log(context.Background(), ErrorLevel, fmt.Sprintf(format, args...), nil)
 	}
 }
 
 // Fatalf logs a formatted message at fatal level and exits.
 func (l *Logger) Fatalf(format string, args ...any) {
-	l.log(FatalLevel, fmt.Sprintf(format, args...), nil)
+	l.log(context.Background(), FatalLevel, fmt.Sprintf(format, args...), nil)
+	l.exit()
 }
 
 // Panicf logs a formatted message at panic level and panics.
 func (l *Logger) Panicf(format string, args ...any) {
 	msg := fmt.Sprintf(format, args...)
-	l.log(PanicLevel, msg, nil)
+	l.log(context.Background(), PanicLevel, msg, nil)
 	panic(msg)
 }
 
 // Printf logs a formatted message at info level (stdlib log compatibility).
 func (l *Logger) Printf(format string, args ...any) {
 	if l.IsEnabled(InfoLevel) {
-		l.log(InfoLevel, fmt.Sprintf(format, args...), nil)
+		l.log(context.Background(), InfoLevel, fmt.Sprintf(format, args...), nil)
 	}
 }
 
 // Print logs a message at info level (stdlib log compatibility).
 func (l *Logger) Print(args ...any) {
 	if l.IsEnabled(InfoLevel) {
-		l.log(InfoLevel, fmt.Sprint(args...), nil)
+		l.log(context.Background(), InfoLevel, fmt.Sprint(args...), nil)
 	}
 }
 
 // Println logs a message at info level (stdlib log compatibility).
 func (l *Logger) Println(args ...any) {
 	if l.IsEnabled(InfoLevel) {
-		l.log(InfoLevel, fmt.Sprint(args...), nil)
+		l.log(context.Background(), InfoLevel, fmt.Sprint(args...), nil)
 	}
 }
 
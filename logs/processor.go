@@ -0,0 +1,52 @@
+package logs
+
+// Processor transforms entries before they reach hooks, the recorder and
+// the formatter. Processors run in order, each receiving the entry the
+// previous one returned; they may modify it in place, as it is not shared
+// until the chain completes, or return a different entry. Returning nil
+// drops the entry.
+//
+// Processors make enrichment, rewriting, filtering and redaction
+// composable stages:
+//
+//	logs.New(&logs.Options{Processors: []logs.Processor{
+//		logs.ProcessorFunc(func(e *logs.Entry) *logs.Entry {
+//			if e.Message == "health check" {
+//				return nil
+//			}
+//			e.Fields = append(e.Fields, logs.String("region", region))
+//			return e
+//		}),
+//		logs.NewRedactor(),
+//	}})
+type Processor interface {
+	Process(entry *Entry) *Entry
+}
+
+// ProcessorFunc adapts a function to the Processor interface.
+type ProcessorFunc func(entry *Entry) *Entry
+
+// Process implements Processor.
+func (f ProcessorFunc) Process(entry *Entry) *Entry {
+	return f(entry)
+}
+
+// Process implements Processor, redacting entry in place.
+func (r *Redactor) Process(entry *Entry) *Entry {
+	r.RedactEntry(entry)
+	return entry
+}
+
+// process runs the processors and then the redactor on e, returning nil
+// if a processor dropped it. l.mu must be held.
+func (l *Logger) process(e *Entry) *Entry {
+	for _, p := range l.processors {
+		if e = p.Process(e); e == nil {
+			return nil
+		}
+	}
+	if l.redactor != nil {
+		l.redactor.RedactEntry(e)
+	}
+	return e
+}
@@ -0,0 +1,188 @@
+package logs
+
+import (
+	"regexp"
+
+	"github.com/kolosys/lumen/internal/redact"
+)
+
+// RedactedValue replaces the values of redacted fields.
+const RedactedValue = "[REDACTED]"
+
+var (
+	creditCardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// DefaultRedactedKeys returns key patterns covering common credentials.
+// trace.DefaultRedactionRules drops span attributes matching the same
+// patterns.
+func DefaultRedactedKeys() []string {
+	return redact.CredentialKeys()
+}
+
+// scrubber replaces the matches of a pattern in string values.
+type scrubber struct {
+	pattern *regexp.Regexp
+	replace func(match string) string
+}
+
+// Redactor masks sensitive data in entries before they reach hooks and
+// the formatter. It replaces the values of fields whose keys match its
+// key patterns with RedactedValue, rewrites matches of its scrubbers in
+// the message and string field values, and then applies its custom
+// functions to every field.
+//
+//	redactor := logs.NewRedactor("password", "*token*", "ssn").
+//		ScrubCreditCards().
+//		ScrubEmails()
+//	logger := logs.New(&logs.Options{Redactor: redactor})
+//
+// A Redactor must not be modified once it is in use.
+type Redactor struct {
+	keys      redact.Patterns
+	scrubbers []scrubber
+	funcs     []func(Field) Field
+}
+
+// NewRedactor creates a Redactor masking the fields whose keys match one
+// of the case-insensitive glob patterns given, e.g. "password" or
+// "*token*". The patterns use path.Match syntax, as do the rules of
+// trace.RedactProcessor. If none are given, DefaultRedactedKeys is used.
+func NewRedactor(keys ...string) *Redactor {
+	if len(keys) == 0 {
+		keys = DefaultRedactedKeys()
+	}
+	return &Redactor{keys: redact.Compile(keys)}
+}
+
+// Scrub replaces the matches of pattern in messages and string values
+// with replacement, which may refer to submatches as in
+// regexp.Regexp.ReplaceAllString.
+func (r *Redactor) Scrub(pattern *regexp.Regexp, replacement string) *Redactor {
+	r.scrubbers = append(r.scrubbers, scrubber{
+		pattern: pattern,
+		replace: func(match string) string {
+			return pattern.ReplaceAllString(match, replacement)
+		},
+	})
+	return r
+}
+
+// ScrubCreditCards masks payment card numbers, written with or without
+// space or dash separators, keeping their last four digits. Only numbers
+// passing the Luhn check are masked, so most other long numbers, such as
+// IDs and timestamps, are left alone.
+func (r *Redactor) ScrubCreditCards() *Redactor {
+	r.scrubbers = append(r.scrubbers, scrubber{
+		pattern: creditCardPattern,
+		replace: func(match string) string {
+			digits := make([]byte, 0, len(match))
+			for i := 0; i < len(match); i++ {
+				if c := match[i]; c >= '0' && c <= '9' {
+					digits = append(digits, c)
+				}
+			}
+			if !luhnValid(digits) {
+				return match
+			}
+			return "[CARD ****" + string(digits[len(digits)-4:]) + "]"
+		},
+	})
+	return r
+}
+
+// ScrubEmails masks email addresses.
+func (r *Redactor) ScrubEmails() *Redactor {
+	r.scrubbers = append(r.scrubbers, scrubber{
+		pattern: emailPattern,
+		replace: func(string) string { return "[EMAIL]" },
+	})
+	return r
+}
+
+// WithFunc adds a function applied to every field after key matching and
+// scrubbing, e.g. to hash user IDs or drop a field's detail. It returns
+// the field to log in place of the one given.
+func (r *Redactor) WithFunc(fn func(Field) Field) *Redactor {
+	r.funcs = append(r.funcs, fn)
+	return r
+}
+
+// Redact returns field with the Redactor's rules applied.
+func (r *Redactor) Redact(field Field) Field {
+	if r.matchKey(field.Key) {
+		field = String(field.Key, RedactedValue)
+	} else if g, ok := field.Interface.(groupFields); ok {
+		redacted := make(groupFields, len(g))
+		for i, f := range g {
+			redacted[i] = r.Redact(f)
+		}
+		field.Interface = redacted
+	} else if len(r.scrubbers) > 0 {
+		field = r.scrubField(field)
+	}
+	for _, fn := range r.funcs {
+		field = fn(field)
+	}
+	return field
+}
+
+// RedactEntry applies the Redactor's rules to the message and fields of
+// entry in place.
+func (r *Redactor) RedactEntry(entry *Entry) {
+	entry.Message = r.scrub(entry.Message)
+	for i := range entry.Fields {
+		entry.Fields[i] = r.Redact(entry.Fields[i])
+	}
+}
+
+func (r *Redactor) matchKey(key string) bool {
+	if key != "" && key[0] == '_' {
+		return false
+	}
+	return r.keys.Index(key) >= 0
+}
+
+// scrubField scrubs the text of fields that have one. Error fields keep
+// their type, so formatters still see the error, but their message is
+// scrubbed; other values that change become string fields.
+func (r *Redactor) scrubField(field Field) Field {
+	switch field.Type {
+	case FieldTypeString, FieldTypeError:
+		field.String = r.scrub(field.String)
+		return field
+	case FieldTypeStringer, FieldTypeBytes, FieldTypeAny, FieldTypeArray:
+		if field.Interface == nil {
+			return field
+		}
+		s := field.StringValue()
+		if scrubbed := r.scrub(s); scrubbed != s {
+			return String(field.Key, scrubbed)
+		}
+	}
+	return field
+}
+
+func (r *Redactor) scrub(s string) string {
+	for _, sc := range r.scrubbers {
+		s = sc.pattern.ReplaceAllStringFunc(s, sc.replace)
+	}
+	return s
+}
+
+// luhnValid reports whether digits pass the Luhn checksum used by card
+// numbers.
+func luhnValid(digits []byte) bool {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 0 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,228 @@
+package logs
+
+import (
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// levelRule sets the level of the loggers whose names match pattern.
+type levelRule struct {
+	pattern string
+	level   Level
+}
+
+// levels is the registry of per-name levels. gen is bumped on every
+// change so loggers know to re-resolve their cached level; it stays 0
+// until the first rule is set, keeping the check free for programs that
+// never use the registry.
+var levels struct {
+	mu    sync.RWMutex
+	rules []levelRule
+	gen   atomic.Uint64
+}
+
+// SetLevelFor sets the level of all named loggers whose names match
+// pattern, a glob as in path.Match, e.g. "gateway" or "gateway.shard.*".
+// It applies at once to existing loggers as well as ones created later,
+// and takes precedence over levels set with Logger.SetLevel.
+//
+// A rule also applies to the descendants of the loggers it matches:
+// "gateway" sets the level of gateway.shard.0 too, unless a rule matches a
+// longer prefix of its name, such as "gateway.shard.*". Of rules matching
+// the same prefix, the last set wins.
+func SetLevelFor(pattern string, level Level) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	for i, r := range levels.rules {
+		if r.pattern == pattern {
+			levels.rules = append(levels.rules[:i], levels.rules[i+1:]...)
+			break
+		}
+	}
+	levels.rules = append(levels.rules, levelRule{pattern: pattern, level: level})
+	levels.gen.Add(1)
+}
+
+// ClearLevelFor removes the rule set for pattern by SetLevelFor. The
+// loggers it matched return to the level of the next closest rule, or to
+// their own.
+func ClearLevelFor(pattern string) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	for i, r := range levels.rules {
+		if r.pattern == pattern {
+			levels.rules = append(levels.rules[:i], levels.rules[i+1:]...)
+			levels.gen.Add(1)
+			return
+		}
+	}
+}
+
+// ResetLevels removes all rules set by SetLevelFor.
+func ResetLevels() {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	if len(levels.rules) > 0 {
+		levels.rules = nil
+		levels.gen.Add(1)
+	}
+}
+
+// LevelRules returns the patterns set by SetLevelFor and their levels.
+func LevelRules() map[string]Level {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	rules := make(map[string]Level, len(levels.rules))
+	for _, r := range levels.rules {
+		rules[r.pattern] = r.level
+	}
+	return rules
+}
+
+// LevelFor returns the level set by SetLevelFor for loggers named name,
+// and whether any rule applies to it.
+func LevelFor(name string) (Level, bool) {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	return levelFor(name)
+}
+
+// levelFor resolves the level of name, trying the name and then each
+// shorter dotted prefix against the rules, newest first. levels.mu must
+// be held.
+func levelFor(name string) (Level, bool) {
+	if name == "" {
+		return 0, false
+	}
+	for prefix := name; ; {
+		for i := len(levels.rules) - 1; i >= 0; i-- {
+			if ok, _ := path.Match(levels.rules[i].pattern, prefix); ok {
+				return levels.rules[i].level, true
+			}
+		}
+		dot := strings.LastIndexByte(prefix, '.')
+		if dot < 0 {
+			return 0, false
+		}
+		prefix = prefix[:dot]
+	}
+}
+
+// effectiveLevel returns the level of the registry rule applying to l,
+// or l's own level. The resolved rule is cached along with the registry
+// generation it was resolved at.
+func (l *Logger) effectiveLevel() Level {
+	l.sync()
+	gen := levels.gen.Load()
+	if gen == 0 || l.name == "" {
+		return Level(l.level.Load())
+	}
+	// The cache holds the generation in its upper bits and the level
+	// plus one, or zero if no rule applies, in its lowest byte.
+	cached := l.levelCache.Load()
+	if cached>>8 != gen {
+		levels.mu.RLock()
+		level, ok := levelFor(l.name)
+		gen = levels.gen.Load()
+		levels.mu.RUnlock()
+		cached = gen << 8
+		if ok {
+			cached |= uint64(level) + 1
+		}
+		l.levelCache.Store(cached)
+	}
+	if cached&0xff != 0 {
+		return Level(cached&0xff - 1)
+	}
+	return Level(l.level.Load())
+}
+
+// named holds the loggers returned by GetNamed.
+var named struct {
+	mu      sync.RWMutex
+	loggers map[string]*Logger
+}
+
+// defaultGen counts SetDefault calls, so the loggers returned by GetNamed
+// know to derive from the new default logger.
+var defaultGen atomic.Uint64
+
+// follower links a logger returned by GetNamed to the default logger: the
+// logger acts through target, a child of the default logger named name,
+// derived again after each SetDefault.
+type follower struct {
+	name   string
+	mu     sync.Mutex
+	gen    atomic.Uint64
+	target atomic.Pointer[Logger]
+}
+
+// current returns the logger l acts through: l itself, or for a logger
+// returned by GetNamed, its child of the current default logger.
+func (l *Logger) current() *Logger {
+	f := l.follow
+	if f == nil {
+		return l
+	}
+	gen := defaultGen.Load()
+	if f.gen.Load() == gen {
+		return f.target.Load()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.gen.Load() != gen {
+		f.target.Store(Default().Named(f.name))
+		f.gen.Store(gen)
+	}
+	return f.target.Load()
+}
+
+// GetNamed returns the logger named name, creating it from the default
+// logger on first use. Every call with the same name returns the same
+// logger, so a library can fetch its component logger wherever it needs
+// it rather than having one passed down:
+//
+//	var log = logs.GetNamed("gateway.shard")
+//
+// The logger follows the default logger, so one fetched during package
+// initialization picks up the output, level and fields of a default set
+// later with SetDefault. Settings made on it directly, such as SetLevel,
+// last until the next SetDefault; use SetLevelFor for per-name levels.
+// Loggers derived from it with With or Named do not follow.
+func GetNamed(name string) *Logger {
+	named.mu.RLock()
+	l, ok := named.loggers[name]
+	named.mu.RUnlock()
+	if ok {
+		return l
+	}
+
+	named.mu.Lock()
+	defer named.mu.Unlock()
+	if l, ok := named.loggers[name]; ok {
+		return l
+	}
+	if named.loggers == nil {
+		named.loggers = make(map[string]*Logger)
+	}
+	f := &follower{name: name}
+	f.target.Store(defaultLogger.Named(name))
+	f.gen.Store(defaultGen.Load())
+	l = defaultLogger.Named(name)
+	l.follow = f
+	named.loggers[name] = l
+	return l
+}
+
+// NamedLoggers returns the loggers created by GetNamed, by name.
+func NamedLoggers() map[string]*Logger {
+	named.mu.RLock()
+	defer named.mu.RUnlock()
+	out := make(map[string]*Logger, len(named.loggers))
+	for name, l := range named.loggers {
+		out[name] = l
+	}
+	return out
+}
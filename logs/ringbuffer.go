@@ -0,0 +1,133 @@
+package logs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/kolosys/lumen/internal/report"
+)
+
+// RingBufferOptions configures a RingBufferHook.
+type RingBufferOptions struct {
+	// Size is the number of entries kept. Default: 1000
+	Size int
+
+	// Formatter formats dumped entries. Defaults to a JSONFormatter.
+	Formatter Formatter
+
+	// DumpTo, if set, receives a dump of the buffer whenever an entry at
+	// DumpLevel or above is fired, e.g. os.Stderr. The buffer is cleared
+	// after each such dump, so entries are dumped at most once.
+	DumpTo io.Writer
+
+	// DumpLevel is the least severe level that triggers a dump to DumpTo.
+	// Defaults to ErrorLevel.
+	DumpLevel Level
+}
+
+func (o *RingBufferOptions) applyDefaults() {
+	if o.Size <= 0 {
+		o.Size = 1000
+	}
+	if o.Formatter == nil {
+		o.Formatter = &JSONFormatter{}
+	}
+	if o.DumpLevel == 0 {
+		o.DumpLevel = ErrorLevel
+	}
+}
+
+// RingBufferHook is a flight recorder: it keeps the last entries of every
+// level in memory and dumps them when an error occurs or an operator asks,
+// giving the context of a failure without verbose steady-state logging.
+// Install it as the logger's recorder so that it also receives entries
+// below the logger's level, which are otherwise discarded:
+//
+//	ring := logs.NewRingBufferHook(&logs.RingBufferOptions{DumpTo: os.Stderr})
+//	logger.SetRecorder(ring, logs.TraceLevel)
+//	http.Handle("/debug/logs", ring)
+type RingBufferHook struct {
+	*RingHook
+	opts RingBufferOptions
+
+	// dumpMu serializes dumps, so concurrent ones are not interleaved.
+	dumpMu sync.Mutex
+}
+
+// NewRingBufferHook creates a RingBufferHook.
+func NewRingBufferHook(opts *RingBufferOptions) *RingBufferHook {
+	h := &RingBufferHook{}
+	if opts != nil {
+		h.opts = *opts
+	}
+	h.opts.applyDefaults()
+	h.RingHook = NewRingHook(h.opts.Size)
+	return h
+}
+
+// Fire implements Hook, dumping the buffer to DumpTo if the entry is
+// severe enough.
+func (h *RingBufferHook) Fire(entry *Entry) {
+	h.RingHook.Fire(entry)
+	if h.opts.DumpTo == nil || entry.Level > h.opts.DumpLevel {
+		return
+	}
+	h.dumpMu.Lock()
+	defer h.dumpMu.Unlock()
+	if err := h.dump(h.opts.DumpTo, h.drain()); err != nil {
+		report.Error(report.LogHook, fmt.Errorf("logs: ring buffer dump: %w", err))
+	}
+}
+
+// Dump writes the buffered entries to w, oldest first, without clearing
+// the buffer.
+func (h *RingBufferHook) Dump(w io.Writer) error {
+	h.dumpMu.Lock()
+	defer h.dumpMu.Unlock()
+	return h.dump(w, h.Entries())
+}
+
+// ServeHTTP implements http.Handler, responding with a dump of the
+// buffer.
+func (h *RingBufferHook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := h.opts.Formatter.(*JSONFormatter); ok {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	h.Dump(w)
+}
+
+// drain returns the buffered entries and clears the buffer.
+func (h *RingBufferHook) drain() []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []Entry
+	if h.full {
+		out = append(out, h.entries[h.next:]...)
+	}
+	out = append(out, h.entries[:h.next]...)
+	clear(h.entries)
+	h.next, h.full = 0, false
+	return out
+}
+
+func (h *RingBufferHook) dump(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	for i := range entries {
+		data, err := h.opts.Formatter.Format(&entries[i])
+		if err != nil {
+			return err
+		}
+		bw.Write(data)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,328 @@
+package logs
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+)
+
+// backupTimeFormat is the timestamp in the names of rotated files, in UTC.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFileOptions configures a RotatingFileWriter.
+type RotatingFileOptions struct {
+	// MaxSize is the size in bytes a file may reach before it is rotated.
+	// Defaults to 100 MiB.
+	MaxSize int64
+
+	// MaxBackups is the number of rotated files to keep. Zero keeps them
+	// all, unless MaxAge removes them.
+	MaxBackups int
+
+	// MaxAge removes rotated files older than this. Zero keeps them
+	// regardless of age.
+	MaxAge time.Duration
+
+	// Compress gzips rotated files, adding .gz to their names.
+	Compress bool
+
+	// FileMode is the permission of new files. Defaults to 0644.
+	FileMode fs.FileMode
+}
+
+func (o *RotatingFileOptions) applyDefaults() {
+	if o.MaxSize <= 0 {
+		o.MaxSize = 100 << 20
+	}
+	if o.FileMode == 0 {
+		o.FileMode = 0644
+	}
+}
+
+// RotatingFileWriter is an io.Writer that appends to a file and rotates it
+// when it would grow past MaxSize. The full file is renamed alongside it
+// with the time of rotation, e.g. app-2024-05-01T10-30-00.000.log for
+// app.log, and a new file is started. A write never spans two files.
+//
+// Use it as the Output of a logger, or of a WriterHook:
+//
+//	w, err := logs.NewRotatingFileWriter("/var/log/app/app.log", &logs.RotatingFileOptions{
+//		MaxSize:    50 << 20,
+//		MaxBackups: 10,
+//		MaxAge:     7 * 24 * time.Hour,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer w.Close()
+//	log := logs.New(&logs.Options{Output: w, Formatter: &logs.JSONFormatter{}})
+type RotatingFileWriter struct {
+	path string
+	opts RotatingFileOptions
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	archive archiver
+}
+
+// NewRotatingFileWriter opens path for appending, creating it and its
+// directory if needed.
+func NewRotatingFileWriter(path string, opts *RotatingFileOptions) (*RotatingFileWriter, error) {
+	w := &RotatingFileWriter{path: path}
+	if opts != nil {
+		w.opts = *opts
+	}
+	w.opts.applyDefaults()
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write implements io.Writer.
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file now, e.g. on SIGHUP.
+func (w *RotatingFileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Sync commits the file to stable storage.
+func (w *RotatingFileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.file.Sync()
+}
+
+// Close closes the file, after waiting for rotated files to be
+// compressed and removed. Later writes fail with os.ErrClosed.
+func (w *RotatingFileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	w.archive.wait()
+	return err
+}
+
+func (w *RotatingFileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, w.opts.FileMode)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, fi.Size()
+	return nil
+}
+
+// rotate renames the current file to a backup and opens a new one. The
+// rename is atomic, so readers see either the full old file or the new
+// one at path.
+func (w *RotatingFileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	if err := os.Rename(w.path, w.newBackupName()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		// Keep writing to the full file rather than losing entries.
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.archive.run(w.cleanup)
+	return nil
+}
+
+// newBackupName returns the name for a backup rotated now. Rotations
+// within the same millisecond are given later times rather than replace
+// each other.
+func (w *RotatingFileWriter) newBackupName() string {
+	prefix, ext := w.backupPattern()
+	for t := time.Now(); ; t = t.Add(time.Millisecond) {
+		name := prefix + t.UTC().Format(backupTimeFormat) + ext
+		if _, err := os.Lstat(name); errors.Is(err, fs.ErrNotExist) {
+			return name
+		}
+	}
+}
+
+// backupPattern returns what the names of backups start and end with.
+func (w *RotatingFileWriter) backupPattern() (prefix, ext string) {
+	ext = filepath.Ext(w.path)
+	return strings.TrimSuffix(w.path, ext) + "-", ext
+}
+
+// cleanup removes the backups beyond MaxBackups or older than MaxAge and
+// compresses the others if Compress is set.
+func (w *RotatingFileWriter) cleanup() error {
+	if w.opts.MaxBackups <= 0 && w.opts.MaxAge <= 0 && !w.opts.Compress {
+		return nil
+	}
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var errs []error
+	for i, b := range backups {
+		tooMany := w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups
+		tooOld := w.opts.MaxAge > 0 && now.Sub(b.time) > w.opts.MaxAge
+		errs = append(errs, b.retire(tooMany || tooOld, w.opts.Compress))
+	}
+	return errors.Join(errs...)
+}
+
+// backup is a rotated log file.
+type backup struct {
+	path string
+	time time.Time
+}
+
+// retire removes the backup, or compresses it if it is not already.
+func (b backup) retire(remove, compress bool) error {
+	switch {
+	case remove:
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	case compress && !strings.HasSuffix(b.path, compressSuffix):
+		return compressFile(b.path)
+	}
+	return nil
+}
+
+// backups returns the rotated files of path, newest first.
+func (w *RotatingFileWriter) backups() ([]backup, error) {
+	prefix, ext := w.backupPattern()
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(prefix)
+	var backups []backup
+	for _, e := range entries {
+		name := e.Name()
+		stamp := strings.TrimSuffix(name, compressSuffix)
+		if e.IsDir() || !strings.HasPrefix(stamp, base) || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(stamp[len(base):], ext))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(filepath.Dir(w.path), name), time: t})
+	}
+	slices.SortFunc(backups, func(a, b backup) int { return b.time.Compare(a.time) })
+	return backups, nil
+}
+
+// compressSuffix is added to the names of compressed log files.
+const compressSuffix = ".gz"
+
+// compressFile gzips path to path.gz and removes path. The archive is
+// written under a temporary name first, so a partial one is never left
+// under the final name.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + compressSuffix + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode())
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+compressSuffix)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// archiver compresses and removes rotated files off the write path, one
+// run at a time.
+type archiver struct {
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+// run calls fn in the background, reporting its error.
+func (a *archiver) run(fn func() error) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if err := fn(); err != nil {
+			report.Error(report.Logs, fmt.Errorf("logs: archive rotated files: %w", err))
+		}
+	}()
+}
+
+// wait waits for the runs started so far.
+func (a *archiver) wait() { a.wg.Wait() }
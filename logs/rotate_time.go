@@ -0,0 +1,230 @@
+package logs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotationPeriod is how often a TimedRotatingWriter starts a new file.
+type RotationPeriod int
+
+const (
+	// RotateDaily starts a file at midnight, named like app-2024-05-01.log.
+	RotateDaily RotationPeriod = iota
+	// RotateHourly starts a file each hour, named like app-2024-05-01T15.log.
+	RotateHourly
+)
+
+// layout is the time layout of the file names of a period.
+func (p RotationPeriod) layout() string {
+	if p == RotateHourly {
+		return "2006-01-02T15"
+	}
+	return "2006-01-02"
+}
+
+// start returns the start of the period containing t.
+func (p RotationPeriod) start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	if p == RotateHourly {
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// next returns the start of the period after the one starting at start.
+func (p RotationPeriod) next(start time.Time) time.Time {
+	if p == RotateHourly {
+		return start.Add(time.Hour)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// TimedRotatingOptions configures a TimedRotatingWriter.
+type TimedRotatingOptions struct {
+	// Period is how often a new file is started. Defaults to RotateDaily.
+	Period RotationPeriod
+
+	// MaxAge is the retention window: files are removed once the period
+	// they cover ended more than MaxAge ago. Zero keeps them all.
+	MaxAge time.Duration
+
+	// Compress gzips the files of past periods, adding .gz to their names.
+	Compress bool
+
+	// UTC names and starts periods in UTC instead of local time.
+	UTC bool
+
+	// FileMode is the permission of new files. Defaults to 0644.
+	FileMode fs.FileMode
+}
+
+func (o *TimedRotatingOptions) applyDefaults() {
+	if o.FileMode == 0 {
+		o.FileMode = 0644
+	}
+}
+
+// TimedRotatingWriter is an io.Writer that writes each day's or hour's
+// entries to their own file. The period is inserted into the name given,
+// so app.log is written as app-2024-05-01.log, then app-2024-05-02.log.
+// Files of past periods are compressed and removed in the background
+// according to the retention policy, including those left by a previous
+// run of the process.
+//
+//	w, err := logs.NewTimedRotatingWriter("/var/log/app/app.log", &logs.TimedRotatingOptions{
+//		MaxAge:   30 * 24 * time.Hour,
+//		Compress: true,
+//	})
+type TimedRotatingWriter struct {
+	path string
+	opts TimedRotatingOptions
+
+	mu      sync.Mutex
+	file    *os.File
+	name    string
+	next    time.Time
+	archive archiver
+}
+
+// NewTimedRotatingWriter opens the file of the current period of path for
+// appending, creating it and its directory if needed.
+func NewTimedRotatingWriter(path string, opts *TimedRotatingOptions) (*TimedRotatingWriter, error) {
+	w := &TimedRotatingWriter{path: path}
+	if opts != nil {
+		w.opts = *opts
+	}
+	w.opts.applyDefaults()
+	if err := w.open(w.now()); err != nil {
+		return nil, err
+	}
+	w.archive.run(w.cleanup(w.name))
+	return w, nil
+}
+
+// Write implements io.Writer.
+func (w *TimedRotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if now := w.now(); !now.Before(w.next) {
+		if err := w.file.Close(); err != nil {
+			return 0, err
+		}
+		w.file = nil
+		if err := w.open(now); err != nil {
+			return 0, err
+		}
+		w.archive.run(w.cleanup(w.name))
+	}
+	return w.file.Write(p)
+}
+
+// Name returns the path of the file being written.
+func (w *TimedRotatingWriter) Name() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.name
+}
+
+// Sync commits the file to stable storage.
+func (w *TimedRotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.file.Sync()
+}
+
+// Close closes the file, after waiting for past files to be compressed
+// and removed. Later writes fail with os.ErrClosed.
+func (w *TimedRotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	w.archive.wait()
+	return err
+}
+
+func (w *TimedRotatingWriter) now() time.Time {
+	if w.opts.UTC {
+		return time.Now().UTC()
+	}
+	return time.Now()
+}
+
+// open opens the file of the period containing now.
+func (w *TimedRotatingWriter) open(now time.Time) error {
+	start := w.opts.Period.start(now)
+	prefix, ext := w.pattern()
+	name := prefix + start.Format(w.opts.Period.layout()) + ext
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, w.opts.FileMode)
+	if err != nil {
+		return err
+	}
+	w.file, w.name, w.next = f, name, w.opts.Period.next(start)
+	return nil
+}
+
+// pattern returns what the names of the period files start and end with.
+func (w *TimedRotatingWriter) pattern() (prefix, ext string) {
+	ext = filepath.Ext(w.path)
+	return strings.TrimSuffix(w.path, ext) + "-", ext
+}
+
+// cleanup returns a function removing the files of periods that ended
+// more than MaxAge ago and compressing the other past files if Compress
+// is set. The current file is left alone.
+func (w *TimedRotatingWriter) cleanup(current string) func() error {
+	return func() error {
+		if w.opts.MaxAge <= 0 && !w.opts.Compress {
+			return nil
+		}
+		return w.archivePast(filepath.Base(current))
+	}
+}
+
+func (w *TimedRotatingWriter) archivePast(current string) error {
+	prefix, ext := w.pattern()
+	dir, base := filepath.Dir(prefix), filepath.Base(prefix)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	now := w.now()
+	var errs []error
+	for _, e := range entries {
+		name := e.Name()
+		stamp := strings.TrimSuffix(name, compressSuffix)
+		if e.IsDir() || name == current || !strings.HasPrefix(stamp, base) || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		start, err := time.ParseInLocation(w.opts.Period.layout(), strings.TrimSuffix(stamp[len(base):], ext), now.Location())
+		if err != nil {
+			continue
+		}
+		end := w.opts.Period.next(start)
+		if end.After(now) {
+			continue
+		}
+		old := w.opts.MaxAge > 0 && now.Sub(end) > w.opts.MaxAge
+		errs = append(errs, backup{path: filepath.Join(dir, name), time: end}.retire(old, w.opts.Compress))
+	}
+	return errors.Join(errs...)
+}
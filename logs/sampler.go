@@ -1,6 +1,7 @@
 package logs
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,6 +13,22 @@ type Sampler interface {
 	Sample(level Level, msg string) bool
 }
 
+// ContextSampler is a Sampler that also sees the context of the entry.
+// The logger calls SampleContext instead of Sample, with
+// context.Background() for entries logged without a context.
+type ContextSampler interface {
+	Sampler
+	SampleContext(ctx context.Context, level Level, msg string) bool
+}
+
+// sample consults s, passing ctx on if s is a ContextSampler.
+func sample(s Sampler, ctx context.Context, level Level, msg string) bool {
+	if cs, ok := s.(ContextSampler); ok {
+		return cs.SampleContext(ctx, level, msg)
+	}
+	return s.Sample(level, msg)
+}
+
 // RateSampler limits logs to a certain rate per message.
 type RateSampler struct {
 	rate    int           // max logs per interval
@@ -108,11 +125,17 @@ func (s *LevelSampler) WithLevel(level Level, sampler Sampler) *LevelSampler {
 
 // Sample implements Sampler.
 func (s *LevelSampler) Sample(level Level, msg string) bool {
+	return s.SampleContext(context.Background(), level, msg)
+}
+
+// SampleContext implements ContextSampler, passing ctx on to the chosen
+// sampler.
+func (s *LevelSampler) SampleContext(ctx context.Context, level Level, msg string) bool {
 	if sampler, ok := s.samplers[level]; ok {
-		return sampler.Sample(level, msg)
+		return sample(sampler, ctx, level, msg)
 	}
 	if s.fallback != nil {
-		return s.fallback.Sample(level, msg)
+		return sample(s.fallback, ctx, level, msg)
 	}
 	return true
 }
@@ -177,8 +200,13 @@ func NewCompositeSampler(samplers ...Sampler) *CompositeSampler {
 
 // Sample implements Sampler.
 func (s *CompositeSampler) Sample(level Level, msg string) bool {
+	return s.SampleContext(context.Background(), level, msg)
+}
+
+// SampleContext implements ContextSampler, passing ctx on to the samplers.
+func (s *CompositeSampler) SampleContext(ctx context.Context, level Level, msg string) bool {
 	for _, sampler := range s.samplers {
-		if !sampler.Sample(level, msg) {
+		if !sample(sampler, ctx, level, msg) {
 			return false
 		}
 	}
@@ -226,3 +254,66 @@ type NeverSampler struct{}
 func (s *NeverSampler) Sample(level Level, msg string) bool {
 	return false
 }
+
+// DecisionSampler ties verbose entries to a per-request sampling decision,
+// so that a request whose trace is sampled keeps its debug logs and one
+// whose trace is not stays quiet. Entries more verbose than the threshold
+// are kept exactly when the decision in their context says sampled; see
+// WithSamplingDecision. Other entries go to the fallback sampler, if any.
+//
+// The logger's own level must admit the verbose entries, e.g. DebugLevel
+// with a threshold of InfoLevel.
+type DecisionSampler struct {
+	threshold atomic.Int32
+	fallback  Sampler
+	decide    func(ctx context.Context) (sampled, ok bool)
+	undecided bool
+}
+
+// NewDecisionSampler creates a sampler that lets the context decide for
+// entries more verbose than threshold. fallback may be nil.
+func NewDecisionSampler(threshold Level, fallback Sampler) *DecisionSampler {
+	s := &DecisionSampler{fallback: fallback, decide: SamplingDecision}
+	s.threshold.Store(int32(threshold))
+	return s
+}
+
+// WithDecision replaces how the decision is read from the context, e.g.
+// to ask the active span directly. The default is SamplingDecision.
+func (s *DecisionSampler) WithDecision(decide func(ctx context.Context) (sampled, ok bool)) *DecisionSampler {
+	s.decide = decide
+	return s
+}
+
+// WithUndecided sets whether verbose entries without a decision, including
+// those logged without a context, are kept. The default is false.
+func (s *DecisionSampler) WithUndecided(keep bool) *DecisionSampler {
+	s.undecided = keep
+	return s
+}
+
+// SetThreshold changes the threshold.
+func (s *DecisionSampler) SetThreshold(level Level) {
+	s.threshold.Store(int32(level))
+}
+
+// Threshold returns the current threshold.
+func (s *DecisionSampler) Threshold() Level {
+	return Level(s.threshold.Load())
+}
+
+// Sample implements Sampler. Verbose entries have no decision here.
+func (s *DecisionSampler) Sample(level Level, msg string) bool {
+	return s.SampleContext(context.Background(), level, msg)
+}
+
+// SampleContext implements ContextSampler.
+func (s *DecisionSampler) SampleContext(ctx context.Context, level Level, msg string) bool {
+	if level <= s.Threshold() {
+		return s.fallback == nil || sample(s.fallback, ctx, level, msg)
+	}
+	if sampled, ok := s.decide(ctx); ok {
+		return sampled
+	}
+	return s.undecided
+}
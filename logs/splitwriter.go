@@ -0,0 +1,48 @@
+package logs
+
+import (
+	"io"
+	"os"
+)
+
+// LevelWriter is an io.Writer that is told the level of each entry. A
+// logger whose output implements it calls WriteLevel instead of Write.
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(level Level, p []byte) (int, error)
+}
+
+// LevelSplitWriter routes entries at Threshold and above to Stderr and
+// the rest to Stdout, the convention most container log collectors
+// expect.
+//
+//	logs.New(&logs.Options{Output: logs.NewLevelSplitWriter()})
+type LevelSplitWriter struct {
+	// Stdout receives entries below Threshold, and plain writes.
+	Stdout io.Writer
+
+	// Stderr receives entries at Threshold and above.
+	Stderr io.Writer
+
+	// Threshold is the least severe level written to Stderr.
+	Threshold Level
+}
+
+// NewLevelSplitWriter creates a LevelSplitWriter writing warnings and
+// above to os.Stderr and everything else to os.Stdout.
+func NewLevelSplitWriter() *LevelSplitWriter {
+	return &LevelSplitWriter{Stdout: os.Stdout, Stderr: os.Stderr, Threshold: WarnLevel}
+}
+
+// Write implements io.Writer, writing p to Stdout.
+func (w *LevelSplitWriter) Write(p []byte) (int, error) {
+	return w.Stdout.Write(p)
+}
+
+// WriteLevel implements LevelWriter.
+func (w *LevelSplitWriter) WriteLevel(level Level, p []byte) (int, error) {
+	if level <= w.Threshold {
+		return w.Stderr.Write(p)
+	}
+	return w.Stdout.Write(p)
+}
@@ -0,0 +1,414 @@
+// Package sqllog logs the statements run through database/sql by wrapping
+// the driver underneath it:
+//
+//	sqllog.Register("postgres-logged", &pq.Driver{}, &sqllog.Options{
+//		SlowThreshold: 200 * time.Millisecond,
+//		Args:          true,
+//	})
+//	db, err := sql.Open("postgres-logged", dsn)
+//
+// or, for drivers that provide a connector:
+//
+//	db := sql.OpenDB(sqllog.WrapConnector(connector, nil))
+//
+// Each exec and query is logged with its text, duration, rows affected and
+// error, in the context it was run with, so request and trace fields carry
+// over. Successful statements are logged at Level, slow ones at SlowLevel
+// and failed ones at ErrorLevel. Transactions and rows are passed through
+// unchanged.
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/trace/semconv"
+)
+
+// Log field keys. The statement text is logged under db.query.text.
+const (
+	DurationKey     = "db.duration"
+	RowsAffectedKey = "db.rows_affected"
+	ArgsKey         = "db.args"
+)
+
+// Options configures the logging driver.
+type Options struct {
+	// Logger logs the statements. Defaults to the logger of each
+	// statement's context, see logs.LoggerFromContext.
+	Logger *logs.Logger
+
+	// System is logged as db.system, e.g. semconv.DBSystemPostgreSQL.
+	System string
+
+	// Level is the level of statements that succeed within SlowThreshold.
+	// Default: DebugLevel
+	Level logs.Level
+
+	// SlowThreshold, if set, is the duration from which statements are
+	// logged at SlowLevel, as "slow sql exec" or "slow sql query".
+	SlowThreshold time.Duration
+
+	// SlowLevel is the level of slow statements. Default: WarnLevel
+	SlowLevel logs.Level
+
+	// ErrorLevel is the level of failed statements. Default: ErrorLevel
+	ErrorLevel logs.Level
+
+	// Args logs the arguments of statements as a group under ArgsKey,
+	// keyed by name for named arguments and by ordinal otherwise. Byte
+	// slices are logged by their length only.
+	Args bool
+
+	// Redactor, if set, is applied to each argument, so that its key
+	// patterns match the names of named arguments and its scrubbers
+	// their values.
+	Redactor *logs.Redactor
+}
+
+func (o *Options) applyDefaults() {
+	if o.Level == 0 {
+		o.Level = logs.DebugLevel
+	}
+	if o.SlowLevel == 0 {
+		o.SlowLevel = logs.WarnLevel
+	}
+	if o.ErrorLevel == 0 {
+		o.ErrorLevel = logs.ErrorLevel
+	}
+}
+
+// Register registers d, wrapped to log its statements, as a database/sql
+// driver named name. Like sql.Register, it panics if name is taken.
+func Register(name string, d driver.Driver, opts *Options) {
+	sql.Register(name, Wrap(d, opts))
+}
+
+// Wrap returns d wrapped to log the statements run on its connections.
+func Wrap(d driver.Driver, opts *Options) driver.Driver {
+	return &loggingDriver{Driver: d, log: newStatementLogger(opts)}
+}
+
+// WrapConnector returns c wrapped to log the statements run on its
+// connections, for sql.OpenDB.
+func WrapConnector(c driver.Connector, opts *Options) driver.Connector {
+	d := &loggingDriver{Driver: c.Driver(), log: newStatementLogger(opts)}
+	return &connector{Connector: c, driver: d}
+}
+
+// statementLogger logs statements with the options of one driver.
+type statementLogger struct {
+	opts Options
+}
+
+func newStatementLogger(opts *Options) *statementLogger {
+	l := &statementLogger{}
+	if opts != nil {
+		l.opts = *opts
+	}
+	l.opts.applyDefaults()
+	return l
+}
+
+// log logs a statement that started at start. ErrSkip, which asks
+// database/sql to retry the statement another way, is not logged.
+func (l *statementLogger) log(ctx context.Context, op, query string, args []driver.NamedValue, start time.Time, res driver.Result, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	elapsed := time.Since(start)
+	logger := l.opts.Logger
+	if logger == nil {
+		logger = logs.LoggerFromContext(ctx)
+	}
+
+	level, msg := l.opts.Level, "sql "+op
+	switch {
+	case err != nil:
+		level = l.opts.ErrorLevel
+	case l.opts.SlowThreshold > 0 && elapsed >= l.opts.SlowThreshold:
+		level, msg = l.opts.SlowLevel, "slow sql "+op
+	}
+	if !logger.IsEnabled(level) {
+		return
+	}
+
+	fields := make([]logs.Field, 0, 6)
+	if l.opts.System != "" {
+		fields = append(fields, logs.String(semconv.DBSystemKey, l.opts.System))
+	}
+	fields = append(fields, logs.String(semconv.DBStatementKey, query), logs.Duration(DurationKey, elapsed))
+	if res != nil && err == nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			fields = append(fields, logs.Int64(RowsAffectedKey, n))
+		}
+	}
+	if l.opts.Args && len(args) > 0 {
+		fields = append(fields, l.argsField(args))
+	}
+	if err != nil {
+		fields = append(fields, logs.Err(err))
+	}
+	logger.LogContext(ctx, level, msg, fields...)
+}
+
+func (l *statementLogger) argsField(args []driver.NamedValue) logs.Field {
+	fields := make([]logs.Field, len(args))
+	for i, a := range args {
+		key := a.Name
+		if key == "" {
+			key = strconv.Itoa(a.Ordinal)
+		}
+		f := logs.Any(key, a.Value)
+		if b, ok := a.Value.([]byte); ok {
+			f = logs.String(key, fmt.Sprintf("<%d bytes>", len(b)))
+		}
+		if l.opts.Redactor != nil {
+			f = l.opts.Redactor.Redact(f)
+		}
+		fields[i] = f
+	}
+	return logs.Group(ArgsKey, fields...)
+}
+
+// loggingDriver wraps the connections of a driver.
+type loggingDriver struct {
+	driver.Driver
+	log *statementLogger
+}
+
+func (d *loggingDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, log: d.log}, nil
+}
+
+func (d *loggingDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.Driver.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &connector{Connector: c, driver: d}, nil
+	}
+	return &connector{Connector: dsnConnector{name: name, driver: d.Driver}, driver: d}, nil
+}
+
+// dsnConnector is the connector of a driver without one, as in
+// database/sql.
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.name) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.driver }
+
+// connector wraps the connections of a connector.
+type connector struct {
+	driver.Connector
+	driver *loggingDriver
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, log: c.driver.log}, nil
+}
+
+func (c *connector) Driver() driver.Driver { return c.driver }
+
+// Close closes the wrapped connector if it is an io.Closer, as sql.DB.Close
+// does.
+func (c *connector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// conn logs the statements run on a connection. It implements the
+// optional interfaces database/sql looks for, falling back to what
+// database/sql itself does when the wrapped connection lacks them.
+type conn struct {
+	driver.Conn
+	log *statementLogger
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var s driver.Stmt
+	var err error
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = pc.PrepareContext(ctx, query)
+	} else {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		s, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, conn: c, query: query}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sqllog: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sqllog: driver does not support read-only transactions")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Conn.Begin()
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	switch ec := c.Conn.(type) {
+	case driver.ExecerContext:
+		res, err = ec.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var values []driver.Value
+		if values, err = namedValues(ctx, args); err == nil {
+			res, err = ec.Exec(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.log.log(ctx, "exec", query, args, start, res, err)
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	switch qc := c.Conn.(type) {
+	case driver.QueryerContext:
+		rows, err = qc.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var values []driver.Value
+		if values, err = namedValues(ctx, args); err == nil {
+			rows, err = qc.Query(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.log.log(ctx, "query", query, args, start, nil, err)
+	return rows, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// stmt logs the executions of a prepared statement.
+type stmt struct {
+	driver.Stmt
+	conn  *conn
+	query string
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = ec.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(ctx, args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	s.conn.log.log(ctx, "exec", s.query, args, start, res, err)
+	return res, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(ctx, args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	s.conn.log.log(ctx, "query", s.query, args, start, nil, err)
+	return rows, err
+}
+
+// CheckNamedValue defers to the statement's checker, then the
+// connection's, as database/sql does.
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+// namedValues converts args for the pre-context driver interfaces, which
+// take neither names nor a context.
+func namedValues(ctx context.Context, args []driver.NamedValue) ([]driver.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("sqllog: driver does not support the use of Named Parameters")
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,131 @@
+package sqllog_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	. "github.com/kolosys/lumen/logs/sqllog"
+)
+
+// fakeDriver runs no SQL: "FAIL" statements fail, "SLOW" ones take 20ms,
+// and every exec affects 3 rows.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+func (fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	return run(query)
+}
+
+func (fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if _, err := run(query); err != nil {
+		return nil, err
+	}
+	return fakeRows{}, nil
+}
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error                                 { return nil }
+func (fakeStmt) NumInput() int                                { return -1 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) { return run(s.query) }
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"n"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+func run(query string) (driver.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "FAIL"):
+		return nil, errors.New("syntax error")
+	case strings.HasPrefix(query, "SLOW"):
+		time.Sleep(20 * time.Millisecond)
+	}
+	return driver.RowsAffected(3), nil
+}
+
+func TestLoggingDriver(t *testing.T) {
+	var buf bytes.Buffer
+	Register("sqllog-fake", fakeDriver{}, &Options{
+		Logger:        logs.New(&logs.Options{Output: &buf, Formatter: &logs.JSONFormatter{}, Level: logs.DebugLevel}),
+		System:        "fake",
+		SlowThreshold: 10 * time.Millisecond,
+		Args:          true,
+		Redactor:      logs.NewRedactor("password"),
+	})
+	db, err := sql.Open("sqllog-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "UPDATE users SET name = ?", "alice", sql.Named("password", "hunter2"), []byte("avatar")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "FAIL"); err == nil {
+		t.Fatal("expected an error")
+	}
+	rows, err := db.QueryContext(ctx, "SLOW SELECT n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	stmt, err := db.Prepare("DELETE FROM sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		entries = append(entries, m)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d:\n%s", len(entries), buf.String())
+	}
+
+	exec := entries[0]
+	args, _ := exec[ArgsKey].(map[string]any)
+	if exec["level"] != "debug" || exec["msg"] != "sql exec" || exec["db.query.text"] != "UPDATE users SET name = ?" ||
+		exec["db.system"] != "fake" || exec[RowsAffectedKey] != float64(3) || exec[DurationKey] == nil {
+		t.Errorf("unexpected exec entry: %v", exec)
+	}
+	if args["1"] != "alice" || args["password"] != logs.RedactedValue || args["3"] != "<6 bytes>" {
+		t.Errorf("unexpected args: %v", args)
+	}
+	if failed := entries[1]; failed["level"] != "error" || failed["error"] != "syntax error" {
+		t.Errorf("unexpected error entry: %v", failed)
+	}
+	if slow := entries[2]; slow["level"] != "warn" || slow["msg"] != "slow sql query" {
+		t.Errorf("unexpected slow entry: %v", slow)
+	}
+	if prepared := entries[3]; prepared["msg"] != "sql exec" || prepared["db.query.text"] != "DELETE FROM sessions" {
+		t.Errorf("unexpected prepared entry: %v", prepared)
+	}
+}
@@ -0,0 +1,103 @@
+package logs
+
+import (
+	"runtime"
+	"strings"
+)
+
+// StackFrame is a frame of a stack trace captured for an entry.
+type StackFrame struct {
+	Function string `json:"func"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// StackOptions configures the stack traces captured for entries.
+type StackOptions struct {
+	// Level is the least severe level stack traces are captured for.
+	// Default: ErrorLevel
+	Level Level
+
+	// MaxFrames is the most frames captured, from the call site up.
+	// Default: 32
+	MaxFrames int
+
+	// SkipPrefixes leaves out the frames of functions whose names start
+	// with one of them, e.g. "github.com/kolosys/lumen/" to drop
+	// middleware frames as well. Frames of this package are always left
+	// out. Default: "runtime."
+	SkipPrefixes []string
+}
+
+func (o *StackOptions) applyDefaults() {
+	if o.Level == 0 {
+		o.Level = ErrorLevel
+	}
+	if o.MaxFrames <= 0 {
+		o.MaxFrames = 32
+	}
+	if o.SkipPrefixes == nil {
+		o.SkipPrefixes = []string{"runtime."}
+	}
+}
+
+// stackOptions returns the stack options of opts, or nil if stack traces
+// are off.
+func stackOptions(opts *Options) *StackOptions {
+	var so StackOptions
+	switch {
+	case opts.Stack != nil:
+		so = *opts.Stack
+	case opts.AddStack:
+	default:
+		return nil
+	}
+	so.applyDefaults()
+	return &so
+}
+
+// captureStack returns the frames of the calling goroutine's stack past
+// this package's, skipping the frames o filters out.
+func captureStack(o *StackOptions) []StackFrame {
+	pcs := make([]uintptr, o.MaxFrames+32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	out := make([]StackFrame, 0, min(n, o.MaxFrames))
+	for len(out) < o.MaxFrames {
+		frame, more := frames.Next()
+		if !skipFrame(frame.Function, o.SkipPrefixes) {
+			out = append(out, StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+func skipFrame(function string, prefixes []string) bool {
+	if strings.HasPrefix(function, pkgPrefix) {
+		return true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(function, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatStack renders frames in the layout of runtime.Stack, without the
+// goroutine header, for Entry.Stack.
+func formatStack(frames []StackFrame) string {
+	var b strings.Builder
+	for _, f := range frames {
+		b.WriteString(f.Function)
+		b.WriteString("(...)\n\t")
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.Write(appendInt(nil, f.Line))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
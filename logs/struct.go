@@ -250,11 +250,15 @@ func V(key string, value any) Field {
 }
 
 // Object creates a field from any value, attempting to extract structure.
-// For structs, it extracts fields. For other types, it uses Any.
+// A LogMarshaler encodes itself. For structs, it extracts fields. For
+// other types, it uses Any.
 func Object(key string, v any) Field {
 	if v == nil {
 		return String(key, "null")
 	}
+	if m, ok := v.(LogMarshaler); ok {
+		return Field{Key: key, Type: FieldTypeObject, Interface: m}
+	}
 
 	val := reflect.ValueOf(v)
 	if val.Kind() == reflect.Ptr {
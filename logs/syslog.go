@@ -0,0 +1,351 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+)
+
+// SyslogFacility is the syslog facility entries are logged under.
+type SyslogFacility int
+
+// Syslog facilities, as numbered by RFC 5424.
+const (
+	FacilityKern     SyslogFacility = 0
+	FacilityUser     SyslogFacility = 1
+	FacilityMail     SyslogFacility = 2
+	FacilityDaemon   SyslogFacility = 3
+	FacilityAuth     SyslogFacility = 4
+	FacilitySyslog   SyslogFacility = 5
+	FacilityLPR      SyslogFacility = 6
+	FacilityNews     SyslogFacility = 7
+	FacilityUUCP     SyslogFacility = 8
+	FacilityCron     SyslogFacility = 9
+	FacilityAuthPriv SyslogFacility = 10
+	FacilityFTP      SyslogFacility = 11
+	FacilityLocal0   SyslogFacility = 16
+	FacilityLocal1   SyslogFacility = 17
+	FacilityLocal2   SyslogFacility = 18
+	FacilityLocal3   SyslogFacility = 19
+	FacilityLocal4   SyslogFacility = 20
+	FacilityLocal5   SyslogFacility = 21
+	FacilityLocal6   SyslogFacility = 22
+	FacilityLocal7   SyslogFacility = 23
+)
+
+// SyslogFormat is the message format a SyslogHook writes.
+type SyslogFormat int
+
+const (
+	// SyslogRFC5424 writes RFC 5424 messages with the entry's fields as
+	// structured data. It is the default.
+	SyslogRFC5424 SyslogFormat = iota
+	// SyslogRFC3164 writes BSD syslog messages with the fields appended to
+	// the message as key=value pairs, for daemons that predate RFC 5424.
+	SyslogRFC3164
+)
+
+// SyslogSDID is the SD-ID of the structured data element holding an
+// entry's fields. 32473 is the enterprise number RFC 5612 reserves for
+// documentation and examples.
+const SyslogSDID = "lumen@32473"
+
+const (
+	syslogTimeout     = 5 * time.Second
+	syslogRedialDelay = time.Second
+)
+
+// errSyslogDown is reported for entries dropped while waiting to
+// reconnect.
+var errSyslogDown = errors.New("not connected")
+
+// localSyslogPaths are the sockets tried when no address is given.
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogHook writes entries to a local or remote syslog daemon. A failed
+// write is retried once on a new connection; while the daemon stays
+// unreachable, entries are dropped and reported to lumen.OnInternalError,
+// and reconnecting is attempted at most once a second.
+type SyslogHook struct {
+	network  string
+	addr     string
+	facility SyslogFacility
+	levels   []Level
+	hostname string
+	appName  string
+	pid      string
+
+	mu       sync.Mutex
+	format   SyslogFormat
+	conn     net.Conn
+	nextDial time.Time
+	closed   bool
+	buf      []byte
+}
+
+// NewSyslogHook connects to the syslog daemon at addr over network, e.g.
+// "udp" and "logs.example.com:514", or "tcp" for octet-counted framing as
+// in RFC 6587. With an empty network and addr it connects to the local
+// daemon's Unix socket. Entries are logged under facility, at the
+// severity of their level.
+func NewSyslogHook(network, addr string, facility SyslogFacility, levels ...Level) (*SyslogHook, error) {
+	hostname, _ := os.Hostname()
+	h := &SyslogHook{
+		network:  network,
+		addr:     addr,
+		facility: facility,
+		levels:   levels,
+		hostname: orNil(hostname),
+		appName:  orNil(filepath.Base(os.Args[0])),
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+	conn, err := h.dial()
+	if err != nil {
+		return nil, err
+	}
+	h.conn = conn
+	return h, nil
+}
+
+// SetFormat sets the message format. See SyslogRFC3164.
+func (h *SyslogHook) SetFormat(format SyslogFormat) {
+	h.mu.Lock()
+	h.format = format
+	h.mu.Unlock()
+}
+
+// SetAppName sets the APP-NAME, or tag, of messages. Defaults to the
+// program name.
+func (h *SyslogHook) SetAppName(name string) {
+	h.mu.Lock()
+	h.appName = orNil(name)
+	h.mu.Unlock()
+}
+
+// Fire implements Hook.
+func (h *SyslogHook) Fire(entry *Entry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+
+	if h.format == SyslogRFC3164 {
+		h.buf = h.append3164(h.buf[:0], entry)
+	} else {
+		h.buf = h.append5424(h.buf[:0], entry)
+	}
+	if err := h.write(h.buf); err != nil {
+		report.Error(report.LogHook, fmt.Errorf("logs: syslog: %w", err))
+	}
+}
+
+// Levels implements Hook.
+func (h *SyslogHook) Levels() []Level {
+	return h.levels
+}
+
+// Close closes the connection. Later entries are dropped.
+func (h *SyslogHook) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	if h.conn == nil {
+		return nil
+	}
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
+
+// write sends msg, reconnecting once if the connection has failed.
+func (h *SyslogHook) write(msg []byte) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if h.conn == nil {
+			if time.Now().Before(h.nextDial) {
+				return errSyslogDown
+			}
+			if h.conn, err = h.dial(); err != nil {
+				h.nextDial = time.Now().Add(syslogRedialDelay)
+				return err
+			}
+		}
+		h.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		if err = h.send(msg); err == nil {
+			return nil
+		}
+		h.conn.Close()
+		h.conn = nil
+	}
+	return err
+}
+
+// send frames msg for the transport and writes it.
+func (h *SyslogHook) send(msg []byte) error {
+	var err error
+	switch h.conn.LocalAddr().Network() {
+	case "tcp", "tcp4", "tcp6":
+		// RFC 6587 octet counting.
+		_, err = fmt.Fprintf(h.conn, "%d %s", len(msg), msg)
+	case "unix":
+		_, err = h.conn.Write(append(msg, '\n'))
+	default:
+		_, err = h.conn.Write(msg)
+	}
+	return err
+}
+
+func (h *SyslogHook) dial() (net.Conn, error) {
+	if h.network != "" || h.addr != "" {
+		return net.DialTimeout(h.network, h.addr, syslogTimeout)
+	}
+	for _, path := range localSyslogPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.DialTimeout(network, path, syslogTimeout); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("logs: no local syslog socket found")
+}
+
+// severity maps a level to a syslog severity.
+func severity(level Level) int {
+	switch level {
+	case PanicLevel:
+		return 1 // alert
+	case FatalLevel:
+		return 2 // critical
+	case ErrorLevel:
+		return 3
+	case WarnLevel:
+		return 4
+	case InfoLevel:
+		return 6 // informational; 5, notice, has no level
+	default:
+		return 7
+	}
+}
+
+func (h *SyslogHook) priority(level Level) int {
+	return int(h.facility)*8 + severity(level)
+}
+
+// append5424 appends entry as an RFC 5424 message:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID PARAM="VALUE"...] MSG
+func (h *SyslogHook) append5424(dst []byte, entry *Entry) []byte {
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	dst = append(dst, '<')
+	dst = strconv.AppendInt(dst, int64(h.priority(entry.Level)), 10)
+	dst = append(dst, ">1 "...)
+	dst = t.AppendFormat(dst, "2006-01-02T15:04:05.000000Z07:00")
+	dst = append(dst, ' ')
+	dst = append(dst, h.hostname...)
+	dst = append(dst, ' ')
+	dst = append(dst, h.appName...)
+	dst = append(dst, ' ')
+	dst = append(dst, h.pid...)
+	dst = append(dst, " - "...)
+
+	if len(entry.Fields) == 0 && entry.Caller == "" {
+		dst = append(dst, '-')
+	} else {
+		dst = append(dst, '[')
+		dst = append(dst, SyslogSDID...)
+		if entry.Caller != "" {
+			dst = appendSDParam(dst, "caller", entry.Caller)
+		}
+		for _, f := range entry.Fields {
+			dst = appendSDParam(dst, f.Key, f.StringValue())
+		}
+		dst = append(dst, ']')
+	}
+	if entry.Message != "" {
+		dst = append(dst, ' ')
+		dst = append(dst, entry.Message...)
+	}
+	return dst
+}
+
+// appendSDParam appends a structured data parameter. Names are limited to
+// 32 printable ASCII characters other than '=', ' ', ']' and '"'; values
+// escape '"', '\' and ']'.
+func appendSDParam(dst []byte, name, value string) []byte {
+	dst = append(dst, ' ')
+	n := 0
+	for i := 0; i < len(name) && n < 32; i++ {
+		c := name[i]
+		if c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		dst = append(dst, c)
+		n++
+	}
+	if n == 0 {
+		dst = append(dst, '_')
+	}
+	dst = append(dst, '=', '"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"', '\\', ']':
+			dst = append(dst, '\\', c)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
+
+// append3164 appends entry as a BSD syslog message:
+//
+//	<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG key=value...
+func (h *SyslogHook) append3164(dst []byte, entry *Entry) []byte {
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	dst = append(dst, '<')
+	dst = strconv.AppendInt(dst, int64(h.priority(entry.Level)), 10)
+	dst = append(dst, '>')
+	dst = t.AppendFormat(dst, time.Stamp)
+	dst = append(dst, ' ')
+	dst = append(dst, h.hostname...)
+	dst = append(dst, ' ')
+	dst = append(dst, h.appName...)
+	dst = append(dst, '[')
+	dst = append(dst, h.pid...)
+	dst = append(dst, "]: "...)
+	dst = append(dst, entry.Message...)
+	for _, f := range entry.Fields {
+		dst = append(dst, ' ')
+		dst = append(dst, f.Key...)
+		dst = append(dst, '=')
+		if v := f.StringValue(); strings.ContainsAny(v, " \"=") || v == "" {
+			dst = strconv.AppendQuote(dst, v)
+		} else {
+			dst = append(dst, v...)
+		}
+	}
+	return dst
+}
+
+// orNil returns s, or the RFC 5424 NILVALUE if s is empty.
+func orNil(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,220 @@
+package logs
+
+import (
+	"sync"
+	"time"
+)
+
+// AlertKey is the field key marking the entries a ThresholdHook logs.
+const AlertKey = "alert"
+
+// ThresholdOptions configures a ThresholdHook.
+type ThresholdOptions struct {
+	// Levels are the levels counted. Defaults to error, fatal and panic.
+	Levels []Level
+
+	// Window is the sliding window entries are counted over. Default: 1m
+	Window time.Duration
+
+	// Threshold is the number of entries within Window at which the hook
+	// fires. Default: 10
+	Threshold int
+
+	// Recover is the number of entries within Window at or below which a
+	// firing hook recovers. Keeping it below Threshold stops a rate
+	// hovering around the threshold from signalling on every entry.
+	// Default: Threshold/2
+	Recover int
+
+	// OnChange is called when the hook fires and when it recovers, on a
+	// goroutine of its own rather than the one logging.
+	OnChange func(ThresholdEvent)
+
+	// Logger, if set, is logged to when the hook fires, at error level
+	// with severity "critical", and at info level when it recovers. The
+	// entries have an AlertKey field and are not counted, so Logger may be
+	// the logger the hook is attached to.
+	Logger *Logger
+}
+
+func (o *ThresholdOptions) applyDefaults() {
+	if len(o.Levels) == 0 {
+		o.Levels = []Level{ErrorLevel, FatalLevel, PanicLevel}
+	}
+	if o.Window <= 0 {
+		o.Window = time.Minute
+	}
+	if o.Threshold <= 0 {
+		o.Threshold = 10
+	}
+	if o.Recover < 0 || o.Recover >= o.Threshold {
+		o.Recover = o.Threshold / 2
+	}
+}
+
+// ThresholdEvent reports a ThresholdHook firing or recovering.
+type ThresholdEvent struct {
+	// Firing is true when the threshold was crossed, and false on
+	// recovery.
+	Firing bool
+	// Count is the number of entries within Window.
+	Count  int
+	Window time.Duration
+	Time   time.Time
+}
+
+// ThresholdHook is lightweight in-process alerting on error rates. It
+// counts entries over a sliding window and signals once when their number
+// reaches a threshold, then once more when it has fallen back to the
+// recovery level:
+//
+//	logger.AddHook(logs.NewThresholdHook(&logs.ThresholdOptions{
+//		Threshold: 50,
+//		Window:    time.Minute,
+//		Logger:    logger,
+//		OnChange: func(ev logs.ThresholdEvent) {
+//			if ev.Firing {
+//				pager.Trigger("error rate above 50/min")
+//			}
+//		},
+//	}))
+type ThresholdHook struct {
+	opts ThresholdOptions
+
+	mu     sync.Mutex
+	times  []time.Time // oldest first, at most Threshold
+	firing bool
+	timer  *time.Timer
+	closed bool
+}
+
+// NewThresholdHook creates a ThresholdHook.
+func NewThresholdHook(opts *ThresholdOptions) *ThresholdHook {
+	h := &ThresholdHook{}
+	if opts != nil {
+		h.opts = *opts
+	}
+	h.opts.applyDefaults()
+	h.times = make([]time.Time, 0, h.opts.Threshold)
+	return h
+}
+
+// Fire implements Hook.
+func (h *ThresholdHook) Fire(entry *Entry) {
+	if _, ok := entry.GetField(AlertKey); ok {
+		return
+	}
+	now := time.Now()
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.expire(now)
+	if len(h.times) == h.opts.Threshold {
+		// Only whether the threshold is reached matters, so the oldest
+		// time can go.
+		h.times = append(h.times[:0], h.times[1:]...)
+	}
+	h.times = append(h.times, now)
+	var ev *ThresholdEvent
+	if !h.firing && len(h.times) >= h.opts.Threshold {
+		h.firing = true
+		ev = &ThresholdEvent{Firing: true, Count: len(h.times), Window: h.opts.Window, Time: now}
+		h.schedule()
+	}
+	h.mu.Unlock()
+
+	if ev != nil {
+		// Logging to Logger from within its own hooks would deadlock.
+		go h.signal(*ev)
+	}
+}
+
+// Levels implements Hook.
+func (h *ThresholdHook) Levels() []Level {
+	return h.opts.Levels
+}
+
+// Firing reports whether the threshold has been crossed without the hook
+// having recovered since.
+func (h *ThresholdHook) Firing() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.firing
+}
+
+// Close stops the hook. It signals nothing more.
+func (h *ThresholdHook) Close() error {
+	h.mu.Lock()
+	h.closed = true
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.mu.Unlock()
+	return nil
+}
+
+// expire drops the times that have left the window.
+func (h *ThresholdHook) expire(now time.Time) {
+	cutoff := now.Add(-h.opts.Window)
+	i := 0
+	for i < len(h.times) && !h.times[i].After(cutoff) {
+		i++
+	}
+	if i > 0 {
+		h.times = append(h.times[:0], h.times[i:]...)
+	}
+}
+
+// schedule arms the timer for when enough times will have left the
+// window for the count to drop to Recover.
+func (h *ThresholdHook) schedule() {
+	wait := time.Duration(0)
+	if n := len(h.times) - h.opts.Recover; n > 0 {
+		wait = time.Until(h.times[n-1].Add(h.opts.Window))
+	}
+	if h.timer == nil {
+		h.timer = time.AfterFunc(wait, h.check)
+	} else {
+		h.timer.Reset(wait)
+	}
+}
+
+// check runs on the timer, recovering the hook or re-arming the timer if
+// entries kept arriving.
+func (h *ThresholdHook) check() {
+	now := time.Now()
+
+	h.mu.Lock()
+	if h.closed || !h.firing {
+		h.mu.Unlock()
+		return
+	}
+	h.expire(now)
+	if len(h.times) > h.opts.Recover {
+		h.schedule()
+		h.mu.Unlock()
+		return
+	}
+	h.firing = false
+	ev := ThresholdEvent{Count: len(h.times), Window: h.opts.Window, Time: now}
+	h.mu.Unlock()
+
+	h.signal(ev)
+}
+
+func (h *ThresholdHook) signal(ev ThresholdEvent) {
+	if h.opts.OnChange != nil {
+		h.opts.OnChange(ev)
+	}
+	if l := h.opts.Logger; l != nil {
+		fields := []Field{String(AlertKey, "error_rate"), Int("count", ev.Count), Duration("window", ev.Window)}
+		if ev.Firing {
+			l.Error("error rate threshold crossed", append(fields, String("severity", "critical"))...)
+		} else {
+			l.Info("error rate recovered", fields...)
+		}
+	}
+}
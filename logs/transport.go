@@ -0,0 +1,254 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/kolosys/lumen/trace/semconv"
+)
+
+// Field keys of the entries a LoggingTransport logs, besides the
+// OpenTelemetry HTTP names for method, URL and status.
+const (
+	HTTPDurationKey     = "http.duration"
+	HTTPResendCountKey  = "http.request.resend_count"
+	HTTPRequestBodyKey  = "http.request.body"
+	HTTPResponseBodyKey = "http.response.body"
+)
+
+// LoggingTransport is an http.RoundTripper logging the outbound requests
+// it sends, with their URL, status and latency, so that calls to
+// dependencies are observable alongside the inbound requests httplog
+// logs:
+//
+//	client := &http.Client{
+//		Transport: logs.NewLoggingTransport(nil, logger).
+//			WithRetries(2, 100*time.Millisecond).
+//			WithBodies(4096, logs.NewRedactor()),
+//	}
+//
+// Successful requests are logged at debug, 4xx responses at warn, and 5xx
+// responses and failed round trips at error. A LoggingTransport must not
+// be modified once it is in use.
+type LoggingTransport struct {
+	base     http.RoundTripper
+	logger   *Logger
+	retries  int
+	backoff  time.Duration
+	bodies   int
+	redactor *Redactor
+}
+
+// NewLoggingTransport creates a LoggingTransport sending requests through
+// base, http.DefaultTransport if nil, and logging them to logger, or if
+// nil to the logger of each request's context.
+func NewLoggingTransport(base http.RoundTripper, logger *Logger) *LoggingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &LoggingTransport{base: base, logger: logger}
+}
+
+// WithRetries retries idempotent requests up to n times after a failed
+// round trip or a 429 or 5xx response, waiting a random time up to
+// backoff, doubled for each further retry. Each attempt is logged, with
+// its number under HTTPResendCountKey. Requests with a body are retried
+// only if they have GetBody, as those made by http.NewRequest do.
+func (t *LoggingTransport) WithRetries(n int, backoff time.Duration) *LoggingTransport {
+	t.retries, t.backoff = n, backoff
+	return t
+}
+
+// WithBodies logs the first limit bytes of request and response bodies.
+// If redactor is not nil, it is applied to them: JSON bodies that fit
+// within limit have the values of keys matching its key patterns masked,
+// and all bodies are scrubbed. Response bodies are read up to limit
+// before RoundTrip returns, so leave bodies out for streaming responses.
+func (t *LoggingTransport) WithBodies(limit int, redactor *Redactor) *LoggingTransport {
+	t.bodies, t.redactor = limit, redactor
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *LoggingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	retries := t.retries
+	if !idempotent(r) || (r.Body != nil && r.Body != http.NoBody && r.GetBody == nil) {
+		retries = 0
+	}
+	backoff := t.backoff
+	for attempt := 0; ; attempt++ {
+		req := r
+		if attempt > 0 {
+			req = r.Clone(r.Context())
+			if r.GetBody != nil {
+				body, err := r.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+		resp, err := t.send(req, attempt)
+		retry := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retry || attempt >= retries || r.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		delay := time.Duration(rand.Int64N(int64(backoff) + 1))
+		select {
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+}
+
+// send makes one attempt at a request and logs it.
+func (t *LoggingTransport) send(r *http.Request, attempt int) (*http.Response, error) {
+	ctx := r.Context()
+	logger := t.logger
+	if logger == nil {
+		logger = LoggerFromContext(ctx)
+	}
+
+	fields := make([]Field, 0, 8)
+	fields = append(fields,
+		String(semconv.HTTPMethodKey, r.Method),
+		String(semconv.URLFullKey, r.URL.Redacted()),
+	)
+	if attempt > 0 {
+		fields = append(fields, Int(HTTPResendCountKey, attempt))
+	}
+	if t.bodies > 0 && r.Body != nil && r.Body != http.NoBody {
+		var body []byte
+		body, r = t.peekRequest(r)
+		fields = append(fields, t.bodyField(HTTPRequestBodyKey, body))
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(r)
+	elapsed := time.Since(start)
+
+	level := ErrorLevel
+	if err != nil {
+		fields = append(fields, Duration(HTTPDurationKey, elapsed), Err(err))
+	} else {
+		switch {
+		case resp.StatusCode >= 500:
+		case resp.StatusCode >= 400:
+			level = WarnLevel
+		default:
+			level = DebugLevel
+		}
+		fields = append(fields, Int(semconv.HTTPStatusCodeKey, resp.StatusCode), Duration(HTTPDurationKey, elapsed))
+		if t.bodies > 0 && logger.IsEnabled(level) {
+			fields = append(fields, t.bodyField(HTTPResponseBodyKey, peekResponse(resp, t.bodies)))
+		}
+	}
+	logger.LogContext(ctx, level, "http client request", fields...)
+	return resp, err
+}
+
+// peekRequest returns the first bytes of r's body, and the request to
+// send: r itself if the bytes could be read from a copy of the body, and
+// otherwise a clone whose body replays them.
+func (t *LoggingTransport) peekRequest(r *http.Request) ([]byte, *http.Request) {
+	if r.GetBody != nil {
+		if body, err := r.GetBody(); err == nil {
+			data, _ := io.ReadAll(io.LimitReader(body, int64(t.bodies)+1))
+			body.Close()
+			return data, r
+		}
+	}
+	data, _ := io.ReadAll(io.LimitReader(r.Body, int64(t.bodies)+1))
+	clone := r.Clone(r.Context())
+	clone.Body = readCloser{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	return data, clone
+}
+
+// peekResponse returns up to limit+1 bytes of resp's body, leaving the
+// body to read in full.
+func peekResponse(resp *http.Response, limit int) []byte {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	resp.Body = readCloser{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	return data
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyField returns a field holding a body peeked with one byte beyond
+// the limit, which marks it truncated.
+func (t *LoggingTransport) bodyField(key string, data []byte) Field {
+	truncated := len(data) > t.bodies
+	if truncated {
+		data = data[:t.bodies]
+	}
+	if t.redactor != nil && !truncated {
+		data = t.redactor.redactJSON(data)
+	}
+	s := string(data)
+	if truncated {
+		s += "…"
+	}
+	f := String(key, s)
+	if t.redactor != nil {
+		f = t.redactor.Redact(f)
+	}
+	return f
+}
+
+// redactJSON masks the values of the object keys in a JSON document that
+// match the Redactor's key patterns. Other data is returned as is.
+func (r *Redactor) redactJSON(data []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if dec.Decode(&v) != nil || dec.More() {
+		return data
+	}
+	if out, err := json.Marshal(r.redactValue(v)); err == nil {
+		return out
+	}
+	return data
+}
+
+func (r *Redactor) redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if r.matchKey(k) {
+				v[k] = RedactedValue
+			} else {
+				v[k] = r.redactValue(e)
+			}
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = r.redactValue(e)
+		}
+	}
+	return v
+}
+
+// idempotent reports whether r may be sent again, by its method or an
+// Idempotency-Key header.
+func idempotent(r *http.Request) bool {
+	switch r.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.Header.Get("Idempotency-Key") != ""
+}
@@ -0,0 +1,189 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+	"github.com/kolosys/lumen/internal/watch"
+)
+
+// family holds the settings Reconfigure applies to a logger created by
+// New and every logger derived from it. Loggers copy their output,
+// formatter and level, so each adopts a new generation lazily, the next
+// time it logs.
+type family struct {
+	gen atomic.Uint64
+
+	// writes is held for reading while an entry is written, so that
+	// Reconfigure closes a replaced output only once writes to it end.
+	writes sync.RWMutex
+
+	mu         sync.Mutex
+	output     io.Writer
+	outputName string
+	formatter  Formatter
+	level      Level
+}
+
+// sync adopts the family's settings if they changed since l last did.
+func (l *Logger) sync() {
+	gen := l.family.gen.Load()
+	if gen == l.familyGen.Load() {
+		return
+	}
+	f := l.family
+	f.mu.Lock()
+	output, formatter, level, gen := f.output, f.formatter, f.level, f.gen.Load()
+	f.mu.Unlock()
+
+	l.mu.Lock()
+	l.output, l.formatter = output, formatter
+	l.mu.Unlock()
+	l.level.Store(int32(level))
+	l.familyGen.Store(gen)
+}
+
+// Reconfigure applies the level, format and output of cfg to l and to
+// every logger created from the same New, including ones created before
+// the call; they replace settings made with SetLevel, SetFormatter and
+// SetOutput. Other fields of cfg are ignored, as changing them needs a
+// new logger. cfg is validated first, so either every change is applied
+// or none is.
+//
+// Entries already queued for asynchronous writing are written to the new
+// output. A file output that is replaced is closed once the writes in
+// progress to it finish; an unchanged output is kept as it is.
+func (l *Logger) Reconfigure(cfg Config) error {
+	l = l.current()
+	level, err := parseConfigLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	formatter, err := newFormatter(cfg.Format)
+	if err != nil {
+		return err
+	}
+
+	f := l.family
+	f.mu.Lock()
+	var replaced io.Writer
+	var replacedName string
+	if f.output == nil || cfg.Output != f.outputName {
+		output, err := openOutput(cfg.Output)
+		if err != nil {
+			f.mu.Unlock()
+			return err
+		}
+		replaced, replacedName = f.output, f.outputName
+		f.output, f.outputName = output, cfg.Output
+	}
+	f.formatter = formatter
+	f.level = level
+	f.gen.Add(1)
+	f.mu.Unlock()
+
+	if replaced != nil {
+		// Writes starting from now adopt the new output; wait for the
+		// ones in progress before closing the old one.
+		f.writes.Lock()
+		f.writes.Unlock()
+		if err := closeOutput(replaced, replacedName); err != nil {
+			report.Error(report.Logs, fmt.Errorf("logs: close replaced output: %w", err))
+		}
+	}
+	return nil
+}
+
+// WatchOptions configures Logger.WatchConfig.
+type WatchOptions struct {
+	// Interval is how often the file's modification time is checked.
+	// Defaults to 2s.
+	Interval time.Duration
+
+	// Decode parses the file, e.g. yaml.Unmarshal. Defaults to JSON.
+	Decode func(data []byte, v any) error
+
+	// DisableSIGHUP stops SIGHUP from triggering a reload.
+	DisableSIGHUP bool
+
+	// OnError is called when the file cannot be read or applied. Errors
+	// are logged at error level by default.
+	OnError func(err error)
+}
+
+func (o *WatchOptions) applyDefaults() {
+	if o.Interval <= 0 {
+		o.Interval = 2 * time.Second
+	}
+	if o.Decode == nil {
+		o.Decode = json.Unmarshal
+	}
+}
+
+// Watch reconfigures the default logger from the Config file at path; see
+// Logger.WatchConfig.
+func Watch(path string, opts *WatchOptions) (stop func()) {
+	return defaultLogger.WatchConfig(path, opts)
+}
+
+// WatchConfig applies the Config file at path with Reconfigure now,
+// whenever its modification time changes and on SIGHUP, until the
+// returned stop function is called.
+//
+//	stop := log.WatchConfig("/etc/app/logging.json", nil)
+//	defer stop()
+func (l *Logger) WatchConfig(path string, opts *WatchOptions) (stop func()) {
+	l = l.current()
+	var wo WatchOptions
+	if opts != nil {
+		wo = *opts
+	}
+	wo.applyDefaults()
+	if wo.OnError == nil {
+		wo.OnError = func(err error) {
+			l.Error("logs: config reload failed", Err(err))
+		}
+	}
+
+	return watch.File(path, watch.Options{
+		Interval:  wo.Interval,
+		SIGHUP:    !wo.DisableSIGHUP,
+		Immediate: true,
+		Reload:    func() error { return l.reconfigureFrom(path, wo.Decode) },
+		OnError:   wo.OnError,
+	})
+}
+
+func (l *Logger) reconfigureFrom(path string, decode func(data []byte, v any) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfg Config
+	if err := decode(data, &cfg); err != nil {
+		return fmt.Errorf("logs: parse %s: %w", path, err)
+	}
+	return l.Reconfigure(cfg)
+}
+
+// newFormatter returns the built-in formatter named format.
+func newFormatter(format string) (Formatter, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return &TextFormatter{}, nil
+	case "json":
+		return &JSONFormatter{}, nil
+	case "pretty":
+		return &PrettyFormatter{}, nil
+	case "datadog":
+		return &DatadogFormatter{}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
@@ -0,0 +1,12 @@
+module github.com/kolosys/lumen/logs/zapadapter
+
+go 1.24
+
+require (
+	github.com/kolosys/lumen v0.0.0
+	go.uber.org/zap v1.27.0
+)
+
+require go.uber.org/multierr v1.10.0 // indirect
+
+replace github.com/kolosys/lumen => ../../
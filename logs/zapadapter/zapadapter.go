@@ -0,0 +1,166 @@
+// Package zapadapter implements a zapcore.Core backed by a lumen Logger,
+// so that code logging through zap can move to lumen before its call
+// sites are rewritten:
+//
+//	zl := zap.New(zapadapter.NewCore(logger))
+//	zl.Named("db").Info("connected", zap.String("host", host))
+//
+// The lumen logger decides the level, format, output, hooks and
+// redaction. zap's logger names become lumen names, nested below the
+// logger's own, and zap namespaces become lumen namespaces.
+package zapadapter
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/kolosys/lumen/logs"
+)
+
+// StacktraceKey is the field holding the stack traces zap captures.
+const StacktraceKey = "stacktrace"
+
+// Core is a zapcore.Core writing to a lumen Logger.
+type Core struct {
+	logger *logs.Logger
+	named  *sync.Map // name -> *logs.Logger, shared by the cores made by With
+}
+
+// NewCore creates a Core logging to logger.
+func NewCore(logger *logs.Logger) *Core {
+	return &Core{logger: logger, named: new(sync.Map)}
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *Core) Enabled(l zapcore.Level) bool {
+	return c.logger.IsEnabled(Level(l))
+}
+
+// With implements zapcore.Core.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	return &Core{logger: c.logger.With(Fields(fields)...), named: new(sync.Map)}
+}
+
+// Check implements zapcore.Core.
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. Panic and fatal entries are logged
+// without panicking or exiting, which zap does itself.
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	logger := c.logger
+	if ent.LoggerName != "" {
+		named, ok := c.named.Load(ent.LoggerName)
+		if !ok {
+			named, _ = c.named.LoadOrStore(ent.LoggerName, c.logger.Named(ent.LoggerName))
+		}
+		logger = named.(*logs.Logger)
+	}
+	lf := Fields(fields)
+	if ent.Stack != "" {
+		lf = append(lf, logs.String(StacktraceKey, ent.Stack))
+	}
+	logger.Log(Level(ent.Level), ent.Message, lf...)
+	return nil
+}
+
+// Sync implements zapcore.Core. Entries are flushed by closing the
+// lumen logger.
+func (c *Core) Sync() error {
+	return nil
+}
+
+// Level returns the lumen level of a zap level. DPanic, which only panics
+// in development, is logged at error.
+func Level(l zapcore.Level) logs.Level {
+	switch l {
+	case zapcore.DebugLevel:
+		return logs.DebugLevel
+	case zapcore.InfoLevel:
+		return logs.InfoLevel
+	case zapcore.WarnLevel:
+		return logs.WarnLevel
+	case zapcore.ErrorLevel, zapcore.DPanicLevel:
+		return logs.ErrorLevel
+	case zapcore.PanicLevel:
+		return logs.PanicLevel
+	case zapcore.FatalLevel:
+		return logs.FatalLevel
+	}
+	if l < zapcore.DebugLevel {
+		return logs.TraceLevel
+	}
+	return logs.ErrorLevel
+}
+
+// Fields converts zap fields, keeping their order.
+func Fields(fields []zapcore.Field) []logs.Field {
+	enc := &fieldEncoder{fields: make([]logs.Field, 0, len(fields))}
+	for _, f := range fields {
+		switch f.Type {
+		case zapcore.SkipType:
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok {
+				enc.fields = append(enc.fields, logs.NamedErr(f.Key, err))
+			}
+		default:
+			f.AddTo(enc)
+		}
+	}
+	return enc.fields
+}
+
+// fieldEncoder is a zapcore.ObjectEncoder collecting lumen fields.
+type fieldEncoder struct {
+	fields []logs.Field
+}
+
+func (e *fieldEncoder) add(f logs.Field) { e.fields = append(e.fields, f) }
+
+func (e *fieldEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	err := m.AddArray(key, arr)
+	e.add(logs.Any(key, m.Fields[key]))
+	return err
+}
+
+func (e *fieldEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	nested := &fieldEncoder{}
+	err := obj.MarshalLogObject(nested)
+	e.add(logs.Group(key, nested.fields...))
+	return err
+}
+
+func (e *fieldEncoder) AddBinary(key string, v []byte)          { e.add(logs.Bytes(key, v)) }
+func (e *fieldEncoder) AddByteString(key string, v []byte)      { e.add(logs.String(key, string(v))) }
+func (e *fieldEncoder) AddBool(key string, v bool)              { e.add(logs.Bool(key, v)) }
+func (e *fieldEncoder) AddComplex128(key string, v complex128)  { e.add(logs.Any(key, v)) }
+func (e *fieldEncoder) AddComplex64(key string, v complex64)    { e.add(logs.Any(key, v)) }
+func (e *fieldEncoder) AddDuration(key string, v time.Duration) { e.add(logs.Duration(key, v)) }
+func (e *fieldEncoder) AddFloat64(key string, v float64)        { e.add(logs.Float64(key, v)) }
+func (e *fieldEncoder) AddFloat32(key string, v float32)        { e.add(logs.Float32(key, v)) }
+func (e *fieldEncoder) AddInt(key string, v int)                { e.add(logs.Int(key, v)) }
+func (e *fieldEncoder) AddInt64(key string, v int64)            { e.add(logs.Int64(key, v)) }
+func (e *fieldEncoder) AddInt32(key string, v int32)            { e.add(logs.Int32(key, v)) }
+func (e *fieldEncoder) AddInt16(key string, v int16)            { e.add(logs.Int16(key, v)) }
+func (e *fieldEncoder) AddInt8(key string, v int8)              { e.add(logs.Int8(key, v)) }
+func (e *fieldEncoder) AddString(key, v string)                 { e.add(logs.String(key, v)) }
+func (e *fieldEncoder) AddTime(key string, v time.Time)         { e.add(logs.Time(key, v)) }
+func (e *fieldEncoder) AddUint(key string, v uint)              { e.add(logs.Uint(key, v)) }
+func (e *fieldEncoder) AddUint64(key string, v uint64)          { e.add(logs.Uint64(key, v)) }
+func (e *fieldEncoder) AddUint32(key string, v uint32)          { e.add(logs.Uint32(key, v)) }
+func (e *fieldEncoder) AddUint16(key string, v uint16)          { e.add(logs.Uint16(key, v)) }
+func (e *fieldEncoder) AddUint8(key string, v uint8)            { e.add(logs.Uint8(key, v)) }
+func (e *fieldEncoder) AddUintptr(key string, v uintptr)        { e.add(logs.Uint64(key, uint64(v))) }
+func (e *fieldEncoder) OpenNamespace(key string)                { e.add(logs.Namespace(key)) }
+
+func (e *fieldEncoder) AddReflected(key string, v any) error {
+	e.add(logs.Any(key, v))
+	return nil
+}
@@ -0,0 +1,73 @@
+package zapadapter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/logs/zapadapter"
+)
+
+type user struct{ id, plan string }
+
+func (u user) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("id", u.id)
+	enc.AddString("plan", u.plan)
+	return nil
+}
+
+func TestCore(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logs.New(&logs.Options{Output: &buf, Formatter: &logs.JSONFormatter{}}).Named("app")
+	zl := zap.New(zapadapter.NewCore(logger)).With(zap.String("region", "eu"))
+
+	zl.Debug("below the logger's level")
+	zl.Named("db").Warn("slow query",
+		zap.Int("rows", 12),
+		zap.Error(errors.New("timeout")),
+		zap.Object("user", user{"u1", "pro"}),
+		zap.Strings("tables", []string{"a", "b"}),
+		zap.Namespace("conn"),
+		zap.Bool("pooled", true),
+	)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 entry, got %d:\n%s", len(lines), buf.String())
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("%v: %s", err, lines[0])
+	}
+	u, _ := got["user"].(map[string]any)
+	conn, _ := got["conn"].(map[string]any)
+	if got["msg"] != "slow query" || got["level"] != "warn" || got["logger"] != "app.db" ||
+		got["region"] != "eu" || got["rows"] != float64(12) || got["error"] != "timeout" {
+		t.Errorf("unexpected entry: %v", got)
+	}
+	if u["id"] != "u1" || u["plan"] != "pro" || conn["pooled"] != true {
+		t.Errorf("unexpected nested fields: %v", got)
+	}
+	if tables, _ := got["tables"].([]any); len(tables) != 2 {
+		t.Errorf("tables = %v", got["tables"])
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for l, want := range map[zapcore.Level]logs.Level{
+		zapcore.DebugLevel:  logs.DebugLevel,
+		zapcore.WarnLevel:   logs.WarnLevel,
+		zapcore.DPanicLevel: logs.ErrorLevel,
+		zapcore.FatalLevel:  logs.FatalLevel,
+	} {
+		if got := zapadapter.Level(l); got != want {
+			t.Errorf("Level(%v) = %v, want %v", l, got, want)
+		}
+	}
+}
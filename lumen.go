@@ -0,0 +1,362 @@
+// Package lumen sets up logs, metrics and tracing together.
+//
+// Setup builds a logger, a metrics registry and a tracer from one Config,
+// describing the same service in all three, and returns them with a
+// single Shutdown that flushes everything:
+//
+//	cfg := lumen.ConfigFromEnv()
+//	cfg.ServiceName = "checkout"
+//	obs, err := lumen.Setup(cfg)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer obs.Shutdown(context.Background())
+//
+//	obs.Logger.Info("started")
+//	ctx, span := obs.Tracer.Start(ctx, "charge")
+//
+// The packages remain usable on their own; lumen only wires them up.
+package lumen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kolosys/lumen/expr"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/resource"
+	"github.com/kolosys/lumen/trace"
+)
+
+var (
+	ErrUnknownExporter = errors.New("lumen: unknown exporter")
+	ErrUnknownFormat   = errors.New("lumen: unknown log format")
+	ErrMissingEndpoint = errors.New("lumen: otlp exporter needs an endpoint")
+)
+
+// Observability holds the telemetry configured by Setup.
+type Observability struct {
+	// Config is the configuration passed to Setup, with defaults applied.
+	// Reload does not change it; see CurrentConfig.
+	Config  Config
+	Logger  *logs.Logger
+	Metrics *metrics.Registry
+	Tracer  *trace.Tracer
+
+	// Resource describes the service to all three signals: its identity
+	// is added to log entries and metric series, and all its attributes
+	// to exported spans and metrics.
+	Resource *resource.Resource
+
+	// RecentSpans holds the latest ended sampled spans, or is nil if
+	// disabled by Config.Trace.RecentSpans.
+	RecentSpans *RecentSpans
+
+	// FlightRecorder holds recent logs, spans and metric snapshots for
+	// crash dumps, or is nil unless Config.FlightRecorder.Enabled is set.
+	FlightRecorder *FlightRecorder
+
+	server     *http.Server
+	correlator *correlator
+
+	mu             sync.Mutex
+	live           Config
+	sampler        *dynamicSampler
+	logSampler     *logs.DecisionSampler
+	traceExporter  *OTLPTraceExporter
+	metricExporter *OTLPMetricExporter
+}
+
+// Setup configures logs, metrics and tracing from cfg. The service is
+// described by one resource.Resource, built from cfg and the detected
+// host, Kubernetes and cloud attributes, which every log entry, metric
+// series and exported span carries. Export failures are logged as
+// warnings. Unless
+// cfg.DisableCorrelation is set, the signals are also linked through the
+// active span: context logs carry its trace_id and span_id, observations
+// made with Histogram.ObserveContext or Counter.AddContext record them as
+// exemplars, and each span gets a log.count attribute.
+func Setup(cfg Config) (_ *Observability, err error) {
+	res := cfg.resource()
+	cfg.ServiceName = res.ServiceName()
+	cfg.applyDefaults()
+	o := &Observability{Config: cfg, Resource: res, live: cfg, sampler: newDynamicSampler(cfg.sampler())}
+	if !cfg.DisableCorrelation {
+		o.correlator = &correlator{verbose: o.boosted}
+	}
+
+	if o.Logger, err = o.newLogger(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			o.closePartial()
+		}
+	}()
+
+	if o.Metrics, err = o.newRegistry(); err != nil {
+		return nil, err
+	}
+	if fr := cfg.FlightRecorder; fr.Enabled {
+		o.FlightRecorder = NewFlightRecorder(&FlightRecorderOptions{
+			Logs:            fr.Logs,
+			Spans:           fr.Spans,
+			MetricSnapshots: fr.MetricSnapshots,
+			MetricInterval:  fr.MetricInterval,
+			Registry:        o.Metrics,
+			Resource:        res,
+			Dir:             fr.Dir,
+		})
+		o.Logger.SetRecorder(o.FlightRecorder, logs.DebugLevel)
+	}
+	if o.Tracer, err = o.newTracer(); err != nil {
+		return nil, err
+	}
+	if o.FlightRecorder != nil {
+		o.FlightRecorder.Start()
+	}
+
+	if cfg.Metrics.ListenAddr != "" {
+		if err = o.serveMetrics(cfg.Metrics.ListenAddr); err != nil {
+			return nil, err
+		}
+	}
+
+	defaultObs.Store(o)
+	if cfg.SetDefaults {
+		logs.SetDefault(o.Logger)
+		metrics.SetDefaultRegistry(o.Metrics)
+		trace.SetDefault(o.Tracer)
+	}
+	return o, nil
+}
+
+// closePartial releases what a failed Setup built before the error.
+func (o *Observability) closePartial() {
+	if o.FlightRecorder != nil {
+		o.FlightRecorder.Stop()
+	}
+	if o.Tracer != nil {
+		o.Tracer.Close()
+	}
+	if o.Metrics != nil {
+		o.Metrics.Close()
+	}
+	o.Logger.Close()
+}
+
+func (o *Observability) newLogger() (*logs.Logger, error) {
+	cfg := o.Config
+	var formatter logs.Formatter
+	switch strings.ToLower(cfg.Logs.Format) {
+	case "", "text":
+		formatter = &logs.TextFormatter{}
+	case "json":
+		formatter = &logs.JSONFormatter{}
+	case "pretty":
+		formatter = &logs.PrettyFormatter{}
+	case "datadog":
+		formatter = &logs.DatadogFormatter{
+			Service: o.Resource.ServiceName(),
+			Env:     o.Resource.Get(resource.EnvironmentKey),
+			Version: o.Resource.Get(resource.ServiceVersionKey),
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, cfg.Logs.Format)
+	}
+
+	level, err := parseLevel(cfg.Logs.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &logs.Options{
+		Output:    cfg.Logs.Output,
+		Level:     level,
+		Formatter: formatter,
+		AddCaller: cfg.Logs.AddCaller,
+		Resource:  o.Resource,
+	}
+	if o.correlator != nil {
+		opts.ContextFields = o.correlator.logFields
+		opts.Hooks = []logs.Hook{o.correlator}
+	}
+	if cfg.Logs.FollowTraceSampling {
+		o.logSampler = logs.NewDecisionSampler(level, nil).WithDecision(traceDecision)
+		opts.Sampler = o.logSampler
+		opts.Level = o.loggerLevel(level)
+	}
+	return logs.New(opts), nil
+}
+
+// loggerLevel returns the logger level for the configured level: with
+// FollowTraceSampling the logger admits debug entries and the log sampler
+// applies the configured level to entries outside sampled traces.
+func (o *Observability) loggerLevel(level logs.Level) logs.Level {
+	if o.logSampler == nil {
+		return level
+	}
+	o.logSampler.SetThreshold(level)
+	return max(level, logs.DebugLevel)
+}
+
+// traceDecision reads the sampling decision of the active span, or one
+// recorded with logs.WithSamplingDecision.
+func traceDecision(ctx context.Context) (sampled, ok bool) {
+	if span := trace.SpanFromContext(ctx); span != nil {
+		return span.IsSampled(), true
+	}
+	return logs.SamplingDecision(ctx)
+}
+
+func (o *Observability) newRegistry() (*metrics.Registry, error) {
+	cfg := o.Config
+	opts := &metrics.Options{
+		Prefix:   cfg.Metrics.Prefix,
+		Resource: o.Resource,
+		OnPushError: func(err error) {
+			o.Logger.Warn("metrics export failed", logs.Err(err))
+		},
+	}
+	if o.correlator != nil {
+		opts.ExemplarFromContext = o.correlator.exemplar
+	}
+	for _, src := range cfg.Metrics.Drop {
+		e, err := expr.Compile(src)
+		if err != nil {
+			return nil, fmt.Errorf("lumen: metrics drop rule: %w", err)
+		}
+		opts.Relabel = append(opts.Relabel, metrics.RelabelRule{Action: metrics.RelabelDrop, Match: e.MatchSeries})
+	}
+
+	switch strings.ToLower(cfg.Metrics.Exporter) {
+	case "", ExporterNone:
+	case ExporterOTLP:
+		if cfg.Metrics.Endpoint == "" {
+			return nil, ErrMissingEndpoint
+		}
+		opts.PushInterval = cfg.Metrics.PushInterval
+		o.metricExporter = NewOTLPMetricExporter(cfg.Metrics.Endpoint, &OTLPOptions{Resource: o.Resource.Map()})
+		opts.PushExporter = o.metricExporter
+	default:
+		return nil, fmt.Errorf("%w: metrics %q", ErrUnknownExporter, cfg.Metrics.Exporter)
+	}
+	return metrics.NewRegistry(opts), nil
+}
+
+func (o *Observability) newTracer() (*trace.Tracer, error) {
+	cfg := o.Config
+	opts := &trace.Options{
+		ServiceName: cfg.ServiceName,
+		Resource:    o.Resource,
+		Sampler:     o.sampler,
+		OnExportError: func(err error) {
+			o.Logger.Warn("trace export failed", logs.Err(err))
+		},
+	}
+	if o.correlator != nil {
+		opts.Processors = append(opts.Processors, o.correlator)
+	}
+	for _, src := range cfg.Trace.Drop {
+		e, err := expr.Compile(src)
+		if err != nil {
+			return nil, fmt.Errorf("lumen: trace drop rule: %w", err)
+		}
+		opts.Processors = append(opts.Processors, expr.DropSpans(e))
+	}
+	if cfg.Trace.RecentSpans > 0 {
+		o.RecentSpans = NewRecentSpans(cfg.Trace.RecentSpans)
+		opts.Processors = append(opts.Processors, o.RecentSpans)
+	}
+	if o.FlightRecorder != nil {
+		opts.Processors = append(opts.Processors, o.FlightRecorder)
+	}
+
+	switch exporter := strings.ToLower(cfg.Trace.Exporter); {
+	case cfg.Trace.SpanExporter != nil:
+		opts.Exporter = cfg.Trace.SpanExporter
+	case exporter == "" || exporter == ExporterNone:
+	case exporter == ExporterStdout:
+		opts.Exporter = trace.NewWriterExporter(os.Stdout)
+	case exporter == ExporterOTLP:
+		if cfg.Trace.Endpoint == "" {
+			return nil, ErrMissingEndpoint
+		}
+		o.traceExporter = NewOTLPTraceExporter(cfg.Trace.Endpoint, nil)
+		opts.Exporter = o.traceExporter
+		opts.AsyncExport = true
+	default:
+		return nil, fmt.Errorf("%w: trace %q", ErrUnknownExporter, cfg.Trace.Exporter)
+	}
+	return trace.New(opts), nil
+}
+
+func (o *Observability) serveMetrics(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.HTTPHandler(o.Metrics))
+	o.server = &http.Server{Handler: mux}
+	go func() {
+		if err := o.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			o.Logger.Error("metrics server stopped", logs.Err(err))
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the metrics server, flushes pending spans and metrics,
+// then closes the logger. It returns ctx's error if ctx ends first; the
+// flushes keep running in the background.
+func (o *Observability) Shutdown(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		var errs []error
+		if o.FlightRecorder != nil {
+			o.FlightRecorder.Stop()
+		}
+		if o.server != nil {
+			errs = append(errs, o.server.Shutdown(ctx))
+		}
+		errs = append(errs, o.Tracer.Close(), o.Metrics.Close(), o.Logger.Close())
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Config) resource() *resource.Resource {
+	return resource.New(&resource.Options{
+		ServiceName:      c.ServiceName,
+		ServiceVersion:   c.ServiceVersion,
+		Environment:      c.Environment,
+		InstanceID:       c.InstanceID,
+		Attributes:       c.ResourceAttributes,
+		DisableDetection: c.DisableResourceDetection,
+	})
+}
+
+func (c *Config) sampler() trace.Sampler {
+	switch r := c.Trace.SampleRatio; {
+	case r < 0:
+		return trace.NeverSample()
+	case r == 0 || r >= 1:
+		return trace.ParentBasedSample(trace.AlwaysSample())
+	default:
+		return trace.ParentBasedSample(trace.TraceIDRatioSample(r))
+	}
+}
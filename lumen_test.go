@@ -0,0 +1,838 @@
+package lumen_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+	"github.com/kolosys/lumen/trace/semconv"
+)
+
+func TestSetupExportsAllSignals(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]string)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] += string(body)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	var out bytes.Buffer
+	obs, err := Setup(Config{
+		ServiceName:        "checkout",
+		Environment:        "test",
+		InstanceID:         "checkout-1",
+		OTLPEndpoint:       collector.URL,
+		ResourceAttributes: map[string]string{"team": "payments"},
+		Logs:               LogsConfig{Format: "json", Output: &out},
+		Metrics:            MetricsConfig{Exporter: ExporterOTLP, PushInterval: time.Hour},
+		Trace:              TraceConfig{Exporter: ExporterOTLP},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obs.Logger.Info("started")
+	obs.Metrics.Counter("orders_total", "Orders.").Inc()
+	_, span := obs.Tracer.Start(context.Background(), "charge")
+	span.End()
+
+	if err := obs.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), `"service":"checkout"`) || !strings.Contains(out.String(), `"service_instance":"checkout-1"`) {
+		t.Errorf("expected identity fields in log output, got %s", out.String())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(bodies["/v1/traces"], `"charge"`) || !strings.Contains(bodies["/v1/traces"], "deployment.environment") ||
+		!strings.Contains(bodies["/v1/traces"], `"payments"`) {
+		t.Errorf("unexpected traces export: %s", bodies["/v1/traces"])
+	}
+	if !strings.Contains(bodies["/v1/metrics"], `"orders_total"`) || !strings.Contains(bodies["/v1/metrics"], `"payments"`) ||
+		!strings.Contains(bodies["/v1/metrics"], `"checkout-1"`) {
+		t.Errorf("unexpected metrics export: %s", bodies["/v1/metrics"])
+	}
+}
+
+func TestSetupRejectsBadConfig(t *testing.T) {
+	if _, err := Setup(Config{Trace: TraceConfig{Exporter: "zipkin"}}); !errors.Is(err, ErrUnknownExporter) {
+		t.Errorf("expected ErrUnknownExporter, got %v", err)
+	}
+	if _, err := Setup(Config{Metrics: MetricsConfig{Exporter: ExporterOTLP}}); !errors.Is(err, ErrMissingEndpoint) {
+		t.Errorf("expected ErrMissingEndpoint, got %v", err)
+	}
+	if _, err := Setup(Config{Trace: TraceConfig{Drop: []string{"name =="}}}); err == nil {
+		t.Error("expected an error for an invalid drop rule")
+	}
+}
+
+func TestSetupClosesOnError(t *testing.T) {
+	var pushes sync.WaitGroup
+	pushes.Add(1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/metrics" {
+			pushes.Done()
+		}
+	}))
+	defer collector.Close()
+
+	// The registry is built and must be closed, flushing it once, when
+	// the tracer fails.
+	_, err := Setup(Config{
+		OTLPEndpoint: collector.URL,
+		Logs:         LogsConfig{Output: io.Discard},
+		Metrics:      MetricsConfig{Exporter: ExporterOTLP, PushInterval: time.Hour},
+		Trace:        TraceConfig{Drop: []string{"name =="}},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an invalid drop rule")
+	}
+	pushes.Wait()
+
+	// The listener fails after everything else is built.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	pushes.Add(1)
+	if _, err := Setup(Config{
+		OTLPEndpoint: collector.URL,
+		Logs:         LogsConfig{Output: io.Discard},
+		Metrics:      MetricsConfig{Exporter: ExporterOTLP, PushInterval: time.Hour, ListenAddr: l.Addr().String()},
+	}); err == nil {
+		t.Fatal("expected an error for an address in use")
+	}
+	pushes.Wait()
+}
+
+func TestFollowTraceSampling(t *testing.T) {
+	var out bytes.Buffer
+	cfg := Config{
+		ServiceName: "checkout",
+		Logs:        LogsConfig{Format: "json", Output: &out, FollowTraceSampling: true},
+		Trace:       TraceConfig{SpanExporter: trace.NewInMemoryExporter()},
+	}
+	obs, err := Setup(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	ctx, span := obs.Tracer.Start(context.Background(), "charge")
+	obs.Logger.DebugContext(ctx, "sampled debug")
+	span.End()
+	obs.Logger.Debug("background debug")
+	obs.Logger.Info("background info")
+
+	cfg.Trace.SampleRatio = -1
+	if err := obs.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	ctx, span = obs.Tracer.Start(context.Background(), "refund")
+	obs.Logger.DebugContext(ctx, "unsampled debug")
+	span.End()
+
+	for msg, want := range map[string]bool{
+		"sampled debug":    true,
+		"background debug": false,
+		"background info":  true,
+		"unsampled debug":  false,
+	} {
+		if strings.Contains(out.String(), `"msg":"`+msg+`"`) != want {
+			t.Errorf("%q logged = %v, want %v", msg, !want, want)
+		}
+	}
+}
+
+func TestSetupDropRules(t *testing.T) {
+	spans := trace.NewInMemoryExporter()
+	obs, err := Setup(Config{
+		ServiceName: "checkout",
+		Logs:        LogsConfig{Output: io.Discard},
+		Metrics:     MetricsConfig{Drop: []string{`name =~ "^debug_"`}},
+		Trace:       TraceConfig{SpanExporter: spans, Drop: []string{`name == "GET /health" && status != error`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	for _, name := range []string{"GET /health", "charge"} {
+		_, span := obs.Tracer.Start(context.Background(), name)
+		span.End()
+	}
+	if ended := spans.Spans(); len(ended) != 1 || ended[0].Name != "charge" {
+		t.Errorf("expected only charge to be exported, got %d spans", len(ended))
+	}
+
+	obs.Metrics.Counter("debug_calls_total", "").Inc()
+	obs.Metrics.Counter("charges_total", "").Inc()
+	for _, s := range obs.Metrics.Collect() {
+		if strings.HasPrefix(s.Name, "debug_") {
+			t.Errorf("expected %s to be dropped", s.Name)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "billing")
+	t.Setenv("LUMEN_LOG_LEVEL", "debug")
+	t.Setenv("LUMEN_TRACE_SAMPLE_RATIO", "0.25")
+	t.Setenv("LUMEN_METRICS_PUSH_INTERVAL", "15s")
+
+	cfg := ConfigFromEnv()
+	if cfg.ServiceName != "billing" || cfg.Logs.Level != "debug" ||
+		cfg.Trace.SampleRatio != 0.25 || cfg.Metrics.PushInterval != 15*time.Second {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestWatchConfigReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lumen.json")
+	if err := os.WriteFile(path, []byte(`{"service_name":"checkout"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfigFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	cfg.Logs = LogsConfig{Format: "json", Output: &out}
+	obs, err := Setup(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	stop := obs.WatchConfig(path, &WatchOptions{Interval: 10 * time.Millisecond, DisableSIGHUP: true})
+	// Ensure a different modification time on coarse-grained filesystems.
+	time.Sleep(20 * time.Millisecond)
+	data := `{"service_name":"checkout","logs":{"level":"debug"},"trace":{"sample_ratio":0.5}}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for obs.Logger.GetLevel() != logs.DebugLevel && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	if got := obs.CurrentConfig(); got.Logs.Level != "debug" || got.Trace.SampleRatio != 0.5 {
+		t.Fatalf("config not reloaded: %+v", got)
+	}
+	if !strings.Contains(out.String(), "lumen config reloaded") || !strings.Contains(out.String(), "logs.level") {
+		t.Errorf("expected an audit entry, got %s", out.String())
+	}
+
+	bad := obs.CurrentConfig()
+	bad.Logs.Level = "verbose"
+	bad.Trace.SampleRatio = 1
+	if err := obs.Reload(bad); !errors.Is(err, ErrInvalidLevel) {
+		t.Errorf("expected ErrInvalidLevel, got %v", err)
+	}
+	if got := obs.CurrentConfig(); got.Trace.SampleRatio != 0.5 {
+		t.Errorf("invalid reload applied changes: %+v", got)
+	}
+}
+
+func TestReloadAuditOrder(t *testing.T) {
+	var out bytes.Buffer
+	cfg := Config{ServiceName: "checkout", Logs: LogsConfig{Format: "json", Output: &out}}
+	obs, err := Setup(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	for i := range 5 {
+		out.Reset()
+		next := obs.CurrentConfig()
+		next.ServiceName += "-v2"
+		next.Logs.Format += "x"
+		next.Metrics.Exporter += "x"
+		next.Trace.Exporter += "x"
+		next.Trace.SampleRatio = 1 / float64(i+2)
+		if next.Logs.Level == "debug" {
+			next.Logs.Level = "info"
+		} else {
+			next.Logs.Level = "debug"
+		}
+		if err := obs.Reload(next); err != nil {
+			t.Fatal(err)
+		}
+
+		var entry struct {
+			Changes         []string `json:"changes"`
+			RestartRequired []string `json:"restart_required"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+			t.Fatalf("%v: %s", err, out.String())
+		}
+		if !slices.IsSorted(entry.Changes) || len(entry.Changes) != 2 {
+			t.Errorf("changes = %q", entry.Changes)
+		}
+		if want := []string{"logs.format", "metrics.exporter", "service_name", "trace.exporter"}; !slices.Equal(entry.RestartRequired, want) {
+			t.Errorf("restart_required = %q, want %q", entry.RestartRequired, want)
+		}
+	}
+}
+
+func TestSetupCorrelatesSignals(t *testing.T) {
+	var out bytes.Buffer
+	spans := trace.NewInMemoryExporter()
+	obs, err := Setup(Config{
+		ServiceName: "checkout",
+		Logs:        LogsConfig{Format: "json", Output: &out},
+		Trace:       TraceConfig{SpanExporter: spans},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, span := obs.Tracer.Start(context.Background(), "charge")
+	obs.Logger.InfoContext(ctx, "charging")
+	obs.Logger.DebugContext(ctx, "filtered by level")
+	obs.Logger.WarnContext(ctx, "retrying")
+	latency := obs.Metrics.Histogram("charge_seconds", "", nil)
+	latency.ObserveContext(ctx, 0.2)
+	traceID := span.TraceID().String()
+	span.End()
+
+	if !strings.Contains(out.String(), `"trace_id":"`+traceID+`"`) {
+		t.Errorf("expected trace_id in context logs, got %s", out.String())
+	}
+
+	var exemplar *metrics.Exemplar
+	for _, s := range latency.Collect() {
+		if s.Exemplar != nil {
+			exemplar = s.Exemplar
+		}
+	}
+	if exemplar == nil || exemplar.Labels.Get(TraceIDKey) != traceID {
+		t.Errorf("expected a trace exemplar, got %+v", exemplar)
+	}
+
+	ended := spans.Spans()
+	if len(ended) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(ended))
+	}
+	var count any
+	for _, a := range ended[0].Attributes {
+		if a.Key == LogCountKey {
+			count = a.Value
+		}
+	}
+	if count != int64(2) {
+		t.Errorf("expected log.count 2, got %v", count)
+	}
+	if err := obs.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	obs, err := Setup(Config{ServiceName: "checkout", ServiceVersion: "1.2.3", Logs: LogsConfig{Output: io.Discard}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	_, span := obs.Tracer.Start(context.Background(), "charge")
+	span.End()
+
+	srv := httptest.NewServer(obs.AdminHandler(&AdminOptions{
+		BearerToken: "secret",
+		HealthChecks: map[string]func(context.Context) error{
+			"db": func(context.Context) error { return errors.New("down") },
+		},
+	}))
+	defer srv.Close()
+
+	do := func(method, path, token string) (int, string) {
+		req, _ := http.NewRequest(method, srv.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, _ := do("GET", "/buildinfo", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", code)
+	}
+	if code, body := do("GET", "/buildinfo", "secret"); code != http.StatusOK || !strings.Contains(body, `"version":"1.2.3"`) {
+		t.Errorf("unexpected buildinfo: %d %s", code, body)
+	}
+	if code, body := do("GET", "/healthz", ""); code != http.StatusServiceUnavailable || !strings.Contains(body, `"db":"down"`) {
+		t.Errorf("unexpected health: %d %s", code, body)
+	}
+	if code, body := do("POST", "/loglevel?logger=root&level=debug", "secret"); code != http.StatusOK || !strings.Contains(body, `"root":"debug"`) {
+		t.Errorf("unexpected loglevel response: %d %s", code, body)
+	}
+	if code, _ := do("POST", "/loglevel?logger=root&level=loud", "secret"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid level, got %d", code)
+	}
+	if code, body := do("GET", "/traces/recent", "secret"); code != http.StatusOK || !strings.Contains(body, `"charge"`) {
+		t.Errorf("unexpected recent traces: %d %s", code, body)
+	}
+	if code, _ := do("GET", "/metrics", "secret"); code != http.StatusOK {
+		t.Errorf("expected metrics, got %d", code)
+	}
+}
+
+func TestAdminLogLevelFollowTraceSampling(t *testing.T) {
+	var out bytes.Buffer
+	obs, err := Setup(Config{
+		ServiceName: "checkout",
+		Logs:        LogsConfig{Format: "json", Output: &out, FollowTraceSampling: true},
+		Trace:       TraceConfig{SpanExporter: trace.NewInMemoryExporter()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	srv := httptest.NewServer(obs.AdminHandler(nil))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/loglevel?level=warn", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"root":"warn"`) {
+		t.Fatalf("unexpected loglevel response: %d %s", resp.StatusCode, body)
+	}
+
+	// The logger still admits debug entries of sampled traces.
+	ctx, span := obs.Tracer.Start(context.Background(), "charge")
+	obs.Logger.DebugContext(ctx, "sampled debug")
+	span.End()
+
+	// A later Reload of the current config keeps the level set here.
+	cfg := obs.CurrentConfig()
+	if cfg.Logs.Level != "warn" {
+		t.Errorf("live level = %q, want warn", cfg.Logs.Level)
+	}
+	cfg.Trace.SampleRatio = 0.5
+	if err := obs.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	obs.Logger.Info("background info")
+	obs.Logger.Warn("background warn")
+
+	for msg, want := range map[string]bool{
+		"sampled debug":   true,
+		"background info": false,
+		"background warn": true,
+	} {
+		if strings.Contains(out.String(), `"msg":"`+msg+`"`) != want {
+			t.Errorf("%q logged = %v, want %v", msg, !want, want)
+		}
+	}
+}
+
+func TestFlightRecorderDumps(t *testing.T) {
+	var out bytes.Buffer
+	dir := t.TempDir()
+	obs, err := Setup(Config{
+		ServiceName:    "checkout",
+		Logs:           LogsConfig{Output: &out},
+		FlightRecorder: FlightRecorderConfig{Enabled: true, Dir: dir},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	obs.Logger.Debug("cache miss", logs.String("key", "user:42"))
+	obs.Metrics.Counter("orders_total", "Orders.").Inc()
+	_, span := obs.Tracer.Start(context.Background(), "charge")
+	span.End()
+	obs.FlightRecorder.SnapshotMetrics()
+	if strings.Contains(out.String(), "cache miss") {
+		t.Error("debug entry was printed at info level")
+	}
+
+	srv := httptest.NewServer(obs.AdminHandler(nil))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/flightrecorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{`"reason": "admin request"`, `cache miss`, `user:42`, `"charge"`, `# TYPE orders_total counter`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("dump lacks %s:\n%s", want, body)
+		}
+	}
+
+	func() {
+		defer func() { recover() }()
+		defer obs.FlightRecorder.Recover()
+		panic("boom")
+	}()
+	files, _ := filepath.Glob(filepath.Join(dir, "flight-*.json"))
+	if len(files) != 1 {
+		t.Fatalf("dump files = %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), `"reason": "panic: boom"`) || !strings.Contains(string(data), "TestFlightRecorderDumps") {
+		t.Errorf("panic dump:\n%s", data)
+	}
+}
+
+func TestBoost(t *testing.T) {
+	var out bytes.Buffer
+	spans := trace.NewInMemoryExporter()
+	obs, err := Setup(Config{
+		ServiceName: "checkout",
+		Logs:        LogsConfig{Format: "json", Output: &out},
+		Trace:       TraceConfig{SpanExporter: spans, SampleRatio: -1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	handler := obs.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Logger.Debug("handling " + r.URL.Path)
+		logs.CtxDebug(r.Context(), "via context "+r.URL.Path)
+	}))
+	serve := func(path string) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	stop := obs.Boost(context.Background(), time.Minute, semconv.URLPath("/checkout"))
+	serve("/checkout")
+	serve("/health")
+	stop()
+	serve("/checkout")
+
+	if got := strings.Count(out.String(), "handling /checkout"); got != 1 {
+		t.Errorf("boosted debug entries = %d, want 1:\n%s", got, out.String())
+	}
+	if strings.Contains(out.String(), "handling /health") {
+		t.Error("debug entry logged outside the boost scope")
+	}
+	ended := spans.Spans()
+	if len(ended) != 1 {
+		t.Fatalf("sampled spans = %d, want only the boosted request", len(ended))
+	}
+	var events []string
+	for _, e := range ended[0].Events {
+		events = append(events, e.Name)
+	}
+	if got := strings.Join(events, ","); got != "handling /checkout,via context /checkout" {
+		t.Errorf("span events = %q", got)
+	}
+
+	Boost(context.Background(), 20*time.Millisecond)
+	if obs.Logger.GetLevel() != logs.DebugLevel {
+		t.Errorf("level during unscoped boost = %v", obs.Logger.GetLevel())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for obs.Logger.GetLevel() != logs.InfoLevel && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if obs.Logger.GetLevel() != logs.InfoLevel {
+		t.Errorf("level after boost = %v, want info", obs.Logger.GetLevel())
+	}
+}
+
+func TestMiddlewarePopulatesContext(t *testing.T) {
+	var out bytes.Buffer
+	spans := trace.NewInMemoryExporter()
+	obs, err := Setup(Config{
+		ServiceName: "checkout",
+		Logs:        LogsConfig{Format: "json", Output: &out},
+		Trace:       TraceConfig{SpanExporter: spans},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	handler := obs.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tel := FromContext(r.Context())
+		tel.Logger.Info("handling")
+		tel.Span.SetAttribute("order.id", "42")
+		tel.Metrics.Inc("orders_total", "status", "ok")
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest("POST", "/orders", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(out.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) {
+		t.Errorf("expected the propagated trace_id in request logs, got %s", out.String())
+	}
+	if got := obs.Metrics.Counter("orders_total", "", "status").Value("ok"); got != 1 {
+		t.Errorf("expected orders_total 1, got %g", got)
+	}
+	ended := spans.Spans()
+	if len(ended) != 1 || ended[0].Name != "POST /orders" {
+		t.Fatalf("unexpected spans: %+v", ended)
+	}
+	var attrs []string
+	for _, a := range ended[0].Attributes {
+		attrs = append(attrs, a.Key)
+	}
+	if !slices.Contains(attrs, "order.id") || !slices.Contains(attrs, "http.response.status_code") {
+		t.Errorf("unexpected span attributes: %v", attrs)
+	}
+}
+
+func TestMiddlewareChildSpanLogs(t *testing.T) {
+	var out bytes.Buffer
+	spans := trace.NewInMemoryExporter()
+	obs, err := Setup(Config{
+		ServiceName: "checkout",
+		Logs:        LogsConfig{Format: "json", Output: &out},
+		Trace:       TraceConfig{SpanExporter: spans},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	var childID string
+	handler := obs.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, child := obs.Tracer.Start(r.Context(), "charge")
+		childID = child.SpanID().String()
+		FromContext(ctx).Logger.InfoContext(ctx, "hello")
+		child.End()
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders", nil))
+
+	line := strings.TrimSpace(out.String())
+	for _, key := range []string{TraceIDKey, SpanIDKey} {
+		if n := strings.Count(line, `"`+key+`"`); n != 1 {
+			t.Errorf("%s appears %d times: %s", key, n, line)
+		}
+	}
+	if !strings.Contains(line, `"span_id":"`+childID+`"`) {
+		t.Errorf("expected the child span's ID %s, got %s", childID, line)
+	}
+
+	counts := map[string]any{}
+	for _, s := range spans.Spans() {
+		for _, a := range s.Attributes {
+			if a.Key == LogCountKey {
+				counts[s.Name] = a.Value
+			}
+		}
+	}
+	if counts["charge"] != int64(1) || counts["GET /orders"] != int64(0) {
+		t.Errorf("log.count by span = %v, want the entry counted against the child", counts)
+	}
+}
+
+func TestFromContextWithoutMiddleware(t *testing.T) {
+	tel := FromContext(context.Background())
+	if tel.Logger == nil || tel.Span == nil || tel.Metrics == nil {
+		t.Fatalf("expected fallback handles, got %+v", tel)
+	}
+	tel.Span.SetAttribute("ignored", true)
+	tel.Span.End()
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+type failingPushExporter struct{}
+
+func (failingPushExporter) Export(context.Context, []metrics.Sample) error {
+	return errors.New("connection refused")
+}
+
+func TestOnInternalError(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[string]error)
+	remove := OnInternalError(func(component string, err error) {
+		mu.Lock()
+		got[component] = err
+		mu.Unlock()
+	})
+
+	logger := logs.New(&logs.Options{
+		Output: failingWriter{},
+		Hooks:  []logs.Hook{logs.NewWriterHook(failingWriter{}, &logs.JSONFormatter{})},
+	})
+	logger.Info("lost")
+
+	tracer := trace.New(&trace.Options{ServiceName: "api", Exporter: trace.NewWriterExporter(failingWriter{})})
+	_, span := tracer.Start(t.Context(), "op")
+	span.End()
+	tracer.Close()
+
+	reg := metrics.NewRegistry(&metrics.Options{PushExporter: failingPushExporter{}, PushInterval: time.Hour})
+	reg.Counter("requests_total", "Requests.").Inc()
+	reg.Close()
+
+	mu.Lock()
+	for _, component := range []string{ComponentLogs, ComponentLogHook, ComponentTraceExport, ComponentMetricsPush} {
+		if got[component] == nil {
+			t.Errorf("no error reported for %s", component)
+		}
+	}
+	if !errors.Is(got[ComponentMetricsPush], metrics.ErrExporterFailed) {
+		t.Errorf("push error = %v", got[ComponentMetricsPush])
+	}
+	clear(got)
+	mu.Unlock()
+
+	remove()
+	logger.Info("lost again")
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 0 {
+		t.Errorf("reported after remove: %v", got)
+	}
+}
+
+// writeConfigMap lays out data the way the kubelet mounts a ConfigMap: a
+// timestamped directory, a ..data symlink swapped atomically to it, and a
+// symlink per key through ..data.
+func writeConfigMap(t *testing.T, dir, revision string, data map[string]string) {
+	t.Helper()
+	if err := os.Mkdir(filepath.Join(dir, revision), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range data {
+		if err := os.WriteFile(filepath.Join(dir, revision, key), []byte(value), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Symlink(filepath.Join("..data", key), filepath.Join(dir, key))
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(revision, tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if _, ok := data[e.Name()]; !ok && !strings.HasPrefix(e.Name(), "..") {
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+func TestWatchConfigMap(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigMap(t, dir, "..2026_10_17_08_00_00.1", map[string]string{
+		"logs.level": "debug\n",
+		"app.flags":  "ignored",
+	})
+	obs, err := Setup(Config{ServiceName: "checkout", Logs: LogsConfig{Output: io.Discard}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	var failures []error
+	w := obs.WatchConfigMap(dir, &ConfigMapOptions{
+		Interval: 5 * time.Millisecond,
+		OnError:  func(err error) { failures = append(failures, err) },
+	})
+	defer w.Stop()
+	if st := w.Status(); st.Revision != "..2026_10_17_08_00_00.1" || obs.Logger.GetLevel() != logs.DebugLevel {
+		t.Fatalf("initial revision not applied: %+v", st)
+	}
+
+	waitRevision := func(rev string) ConfigMapStatus {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if st := w.Status(); st.Revision == rev || st.FailedRevision == rev {
+				return st
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("revision %s not seen: %+v", rev, w.Status())
+		return ConfigMapStatus{}
+	}
+
+	writeConfigMap(t, dir, "..2026_10_17_09_00_00.2", map[string]string{
+		"lumen.json":         `{"logs":{"level":"warn"}}`,
+		"trace.sample_ratio": "0.25",
+	})
+	waitRevision("..2026_10_17_09_00_00.2")
+	if got := obs.CurrentConfig(); got.Logs.Level != "warn" || got.Trace.SampleRatio != 0.25 {
+		t.Errorf("config not applied: %+v", got)
+	}
+
+	writeConfigMap(t, dir, "..2026_10_17_10_00_00.3", map[string]string{"trace.ratio": "0.5"})
+	st := waitRevision("..2026_10_17_10_00_00.3")
+	if st.Revision != "..2026_10_17_09_00_00.2" || !strings.Contains(st.Error, "trace.ratio") {
+		t.Errorf("status = %+v", st)
+	}
+	w.Stop()
+	if len(failures) != 1 || !errors.Is(failures[0], ErrUnknownConfigKey) {
+		t.Errorf("failures = %v", failures)
+	}
+
+	rec := httptest.NewRecorder()
+	obs.AdminHandler(&AdminOptions{ConfigMap: w}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/configmap", nil))
+	if !strings.Contains(rec.Body.String(), `"revision":"..2026_10_17_09_00_00.2"`) {
+		t.Errorf("/configmap = %s", rec.Body.String())
+	}
+}
+
+func TestDatadogLogFormat(t *testing.T) {
+	var out bytes.Buffer
+	obs, err := Setup(Config{
+		ServiceName:              "checkout",
+		ServiceVersion:           "1.2.3",
+		DisableResourceDetection: true,
+		Logs:                     LogsConfig{Format: "datadog", Output: &out},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obs.Shutdown(context.Background())
+
+	ctx, span := obs.Tracer.Start(context.Background(), "op")
+	obs.Logger.InfoContext(ctx, "correlated")
+	span.End()
+
+	line := out.String()
+	for _, want := range []string{`"status":"info"`, `"dd.service":"checkout"`, `"dd.version":"1.2.3"`, `"dd.trace_id":"`, `"dd.span_id":"`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("missing %s in %s", want, line)
+		}
+	}
+}
@@ -0,0 +1,430 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// GaugeMerge selects how an Aggregator combines a gauge series reported by
+// several workers.
+type GaugeMerge int
+
+const (
+	// GaugeSum adds the workers' values, for gauges such as in-flight
+	// requests or open connections.
+	GaugeSum GaugeMerge = iota
+	// GaugeMax keeps the largest value.
+	GaugeMax
+	// GaugeMin keeps the smallest value.
+	GaugeMin
+	// GaugeLatest keeps the value from the most recent push.
+	GaugeLatest
+)
+
+// AggregatorOptions configures an Aggregator.
+type AggregatorOptions struct {
+	// Gauges selects how gauge series are combined. Defaults to GaugeSum.
+	Gauges GaugeMerge
+
+	// WorkerTTL forgets a worker that has not pushed for this long. Its
+	// counters then drop out of the sums, which scrapers see as a counter
+	// reset. Zero keeps workers forever.
+	WorkerTTL time.Duration
+
+	// MaxBodyBytes limits the size of a push. Defaults to 8 MiB.
+	MaxBodyBytes int64
+}
+
+func (o *AggregatorOptions) applyDefaults() {
+	if o.MaxBodyBytes <= 0 {
+		o.MaxBodyBytes = 8 << 20
+	}
+}
+
+// Aggregator receives metrics pushed by several processes, such as
+// pre-forked workers or fanned-out CLI invocations, and merges them into
+// one view registered in a Registry, so a single endpoint serves them all:
+//
+//	r := metrics.NewRegistry(nil)
+//	agg := metrics.NewAggregator(r, nil)
+//	http.Handle("/push", agg)
+//	http.Handle("/metrics", metrics.HTTPHandler(r))
+//
+// Workers push with an AggregatorExporter. Counters and histograms are
+// summed across workers; gauges are combined as AggregatorOptions.Gauges
+// selects, and summary quantiles report the largest worker's value. Exponential histogram buckets are not merged, only their count
+// and sum.
+type Aggregator struct {
+	r    *Registry
+	opts AggregatorOptions
+
+	mu       sync.Mutex
+	workers  map[string]*aggregatorWorker
+	families map[string]*aggregatedFamily
+}
+
+type aggregatorWorker struct {
+	seen     time.Time
+	families map[string]pushedFamily
+}
+
+// NewAggregator creates an aggregator that registers merged metrics in r.
+func NewAggregator(r *Registry, opts *AggregatorOptions) *Aggregator {
+	if opts == nil {
+		opts = &AggregatorOptions{}
+	}
+	o := *opts
+	o.applyDefaults()
+
+	return &Aggregator{
+		r:        r,
+		opts:     o,
+		workers:  make(map[string]*aggregatorWorker),
+		families: make(map[string]*aggregatedFamily),
+	}
+}
+
+// ServeHTTP accepts a push from an AggregatorExporter.
+func (a *Aggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var p pushPayload
+	body := http.MaxBytesReader(w, r.Body, a.opts.MaxBodyBytes)
+	if err := json.NewDecoder(body).Decode(&p); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if p.Worker == "" {
+		http.Error(w, "missing worker", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.Accept(p.Worker, p.families()); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Accept records the metrics of one worker, replacing what it pushed
+// before. It fails if a family conflicts with a metric already registered
+// under the same name.
+func (a *Aggregator) Accept(worker string, families []MetricFamily) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	pushed := make(map[string]pushedFamily, len(families))
+	for _, f := range families {
+		af, ok := a.families[f.Name]
+		if !ok {
+			af = &aggregatedFamily{a: a, name: f.Name, help: f.Help, typ: f.Type}
+			if err := a.r.Register(af); err != nil {
+				return fmt.Errorf("aggregate %s: %w", f.Name, err)
+			}
+			a.families[f.Name] = af
+		} else if af.typ != f.Type {
+			return fmt.Errorf("aggregate %s: %w", f.Name, ErrMetricExists)
+		}
+		pushed[f.Name] = pushedFamily{samples: f.Samples}
+	}
+
+	a.workers[worker] = &aggregatorWorker{seen: time.Now(), families: pushed}
+	return nil
+}
+
+// Workers returns the IDs of the workers currently contributing metrics.
+func (a *Aggregator) Workers() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expire()
+
+	ids := make([]string, 0, len(a.workers))
+	for id := range a.workers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (a *Aggregator) expire() {
+	if a.opts.WorkerTTL <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-a.opts.WorkerTTL)
+	for id, w := range a.workers {
+		if w.seen.Before(cutoff) {
+			delete(a.workers, id)
+		}
+	}
+}
+
+type pushedFamily struct {
+	samples []Sample
+}
+
+// aggregatedFamily is the merged view of one family, registered in the
+// aggregator's registry.
+type aggregatedFamily struct {
+	a    *Aggregator
+	name string
+	help string
+	typ  MetricType
+}
+
+func (f *aggregatedFamily) Name() string         { return f.name }
+func (f *aggregatedFamily) Help() string         { return f.help }
+func (f *aggregatedFamily) Type() MetricType     { return f.typ }
+func (f *aggregatedFamily) LabelNames() []string { return nil }
+
+// Collect merges the family's series across workers.
+func (f *aggregatedFamily) Collect() []Sample {
+	a := f.a
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expire()
+
+	type merged struct {
+		sample Sample
+		seen   time.Time
+	}
+	var order []string
+	series := make(map[string]*merged)
+
+	for _, w := range a.workers {
+		for _, s := range w.families[f.name].samples {
+			key := s.Name + "\xff" + s.Labels.Hash()
+			m, ok := series[key]
+			if !ok {
+				series[key] = &merged{sample: s, seen: w.seen}
+				order = append(order, key)
+				continue
+			}
+			m.sample.Value = f.merge(s.Name, m.sample.Value, s.Value, m.seen, w.seen)
+			m.seen = later(m.seen, w.seen)
+			if s.Created.Before(m.sample.Created) {
+				m.sample.Created = s.Created
+			}
+			m.sample.Timestamp = later(m.sample.Timestamp, s.Timestamp)
+		}
+	}
+
+	samples := make([]Sample, 0, len(order))
+	for _, key := range order {
+		samples = append(samples, series[key].sample)
+	}
+	return samples
+}
+
+func (f *aggregatedFamily) merge(name string, acc, v float64, accSeen, seen time.Time) float64 {
+	if f.typ == MetricTypeSummary && name == f.name {
+		// Quantiles cannot be combined; the largest is a safe upper bound.
+		return max(acc, v)
+	}
+	if f.typ != MetricTypeGauge {
+		return acc + v
+	}
+	switch f.a.opts.Gauges {
+	case GaugeMax:
+		return max(acc, v)
+	case GaugeMin:
+		return min(acc, v)
+	case GaugeLatest:
+		if seen.After(accSeen) {
+			return v
+		}
+		return acc
+	default:
+		return acc + v
+	}
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// AggregatorExporterOptions configures an AggregatorExporter.
+type AggregatorExporterOptions struct {
+	// Worker identifies this process to the aggregator. Defaults to
+	// "<hostname>-<pid>".
+	Worker string
+
+	// Client sends the pushes. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// AggregatorExporter pushes a registry's metrics to an Aggregator. Set it
+// as Options.PushExporter in each worker:
+//
+//	metrics.NewRegistry(&metrics.Options{
+//		PushInterval: 10 * time.Second,
+//		PushExporter: metrics.NewAggregatorExporter("http://127.0.0.1:9100/push", nil),
+//	})
+type AggregatorExporter struct {
+	url    string
+	worker string
+	client *http.Client
+}
+
+// NewAggregatorExporter creates an exporter pushing to the aggregator
+// served at url.
+func NewAggregatorExporter(url string, opts *AggregatorExporterOptions) *AggregatorExporter {
+	if opts == nil {
+		opts = &AggregatorExporterOptions{}
+	}
+	e := &AggregatorExporter{url: url, worker: opts.Worker, client: opts.Client}
+	if e.worker == "" {
+		host, _ := os.Hostname()
+		e.worker = host + "-" + strconv.Itoa(os.Getpid())
+	}
+	if e.client == nil {
+		e.client = http.DefaultClient
+	}
+	return e
+}
+
+// ExportFamilies pushes families with their metadata.
+func (e *AggregatorExporter) ExportFamilies(ctx context.Context, families []MetricFamily) error {
+	p := pushPayload{Worker: e.worker, Families: make([]pushedFamilyJSON, 0, len(families))}
+	for _, f := range families {
+		pf := pushedFamilyJSON{Name: f.Name, Help: f.Help, Type: f.Type.String()}
+		for _, s := range f.Samples {
+			pf.Samples = append(pf.Samples, newPushedSample(s))
+		}
+		p.Families = append(p.Families, pf)
+	}
+	return e.send(ctx, p)
+}
+
+// Export pushes bare samples, each sample name as an untyped family. The
+// registry push loop uses ExportFamilies instead.
+func (e *AggregatorExporter) Export(ctx context.Context, samples []Sample) error {
+	byName := make(map[string]int)
+	p := pushPayload{Worker: e.worker}
+	for _, s := range samples {
+		i, ok := byName[s.Name]
+		if !ok {
+			i = len(p.Families)
+			byName[s.Name] = i
+			p.Families = append(p.Families, pushedFamilyJSON{Name: s.Name, Type: MetricTypeUnknown.String()})
+		}
+		p.Families[i].Samples = append(p.Families[i].Samples, newPushedSample(s))
+	}
+	return e.send(ctx, p)
+}
+
+func (e *AggregatorExporter) send(ctx context.Context, p pushPayload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("aggregator responded %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// pushPayload is the JSON body of a push. Values are strings so NaN and
+// infinities survive encoding.
+type pushPayload struct {
+	Worker   string             `json:"worker"`
+	Families []pushedFamilyJSON `json:"families"`
+}
+
+type pushedFamilyJSON struct {
+	Name    string             `json:"name"`
+	Help    string             `json:"help,omitempty"`
+	Type    string             `json:"type"`
+	Samples []pushedSampleJSON `json:"samples"`
+}
+
+type pushedSampleJSON struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Value     string            `json:"value"`
+	Timestamp int64             `json:"timestamp_ms,omitempty"`
+	Created   int64             `json:"created_ms,omitempty"`
+}
+
+func newPushedSample(s Sample) pushedSampleJSON {
+	ps := pushedSampleJSON{Name: s.Name, Value: formatFloat(s.Value)}
+	if s.Labels.Len() > 0 {
+		ps.Labels = make(map[string]string, s.Labels.Len())
+		for i, k := range s.Labels.keys {
+			ps.Labels[k] = s.Labels.values[i]
+		}
+	}
+	if !s.Timestamp.IsZero() {
+		ps.Timestamp = s.Timestamp.UnixMilli()
+	}
+	if !s.Created.IsZero() {
+		ps.Created = s.Created.UnixMilli()
+	}
+	return ps
+}
+
+func (p *pushPayload) families() []MetricFamily {
+	families := make([]MetricFamily, 0, len(p.Families))
+	for _, pf := range p.Families {
+		f := MetricFamily{Name: pf.Name, Help: pf.Help, Type: parseMetricType(pf.Type)}
+		for _, ps := range pf.Samples {
+			v, err := strconv.ParseFloat(ps.Value, 64)
+			if err != nil {
+				continue
+			}
+			s := Sample{Name: ps.Name, Labels: LabelsFromMap(ps.Labels), Value: v}
+			if ps.Timestamp != 0 {
+				s.Timestamp = time.UnixMilli(ps.Timestamp)
+			}
+			if ps.Created != 0 {
+				s.Created = time.UnixMilli(ps.Created)
+			}
+			f.Samples = append(f.Samples, s)
+		}
+		families = append(families, f)
+	}
+	return families
+}
+
+func parseMetricType(s string) MetricType {
+	for t := MetricTypeCounter; t < MetricTypeUnknown; t++ {
+		if t.String() == s {
+			return t
+		}
+	}
+	return MetricTypeUnknown
+}
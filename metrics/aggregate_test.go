@@ -0,0 +1,49 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestAggregatorMergesWorkers(t *testing.T) {
+	r := NewRegistry(nil)
+	srv := httptest.NewServer(NewAggregator(r, &AggregatorOptions{Gauges: GaugeMax}))
+	defer srv.Close()
+
+	for i, worker := range []string{"a", "b"} {
+		w := NewRegistry(nil)
+		w.Counter("jobs_total", "Jobs.", "queue").Add(float64(i+1), "default")
+		w.Gauge("queue_depth", "Depth.").Set(float64(10 * (i + 1)))
+		w.Histogram("job_seconds", "Duration.", []float64{1}).Observe(0.5)
+
+		e := NewAggregatorExporter(srv.URL, &AggregatorExporterOptions{Worker: worker})
+		if err := e.ExportFamilies(context.Background(), w.Gather()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	values := make(map[string]float64)
+	types := make(map[string]MetricType)
+	for _, f := range r.Gather() {
+		types[f.Name] = f.Type
+		for _, s := range f.Samples {
+			values[s.Name+s.Labels.Hash()] = s.Value
+		}
+	}
+
+	if got := values["jobs_total"+NewLabels("queue", "default").Hash()]; got != 3 {
+		t.Errorf("expected counters summed to 3, got %g", got)
+	}
+	if got := values["queue_depth"+Labels{}.Hash()]; got != 20 {
+		t.Errorf("expected gauge max 20, got %g", got)
+	}
+	if got := values["job_seconds_count"+Labels{}.Hash()]; got != 2 {
+		t.Errorf("expected histogram count 2, got %g", got)
+	}
+	if types["jobs_total"] != MetricTypeCounter || types["job_seconds"] != MetricTypeHistogram {
+		t.Errorf("expected types to survive the push, got %v", types)
+	}
+}
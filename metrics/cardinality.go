@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowLabelValue replaces every label value of observations aggregated
+// by the cardinality guard.
+const OverflowLabelValue = "other"
+
+// cardinalityOverflowMetric counts label sets aggregated or rejected by the
+// cardinality guard, labeled by metric name.
+const cardinalityOverflowMetric = "lumen_metrics_cardinality_overflow_total"
+
+// cardinalityGuard is the per-registry series limit installed into each
+// registered metric.
+type cardinalityGuard struct {
+	max     int64
+	reject  bool
+	onLimit func(metric string, labels Labels)
+}
+
+// cardinality tracks a metric's series count against its guard. It is
+// embedded in the labeled metric types.
+type cardinality struct {
+	guard  atomic.Pointer[cardinalityGuard]
+	series atomic.Int64
+
+	ttl     atomic.Int64 // Options.SeriesTTL in nanoseconds
+	touched sync.Map     // series hash -> *seriesTouch
+}
+
+func (c *cardinality) setCardinalityGuard(g *cardinalityGuard) {
+	c.guard.Store(g)
+}
+
+// cardinalityLimited is implemented by metrics that honour
+// Options.MaxSeriesPerMetric.
+type cardinalityLimited interface {
+	setCardinalityGuard(*cardinalityGuard)
+}
+
+// loadSeries returns the series for labels, creating it with newValue if
+// needed. Once the metric holds the maximum number of series, new label
+// sets are folded into a single series whose values are all
+// OverflowLabelValue, or rejected with a nil result.
+func loadSeries[V any](values *sync.Map, c *cardinality, name string, labels Labels, newValue func(Labels) *V) *V {
+	v, hash := lookupSeries(values, c, name, labels, newValue)
+	if v != nil {
+		c.touch(hash)
+	}
+	return v
+}
+
+func lookupSeries[V any](values *sync.Map, c *cardinality, name string, labels Labels, newValue func(Labels) *V) (*V, string) {
+	hash := labels.Hash()
+	if val, ok := values.Load(hash); ok {
+		return val.(*V), hash
+	}
+
+	if g := c.guard.Load(); g != nil && g.max > 0 {
+		if c.series.Add(1) > g.max {
+			c.series.Add(-1)
+			if g.onLimit != nil {
+				g.onLimit(name, labels)
+			}
+			if g.reject {
+				return nil, ""
+			}
+			labels = overflowLabels(labels)
+			hash = labels.Hash()
+			// The overflow series is exempt from the limit.
+			val, _ := values.LoadOrStore(hash, newValue(labels))
+			return val.(*V), hash
+		}
+		val, loaded := values.LoadOrStore(hash, newValue(labels))
+		if loaded {
+			c.series.Add(-1)
+		}
+		return val.(*V), hash
+	}
+
+	val, loaded := values.LoadOrStore(hash, newValue(labels))
+	if !loaded {
+		c.series.Add(1)
+	}
+	return val.(*V), hash
+}
+
+func overflowLabels(l Labels) Labels {
+	o := Labels{
+		keys:   l.keys,
+		values: make([]string, len(l.values)),
+	}
+	for i := range o.values {
+		o.values[i] = OverflowLabelValue
+	}
+	return o
+}
@@ -0,0 +1,93 @@
+package metrics_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func seriesOf(r *Registry, name string) map[string]float64 {
+	series := map[string]float64{}
+	for _, s := range r.Collect() {
+		if s.Name == name {
+			series[fmt.Sprint(s.Labels.Values())] = s.Value
+		}
+	}
+	return series
+}
+
+func TestCardinalityOverflowSeries(t *testing.T) {
+	var limited []string
+	r := NewRegistry(&Options{
+		MaxSeriesPerMetric: 2,
+		OnCardinalityLimit: func(metric string, labels Labels) {
+			limited = append(limited, metric+":"+labels.Get("user"))
+		},
+	})
+	c := r.Counter("requests_total", "Requests.", "user", "code")
+	for _, user := range []string{"u1", "u2", "u3", "u4", "u1"} {
+		c.Inc(user, "200")
+	}
+
+	// Series are keyed by label values in label-name order: code, user.
+	want := map[string]float64{"[200 u1]": 2, "[200 u2]": 1, "[other other]": 2}
+	got := seriesOf(r, "requests_total")
+	if len(got) != len(want) {
+		t.Fatalf("series = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("series %s = %g, want %g", k, got[k], v)
+		}
+	}
+	if v := seriesOf(r, "lumen_metrics_cardinality_overflow_total")["[requests_total]"]; v != 2 {
+		t.Errorf("overflow count = %g, want 2", v)
+	}
+	if len(limited) != 2 || limited[0] != "requests_total:u3" || limited[1] != "requests_total:u4" {
+		t.Errorf("OnCardinalityLimit calls = %v", limited)
+	}
+}
+
+func TestCardinalityReject(t *testing.T) {
+	r := NewRegistry(&Options{MaxSeriesPerMetric: 2, CardinalityReject: true})
+	h := r.Histogram("latency_seconds", "", []float64{1}, "route")
+	for _, route := range []string{"/a", "/b", "/c", "/a"} {
+		h.Observe(0.5, route)
+	}
+
+	got := seriesOf(r, "latency_seconds_count")
+	if len(got) != 2 || got["[/a]"] != 2 || got["[/b]"] != 1 {
+		t.Errorf("series = %v, want /a and /b only", got)
+	}
+	if v := seriesOf(r, "lumen_metrics_cardinality_overflow_total")["[latency_seconds]"]; v != 1 {
+		t.Errorf("overflow count = %g, want 1", v)
+	}
+}
+
+func TestCardinalityConcurrent(t *testing.T) {
+	r := NewRegistry(&Options{MaxSeriesPerMetric: 10})
+	c := r.Counter("events_total", "", "id")
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				c.Inc(fmt.Sprint(g*100 + i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	series := seriesOf(r, "events_total")
+	var total float64
+	for _, v := range series {
+		total += v
+	}
+	if len(series) != 11 || total != 800 {
+		t.Errorf("got %d series totalling %g, want 10 plus the overflow series totalling 800", len(series), total)
+	}
+}
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"context"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,11 +14,59 @@ type Counter struct {
 	help       string
 	labelNames []string
 	values     sync.Map
+	cardinality
+	unitMeta
+	invalidReporting
+	contextExemplars
 }
 
 type counterValue struct {
-	labels Labels
-	value  atomic.Uint64
+	labels   Labels
+	created  time.Time
+	exemplar atomic.Pointer[Exemplar]
+	counterCell
+}
+
+func newCounterValue(labels Labels) *counterValue {
+	return &counterValue{labels: labels, created: time.Now()}
+}
+
+// counterCell keeps whole-number increments in an integer so Inc stays
+// exact at any count, and accumulates fractional increments (or those that
+// would overflow the integer) as float64 bits.
+type counterCell struct {
+	ints      atomic.Uint64
+	floatBits atomic.Uint64
+}
+
+// maxExactInt is the largest integer a float64 represents exactly.
+const maxExactInt = 1 << 53
+
+func (cc *counterCell) add(delta float64) {
+	if delta <= maxExactInt && delta == math.Trunc(delta) {
+		n := uint64(delta)
+		for {
+			old := cc.ints.Load()
+			if old > math.MaxUint64-n {
+				break
+			}
+			if cc.ints.CompareAndSwap(old, old+n) {
+				return
+			}
+		}
+	}
+
+	for {
+		oldBits := cc.floatBits.Load()
+		newVal := math.Float64frombits(oldBits) + delta
+		if cc.floatBits.CompareAndSwap(oldBits, math.Float64bits(newVal)) {
+			return
+		}
+	}
+}
+
+func (cc *counterCell) load() float64 {
+	return float64(cc.ints.Load()) + math.Float64frombits(cc.floatBits.Load())
 }
 
 // NewCounter creates a new counter.
@@ -38,19 +88,54 @@ func (c *Counter) Inc(labelValues ...string) {
 	c.Add(1, labelValues...)
 }
 
-// Add increments by the given value.
+// Add increments by the given value. Negative and NaN deltas are rejected
+// and reported to the registry's Options.OnInvalidObservation.
 func (c *Counter) Add(delta float64, labelValues ...string) {
-	if delta < 0 {
+	c.add(delta, labelValues)
+}
+
+// AddE is like Add but returns an error wrapping ErrInvalidDelta for a
+// negative or NaN delta.
+func (c *Counter) AddE(delta float64, labelValues ...string) error {
+	_, err := c.add(delta, labelValues)
+	return err
+}
+
+// AddWithExemplar increments by the given value and records an exemplar,
+// such as NewLabels("trace_id", id), for the series. Exemplars whose
+// labels exceed 128 characters in total are discarded.
+func (c *Counter) AddWithExemplar(delta float64, exemplar Labels, labelValues ...string) {
+	cv, _ := c.add(delta, labelValues)
+	if cv == nil {
 		return
 	}
+	if ex := newExemplar(exemplar, delta); ex != nil {
+		cv.exemplar.Store(ex)
+	}
+}
 
-	labels := c.makeLabels(labelValues)
-	hash := labels.Hash()
+// AddContext increments by the given value with the exemplar the
+// registry's Options.ExemplarFromContext derives from ctx, if any.
+func (c *Counter) AddContext(ctx context.Context, delta float64, labelValues ...string) {
+	if ex := c.contextExemplar(ctx); ex.Len() > 0 {
+		c.AddWithExemplar(delta, ex, labelValues...)
+		return
+	}
+	c.add(delta, labelValues)
+}
 
-	val, _ := c.values.LoadOrStore(hash, &counterValue{labels: labels})
-	cv := val.(*counterValue)
+func (c *Counter) add(delta float64, labelValues []string) (*counterValue, error) {
+	if err := c.checkDelta(c.name, delta); err != nil {
+		return nil, err
+	}
+
+	cv := loadSeries(&c.values, &c.cardinality, c.name, c.makeLabels(labelValues), newCounterValue)
+	if cv == nil {
+		return nil, nil
+	}
 
-	cv.value.Add(uint64(delta * 1000000))
+	cv.add(delta)
+	return cv, nil
 }
 
 // Value returns the current value for the given labels.
@@ -59,7 +144,7 @@ func (c *Counter) Value(labelValues ...string) float64 {
 	hash := labels.Hash()
 
 	if val, ok := c.values.Load(hash); ok {
-		return float64(val.(*counterValue).value.Load()) / 1000000
+		return val.(*counterValue).load()
 	}
 	return 0
 }
@@ -74,8 +159,10 @@ func (c *Counter) Collect() []Sample {
 		samples = append(samples, Sample{
 			Name:      c.name,
 			Labels:    cv.labels,
-			Value:     float64(cv.value.Load()) / 1000000,
+			Value:     cv.load(),
 			Timestamp: now,
+			Exemplar:  cv.exemplar.Load(),
+			Created:   cv.created,
 		})
 		return true
 	})
@@ -89,6 +176,7 @@ func (c *Counter) Reset() {
 		c.values.Delete(key)
 		return true
 	})
+	c.series.Store(0)
 }
 
 func (c *Counter) makeLabels(values []string) Labels {
@@ -0,0 +1,221 @@
+package metrics_test
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestCounterSmallIncrements(t *testing.T) {
+	c := NewCounter("small_total", "")
+	for range 10 {
+		c.Add(1e-7)
+	}
+	if got := c.Value(); math.Abs(got-1e-6) > 1e-15 {
+		t.Errorf("expected 1e-6, got %g", got)
+	}
+}
+
+func TestCounterLargeIncrements(t *testing.T) {
+	c := NewCounter("large_total", "")
+	c.Add(1e20)
+	c.Add(1e20)
+	if got := c.Value(); got != 2e20 {
+		t.Errorf("expected 2e20, got %g", got)
+	}
+}
+
+func TestCounterIntegerOverflow(t *testing.T) {
+	c := NewCounter("overflow_total", "")
+	const step = float64(1 << 53)
+	for range 4096 {
+		c.Add(step)
+	}
+	want := step * 4096
+	if got := c.Value(); got != want {
+		t.Errorf("expected %g, got %g", want, got)
+	}
+
+	c.Add(step)
+	if got := c.Value(); got <= want {
+		t.Errorf("expected counter to keep growing past 2^64, got %g", got)
+	}
+}
+
+func TestCounterExactIntegers(t *testing.T) {
+	c := NewCounter("exact_total", "")
+	c.Add(1 << 52)
+	c.Inc()
+	if got := c.Value(); got != 1<<52+1 {
+		t.Errorf("expected %d, got %g", uint64(1<<52+1), got)
+	}
+}
+
+func TestCounterIgnoresInvalidDeltas(t *testing.T) {
+	c := NewCounter("invalid_total", "")
+	c.Add(5)
+	c.Add(-1)
+	c.Add(math.NaN())
+	if got := c.Value(); got != 5 {
+		t.Errorf("expected 5, got %g", got)
+	}
+}
+
+func TestCounterConcurrent(t *testing.T) {
+	c := NewCounter("concurrent_total", "", "kind")
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				c.Inc("int")
+				c.Add(0.5, "float")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := c.Value("int"); got != 8000 {
+		t.Errorf("expected 8000, got %g", got)
+	}
+	if got := c.Value("float"); got != 4000 {
+		t.Errorf("expected 4000, got %g", got)
+	}
+}
+
+func TestShardedCounterConcurrent(t *testing.T) {
+	c := NewShardedCounter("hot_total", "", "kind")
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10000 {
+				c.Inc("a")
+			}
+			c.Add(0.25, "b")
+		}()
+	}
+	wg.Wait()
+
+	if got := c.Value("a"); got != 160000 {
+		t.Errorf("expected 160000, got %g", got)
+	}
+	if got := c.Value("b"); got != 4 {
+		t.Errorf("expected 4, got %g", got)
+	}
+	if samples := c.Collect(); len(samples) != 2 {
+		t.Errorf("expected 2 series, got %d", len(samples))
+	}
+}
+
+func TestShardedCounterConcurrentReads(t *testing.T) {
+	r := NewRegistry(nil)
+	c := r.ShardedCounter("hits_total", "Hits.", "path")
+	if r.ShardedCounter("hits_total", "Hits.", "path") != c {
+		t.Fatal("expected the registered sharded counter to be returned")
+	}
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				c.Inc("/a")
+				c.Add(0.5, "/b")
+			}
+		}()
+	}
+	// Read while writers run.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			r.Collect()
+			c.Value("/a")
+		}
+	}()
+	wg.Wait()
+
+	if got := c.Value("/a"); got != 16000 {
+		t.Errorf("/a = %g, want 16000", got)
+	}
+	if got := c.Value("/b"); got != 8000 {
+		t.Errorf("/b = %g, want 8000", got)
+	}
+	if got := seriesOf(r, "hits_total"); len(got) != 2 || got["[/a]"] != 16000 {
+		t.Errorf("collected %v", got)
+	}
+}
+
+func TestShardedCounterIgnoresInvalidDeltas(t *testing.T) {
+	c := NewShardedCounter("hits_total", "")
+	c.Add(2)
+	c.Add(-1)
+	c.Add(math.NaN())
+	if got := c.Value(); got != 2 {
+		t.Errorf("expected 2, got %g", got)
+	}
+
+	c.Reset()
+	if got := c.Value(); got != 0 {
+		t.Errorf("expected 0 after Reset, got %g", got)
+	}
+}
+
+func TestCounterCreatedAdvancesOnReset(t *testing.T) {
+	c := NewCounter("requests_total", "")
+	c.Inc()
+	first := c.Collect()[0].Created
+	if first.IsZero() {
+		t.Fatal("expected a created timestamp")
+	}
+
+	time.Sleep(time.Millisecond)
+	c.Inc()
+	if got := c.Collect()[0].Created; !got.Equal(first) {
+		t.Errorf("expected created to stay %v while counting, got %v", first, got)
+	}
+
+	c.Reset()
+	c.Inc()
+	if got := c.Collect()[0].Created; !got.After(first) {
+		t.Errorf("expected created after reset to be later than %v, got %v", first, got)
+	}
+}
+
+func TestCounterRejectsNegativeDelta(t *testing.T) {
+	var reported []string
+	var logged int
+	r := NewRegistry(&Options{
+		OnInvalidObservation: func(metric string, err error) {
+			if !errors.Is(err, ErrInvalidDelta) {
+				t.Errorf("expected ErrInvalidDelta, got %v", err)
+			}
+			reported = append(reported, metric)
+		},
+		Debugf: func(string, ...any) { logged++ },
+	})
+	c := r.Counter("jobs_total", "Jobs.")
+
+	c.Add(-1)
+	if err := c.AddE(math.NaN()); !errors.Is(err, ErrInvalidDelta) {
+		t.Errorf("expected ErrInvalidDelta for NaN, got %v", err)
+	}
+	if err := c.AddE(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Value(); got != 2 {
+		t.Errorf("expected 2, got %g", got)
+	}
+	if len(reported) != 2 || reported[0] != "jobs_total" || logged != 2 {
+		t.Errorf("expected 2 reports and debug logs, got %v and %d", reported, logged)
+	}
+}
@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+)
+
+// DefaultCompression is the t-digest compression used when none is given.
+const DefaultCompression = 100
+
+// TDigest estimates quantiles of a stream of values in bounded memory,
+// using the merging t-digest. It keeps roughly 2×compression centroids
+// and is most accurate at the tails, so it suits high quantiles such as
+// p99.9 that fixed buckets resolve poorly. Digests can be merged, e.g. to
+// combine windows or processes.
+//
+// A TDigest is not safe for concurrent use; Summary wraps it with locking.
+type TDigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	sum         float64
+	min         float64
+	max         float64
+}
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// NewTDigest creates an empty digest. Higher compression trades memory
+// for accuracy; values <= 0 select DefaultCompression.
+func NewTDigest(compression float64) *TDigest {
+	if compression <= 0 {
+		compression = DefaultCompression
+	}
+	return &TDigest{compression: compression, min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// Add records a value. NaN is ignored.
+func (d *TDigest) Add(v float64) {
+	d.AddWeighted(v, 1)
+}
+
+// AddWeighted records a value with the given weight, as if it had been
+// added weight times. NaN values and non-positive weights are ignored.
+func (d *TDigest) AddWeighted(v, weight float64) {
+	if math.IsNaN(v) || !(weight > 0) {
+		return
+	}
+	d.buffer = append(d.buffer, centroid{mean: v, weight: weight})
+	d.count += weight
+	d.sum += v * weight
+	d.min = min(d.min, v)
+	d.max = max(d.max, v)
+	if len(d.buffer) >= d.bufferSize() {
+		d.compress()
+	}
+}
+
+// Merge adds all values recorded in other to d. other is not modified.
+func (d *TDigest) Merge(other *TDigest) {
+	if other == nil || other.count == 0 {
+		return
+	}
+	d.buffer = append(d.buffer, other.centroids...)
+	d.buffer = append(d.buffer, other.buffer...)
+	d.count += other.count
+	d.sum += other.sum
+	d.min = min(d.min, other.min)
+	d.max = max(d.max, other.max)
+	d.compress()
+}
+
+// Quantile returns the estimated value at quantile q in [0, 1], or NaN if
+// the digest is empty.
+func (d *TDigest) Quantile(q float64) float64 {
+	d.compress()
+	cs := d.centroids
+	switch {
+	case len(cs) == 0 || math.IsNaN(q):
+		return math.NaN()
+	case q <= 0:
+		return d.min
+	case q >= 1:
+		return d.max
+	case len(cs) == 1:
+		return cs[0].mean
+	}
+
+	// Each centroid's mean sits at the middle of its weight; interpolate
+	// between neighbouring centres, and towards min and max at the ends.
+	target := q * d.count
+	if first := cs[0]; target < first.weight/2 {
+		return d.min + (first.mean-d.min)*target/(first.weight/2)
+	}
+	var cum float64
+	for i := 0; i < len(cs)-1; i++ {
+		left := cum + cs[i].weight/2
+		right := cum + cs[i].weight + cs[i+1].weight/2
+		if target <= right {
+			t := (target - left) / (right - left)
+			return cs[i].mean + t*(cs[i+1].mean-cs[i].mean)
+		}
+		cum += cs[i].weight
+	}
+	last := cs[len(cs)-1]
+	t := (target - (d.count - last.weight/2)) / (last.weight / 2)
+	return last.mean + t*(d.max-last.mean)
+}
+
+// Count returns the total weight recorded.
+func (d *TDigest) Count() float64 { return d.count }
+
+// Sum returns the weighted sum of the values recorded.
+func (d *TDigest) Sum() float64 { return d.sum }
+
+// Min returns the smallest value recorded, or +Inf if the digest is empty.
+func (d *TDigest) Min() float64 { return d.min }
+
+// Max returns the largest value recorded, or -Inf if the digest is empty.
+func (d *TDigest) Max() float64 { return d.max }
+
+// Reset empties the digest.
+func (d *TDigest) Reset() {
+	*d = TDigest{compression: d.compression, centroids: d.centroids[:0], buffer: d.buffer[:0],
+		min: math.Inf(1), max: math.Inf(-1)}
+}
+
+func (d *TDigest) bufferSize() int {
+	return int(d.compression) * 5
+}
+
+// compress merges buffered values into the centroids. Adjacent centroids
+// are combined while the result stays within the size the k1 scale
+// function allows at that quantile, which keeps centroids small near 0 and 1.
+func (d *TDigest) compress() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	all := append(d.centroids, d.buffer...)
+	d.buffer = d.buffer[:0]
+	slices.SortFunc(all, func(a, b centroid) int {
+		switch {
+		case a.mean < b.mean:
+			return -1
+		case a.mean > b.mean:
+			return 1
+		}
+		return 0
+	})
+
+	merged := make([]centroid, 0, int(d.compression)*2)
+	cur := all[0]
+	var done float64
+	limit := d.count * d.kInverse(d.k(0)+1)
+	for _, c := range all[1:] {
+		if done+cur.weight+c.weight <= limit {
+			w := cur.weight + c.weight
+			cur.mean += (c.mean - cur.mean) * c.weight / w
+			cur.weight = w
+			continue
+		}
+		merged = append(merged, cur)
+		done += cur.weight
+		limit = d.count * d.kInverse(d.k(done/d.count)+1)
+		cur = c
+	}
+	d.centroids = append(merged, cur)
+}
+
+// k is the k1 scale function, δ/2π·asin(2q-1).
+func (d *TDigest) k(q float64) float64 {
+	return d.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+func (d *TDigest) kInverse(k float64) float64 {
+	if k >= d.compression/4 {
+		return 1
+	}
+	return (math.Sin(k*2*math.Pi/d.compression) + 1) / 2
+}
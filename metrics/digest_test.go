@@ -0,0 +1,74 @@
+package metrics_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestTDigestAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	values := make([]float64, 100000)
+	a, b := NewTDigest(0), NewTDigest(0)
+	for i := range values {
+		values[i] = rng.ExpFloat64()
+		if i%2 == 0 {
+			a.Add(values[i])
+		} else {
+			b.Add(values[i])
+		}
+	}
+	a.Merge(b)
+	slices.Sort(values)
+
+	if a.Count() != float64(len(values)) {
+		t.Fatalf("expected count %d, got %g", len(values), a.Count())
+	}
+	for _, q := range []float64{0.01, 0.5, 0.9, 0.99, 0.999} {
+		got := a.Quantile(q)
+		// Compare ranks: the estimate must sit close to q in the data.
+		rank := float64(sortSearch(values, got)) / float64(len(values))
+		if tol := math.Max(0.001, 0.02*math.Min(q, 1-q)); math.Abs(rank-q) > tol {
+			t.Errorf("q=%g: estimate %g has rank %g", q, got, rank)
+		}
+	}
+	if a.Quantile(0) != values[0] || a.Quantile(1) != values[len(values)-1] {
+		t.Error("expected the extremes to be exact")
+	}
+}
+
+func sortSearch(sorted []float64, v float64) int {
+	i, _ := slices.BinarySearch(sorted, v)
+	return i
+}
+
+func TestSummaryWindow(t *testing.T) {
+	s := NewSummary("latency_seconds", "", &SummaryOptions{MaxAge: time.Hour}, "method")
+	for i := 1; i <= 1000; i++ {
+		s.Observe(float64(i), "get")
+	}
+
+	if got := s.Quantile(0.5, "get"); math.Abs(got-500) > 10 {
+		t.Errorf("expected median near 500, got %g", got)
+	}
+	if got := s.Quantile(0.5, "put"); !math.IsNaN(got) {
+		t.Errorf("expected NaN for an unknown series, got %g", got)
+	}
+
+	var quantiles, count int
+	for _, sample := range s.Collect() {
+		switch sample.Name {
+		case "latency_seconds":
+			quantiles++
+		case "latency_seconds_count":
+			count = int(sample.Value)
+		}
+	}
+	if quantiles != 4 || count != 1000 {
+		t.Errorf("expected 4 quantiles and count 1000, got %d and %d", quantiles, count)
+	}
+}
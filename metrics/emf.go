@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// emfMaxMetrics is the CloudWatch limit on metrics per EMF document.
+const emfMaxMetrics = 100
+
+// EMFOptions configures an EMFExporter.
+type EMFOptions struct {
+	// Writer receives one JSON document per line. Defaults to os.Stdout,
+	// which Lambda and the ECS/Fargate awslogs driver forward to CloudWatch.
+	Writer io.Writer
+
+	// Namespace is the CloudWatch namespace. Defaults to "lumen".
+	Namespace string
+
+	// Dimensions lists the label names used as CloudWatch dimensions.
+	// Other labels are written as searchable properties. Nil uses every
+	// label as a dimension; CloudWatch accepts at most 30.
+	Dimensions []string
+
+	// Units maps metric names to CloudWatch units such as "Seconds",
+	// "Bytes" or "Count".
+	Units map[string]string
+
+	// Properties are constant fields added to every document.
+	Properties map[string]string
+}
+
+func (o *EMFOptions) applyDefaults() {
+	if o.Writer == nil {
+		o.Writer = os.Stdout
+	}
+	if o.Namespace == "" {
+		o.Namespace = "lumen"
+	}
+}
+
+// EMFExporter writes samples in the CloudWatch Embedded Metric Format, so
+// workloads whose logs reach CloudWatch get metrics without an agent.
+// Samples sharing a label set are written as one document. Histogram
+// buckets are skipped; their _sum and _count series are exported. Values
+// are exported as collected, so counters are cumulative.
+type EMFExporter struct {
+	opts EMFOptions
+	mu   sync.Mutex
+}
+
+// NewEMFExporter creates an EMF exporter.
+func NewEMFExporter(opts *EMFOptions) *EMFExporter {
+	if opts == nil {
+		opts = &EMFOptions{}
+	}
+	o := *opts
+	o.applyDefaults()
+	return &EMFExporter{opts: o}
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit,omitempty"`
+}
+
+type emfGroup struct {
+	labels  Labels
+	time    time.Time
+	samples []Sample
+}
+
+// Export writes the samples as EMF documents. It implements PushExporter.
+func (e *EMFExporter) Export(_ context.Context, samples []Sample) error {
+	var order []string
+	groups := make(map[string]*emfGroup)
+
+	for _, s := range samples {
+		if strings.HasSuffix(s.Name, "_bucket") || math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+		key := s.Labels.Hash()
+		g, ok := groups[key]
+		if !ok {
+			g = &emfGroup{labels: s.Labels, time: s.Timestamp}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.samples = append(g.samples, s)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	enc := json.NewEncoder(e.opts.Writer)
+	for _, key := range order {
+		g := groups[key]
+		for chunk := range slices.Chunk(g.samples, emfMaxMetrics) {
+			if err := enc.Encode(e.document(g, chunk)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *EMFExporter) document(g *emfGroup, samples []Sample) map[string]any {
+	doc := make(map[string]any, len(samples)+g.labels.Len()+len(e.opts.Properties)+1)
+	for k, v := range e.opts.Properties {
+		doc[k] = v
+	}
+
+	dims := []string{}
+	for i, k := range g.labels.keys {
+		doc[k] = g.labels.values[i]
+		if e.opts.Dimensions == nil || slices.Contains(e.opts.Dimensions, k) {
+			dims = append(dims, k)
+		}
+	}
+	if len(dims) > 30 {
+		dims = dims[:30]
+	}
+
+	directive := emfDirective{
+		Namespace:  e.opts.Namespace,
+		Dimensions: [][]string{dims},
+	}
+	for _, s := range samples {
+		doc[s.Name] = s.Value
+		directive.Metrics = append(directive.Metrics, emfMetric{Name: s.Name, Unit: e.opts.Units[s.Name]})
+	}
+
+	ts := g.time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	doc["_aws"] = map[string]any{
+		"Timestamp":         ts.UnixMilli(),
+		"CloudWatchMetrics": []emfDirective{directive},
+	}
+	return doc
+}
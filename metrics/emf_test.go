@@ -0,0 +1,66 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestEMFExporter(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEMFExporter(&EMFOptions{
+		Writer:     &buf,
+		Namespace:  "shop",
+		Dimensions: []string{"service"},
+		Units:      map[string]string{"latency_seconds_sum": "Seconds"},
+		Properties: map[string]string{"version": "1.2"},
+	})
+
+	ts := time.UnixMilli(1700000000000)
+	api := NewLabels("service", "api", "pod", "api-1")
+	err := e.Export(context.Background(), []Sample{
+		{Name: "requests_total", Labels: api, Value: 12, Timestamp: ts},
+		{Name: "latency_seconds_bucket", Labels: NewLabels("service", "api", "pod", "api-1", "le", "1"), Value: 3, Timestamp: ts},
+		{Name: "latency_seconds_sum", Labels: api, Value: 1.5, Timestamp: ts},
+		{Name: "queue_depth", Labels: NewLabels("service", "worker"), Value: 4, Timestamp: ts},
+		{Name: "broken", Labels: api, Value: math.NaN(), Timestamp: ts},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"_aws":{"CloudWatchMetrics":[{"Namespace":"shop","Dimensions":[["service"]],"Metrics":[{"Name":"requests_total"},{"Name":"latency_seconds_sum","Unit":"Seconds"}]}],"Timestamp":1700000000000},"latency_seconds_sum":1.5,"pod":"api-1","requests_total":12,"service":"api","version":"1.2"}
+{"_aws":{"CloudWatchMetrics":[{"Namespace":"shop","Dimensions":[["service"]],"Metrics":[{"Name":"queue_depth"}]}],"Timestamp":1700000000000},"queue_depth":4,"service":"worker","version":"1.2"}
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestEMFExporterSplitsDocuments(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEMFExporter(&EMFOptions{Writer: &buf})
+
+	samples := make([]Sample, 150)
+	for i := range samples {
+		samples[i] = Sample{Name: fmt.Sprintf("m%03d", i), Value: float64(i)}
+	}
+	if err := e.Export(context.Background(), samples); err != nil {
+		t.Fatal(err)
+	}
+
+	docs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(docs) != 2 {
+		t.Fatalf("expected 150 metrics to be split into 2 documents, got %d", len(docs))
+	}
+	if !strings.Contains(docs[0], `"m099":99`) || strings.Contains(docs[0], `"m100"`) ||
+		!strings.Contains(docs[1], `"m149":149`) || !strings.Contains(docs[1], `"Namespace":"lumen","Dimensions":[[]]`) {
+		t.Errorf("unexpected documents:\n%s", buf.String())
+	}
+}
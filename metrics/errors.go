@@ -10,4 +10,9 @@ var (
 	ErrInvalidLabelName  = errors.New("metrics: invalid label name")
 	ErrLabelMismatch     = errors.New("metrics: label names do not match")
 	ErrExporterFailed    = errors.New("metrics: exporter failed")
+	ErrUnknownPreset     = errors.New("metrics: unknown bucket preset")
+	ErrNamingConvention  = errors.New("metrics: name violates naming conventions")
+	ErrInvalidDelta      = errors.New("metrics: counter delta must not be negative or NaN")
+
+	ErrStaleMarkersUnsupported = errors.New("metrics: stale markers are not sent to family exporters")
 )
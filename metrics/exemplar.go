@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// contextExemplars is embedded in metrics that can take exemplar labels
+// from a context via Options.ExemplarFromContext.
+type contextExemplars struct {
+	fromContext atomic.Pointer[func(context.Context) Labels]
+}
+
+func (c *contextExemplars) setExemplarFromContext(fn func(context.Context) Labels) {
+	c.fromContext.Store(&fn)
+}
+
+func (c *contextExemplars) contextExemplar(ctx context.Context) Labels {
+	fn := c.fromContext.Load()
+	if fn == nil || ctx == nil {
+		return Labels{}
+	}
+	return (*fn)(ctx)
+}
+
+// exemplarContexter is implemented by metrics that honour
+// Options.ExemplarFromContext.
+type exemplarContexter interface {
+	setExemplarFromContext(func(context.Context) Labels)
+}
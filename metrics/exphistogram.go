@@ -0,0 +1,365 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Scale limits for exponential histograms. The range matches the schemas
+// Prometheus native histograms accept after downscaling, and OTLP's
+// exponential histogram scale.
+const (
+	MinExponentialScale = -4
+	MaxExponentialScale = 20
+)
+
+// ExponentialHistogramOptions configures an ExponentialHistogram.
+type ExponentialHistogramOptions struct {
+	// MaxScale is the starting (and highest) resolution. Each bucket's
+	// upper bound is base^(index+1) with base = 2^(2^-scale).
+	// Nil defaults to MaxExponentialScale; values outside
+	// [MinExponentialScale, MaxExponentialScale] are clamped.
+	MaxScale *int32
+
+	// MaxSize bounds the number of buckets per sign. When an observation
+	// would exceed it, the histogram halves its resolution until the
+	// range fits. Defaults to 160.
+	MaxSize int
+
+	// ZeroThreshold is the width of the zero bucket; observations with an
+	// absolute value at or below it are counted as zero.
+	ZeroThreshold float64
+}
+
+func (o *ExponentialHistogramOptions) applyDefaults() {
+	scale := int32(MaxExponentialScale)
+	if o.MaxScale != nil {
+		scale = min(max(*o.MaxScale, MinExponentialScale), MaxExponentialScale)
+	}
+	o.MaxScale = &scale
+	if o.MaxSize <= 0 {
+		o.MaxSize = 160
+	}
+	if o.MaxSize < 2 {
+		o.MaxSize = 2
+	}
+}
+
+// ExponentialHistogram is a histogram with exponentially sized buckets
+// whose resolution adapts to the observed range, so accurate latency
+// distributions need no bucket layout chosen up front.
+//
+// Text exposition only carries its count and sum; the bucket data is
+// exported via the Prometheus protobuf format and OTLP.
+type ExponentialHistogram struct {
+	name       string
+	help       string
+	labelNames []string
+	opts       ExponentialHistogramOptions
+	values     sync.Map
+	cardinality
+	unitMeta
+}
+
+// ExponentialBucketCounts holds the populated buckets of one sign. Counts[i]
+// is the number of observations in the bucket with index Offset+i.
+type ExponentialBucketCounts struct {
+	Offset int32
+	Counts []uint64
+}
+
+// ExponentialHistogramPoint is a snapshot of one exponential histogram
+// series.
+type ExponentialHistogramPoint struct {
+	Labels        Labels
+	Scale         int32
+	Count         uint64
+	Sum           float64
+	Min           float64
+	Max           float64
+	ZeroThreshold float64
+	ZeroCount     uint64
+	Positive      ExponentialBucketCounts
+	Negative      ExponentialBucketCounts
+	Timestamp     time.Time
+	Created       time.Time
+}
+
+// ExponentialCollector is implemented by metrics that expose exponential
+// histogram data. Registry.Gather attaches it to the metric's family.
+type ExponentialCollector interface {
+	CollectExponential() []ExponentialHistogramPoint
+}
+
+type expHistogramValue struct {
+	mu        sync.Mutex
+	labels    Labels
+	created   time.Time
+	scale     int32
+	count     uint64
+	sum       float64
+	min       float64
+	max       float64
+	zeroCount uint64
+	positive  expBuckets
+	negative  expBuckets
+}
+
+// NewExponentialHistogram creates a new exponential histogram.
+func NewExponentialHistogram(name, help string, opts *ExponentialHistogramOptions, labelNames ...string) *ExponentialHistogram {
+	if opts == nil {
+		opts = &ExponentialHistogramOptions{}
+	}
+	o := *opts
+	o.applyDefaults()
+
+	return &ExponentialHistogram{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		opts:       o,
+	}
+}
+
+func (h *ExponentialHistogram) Name() string         { return h.name }
+func (h *ExponentialHistogram) Help() string         { return h.help }
+func (h *ExponentialHistogram) Type() MetricType     { return MetricTypeHistogram }
+func (h *ExponentialHistogram) LabelNames() []string { return h.labelNames }
+
+// Observe adds an observation. NaN and infinite values are ignored.
+func (h *ExponentialHistogram) Observe(value float64, labelValues ...string) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	hv := loadSeries(&h.values, &h.cardinality, h.name, h.makeLabels(labelValues), func(labels Labels) *expHistogramValue {
+		return &expHistogramValue{labels: labels, created: time.Now(), scale: *h.opts.MaxScale}
+	})
+	if hv == nil {
+		return
+	}
+
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+
+	if hv.count == 0 || value < hv.min {
+		hv.min = value
+	}
+	if hv.count == 0 || value > hv.max {
+		hv.max = value
+	}
+	hv.count++
+	hv.sum += value
+
+	abs := math.Abs(value)
+	if abs <= h.opts.ZeroThreshold || abs == 0 {
+		hv.zeroCount++
+		return
+	}
+
+	b := &hv.positive
+	if value < 0 {
+		b = &hv.negative
+	}
+
+	index := mapToIndex(abs, hv.scale)
+	lo, hi := b.rangeWith(index)
+	if int(hi-lo)+1 > h.opts.MaxSize {
+		change := int32(0)
+		for int((hi>>change)-(lo>>change))+1 > h.opts.MaxSize && hv.scale-change > MinExponentialScale {
+			change++
+		}
+		hv.positive.downscale(change)
+		hv.negative.downscale(change)
+		hv.scale -= change
+		index = mapToIndex(abs, hv.scale)
+	}
+	b.increment(index)
+}
+
+// Collect returns the count, sum and a single +Inf bucket per series, which
+// is all the Prometheus text format can express for this type.
+func (h *ExponentialHistogram) Collect() []Sample {
+	var samples []Sample
+
+	for _, p := range h.CollectExponential() {
+		samples = append(samples,
+			Sample{
+				Name:      h.name + "_bucket",
+				Labels:    p.Labels.Merge(NewLabels("le", "+Inf")),
+				Value:     float64(p.Count),
+				Timestamp: p.Timestamp,
+				Created:   p.Created,
+			},
+			Sample{Name: h.name + "_sum", Labels: p.Labels, Value: p.Sum, Timestamp: p.Timestamp, Created: p.Created},
+			Sample{Name: h.name + "_count", Labels: p.Labels, Value: float64(p.Count), Timestamp: p.Timestamp, Created: p.Created},
+		)
+	}
+
+	return samples
+}
+
+// CollectExponential returns a snapshot of every series.
+func (h *ExponentialHistogram) CollectExponential() []ExponentialHistogramPoint {
+	var points []ExponentialHistogramPoint
+	now := time.Now()
+
+	h.values.Range(func(_, value any) bool {
+		hv := value.(*expHistogramValue)
+		hv.mu.Lock()
+		points = append(points, ExponentialHistogramPoint{
+			Labels:        hv.labels,
+			Scale:         hv.scale,
+			Count:         hv.count,
+			Sum:           hv.sum,
+			Min:           hv.min,
+			Max:           hv.max,
+			ZeroThreshold: h.opts.ZeroThreshold,
+			ZeroCount:     hv.zeroCount,
+			Positive:      hv.positive.snapshot(),
+			Negative:      hv.negative.snapshot(),
+			Timestamp:     now,
+			Created:       hv.created,
+		})
+		hv.mu.Unlock()
+		return true
+	})
+
+	return points
+}
+
+// Reset resets all histogram values.
+func (h *ExponentialHistogram) Reset() {
+	h.values.Range(func(key, _ any) bool {
+		h.values.Delete(key)
+		return true
+	})
+	h.series.Store(0)
+}
+
+func (h *ExponentialHistogram) makeLabels(values []string) Labels {
+	if len(h.labelNames) == 0 {
+		return Labels{}
+	}
+
+	if len(values) != len(h.labelNames) {
+		if len(values) < len(h.labelNames) {
+			padded := make([]string, len(h.labelNames))
+			copy(padded, values)
+			values = padded
+		} else {
+			values = values[:len(h.labelNames)]
+		}
+	}
+
+	pairs := make([]string, 0, len(h.labelNames)*2)
+	for i, name := range h.labelNames {
+		pairs = append(pairs, name, values[i])
+	}
+	return NewLabels(pairs...)
+}
+
+// Downscale returns the point with its buckets merged down to scale.
+// It returns p unchanged if scale is not lower than p.Scale.
+func (p ExponentialHistogramPoint) Downscale(scale int32) ExponentialHistogramPoint {
+	if scale >= p.Scale {
+		return p
+	}
+	change := p.Scale - scale
+	pos := expBuckets{offset: p.Positive.Offset, counts: append([]uint64(nil), p.Positive.Counts...)}
+	neg := expBuckets{offset: p.Negative.Offset, counts: append([]uint64(nil), p.Negative.Counts...)}
+	pos.downscale(change)
+	neg.downscale(change)
+	p.Scale = scale
+	p.Positive = pos.snapshot()
+	p.Negative = neg.snapshot()
+	return p
+}
+
+// LowerBound returns the exclusive lower bound of the bucket at index for
+// the given scale. The bucket's inclusive upper bound is
+// LowerBound(index+1, scale).
+func LowerBound(index, scale int32) float64 {
+	if scale <= 0 {
+		return math.Ldexp(1, int(index)<<-scale)
+	}
+	return math.Exp(float64(index) * math.Ln2 / float64(int64(1)<<scale))
+}
+
+// mapToIndex returns the index of the bucket holding v (> 0) at scale,
+// where bucket i covers (base^i, base^(i+1)].
+func mapToIndex(v float64, scale int32) int32 {
+	frac, exp := math.Frexp(v)
+	// v = frac * 2^exp with frac in [0.5, 1); exact powers of two sit on a
+	// bucket's upper bound and belong to the lower bucket.
+	exact := frac == 0.5
+	if scale <= 0 {
+		e := exp - 1
+		if exact {
+			e--
+		}
+		return int32(e >> -scale)
+	}
+	if exact {
+		return int32((exp-1)<<scale) - 1
+	}
+	index := int32(math.Ceil(math.Log(v)*math.Ldexp(math.Log2E, int(scale)))) - 1
+	// Guard against rounding at bucket boundaries.
+	if v <= LowerBound(index, scale) {
+		index--
+	} else if v > LowerBound(index+1, scale) {
+		index++
+	}
+	return index
+}
+
+type expBuckets struct {
+	offset int32
+	counts []uint64
+}
+
+func (b *expBuckets) rangeWith(index int32) (lo, hi int32) {
+	if len(b.counts) == 0 {
+		return index, index
+	}
+	lo, hi = b.offset, b.offset+int32(len(b.counts))-1
+	return min(lo, index), max(hi, index)
+}
+
+func (b *expBuckets) increment(index int32) {
+	switch {
+	case len(b.counts) == 0:
+		b.offset = index
+		b.counts = append(b.counts, 0)
+	case index < b.offset:
+		grown := make([]uint64, int(b.offset-index)+len(b.counts))
+		copy(grown[b.offset-index:], b.counts)
+		b.counts = grown
+		b.offset = index
+	case int(index-b.offset) >= len(b.counts):
+		b.counts = append(b.counts, make([]uint64, int(index-b.offset)-len(b.counts)+1)...)
+	}
+	b.counts[index-b.offset]++
+}
+
+func (b *expBuckets) downscale(change int32) {
+	if change <= 0 || len(b.counts) == 0 {
+		return
+	}
+	newOffset := b.offset >> change
+	last := (b.offset + int32(len(b.counts)) - 1) >> change
+	merged := make([]uint64, last-newOffset+1)
+	for i, c := range b.counts {
+		merged[((b.offset+int32(i))>>change)-newOffset] += c
+	}
+	b.offset = newOffset
+	b.counts = merged
+}
+
+func (b *expBuckets) snapshot() ExponentialBucketCounts {
+	return ExponentialBucketCounts{
+		Offset: b.offset,
+		Counts: append([]uint64(nil), b.counts...),
+	}
+}
@@ -0,0 +1,97 @@
+package metrics_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestExponentialHistogramBucketBounds(t *testing.T) {
+	values := []float64{1, 2, 4, 0.5, 3, 1.5, 1e-9, 123456.789, math.Nextafter(2, 3), math.Nextafter(2, 1)}
+
+	for _, scale := range []int32{-4, -2, -1, 0, 1, 3, 8, 20} {
+		for _, v := range values {
+			h := NewExponentialHistogram("h", "", &ExponentialHistogramOptions{MaxScale: &scale})
+			h.Observe(v)
+			p := h.CollectExponential()[0]
+			if p.Scale != scale {
+				t.Fatalf("scale %d: single observation changed scale to %d", scale, p.Scale)
+			}
+			index := p.Positive.Offset
+			lo, hi := LowerBound(index, p.Scale), LowerBound(index+1, p.Scale)
+			if !(v > lo && v <= hi) {
+				t.Errorf("scale %d: %g placed in bucket %d = (%g, %g]", scale, v, index, lo, hi)
+			}
+		}
+	}
+}
+
+func TestExponentialHistogramRescales(t *testing.T) {
+	h := NewExponentialHistogram("latency_seconds", "", &ExponentialHistogramOptions{MaxSize: 20})
+	for v := 1e-6; v < 1e6; v *= 1.1 {
+		h.Observe(v)
+		h.Observe(-v)
+	}
+	h.Observe(0)
+
+	p := h.CollectExponential()[0]
+	if len(p.Positive.Counts) > 20 || len(p.Negative.Counts) > 20 {
+		t.Fatalf("bucket count exceeds MaxSize: %d/%d", len(p.Positive.Counts), len(p.Negative.Counts))
+	}
+	if p.Scale >= MaxExponentialScale {
+		t.Fatalf("expected scale to drop, got %d", p.Scale)
+	}
+
+	total := p.ZeroCount
+	for _, c := range p.Positive.Counts {
+		total += c
+	}
+	for _, c := range p.Negative.Counts {
+		total += c
+	}
+	if total != p.Count {
+		t.Errorf("bucket counts sum to %d, want %d", total, p.Count)
+	}
+	if p.ZeroCount != 1 {
+		t.Errorf("expected 1 zero observation, got %d", p.ZeroCount)
+	}
+	if p.Min >= 0 || p.Max <= 0 {
+		t.Errorf("unexpected min/max %g/%g", p.Min, p.Max)
+	}
+
+	down := p.Downscale(p.Scale - 2)
+	var before, after uint64
+	for _, c := range p.Positive.Counts {
+		before += c
+	}
+	for _, c := range down.Positive.Counts {
+		after += c
+	}
+	if before != after {
+		t.Errorf("downscale lost observations: %d != %d", after, before)
+	}
+}
+
+func TestExponentialHistogramExposition(t *testing.T) {
+	r := NewRegistry(nil)
+	h := r.ExponentialHistogram("rpc_seconds", "RPC latency", nil, "method")
+	h.Observe(0.25, "get")
+
+	var text bytes.Buffer
+	WriteFamilies(&text, r.Gather())
+	for _, want := range []string{`rpc_seconds_count{method="get"} 1`, `rpc_seconds_bucket{le="+Inf",method="get"} 1`} {
+		if !bytes.Contains(text.Bytes(), []byte(want)) {
+			t.Errorf("expected %q in:\n%s", want, text.String())
+		}
+	}
+
+	var proto bytes.Buffer
+	if err := WriteProtobuf(&proto, r.Gather()); err != nil {
+		t.Fatal(err)
+	}
+	if proto.Len() == 0 || !bytes.Contains(proto.Bytes(), []byte("rpc_seconds")) {
+		t.Errorf("unexpected protobuf output: %x", proto.Bytes())
+	}
+}
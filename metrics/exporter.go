@@ -1,7 +1,10 @@
 package metrics
 
 import (
+	"context"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // Exporter exports metrics.
@@ -14,13 +17,112 @@ type NopExporter struct{}
 
 func (NopExporter) Export([]Sample) {}
 
+// PushExporter sends samples to a remote system and reports failures, so
+// the registry's push loop can retry them.
+type PushExporter interface {
+	Export(ctx context.Context, samples []Sample) error
+}
+
+// FamilyExporter is implemented by push exporters that need whole metric
+// families, with their help text and type, rather than bare samples. The
+// registry's push loop calls ExportFamilies instead of Export for them.
+// Stale markers are not sent to family exporters; see
+// Options.StaleMarkers.
+type FamilyExporter interface {
+	ExportFamilies(ctx context.Context, families []MetricFamily) error
+}
+
+// AdaptExporter wraps an Exporter, which cannot fail, as a PushExporter.
+func AdaptExporter(e Exporter) PushExporter {
+	return exporterAdapter{e}
+}
+
+type exporterAdapter struct {
+	e Exporter
+}
+
+func (a exporterAdapter) Export(_ context.Context, samples []Sample) error {
+	a.e.Export(samples)
+	return nil
+}
+
+// Content types served by HTTPHandler.
+const (
+	ContentTypePrometheus  = "text/plain; version=0.0.4; charset=utf-8"
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	ContentTypeProtobuf    = "application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited"
+)
+
+type exposition int
+
+const (
+	expositionPrometheus exposition = iota
+	expositionOpenMetrics
+	expositionProtobuf
+)
+
 // HTTPHandler returns an http.Handler for the Prometheus endpoint.
+// The format follows the scraper's Accept header: OpenMetrics (with
+// exemplars), the Prometheus protobuf format (with native histograms), or
+// the Prometheus text format by default. Responses are gzip-compressed
+// when the scraper accepts it. Use NewHandler for authentication and
+// scrape limits.
 func HTTPHandler(registry *Registry) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		samples := registry.Collect()
-		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
-		WritePrometheus(w, samples)
-	})
+	return NewHandler(registry, nil)
+}
+
+func writeExposition(w http.ResponseWriter, r *http.Request, registry *Registry, families []MetricFamily) {
+	timestamps := registry.opts.ExposeTimestamps
+	switch negotiate(r.Header.Get("Accept")) {
+	case expositionOpenMetrics:
+		w.Header().Set("Content-Type", ContentTypeOpenMetrics)
+		WriteOpenMetrics(w, families, &OpenMetricsOptions{Timestamps: timestamps})
+	case expositionProtobuf:
+		w.Header().Set("Content-Type", ContentTypeProtobuf)
+		WriteProtobuf(w, families)
+	default:
+		w.Header().Set("Content-Type", ContentTypePrometheus)
+		WriteText(w, families, &TextOptions{Timestamps: timestamps})
+	}
+}
+
+// negotiate picks the supported format with the highest q-value in an
+// Accept header, preferring earlier entries on ties.
+func negotiate(accept string) exposition {
+	best, bestQ := expositionPrometheus, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.TrimSpace(params[0])
+
+		q, proto := 1.0, ""
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			switch k {
+			case "q":
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			case "proto":
+				proto = v
+			}
+		}
+
+		var format exposition
+		switch {
+		case mediaType == "application/openmetrics-text":
+			format = expositionOpenMetrics
+		case mediaType == "application/vnd.google.protobuf" && proto == "io.prometheus.client.MetricFamily":
+			format = expositionProtobuf
+		case mediaType == "text/plain", mediaType == "*/*":
+			format = expositionPrometheus
+		default:
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best
 }
 
 // DefaultHTTPHandler returns an http.Handler using the default registry.
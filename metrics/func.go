@@ -0,0 +1,69 @@
+package metrics
+
+import "time"
+
+// GaugeFunc is a gauge whose value is computed by a callback at collection
+// time, for values such as queue depth or cache size that are cheaper to
+// read on demand than to keep updated.
+type GaugeFunc struct {
+	name   string
+	help   string
+	labels Labels
+	fn     func() float64
+	unitMeta
+}
+
+// NewGaugeFunc creates a gauge that reports fn's result. labels are
+// constant key-value pairs attached to the sample.
+func NewGaugeFunc(name, help string, fn func() float64, labels ...string) *GaugeFunc {
+	return &GaugeFunc{name: name, help: help, labels: NewLabels(labels...), fn: fn}
+}
+
+func (g *GaugeFunc) Name() string         { return g.name }
+func (g *GaugeFunc) Help() string         { return g.help }
+func (g *GaugeFunc) Type() MetricType     { return MetricTypeGauge }
+func (g *GaugeFunc) LabelNames() []string { return g.labels.Keys() }
+
+// Collect calls the callback and returns its value as a single sample.
+func (g *GaugeFunc) Collect() []Sample {
+	return []Sample{{Name: g.name, Labels: g.labels, Value: g.fn(), Timestamp: time.Now()}}
+}
+
+// CounterFunc is a counter whose value is computed by a callback at
+// collection time. The callback must return a monotonically increasing
+// value, such as a total maintained by another library.
+type CounterFunc struct {
+	name   string
+	help   string
+	labels Labels
+	fn     func() float64
+	unitMeta
+}
+
+// NewCounterFunc creates a counter that reports fn's result. labels are
+// constant key-value pairs attached to the sample.
+func NewCounterFunc(name, help string, fn func() float64, labels ...string) *CounterFunc {
+	return &CounterFunc{name: name, help: help, labels: NewLabels(labels...), fn: fn}
+}
+
+func (c *CounterFunc) Name() string         { return c.name }
+func (c *CounterFunc) Help() string         { return c.help }
+func (c *CounterFunc) Type() MetricType     { return MetricTypeCounter }
+func (c *CounterFunc) LabelNames() []string { return c.labels.Keys() }
+
+// Collect calls the callback and returns its value as a single sample.
+func (c *CounterFunc) Collect() []Sample {
+	return []Sample{{Name: c.name, Labels: c.labels, Value: c.fn(), Timestamp: time.Now()}}
+}
+
+// RegisterGaugeFunc registers a gauge evaluated by fn at collection time.
+// labels are constant key-value pairs.
+func (r *Registry) RegisterGaugeFunc(name, help string, fn func() float64, labels ...string) error {
+	return r.Register(NewGaugeFunc(name, help, fn, labels...))
+}
+
+// RegisterCounterFunc registers a counter evaluated by fn at collection
+// time. labels are constant key-value pairs.
+func (r *Registry) RegisterCounterFunc(name, help string, fn func() float64, labels ...string) error {
+	return r.Register(NewCounterFunc(name, help, fn, labels...))
+}
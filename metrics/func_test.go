@@ -0,0 +1,50 @@
+package metrics_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestFuncMetrics(t *testing.T) {
+	r := NewRegistry(nil)
+	depth, calls := 3.0, 0
+	if err := r.RegisterGaugeFunc("queue_depth", "Queued jobs.", func() float64 { calls++; return depth }, "queue", "email"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RegisterCounterFunc("cache_hits_total", "Cache hits.", func() float64 { return 42 }); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Errorf("callback ran %d times before collection", calls)
+	}
+
+	var buf strings.Builder
+	if err := WriteFamilies(&buf, r.Gather()); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP cache_hits_total Cache hits.
+# TYPE cache_hits_total counter
+cache_hits_total 42
+# HELP queue_depth Queued jobs.
+# TYPE queue_depth gauge
+queue_depth{queue="email"} 3
+`
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	depth = 5
+	if got := seriesOf(r, "queue_depth")["[email]"]; got != 5 {
+		t.Errorf("expected the callback to be evaluated on each collection, got %g", got)
+	}
+	if calls != 2 {
+		t.Errorf("callback ran %d times, want 2", calls)
+	}
+
+	if err := r.RegisterGaugeFunc("queue_depth", "", func() float64 { return 0 }); !errors.Is(err, ErrMetricExists) {
+		t.Errorf("expected ErrMetricExists, got %v", err)
+	}
+}
@@ -13,6 +13,8 @@ type Gauge struct {
 	help       string
 	labelNames []string
 	values     sync.Map
+	cardinality
+	unitMeta
 }
 
 type gaugeValue struct {
@@ -36,14 +38,18 @@ func (g *Gauge) LabelNames() []string { return g.labelNames }
 
 // Set sets the gauge to a value.
 func (g *Gauge) Set(value float64, labelValues ...string) {
-	labels := g.makeLabels(labelValues)
-	hash := labels.Hash()
-
-	val, _ := g.values.LoadOrStore(hash, &gaugeValue{labels: labels})
-	gv := val.(*gaugeValue)
+	gv := g.lookup(labelValues)
+	if gv == nil {
+		return
+	}
 	gv.bits.Store(math.Float64bits(value))
 }
 
+// SetToCurrentTime sets the gauge to the current Unix time in seconds.
+func (g *Gauge) SetToCurrentTime(labelValues ...string) {
+	g.Set(unixSeconds(time.Now()), labelValues...)
+}
+
 // Inc increments by 1.
 func (g *Gauge) Inc(labelValues ...string) {
 	g.Add(1, labelValues...)
@@ -56,11 +62,10 @@ func (g *Gauge) Dec(labelValues ...string) {
 
 // Add adds a delta.
 func (g *Gauge) Add(delta float64, labelValues ...string) {
-	labels := g.makeLabels(labelValues)
-	hash := labels.Hash()
-
-	val, _ := g.values.LoadOrStore(hash, &gaugeValue{labels: labels})
-	gv := val.(*gaugeValue)
+	gv := g.lookup(labelValues)
+	if gv == nil {
+		return
+	}
 
 	for {
 		oldBits := gv.bits.Load()
@@ -107,6 +112,13 @@ func (g *Gauge) Reset() {
 		g.values.Delete(key)
 		return true
 	})
+	g.series.Store(0)
+}
+
+func (g *Gauge) lookup(labelValues []string) *gaugeValue {
+	return loadSeries(&g.values, &g.cardinality, g.name, g.makeLabels(labelValues), func(labels Labels) *gaugeValue {
+		return &gaugeValue{labels: labels}
+	})
 }
 
 func (g *Gauge) makeLabels(values []string) Labels {
@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HandlerOptions configures NewHandler.
+type HandlerOptions struct {
+	// DisableCompression turns off gzip responses.
+	DisableCompression bool
+
+	// Username and Password, when Username is set, require HTTP basic
+	// authentication.
+	Username string
+	Password string
+
+	// BearerToken, when set, requires an "Authorization: Bearer" header
+	// with this token. Either credential is accepted if both are set.
+	BearerToken string
+
+	// MaxConcurrent limits scrapes gathering at the same time
+	// (0 = unlimited). Further scrapes wait for a slot until Timeout.
+	MaxConcurrent int
+
+	// Timeout bounds how long a scrape waits for a slot and for gathering
+	// to finish before failing with 503 (0 = no limit). A gather that
+	// times out keeps its slot until it completes, so slow collections
+	// cannot pile up.
+	Timeout time.Duration
+}
+
+type handler struct {
+	registry *Registry
+	opts     HandlerOptions
+	slots    chan struct{}
+}
+
+// NewHandler returns an http.Handler for the Prometheus endpoint with
+// compression, authentication and scrape limits. See HTTPHandler for the
+// formats served.
+func NewHandler(registry *Registry, opts *HandlerOptions) http.Handler {
+	if opts == nil {
+		opts = &HandlerOptions{}
+	}
+	h := &handler{registry: registry, opts: *opts}
+	if opts.MaxConcurrent > 0 {
+		h.slots = make(chan struct{}, opts.MaxConcurrent)
+	}
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		if h.opts.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	if h.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.opts.Timeout)
+		defer cancel()
+	}
+
+	families, ok := h.gather(ctx)
+	if !ok {
+		http.Error(w, "metrics collection timed out", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+	if !h.opts.DisableCompression && acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		writeExposition(&gzipResponseWriter{ResponseWriter: w, w: gz}, r, h.registry, families)
+		return
+	}
+	writeExposition(w, r, h.registry, families)
+}
+
+// gather collects the registry within the handler's concurrency and time
+// limits. The gather runs to completion even if ctx ends first.
+func (h *handler) gather(ctx context.Context) ([]MetricFamily, bool) {
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+
+	if h.opts.Timeout <= 0 {
+		defer h.release()
+		return h.registry.Gather(), true
+	}
+
+	done := make(chan []MetricFamily, 1)
+	go func() {
+		defer h.release()
+		done <- h.registry.Gather()
+	}()
+	select {
+	case families := <-done:
+		return families, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+func (h *handler) release() {
+	if h.slots != nil {
+		<-h.slots
+	}
+}
+
+func (h *handler) authorized(r *http.Request) bool {
+	if h.opts.Username == "" && h.opts.BearerToken == "" {
+		return true
+	}
+	if h.opts.Username != "" {
+		if user, pass, ok := r.BasicAuth(); ok &&
+			subtle.ConstantTimeCompare([]byte(user), []byte(h.opts.Username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(pass), []byte(h.opts.Password)) == 1 {
+			return true
+		}
+	}
+	if h.opts.BearerToken != "" {
+		auth := r.Header.Get("Authorization")
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(h.opts.BearerToken)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if k, v, _ := strings.Cut(strings.TrimSpace(p), "="); k == "q" {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		return q > 0
+	}
+	return false
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	w *gzip.Writer
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	return g.w.Write(p)
+}
@@ -0,0 +1,163 @@
+package metrics_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func newHandlerRegistry() *Registry {
+	r := NewRegistry(nil)
+	r.Counter("scrapes_total", "Scrapes.").Inc()
+	return r
+}
+
+func scrape(t *testing.T, h http.Handler, setup func(*http.Request)) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if setup != nil {
+		setup(req)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerAuth(t *testing.T) {
+	h := NewHandler(newHandlerRegistry(), &HandlerOptions{
+		Username:    "prom",
+		Password:    "secret",
+		BearerToken: "tok",
+	})
+
+	tests := []struct {
+		name  string
+		setup func(*http.Request)
+		want  int
+	}{
+		{"no credentials", nil, http.StatusUnauthorized},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("prom", "wrong") }, http.StatusUnauthorized},
+		{"wrong user", func(r *http.Request) { r.SetBasicAuth("other", "secret") }, http.StatusUnauthorized},
+		{"wrong token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"token without scheme", func(r *http.Request) { r.Header.Set("Authorization", "tok") }, http.StatusUnauthorized},
+		{"basic auth", func(r *http.Request) { r.SetBasicAuth("prom", "secret") }, http.StatusOK},
+		{"bearer token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer tok") }, http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := scrape(t, h, tc.setup)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.want)
+			}
+			if tc.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != `Basic realm="metrics"` {
+				t.Errorf("WWW-Authenticate = %q", rec.Header().Get("WWW-Authenticate"))
+			}
+			if tc.want == http.StatusOK && !strings.Contains(rec.Body.String(), "scrapes_total 1") {
+				t.Errorf("unexpected body:\n%s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandlerCompression(t *testing.T) {
+	r := newHandlerRegistry()
+	gzipped := func(req *http.Request) { req.Header.Set("Accept-Encoding", "gzip, deflate") }
+
+	rec := scrape(t, NewHandler(r, nil), gzipped)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "scrapes_total 1") {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+
+	for name, setup := range map[string]func(*http.Request){
+		"no Accept-Encoding": nil,
+		"gzip refused":       func(req *http.Request) { req.Header.Set("Accept-Encoding", "gzip;q=0, identity") },
+	} {
+		rec := scrape(t, NewHandler(r, nil), setup)
+		if rec.Header().Get("Content-Encoding") != "" || !strings.Contains(rec.Body.String(), "scrapes_total 1") {
+			t.Errorf("%s: Content-Encoding %q, body:\n%s", name, rec.Header().Get("Content-Encoding"), rec.Body)
+		}
+	}
+
+	rec = scrape(t, NewHandler(r, &HandlerOptions{DisableCompression: true}), gzipped)
+	if rec.Header().Get("Content-Encoding") != "" || !strings.Contains(rec.Body.String(), "scrapes_total 1") {
+		t.Errorf("DisableCompression: Content-Encoding %q, body:\n%s", rec.Header().Get("Content-Encoding"), rec.Body)
+	}
+}
+
+// blockingRegistry returns a registry whose gathering blocks until
+// release is closed, signalling entered each time a gather starts.
+func blockingRegistry(t *testing.T) (r *Registry, entered chan struct{}, release chan struct{}) {
+	r = NewRegistry(nil)
+	entered, release = make(chan struct{}, 4), make(chan struct{})
+	if err := r.RegisterGaugeFunc("slow", "Blocks gathering.", func() float64 {
+		entered <- struct{}{}
+		<-release
+		return 1
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return r, entered, release
+}
+
+func TestHandlerMaxConcurrent(t *testing.T) {
+	r, entered, release := blockingRegistry(t)
+	h := NewHandler(r, &HandlerOptions{MaxConcurrent: 1})
+
+	first := make(chan int)
+	go func() { first <- scrape(t, h, nil).Code }()
+	<-entered
+
+	// A scrape past the limit waits for a slot until its context ends.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	rec := scrape(t, h, func(req *http.Request) { *req = *req.WithContext(ctx) })
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("scrape past the limit: status = %d, want 503", rec.Code)
+	}
+
+	// One without a deadline is queued and served once the slot frees.
+	queued := make(chan int)
+	go func() { queued <- scrape(t, h, nil).Code }()
+	select {
+	case <-entered:
+		t.Fatal("queued scrape gathered while the slot was taken")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("first scrape: status = %d", code)
+	}
+	if code := <-queued; code != http.StatusOK {
+		t.Errorf("queued scrape: status = %d", code)
+	}
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	r, entered, release := blockingRegistry(t)
+	defer close(release)
+	h := NewHandler(r, &HandlerOptions{Timeout: 20 * time.Millisecond})
+
+	rec := scrape(t, h, nil)
+	<-entered
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
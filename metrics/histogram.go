@@ -1,7 +1,9 @@
 package metrics
 
 import (
+	"context"
 	"math"
+	"runtime"
 	"sort"
 	"strconv"
 	"sync"
@@ -15,15 +17,22 @@ type Histogram struct {
 	help       string
 	labelNames []string
 	buckets    []float64
+	consistent atomic.Bool
 	values     sync.Map
+	cardinality
+	unitMeta
+	contextExemplars
 }
 
 type histogramValue struct {
 	labels     Labels
+	created    time.Time
 	buckets    []float64
 	counts     []atomic.Uint64
+	started    atomic.Uint64 // observations begun; countTotal counts those finished
 	countTotal atomic.Uint64
 	sumBits    atomic.Uint64
+	exemplars  []atomic.Pointer[Exemplar] // one per bucket plus +Inf
 }
 
 // NewHistogram creates a new histogram.
@@ -55,20 +64,47 @@ func (h *Histogram) LabelNames() []string { return h.labelNames }
 
 // Observe adds an observation.
 func (h *Histogram) Observe(value float64, labelValues ...string) {
-	labels := h.makeLabels(labelValues)
-	hash := labels.Hash()
+	h.observe(value, labelValues)
+}
+
+// ObserveWithExemplar adds an observation and records it as the exemplar
+// of the bucket it falls into.
+func (h *Histogram) ObserveWithExemplar(value float64, exemplar Labels, labelValues ...string) {
+	hv := h.observe(value, labelValues)
+	if hv == nil {
+		return
+	}
+	ex := newExemplar(exemplar, value)
+	if ex == nil {
+		return
+	}
+	i := sort.SearchFloat64s(h.buckets, value)
+	hv.exemplars[i].Store(ex)
+}
+
+// ObserveContext adds an observation with the exemplar the registry's
+// Options.ExemplarFromContext derives from ctx, if any.
+func (h *Histogram) ObserveContext(ctx context.Context, value float64, labelValues ...string) {
+	if ex := h.contextExemplar(ctx); ex.Len() > 0 {
+		h.ObserveWithExemplar(value, ex, labelValues...)
+		return
+	}
+	h.observe(value, labelValues)
+}
 
-	val, _ := h.values.LoadOrStore(hash, h.newHistogramValue(labels))
-	hv := val.(*histogramValue)
+func (h *Histogram) observe(value float64, labelValues []string) *histogramValue {
+	hv := loadSeries(&h.values, &h.cardinality, h.name, h.makeLabels(labelValues), h.newHistogramValue)
+	if hv == nil {
+		return nil
+	}
 
+	hv.started.Add(1)
 	for i, bucket := range h.buckets {
 		if value <= bucket {
 			hv.counts[i].Add(1)
 		}
 	}
 
-	hv.countTotal.Add(1)
-
 	for {
 		oldBits := hv.sumBits.Load()
 		newSum := math.Float64frombits(oldBits) + value
@@ -76,13 +112,73 @@ func (h *Histogram) Observe(value float64, labelValues ...string) {
 			break
 		}
 	}
+
+	hv.countTotal.Add(1)
+	return hv
+}
+
+// maxSnapshotAttempts bounds the retries of a consistent snapshot, so
+// collection cannot be starved by a constant stream of observations.
+const maxSnapshotAttempts = 100
+
+type histogramSnapshot struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// snapshot reads a series. An observation updates the buckets, sum and
+// count one after another, so a read racing it can see the buckets ahead
+// of the count. When started, read after the values, equals the count read
+// before them, no observation was in flight and the read is exact; in
+// consistent mode the read is retried until then. Either way the buckets
+// are clamped to be cumulative and no larger than the count.
+func (h *Histogram) snapshot(hv *histogramValue) histogramSnapshot {
+	s := histogramSnapshot{counts: make([]uint64, len(hv.counts))}
+	attempts := 1
+	if h.consistent.Load() {
+		attempts = maxSnapshotAttempts
+	}
+
+	for i := range attempts {
+		if i > 0 {
+			runtime.Gosched()
+		}
+		s.count = hv.countTotal.Load()
+		for j := range hv.counts {
+			s.counts[j] = hv.counts[j].Load()
+		}
+		s.sum = math.Float64frombits(hv.sumBits.Load())
+		if hv.started.Load() == s.count {
+			break
+		}
+	}
+
+	var prev uint64
+	for j := range s.counts {
+		s.counts[j] = min(max(s.counts[j], prev), s.count)
+		prev = s.counts[j]
+	}
+	return s
+}
+
+// consistentSnapshotter is implemented by metrics that support
+// Options.ConsistentSnapshots.
+type consistentSnapshotter interface {
+	setConsistentSnapshots(bool)
+}
+
+func (h *Histogram) setConsistentSnapshots(on bool) {
+	h.consistent.Store(on)
 }
 
 func (h *Histogram) newHistogramValue(labels Labels) *histogramValue {
 	return &histogramValue{
-		labels:  labels,
-		buckets: h.buckets,
-		counts:  make([]atomic.Uint64, len(h.buckets)),
+		labels:    labels,
+		created:   time.Now(),
+		buckets:   h.buckets,
+		counts:    make([]atomic.Uint64, len(h.buckets)),
+		exemplars: make([]atomic.Pointer[Exemplar], len(h.buckets)+1),
 	}
 }
 
@@ -93,9 +189,10 @@ func (h *Histogram) Collect() []Sample {
 
 	h.values.Range(func(_, value any) bool {
 		hv := value.(*histogramValue)
+		snap := h.snapshot(hv)
 
 		for i, bucket := range h.buckets {
-			count := hv.counts[i].Load()
+			count := snap.counts[i]
 
 			bucketLabels := hv.labels.Merge(NewLabels("le", formatFloat(bucket)))
 			samples = append(samples, Sample{
@@ -103,6 +200,8 @@ func (h *Histogram) Collect() []Sample {
 				Labels:    bucketLabels,
 				Value:     float64(count),
 				Timestamp: now,
+				Exemplar:  hv.exemplars[i].Load(),
+				Created:   hv.created,
 			})
 		}
 
@@ -110,22 +209,26 @@ func (h *Histogram) Collect() []Sample {
 		samples = append(samples, Sample{
 			Name:      h.name + "_bucket",
 			Labels:    infLabels,
-			Value:     float64(hv.countTotal.Load()),
+			Value:     float64(snap.count),
 			Timestamp: now,
+			Exemplar:  hv.exemplars[len(h.buckets)].Load(),
+			Created:   hv.created,
 		})
 
 		samples = append(samples, Sample{
 			Name:      h.name + "_sum",
 			Labels:    hv.labels,
-			Value:     math.Float64frombits(hv.sumBits.Load()),
+			Value:     snap.sum,
 			Timestamp: now,
+			Created:   hv.created,
 		})
 
 		samples = append(samples, Sample{
 			Name:      h.name + "_count",
 			Labels:    hv.labels,
-			Value:     float64(hv.countTotal.Load()),
+			Value:     float64(snap.count),
 			Timestamp: now,
+			Created:   hv.created,
 		})
 
 		return true
@@ -140,6 +243,7 @@ func (h *Histogram) Reset() {
 		h.values.Delete(key)
 		return true
 	})
+	h.series.Store(0)
 }
 
 func (h *Histogram) makeLabels(values []string) Labels {
@@ -0,0 +1,99 @@
+package metrics_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestHistogramSnapshotUnderLoad(t *testing.T) {
+	for _, consistent := range []bool{false, true} {
+		r := NewRegistry(&Options{ConsistentSnapshots: consistent})
+		h := r.Histogram("latency", "", []float64{1, 2, 4})
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+						h.Observe(float64(i % 5))
+					}
+				}
+			}()
+		}
+
+		for range 200 {
+			var prev, count, inf float64
+			for _, s := range h.Collect() {
+				switch {
+				case s.Name == "latency_bucket" && s.Labels.Get("le") == "+Inf":
+					inf = s.Value
+				case s.Name == "latency_bucket":
+					if s.Value < prev {
+						t.Fatalf("buckets not cumulative: %g after %g", s.Value, prev)
+					}
+					prev = s.Value
+				case s.Name == "latency_count":
+					count = s.Value
+				}
+			}
+			if prev > count || inf != count {
+				t.Fatalf("consistent=%v: buckets %g, +Inf %g, count %g", consistent, prev, inf, count)
+			}
+		}
+		close(stop)
+		wg.Wait()
+	}
+}
+
+// histogramTotals returns the _count and _sum of the series with the given
+// label value for "op".
+func histogramTotals(h *Histogram, op string) (count, sum float64) {
+	for _, s := range h.Collect() {
+		if s.Labels.Get("op") != op {
+			continue
+		}
+		switch s.Name {
+		case h.Name() + "_count":
+			count = s.Value
+		case h.Name() + "_sum":
+			sum = s.Value
+		}
+	}
+	return count, sum
+}
+
+func TestHistogramTimers(t *testing.T) {
+	h := NewHistogram("op_seconds", "", []float64{0.01, 1}, "op")
+
+	timer := h.StartTimer("timer")
+	time.Sleep(2 * time.Millisecond)
+	if d := timer.ObserveDuration(); d < 2*time.Millisecond {
+		t.Errorf("ObserveDuration returned %v", d)
+	}
+
+	func() {
+		defer h.Time("deferred")()
+		time.Sleep(2 * time.Millisecond)
+	}()
+
+	h.Since(time.Now().Add(-time.Second), "since")
+	h.ObserveDuration(250*time.Millisecond, "duration")
+
+	for op, want := range map[string]float64{"timer": 0.002, "deferred": 0.002, "since": 1} {
+		if count, sum := histogramTotals(h, op); count != 1 || sum < want || sum > want+0.5 {
+			t.Errorf("%s: count %g, sum %gs, want one observation of about %gs", op, count, sum, want)
+		}
+	}
+	if count, sum := histogramTotals(h, "duration"); count != 1 || sum != 0.25 {
+		t.Errorf("duration: count %g, sum %g, want 1 and 0.25", count, sum)
+	}
+}
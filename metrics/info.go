@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Info is a metric with the constant value 1 whose labels carry
+// information about the target, such as its version:
+//
+//	metrics.NewInfo("build_info", "Build information.", "version", "1.4.2")
+//
+// Names should end in _info; OpenMetrics output relies on the suffix.
+type Info struct {
+	name   string
+	help   string
+	labels atomic.Pointer[Labels]
+}
+
+// NewInfo creates an info metric with the given key-value label pairs.
+func NewInfo(name, help string, labels ...string) *Info {
+	i := &Info{name: name, help: help}
+	i.Set(labels...)
+	return i
+}
+
+// NewBuildInfo creates a build_info metric labeled with the main module's
+// version, the VCS revision it was built from and the Go version.
+func NewBuildInfo(help string) *Info {
+	version, revision := "unknown", "unknown"
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if bi.Main.Version != "" {
+			version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" {
+				revision = s.Value
+			}
+		}
+	}
+	return NewInfo("build_info", help,
+		"version", version,
+		"revision", revision,
+		"go_version", runtime.Version(),
+	)
+}
+
+func (i *Info) Name() string         { return i.name }
+func (i *Info) Help() string         { return i.help }
+func (i *Info) Type() MetricType     { return MetricTypeInfo }
+func (i *Info) LabelNames() []string { return i.labels.Load().Keys() }
+
+// Set replaces the info labels.
+func (i *Info) Set(labels ...string) {
+	l := NewLabels(labels...)
+	i.labels.Store(&l)
+}
+
+// Labels returns the current info labels.
+func (i *Info) Labels() Labels {
+	return *i.labels.Load()
+}
+
+// Collect returns the info sample.
+func (i *Info) Collect() []Sample {
+	return []Sample{{Name: i.name, Labels: *i.labels.Load(), Value: 1, Timestamp: time.Now()}}
+}
+
+// Info creates and registers an info metric, or returns the info metric
+// already registered under name if it has the same label names. Use Set to
+// change the labels of an existing one.
+func (r *Registry) Info(name, help string, labels ...string) *Info {
+	i := NewInfo(name, help, labels...)
+	existing, err := getOrRegister(r, i, func(existing *Info) bool {
+		return slices.Equal(existing.LabelNames(), i.LabelNames())
+	})
+	if err != nil {
+		return i
+	}
+	return existing
+}
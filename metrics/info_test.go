@@ -0,0 +1,78 @@
+package metrics_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestInfoSample(t *testing.T) {
+	i := NewInfo("build_info", "Build information.", "version", "1.4.2", "revision", "abc123")
+	samples := i.Collect()
+	if len(samples) != 1 {
+		t.Fatalf("expected 1 sample, got %d", len(samples))
+	}
+	s := samples[0]
+	if s.Name != "build_info" || s.Value != 1 {
+		t.Errorf("unexpected sample %s %g", s.Name, s.Value)
+	}
+	if s.Labels.Len() != 2 || s.Labels.Get("version") != "1.4.2" || s.Labels.Get("revision") != "abc123" {
+		t.Errorf("unexpected labels %v=%v", s.Labels.Keys(), s.Labels.Values())
+	}
+
+	i.Set("version", "1.5.0", "revision", "def456")
+	if got := i.Collect()[0]; got.Value != 1 || got.Labels.Get("version") != "1.5.0" {
+		t.Errorf("unexpected sample after Set: %+v", got)
+	}
+}
+
+func TestRegistryInfoReturnsExisting(t *testing.T) {
+	r := NewRegistry(nil)
+	a := r.Info("build_info", "Build information.", "version", "1.4.2")
+	b := r.Info("build_info", "Build information.", "version", "1.5.0")
+	if a != b {
+		t.Fatal("expected the second call to return the registered info metric")
+	}
+	if got := r.Collect(); len(got) != 1 || got[0].Labels.Get("version") != "1.4.2" {
+		t.Errorf("unexpected samples: %+v", got)
+	}
+
+	if err := r.Register(NewInfo("build_info", "", "commit", "x")); !errors.Is(err, ErrMetricExists) {
+		t.Errorf("expected ErrMetricExists, got %v", err)
+	}
+	if c := r.Info("build_info", "", "commit", "x"); c == a {
+		t.Error("expected info with different label names not to match")
+	}
+}
+
+func TestInfoExposition(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Info("build_info", "Build information.", "version", "1.4.2")
+
+	var text strings.Builder
+	if err := WriteText(&text, r.Gather(), nil); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP build_info Build information.
+# TYPE build_info gauge
+build_info{version="1.4.2"} 1
+`
+	if text.String() != want {
+		t.Errorf("text output:\n%s\nwant:\n%s", text.String(), want)
+	}
+
+	var om strings.Builder
+	if err := WriteOpenMetrics(&om, r.Gather(), nil); err != nil {
+		t.Fatal(err)
+	}
+	want = `# TYPE build info
+# HELP build Build information.
+build_info{version="1.4.2"} 1
+# EOF
+`
+	if om.String() != want {
+		t.Errorf("OpenMetrics output:\n%s\nwant:\n%s", om.String(), want)
+	}
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"maps"
+	"os"
+)
+
+// Environment variables consulted by DetectInstanceLabels, in order of
+// preference for each label.
+var (
+	instanceEnv = []string{"LUMEN_INSTANCE", "POD_NAME", "INSTANCE"}
+	serviceEnv  = []string{"LUMEN_SERVICE", "OTEL_SERVICE_NAME", "SERVICE_NAME"}
+	versionEnv  = []string{"LUMEN_VERSION", "SERVICE_VERSION", "VERSION"}
+)
+
+// DetectInstanceLabels returns labels identifying this process: host (from
+// os.Hostname), service_instance (from LUMEN_INSTANCE, POD_NAME or
+// INSTANCE, falling back to the host), and service and version (from
+// LUMEN_SERVICE, OTEL_SERVICE_NAME or SERVICE_NAME and LUMEN_VERSION,
+// SERVICE_VERSION or VERSION). Labels that cannot be determined are
+// omitted.
+//
+// The instance is not labeled "instance": Prometheus sets that label to
+// the scrape target and would rename ours to exported_instance.
+func DetectInstanceLabels() map[string]string {
+	labels := make(map[string]string, 4)
+	host, _ := os.Hostname()
+	if host != "" {
+		labels["host"] = host
+	}
+	if v := firstEnv(instanceEnv); v != "" {
+		labels["service_instance"] = v
+	} else if host != "" {
+		labels["service_instance"] = host
+	}
+	if v := firstEnv(serviceEnv); v != "" {
+		labels["service"] = v
+	}
+	if v := firstEnv(versionEnv); v != "" {
+		labels["version"] = v
+	}
+	return labels
+}
+
+func firstEnv(keys []string) string {
+	for _, k := range keys {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// defaultLabels combines Options.DefaultLabels with the resource identity
+// and the detected instance labels; explicit defaults win.
+func (o *Options) defaultLabels() Labels {
+	if !o.InstanceLabels && o.Resource == nil {
+		return LabelsFromMap(o.DefaultLabels)
+	}
+	labels := make(map[string]string)
+	for _, kv := range o.Resource.Identity() {
+		labels[kv.Key] = kv.Value
+	}
+	if o.InstanceLabels {
+		maps.Copy(labels, DetectInstanceLabels())
+		if o.InstanceLabelsFunc != nil {
+			o.InstanceLabelsFunc(labels)
+		}
+	}
+	maps.Copy(labels, o.DefaultLabels)
+	return LabelsFromMap(labels)
+}
@@ -0,0 +1,94 @@
+package metrics_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+// clearInstanceEnv unsets every variable DetectInstanceLabels reads.
+func clearInstanceEnv(t *testing.T) {
+	for _, k := range []string{
+		"LUMEN_INSTANCE", "POD_NAME", "INSTANCE",
+		"LUMEN_SERVICE", "OTEL_SERVICE_NAME", "SERVICE_NAME",
+		"LUMEN_VERSION", "SERVICE_VERSION", "VERSION",
+	} {
+		t.Setenv(k, "")
+	}
+}
+
+func TestDetectInstanceLabelsPrecedence(t *testing.T) {
+	host, _ := os.Hostname()
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"LUMEN_INSTANCE first", map[string]string{"LUMEN_INSTANCE": "a", "POD_NAME": "b", "INSTANCE": "c"}, "a"},
+		{"then POD_NAME", map[string]string{"POD_NAME": "b", "INSTANCE": "c"}, "b"},
+		{"then INSTANCE", map[string]string{"INSTANCE": "c"}, "c"},
+		{"then the host", nil, host},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clearInstanceEnv(t)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			labels := DetectInstanceLabels()
+			if got := labels["service_instance"]; got != tc.want {
+				t.Errorf("service_instance = %q, want %q", got, tc.want)
+			}
+			if _, ok := labels["instance"]; ok {
+				t.Error("expected no instance label, which Prometheus reserves for the target")
+			}
+		})
+	}
+}
+
+func TestDetectInstanceLabelsServiceAndVersion(t *testing.T) {
+	clearInstanceEnv(t)
+	t.Setenv("OTEL_SERVICE_NAME", "otel")
+	t.Setenv("SERVICE_NAME", "plain")
+	t.Setenv("SERVICE_VERSION", "1.2.0")
+	t.Setenv("VERSION", "0.0.1")
+
+	labels := DetectInstanceLabels()
+	if labels["service"] != "otel" || labels["version"] != "1.2.0" {
+		t.Errorf("labels = %v", labels)
+	}
+
+	t.Setenv("LUMEN_SERVICE", "lumen")
+	t.Setenv("LUMEN_VERSION", "2.0.0")
+	labels = DetectInstanceLabels()
+	if labels["service"] != "lumen" || labels["version"] != "2.0.0" {
+		t.Errorf("expected the LUMEN_ variables to win, got %v", labels)
+	}
+}
+
+func TestInstanceLabelsDefaultsOverride(t *testing.T) {
+	clearInstanceEnv(t)
+	t.Setenv("POD_NAME", "checkout-7d9f")
+	t.Setenv("SERVICE_NAME", "detected")
+
+	r := NewRegistry(&Options{
+		InstanceLabels: true,
+		InstanceLabelsFunc: func(labels map[string]string) {
+			delete(labels, "host")
+			labels["zone"] = "eu-1a"
+		},
+		DefaultLabels: map[string]string{"service": "checkout", "zone": "eu-1b"},
+	})
+	r.Gauge("up", "").Set(1)
+
+	samples := r.Collect()
+	if len(samples) != 1 {
+		t.Fatalf("samples = %v", samples)
+	}
+	l := samples[0].Labels
+	if l.Get("service_instance") != "checkout-7d9f" || l.Get("service") != "checkout" ||
+		l.Get("zone") != "eu-1b" || l.Get("host") != "" {
+		t.Errorf("expected explicit defaults to override detected and edited labels, got %v %v", l.Keys(), l.Values())
+	}
+}
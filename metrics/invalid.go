@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// invalidReporter is the per-registry handling of rejected observations,
+// installed into each registered metric that validates its input.
+type invalidReporter struct {
+	onInvalid func(metric string, err error)
+	debugf    func(format string, args ...any)
+	self      *selfMetrics
+}
+
+// invalidReporting is embedded in metrics that reject some observations,
+// such as counters given a negative delta.
+type invalidReporting struct {
+	reporter atomic.Pointer[invalidReporter]
+}
+
+func (i *invalidReporting) setInvalidReporter(r *invalidReporter) {
+	i.reporter.Store(r)
+}
+
+// invalidReported is implemented by metrics that report rejected
+// observations to Options.OnInvalidObservation and Options.Debugf.
+type invalidReported interface {
+	setInvalidReporter(*invalidReporter)
+}
+
+// checkDelta returns an error wrapping ErrInvalidDelta, and reports it, if
+// delta is negative or NaN.
+func (i *invalidReporting) checkDelta(metric string, delta float64) error {
+	if delta >= 0 {
+		return nil
+	}
+	err := fmt.Errorf("%w: %s got %g", ErrInvalidDelta, metric, delta)
+	if math.IsNaN(delta) {
+		err = fmt.Errorf("%w: %s got NaN", ErrInvalidDelta, metric)
+	}
+
+	if r := i.reporter.Load(); r != nil {
+		r.self.drop("invalid", 1)
+		if r.debugf != nil {
+			r.debugf("metrics: rejected counter delta %g for %s", delta, metric)
+		}
+		if r.onInvalid != nil {
+			r.onInvalid(metric, err)
+		}
+	}
+	return err
+}
@@ -3,9 +3,17 @@ package metrics
 
 import (
 	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"github.com/kolosys/lumen/internal/report"
 )
 
 // Metric is the interface all metric types implement.
@@ -24,6 +32,14 @@ const (
 	MetricTypeCounter MetricType = iota
 	MetricTypeGauge
 	MetricTypeHistogram
+	MetricTypeInfo
+	MetricTypeStateSet
+	MetricTypeSummary
+
+	// MetricTypeUnknown marks metrics of a kind lumen has no type for, such
+	// as untyped metrics bridged from other libraries. They are exposed as
+	// untyped.
+	MetricTypeUnknown
 )
 
 func (t MetricType) String() string {
@@ -34,6 +50,12 @@ func (t MetricType) String() string {
 		return "gauge"
 	case MetricTypeHistogram:
 		return "histogram"
+	case MetricTypeInfo:
+		return "info"
+	case MetricTypeStateSet:
+		return "stateset"
+	case MetricTypeSummary:
+		return "summary"
 	default:
 		return "unknown"
 	}
@@ -45,6 +67,52 @@ type Sample struct {
 	Labels    Labels
 	Value     float64
 	Timestamp time.Time
+
+	// Exemplar optionally links the sample to an example observation,
+	// typically carrying a trace_id label. Only OpenMetrics output
+	// includes exemplars.
+	Exemplar *Exemplar
+
+	// Created is when the series started accumulating; it is set for
+	// counter and histogram samples. A newer Created than last seen tells
+	// consumers the series was reset rather than decreased. OpenMetrics
+	// writes it as a _created sample, protobuf and OTLP as the start time.
+	Created time.Time
+}
+
+// Exemplar is an example observation attached to a counter or bucket.
+type Exemplar struct {
+	Labels    Labels
+	Value     float64
+	Timestamp time.Time
+}
+
+// maxExemplarRunes is the OpenMetrics limit on the combined length of an
+// exemplar's label names and values.
+const maxExemplarRunes = 128
+
+func newExemplar(labels Labels, value float64) *Exemplar {
+	n := 0
+	for i, k := range labels.keys {
+		n += utf8.RuneCountInString(k) + utf8.RuneCountInString(labels.values[i])
+	}
+	if n > maxExemplarRunes {
+		return nil
+	}
+	return &Exemplar{Labels: labels, Value: value, Timestamp: time.Now()}
+}
+
+// MetricFamily groups the samples of one metric with its metadata.
+type MetricFamily struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Unit    Unit
+	Samples []Sample
+
+	// Exponential holds bucket data for metrics implementing
+	// ExponentialCollector. Text formats ignore it.
+	Exponential []ExponentialHistogramPoint
 }
 
 // Registry manages metric registration and collection.
@@ -55,6 +123,15 @@ type Registry struct {
 	pushWg     sync.WaitGroup
 	closed     atomic.Bool
 	closeOnce  sync.Once
+	guard      *cardinalityGuard
+	invalid    *invalidReporter
+	tracker    *StalenessTracker
+	self       *selfMetrics
+
+	constLabels Labels
+	parent      *Registry
+	childMu     sync.Mutex
+	children    []*Registry
 }
 
 // NewRegistry creates a new metrics registry.
@@ -64,9 +141,42 @@ func NewRegistry(opts *Options) *Registry {
 	}
 	opts.applyDefaults()
 
-	r := &Registry{opts: opts}
+	r := &Registry{opts: opts, constLabels: opts.defaultLabels()}
+	if opts.SelfMetrics {
+		r.self = newSelfMetrics(r)
+	}
+
+	if opts.OnInvalidObservation != nil || opts.Debugf != nil || r.self != nil {
+		r.invalid = &invalidReporter{onInvalid: opts.OnInvalidObservation, debugf: opts.Debugf, self: r.self}
+	}
+
+	if opts.MaxSeriesPerMetric > 0 {
+		overflow := NewCounter(cardinalityOverflowMetric,
+			"Observations aggregated or rejected by the series limit.", "metric")
+		r.metrics.Store(overflow.Name(), overflow)
+		r.guard = &cardinalityGuard{
+			max:    int64(opts.MaxSeriesPerMetric),
+			reject: opts.CardinalityReject,
+			onLimit: func(metric string, labels Labels) {
+				overflow.Inc(metric)
+				if opts.CardinalityReject {
+					r.self.drop("cardinality", 1)
+				}
+				if opts.OnCardinalityLimit != nil {
+					opts.OnCardinalityLimit(metric, labels)
+				}
+			},
+		}
+	}
 
 	if opts.PushInterval > 0 && opts.PushExporter != nil {
+		if opts.StaleMarkers {
+			if _, ok := opts.PushExporter.(FamilyExporter); ok {
+				report.Error(report.MetricsPush, ErrStaleMarkersUnsupported)
+			} else {
+				r.tracker = NewStalenessTracker()
+			}
+		}
 		ctx, cancel := context.WithCancel(context.Background())
 		r.pushCancel = cancel
 		r.pushWg.Add(1)
@@ -77,16 +187,51 @@ func NewRegistry(opts *Options) *Registry {
 }
 
 // Register adds a metric to the registry.
+// It returns ErrInvalidMetricName or ErrInvalidLabelName if the metric's
+// name or label names are not valid Prometheus identifiers.
 func (r *Registry) Register(m Metric) error {
 	if r.closed.Load() {
 		return ErrRegistryClosed
 	}
+	if !ValidMetricName(m.Name()) {
+		return ErrInvalidMetricName
+	}
+	for _, name := range m.LabelNames() {
+		if !ValidLabelName(name) {
+			return ErrInvalidLabelName
+		}
+	}
 
+	if r.opts.StrictNaming {
+		if err := checkNaming(m); err != nil {
+			return err
+		}
+	}
+
+	if r.nameTaken(m.Name()) {
+		return ErrMetricExists
+	}
 	_, loaded := r.metrics.LoadOrStore(m.Name(), m)
 	if loaded {
 		return ErrMetricExists
 	}
 
+	if cl, ok := m.(cardinalityLimited); ok && r.guard != nil {
+		cl.setCardinalityGuard(r.guard)
+	}
+	if ir, ok := m.(invalidReported); ok && r.invalid != nil {
+		ir.setInvalidReporter(r.invalid)
+	}
+	if ec, ok := m.(exemplarContexter); ok && r.opts.ExemplarFromContext != nil {
+		ec.setExemplarFromContext(r.opts.ExemplarFromContext)
+	}
+	if se, ok := m.(seriesExpirer); ok && r.opts.SeriesTTL > 0 {
+		se.setSeriesTTL(r.opts.SeriesTTL)
+	}
+	if cs, ok := m.(consistentSnapshotter); ok && r.opts.ConsistentSnapshots {
+		cs.setConsistentSnapshots(true)
+	}
+
 	return nil
 }
 
@@ -103,31 +248,123 @@ func (r *Registry) Get(name string) (Metric, error) {
 	return nil, ErrMetricNotFound
 }
 
-// Collect gathers all metric samples.
+// Collect gathers all metric samples, including those of sub-registries,
+// with the registry's prefix and default labels applied.
 func (r *Registry) Collect() []Sample {
+	defer r.self.observeCollect(time.Now())
 	var samples []Sample
+	r.expireSeries()
 
 	r.metrics.Range(func(_, value any) bool {
 		m := value.(Metric)
 		samples = append(samples, m.Collect()...)
 		return true
 	})
+	for _, child := range r.subs() {
+		samples = append(samples, child.Collect()...)
+	}
+
+	r.decorateSamples(samples)
+	return r.relabelSamples(samples)
+}
+
+// Gather collects all metrics grouped into families, sorted by name.
+// Series within a family are ordered by their labels so output is stable
+// between scrapes.
+func (r *Registry) Gather() []MetricFamily {
+	defer r.self.observeCollect(time.Now())
+	var families []MetricFamily
+	r.expireSeries()
+
+	r.metrics.Range(func(_, value any) bool {
+		m := value.(Metric)
+		samples := m.Collect()
+		ignore := "le"
+		if m.Type() == MetricTypeSummary {
+			ignore = "quantile"
+		}
+		sort.SliceStable(samples, func(i, j int) bool {
+			return seriesKey(samples[i], ignore) < seriesKey(samples[j], ignore)
+		})
+		family := MetricFamily{
+			Name:    m.Name(),
+			Help:    m.Help(),
+			Type:    m.Type(),
+			Samples: samples,
+		}
+		if um, ok := m.(unitMetric); ok {
+			family.Unit = um.Unit()
+		}
+		if ec, ok := m.(ExponentialCollector); ok {
+			family.Exponential = ec.CollectExponential()
+			sort.SliceStable(family.Exponential, func(i, j int) bool {
+				return family.Exponential[i].Labels.Hash() < family.Exponential[j].Labels.Hash()
+			})
+		}
+		families = append(families, family)
+		return true
+	})
+	for _, child := range r.subs() {
+		families = append(families, child.Gather()...)
+	}
+
+	for i := range families {
+		r.decorateFamily(&families[i])
+	}
+	families = r.relabelFamilies(families)
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].Name < families[j].Name
+	})
+	return families
+}
 
-	return samples
+// seriesKey identifies the series a sample belongs to, ignoring the
+// histogram "le" or summary "quantile" label so a series' buckets or
+// quantiles, sum and count stay together.
+func seriesKey(s Sample, ignore string) string {
+	var sb strings.Builder
+	for i, k := range s.Labels.keys {
+		if k == ignore {
+			continue
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(s.Labels.values[i])
+		sb.WriteByte(',')
+	}
+	return sb.String()
 }
 
-// Close shuts down the registry.
+// Close shuts down the registry. A sub-registry is detached from its
+// parent and no longer collected.
 func (r *Registry) Close() error {
 	r.closeOnce.Do(func() {
 		r.closed.Store(true)
+		if r.parent != nil {
+			r.parent.removeSub(r)
+		}
 		if r.pushCancel != nil {
 			r.pushCancel()
 			r.pushWg.Wait()
+			r.finalPush()
 		}
 	})
 	return nil
 }
 
+func (r *Registry) expireSeries() {
+	if r.opts.SeriesTTL <= 0 {
+		return
+	}
+	now := time.Now()
+	r.metrics.Range(func(_, value any) bool {
+		if se, ok := value.(seriesExpirer); ok {
+			se.expireSeries(now)
+		}
+		return true
+	})
+}
+
 func (r *Registry) pushLoop(ctx context.Context) {
 	defer r.pushWg.Done()
 
@@ -139,33 +376,255 @@ func (r *Registry) pushLoop(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			samples := r.Collect()
-			r.opts.PushExporter.Export(samples)
+			r.push(ctx, r.collectPush())
+		}
+	}
+}
+
+// pushBatch is the payload of one push: families for a FamilyExporter,
+// samples otherwise.
+type pushBatch struct {
+	samples  []Sample
+	families []MetricFamily
+}
+
+func (b pushBatch) len() int {
+	n := len(b.samples)
+	for _, f := range b.families {
+		n += len(f.Samples)
+	}
+	return n
+}
+
+func (r *Registry) collectPush() pushBatch {
+	if _, ok := r.opts.PushExporter.(FamilyExporter); ok {
+		return pushBatch{families: r.Gather()}
+	}
+	samples := r.Collect()
+	if r.tracker != nil {
+		samples = r.tracker.Track(samples)
+	}
+	return pushBatch{samples: samples}
+}
+
+func (r *Registry) export(ctx context.Context, b pushBatch) error {
+	if fe, ok := r.opts.PushExporter.(FamilyExporter); ok {
+		return fe.ExportFamilies(ctx, b.families)
+	}
+	return r.opts.PushExporter.Export(ctx, b.samples)
+}
+
+// push exports a batch, retrying failures with exponential backoff until
+// the retries are used up or ctx is done. Each delay is drawn from the
+// upper half of the current backoff, so delays grow while still spreading
+// out registries that failed together.
+func (r *Registry) push(ctx context.Context, batch pushBatch) {
+	timeout := r.opts.PushTimeout
+	if timeout <= 0 {
+		timeout = r.opts.PushInterval
+	}
+	backoff := r.opts.PushBackoff
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := r.export(attemptCtx, batch)
+		cancel()
+		if err == nil {
+			r.self.pushed(nil, batch.len())
+			return
 		}
+
+		if attempt >= r.opts.PushRetries || ctx.Err() != nil {
+			r.self.pushed(err, batch.len())
+			r.pushFailed(err)
+			return
+		}
+
+		delay := backoff/2 + time.Duration(rand.Int64N(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, r.opts.PushMaxBackoff)
+	}
+}
+
+// finalPush sends the last samples synchronously when the registry closes,
+// without retries.
+func (r *Registry) finalPush() {
+	timeout := r.opts.PushTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	batch := r.collectPush()
+	err := r.export(ctx, batch)
+	r.self.pushed(err, batch.len())
+	if err != nil {
+		r.pushFailed(err)
+	}
+}
+
+// pushFailed reports a push that gave up.
+func (r *Registry) pushFailed(err error) {
+	err = fmt.Errorf("%w: %w", ErrExporterFailed, err)
+	report.Error(report.MetricsPush, err)
+	if r.opts.OnPushError != nil {
+		r.opts.OnPushError(err)
 	}
 }
 
-// Counter creates and registers a counter.
+// Counter creates and registers a counter, or returns the counter already
+// registered under name if it has the same label names. On any other
+// registration error the returned counter works but is not collected; use
+// CounterE to observe the error.
 func (r *Registry) Counter(name, help string, labelNames ...string) *Counter {
-	c := NewCounter(name, help, labelNames...)
-	r.Register(c)
+	c, err := r.CounterE(name, help, labelNames...)
+	if err != nil {
+		return NewCounter(name, help, labelNames...)
+	}
 	return c
 }
 
-// Gauge creates and registers a gauge.
+// CounterE is like Counter but returns the registration error.
+// ErrMetricExists means the name is taken by a different definition.
+func (r *Registry) CounterE(name, help string, labelNames ...string) (*Counter, error) {
+	return getOrRegister(r, NewCounter(name, help, labelNames...), func(existing *Counter) bool {
+		return slices.Equal(existing.labelNames, labelNames)
+	})
+}
+
+// MustCounter is like CounterE but panics on error.
+func (r *Registry) MustCounter(name, help string, labelNames ...string) *Counter {
+	c, err := r.CounterE(name, help, labelNames...)
+	if err != nil {
+		panic(fmt.Sprintf("metrics: register %s: %v", name, err))
+	}
+	return c
+}
+
+// Gauge creates and registers a gauge, or returns the matching gauge
+// already registered under name. See Counter.
 func (r *Registry) Gauge(name, help string, labelNames ...string) *Gauge {
-	g := NewGauge(name, help, labelNames...)
-	r.Register(g)
+	g, err := r.GaugeE(name, help, labelNames...)
+	if err != nil {
+		return NewGauge(name, help, labelNames...)
+	}
 	return g
 }
 
-// Histogram creates and registers a histogram.
+// GaugeE is like Gauge but returns the registration error.
+func (r *Registry) GaugeE(name, help string, labelNames ...string) (*Gauge, error) {
+	return getOrRegister(r, NewGauge(name, help, labelNames...), func(existing *Gauge) bool {
+		return slices.Equal(existing.labelNames, labelNames)
+	})
+}
+
+// MustGauge is like GaugeE but panics on error.
+func (r *Registry) MustGauge(name, help string, labelNames ...string) *Gauge {
+	g, err := r.GaugeE(name, help, labelNames...)
+	if err != nil {
+		panic(fmt.Sprintf("metrics: register %s: %v", name, err))
+	}
+	return g
+}
+
+// Histogram creates and registers a histogram, or returns the histogram
+// already registered under name if its label names and buckets match.
+// Nil buckets select Options.HistogramBuckets. See Counter.
 func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
-	h := NewHistogram(name, help, buckets, labelNames...)
-	r.Register(h)
+	h, err := r.HistogramE(name, help, buckets, labelNames...)
+	if err != nil {
+		return NewHistogram(name, help, r.buckets(buckets), labelNames...)
+	}
+	return h
+}
+
+// HistogramE is like Histogram but returns the registration error.
+func (r *Registry) HistogramE(name, help string, buckets []float64, labelNames ...string) (*Histogram, error) {
+	h := NewHistogram(name, help, r.buckets(buckets), labelNames...)
+	return getOrRegister(r, h, func(existing *Histogram) bool {
+		return slices.Equal(existing.labelNames, labelNames) && slices.Equal(existing.buckets, h.buckets)
+	})
+}
+
+// MustHistogram is like HistogramE but panics on error.
+func (r *Registry) MustHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	h, err := r.HistogramE(name, help, buckets, labelNames...)
+	if err != nil {
+		panic(fmt.Sprintf("metrics: register %s: %v", name, err))
+	}
 	return h
 }
 
+// HistogramPreset is like HistogramE but takes its buckets from a named
+// preset: one of Options.BucketPresets or the built-in PresetLatency,
+// PresetDuration and PresetSize.
+func (r *Registry) HistogramPreset(name, help, preset string, labelNames ...string) (*Histogram, error) {
+	buckets, ok := r.opts.BucketPresets[preset]
+	if !ok {
+		buckets = PresetBuckets(preset)
+	}
+	if buckets == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPreset, preset)
+	}
+	return r.HistogramE(name, help, buckets, labelNames...)
+}
+
+// buckets returns a copy of buckets, or of the registry's default buckets
+// when nil; NewHistogram sorts its argument in place.
+func (r *Registry) buckets(buckets []float64) []float64 {
+	if buckets == nil {
+		buckets = r.opts.HistogramBuckets
+	}
+	return slices.Clone(buckets)
+}
+
+// ShardedCounter creates and registers a sharded counter, or returns the
+// matching one already registered under name.
+func (r *Registry) ShardedCounter(name, help string, labelNames ...string) *ShardedCounter {
+	c, err := getOrRegister(r, NewShardedCounter(name, help, labelNames...), func(existing *ShardedCounter) bool {
+		return slices.Equal(existing.labelNames, labelNames)
+	})
+	if err != nil {
+		return NewShardedCounter(name, help, labelNames...)
+	}
+	return c
+}
+
+// ExponentialHistogram creates and registers an exponential histogram, or
+// returns the matching one already registered under name.
+func (r *Registry) ExponentialHistogram(name, help string, opts *ExponentialHistogramOptions, labelNames ...string) *ExponentialHistogram {
+	h := NewExponentialHistogram(name, help, opts, labelNames...)
+	existing, err := getOrRegister(r, h, func(existing *ExponentialHistogram) bool {
+		return slices.Equal(existing.labelNames, labelNames) && existing.opts == h.opts
+	})
+	if err != nil {
+		return h
+	}
+	return existing
+}
+
+// getOrRegister registers m, or returns the metric already registered
+// under its name when it has the same type and same reports true.
+func getOrRegister[M Metric](r *Registry, m M, same func(existing M) bool) (M, error) {
+	err := r.Register(m)
+	if err == ErrMetricExists {
+		if existing, getErr := r.Get(m.Name()); getErr == nil {
+			if e, ok := existing.(M); ok && same(e) {
+				return e, nil
+			}
+		}
+	}
+	if err != nil {
+		var zero M
+		return zero, err
+	}
+	return m, nil
+}
+
 // Default registry
 var defaultRegistry = NewRegistry(nil)
 
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolosys/lumen/internal/enc"
+)
+
+// OpenMetricsOptions configures WriteOpenMetrics.
+type OpenMetricsOptions struct {
+	// Timestamps appends each sample's timestamp, in seconds.
+	Timestamps bool
+}
+
+// WriteOpenMetrics writes metric families in the OpenMetrics text format.
+// Counter and info families are named without their _total or _info
+// suffix and their samples with it, exemplars are written after the
+// samples that carry them, counters, histograms and summaries get a _created sample
+// per series, and the output ends with the # EOF terminator.
+func WriteOpenMetrics(w io.Writer, families []MetricFamily, opts *OpenMetricsOptions) error {
+	if opts == nil {
+		opts = &OpenMetricsOptions{}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		name := SanitizeMetricName(f.Name)
+		suffix := ""
+		switch f.Type {
+		case MetricTypeCounter:
+			suffix = "_total"
+		case MetricTypeInfo:
+			suffix = "_info"
+		}
+		name = strings.TrimSuffix(name, suffix)
+
+		bw.WriteString("# TYPE ")
+		bw.WriteString(name)
+		bw.WriteByte(' ')
+		bw.WriteString(openMetricsType(f.Type))
+		bw.WriteByte('\n')
+		if f.Unit != "" && strings.HasSuffix(name, "_"+string(f.Unit)) {
+			bw.WriteString("# UNIT ")
+			bw.WriteString(name)
+			bw.WriteByte(' ')
+			bw.WriteString(string(f.Unit))
+			bw.WriteByte('\n')
+		}
+		if f.Help != "" {
+			bw.WriteString("# HELP ")
+			bw.WriteString(name)
+			bw.WriteByte(' ')
+			bw.WriteString(enc.EscapeLabelValue(f.Help))
+			bw.WriteByte('\n')
+		}
+
+		for _, s := range f.Samples {
+			sampleName := SanitizeMetricName(s.Name)
+			if suffix != "" && sampleName == SanitizeMetricName(f.Name) {
+				sampleName = name + suffix
+			}
+			writeOpenMetricsSample(bw, sampleName, s, opts.Timestamps)
+			if created := createdName(f, name, s); created != "" {
+				bw.WriteString(created)
+				writeOpenMetricsLabels(bw, s.Labels)
+				bw.WriteByte(' ')
+				bw.WriteString(formatSeconds(s.Created))
+				bw.WriteByte('\n')
+			}
+		}
+	}
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+// createdName returns the name of the _created sample that follows s, or ""
+// if none does: one per counter, histogram or summary series, after its
+// total or _count sample.
+func createdName(f MetricFamily, name string, s Sample) string {
+	if s.Created.IsZero() {
+		return ""
+	}
+	switch {
+	case f.Type == MetricTypeCounter && s.Name == f.Name,
+		(f.Type == MetricTypeHistogram || f.Type == MetricTypeSummary) && s.Name == f.Name+"_count":
+		return name + "_created"
+	}
+	return ""
+}
+
+func writeOpenMetricsSample(w *bufio.Writer, name string, s Sample, timestamps bool) {
+	w.WriteString(name)
+	writeOpenMetricsLabels(w, s.Labels)
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(s.Value))
+	if timestamps && !s.Timestamp.IsZero() {
+		w.WriteByte(' ')
+		w.WriteString(formatSeconds(s.Timestamp))
+	}
+
+	if ex := s.Exemplar; ex != nil {
+		w.WriteString(" # ")
+		if ex.Labels.Len() == 0 {
+			w.WriteString("{}")
+		} else {
+			writeOpenMetricsLabels(w, ex.Labels)
+		}
+		w.WriteByte(' ')
+		w.WriteString(formatFloat(ex.Value))
+		if !ex.Timestamp.IsZero() {
+			w.WriteByte(' ')
+			w.WriteString(formatSeconds(ex.Timestamp))
+		}
+	}
+	w.WriteByte('\n')
+}
+
+func writeOpenMetricsLabels(w *bufio.Writer, l Labels) {
+	if l.Len() == 0 {
+		return
+	}
+	w.WriteByte('{')
+	for i, key := range l.keys {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(sanitizeLabelName(key))
+		w.WriteString(`="`)
+		w.Write(enc.AppendLabelValue(w.AvailableBuffer(), l.values[i]))
+		w.WriteByte('"')
+	}
+	w.WriteByte('}')
+}
+
+func openMetricsType(t MetricType) string {
+	switch t {
+	case MetricTypeCounter, MetricTypeGauge, MetricTypeHistogram, MetricTypeInfo, MetricTypeStateSet, MetricTypeSummary:
+		return t.String()
+	default:
+		return "unknown"
+	}
+}
+
+// formatSeconds formats t as Unix seconds with millisecond precision.
+func formatSeconds(t time.Time) string {
+	ms := t.UnixMilli()
+	sec, frac := ms/1000, ms%1000
+	if frac < 0 {
+		sec, frac = sec-1, frac+1000
+	}
+	s := strconv.FormatInt(sec, 10)
+	if frac == 0 {
+		return s
+	}
+	f := strconv.FormatInt(frac+1000, 10)[1:]
+	return s + "." + strings.TrimRight(f, "0")
+}
@@ -0,0 +1,106 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	created := time.Unix(1700000000, 0)
+	at := time.Unix(1700000100, 500*int64(time.Millisecond))
+	families := []MetricFamily{{
+		Name: "requests_total",
+		Help: "Requests.",
+		Type: MetricTypeCounter,
+		Samples: []Sample{{
+			Name:      "requests_total",
+			Labels:    NewLabels("code", "200"),
+			Value:     7,
+			Timestamp: at,
+			Created:   created,
+			Exemplar:  &Exemplar{Labels: NewLabels("trace_id", "abc"), Value: 1, Timestamp: at},
+		}},
+	}, {
+		Name: "latency_seconds",
+		Type: MetricTypeHistogram,
+		Unit: UnitSeconds,
+		Samples: []Sample{
+			{Name: "latency_seconds_bucket", Labels: NewLabels("le", "0.5"), Value: 1, Exemplar: &Exemplar{Value: 0.2}},
+			{Name: "latency_seconds_bucket", Labels: NewLabels("le", "+Inf"), Value: 2},
+			{Name: "latency_seconds_sum", Value: 1.2},
+			{Name: "latency_seconds_count", Value: 2, Created: created},
+		},
+	}, {
+		Name:    "build_info",
+		Type:    MetricTypeInfo,
+		Samples: []Sample{{Name: "build_info", Labels: NewLabels("version", "1.0"), Value: 1}},
+	}}
+
+	var buf strings.Builder
+	if err := WriteOpenMetrics(&buf, families, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := `# TYPE requests counter
+# HELP requests Requests.
+requests_total{code="200"} 7 # {trace_id="abc"} 1 1700000100.5
+requests_created{code="200"} 1700000000
+# TYPE latency_seconds histogram
+# UNIT latency_seconds seconds
+latency_seconds_bucket{le="0.5"} 1 # {} 0.2
+latency_seconds_bucket{le="+Inf"} 2
+latency_seconds_sum 1.2
+latency_seconds_count 2
+latency_seconds_created 1700000000
+# TYPE build info
+build_info{version="1.0"} 1
+# EOF
+`
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteOpenMetrics(&buf, families[:1], &OpenMetricsOptions{Timestamps: true}); err != nil {
+		t.Fatal(err)
+	}
+	if line := `requests_total{code="200"} 7 1700000100.5 # {trace_id="abc"} 1 1700000100.5`; !strings.Contains(buf.String(), line+"\n") {
+		t.Errorf("expected %q in:\n%s", line, buf.String())
+	}
+}
+
+func TestHandlerNegotiatesFormat(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Counter("requests_total", "Requests.").AddWithExemplar(1, NewLabels("trace_id", "abc"))
+	h := HTTPHandler(r)
+
+	tests := []struct {
+		accept      string
+		contentType string
+		contains    string
+	}{
+		{"", ContentTypePrometheus, "# TYPE requests_total counter\n"},
+		{"text/plain;version=0.0.4", ContentTypePrometheus, "requests_total 1\n"},
+		{"application/openmetrics-text;version=1.0.0", ContentTypeOpenMetrics, `requests_total 1 # {trace_id="abc"} 1`},
+		{"application/openmetrics-text;q=0.5,text/plain;q=0.9", ContentTypePrometheus, "requests_total 1\n"},
+		{"application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited", ContentTypeProtobuf, "requests_total"},
+		{"application/json", ContentTypePrometheus, "requests_total 1\n"},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", tc.accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Content-Type"); got != tc.contentType {
+			t.Errorf("Accept %q: Content-Type = %q, want %q", tc.accept, got, tc.contentType)
+		}
+		if !strings.Contains(rec.Body.String(), tc.contains) {
+			t.Errorf("Accept %q: expected %q in:\n%s", tc.accept, tc.contains, rec.Body.String())
+		}
+	}
+}
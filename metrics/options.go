@@ -1,6 +1,11 @@
 package metrics
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/kolosys/lumen/resource"
+)
 
 // Options configures a Registry.
 type Options struct {
@@ -10,20 +15,138 @@ type Options struct {
 	// DefaultLabels are added to all metrics.
 	DefaultLabels map[string]string
 
-	// HistogramBuckets defines default histogram bucket boundaries.
+	// Resource adds the service's identity (service, version, env and
+	// service_instance) to every sample. InstanceLabels and DefaultLabels override
+	// it.
+	Resource *resource.Resource
+
+	// InstanceLabels adds the labels from DetectInstanceLabels (host,
+	// service_instance, service, version) to every sample. DefaultLabels
+	// override them.
+	InstanceLabels bool
+
+	// InstanceLabelsFunc can edit the detected instance labels before use,
+	// e.g. to rename, drop or add entries.
+	InstanceLabelsFunc func(labels map[string]string)
+
+	// HistogramBuckets defines the buckets Registry.Histogram uses when
+	// called with nil buckets. Defaults to DefaultHistogramBuckets.
 	HistogramBuckets []float64
 
+	// BucketPresets adds or overrides named bucket layouts for
+	// Registry.HistogramPreset. The built-in presets are PresetLatency,
+	// PresetDuration and PresetSize.
+	BucketPresets map[string][]float64
+
+	// Relabel rules rewrite or drop series at collection and export time,
+	// after Prefix and DefaultLabels are applied. Rules run in order.
+	// Dropping labels can merge series; the remaining labels must still
+	// tell them apart.
+	Relabel []RelabelRule
+
 	// PushInterval sets the interval for push exporters (0 = disabled).
 	PushInterval time.Duration
 
-	// PushExporter is the exporter for push-based metrics.
-	PushExporter Exporter
+	// PushExporter is the exporter for push-based metrics. Wrap an
+	// Exporter with AdaptExporter.
+	PushExporter PushExporter
+
+	// PushTimeout bounds each push attempt. Defaults to PushInterval, or
+	// 10s for the final push on Close.
+	PushTimeout time.Duration
+
+	// PushRetries is the number of retries after a failed push
+	// (default 3; negative disables retrying).
+	PushRetries int
+
+	// PushBackoff is the delay before the first retry, doubled for each
+	// further retry. Each delay is jittered within the upper half of the
+	// backoff. Defaults to 500ms.
+	PushBackoff time.Duration
+
+	// PushMaxBackoff caps the retry delay. Defaults to 30s.
+	PushMaxBackoff time.Duration
+
+	// OnPushError is called when a push fails after all retries. The error
+	// wraps ErrExporterFailed.
+	OnPushError func(err error)
+
+	// OnInvalidObservation is called when a metric rejects a value, such
+	// as a negative or NaN counter increment. The error wraps
+	// ErrInvalidDelta.
+	OnInvalidObservation func(metric string, err error)
+
+	// Debugf, if set, receives debug messages about the registry's own
+	// behaviour, such as rejected observations. A logger's Debugf method
+	// or log.Printf fits.
+	Debugf func(format string, args ...any)
+
+	// ExemplarFromContext, if set, returns exemplar labels such as
+	// trace_id for observations made with a context, e.g. via
+	// Histogram.ObserveContext and Counter.AddContext. An empty result
+	// records no exemplar.
+	ExemplarFromContext func(ctx context.Context) Labels
+
+	// MaxSeriesPerMetric caps the label sets each registered metric may
+	// hold (0 = unlimited). Observations for new label sets beyond the cap
+	// are aggregated into one series with every label set to "other".
+	MaxSeriesPerMetric int
+
+	// CardinalityReject drops observations beyond MaxSeriesPerMetric
+	// instead of aggregating them.
+	CardinalityReject bool
+
+	// OnCardinalityLimit is called with the metric name and the label set
+	// each time the limit aggregates or rejects an observation.
+	OnCardinalityLimit func(metric string, labels Labels)
+
+	// SeriesTTL removes series that have not been written for this long
+	// (0 = keep forever), so label sets that stop appearing do not linger.
+	SeriesTTL time.Duration
+
+	// StaleMarkers makes the push loop send a StaleNaN sample for each
+	// series that disappeared since the previous push. It applies to
+	// PushExporter.Export only: a FamilyExporter, such as an OTLP
+	// exporter, gets no markers and NewRegistry reports
+	// ErrStaleMarkersUnsupported to the internal error handlers. HTTP
+	// scrapes never carry markers; the scraper detects staleness itself.
+	StaleMarkers bool
+
+	// ConsistentSnapshots makes collection retry reading a histogram series
+	// until no observation is in flight, so its buckets, _sum and _count
+	// come from the same set of observations. Without it they can be off
+	// by the observations racing the scrape; buckets never exceed _count
+	// either way.
+	ConsistentSnapshots bool
+
+	// StrictNaming makes Register reject names that break the Prometheus
+	// conventions: counters must end in _total, other types must not,
+	// names must not end in _bucket, _count, _sum or _created, and a
+	// metric with a unit must end with it.
+	StrictNaming bool
+
+	// SelfMetrics registers lumen_metrics_* metrics describing the registry:
+	// registered metrics, series per metric, collection duration, push
+	// outcomes and dropped samples.
+	SelfMetrics bool
+
+	// ExposeTimestamps includes sample timestamps in HTTPHandler output.
+	ExposeTimestamps bool
 }
 
 func (o *Options) applyDefaults() {
 	if o.HistogramBuckets == nil {
 		o.HistogramBuckets = DefaultHistogramBuckets()
 	}
+	if o.PushRetries == 0 {
+		o.PushRetries = 3
+	}
+	if o.PushBackoff <= 0 {
+		o.PushBackoff = 500 * time.Millisecond
+	}
+	if o.PushMaxBackoff <= 0 {
+		o.PushMaxBackoff = 30 * time.Second
+	}
 }
 
 // DefaultHistogramBuckets returns commonly used bucket boundaries.
@@ -31,6 +154,30 @@ func DefaultHistogramBuckets() []float64 {
 	return []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
 }
 
+// Built-in bucket preset names.
+const (
+	// PresetLatency covers request latencies in seconds, 1ms to 10s.
+	PresetLatency = "latency"
+	// PresetDuration covers job and task durations in seconds, 100ms to 1h.
+	PresetDuration = "duration"
+	// PresetSize covers payload sizes in bytes, 64B to 64MiB.
+	PresetSize = "size"
+)
+
+// PresetBuckets returns the bucket boundaries of a built-in preset, or nil
+// if name is unknown.
+func PresetBuckets(name string) []float64 {
+	switch name {
+	case PresetLatency:
+		return []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	case PresetDuration:
+		return []float64{.1, .5, 1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600}
+	case PresetSize:
+		return ExponentialBuckets(64, 4, 11)
+	}
+	return nil
+}
+
 // LinearBuckets creates n buckets of equal width.
 func LinearBuckets(start, width float64, count int) []float64 {
 	buckets := make([]float64, count)
@@ -0,0 +1,449 @@
+// Package otlpconv converts lumen metric families to OTLP metric
+// structures. The types mirror the OTLP protobuf messages and encode to the
+// OTLP/JSON wire format with encoding/json, so gathered metrics can be
+// posted to an OTLP/HTTP collector.
+package otlpconv
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/kolosys/lumen/metrics"
+)
+
+// ScopeName is the instrumentation scope reported for converted metrics.
+const ScopeName = "github.com/kolosys/lumen/metrics"
+
+// Aggregation temporalities as defined by OTLP. Registry metrics are
+// cumulative.
+const (
+	AggregationTemporalityUnspecified = 0
+	AggregationTemporalityDelta       = 1
+	AggregationTemporalityCumulative  = 2
+)
+
+// Exemplar labels mapped to the dedicated OTLP exemplar fields.
+const (
+	TraceIDLabel = "trace_id"
+	SpanIDLabel  = "span_id"
+)
+
+// MetricsData is the top-level OTLP metrics message.
+type MetricsData struct {
+	ResourceMetrics []ResourceMetrics `json:"resourceMetrics"`
+}
+
+// ResourceMetrics groups metrics produced by one resource.
+type ResourceMetrics struct {
+	Resource     Resource       `json:"resource"`
+	ScopeMetrics []ScopeMetrics `json:"scopeMetrics"`
+}
+
+// Resource describes the entity producing telemetry.
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+// ScopeMetrics groups metrics produced by one instrumentation scope.
+type ScopeMetrics struct {
+	Scope   InstrumentationScope `json:"scope"`
+	Metrics []Metric             `json:"metrics"`
+}
+
+// InstrumentationScope identifies the instrumentation library.
+type InstrumentationScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Metric is an OTLP metric. Exactly one of the data fields is set.
+type Metric struct {
+	Name                 string                `json:"name"`
+	Description          string                `json:"description,omitempty"`
+	Unit                 string                `json:"unit,omitempty"`
+	Gauge                *Gauge                `json:"gauge,omitempty"`
+	Sum                  *Sum                  `json:"sum,omitempty"`
+	Histogram            *Histogram            `json:"histogram,omitempty"`
+	ExponentialHistogram *ExponentialHistogram `json:"exponentialHistogram,omitempty"`
+	Summary              *Summary              `json:"summary,omitempty"`
+}
+
+// Gauge holds point-in-time values.
+type Gauge struct {
+	DataPoints []NumberDataPoint `json:"dataPoints"`
+}
+
+// Sum holds accumulated values.
+type Sum struct {
+	DataPoints             []NumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+// Histogram holds explicit-bucket histogram points.
+type Histogram struct {
+	DataPoints             []HistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+// ExponentialHistogram holds exponential-bucket histogram points.
+type ExponentialHistogram struct {
+	DataPoints             []ExponentialHistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                             `json:"aggregationTemporality"`
+}
+
+// NumberDataPoint is a single counter or gauge value.
+type NumberDataPoint struct {
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string,omitempty"`
+	TimeUnixNano      uint64     `json:"timeUnixNano,string"`
+	AsDouble          float64    `json:"asDouble"`
+	Exemplars         []Exemplar `json:"exemplars,omitempty"`
+}
+
+// HistogramDataPoint is an explicit-bucket histogram. BucketCounts has one
+// more entry than ExplicitBounds; the last counts values above every bound.
+type HistogramDataPoint struct {
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string,omitempty"`
+	TimeUnixNano      uint64     `json:"timeUnixNano,string"`
+	Count             uint64     `json:"count,string"`
+	Sum               *float64   `json:"sum,omitempty"`
+	BucketCounts      []uint64   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+	Exemplars         []Exemplar `json:"exemplars,omitempty"`
+}
+
+// Summary holds quantile summaries.
+type Summary struct {
+	DataPoints []SummaryDataPoint `json:"dataPoints"`
+}
+
+// SummaryDataPoint is a set of quantiles with the count and sum of the
+// observations they describe.
+type SummaryDataPoint struct {
+	Attributes        []KeyValue      `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64          `json:"startTimeUnixNano,string,omitempty"`
+	TimeUnixNano      uint64          `json:"timeUnixNano,string"`
+	Count             uint64          `json:"count,string"`
+	Sum               float64         `json:"sum"`
+	QuantileValues    []QuantileValue `json:"quantileValues,omitempty"`
+}
+
+// QuantileValue is the value at one quantile.
+type QuantileValue struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+// ExponentialHistogramDataPoint is an exponential-bucket histogram.
+type ExponentialHistogramDataPoint struct {
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string,omitempty"`
+	TimeUnixNano      uint64     `json:"timeUnixNano,string"`
+	Count             uint64     `json:"count,string"`
+	Sum               *float64   `json:"sum,omitempty"`
+	Scale             int32      `json:"scale"`
+	ZeroCount         uint64     `json:"zeroCount,string"`
+	Positive          Buckets    `json:"positive"`
+	Negative          Buckets    `json:"negative"`
+	Min               *float64   `json:"min,omitempty"`
+	Max               *float64   `json:"max,omitempty"`
+	ZeroThreshold     float64    `json:"zeroThreshold,omitempty"`
+}
+
+// Buckets is a run of exponential histogram bucket counts starting at
+// bucket index Offset.
+type Buckets struct {
+	Offset       int32    `json:"offset"`
+	BucketCounts []uint64 `json:"bucketCounts"`
+}
+
+// Exemplar is an example measurement, optionally tied to a span.
+type Exemplar struct {
+	FilteredAttributes []KeyValue `json:"filteredAttributes,omitempty"`
+	TimeUnixNano       uint64     `json:"timeUnixNano,string"`
+	AsDouble           float64    `json:"asDouble"`
+	TraceID            string     `json:"traceId,omitempty"`
+	SpanID             string     `json:"spanId,omitempty"`
+}
+
+// KeyValue is an attribute.
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue holds an attribute value. Metric labels are always strings.
+type AnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+}
+
+// StringValue returns a string AnyValue.
+func StringValue(s string) AnyValue {
+	return AnyValue{StringValue: &s}
+}
+
+// FromFamilies converts gathered metric families to OTLP under a single
+// resource described by the given attributes.
+func FromFamilies(families []metrics.MetricFamily, resource ...KeyValue) *MetricsData {
+	scope := ScopeMetrics{Scope: InstrumentationScope{Name: ScopeName}}
+	for _, f := range families {
+		scope.Metrics = append(scope.Metrics, FromFamily(f))
+	}
+
+	return &MetricsData{ResourceMetrics: []ResourceMetrics{{
+		Resource:     Resource{Attributes: resource},
+		ScopeMetrics: []ScopeMetrics{scope},
+	}}}
+}
+
+// FromFamily converts a single metric family. Counters become monotonic
+// cumulative sums; histograms with exponential data become exponential
+// histograms.
+func FromFamily(f metrics.MetricFamily) Metric {
+	m := Metric{Name: f.Name, Description: f.Help, Unit: fromUnit(f.Unit)}
+
+	switch f.Type {
+	case metrics.MetricTypeCounter:
+		m.Sum = &Sum{
+			DataPoints:             numberPoints(f.Samples),
+			AggregationTemporality: AggregationTemporalityCumulative,
+			IsMonotonic:            true,
+		}
+	case metrics.MetricTypeHistogram:
+		if len(f.Exponential) > 0 {
+			m.ExponentialHistogram = &ExponentialHistogram{AggregationTemporality: AggregationTemporalityCumulative}
+			for _, p := range f.Exponential {
+				m.ExponentialHistogram.DataPoints = append(m.ExponentialHistogram.DataPoints, FromExponential(p))
+			}
+			break
+		}
+		m.Histogram = &Histogram{
+			DataPoints:             histogramPoints(f),
+			AggregationTemporality: AggregationTemporalityCumulative,
+		}
+	case metrics.MetricTypeSummary:
+		m.Summary = &Summary{DataPoints: summaryPoints(f)}
+	default:
+		m.Gauge = &Gauge{DataPoints: numberPoints(f.Samples)}
+	}
+
+	return m
+}
+
+// summaryPoints groups the quantile, _sum and _count samples of a summary
+// by series.
+func summaryPoints(f metrics.MetricFamily) []SummaryDataPoint {
+	var order []string
+	series := make(map[string]*SummaryDataPoint)
+
+	for _, s := range f.Samples {
+		base, quantile := withoutLabel(s.Labels, "quantile")
+		key := base.Hash()
+		dp, ok := series[key]
+		if !ok {
+			dp = &SummaryDataPoint{
+				Attributes:        FromLabels(base),
+				StartTimeUnixNano: unixNano(s.Created),
+				TimeUnixNano:      unixNano(s.Timestamp),
+			}
+			series[key] = dp
+			order = append(order, key)
+		}
+
+		switch s.Name {
+		case f.Name:
+			q, err := strconv.ParseFloat(quantile, 64)
+			if err != nil {
+				continue
+			}
+			dp.QuantileValues = append(dp.QuantileValues, QuantileValue{Quantile: q, Value: s.Value})
+		case f.Name + "_sum":
+			dp.Sum = s.Value
+		case f.Name + "_count":
+			dp.Count = uint64(s.Value)
+		}
+	}
+
+	points := make([]SummaryDataPoint, 0, len(order))
+	for _, key := range order {
+		points = append(points, *series[key])
+	}
+	return points
+}
+
+// fromUnit maps lumen units to UCUM, which OTLP uses.
+func fromUnit(u metrics.Unit) string {
+	switch u {
+	case metrics.UnitSeconds:
+		return "s"
+	case metrics.UnitBytes:
+		return "By"
+	case metrics.UnitRatio:
+		return "1"
+	}
+	return string(u)
+}
+
+// FromExponential converts an exponential histogram point.
+func FromExponential(p metrics.ExponentialHistogramPoint) ExponentialHistogramDataPoint {
+	sum, lo, hi := p.Sum, p.Min, p.Max
+	dp := ExponentialHistogramDataPoint{
+		Attributes:        FromLabels(p.Labels),
+		StartTimeUnixNano: unixNano(p.Created),
+		TimeUnixNano:      unixNano(p.Timestamp),
+		Count:             p.Count,
+		Sum:               &sum,
+		Scale:             p.Scale,
+		ZeroCount:         p.ZeroCount,
+		Positive:          Buckets{Offset: p.Positive.Offset, BucketCounts: nonNil(p.Positive.Counts)},
+		Negative:          Buckets{Offset: p.Negative.Offset, BucketCounts: nonNil(p.Negative.Counts)},
+		ZeroThreshold:     p.ZeroThreshold,
+	}
+	if p.Count > 0 {
+		dp.Min, dp.Max = &lo, &hi
+	}
+	return dp
+}
+
+// FromLabels converts metric labels to OTLP attributes.
+func FromLabels(l metrics.Labels) []KeyValue {
+	if l.Len() == 0 {
+		return nil
+	}
+	keys, values := l.Keys(), l.Values()
+	attrs := make([]KeyValue, len(keys))
+	for i, k := range keys {
+		attrs[i] = KeyValue{Key: k, Value: StringValue(values[i])}
+	}
+	return attrs
+}
+
+func numberPoints(samples []metrics.Sample) []NumberDataPoint {
+	points := make([]NumberDataPoint, 0, len(samples))
+	for _, s := range samples {
+		dp := NumberDataPoint{
+			Attributes:        FromLabels(s.Labels),
+			StartTimeUnixNano: unixNano(s.Created),
+			TimeUnixNano:      unixNano(s.Timestamp),
+			AsDouble:          s.Value,
+		}
+		if s.Exemplar != nil {
+			dp.Exemplars = []Exemplar{fromExemplar(s.Exemplar)}
+		}
+		points = append(points, dp)
+	}
+	return points
+}
+
+type histogramSeries struct {
+	dp         HistogramDataPoint
+	cumulative []uint64
+}
+
+// histogramPoints rebuilds explicit-bucket points from the cumulative
+// _bucket, _sum and _count samples of a classic histogram.
+func histogramPoints(f metrics.MetricFamily) []HistogramDataPoint {
+	var order []string
+	series := make(map[string]*histogramSeries)
+
+	for _, s := range f.Samples {
+		base, le := withoutLabel(s.Labels, "le")
+		key := base.Hash()
+		hs, ok := series[key]
+		if !ok {
+			hs = &histogramSeries{dp: HistogramDataPoint{
+				Attributes:        FromLabels(base),
+				StartTimeUnixNano: unixNano(s.Created),
+				TimeUnixNano:      unixNano(s.Timestamp),
+			}}
+			series[key] = hs
+			order = append(order, key)
+		}
+
+		switch s.Name {
+		case f.Name + "_bucket":
+			if s.Exemplar != nil {
+				hs.dp.Exemplars = append(hs.dp.Exemplars, fromExemplar(s.Exemplar))
+			}
+			if le == "+Inf" {
+				continue
+			}
+			bound, err := strconv.ParseFloat(le, 64)
+			if err != nil {
+				continue
+			}
+			hs.dp.ExplicitBounds = append(hs.dp.ExplicitBounds, bound)
+			hs.cumulative = append(hs.cumulative, uint64(s.Value))
+		case f.Name + "_sum":
+			sum := s.Value
+			hs.dp.Sum = &sum
+		case f.Name + "_count":
+			hs.dp.Count = uint64(s.Value)
+		}
+	}
+
+	points := make([]HistogramDataPoint, 0, len(order))
+	for _, key := range order {
+		hs := series[key]
+		counts := make([]uint64, len(hs.cumulative)+1)
+		var prev uint64
+		for i, c := range hs.cumulative {
+			counts[i] = c - prev
+			prev = c
+		}
+		counts[len(counts)-1] = hs.dp.Count - prev
+		hs.dp.BucketCounts = counts
+		if hs.dp.ExplicitBounds == nil {
+			hs.dp.ExplicitBounds = []float64{}
+		}
+		points = append(points, hs.dp)
+	}
+	return points
+}
+
+// withoutLabel returns l without the named label, and that label's value.
+func withoutLabel(l metrics.Labels, name string) (metrics.Labels, string) {
+	keys, values := l.Keys(), l.Values()
+	pairs := make([]string, 0, len(keys)*2)
+	value := ""
+	for i, k := range keys {
+		if k == name {
+			value = values[i]
+			continue
+		}
+		pairs = append(pairs, k, values[i])
+	}
+	return metrics.NewLabels(pairs...), value
+}
+
+func fromExemplar(ex *metrics.Exemplar) Exemplar {
+	e := Exemplar{TimeUnixNano: unixNano(ex.Timestamp), AsDouble: ex.Value}
+	keys, values := ex.Labels.Keys(), ex.Labels.Values()
+	for i, k := range keys {
+		switch k {
+		case TraceIDLabel:
+			e.TraceID = values[i]
+		case SpanIDLabel:
+			e.SpanID = values[i]
+		default:
+			e.FilteredAttributes = append(e.FilteredAttributes, KeyValue{Key: k, Value: StringValue(values[i])})
+		}
+	}
+	return e
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func nonNil(counts []uint64) []uint64 {
+	if counts == nil {
+		return []uint64{}
+	}
+	return counts
+}
@@ -0,0 +1,201 @@
+package otlpconv_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolosys/lumen/metrics"
+	. "github.com/kolosys/lumen/metrics/otlpconv"
+)
+
+func float(v float64) *float64 { return &v }
+
+func scale(s int32) *int32 { return &s }
+
+func TestFromFamilyExponential(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   metrics.ExponentialHistogramOptions
+		values []float64
+		want   ExponentialHistogramDataPoint
+	}{
+		{
+			// At scale 0 bucket i is (2^i, 2^(i+1)]; exact powers of two
+			// sit on an upper bound.
+			name:   "scale 0 with zero and negative buckets",
+			opts:   metrics.ExponentialHistogramOptions{MaxScale: scale(0)},
+			values: []float64{1, 2, 3, 4, 0, -3, -0.75},
+			want: ExponentialHistogramDataPoint{
+				Count:     7,
+				Sum:       float(6.25),
+				Scale:     0,
+				ZeroCount: 1,
+				Positive:  Buckets{Offset: -1, BucketCounts: []uint64{1, 1, 2}},
+				Negative:  Buckets{Offset: -1, BucketCounts: []uint64{1, 0, 1}},
+				Min:       float(-3),
+				Max:       float(4),
+			},
+		},
+		{
+			// Base sqrt(2): 1 is in (2^-0.5, 1], 1.5 and 2 in (2^0.5, 2],
+			// 3 in (2^1.5, 4]. 0.25 falls inside the zero threshold.
+			name:   "scale 1 with zero threshold",
+			opts:   metrics.ExponentialHistogramOptions{MaxScale: scale(1), ZeroThreshold: 0.5},
+			values: []float64{1, 1.5, 2, 3, 0.25},
+			want: ExponentialHistogramDataPoint{
+				Count:         5,
+				Sum:           float(7.75),
+				Scale:         1,
+				ZeroCount:     1,
+				Positive:      Buckets{Offset: -1, BucketCounts: []uint64{1, 0, 2, 0, 1}},
+				Negative:      Buckets{BucketCounts: []uint64{}},
+				Min:           float(0.25),
+				Max:           float(3),
+				ZeroThreshold: 0.5,
+			},
+		},
+		{
+			// Base 4: 5 is in (4, 16] and 100 in (64, 256].
+			name:   "negative scale",
+			opts:   metrics.ExponentialHistogramOptions{MaxScale: scale(-1)},
+			values: []float64{5, 100, -1},
+			want: ExponentialHistogramDataPoint{
+				Count:    3,
+				Sum:      float(104),
+				Scale:    -1,
+				Positive: Buckets{Offset: 1, BucketCounts: []uint64{1, 0, 1}},
+				Negative: Buckets{Offset: -1, BucketCounts: []uint64{1}},
+				Min:      float(-1),
+				Max:      float(100),
+			},
+		},
+		{
+			// 1 and 8 are buckets -1 and 2 at scale 0; two buckets only
+			// fit at scale -2, where they become (1/16, 1] and (1, 16].
+			name:   "rescaled to fit",
+			opts:   metrics.ExponentialHistogramOptions{MaxScale: scale(0), MaxSize: 2},
+			values: []float64{1, 8},
+			want: ExponentialHistogramDataPoint{
+				Count:    2,
+				Sum:      float(9),
+				Scale:    -2,
+				Positive: Buckets{Offset: -1, BucketCounts: []uint64{1, 1}},
+				Negative: Buckets{BucketCounts: []uint64{}},
+				Min:      float(1),
+				Max:      float(8),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := metrics.NewExponentialHistogram("latency_seconds", "", &tt.opts, "route")
+			for _, v := range tt.values {
+				h.Observe(v, "/orders")
+			}
+			m := FromFamily(metrics.MetricFamily{
+				Name:        "latency_seconds",
+				Type:        metrics.MetricTypeHistogram,
+				Unit:        metrics.UnitSeconds,
+				Exponential: h.CollectExponential(),
+			})
+			if m.Unit != "s" || m.Histogram != nil || m.ExponentialHistogram == nil {
+				t.Fatalf("metric = %+v", m)
+			}
+			if at := m.ExponentialHistogram.AggregationTemporality; at != AggregationTemporalityCumulative {
+				t.Errorf("temporality = %d", at)
+			}
+
+			points := m.ExponentialHistogram.DataPoints
+			if len(points) != 1 {
+				t.Fatalf("%d data points", len(points))
+			}
+			got := points[0]
+			if got.StartTimeUnixNano == 0 || got.TimeUnixNano < got.StartTimeUnixNano {
+				t.Errorf("times = %d, %d", got.StartTimeUnixNano, got.TimeUnixNano)
+			}
+			got.StartTimeUnixNano, got.TimeUnixNano = 0, 0
+
+			want := tt.want
+			want.Attributes = []KeyValue{{Key: "route", Value: StringValue("/orders")}}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(want)
+				t.Errorf("data point\n got %s\nwant %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestFromExponentialEmpty(t *testing.T) {
+	dp := FromExponential(metrics.ExponentialHistogramPoint{Scale: 0})
+	if dp.Min != nil || dp.Max != nil {
+		t.Errorf("empty point has min/max %v/%v", dp.Min, dp.Max)
+	}
+
+	// Scale 0 and empty bucket runs are encoded, not omitted.
+	data, err := json.Marshal(dp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"scale":0`, `"zeroCount":"0"`, `"positive":{"offset":0,"bucketCounts":[]}`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("expected %s in %s", s, data)
+		}
+	}
+}
+
+func TestFromFamilySummary(t *testing.T) {
+	created := time.Unix(1700000000, 0)
+	now := created.Add(time.Minute)
+	sample := func(name string, value float64, pairs ...string) metrics.Sample {
+		return metrics.Sample{Name: name, Labels: metrics.NewLabels(pairs...), Value: value, Timestamp: now, Created: created}
+	}
+
+	m := FromFamily(metrics.MetricFamily{
+		Name: "rpc_seconds",
+		Type: metrics.MetricTypeSummary,
+		Samples: []metrics.Sample{
+			sample("rpc_seconds", 0.01, "method", "get", "quantile", "0.5"),
+			sample("rpc_seconds", 0.2, "method", "get", "quantile", "0.99"),
+			sample("rpc_seconds_sum", 1.5, "method", "get"),
+			sample("rpc_seconds_count", 40, "method", "get"),
+			sample("rpc_seconds", 0.3, "method", "put", "quantile", "0.5"),
+			sample("rpc_seconds", 9, "method", "put", "quantile", "bogus"),
+			sample("rpc_seconds_sum", 0.9, "method", "put"),
+			sample("rpc_seconds_count", 3, "method", "put"),
+		},
+	})
+	if m.Summary == nil {
+		t.Fatalf("metric = %+v", m)
+	}
+
+	start, ts := uint64(created.UnixNano()), uint64(now.UnixNano())
+	want := []SummaryDataPoint{
+		{
+			Attributes:        []KeyValue{{Key: "method", Value: StringValue("get")}},
+			StartTimeUnixNano: start,
+			TimeUnixNano:      ts,
+			Count:             40,
+			Sum:               1.5,
+			QuantileValues:    []QuantileValue{{Quantile: 0.5, Value: 0.01}, {Quantile: 0.99, Value: 0.2}},
+		},
+		{
+			// The unparsable quantile is dropped.
+			Attributes:        []KeyValue{{Key: "method", Value: StringValue("put")}},
+			StartTimeUnixNano: start,
+			TimeUnixNano:      ts,
+			Count:             3,
+			Sum:               0.9,
+			QuantileValues:    []QuantileValue{{Quantile: 0.5, Value: 0.3}},
+		},
+	}
+	if !reflect.DeepEqual(m.Summary.DataPoints, want) {
+		got, _ := json.Marshal(m.Summary.DataPoints)
+		exp, _ := json.Marshal(want)
+		t.Errorf("data points\n got %s\nwant %s", got, exp)
+	}
+}
@@ -0,0 +1,326 @@
+// Package prombridge connects lumen metrics with
+// github.com/prometheus/client_golang in both directions: a lumen Registry
+// can be served as a prometheus.Gatherer, and prometheus.Collector
+// implementations can be registered into a lumen Registry so third-party
+// instrumentation appears on lumen's metrics endpoint.
+//
+// It lives in its own module so the core lumen module stays free of
+// dependencies.
+package prombridge
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// Gatherer returns a prometheus.Gatherer that reports the metrics of r, for
+// serving them with promhttp or combining them with other gatherers in
+// prometheus.Gatherers. Exponential histograms become native histograms.
+func Gatherer(r *metrics.Registry) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		var buf bytes.Buffer
+		if err := metrics.WriteProtobuf(&buf, r.Gather()); err != nil {
+			return nil, err
+		}
+
+		var families []*dto.MetricFamily
+		for buf.Len() > 0 {
+			mf := &dto.MetricFamily{}
+			if err := protodelim.UnmarshalFrom(&buf, mf); err != nil {
+				return nil, fmt.Errorf("prombridge: decode family: %w", err)
+			}
+			families = append(families, mf)
+		}
+		return families, nil
+	})
+}
+
+// gatherMaxAge bounds how long one Gather of the bridged collectors is
+// reused, so every family of a scrape comes from the same collection.
+const gatherMaxAge = 100 * time.Millisecond
+
+// Register registers collectors into r. Each metric family the collectors
+// report when Register is called becomes a metric in r; families that
+// only appear later are not picked up, so register collectors once they
+// are set up.
+//
+// On error nothing is registered.
+func Register(r *metrics.Registry, collectors ...prometheus.Collector) error {
+	src := &source{reg: prometheus.NewRegistry()}
+	for _, c := range collectors {
+		if err := src.reg.Register(c); err != nil {
+			return err
+		}
+	}
+	families, _, err := src.gather()
+	if err != nil {
+		return err
+	}
+
+	registered := make([]string, 0, len(families))
+	for name, mf := range families {
+		m := &family{src: src, name: name, help: mf.GetHelp(), typ: convertType(mf.GetType())}
+		for _, pm := range mf.GetMetric() {
+			for _, lp := range pm.GetLabel() {
+				m.labelNames = append(m.labelNames, lp.GetName())
+			}
+			break
+		}
+		if err := r.Register(m); err != nil {
+			for _, name := range registered {
+				r.Unregister(name)
+			}
+			return fmt.Errorf("prombridge: register %s: %w", name, err)
+		}
+		registered = append(registered, name)
+	}
+	return nil
+}
+
+// source gathers the bridged collectors, caching the result briefly.
+type source struct {
+	reg *prometheus.Registry
+
+	mu       sync.Mutex
+	families map[string]*dto.MetricFamily
+	taken    time.Time
+}
+
+func (s *source) gather() (map[string]*dto.MetricFamily, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.taken) <= gatherMaxAge {
+		return s.families, s.taken, nil
+	}
+	mfs, err := s.reg.Gather()
+	if err != nil && len(mfs) == 0 {
+		return nil, now, err
+	}
+	s.families = make(map[string]*dto.MetricFamily, len(mfs))
+	for _, mf := range mfs {
+		s.families[mf.GetName()] = mf
+	}
+	s.taken = now
+	return s.families, now, nil
+}
+
+// family is one bridged metric family.
+type family struct {
+	src        *source
+	name       string
+	help       string
+	typ        metrics.MetricType
+	labelNames []string
+}
+
+func (f *family) Name() string             { return f.name }
+func (f *family) Help() string             { return f.help }
+func (f *family) Type() metrics.MetricType { return f.typ }
+func (f *family) LabelNames() []string     { return f.labelNames }
+
+func (f *family) load() (*dto.MetricFamily, time.Time) {
+	families, taken, err := f.src.gather()
+	if err != nil {
+		return nil, taken
+	}
+	return families[f.name], taken
+}
+
+// Collect converts the family's current metrics to samples.
+func (f *family) Collect() []metrics.Sample {
+	mf, taken := f.load()
+	if mf == nil {
+		return nil
+	}
+
+	var samples []metrics.Sample
+	for _, m := range mf.GetMetric() {
+		labels := convertLabels(m.GetLabel())
+		ts := taken
+		if m.TimestampMs != nil {
+			ts = time.UnixMilli(m.GetTimestampMs())
+		}
+		sample := func(suffix string, l metrics.Labels, v float64) metrics.Sample {
+			return metrics.Sample{Name: f.name + suffix, Labels: l, Value: v, Timestamp: ts}
+		}
+
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			s := sample("", labels, m.GetCounter().GetValue())
+			s.Exemplar = convertExemplar(m.GetCounter().GetExemplar())
+			if c := m.GetCounter().GetCreatedTimestamp(); c != nil {
+				s.Created = c.AsTime()
+			}
+			samples = append(samples, s)
+		case dto.MetricType_GAUGE:
+			samples = append(samples, sample("", labels, m.GetGauge().GetValue()))
+		case dto.MetricType_UNTYPED:
+			samples = append(samples, sample("", labels, m.GetUntyped().GetValue()))
+		case dto.MetricType_SUMMARY:
+			sm := m.GetSummary()
+			for _, q := range sm.GetQuantile() {
+				ql := labels.Merge(metrics.NewLabels("quantile", formatFloat(q.GetQuantile())))
+				samples = append(samples, sample("", ql, q.GetValue()))
+			}
+			samples = append(samples,
+				sample("_sum", labels, sm.GetSampleSum()),
+				sample("_count", labels, float64(sm.GetSampleCount())),
+			)
+		case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+			samples = append(samples, f.histogramSamples(m.GetHistogram(), labels, ts)...)
+		}
+	}
+	return samples
+}
+
+func (f *family) histogramSamples(h *dto.Histogram, labels metrics.Labels, ts time.Time) []metrics.Sample {
+	var created time.Time
+	if c := h.GetCreatedTimestamp(); c != nil {
+		created = c.AsTime()
+	}
+	count := float64(h.GetSampleCount())
+	if h.SampleCountFloat != nil {
+		count = h.GetSampleCountFloat()
+	}
+
+	var samples []metrics.Sample
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			continue
+		}
+		v := float64(b.GetCumulativeCount())
+		if b.CumulativeCountFloat != nil {
+			v = b.GetCumulativeCountFloat()
+		}
+		samples = append(samples, metrics.Sample{
+			Name:      f.name + "_bucket",
+			Labels:    labels.Merge(metrics.NewLabels("le", formatFloat(b.GetUpperBound()))),
+			Value:     v,
+			Timestamp: ts,
+			Exemplar:  convertExemplar(b.GetExemplar()),
+			Created:   created,
+		})
+	}
+	return append(samples,
+		metrics.Sample{
+			Name:      f.name + "_bucket",
+			Labels:    labels.Merge(metrics.NewLabels("le", "+Inf")),
+			Value:     count,
+			Timestamp: ts,
+			Created:   created,
+		},
+		metrics.Sample{Name: f.name + "_sum", Labels: labels, Value: h.GetSampleSum(), Timestamp: ts, Created: created},
+		metrics.Sample{Name: f.name + "_count", Labels: labels, Value: count, Timestamp: ts, Created: created},
+	)
+}
+
+// CollectExponential converts native histograms, so lumen's protobuf and
+// OTLP output keep their buckets.
+func (f *family) CollectExponential() []metrics.ExponentialHistogramPoint {
+	mf, taken := f.load()
+	if mf == nil || mf.GetType() != dto.MetricType_HISTOGRAM {
+		return nil
+	}
+
+	var points []metrics.ExponentialHistogramPoint
+	for _, m := range mf.GetMetric() {
+		h := m.GetHistogram()
+		if h.Schema == nil || h.SampleCountFloat != nil {
+			continue
+		}
+		p := metrics.ExponentialHistogramPoint{
+			Labels:        convertLabels(m.GetLabel()),
+			Scale:         h.GetSchema(),
+			Count:         h.GetSampleCount(),
+			Sum:           h.GetSampleSum(),
+			ZeroThreshold: h.GetZeroThreshold(),
+			ZeroCount:     h.GetZeroCount(),
+			Positive:      nativeBuckets(h.GetPositiveSpan(), h.GetPositiveDelta()),
+			Negative:      nativeBuckets(h.GetNegativeSpan(), h.GetNegativeDelta()),
+			Timestamp:     taken,
+		}
+		if c := h.GetCreatedTimestamp(); c != nil {
+			p.Created = c.AsTime()
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+// nativeBuckets expands spans and delta-encoded counts. Prometheus bucket
+// i is exponential histogram bucket i-1.
+func nativeBuckets(spans []*dto.BucketSpan, deltas []int64) metrics.ExponentialBucketCounts {
+	var b metrics.ExponentialBucketCounts
+	if len(spans) == 0 {
+		return b
+	}
+
+	index := spans[0].GetOffset()
+	b.Offset = index - 1
+	var count int64
+	d := 0
+	for i, sp := range spans {
+		if i > 0 {
+			for range sp.GetOffset() {
+				b.Counts = append(b.Counts, 0)
+			}
+		}
+		for range sp.GetLength() {
+			if d < len(deltas) {
+				count += deltas[d]
+				d++
+			}
+			b.Counts = append(b.Counts, uint64(max(count, 0)))
+		}
+	}
+	return b
+}
+
+func convertType(t dto.MetricType) metrics.MetricType {
+	switch t {
+	case dto.MetricType_COUNTER:
+		return metrics.MetricTypeCounter
+	case dto.MetricType_GAUGE:
+		return metrics.MetricTypeGauge
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		return metrics.MetricTypeHistogram
+	case dto.MetricType_SUMMARY:
+		return metrics.MetricTypeSummary
+	default:
+		return metrics.MetricTypeUnknown
+	}
+}
+
+func convertLabels(pairs []*dto.LabelPair) metrics.Labels {
+	kv := make([]string, 0, len(pairs)*2)
+	for _, lp := range pairs {
+		kv = append(kv, lp.GetName(), lp.GetValue())
+	}
+	return metrics.NewLabels(kv...)
+}
+
+func convertExemplar(e *dto.Exemplar) *metrics.Exemplar {
+	if e == nil {
+		return nil
+	}
+	ex := &metrics.Exemplar{Labels: convertLabels(e.GetLabel()), Value: e.GetValue()}
+	if ts := e.GetTimestamp(); ts != nil {
+		ex.Timestamp = ts.AsTime()
+	}
+	return ex
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
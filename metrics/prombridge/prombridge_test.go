@@ -0,0 +1,84 @@
+package prombridge_test
+
+import (
+	"testing"
+
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/metrics/prombridge"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestGatherer(t *testing.T) {
+	r := metrics.NewRegistry(nil)
+	r.Counter("requests_total", "Requests.", "code").Add(3, "200")
+	r.ExponentialHistogram("latency_seconds", "Latency.", nil).Observe(0.5)
+
+	families, err := prombridge.Gatherer(r).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]*dto.MetricFamily)
+	for _, mf := range families {
+		byName[mf.GetName()] = mf
+	}
+
+	if got := byName["requests_total"].GetMetric()[0].GetCounter().GetValue(); got != 3 {
+		t.Errorf("expected counter value 3, got %g", got)
+	}
+	h := byName["latency_seconds"].GetMetric()[0].GetHistogram()
+	if h.Schema == nil || h.GetSampleCount() != 1 {
+		t.Errorf("expected a native histogram with one observation, got %v", h)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	jobs := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "jobs_total", Help: "Jobs."}, []string{"queue"})
+	jobs.WithLabelValues("default").Add(2)
+	sizes := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:                        "job_size_bytes",
+		Help:                        "Job sizes.",
+		Buckets:                     []float64{10, 100},
+		NativeHistogramBucketFactor: 1.1,
+	})
+	sizes.Observe(50)
+	quantiles := prometheus.NewSummary(prometheus.SummaryOpts{Name: "job_wait_seconds", Help: "Wait."})
+	quantiles.Observe(1)
+
+	r := metrics.NewRegistry(nil)
+	if err := prombridge.Register(r, jobs, sizes, quantiles); err != nil {
+		t.Fatal(err)
+	}
+	if err := prombridge.Register(r, prometheus.NewCounter(prometheus.CounterOpts{Name: "jobs_total"})); err == nil {
+		t.Error("expected an error registering a duplicate name")
+	}
+
+	families := make(map[string]metrics.MetricFamily)
+	for _, f := range r.Gather() {
+		families[f.Name] = f
+	}
+
+	jf := families["jobs_total"]
+	if jf.Type != metrics.MetricTypeCounter || len(jf.Samples) != 1 ||
+		jf.Samples[0].Value != 2 || jf.Samples[0].Labels.Get("queue") != "default" {
+		t.Errorf("unexpected jobs_total family %+v", jf)
+	}
+
+	sf := families["job_size_bytes"]
+	if len(sf.Samples) != 5 {
+		t.Errorf("expected 3 buckets plus sum and count, got %d samples", len(sf.Samples))
+	}
+	if len(sf.Exponential) != 1 || sf.Exponential[0].Count != 1 {
+		t.Fatalf("expected the native histogram to be bridged, got %+v", sf.Exponential)
+	}
+	p := sf.Exponential[0]
+	want := metrics.NewExponentialHistogram("want", "", &metrics.ExponentialHistogramOptions{MaxScale: &p.Scale})
+	want.Observe(50)
+	if w := want.CollectExponential()[0].Positive; p.Positive.Offset != w.Offset || len(p.Positive.Counts) != 1 {
+		t.Errorf("expected bucket %d, got %+v", w.Offset, p.Positive)
+	}
+
+	if wf := families["job_wait_seconds"]; wf.Type != metrics.MetricTypeSummary {
+		t.Errorf("expected a summary, got %v", wf.Type)
+	}
+}
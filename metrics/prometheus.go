@@ -1,13 +1,18 @@
 package metrics
 
 import (
-	"fmt"
+	"bufio"
 	"io"
 	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/kolosys/lumen/internal/enc"
 )
 
 // WritePrometheus writes samples in Prometheus text format.
+// Samples carry no metadata, so no HELP or TYPE lines are written;
+// use WriteFamilies for complete exposition output.
 func WritePrometheus(w io.Writer, samples []Sample) {
 	byName := make(map[string][]Sample)
 	for _, s := range samples {
@@ -27,41 +32,161 @@ func WritePrometheus(w io.Writer, samples []Sample) {
 	}
 	sort.Strings(names)
 
+	bw := bufio.NewWriter(w)
 	for _, name := range names {
 		for _, sample := range byName[name] {
-			writePrometheusSample(w, sample)
+			writePrometheusSample(bw, sample, false)
 		}
 	}
+	bw.Flush()
 }
 
-func writePrometheusSample(w io.Writer, s Sample) {
-	var sb strings.Builder
-	sb.WriteString(s.Name)
+// TextOptions configures WriteText.
+type TextOptions struct {
+	// Timestamps appends each sample's timestamp, in milliseconds.
+	Timestamps bool
+}
+
+// WriteFamilies writes metric families in Prometheus text format,
+// preceding each family with its # HELP and # TYPE lines.
+func WriteFamilies(w io.Writer, families []MetricFamily) error {
+	return WriteText(w, families, nil)
+}
+
+// WriteText is WriteFamilies with options.
+func WriteText(w io.Writer, families []MetricFamily, opts *TextOptions) error {
+	if opts == nil {
+		opts = &TextOptions{}
+	}
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		name := SanitizeMetricName(f.Name)
+		if f.Help != "" {
+			bw.WriteString("# HELP ")
+			bw.WriteString(name)
+			bw.WriteByte(' ')
+			bw.WriteString(enc.EscapeHelp(f.Help))
+			bw.WriteByte('\n')
+		}
+		bw.WriteString("# TYPE ")
+		bw.WriteString(name)
+		bw.WriteByte(' ')
+		bw.WriteString(prometheusType(f.Type))
+		bw.WriteByte('\n')
+
+		for _, s := range f.Samples {
+			writePrometheusSample(bw, s, opts.Timestamps)
+		}
+	}
+	return bw.Flush()
+}
+
+func writePrometheusSample(w *bufio.Writer, s Sample, timestamps bool) {
+	w.WriteString(SanitizeMetricName(s.Name))
 
 	if s.Labels.Len() > 0 {
-		sb.WriteByte('{')
+		w.WriteByte('{')
 		for i, key := range s.Labels.keys {
 			if i > 0 {
-				sb.WriteByte(',')
+				w.WriteByte(',')
 			}
-			sb.WriteString(key)
-			sb.WriteString(`="`)
-			sb.WriteString(escapeLabel(s.Labels.values[i]))
-			sb.WriteByte('"')
+			w.WriteString(sanitizeLabelName(key))
+			w.WriteString(`="`)
+			w.Write(enc.AppendLabelValue(w.AvailableBuffer(), s.Labels.values[i]))
+			w.WriteByte('"')
 		}
-		sb.WriteByte('}')
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(s.Value))
+	if timestamps && !s.Timestamp.IsZero() {
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatInt(s.Timestamp.UnixMilli(), 10))
+	}
+	w.WriteByte('\n')
+}
+
+func prometheusType(t MetricType) string {
+	switch t {
+	case MetricTypeCounter, MetricTypeGauge, MetricTypeHistogram, MetricTypeSummary:
+		return t.String()
+	case MetricTypeInfo, MetricTypeStateSet:
+		return "gauge"
+	default:
+		return "untyped"
 	}
+}
 
-	sb.WriteByte(' ')
-	sb.WriteString(fmt.Sprintf("%g", s.Value))
-	sb.WriteByte('\n')
+// ValidMetricName reports whether name matches [a-zA-Z_:][a-zA-Z0-9_:]*.
+func ValidMetricName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !isNameChar(c, true) && (i == 0 || !isDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidLabelName reports whether name matches [a-zA-Z_][a-zA-Z0-9_]*
+// and does not use the reserved "__" prefix.
+func ValidLabelName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "__") {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !isNameChar(c, false) && (i == 0 || !isDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// SanitizeMetricName replaces characters not allowed in a metric name
+// with underscores and prefixes names that start with a digit.
+func SanitizeMetricName(name string) string {
+	if ValidMetricName(name) {
+		return name
+	}
+	return sanitize(name, true)
+}
+
+func sanitizeLabelName(name string) string {
+	if name == "" || ValidLabelName(name) {
+		return name
+	}
+	return sanitize(name, false)
+}
+
+func sanitize(name string, colon bool) string {
+	if name == "" {
+		return "_"
+	}
+	var sb strings.Builder
+	sb.Grow(len(name) + 1)
+	if isDigit(name[0]) {
+		sb.WriteByte('_')
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if isNameChar(c, colon) || isDigit(c) {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
 
-	w.Write([]byte(sb.String()))
+func isNameChar(c byte, colon bool) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || colon && c == ':'
 }
 
-func escapeLabel(s string) string {
-	s = strings.ReplaceAll(s, `\`, `\\`)
-	s = strings.ReplaceAll(s, `"`, `\"`)
-	s = strings.ReplaceAll(s, "\n", `\n`)
-	return s
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
 }
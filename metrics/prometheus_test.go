@@ -0,0 +1,105 @@
+package metrics_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestWriteFamilies(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Counter("requests_total", "Requests \\ served.\nBy code.", "code").Add(3, "2\"00\n")
+	r.Gauge("temperature", "").Set(-1.5)
+	h := r.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	h.Observe(0.05, "/a")
+	h.Observe(2, "/a")
+
+	var buf strings.Builder
+	if err := WriteFamilies(&buf, r.Gather()); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1",route="/a"} 1
+latency_seconds_bucket{le="1",route="/a"} 1
+latency_seconds_bucket{le="+Inf",route="/a"} 2
+latency_seconds_sum{route="/a"} 2.05
+latency_seconds_count{route="/a"} 2
+# HELP requests_total Requests \\ served.\nBy code.
+# TYPE requests_total counter
+requests_total{code="2\"00\n"} 3
+# TYPE temperature gauge
+temperature -1.5
+`
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteFamiliesSanitizesNames(t *testing.T) {
+	families := []MetricFamily{{
+		Name: "http-requests",
+		Type: MetricTypeCounter,
+		Samples: []Sample{{
+			Name:   "http-requests",
+			Labels: NewLabels("status code", "200"),
+			Value:  1,
+		}},
+	}, {
+		Name:    "1st_value",
+		Type:    MetricType(-1),
+		Samples: []Sample{{Name: "1st_value", Value: 2}},
+	}}
+
+	var buf strings.Builder
+	if err := WriteFamilies(&buf, families); err != nil {
+		t.Fatal(err)
+	}
+	want := `# TYPE http_requests counter
+http_requests{status_code="200"} 1
+# TYPE _1st_value untyped
+_1st_value 2
+`
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestValidNames(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"requests_total": true,
+		"ns:requests":    true,
+		"_hidden":        true,
+		"a1":             true,
+		"":               false,
+		"1a":             false,
+		"http-requests":  false,
+		"résumé":         false,
+	} {
+		if got := ValidMetricName(name); got != valid {
+			t.Errorf("ValidMetricName(%q) = %v, want %v", name, got, valid)
+		}
+	}
+	for name, valid := range map[string]bool{
+		"code":     true,
+		"_private": true,
+		"__name__": false,
+		"ns:code":  false,
+		"1code":    false,
+		"":         false,
+	} {
+		if got := ValidLabelName(name); got != valid {
+			t.Errorf("ValidLabelName(%q) = %v, want %v", name, got, valid)
+		}
+	}
+
+	r := NewRegistry(nil)
+	if err := r.Register(NewCounter("http-requests", "")); !errors.Is(err, ErrInvalidMetricName) {
+		t.Errorf("expected ErrInvalidMetricName, got %v", err)
+	}
+	if err := r.Register(NewCounter("requests_total", "", "status code")); !errors.Is(err, ErrInvalidLabelName) {
+		t.Errorf("expected ErrInvalidLabelName, got %v", err)
+	}
+}
@@ -0,0 +1,377 @@
+package metrics
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// nativeMaxSchema is the highest resolution Prometheus native histograms
+// accept; finer exponential histograms are downscaled on output.
+const nativeMaxSchema = 8
+
+// Prometheus client model MetricType values.
+const (
+	protoTypeCounter   = 0
+	protoTypeGauge     = 1
+	protoTypeSummary   = 2
+	protoTypeUntyped   = 3
+	protoTypeHistogram = 4
+)
+
+// WriteProtobuf writes metric families as length-delimited
+// io.prometheus.client.MetricFamily messages, the only exposition format
+// that carries Prometheus native histograms. Exponential histogram data in
+// a family is written as a native histogram; other histograms use classic
+// buckets.
+func WriteProtobuf(w io.Writer, families []MetricFamily) error {
+	var msg, frame protoBuffer
+	for _, f := range families {
+		msg = msg[:0]
+		encodeFamily(&msg, f)
+
+		frame = frame[:0]
+		frame.varint(uint64(len(msg)))
+		frame = append(frame, msg...)
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeFamily(b *protoBuffer, f MetricFamily) {
+	b.string(1, SanitizeMetricName(f.Name))
+	if f.Help != "" {
+		b.string(2, f.Help)
+	}
+	if f.Unit != "" {
+		b.string(5, string(f.Unit))
+	}
+
+	switch f.Type {
+	case MetricTypeCounter:
+		b.uint(3, protoTypeCounter)
+		for _, s := range f.Samples {
+			b.message(4, func(m *protoBuffer) {
+				encodeLabels(m, 1, s.Labels)
+				m.message(3, func(c *protoBuffer) {
+					c.double(1, s.Value)
+					if s.Exemplar != nil {
+						c.message(2, func(e *protoBuffer) { encodeExemplar(e, s.Exemplar) })
+					}
+					encodeTimestamp(c, 3, s.Created)
+				})
+			})
+		}
+	case MetricTypeGauge, MetricTypeInfo, MetricTypeStateSet:
+		b.uint(3, protoTypeGauge)
+		for _, s := range f.Samples {
+			b.message(4, func(m *protoBuffer) {
+				encodeLabels(m, 1, s.Labels)
+				m.message(2, func(g *protoBuffer) { g.double(1, s.Value) })
+			})
+		}
+	case MetricTypeHistogram:
+		b.uint(3, protoTypeHistogram)
+		if len(f.Exponential) > 0 {
+			for _, p := range f.Exponential {
+				b.message(4, func(m *protoBuffer) {
+					encodeLabels(m, 1, p.Labels)
+					m.message(7, func(h *protoBuffer) { encodeNativeHistogram(h, p) })
+				})
+			}
+			return
+		}
+		encodeClassicHistograms(b, f)
+	case MetricTypeSummary:
+		b.uint(3, protoTypeSummary)
+		encodeSummaries(b, f)
+	default:
+		b.uint(3, protoTypeUntyped)
+		for _, s := range f.Samples {
+			b.message(4, func(m *protoBuffer) {
+				encodeLabels(m, 1, s.Labels)
+				m.message(5, func(u *protoBuffer) { u.double(1, s.Value) })
+			})
+		}
+	}
+}
+
+type classicSeries struct {
+	labels  Labels
+	created time.Time
+	count   float64
+	sum     float64
+	buckets []Sample
+}
+
+func encodeClassicHistograms(b *protoBuffer, f MetricFamily) {
+	var order []string
+	series := make(map[string]*classicSeries)
+
+	for _, s := range f.Samples {
+		key := seriesKey(s, "le")
+		cs, ok := series[key]
+		if !ok {
+			cs = &classicSeries{}
+			series[key] = cs
+			order = append(order, key)
+		}
+		switch s.Name {
+		case f.Name + "_bucket":
+			if s.Labels.Get("le") != "+Inf" {
+				cs.buckets = append(cs.buckets, s)
+			}
+		case f.Name + "_sum":
+			cs.sum = s.Value
+			cs.labels = s.Labels
+		case f.Name + "_count":
+			cs.count = s.Value
+			cs.labels = s.Labels
+			cs.created = s.Created
+		}
+	}
+
+	for _, key := range order {
+		cs := series[key]
+		b.message(4, func(m *protoBuffer) {
+			encodeLabels(m, 1, cs.labels)
+			m.message(7, func(h *protoBuffer) {
+				h.uint(1, uint64(cs.count))
+				h.double(2, cs.sum)
+				for _, bucket := range cs.buckets {
+					upper, err := strconv.ParseFloat(bucket.Labels.Get("le"), 64)
+					if err != nil {
+						continue
+					}
+					h.message(3, func(bb *protoBuffer) {
+						bb.uint(1, uint64(bucket.Value))
+						bb.double(2, upper)
+						if bucket.Exemplar != nil {
+							bb.message(3, func(e *protoBuffer) { encodeExemplar(e, bucket.Exemplar) })
+						}
+					})
+				}
+				encodeTimestamp(h, 15, cs.created)
+			})
+		})
+	}
+}
+
+type summarySeries struct {
+	labels    Labels
+	created   time.Time
+	count     float64
+	sum       float64
+	quantiles []Sample
+}
+
+func encodeSummaries(b *protoBuffer, f MetricFamily) {
+	var order []string
+	series := make(map[string]*summarySeries)
+
+	for _, s := range f.Samples {
+		key := seriesKey(s, "quantile")
+		ss, ok := series[key]
+		if !ok {
+			ss = &summarySeries{}
+			series[key] = ss
+			order = append(order, key)
+		}
+		switch s.Name {
+		case f.Name:
+			ss.quantiles = append(ss.quantiles, s)
+		case f.Name + "_sum":
+			ss.sum = s.Value
+			ss.labels = s.Labels
+		case f.Name + "_count":
+			ss.count = s.Value
+			ss.labels = s.Labels
+			ss.created = s.Created
+		}
+	}
+
+	for _, key := range order {
+		ss := series[key]
+		b.message(4, func(m *protoBuffer) {
+			encodeLabels(m, 1, ss.labels)
+			m.message(4, func(sm *protoBuffer) {
+				sm.uint(1, uint64(ss.count))
+				sm.double(2, ss.sum)
+				for _, q := range ss.quantiles {
+					quantile, err := strconv.ParseFloat(q.Labels.Get("quantile"), 64)
+					if err != nil {
+						continue
+					}
+					sm.message(3, func(qm *protoBuffer) {
+						qm.double(1, quantile)
+						qm.double(2, q.Value)
+					})
+				}
+				encodeTimestamp(sm, 4, ss.created)
+			})
+		})
+	}
+}
+
+func encodeNativeHistogram(h *protoBuffer, p ExponentialHistogramPoint) {
+	if p.Scale > nativeMaxSchema {
+		p = p.Downscale(nativeMaxSchema)
+	}
+
+	h.uint(1, p.Count)
+	h.double(2, p.Sum)
+	h.sint(5, int64(p.Scale))
+	h.double(6, p.ZeroThreshold)
+	h.uint(7, p.ZeroCount)
+	encodeTimestamp(h, 15, p.Created)
+
+	negSpans := encodeNativeBuckets(h, 9, 10, p.Negative)
+	posSpans := encodeNativeBuckets(h, 12, 13, p.Positive)
+	if !negSpans && !posSpans {
+		// An empty span marks the message as a native histogram even when
+		// every observation fell into the zero bucket.
+		h.message(12, func(*protoBuffer) {})
+	}
+}
+
+// encodeNativeBuckets writes counts as spans of populated buckets with
+// delta-encoded counts. Runs of more than two empty buckets start a new
+// span. Prometheus bucket i covers (base^(i-1), base^i], one above the
+// exponential histogram index of the same bucket.
+func encodeNativeBuckets(h *protoBuffer, spanField, deltaField int, b ExponentialBucketCounts) bool {
+	type span struct {
+		offset int32
+		length uint32
+	}
+	var spans []span
+	var deltas []int64
+	var prev int64
+	nextIndex := int32(0)
+
+	for i, c := range b.Counts {
+		if c == 0 {
+			continue
+		}
+		index := b.Offset + int32(i) + 1
+		gap := index - nextIndex
+		switch {
+		case len(spans) == 0:
+			spans = append(spans, span{offset: index})
+		case gap > 2:
+			spans = append(spans, span{offset: gap})
+		default:
+			for range gap {
+				deltas = append(deltas, -prev)
+				prev = 0
+			}
+			spans[len(spans)-1].length += uint32(gap)
+		}
+		spans[len(spans)-1].length++
+		deltas = append(deltas, int64(c)-prev)
+		prev = int64(c)
+		nextIndex = index + 1
+	}
+	if len(spans) == 0 {
+		return false
+	}
+
+	for _, sp := range spans {
+		h.message(spanField, func(s *protoBuffer) {
+			s.sint(1, int64(sp.offset))
+			s.uint(2, uint64(sp.length))
+		})
+	}
+	h.message(deltaField, func(d *protoBuffer) {
+		for _, delta := range deltas {
+			d.zigzag(delta)
+		}
+	})
+	return true
+}
+
+func encodeLabels(b *protoBuffer, field int, l Labels) {
+	for i, key := range l.keys {
+		b.message(field, func(lp *protoBuffer) {
+			lp.string(1, sanitizeLabelName(key))
+			lp.string(2, l.values[i])
+		})
+	}
+}
+
+func encodeExemplar(e *protoBuffer, ex *Exemplar) {
+	encodeLabels(e, 1, ex.Labels)
+	e.double(2, ex.Value)
+	encodeTimestamp(e, 3, ex.Timestamp)
+}
+
+// encodeTimestamp writes t as a google.protobuf.Timestamp, omitting zero
+// times.
+func encodeTimestamp(b *protoBuffer, field int, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	b.message(field, func(ts *protoBuffer) {
+		ts.int(1, t.Unix())
+		ts.int(2, int64(t.Nanosecond()))
+	})
+}
+
+// protoBuffer is a minimal protobuf wire-format encoder.
+type protoBuffer []byte
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func (b *protoBuffer) varint(v uint64) {
+	*b = binary.AppendUvarint(*b, v)
+}
+
+func (b *protoBuffer) zigzag(v int64) {
+	b.varint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (b *protoBuffer) tag(field, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (b *protoBuffer) uint(field int, v uint64) {
+	b.tag(field, wireVarint)
+	b.varint(v)
+}
+
+func (b *protoBuffer) int(field int, v int64) {
+	b.tag(field, wireVarint)
+	b.varint(uint64(v))
+}
+
+func (b *protoBuffer) sint(field int, v int64) {
+	b.tag(field, wireVarint)
+	b.zigzag(v)
+}
+
+func (b *protoBuffer) double(field int, v float64) {
+	b.tag(field, wireFixed64)
+	*b = binary.LittleEndian.AppendUint64(*b, math.Float64bits(v))
+}
+
+func (b *protoBuffer) string(field int, s string) {
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(s)))
+	*b = append(*b, s...)
+}
+
+// message encodes a nested message written by fn.
+func (b *protoBuffer) message(field int, fn func(*protoBuffer)) {
+	var inner protoBuffer
+	fn(&inner)
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(inner)))
+	*b = append(*b, inner...)
+}
@@ -0,0 +1,148 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+// recordingExporter is a PushExporter recording when it was called and
+// failing with err, if set.
+type recordingExporter struct {
+	mu      sync.Mutex
+	err     error
+	calls   []time.Time
+	samples []Sample
+}
+
+func (e *recordingExporter) Export(_ context.Context, samples []Sample) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, time.Now())
+	e.samples = samples
+	return e.err
+}
+
+func (e *recordingExporter) snapshot() []time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]time.Time(nil), e.calls...)
+}
+
+func TestPushRetriesWithGrowingBackoff(t *testing.T) {
+	errDown := errors.New("collector down")
+	exp := &recordingExporter{err: errDown}
+	type failure struct {
+		err   error
+		calls []time.Time
+	}
+	failures := make(chan failure, 8)
+	r := NewRegistry(&Options{
+		PushInterval:   10 * time.Millisecond,
+		PushExporter:   exp,
+		PushRetries:    3,
+		PushBackoff:    20 * time.Millisecond,
+		PushMaxBackoff: time.Second,
+		OnPushError: func(err error) {
+			failures <- failure{err, exp.snapshot()}
+		},
+	})
+	defer r.Close()
+	r.Counter("jobs_total", "Jobs.").Inc()
+
+	var f failure
+	select {
+	case f = <-failures:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnPushError was not called")
+	}
+	if !errors.Is(f.err, ErrExporterFailed) || !errors.Is(f.err, errDown) {
+		t.Errorf("OnPushError got %v, want it to wrap ErrExporterFailed and the export error", f.err)
+	}
+	if len(f.calls) != 4 {
+		t.Fatalf("expected 1 attempt and 3 retries, got %d attempts", len(f.calls))
+	}
+
+	// Delays fall in the upper half of a backoff doubling from 20ms.
+	backoff := 20 * time.Millisecond
+	for i := 1; i < len(f.calls); i++ {
+		if delay := f.calls[i].Sub(f.calls[i-1]); delay < backoff/2 {
+			t.Errorf("retry %d after %v, want at least %v", i, delay, backoff/2)
+		}
+		backoff *= 2
+	}
+	first, last := f.calls[1].Sub(f.calls[0]), f.calls[3].Sub(f.calls[2])
+	if last <= first {
+		t.Errorf("last retry delay %v not longer than the first %v", last, first)
+	}
+}
+
+func TestPushRetriesDisabled(t *testing.T) {
+	exp := &recordingExporter{err: errors.New("collector down")}
+	failed := make(chan int, 8)
+	r := NewRegistry(&Options{
+		PushInterval: 10 * time.Millisecond,
+		PushExporter: exp,
+		PushRetries:  -1,
+		OnPushError:  func(error) { failed <- len(exp.snapshot()) },
+	})
+	defer r.Close()
+
+	if n := <-failed; n != 1 {
+		t.Errorf("expected a single attempt, got %d", n)
+	}
+}
+
+func TestCloseFinalPush(t *testing.T) {
+	exp := &recordingExporter{}
+	r := NewRegistry(&Options{PushInterval: time.Hour, PushExporter: exp})
+	c := r.Counter("jobs_total", "Jobs.")
+	c.Inc()
+
+	r.Close()
+	r.Close()
+	if n := len(exp.snapshot()); n != 1 {
+		t.Fatalf("expected exactly one push on Close, got %d", n)
+	}
+	if len(exp.samples) != 1 || exp.samples[0].Name != "jobs_total" || exp.samples[0].Value != 1 {
+		t.Errorf("unexpected final samples: %+v", exp.samples)
+	}
+}
+
+func TestCloseFinalPushFailure(t *testing.T) {
+	errDown := errors.New("collector down")
+	exp := &recordingExporter{err: errDown}
+	var errs []error
+	r := NewRegistry(&Options{
+		PushInterval: time.Hour,
+		PushExporter: exp,
+		OnPushError:  func(err error) { errs = append(errs, err) },
+	})
+
+	r.Close()
+	if n := len(exp.snapshot()); n != 1 {
+		t.Errorf("expected the final push not to be retried, got %d attempts", n)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], errDown) {
+		t.Errorf("OnPushError got %v", errs)
+	}
+}
+
+func TestAdaptExporter(t *testing.T) {
+	var got []Sample
+	exp := AdaptExporter(exporterFunc(func(s []Sample) { got = s }))
+	if err := exp.Export(context.Background(), []Sample{{Name: "up", Value: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "up" {
+		t.Errorf("unexpected samples: %+v", got)
+	}
+}
+
+type exporterFunc func([]Sample)
+
+func (f exporterFunc) Export(s []Sample) { f(s) }
@@ -0,0 +1,215 @@
+package metrics_test
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestRegistryReturnsMatchingMetric(t *testing.T) {
+	r := NewRegistry(nil)
+	a := r.Counter("requests_total", "Requests.", "code")
+	b := r.Counter("requests_total", "Requests.", "code")
+	if a != b {
+		t.Fatal("expected duplicate registration to return the existing counter")
+	}
+
+	a.Inc("200")
+	if got := b.Value("200"); got != 1 {
+		t.Errorf("expected shared value 1, got %g", got)
+	}
+}
+
+func TestRegistryRejectsMismatchedDefinition(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Counter("requests_total", "Requests.", "code")
+
+	if _, err := r.CounterE("requests_total", "Requests.", "method"); !errors.Is(err, ErrMetricExists) {
+		t.Errorf("expected ErrMetricExists for different labels, got %v", err)
+	}
+	if _, err := r.GaugeE("requests_total", "Requests."); !errors.Is(err, ErrMetricExists) {
+		t.Errorf("expected ErrMetricExists for different type, got %v", err)
+	}
+	if _, err := r.HistogramE("latency", "", []float64{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.HistogramE("latency", "", []float64{1, 5}); !errors.Is(err, ErrMetricExists) {
+		t.Errorf("expected ErrMetricExists for different buckets, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected MustGauge to panic")
+		}
+	}()
+	r.MustGauge("requests_total", "")
+}
+
+func TestRegistryHistogramBuckets(t *testing.T) {
+	r := NewRegistry(&Options{
+		HistogramBuckets: []float64{1, 2},
+		BucketPresets:    map[string][]float64{"tiny": {0.5}},
+	})
+
+	buckets := func(h *Histogram) int {
+		h.Observe(0)
+		n := 0
+		for _, s := range h.Collect() {
+			if s.Name == h.Name()+"_bucket" {
+				n++
+			}
+		}
+		return n
+	}
+
+	if got := buckets(r.Histogram("default", "", nil)); got != 3 {
+		t.Errorf("expected registry buckets plus +Inf, got %d buckets", got)
+	}
+	h, err := r.HistogramPreset("custom", "", "tiny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buckets(h); got != 2 {
+		t.Errorf("expected custom preset buckets plus +Inf, got %d buckets", got)
+	}
+	h, err = r.HistogramPreset("latency", "", PresetLatency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buckets(h), len(PresetBuckets(PresetLatency))+1; got != want {
+		t.Errorf("expected %d latency buckets, got %d", want, got)
+	}
+	if _, err := r.HistogramPreset("bogus", "", "bogus"); !errors.Is(err, ErrUnknownPreset) {
+		t.Errorf("expected ErrUnknownPreset, got %v", err)
+	}
+}
+
+func TestRegistryRelabel(t *testing.T) {
+	r := NewRegistry(&Options{Relabel: []RelabelRule{
+		{Action: RelabelDrop, SourceLabel: MetricNameLabel, Regex: regexp.MustCompile(`^debug_`)},
+		{Action: RelabelDrop, SourceLabel: "path", Regex: regexp.MustCompile(`^/health$`)},
+		{Action: RelabelLabelDrop, Regex: regexp.MustCompile(`^pod$`)},
+		{Action: RelabelRename, SourceLabel: "code", TargetLabel: "status"},
+		{Action: RelabelHash, SourceLabel: "user", Modulus: 10},
+	}})
+	r.Counter("debug_events_total", "").Inc()
+	requests := r.Counter("requests_total", "", "path", "code", "pod", "user")
+	requests.Inc("/health", "200", "a", "u1")
+	requests.Inc("/api", "200", "a", "u1")
+
+	families := r.Gather()
+	if len(families) != 1 || families[0].Name != "requests_total" {
+		t.Fatalf("expected only requests_total, got %+v", families)
+	}
+	samples := families[0].Samples
+	if len(samples) != 1 {
+		t.Fatalf("expected the /health series to be dropped, got %d samples", len(samples))
+	}
+	l := samples[0].Labels
+	if l.Get("pod") != "" || l.Get("code") != "" || l.Get("status") != "200" {
+		t.Errorf("expected pod dropped and code renamed to status, got %v", l)
+	}
+	if u := l.Get("user"); u == "u1" || len(u) != 1 {
+		t.Errorf("expected user hashed into [0, 10), got %q", u)
+	}
+	if got := len(r.Collect()); got != 1 {
+		t.Errorf("expected Collect to relabel too, got %d samples", got)
+	}
+}
+
+func TestRegistrySelfMetrics(t *testing.T) {
+	r := NewRegistry(&Options{SelfMetrics: true, MaxSeriesPerMetric: 1, CardinalityReject: true})
+	c := r.Counter("requests_total", "", "code")
+	c.Inc("200")
+	c.Inc("500")
+	r.Gather()
+
+	values := make(map[string]float64)
+	for _, f := range r.Gather() {
+		for _, s := range f.Samples {
+			values[s.Name+s.Labels.Hash()] = s.Value
+		}
+	}
+	check := func(name string, labels Labels, want float64) {
+		t.Helper()
+		if got, ok := values[name+labels.Hash()]; !ok || got != want {
+			t.Errorf("expected %s%v = %g, got %g (present %v)", name, labels, want, got, ok)
+		}
+	}
+	check("lumen_metrics_series", NewLabels("metric", "requests_total"), 1)
+	check("lumen_metrics_dropped_samples_total", NewLabels("reason", "cardinality"), 1)
+	check("lumen_metrics_collect_duration_seconds_count", Labels{}, 1)
+	if values["lumen_metrics_registered"+Labels{}.Hash()] < 1 {
+		t.Error("expected lumen_metrics_registered to count metrics")
+	}
+}
+
+func TestRegistryStrictNaming(t *testing.T) {
+	r := NewRegistry(&Options{StrictNaming: true})
+
+	for _, m := range []Metric{
+		NewCounter("requests", ""),
+		NewGauge("queue_total", ""),
+		NewHistogram("latency_count", "", nil),
+		WithUnit(NewHistogram("latency", "", nil), UnitSeconds),
+	} {
+		if err := r.Register(m); !errors.Is(err, ErrNamingConvention) {
+			t.Errorf("expected ErrNamingConvention for %s, got %v", m.Name(), err)
+		}
+	}
+	for _, m := range []Metric{
+		NewCounter("requests_total", ""),
+		WithUnit(NewCounter("sent_bytes_total", ""), UnitBytes),
+		WithUnit(NewHistogram("latency_seconds", "", nil), UnitSeconds),
+	} {
+		if err := r.Register(m); err != nil {
+			t.Errorf("expected %s to be accepted, got %v", m.Name(), err)
+		}
+	}
+
+	var buf strings.Builder
+	if err := WriteOpenMetrics(&buf, r.Gather(), nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"# UNIT latency_seconds seconds\n", "# UNIT sent_bytes bytes\n"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in output:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestRegistryJobMetrics(t *testing.T) {
+	r := NewRegistry(nil)
+	job := r.JobMetrics("backup", "target")
+
+	if err := job.Run(func() error { return nil }, "db"); err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("disk full")
+	if err := job.Run(func() error { return failed }, "db"); err != failed {
+		t.Fatalf("expected the job's error, got %v", err)
+	}
+
+	if got := job.Runs.Value("db", JobSuccess); got != 1 {
+		t.Errorf("expected 1 successful run, got %g", got)
+	}
+	if got := job.Runs.Value("db", JobFailure); got != 1 {
+		t.Errorf("expected 1 failed run, got %g", got)
+	}
+	if job.LastRun.Time("db").Before(job.LastSuccess.Time("db")) {
+		t.Error("expected the failed run to advance the last run time")
+	}
+	if since := job.LastSuccess.Since("db"); since <= 0 || since > time.Minute {
+		t.Errorf("unexpected time since last success: %v", since)
+	}
+	if r.TimestampGauge("backup_last_success_timestamp_seconds", "", "target") != job.LastSuccess {
+		t.Error("expected the registered timestamp gauge to be returned")
+	}
+	if job.LastSuccess.Unit() != UnitSeconds {
+		t.Errorf("expected seconds unit, got %q", job.LastSuccess.Unit())
+	}
+}
@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strconv"
+)
+
+// RelabelAction selects what a RelabelRule does.
+type RelabelAction int
+
+const (
+	// RelabelDrop drops series whose SourceLabel value matches Regex.
+	RelabelDrop RelabelAction = iota
+	// RelabelKeep drops series whose SourceLabel value does not match Regex.
+	RelabelKeep
+	// RelabelLabelDrop removes labels whose name matches Regex.
+	RelabelLabelDrop
+	// RelabelLabelKeep removes labels whose name does not match Regex.
+	RelabelLabelKeep
+	// RelabelRename moves the value of SourceLabel to TargetLabel.
+	RelabelRename
+	// RelabelHash replaces the value of SourceLabel with its FNV-1a hash,
+	// reduced modulo Modulus when set, to bound or anonymize
+	// high-cardinality values.
+	RelabelHash
+)
+
+// MetricNameLabel is the SourceLabel that refers to a sample's metric name.
+const MetricNameLabel = "__name__"
+
+// RelabelRule is one step of the relabeling pipeline set in
+// Options.Relabel, modelled on Prometheus relabel_config.
+type RelabelRule struct {
+	Action RelabelAction
+
+	// SourceLabel is the label RelabelDrop, RelabelKeep, RelabelRename and
+	// RelabelHash act on; MetricNameLabel matches the metric name.
+	SourceLabel string
+
+	// Regex is matched against the full value (or label name for
+	// RelabelLabelDrop and RelabelLabelKeep). A nil Regex matches
+	// everything. Anchor it with ^ and $ to avoid partial matches.
+	Regex *regexp.Regexp
+
+	// TargetLabel is the new name for RelabelRename.
+	TargetLabel string
+
+	// Modulus bounds RelabelHash results to [0, Modulus) when non-zero.
+	Modulus uint64
+
+	// Match, if set, decides RelabelDrop and RelabelKeep in place of
+	// SourceLabel and Regex, e.g. the MatchSeries method of a compiled
+	// expr.Expr.
+	Match func(name string, labels Labels) bool
+}
+
+func (rule *RelabelRule) match(s string) bool {
+	return rule.Regex == nil || rule.Regex.MatchString(s)
+}
+
+// relabel applies rules to one series. It returns false if the series is
+// dropped.
+func relabel(rules []RelabelRule, name string, labels Labels) (Labels, bool) {
+	changed := false
+	keys, values := labels.keys, labels.values
+
+	get := func(key string) (string, int) {
+		if key == MetricNameLabel {
+			return name, -1
+		}
+		for i, k := range keys {
+			if k == key {
+				return values[i], i
+			}
+		}
+		return "", -1
+	}
+	clone := func() {
+		if !changed {
+			keys = append([]string(nil), keys...)
+			values = append([]string(nil), values...)
+			changed = true
+		}
+	}
+	remove := func(i int) {
+		clone()
+		keys = append(keys[:i], keys[i+1:]...)
+		values = append(values[:i], values[i+1:]...)
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.Action {
+		case RelabelDrop, RelabelKeep:
+			var matched bool
+			if rule.Match != nil {
+				matched = rule.Match(name, Labels{keys: keys, values: values})
+			} else {
+				v, _ := get(rule.SourceLabel)
+				matched = rule.match(v)
+			}
+			if matched == (rule.Action == RelabelDrop) {
+				return Labels{}, false
+			}
+		case RelabelLabelDrop, RelabelLabelKeep:
+			for j := len(keys) - 1; j >= 0; j-- {
+				if rule.match(keys[j]) == (rule.Action == RelabelLabelDrop) {
+					remove(j)
+				}
+			}
+		case RelabelRename:
+			v, j := get(rule.SourceLabel)
+			if j < 0 || rule.TargetLabel == "" {
+				continue
+			}
+			remove(j)
+			if _, t := get(rule.TargetLabel); t >= 0 {
+				remove(t)
+			}
+			keys = append(keys, rule.TargetLabel)
+			values = append(values, v)
+		case RelabelHash:
+			v, j := get(rule.SourceLabel)
+			if j < 0 || !rule.match(v) {
+				continue
+			}
+			h := fnv.New64a()
+			h.Write([]byte(v))
+			sum := h.Sum64()
+			clone()
+			if rule.Modulus > 0 {
+				values[j] = strconv.FormatUint(sum%rule.Modulus, 10)
+			} else {
+				values[j] = strconv.FormatUint(sum, 16)
+			}
+		}
+	}
+
+	if !changed {
+		return labels, true
+	}
+	out := Labels{keys: keys, values: values}
+	out.sort()
+	return out, true
+}
+
+func (r *Registry) relabelSamples(samples []Sample) []Sample {
+	if len(r.opts.Relabel) == 0 {
+		return samples
+	}
+	kept := samples[:0]
+	for _, s := range samples {
+		labels, ok := relabel(r.opts.Relabel, s.Name, s.Labels)
+		if !ok {
+			continue
+		}
+		s.Labels = labels
+		kept = append(kept, s)
+	}
+	r.self.drop("relabel", len(samples)-len(kept))
+	return kept
+}
+
+// relabelFamilies relabels every family, dropping those left without
+// series.
+func (r *Registry) relabelFamilies(families []MetricFamily) []MetricFamily {
+	if len(r.opts.Relabel) == 0 {
+		return families
+	}
+	kept := families[:0]
+	for _, f := range families {
+		had := len(f.Samples) + len(f.Exponential)
+		f.Samples = r.relabelSamples(f.Samples)
+		points := f.Exponential[:0]
+		for _, p := range f.Exponential {
+			labels, ok := relabel(r.opts.Relabel, f.Name, p.Labels)
+			if !ok {
+				continue
+			}
+			p.Labels = labels
+			points = append(points, p)
+		}
+		f.Exponential = points
+		if had > 0 && len(f.Samples)+len(f.Exponential) == 0 {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
@@ -0,0 +1,160 @@
+package metrics_test
+
+import (
+	"hash/fnv"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+// relabeled returns the series of requests_total after applying rules,
+// rendered as name{k=v,...} and sorted.
+func relabeled(rules []RelabelRule) []string {
+	r := NewRegistry(&Options{Relabel: rules})
+	c := r.Counter("requests_total", "", "path", "code", "user")
+	c.Inc("/api", "200", "u1")
+	c.Inc("/health", "500", "u2")
+
+	var series []string
+	for _, s := range r.Collect() {
+		pairs := make([]string, s.Labels.Len())
+		for i, k := range s.Labels.Keys() {
+			pairs[i] = k + "=" + s.Labels.Values()[i]
+		}
+		series = append(series, s.Name+"{"+strings.Join(pairs, ",")+"}")
+	}
+	slices.Sort(series)
+	return series
+}
+
+func fnvHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+func TestRelabelRules(t *testing.T) {
+	var (
+		api    = "requests_total{code=200,path=/api,user=u1}"
+		health = "requests_total{code=500,path=/health,user=u2}"
+	)
+	tests := []struct {
+		name  string
+		rules []RelabelRule
+		want  []string
+	}{
+		{"no rules", nil, []string{api, health}},
+		{
+			"drop",
+			[]RelabelRule{{Action: RelabelDrop, SourceLabel: "path", Regex: regexp.MustCompile(`^/health$`)}},
+			[]string{api},
+		},
+		{
+			"drop on a missing label matches the empty value",
+			[]RelabelRule{{Action: RelabelDrop, SourceLabel: "region", Regex: regexp.MustCompile(`^$`)}},
+			nil,
+		},
+		{
+			"drop by metric name",
+			[]RelabelRule{{Action: RelabelDrop, SourceLabel: MetricNameLabel, Regex: regexp.MustCompile(`^requests_`)}},
+			nil,
+		},
+		{
+			"keep",
+			[]RelabelRule{{Action: RelabelKeep, SourceLabel: "code", Regex: regexp.MustCompile(`^2..$`)}},
+			[]string{api},
+		},
+		{
+			"keep with nil regex",
+			[]RelabelRule{{Action: RelabelKeep, SourceLabel: "code"}},
+			[]string{api, health},
+		},
+		{
+			"match func",
+			[]RelabelRule{{Action: RelabelDrop, Match: func(name string, l Labels) bool {
+				return name == "requests_total" && l.Get("user") == "u1"
+			}}},
+			[]string{health},
+		},
+		{
+			"labeldrop",
+			[]RelabelRule{{Action: RelabelLabelDrop, Regex: regexp.MustCompile(`^(user|code)$`)}},
+			[]string{"requests_total{path=/api}", "requests_total{path=/health}"},
+		},
+		{
+			"labelkeep",
+			[]RelabelRule{{Action: RelabelLabelKeep, Regex: regexp.MustCompile(`^code$`)}},
+			[]string{"requests_total{code=200}", "requests_total{code=500}"},
+		},
+		{
+			"rename",
+			[]RelabelRule{{Action: RelabelRename, SourceLabel: "code", TargetLabel: "status"}},
+			[]string{
+				"requests_total{path=/api,status=200,user=u1}",
+				"requests_total{path=/health,status=500,user=u2}",
+			},
+		},
+		{
+			"rename over an existing label",
+			[]RelabelRule{{Action: RelabelRename, SourceLabel: "path", TargetLabel: "user"}},
+			[]string{"requests_total{code=200,user=/api}", "requests_total{code=500,user=/health}"},
+		},
+		{
+			"rename of a missing label",
+			[]RelabelRule{{Action: RelabelRename, SourceLabel: "region", TargetLabel: "zone"}},
+			[]string{api, health},
+		},
+		{
+			"hash",
+			[]RelabelRule{{Action: RelabelHash, SourceLabel: "user", Regex: regexp.MustCompile(`^u1$`)}},
+			[]string{
+				"requests_total{code=200,path=/api,user=" + strconv.FormatUint(fnvHash("u1"), 16) + "}",
+				health,
+			},
+		},
+		{
+			"hash with modulus",
+			[]RelabelRule{{Action: RelabelHash, SourceLabel: "user", Modulus: 7}},
+			[]string{
+				"requests_total{code=200,path=/api,user=" + strconv.FormatUint(fnvHash("u1")%7, 10) + "}",
+				"requests_total{code=500,path=/health,user=" + strconv.FormatUint(fnvHash("u2")%7, 10) + "}",
+			},
+		},
+		{
+			"rules apply in order",
+			[]RelabelRule{
+				{Action: RelabelRename, SourceLabel: "code", TargetLabel: "status"},
+				{Action: RelabelDrop, SourceLabel: "status", Regex: regexp.MustCompile(`^5`)},
+				{Action: RelabelDrop, SourceLabel: "code", Regex: regexp.MustCompile(`^2`)},
+			},
+			[]string{"requests_total{path=/api,status=200,user=u1}"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := relabeled(tc.rules); !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRelabelFamilies(t *testing.T) {
+	r := NewRegistry(&Options{Relabel: []RelabelRule{
+		{Action: RelabelDrop, SourceLabel: "path", Regex: regexp.MustCompile(`^/health$`)},
+	}})
+	r.Counter("probes_total", "", "path").Inc("/health")
+	r.Counter("requests_total", "", "path")
+
+	var names []string
+	for _, f := range r.Gather() {
+		names = append(names, f.Name)
+	}
+	if !slices.Equal(names, []string{"requests_total"}) {
+		t.Errorf("expected families emptied by relabeling to be dropped and empty ones kept, got %v", names)
+	}
+}
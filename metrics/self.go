@@ -0,0 +1,107 @@
+package metrics
+
+import "time"
+
+// selfMetrics reports on the registry itself when Options.SelfMetrics is
+// set: what it holds, how long collection takes and what it loses.
+type selfMetrics struct {
+	collect *Histogram
+	pushes  *Counter
+	dropped *Counter
+}
+
+func newSelfMetrics(r *Registry) *selfMetrics {
+	s := &selfMetrics{
+		collect: NewHistogram("lumen_metrics_collect_duration_seconds",
+			"Time taken to collect the registry.", PresetBuckets(PresetLatency)),
+		pushes: NewCounter("lumen_metrics_pushes_total",
+			"Push attempts by outcome, after retries.", "result"),
+		dropped: NewCounter("lumen_metrics_dropped_samples_total",
+			"Samples or observations discarded, by reason.", "reason"),
+	}
+	registered := NewGaugeFunc("lumen_metrics_registered", "Number of registered metrics.", func() float64 {
+		n := 0
+		r.eachMetric("", func(string, Metric) { n++ })
+		return float64(n)
+	})
+	series := &seriesGauge{r: r}
+
+	for _, m := range []Metric{s.collect, s.pushes, s.dropped, registered, series} {
+		r.metrics.Store(m.Name(), m)
+	}
+	return s
+}
+
+func (s *selfMetrics) observeCollect(start time.Time) {
+	if s != nil {
+		s.collect.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (s *selfMetrics) pushed(err error, samples int) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		s.pushes.Inc("failure")
+		s.drop("push", samples)
+		return
+	}
+	s.pushes.Inc("success")
+}
+
+func (s *selfMetrics) drop(reason string, n int) {
+	if s != nil && n > 0 {
+		s.dropped.Add(float64(n), reason)
+	}
+}
+
+// seriesCounter is implemented by the metric types that track their series
+// count.
+type seriesCounter interface {
+	seriesCount() int64
+}
+
+func (c *cardinality) seriesCount() int64 { return c.series.Load() }
+
+// seriesGauge reports the number of series held by each metric in the
+// registry tree.
+type seriesGauge struct {
+	r *Registry
+}
+
+func (g *seriesGauge) Name() string         { return "lumen_metrics_series" }
+func (g *seriesGauge) Help() string         { return "Number of series held, by metric." }
+func (g *seriesGauge) Type() MetricType     { return MetricTypeGauge }
+func (g *seriesGauge) LabelNames() []string { return []string{"metric"} }
+
+func (g *seriesGauge) Collect() []Sample {
+	var samples []Sample
+	now := time.Now()
+	g.r.eachMetric("", func(name string, m Metric) {
+		sc, ok := m.(seriesCounter)
+		if !ok {
+			return
+		}
+		samples = append(samples, Sample{
+			Name:      g.Name(),
+			Labels:    NewLabels("metric", name),
+			Value:     float64(sc.seriesCount()),
+			Timestamp: now,
+		})
+	})
+	return samples
+}
+
+// eachMetric calls fn for every metric in the registry and its
+// sub-registries, with the name it is exposed under below r.
+func (r *Registry) eachMetric(prefix string, fn func(name string, m Metric)) {
+	r.metrics.Range(func(_, value any) bool {
+		m := value.(Metric)
+		fn(prefix+m.Name(), m)
+		return true
+	})
+	for _, child := range r.subs() {
+		child.eachMetric(prefix+child.opts.Prefix, fn)
+	}
+}
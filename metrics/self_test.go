@@ -0,0 +1,91 @@
+package metrics_test
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+// selfValues gathers r and returns the lumen_metrics_* samples keyed by
+// name and label hash.
+func selfValues(r *Registry) map[string]float64 {
+	values := make(map[string]float64)
+	for _, f := range r.Gather() {
+		for _, s := range f.Samples {
+			if strings.HasPrefix(s.Name, "lumen_metrics_") {
+				values[s.Name+s.Labels.Hash()] = s.Value
+			}
+		}
+	}
+	return values
+}
+
+func TestSelfMetricsDrops(t *testing.T) {
+	r := NewRegistry(&Options{SelfMetrics: true, Relabel: []RelabelRule{
+		{Action: RelabelDrop, SourceLabel: "path", Regex: regexp.MustCompile(`^/health$`)},
+	}})
+	c := r.Counter("requests_total", "", "path")
+	c.Inc("/health")
+	c.Inc("/api")
+	c.Add(-1, "/api")
+	r.Sub("db_").Counter("queries_total", "", "op").Inc("select")
+	r.Gather() // relabel drops are counted while gathering
+
+	values := selfValues(r)
+	for key, want := range map[string]float64{
+		"lumen_metrics_dropped_samples_total" + NewLabels("reason", "relabel").Hash(): 1,
+		"lumen_metrics_dropped_samples_total" + NewLabels("reason", "invalid").Hash(): 1,
+		"lumen_metrics_series" + NewLabels("metric", "requests_total").Hash():         2,
+		"lumen_metrics_series" + NewLabels("metric", "db_queries_total").Hash():       1,
+	} {
+		if got, ok := values[key]; !ok || got != want {
+			t.Errorf("%s = %g (present %v), want %g", key, got, ok, want)
+		}
+	}
+}
+
+func TestSelfMetricsPushes(t *testing.T) {
+	exp := &recordingExporter{}
+	r := NewRegistry(&Options{
+		SelfMetrics:  true,
+		PushInterval: time.Hour,
+		PushExporter: exp,
+	})
+	r.Counter("jobs_total", "").Inc()
+	r.Close()
+
+	values := selfValues(r)
+	if got := values["lumen_metrics_pushes_total"+NewLabels("result", "success").Hash()]; got != 1 {
+		t.Errorf("successful pushes = %g, want 1", got)
+	}
+
+	exp = &recordingExporter{err: errors.New("collector down")}
+	r = NewRegistry(&Options{
+		SelfMetrics:  true,
+		PushInterval: time.Hour,
+		PushExporter: exp,
+		OnPushError:  func(error) {},
+	})
+	r.Counter("jobs_total", "").Inc()
+	r.Close()
+
+	values = selfValues(r)
+	if got := values["lumen_metrics_pushes_total"+NewLabels("result", "failure").Hash()]; got != 1 {
+		t.Errorf("failed pushes = %g, want 1", got)
+	}
+	if got := values["lumen_metrics_dropped_samples_total"+NewLabels("reason", "push").Hash()]; got != float64(len(exp.samples)) || got == 0 {
+		t.Errorf("samples dropped by the failed push = %g, want %d", got, len(exp.samples))
+	}
+}
+
+func TestSelfMetricsDisabled(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Counter("requests_total", "").Inc()
+	if values := selfValues(r); len(values) != 0 {
+		t.Errorf("expected no self-metrics by default, got %v", values)
+	}
+}
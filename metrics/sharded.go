@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ShardedCounter is a counter for very hot paths. Each series spreads its
+// increments over several cache-line-padded cells so goroutines on
+// different CPUs rarely contend, and Collect sums the cells. It trades
+// memory and read cost for write throughput; prefer Counter unless
+// profiling shows contention on a counter.
+type ShardedCounter struct {
+	name       string
+	help       string
+	labelNames []string
+	shards     int
+	values     sync.Map
+	cardinality
+	unitMeta
+	invalidReporting
+}
+
+type shardedValue struct {
+	labels  Labels
+	created time.Time
+	cells   []paddedCell
+}
+
+// paddedCell keeps each counterCell on its own cache line.
+type paddedCell struct {
+	counterCell
+	_ [64 - 16]byte
+}
+
+// NewShardedCounter creates a sharded counter with one shard per
+// GOMAXPROCS, rounded up to a power of two.
+func NewShardedCounter(name, help string, labelNames ...string) *ShardedCounter {
+	shards := 1
+	for shards < runtime.GOMAXPROCS(0) {
+		shards <<= 1
+	}
+
+	return &ShardedCounter{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		shards:     shards,
+	}
+}
+
+func (c *ShardedCounter) Name() string         { return c.name }
+func (c *ShardedCounter) Help() string         { return c.help }
+func (c *ShardedCounter) Type() MetricType     { return MetricTypeCounter }
+func (c *ShardedCounter) LabelNames() []string { return c.labelNames }
+
+// Inc increments by 1.
+func (c *ShardedCounter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments by the given value. Negative and NaN deltas are rejected
+// as for Counter.Add.
+func (c *ShardedCounter) Add(delta float64, labelValues ...string) {
+	if c.checkDelta(c.name, delta) != nil {
+		return
+	}
+
+	sv := loadSeries(&c.values, &c.cardinality, c.name, c.makeLabels(labelValues), c.newShardedValue)
+	if sv == nil {
+		return
+	}
+
+	// rand.Uint32 reads per-thread state, so concurrent callers tend to
+	// land on different cells without coordinating.
+	sv.cells[rand.Uint32()&uint32(c.shards-1)].add(delta)
+}
+
+func (c *ShardedCounter) newShardedValue(labels Labels) *shardedValue {
+	return &shardedValue{labels: labels, created: time.Now(), cells: make([]paddedCell, c.shards)}
+}
+
+func (sv *shardedValue) load() float64 {
+	var total float64
+	for i := range sv.cells {
+		total += sv.cells[i].load()
+	}
+	return total
+}
+
+// Value returns the current value for the given labels.
+func (c *ShardedCounter) Value(labelValues ...string) float64 {
+	labels := c.makeLabels(labelValues)
+	if val, ok := c.values.Load(labels.Hash()); ok {
+		return val.(*shardedValue).load()
+	}
+	return 0
+}
+
+// Collect returns all samples.
+func (c *ShardedCounter) Collect() []Sample {
+	var samples []Sample
+	now := time.Now()
+
+	c.values.Range(func(_, value any) bool {
+		sv := value.(*shardedValue)
+		samples = append(samples, Sample{
+			Name:      c.name,
+			Labels:    sv.labels,
+			Value:     sv.load(),
+			Timestamp: now,
+			Created:   sv.created,
+		})
+		return true
+	})
+
+	return samples
+}
+
+// Reset resets all counter values.
+func (c *ShardedCounter) Reset() {
+	c.values.Range(func(key, _ any) bool {
+		c.values.Delete(key)
+		return true
+	})
+	c.series.Store(0)
+}
+
+func (c *ShardedCounter) makeLabels(values []string) Labels {
+	if len(c.labelNames) == 0 {
+		return Labels{}
+	}
+
+	if len(values) != len(c.labelNames) {
+		if len(values) < len(c.labelNames) {
+			padded := make([]string, len(c.labelNames))
+			copy(padded, values)
+			values = padded
+		} else {
+			values = values[:len(c.labelNames)]
+		}
+	}
+
+	pairs := make([]string, 0, len(c.labelNames)*2)
+	for i, name := range c.labelNames {
+		pairs = append(pairs, name, values[i])
+	}
+	return NewLabels(pairs...)
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DBNameLabel is the label identifying the database in DBStatsCollector
+// metrics.
+const DBNameLabel = "db_name"
+
+// dbStatsMaxAge bounds how long a snapshot is reused, so the metrics of
+// one scrape come from a single consistent DBStats call per database.
+const dbStatsMaxAge = 100 * time.Millisecond
+
+// DBStatsCollector exports the connection pool statistics of one or more
+// *sql.DB handles, labeled by database name:
+//
+//	c, _ := metrics.NewDBStatsCollector(registry)
+//	c.Add("users", usersDB)
+type DBStatsCollector struct {
+	mu       sync.Mutex
+	dbs      map[string]*sql.DB
+	snapshot map[string]sql.DBStats
+	taken    time.Time
+}
+
+type dbStatsMetric struct {
+	name  string
+	help  string
+	typ   MetricType
+	value func(sql.DBStats) float64
+	c     *DBStatsCollector
+}
+
+// NewDBStatsCollector creates a collector and registers its metrics in r.
+func NewDBStatsCollector(r *Registry) (*DBStatsCollector, error) {
+	c := &DBStatsCollector{dbs: make(map[string]*sql.DB)}
+
+	gauge := func(name, help string, fn func(sql.DBStats) float64) *dbStatsMetric {
+		return &dbStatsMetric{name: name, help: help, typ: MetricTypeGauge, value: fn, c: c}
+	}
+	counter := func(name, help string, fn func(sql.DBStats) float64) *dbStatsMetric {
+		return &dbStatsMetric{name: name, help: help, typ: MetricTypeCounter, value: fn, c: c}
+	}
+
+	ms := []*dbStatsMetric{
+		gauge("go_sql_max_open_connections", "Maximum number of open connections to the database.",
+			func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }),
+		gauge("go_sql_open_connections", "The number of established connections both in use and idle.",
+			func(s sql.DBStats) float64 { return float64(s.OpenConnections) }),
+		gauge("go_sql_in_use_connections", "The number of connections currently in use.",
+			func(s sql.DBStats) float64 { return float64(s.InUse) }),
+		gauge("go_sql_idle_connections", "The number of idle connections.",
+			func(s sql.DBStats) float64 { return float64(s.Idle) }),
+		counter("go_sql_wait_count_total", "The total number of connections waited for.",
+			func(s sql.DBStats) float64 { return float64(s.WaitCount) }),
+		counter("go_sql_wait_duration_seconds_total", "The total time blocked waiting for a new connection.",
+			func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }),
+		counter("go_sql_max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns.",
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }),
+		counter("go_sql_max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime.",
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }),
+		counter("go_sql_max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime.",
+			func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }),
+	}
+
+	for i, m := range ms {
+		if err := r.Register(m); err != nil {
+			for _, registered := range ms[:i] {
+				r.Unregister(registered.name)
+			}
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Add starts collecting stats for db under the given name, replacing any
+// database previously added with that name.
+func (c *DBStatsCollector) Add(name string, db *sql.DB) {
+	c.mu.Lock()
+	c.dbs[name] = db
+	c.taken = time.Time{}
+	c.mu.Unlock()
+}
+
+// Remove stops collecting stats for the named database.
+func (c *DBStatsCollector) Remove(name string) {
+	c.mu.Lock()
+	delete(c.dbs, name)
+	c.taken = time.Time{}
+	c.mu.Unlock()
+}
+
+func (c *DBStatsCollector) stats() (map[string]sql.DBStats, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.taken) > dbStatsMaxAge {
+		c.snapshot = make(map[string]sql.DBStats, len(c.dbs))
+		for name, db := range c.dbs {
+			c.snapshot[name] = db.Stats()
+		}
+		c.taken = now
+	}
+	return c.snapshot, c.taken
+}
+
+func (m *dbStatsMetric) Name() string         { return m.name }
+func (m *dbStatsMetric) Help() string         { return m.help }
+func (m *dbStatsMetric) Type() MetricType     { return m.typ }
+func (m *dbStatsMetric) LabelNames() []string { return []string{DBNameLabel} }
+
+func (m *dbStatsMetric) Collect() []Sample {
+	snapshot, taken := m.c.stats()
+
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	samples := make([]Sample, 0, len(names))
+	for _, name := range names {
+		samples = append(samples, Sample{
+			Name:      m.name,
+			Labels:    NewLabels(DBNameLabel, name),
+			Value:     m.value(snapshot[name]),
+			Timestamp: taken,
+		})
+	}
+	return samples
+}
@@ -0,0 +1,69 @@
+package metrics_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+// nopConnector opens no connections; the pool stats are still reported.
+type nopConnector struct{}
+
+func (nopConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("no connections")
+}
+func (nopConnector) Driver() driver.Driver { return nil }
+
+func TestDBStatsCollector(t *testing.T) {
+	r := NewRegistry(nil)
+	c, err := NewDBStatsCollector(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, orders := sql.OpenDB(nopConnector{}), sql.OpenDB(nopConnector{})
+	defer users.Close()
+	defer orders.Close()
+	users.SetMaxOpenConns(5)
+	orders.SetMaxOpenConns(10)
+	c.Add("users", users)
+	c.Add("orders", orders)
+
+	got := seriesOf(r, "go_sql_max_open_connections")
+	if len(got) != 2 || got["[users]"] != 5 || got["[orders]"] != 10 {
+		t.Errorf("max open connections = %v", got)
+	}
+	if got := seriesOf(r, "go_sql_wait_count_total"); len(got) != 2 || got["[users]"] != 0 {
+		t.Errorf("wait count = %v", got)
+	}
+
+	c.Remove("orders")
+	if got := seriesOf(r, "go_sql_open_connections"); len(got) != 1 || got["[users]"] != 0 {
+		t.Errorf("after Remove: %v", got)
+	}
+
+	for _, f := range r.Gather() {
+		if f.Name == "go_sql_wait_count_total" && f.Type != MetricTypeCounter ||
+			f.Name == "go_sql_idle_connections" && f.Type != MetricTypeGauge {
+			t.Errorf("%s has type %v", f.Name, f.Type)
+		}
+	}
+}
+
+func TestDBStatsCollectorRollsBack(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Gauge("go_sql_idle_connections", "")
+
+	if _, err := NewDBStatsCollector(r); !errors.Is(err, ErrMetricExists) {
+		t.Fatalf("expected ErrMetricExists, got %v", err)
+	}
+	for _, name := range []string{"go_sql_max_open_connections", "go_sql_in_use_connections"} {
+		if _, err := r.Get(name); !errors.Is(err, ErrMetricNotFound) {
+			t.Errorf("expected %s to be unregistered after the failure", name)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// staleNaNBits is the NaN payload Prometheus uses as a staleness marker.
+const staleNaNBits uint64 = 0x7ff0000000000002
+
+// StaleNaN is the sample value marking a series as stale. Remote-write
+// pipelines end the series at that point instead of interpolating across
+// the gap.
+var StaleNaN = math.Float64frombits(staleNaNBits)
+
+// IsStaleNaN reports whether v is the staleness marker.
+func IsStaleNaN(v float64) bool {
+	return math.Float64bits(v) == staleNaNBits
+}
+
+// StalenessTracker remembers the series of successive collections and
+// appends a StaleNaN sample for every series that disappeared since the
+// previous one, whether it expired, was reset or was unregistered.
+type StalenessTracker struct {
+	mu   sync.Mutex
+	prev map[string]Sample
+}
+
+// NewStalenessTracker creates a tracker.
+func NewStalenessTracker() *StalenessTracker {
+	return &StalenessTracker{prev: make(map[string]Sample)}
+}
+
+// Track returns samples followed by staleness markers for the series seen
+// in the previous call but missing from samples.
+func (t *StalenessTracker) Track(samples []Sample) []Sample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	seen := make(map[string]Sample, len(samples))
+	for _, s := range samples {
+		seen[s.Name+"{"+s.Labels.Hash()+"}"] = Sample{Name: s.Name, Labels: s.Labels}
+	}
+	for key, s := range t.prev {
+		if _, ok := seen[key]; !ok {
+			s.Value = StaleNaN
+			s.Timestamp = now
+			samples = append(samples, s)
+		}
+	}
+	t.prev = seen
+	return samples
+}
+
+// seriesTouch records when a series was last written.
+type seriesTouch struct {
+	last atomic.Int64
+}
+
+// seriesExpirer is implemented by metrics that drop series idle for longer
+// than Options.SeriesTTL.
+type seriesExpirer interface {
+	setSeriesTTL(time.Duration)
+	expireSeries(now time.Time)
+}
+
+func (c *cardinality) setSeriesTTL(ttl time.Duration) {
+	c.ttl.Store(int64(ttl))
+}
+
+func (c *cardinality) touch(hash string) {
+	if c.ttl.Load() <= 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+	if t, ok := c.touched.Load(hash); ok {
+		t.(*seriesTouch).last.Store(now)
+		return
+	}
+	t := &seriesTouch{}
+	t.last.Store(now)
+	c.touched.Store(hash, t)
+}
+
+// expire removes the series of values not written within the TTL.
+func (c *cardinality) expire(values *sync.Map, now time.Time) {
+	ttl := c.ttl.Load()
+	if ttl <= 0 {
+		return
+	}
+	cutoff := now.UnixNano() - ttl
+	c.touched.Range(func(key, value any) bool {
+		if value.(*seriesTouch).last.Load() < cutoff {
+			c.touched.Delete(key)
+			if _, ok := values.LoadAndDelete(key); ok {
+				c.series.Add(-1)
+			}
+		}
+		return true
+	})
+}
+
+func (c *Counter) expireSeries(now time.Time)              { c.expire(&c.values, now) }
+func (g *Gauge) expireSeries(now time.Time)                { g.expire(&g.values, now) }
+func (h *Histogram) expireSeries(now time.Time)            { h.expire(&h.values, now) }
+func (h *ExponentialHistogram) expireSeries(now time.Time) { h.expire(&h.values, now) }
+func (c *ShardedCounter) expireSeries(now time.Time)       { c.expire(&c.values, now) }
+func (m *Meter) expireSeries(now time.Time)                { m.expire(&m.values, now) }
+func (s *StateSet) expireSeries(now time.Time)             { s.expire(&s.values, now) }
+func (s *Summary) expireSeries(now time.Time)              { s.expire(&s.values, now) }
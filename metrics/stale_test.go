@@ -0,0 +1,161 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestSeriesTTL(t *testing.T) {
+	r := NewRegistry(&Options{SeriesTTL: 100 * time.Millisecond, MaxSeriesPerMetric: 2, CardinalityReject: true})
+	c := r.Counter("requests_total", "", "user")
+	g := r.Gauge("sessions", "", "user")
+	c.Inc("idle")
+	c.Inc("busy")
+	g.Set(1, "idle")
+
+	time.Sleep(60 * time.Millisecond)
+	c.Inc("busy")
+	time.Sleep(60 * time.Millisecond)
+
+	if got := seriesOf(r, "requests_total"); len(got) != 1 || got["[busy]"] != 2 {
+		t.Errorf("expected only the recently written series to remain, got %v", got)
+	}
+	if got := seriesOf(r, "sessions"); len(got) != 0 {
+		t.Errorf("expected the idle gauge series to expire, got %v", got)
+	}
+
+	// An expired series frees its slot under the cardinality limit.
+	c.Inc("new")
+	if got := seriesOf(r, "requests_total"); len(got) != 2 || got["[new]"] != 1 {
+		t.Errorf("expected the new series to take the expired slot, got %v", got)
+	}
+}
+
+func TestStalenessTracker(t *testing.T) {
+	a := Sample{Name: "up", Labels: NewLabels("job", "a"), Value: 1}
+	b := Sample{Name: "up", Labels: NewLabels("job", "b"), Value: 1}
+	tracker := NewStalenessTracker()
+
+	if got := tracker.Track([]Sample{a, b}); len(got) != 2 {
+		t.Fatalf("first collection: %v", got)
+	}
+	got := tracker.Track([]Sample{a})
+	if len(got) != 2 || got[1].Labels.Get("job") != "b" || !IsStaleNaN(got[1].Value) || got[1].Timestamp.IsZero() {
+		t.Fatalf("expected a staleness marker for job b, got %v", got)
+	}
+	if got := tracker.Track([]Sample{a}); len(got) != 1 {
+		t.Errorf("expected a single marker per disappearance, got %v", got)
+	}
+
+	if !math.IsNaN(StaleNaN) || IsStaleNaN(math.NaN()) {
+		t.Error("expected StaleNaN to be a NaN distinct from math.NaN()")
+	}
+}
+
+func TestPushStaleMarkers(t *testing.T) {
+	exp := &recordingExporter{}
+	r := NewRegistry(&Options{
+		PushInterval: 10 * time.Millisecond,
+		PushExporter: exp,
+		StaleMarkers: true,
+	})
+	defer r.Close()
+	g := r.Gauge("queue_depth", "", "queue")
+	g.Set(3, "emails")
+
+	waitFor := func(match func(Sample) bool) bool {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			exp.mu.Lock()
+			samples := exp.samples
+			exp.mu.Unlock()
+			for _, s := range samples {
+				if match(s) {
+					return true
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return false
+	}
+	if !waitFor(func(s Sample) bool { return s.Value == 3 }) {
+		t.Fatal("series was never pushed")
+	}
+	g.Reset()
+	if !waitFor(func(s Sample) bool { return s.Labels.Get("queue") == "emails" && IsStaleNaN(s.Value) }) {
+		t.Error("expected a staleness marker after the series was reset")
+	}
+}
+
+type familyExporter struct{}
+
+func (familyExporter) Export(context.Context, []Sample) error               { return nil }
+func (familyExporter) ExportFamilies(context.Context, []MetricFamily) error { return nil }
+
+func TestStaleMarkersFamilyExporter(t *testing.T) {
+	var reported []error
+	remove := report.Subscribe(func(component string, err error) {
+		if component == report.MetricsPush {
+			reported = append(reported, err)
+		}
+	})
+	defer remove()
+
+	r := NewRegistry(&Options{PushInterval: time.Hour, PushExporter: familyExporter{}, StaleMarkers: true})
+	r.Close()
+	if len(reported) != 1 || !errors.Is(reported[0], ErrStaleMarkersUnsupported) {
+		t.Errorf("reported %v, want ErrStaleMarkersUnsupported", reported)
+	}
+}
+
+func TestExpositionTimestamps(t *testing.T) {
+	ts := time.UnixMilli(1700000000123)
+	fams := []MetricFamily{{
+		Name: "temperature",
+		Type: MetricTypeGauge,
+		Samples: []Sample{
+			{Name: "temperature", Labels: NewLabels("room", "a"), Value: 21.5, Timestamp: ts},
+			{Name: "temperature", Labels: NewLabels("room", "b"), Value: 19},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, fams, &TextOptions{Timestamps: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := `# TYPE temperature gauge
+temperature{room="a"} 21.5 1700000000123
+temperature{room="b"} 19
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteText(&buf, fams, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "1700000000123") {
+		t.Errorf("expected no timestamps by default:\n%s", buf.String())
+	}
+
+	r := NewRegistry(&Options{ExposeTimestamps: true})
+	r.Gauge("temperature", "").Set(20)
+	rec := scrape(t, HTTPHandler(r), nil)
+	if fields := strings.Fields(strings.TrimSpace(rec.Body.String())); len(fields) == 0 || len(fields[len(fields)-1]) != 13 {
+		t.Errorf("expected the handler to append millisecond timestamps:\n%s", rec.Body)
+	}
+	rec = scrape(t, HTTPHandler(r), func(req *http.Request) { req.Header.Set("Accept", ContentTypeOpenMetrics) })
+	if !strings.Contains(rec.Body.String(), "temperature 20 1") {
+		t.Errorf("expected OpenMetrics timestamps:\n%s", rec.Body)
+	}
+}
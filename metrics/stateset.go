@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxStates is the number of states a StateSet can hold; each series keeps
+// its states as bits of one word so updates are atomic.
+const maxStates = 64
+
+// StateSet is a set of boolean states, exposed as one series per state
+// with the metric name as the state label and a value of 1 or 0:
+//
+//	s := metrics.NewStateSet("breaker_state", "Circuit breaker state.", []string{"closed", "open", "half_open"}, "backend")
+//	s.Set("open", "payments")  // breaker_state{backend="payments",breaker_state="open"} 1
+//
+// Set makes one state active; Enable and Disable toggle states
+// independently for sets whose states are not mutually exclusive.
+type StateSet struct {
+	name       string
+	help       string
+	labelNames []string
+	states     []string
+	values     sync.Map
+	cardinality
+}
+
+type stateSetValue struct {
+	labels Labels
+	bits   atomic.Uint64
+}
+
+// NewStateSet creates a state set. States beyond the 64th are ignored.
+func NewStateSet(name, help string, states []string, labelNames ...string) *StateSet {
+	if len(states) > maxStates {
+		states = states[:maxStates]
+	}
+	return &StateSet{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		states:     slices.Clone(states),
+	}
+}
+
+func (s *StateSet) Name() string     { return s.name }
+func (s *StateSet) Help() string     { return s.help }
+func (s *StateSet) Type() MetricType { return MetricTypeStateSet }
+
+// LabelNames returns the label names, including the state label named
+// after the metric.
+func (s *StateSet) LabelNames() []string {
+	return append(slices.Clone(s.labelNames), s.name)
+}
+
+// States returns the state names.
+func (s *StateSet) States() []string {
+	return slices.Clone(s.states)
+}
+
+// Set atomically makes state the only active state. Unknown states are
+// ignored.
+func (s *StateSet) Set(state string, labelValues ...string) {
+	i := slices.Index(s.states, state)
+	if i < 0 {
+		return
+	}
+	if v := s.lookup(labelValues); v != nil {
+		v.bits.Store(1 << i)
+	}
+}
+
+// Enable marks state active without changing the others.
+func (s *StateSet) Enable(state string, labelValues ...string) {
+	s.update(state, labelValues, true)
+}
+
+// Disable marks state inactive without changing the others.
+func (s *StateSet) Disable(state string, labelValues ...string) {
+	s.update(state, labelValues, false)
+}
+
+func (s *StateSet) update(state string, labelValues []string, on bool) {
+	i := slices.Index(s.states, state)
+	if i < 0 {
+		return
+	}
+	v := s.lookup(labelValues)
+	if v == nil {
+		return
+	}
+	if on {
+		v.bits.Or(1 << i)
+	} else {
+		v.bits.And(^uint64(1 << i))
+	}
+}
+
+// Active returns the active states for the given labels.
+func (s *StateSet) Active(labelValues ...string) []string {
+	labels := s.makeLabels(labelValues)
+	val, ok := s.values.Load(labels.Hash())
+	if !ok {
+		return nil
+	}
+	bits := val.(*stateSetValue).bits.Load()
+	var active []string
+	for i, state := range s.states {
+		if bits&(1<<i) != 0 {
+			active = append(active, state)
+		}
+	}
+	return active
+}
+
+// Collect returns one sample per state and series.
+func (s *StateSet) Collect() []Sample {
+	var samples []Sample
+	now := time.Now()
+
+	s.values.Range(func(_, value any) bool {
+		v := value.(*stateSetValue)
+		bits := v.bits.Load()
+		for i, state := range s.states {
+			samples = append(samples, Sample{
+				Name:      s.name,
+				Labels:    v.labels.Merge(NewLabels(s.name, state)),
+				Value:     float64(bits >> i & 1),
+				Timestamp: now,
+			})
+		}
+		return true
+	})
+
+	return samples
+}
+
+// Reset clears all series.
+func (s *StateSet) Reset() {
+	s.values.Range(func(key, _ any) bool {
+		s.values.Delete(key)
+		return true
+	})
+	s.series.Store(0)
+}
+
+func (s *StateSet) lookup(labelValues []string) *stateSetValue {
+	return loadSeries(&s.values, &s.cardinality, s.name, s.makeLabels(labelValues), func(labels Labels) *stateSetValue {
+		return &stateSetValue{labels: labels}
+	})
+}
+
+func (s *StateSet) makeLabels(values []string) Labels {
+	if len(s.labelNames) == 0 {
+		return Labels{}
+	}
+
+	if len(values) != len(s.labelNames) {
+		if len(values) < len(s.labelNames) {
+			padded := make([]string, len(s.labelNames))
+			copy(padded, values)
+			values = padded
+		} else {
+			values = values[:len(s.labelNames)]
+		}
+	}
+
+	pairs := make([]string, 0, len(s.labelNames)*2)
+	for i, name := range s.labelNames {
+		pairs = append(pairs, name, values[i])
+	}
+	return NewLabels(pairs...)
+}
+
+// StateSet creates and registers a state set, or returns the matching one
+// already registered under name.
+func (r *Registry) StateSet(name, help string, states []string, labelNames ...string) *StateSet {
+	s := NewStateSet(name, help, states, labelNames...)
+	existing, err := getOrRegister(r, s, func(existing *StateSet) bool {
+		return slices.Equal(existing.labelNames, labelNames) && slices.Equal(existing.states, s.states)
+	})
+	if err != nil {
+		return s
+	}
+	return existing
+}
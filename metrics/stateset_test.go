@@ -0,0 +1,77 @@
+package metrics_test
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func stateValues(s *StateSet, labelName string) map[string]float64 {
+	got := map[string]float64{}
+	for _, sample := range s.Collect() {
+		got[sample.Labels.Get(labelName)] = sample.Value
+	}
+	return got
+}
+
+func TestStateSetOneHot(t *testing.T) {
+	s := NewStateSet("breaker_state", "", []string{"closed", "open", "half_open"})
+
+	s.Set("open")
+	want := map[string]float64{"closed": 0, "open": 1, "half_open": 0}
+	if got := stateValues(s, "breaker_state"); !maps.Equal(got, want) {
+		t.Errorf("after Set(open): %v, want %v", got, want)
+	}
+
+	s.Set("half_open")
+	want = map[string]float64{"closed": 0, "open": 0, "half_open": 1}
+	if got := stateValues(s, "breaker_state"); !maps.Equal(got, want) {
+		t.Errorf("after Set(half_open): %v, want %v", got, want)
+	}
+
+	s.Set("unknown")
+	if got := stateValues(s, "breaker_state"); !maps.Equal(got, want) {
+		t.Errorf("unknown state changed the set: %v", got)
+	}
+
+	s.Enable("closed")
+	want["closed"] = 1
+	if got := stateValues(s, "breaker_state"); !maps.Equal(got, want) {
+		t.Errorf("after Enable(closed): %v, want %v", got, want)
+	}
+}
+
+func TestStateSetExposition(t *testing.T) {
+	r := NewRegistry(nil)
+	s := r.StateSet("breaker_state", "Circuit breaker state.", []string{"closed", "open"}, "backend")
+	s.Set("open", "payments")
+
+	var text strings.Builder
+	if err := WriteText(&text, r.Gather(), nil); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP breaker_state Circuit breaker state.
+# TYPE breaker_state gauge
+breaker_state{backend="payments",breaker_state="closed"} 0
+breaker_state{backend="payments",breaker_state="open"} 1
+`
+	if text.String() != want {
+		t.Errorf("text output:\n%s\nwant:\n%s", text.String(), want)
+	}
+
+	var om strings.Builder
+	if err := WriteOpenMetrics(&om, r.Gather(), nil); err != nil {
+		t.Fatal(err)
+	}
+	want = `# TYPE breaker_state stateset
+# HELP breaker_state Circuit breaker state.
+breaker_state{backend="payments",breaker_state="closed"} 0
+breaker_state{backend="payments",breaker_state="open"} 1
+# EOF
+`
+	if om.String() != want {
+		t.Errorf("OpenMetrics output:\n%s\nwant:\n%s", om.String(), want)
+	}
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"slices"
+	"strings"
+)
+
+// Sub returns a child registry whose metrics are collected by r. Metric
+// names registered in the child are exposed with prefix prepended, and
+// labels (key-value pairs) are added to every series, so a library can
+// own a registry without knowing where the application exposes it:
+//
+//	db := registry.Sub("myapp_db_", "pool", "primary")
+//	db.Counter("queries_total", "Queries executed.")  // myapp_db_queries_total{pool="primary"}
+//
+// Prefixes and labels nest: a sub-registry of db also inherits db's. The
+// child shares r's cardinality limit but does not push on its own.
+func (r *Registry) Sub(prefix string, labels ...string) *Registry {
+	constLabels := NewLabels(labels...)
+	defaults := make(map[string]string, constLabels.Len())
+	for i, k := range constLabels.keys {
+		defaults[k] = constLabels.values[i]
+	}
+
+	child := &Registry{
+		opts: &Options{
+			Prefix:              prefix,
+			DefaultLabels:       defaults,
+			HistogramBuckets:    r.opts.HistogramBuckets,
+			BucketPresets:       r.opts.BucketPresets,
+			StrictNaming:        r.opts.StrictNaming,
+			ConsistentSnapshots: r.opts.ConsistentSnapshots,
+			SeriesTTL:           r.opts.SeriesTTL,
+			ExemplarFromContext: r.opts.ExemplarFromContext,
+		},
+		guard:       r.guard,
+		invalid:     r.invalid,
+		constLabels: constLabels,
+		parent:      r,
+	}
+
+	r.childMu.Lock()
+	r.children = append(r.children, child)
+	r.childMu.Unlock()
+	return child
+}
+
+func (r *Registry) subs() []*Registry {
+	r.childMu.Lock()
+	defer r.childMu.Unlock()
+	return slices.Clone(r.children)
+}
+
+func (r *Registry) removeSub(child *Registry) {
+	r.childMu.Lock()
+	defer r.childMu.Unlock()
+	r.children = slices.DeleteFunc(r.children, func(c *Registry) bool { return c == child })
+}
+
+// nameTaken reports whether another metric in the registry tree is already
+// exposed under the name a metric registered here would get.
+func (r *Registry) nameTaken(name string) bool {
+	root := r
+	for root.parent != nil {
+		name = root.opts.Prefix + name
+		root = root.parent
+	}
+	return root.exposes(name)
+}
+
+func (r *Registry) exposes(name string) bool {
+	if _, ok := r.metrics.Load(name); ok {
+		return true
+	}
+	for _, child := range r.subs() {
+		if rest, ok := strings.CutPrefix(name, child.opts.Prefix); ok && child.exposes(rest) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Registry) decorateSamples(samples []Sample) {
+	if r.opts.Prefix == "" && r.constLabels.Len() == 0 {
+		return
+	}
+	for i := range samples {
+		samples[i].Name = r.opts.Prefix + samples[i].Name
+		samples[i].Labels = withDefaults(samples[i].Labels, r.constLabels)
+	}
+}
+
+func (r *Registry) decorateFamily(f *MetricFamily) {
+	if r.opts.Prefix == "" && r.constLabels.Len() == 0 {
+		return
+	}
+	f.Name = r.opts.Prefix + f.Name
+	r.decorateSamples(f.Samples)
+	for i := range f.Exponential {
+		f.Exponential[i].Labels = withDefaults(f.Exponential[i].Labels, r.constLabels)
+	}
+}
+
+// withDefaults adds the default labels not already set in l.
+func withDefaults(l, defaults Labels) Labels {
+	if defaults.Len() == 0 {
+		return l
+	}
+	var missing []string
+	for i, k := range defaults.keys {
+		if !slices.Contains(l.keys, k) {
+			missing = append(missing, k, defaults.values[i])
+		}
+	}
+	if len(missing) == 0 {
+		return l
+	}
+	return l.Merge(NewLabels(missing...))
+}
@@ -0,0 +1,69 @@
+package metrics_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestSubRegistry(t *testing.T) {
+	r := NewRegistry(&Options{Prefix: "app_", DefaultLabels: map[string]string{"env": "prod"}})
+	db := r.Sub("db_", "pool", "primary")
+	shard := db.Sub("shard_", "shard", "1")
+
+	db.Counter("queries_total", "Queries executed.", "op").Inc("select")
+	shard.Gauge("lag_seconds", "Replication lag.").Set(2)
+	// A label set on the series wins over an inherited one.
+	shard.Gauge("rows", "Rows.", "pool").Set(7, "replica")
+
+	var buf bytes.Buffer
+	if err := WriteFamilies(&buf, r.Gather()); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP app_db_queries_total Queries executed.
+# TYPE app_db_queries_total counter
+app_db_queries_total{env="prod",op="select",pool="primary"} 1
+# HELP app_db_shard_lag_seconds Replication lag.
+# TYPE app_db_shard_lag_seconds gauge
+app_db_shard_lag_seconds{env="prod",pool="primary",shard="1"} 2
+# HELP app_db_shard_rows Rows.
+# TYPE app_db_shard_rows gauge
+app_db_shard_rows{env="prod",pool="replica",shard="1"} 7
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestSubRegistryNameCollision(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Counter("db_queries_total", "")
+	db := r.Sub("db_")
+
+	if _, err := db.CounterE("queries_total", ""); !errors.Is(err, ErrMetricExists) {
+		t.Errorf("expected ErrMetricExists for a name exposed by the parent, got %v", err)
+	}
+	db.Gauge("open", "")
+	if _, err := r.GaugeE("db_open", ""); !errors.Is(err, ErrMetricExists) {
+		t.Errorf("expected ErrMetricExists for a name exposed by a child, got %v", err)
+	}
+}
+
+func TestSubRegistryClose(t *testing.T) {
+	r := NewRegistry(nil)
+	db := r.Sub("db_")
+	db.Counter("queries_total", "").Inc()
+	if got := seriesOf(r, "db_queries_total"); len(got) != 1 {
+		t.Fatalf("before Close: %v", got)
+	}
+
+	db.Close()
+	if got := seriesOf(r, "db_queries_total"); len(got) != 0 {
+		t.Errorf("expected a closed sub-registry to be detached, got %v", got)
+	}
+	if _, err := r.CounterE("db_queries_total", ""); err != nil {
+		t.Errorf("expected the name to be free after Close, got %v", err)
+	}
+}
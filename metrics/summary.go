@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SummaryOptions configures a Summary.
+type SummaryOptions struct {
+	// Quantiles are the quantiles exported on collection.
+	// Defaults to 0.5, 0.9, 0.99 and 0.999.
+	Quantiles []float64
+
+	// MaxAge is the sliding window quantiles are computed over.
+	// Defaults to 10 minutes.
+	MaxAge time.Duration
+
+	// AgeBuckets is the number of digests the window is split into; the
+	// window slides in steps of MaxAge/AgeBuckets. Defaults to 5.
+	AgeBuckets int
+
+	// Compression is the t-digest compression. Defaults to
+	// DefaultCompression.
+	Compression float64
+}
+
+func (o *SummaryOptions) applyDefaults() {
+	if len(o.Quantiles) == 0 {
+		o.Quantiles = []float64{0.5, 0.9, 0.99, 0.999}
+	}
+	if o.MaxAge <= 0 {
+		o.MaxAge = 10 * time.Minute
+	}
+	if o.AgeBuckets <= 0 {
+		o.AgeBuckets = 5
+	}
+	if o.Compression <= 0 {
+		o.Compression = DefaultCompression
+	}
+}
+
+// Summary estimates quantiles over a sliding window with t-digests, for
+// SLO checks where histogram buckets are too coarse:
+//
+//	latency := metrics.NewSummary("rpc_seconds", "RPC latency.", nil, "method")
+//	latency.Observe(d.Seconds(), "get")
+//	if latency.Quantile(0.999, "get") > budget { ... }
+//
+// It is exported as a Prometheus summary: one sample per configured
+// quantile plus _sum and _count, which cover all observations since the
+// series was created. Quantiles of summaries cannot be aggregated across
+// instances; merge digests with Digest and TDigest.Merge instead.
+type Summary struct {
+	name       string
+	help       string
+	labelNames []string
+	opts       SummaryOptions
+	step       time.Duration
+	values     sync.Map
+	cardinality
+	unitMeta
+}
+
+type summaryValue struct {
+	mu      sync.Mutex
+	labels  Labels
+	created time.Time
+	count   uint64
+	sum     float64
+	window  []summarySlot
+}
+
+type summarySlot struct {
+	epoch  int64
+	digest *TDigest
+}
+
+// NewSummary creates a new summary.
+func NewSummary(name, help string, opts *SummaryOptions, labelNames ...string) *Summary {
+	if opts == nil {
+		opts = &SummaryOptions{}
+	}
+	o := *opts
+	o.applyDefaults()
+	o.Quantiles = slices.Clone(o.Quantiles)
+	slices.Sort(o.Quantiles)
+
+	return &Summary{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		opts:       o,
+		step:       max(o.MaxAge/time.Duration(o.AgeBuckets), 1),
+	}
+}
+
+func (s *Summary) Name() string         { return s.name }
+func (s *Summary) Help() string         { return s.help }
+func (s *Summary) Type() MetricType     { return MetricTypeSummary }
+func (s *Summary) LabelNames() []string { return s.labelNames }
+
+// Observe adds an observation. NaN values are ignored.
+func (s *Summary) Observe(value float64, labelValues ...string) {
+	if math.IsNaN(value) {
+		return
+	}
+	sv := loadSeries(&s.values, &s.cardinality, s.name, s.makeLabels(labelValues), func(labels Labels) *summaryValue {
+		sv := &summaryValue{labels: labels, created: time.Now(), window: make([]summarySlot, s.opts.AgeBuckets)}
+		for i := range sv.window {
+			sv.window[i].digest = NewTDigest(s.opts.Compression)
+		}
+		return sv
+	})
+	if sv == nil {
+		return
+	}
+
+	epoch := time.Now().UnixNano() / int64(s.step)
+	sv.mu.Lock()
+	slot := &sv.window[epoch%int64(len(sv.window))]
+	if slot.epoch != epoch {
+		slot.epoch = epoch
+		slot.digest.Reset()
+	}
+	slot.digest.Add(value)
+	sv.count++
+	sv.sum += value
+	sv.mu.Unlock()
+}
+
+// Quantile returns the estimated value at quantile q over the window, or
+// NaN if the window holds no observations.
+func (s *Summary) Quantile(q float64, labelValues ...string) float64 {
+	return s.Digest(labelValues...).Quantile(q)
+}
+
+// Digest returns a copy of the series' digest over the window, for
+// arbitrary quantile queries or merging with other digests.
+func (s *Summary) Digest(labelValues ...string) *TDigest {
+	val, ok := s.values.Load(s.makeLabels(labelValues).Hash())
+	if !ok {
+		return NewTDigest(s.opts.Compression)
+	}
+	return s.window(val.(*summaryValue), time.Now())
+}
+
+func (s *Summary) window(sv *summaryValue, now time.Time) *TDigest {
+	current := now.UnixNano() / int64(s.step)
+	d := NewTDigest(s.opts.Compression)
+	sv.mu.Lock()
+	for _, slot := range sv.window {
+		if slot.epoch > current-int64(len(sv.window)) && slot.epoch <= current {
+			d.Merge(slot.digest)
+		}
+	}
+	sv.mu.Unlock()
+	return d
+}
+
+// Collect returns the quantile, _sum and _count samples of every series.
+func (s *Summary) Collect() []Sample {
+	var samples []Sample
+	now := time.Now()
+
+	s.values.Range(func(_, value any) bool {
+		sv := value.(*summaryValue)
+		d := s.window(sv, now)
+		for _, q := range s.opts.Quantiles {
+			samples = append(samples, Sample{
+				Name:      s.name,
+				Labels:    sv.labels.Merge(NewLabels("quantile", formatFloat(q))),
+				Value:     d.Quantile(q),
+				Timestamp: now,
+				Created:   sv.created,
+			})
+		}
+
+		sv.mu.Lock()
+		count, sum := sv.count, sv.sum
+		sv.mu.Unlock()
+		samples = append(samples,
+			Sample{Name: s.name + "_sum", Labels: sv.labels, Value: sum, Timestamp: now, Created: sv.created},
+			Sample{Name: s.name + "_count", Labels: sv.labels, Value: float64(count), Timestamp: now, Created: sv.created},
+		)
+		return true
+	})
+
+	return samples
+}
+
+// Reset clears all series.
+func (s *Summary) Reset() {
+	s.values.Range(func(key, _ any) bool {
+		s.values.Delete(key)
+		return true
+	})
+	s.series.Store(0)
+}
+
+func (s *Summary) makeLabels(values []string) Labels {
+	if len(s.labelNames) == 0 {
+		return Labels{}
+	}
+
+	if len(values) != len(s.labelNames) {
+		if len(values) < len(s.labelNames) {
+			padded := make([]string, len(s.labelNames))
+			copy(padded, values)
+			values = padded
+		} else {
+			values = values[:len(s.labelNames)]
+		}
+	}
+
+	pairs := make([]string, 0, len(s.labelNames)*2)
+	for i, name := range s.labelNames {
+		pairs = append(pairs, name, values[i])
+	}
+	return NewLabels(pairs...)
+}
+
+// Summary creates and registers a summary, or returns the matching one
+// already registered under name.
+func (r *Registry) Summary(name, help string, opts *SummaryOptions, labelNames ...string) *Summary {
+	s := NewSummary(name, help, opts, labelNames...)
+	existing, err := getOrRegister(r, s, func(existing *Summary) bool {
+		return slices.Equal(existing.labelNames, labelNames) &&
+			slices.Equal(existing.opts.Quantiles, s.opts.Quantiles) &&
+			existing.opts.MaxAge == s.opts.MaxAge && existing.opts.AgeBuckets == s.opts.AgeBuckets
+	})
+	if err != nil {
+		return s
+	}
+	return existing
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import "time"
+
+// Timer measures a duration and records it in a Histogram in seconds.
+type Timer struct {
+	h           *Histogram
+	labelValues []string
+	start       time.Time
+}
+
+// StartTimer starts a timer that observes into h when ObserveDuration is
+// called.
+func (h *Histogram) StartTimer(labelValues ...string) *Timer {
+	return &Timer{h: h, labelValues: labelValues, start: time.Now()}
+}
+
+// ObserveDuration records the time elapsed since the timer started and
+// returns it.
+func (t *Timer) ObserveDuration() time.Duration {
+	d := time.Since(t.start)
+	t.h.Observe(d.Seconds(), t.labelValues...)
+	return d
+}
+
+// Time starts timing and returns a function that records the elapsed time,
+// for use with defer:
+//
+//	defer h.Time("GET")()
+func (h *Histogram) Time(labelValues ...string) func() {
+	start := time.Now()
+	return func() {
+		h.Observe(time.Since(start).Seconds(), labelValues...)
+	}
+}
+
+// Since records the time elapsed since start, in seconds.
+func (h *Histogram) Since(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// ObserveDuration records d in seconds.
+func (h *Histogram) ObserveDuration(d time.Duration, labelValues ...string) {
+	h.Observe(d.Seconds(), labelValues...)
+}
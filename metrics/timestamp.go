@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// TimestampGauge is a gauge holding Unix timestamps in seconds, the usual
+// shape of "last success time" metrics. Alerting on staleness is then
+// time() - metric > threshold:
+//
+//	lastSync := reg.TimestampGauge("sync_last_success_timestamp_seconds", "Last successful sync.")
+//	if err := sync(); err == nil {
+//		lastSync.Mark()
+//	}
+type TimestampGauge struct {
+	*Gauge
+}
+
+// NewTimestampGauge creates a new timestamp gauge with UnitSeconds.
+func NewTimestampGauge(name, help string, labelNames ...string) *TimestampGauge {
+	return &TimestampGauge{Gauge: WithUnit(NewGauge(name, help, labelNames...), UnitSeconds)}
+}
+
+// Mark sets the gauge to the current time.
+func (t *TimestampGauge) Mark(labelValues ...string) {
+	t.SetToCurrentTime(labelValues...)
+}
+
+// SetTime sets the gauge to tm.
+func (t *TimestampGauge) SetTime(tm time.Time, labelValues ...string) {
+	t.Set(unixSeconds(tm), labelValues...)
+}
+
+// Time returns the recorded time, or the zero time if none was set.
+func (t *TimestampGauge) Time(labelValues ...string) time.Time {
+	v := t.Value(labelValues...)
+	if v == 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// Since returns the time elapsed since the recorded time, or zero if none
+// was set.
+func (t *TimestampGauge) Since(labelValues ...string) time.Duration {
+	tm := t.Time(labelValues...)
+	if tm.IsZero() {
+		return 0
+	}
+	return time.Since(tm)
+}
+
+// TimestampGauge creates and registers a timestamp gauge, or returns the
+// matching one already registered under name.
+func (r *Registry) TimestampGauge(name, help string, labelNames ...string) *TimestampGauge {
+	t := NewTimestampGauge(name, help, labelNames...)
+	existing, err := getOrRegister(r, t, func(existing *TimestampGauge) bool {
+		return slices.Equal(existing.labelNames, labelNames)
+	})
+	if err != nil {
+		return t
+	}
+	return existing
+}
+
+// Job result label values.
+const (
+	JobSuccess = "success"
+	JobFailure = "failure"
+)
+
+// JobMetrics tracks the runs of a periodic or cron-style job:
+//
+//	<name>_runs_total{result}                 runs by result
+//	<name>_last_run_timestamp_seconds         when the last run finished
+//	<name>_last_success_timestamp_seconds     when the last successful run finished
+//	<name>_last_duration_seconds              how long the last run took
+//
+// Extra label names are added to every series, for example to track
+// several jobs with one set of metrics.
+type JobMetrics struct {
+	Runs         *Counter
+	LastRun      *TimestampGauge
+	LastSuccess  *TimestampGauge
+	LastDuration *Gauge
+}
+
+// NewJobMetrics creates unregistered job metrics.
+func NewJobMetrics(name string, labelNames ...string) *JobMetrics {
+	return &JobMetrics{
+		Runs:         NewCounter(name+"_runs_total", "Job runs by result.", jobRunLabels(labelNames)...),
+		LastRun:      NewTimestampGauge(name+"_last_run_timestamp_seconds", "Time the last job run finished.", labelNames...),
+		LastSuccess:  NewTimestampGauge(name+"_last_success_timestamp_seconds", "Time the last successful job run finished.", labelNames...),
+		LastDuration: WithUnit(NewGauge(name+"_last_duration_seconds", "Duration of the last job run.", labelNames...), UnitSeconds),
+	}
+}
+
+// JobMetrics creates and registers job metrics, reusing any matching
+// metrics already registered.
+func (r *Registry) JobMetrics(name string, labelNames ...string) *JobMetrics {
+	return &JobMetrics{
+		Runs:         r.Counter(name+"_runs_total", "Job runs by result.", jobRunLabels(labelNames)...),
+		LastRun:      r.TimestampGauge(name+"_last_run_timestamp_seconds", "Time the last job run finished.", labelNames...),
+		LastSuccess:  r.TimestampGauge(name+"_last_success_timestamp_seconds", "Time the last successful job run finished.", labelNames...),
+		LastDuration: WithUnit(r.Gauge(name+"_last_duration_seconds", "Duration of the last job run.", labelNames...), UnitSeconds),
+	}
+}
+
+// Start begins a run. Call the returned function with the run's error
+// when it finishes.
+func (j *JobMetrics) Start(labelValues ...string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		j.record(start, time.Now(), err, labelValues)
+	}
+}
+
+// Run runs fn and records it, returning fn's error.
+func (j *JobMetrics) Run(fn func() error, labelValues ...string) error {
+	done := j.Start(labelValues...)
+	err := fn()
+	done(err)
+	return err
+}
+
+func (j *JobMetrics) record(start, end time.Time, err error, labelValues []string) {
+	result := JobSuccess
+	if err != nil {
+		result = JobFailure
+	}
+	runLabels := make([]string, len(j.Runs.LabelNames()))
+	copy(runLabels, labelValues)
+	runLabels[len(runLabels)-1] = result
+	j.Runs.Inc(runLabels...)
+	j.LastRun.SetTime(end, labelValues...)
+	j.LastDuration.Set(end.Sub(start).Seconds(), labelValues...)
+	if err == nil {
+		j.LastSuccess.SetTime(end, labelValues...)
+	}
+}
+
+func jobRunLabels(labelNames []string) []string {
+	return append(slices.Clone(labelNames), "result")
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Unit is the unit a metric is measured in. OpenMetrics output announces
+// it with a # UNIT line, and OTLP output carries it as the metric unit.
+// By convention the metric name ends with the unit, e.g.
+// request_duration_seconds.
+type Unit string
+
+// Common units, in the base units Prometheus recommends.
+const (
+	UnitSeconds Unit = "seconds"
+	UnitBytes   Unit = "bytes"
+	UnitRatio   Unit = "ratio"
+)
+
+// unitMeta holds the unit of a metric. It is embedded in the metric types.
+type unitMeta struct {
+	unit atomic.Value // Unit
+}
+
+// Unit returns the metric's unit, or "" if none was set.
+func (u *unitMeta) Unit() Unit {
+	unit, _ := u.unit.Load().(Unit)
+	return unit
+}
+
+func (u *unitMeta) setUnit(unit Unit) {
+	u.unit.Store(unit)
+}
+
+// unitMetric is implemented by metrics that carry a unit.
+type unitMetric interface {
+	Unit() Unit
+}
+
+// WithUnit sets the unit of m and returns it, for use around a
+// constructor:
+//
+//	latency := metrics.WithUnit(metrics.NewHistogram("latency_seconds", "Latency.", nil), metrics.UnitSeconds)
+//	registry.Register(latency)
+//
+// Set the unit before registering so Options.StrictNaming can check the
+// name against it. Metric types without unit support are returned as is.
+func WithUnit[M Metric](m M, unit Unit) M {
+	if um, ok := any(m).(interface{ setUnit(Unit) }); ok {
+		um.setUnit(unit)
+	}
+	return m
+}
+
+// reservedSuffixes are generated for histogram and counter series and may
+// not end a metric name.
+var reservedSuffixes = []string{"_bucket", "_count", "_sum", "_created"}
+
+// checkNaming enforces the Prometheus naming conventions for
+// Options.StrictNaming: counters end in _total and only counters do, info
+// metrics end in _info, no name ends in a suffix reserved for generated
+// series, and names end with their unit.
+func checkNaming(m Metric) error {
+	name := m.Name()
+	base := name
+	switch m.Type() {
+	case MetricTypeCounter:
+		var ok bool
+		if base, ok = strings.CutSuffix(name, "_total"); !ok {
+			return fmt.Errorf("%w: counter %s must end in _total", ErrNamingConvention, name)
+		}
+	case MetricTypeInfo:
+		if !strings.HasSuffix(name, "_info") {
+			return fmt.Errorf("%w: info metric %s must end in _info", ErrNamingConvention, name)
+		}
+	default:
+		if strings.HasSuffix(name, "_total") {
+			return fmt.Errorf("%w: %s ends in _total but is not a counter", ErrNamingConvention, name)
+		}
+	}
+	for _, suffix := range reservedSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return fmt.Errorf("%w: %s ends in reserved suffix %s", ErrNamingConvention, name, suffix)
+		}
+	}
+	if um, ok := m.(unitMetric); ok {
+		if unit := um.Unit(); unit != "" && !strings.HasSuffix(base, "_"+string(unit)) {
+			return fmt.Errorf("%w: %s must end in _%s", ErrNamingConvention, base, unit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MeterOptions configures a Meter.
+type MeterOptions struct {
+	// Windows are the sliding windows exported and queryable.
+	// Defaults to 1m, 5m and 15m.
+	Windows []time.Duration
+
+	// Resolution is the width of the time slots the windows are built
+	// from; window edges are accurate to one slot. Defaults to 5s.
+	Resolution time.Duration
+}
+
+func (o *MeterOptions) applyDefaults() {
+	if len(o.Windows) == 0 {
+		o.Windows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+	}
+	if o.Resolution <= 0 {
+		o.Resolution = 5 * time.Second
+	}
+}
+
+// Meter tracks values over sliding windows: their rate per second and
+// rolling maximum and minimum. It can be queried in-process, for example
+// for admission control or load shedding, and is exported as a gauge with
+// "window" and "stat" (rate, max, min) labels:
+//
+//	m := metrics.NewMeter("requests", "Request rate.", nil)
+//	m.Mark()
+//	if m.Rate(time.Minute) > limit { ... }
+type Meter struct {
+	name       string
+	help       string
+	labelNames []string
+	windows    []time.Duration
+	resolution time.Duration
+	slots      int
+	values     sync.Map
+	cardinality
+	unitMeta
+}
+
+type meterValue struct {
+	mu     sync.Mutex
+	labels Labels
+	slots  []meterSlot
+}
+
+type meterSlot struct {
+	epoch int64
+	count uint64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// WindowStats summarizes the values recorded within a window.
+type WindowStats struct {
+	Count uint64
+	Sum   float64
+	Rate  float64 // Sum per second
+	Min   float64
+	Max   float64
+}
+
+// NewMeter creates a new meter.
+func NewMeter(name, help string, opts *MeterOptions, labelNames ...string) *Meter {
+	if opts == nil {
+		opts = &MeterOptions{}
+	}
+	o := *opts
+	o.applyDefaults()
+
+	windows := slices.Clone(o.Windows)
+	slices.Sort(windows)
+	longest := windows[len(windows)-1]
+
+	return &Meter{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		windows:    windows,
+		resolution: o.Resolution,
+		slots:      int((longest+o.Resolution-1)/o.Resolution) + 1,
+	}
+}
+
+func (m *Meter) Name() string         { return m.name }
+func (m *Meter) Help() string         { return m.help }
+func (m *Meter) Type() MetricType     { return MetricTypeGauge }
+func (m *Meter) LabelNames() []string { return m.labelNames }
+
+// Mark records one event.
+func (m *Meter) Mark(labelValues ...string) {
+	m.Add(1, labelValues...)
+}
+
+// Add records a value. Rates sum the values, so Add(n) records n events;
+// Max and Min report the largest and smallest single value.
+func (m *Meter) Add(value float64, labelValues ...string) {
+	if math.IsNaN(value) {
+		return
+	}
+	mv := loadSeries(&m.values, &m.cardinality, m.name, m.makeLabels(labelValues), func(labels Labels) *meterValue {
+		return &meterValue{labels: labels, slots: make([]meterSlot, m.slots)}
+	})
+	if mv == nil {
+		return
+	}
+
+	epoch := time.Now().UnixNano() / int64(m.resolution)
+	mv.mu.Lock()
+	s := &mv.slots[epoch%int64(len(mv.slots))]
+	if s.epoch != epoch || s.count == 0 {
+		*s = meterSlot{epoch: epoch, min: value, max: value}
+	}
+	s.count++
+	s.sum += value
+	s.min = min(s.min, value)
+	s.max = max(s.max, value)
+	mv.mu.Unlock()
+}
+
+// Stats returns the statistics for the window ending now. Windows longer
+// than the longest configured window are truncated to it.
+func (m *Meter) Stats(window time.Duration, labelValues ...string) WindowStats {
+	labels := m.makeLabels(labelValues)
+	val, ok := m.values.Load(labels.Hash())
+	if !ok {
+		return WindowStats{}
+	}
+	return m.stats(val.(*meterValue), window, time.Now())
+}
+
+// Rate returns the per-second rate over the window.
+func (m *Meter) Rate(window time.Duration, labelValues ...string) float64 {
+	return m.Stats(window, labelValues...).Rate
+}
+
+// Max returns the largest value recorded in the window.
+func (m *Meter) Max(window time.Duration, labelValues ...string) float64 {
+	return m.Stats(window, labelValues...).Max
+}
+
+// Min returns the smallest value recorded in the window.
+func (m *Meter) Min(window time.Duration, labelValues ...string) float64 {
+	return m.Stats(window, labelValues...).Min
+}
+
+func (m *Meter) stats(mv *meterValue, window time.Duration, now time.Time) WindowStats {
+	n := int64((window + m.resolution - 1) / m.resolution)
+	n = min(max(n, 1), int64(m.slots-1))
+	current := now.UnixNano() / int64(m.resolution)
+
+	var st WindowStats
+	mv.mu.Lock()
+	for i := range mv.slots {
+		s := &mv.slots[i]
+		if s.count == 0 || s.epoch <= current-n || s.epoch > current {
+			continue
+		}
+		if st.Count == 0 {
+			st.Min, st.Max = s.min, s.max
+		}
+		st.Count += s.count
+		st.Sum += s.sum
+		st.Min = min(st.Min, s.min)
+		st.Max = max(st.Max, s.max)
+	}
+	mv.mu.Unlock()
+
+	st.Rate = st.Sum / (time.Duration(n) * m.resolution).Seconds()
+	return st
+}
+
+// Collect returns the rate, max and min of every series for each window.
+func (m *Meter) Collect() []Sample {
+	var samples []Sample
+	now := time.Now()
+
+	m.values.Range(func(_, value any) bool {
+		mv := value.(*meterValue)
+		for _, w := range m.windows {
+			st := m.stats(mv, w, now)
+			window := formatWindow(w)
+			for _, stat := range []struct {
+				name  string
+				value float64
+			}{{"rate", st.Rate}, {"max", st.Max}, {"min", st.Min}} {
+				samples = append(samples, Sample{
+					Name:      m.name,
+					Labels:    mv.labels.Merge(NewLabels("window", window, "stat", stat.name)),
+					Value:     stat.value,
+					Timestamp: now,
+				})
+			}
+		}
+		return true
+	})
+
+	return samples
+}
+
+// Reset clears all series.
+func (m *Meter) Reset() {
+	m.values.Range(func(key, _ any) bool {
+		m.values.Delete(key)
+		return true
+	})
+	m.series.Store(0)
+}
+
+func (m *Meter) makeLabels(values []string) Labels {
+	if len(m.labelNames) == 0 {
+		return Labels{}
+	}
+
+	if len(values) != len(m.labelNames) {
+		if len(values) < len(m.labelNames) {
+			padded := make([]string, len(m.labelNames))
+			copy(padded, values)
+			values = padded
+		} else {
+			values = values[:len(m.labelNames)]
+		}
+	}
+
+	pairs := make([]string, 0, len(m.labelNames)*2)
+	for i, name := range m.labelNames {
+		pairs = append(pairs, name, values[i])
+	}
+	return NewLabels(pairs...)
+}
+
+// formatWindow renders a window compactly, e.g. "1m", "90s", "1h".
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	for _, zero := range []string{"0s", "0m"} {
+		if strings.HasSuffix(s, "m"+zero) || strings.HasSuffix(s, "h"+zero) {
+			s = strings.TrimSuffix(s, zero)
+		}
+	}
+	return s
+}
+
+// Meter creates and registers a meter, or returns the matching one already
+// registered under name.
+func (r *Registry) Meter(name, help string, opts *MeterOptions, labelNames ...string) *Meter {
+	m := NewMeter(name, help, opts, labelNames...)
+	existing, err := getOrRegister(r, m, func(existing *Meter) bool {
+		return slices.Equal(existing.labelNames, labelNames) &&
+			slices.Equal(existing.windows, m.windows) && existing.resolution == m.resolution
+	})
+	if err != nil {
+		return m
+	}
+	return existing
+}
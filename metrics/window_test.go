@@ -0,0 +1,78 @@
+package metrics_test
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/metrics"
+)
+
+func TestMeterWindow(t *testing.T) {
+	const window = 200 * time.Millisecond
+	m := NewMeter("requests", "", &MeterOptions{
+		Windows:    []time.Duration{window},
+		Resolution: 20 * time.Millisecond,
+	}, "route")
+
+	for _, v := range []float64{3, 1, 6, math.NaN()} {
+		m.Add(v, "/a")
+	}
+	m.Mark("/b")
+
+	st := m.Stats(window, "/a")
+	if st.Count != 3 || st.Sum != 10 || st.Min != 1 || st.Max != 6 {
+		t.Errorf("stats = %+v", st)
+	}
+	if got, want := m.Rate(window, "/a"), 10/window.Seconds(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("rate = %g, want %g", got, want)
+	}
+	if got := m.Rate(window, "/b"); got != 1/window.Seconds() {
+		t.Errorf("rate of /b = %g", got)
+	}
+	if got := m.Stats(window, "/missing"); got != (WindowStats{}) {
+		t.Errorf("stats of an unknown series = %+v", got)
+	}
+
+	// Values leave the window once it slides past them.
+	time.Sleep(window + 60*time.Millisecond)
+	if st := m.Stats(window, "/a"); st != (WindowStats{}) {
+		t.Errorf("expected the window to be empty, got %+v", st)
+	}
+	m.Add(2, "/a")
+	if m.Max(window, "/a") != 2 || m.Min(window, "/a") != 2 {
+		t.Errorf("expected max and min to restart at 2, got %+v", m.Stats(window, "/a"))
+	}
+}
+
+func TestMeterCollect(t *testing.T) {
+	r := NewRegistry(nil)
+	m := r.Meter("load", "Load.", &MeterOptions{
+		Windows: []time.Duration{time.Hour, time.Minute, 90 * time.Second},
+	})
+	if r.Meter("load", "Load.", &MeterOptions{
+		Windows: []time.Duration{time.Minute, 90 * time.Second, time.Hour},
+	}) != m {
+		t.Error("expected the same windows in another order to return the existing meter")
+	}
+	m.Add(4)
+
+	var got []string
+	for _, s := range r.Collect() {
+		got = append(got, s.Labels.Get("window")+"/"+s.Labels.Get("stat"))
+		if s.Labels.Get("stat") == "max" && s.Value != 4 {
+			t.Errorf("%v = %g, want 4", s.Labels.Values(), s.Value)
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		"1h/max", "1h/min", "1h/rate",
+		"1m/max", "1m/min", "1m/rate",
+		"1m30s/max", "1m30s/min", "1m30s/rate",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("series = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package lumen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sync/atomic"
+
+	"github.com/kolosys/lumen/metrics"
+	metricotlp "github.com/kolosys/lumen/metrics/otlpconv"
+	"github.com/kolosys/lumen/resource"
+	"github.com/kolosys/lumen/trace"
+	traceotlp "github.com/kolosys/lumen/trace/otlpconv"
+)
+
+// Resource attribute keys sent with OTLP exports.
+const (
+	ServiceNameKey    = resource.ServiceNameKey
+	ServiceVersionKey = resource.ServiceVersionKey
+	EnvironmentKey    = resource.EnvironmentKey
+)
+
+// OTLPOptions configures the OTLP/HTTP exporters.
+type OTLPOptions struct {
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string
+
+	// Resource holds attributes describing the process, such as
+	// service.version. Spans carry their tracer's resource already; these
+	// attributes are added where a span's resource lacks them.
+	// service.name is always taken from the spans.
+	Resource map[string]string
+
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+func (o *OTLPOptions) applyDefaults() {
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+}
+
+// OTLPTraceExporter posts spans as OTLP/JSON to an OTLP/HTTP collector's
+// traces URL, e.g. "http://localhost:4318/v1/traces". It implements
+// trace.Exporter.
+type OTLPTraceExporter struct {
+	url  atomic.Pointer[string]
+	opts OTLPOptions
+}
+
+// NewOTLPTraceExporter creates an exporter posting to url.
+func NewOTLPTraceExporter(url string, opts *OTLPOptions) *OTLPTraceExporter {
+	if opts == nil {
+		opts = &OTLPOptions{}
+	}
+	o := *opts
+	o.applyDefaults()
+	e := &OTLPTraceExporter{opts: o}
+	e.url.Store(&url)
+	return e
+}
+
+// SetURL changes the URL later exports are posted to.
+func (e *OTLPTraceExporter) SetURL(url string) { e.url.Store(&url) }
+
+// ExportSpans sends spans in one request.
+func (e *OTLPTraceExporter) ExportSpans(ctx context.Context, spans []*trace.SpanSnapshot) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	td := traceotlp.FromSnapshots(spans)
+	for i := range td.ResourceSpans {
+		res := &td.ResourceSpans[i].Resource
+		for _, k := range slices.Sorted(maps.Keys(e.opts.Resource)) {
+			if !slices.ContainsFunc(res.Attributes, func(kv traceotlp.KeyValue) bool { return kv.Key == k }) {
+				res.Attributes = append(res.Attributes, traceotlp.KeyValue{Key: k, Value: traceotlp.StringValue(e.opts.Resource[k])})
+			}
+		}
+	}
+	return postOTLP(ctx, *e.url.Load(), &e.opts, td)
+}
+
+// Close implements trace.Exporter.
+func (e *OTLPTraceExporter) Close() error { return nil }
+
+// OTLPMetricExporter posts metric families as OTLP/JSON to an OTLP/HTTP
+// collector's metrics URL, e.g. "http://localhost:4318/v1/metrics". Set
+// it as metrics.Options.PushExporter.
+type OTLPMetricExporter struct {
+	url      atomic.Pointer[string]
+	opts     OTLPOptions
+	resource []metricotlp.KeyValue
+}
+
+// NewOTLPMetricExporter creates an exporter posting to url.
+func NewOTLPMetricExporter(url string, opts *OTLPOptions) *OTLPMetricExporter {
+	if opts == nil {
+		opts = &OTLPOptions{}
+	}
+	o := *opts
+	o.applyDefaults()
+
+	e := &OTLPMetricExporter{opts: o}
+	e.url.Store(&url)
+	for _, k := range slices.Sorted(maps.Keys(o.Resource)) {
+		e.resource = append(e.resource, metricotlp.KeyValue{Key: k, Value: metricotlp.StringValue(o.Resource[k])})
+	}
+	return e
+}
+
+// SetURL changes the URL later exports are posted to.
+func (e *OTLPMetricExporter) SetURL(url string) { e.url.Store(&url) }
+
+// ExportFamilies sends families in one request.
+func (e *OTLPMetricExporter) ExportFamilies(ctx context.Context, families []metrics.MetricFamily) error {
+	return postOTLP(ctx, *e.url.Load(), &e.opts, metricotlp.FromFamilies(families, e.resource...))
+}
+
+// Export sends bare samples, each sample name as an untyped family. The
+// registry push loop uses ExportFamilies instead.
+func (e *OTLPMetricExporter) Export(ctx context.Context, samples []metrics.Sample) error {
+	var families []metrics.MetricFamily
+	byName := make(map[string]int)
+	for _, s := range samples {
+		i, ok := byName[s.Name]
+		if !ok {
+			i = len(families)
+			byName[s.Name] = i
+			families = append(families, metrics.MetricFamily{Name: s.Name, Type: metrics.MetricTypeUnknown})
+		}
+		families[i].Samples = append(families[i].Samples, s)
+	}
+	return e.ExportFamilies(ctx, families)
+}
+
+func postOTLP(ctx context.Context, url string, opts *OTLPOptions, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("otlp collector responded %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,37 @@
+package profile
+
+import (
+	"context"
+	"runtime/pprof"
+
+	"github.com/kolosys/lumen/trace"
+)
+
+// Profiler label keys set by Do and WithTraceLabels.
+const (
+	TraceIDLabel  = "trace_id"
+	SpanNameLabel = "span_name"
+)
+
+// Do runs f with the trace ID and span name of the span in ctx attached
+// as pprof labels, so that CPU samples taken while f runs, including in
+// goroutines it starts, can be attributed to the trace.
+func Do(ctx context.Context, f func(context.Context)) {
+	pprof.Do(ctx, traceLabels(ctx), f)
+}
+
+// WithTraceLabels returns ctx with the trace labels of Do added, and
+// applies them to the current goroutine.
+func WithTraceLabels(ctx context.Context) context.Context {
+	ctx = pprof.WithLabels(ctx, traceLabels(ctx))
+	pprof.SetGoroutineLabels(ctx)
+	return ctx
+}
+
+func traceLabels(ctx context.Context) pprof.LabelSet {
+	span := trace.SpanFromContext(ctx)
+	if span == nil || !span.TraceID().IsValid() {
+		return pprof.Labels()
+	}
+	return pprof.Labels(TraceIDLabel, span.TraceID().String(), SpanNameLabel, span.Name())
+}
@@ -0,0 +1,384 @@
+// Package profile captures runtime profiles on a schedule and ships them
+// to a Sink. Each capture is tagged with the service resource and, when a
+// tracer tracks active spans, with the IDs of the traces running at the
+// time, so that a slow trace can be matched to the CPU profile that
+// covered it.
+//
+// Continuous profiling has a cost, so the profiler keeps it bounded: the
+// CPU profile runs for at most Options.MaxDutyCycle of each interval,
+// goroutine dumps are skipped above Options.MaxGoroutines, and profiles
+// larger than Options.MaxProfileBytes are dropped.
+//
+// Basic usage:
+//
+//	p, err := profile.New(&profile.Options{
+//		Sink:     profile.NewDirSink("/var/lib/app/profiles", 100),
+//		Resource: obs.Resource,
+//		Tracer:   obs.Tracer,
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	p.Start()
+//	defer p.Stop()
+package profile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolosys/lumen/internal/report"
+	"github.com/kolosys/lumen/resource"
+	"github.com/kolosys/lumen/trace"
+)
+
+// Type is a kind of runtime profile.
+type Type string
+
+// Profile types, named as in runtime/pprof.
+const (
+	CPU       Type = "cpu"
+	Heap      Type = "heap"
+	Allocs    Type = "allocs"
+	Goroutine Type = "goroutine"
+	Mutex     Type = "mutex"
+	Block     Type = "block"
+)
+
+var (
+	ErrNoSink      = errors.New("profile: sink is required")
+	ErrUnknownType = errors.New("profile: unknown profile type")
+	ErrCPUBusy     = errors.New("profile: CPU profiling already in progress")
+	ErrTooLarge    = errors.New("profile: profile exceeds MaxProfileBytes")
+	ErrSkipped     = errors.New("profile: capture skipped by overhead limit")
+)
+
+// Profile is one captured profile.
+type Profile struct {
+	Type Type
+
+	// Time is when the capture started.
+	Time time.Time
+
+	// Duration is how long a CPU profile ran; zero for snapshots.
+	Duration time.Duration
+
+	// Data is the gzipped pprof protobuf.
+	Data []byte
+
+	// Resource identifies the process that was profiled.
+	Resource *resource.Resource
+
+	// TraceIDs are the traces that were active when the capture started.
+	TraceIDs []string
+}
+
+// Options configures a Profiler.
+type Options struct {
+	// Sink receives the captured profiles. Required.
+	Sink Sink
+
+	// Types lists the profiles to capture each interval.
+	// Default: CPU, Heap and Goroutine.
+	Types []Type
+
+	// Interval is the time between captures. Default: 1m; minimum 10s.
+	Interval time.Duration
+
+	// CPUDuration is how long the CPU profile runs. Default: 10s.
+	CPUDuration time.Duration
+
+	// MaxDutyCycle caps CPUDuration as a fraction of Interval.
+	// Default: 0.25.
+	MaxDutyCycle float64
+
+	// MaxGoroutines skips goroutine profiles when more goroutines are
+	// running, since the dump stops the world for its duration.
+	// Default: 10000.
+	MaxGoroutines int
+
+	// MaxProfileBytes drops profiles larger than this. Default: 16MB.
+	MaxProfileBytes int
+
+	// MutexProfileFraction is passed to runtime.SetMutexProfileFraction
+	// while the profiler runs, if Mutex is among Types. Default: 10.
+	MutexProfileFraction int
+
+	// BlockProfileRate is passed to runtime.SetBlockProfileRate while the
+	// profiler runs, if Block is among Types. Default: 10000 (10µs).
+	BlockProfileRate int
+
+	// Resource tags every profile.
+	Resource *resource.Resource
+
+	// Tracer, if set and tracking active spans, supplies the trace IDs
+	// recorded with each profile.
+	Tracer *trace.Tracer
+
+	// MaxTraceIDs caps the trace IDs recorded per profile. Default: 100.
+	MaxTraceIDs int
+
+	// OnError is called when a scheduled capture or its delivery fails.
+	OnError func(Type, error)
+}
+
+func (o *Options) applyDefaults() {
+	if len(o.Types) == 0 {
+		o.Types = []Type{CPU, Heap, Goroutine}
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Minute
+	}
+	if o.Interval < 10*time.Second {
+		o.Interval = 10 * time.Second
+	}
+	if o.CPUDuration <= 0 {
+		o.CPUDuration = 10 * time.Second
+	}
+	if o.MaxDutyCycle <= 0 || o.MaxDutyCycle > 1 {
+		o.MaxDutyCycle = 0.25
+	}
+	if limit := time.Duration(float64(o.Interval) * o.MaxDutyCycle); o.CPUDuration > limit {
+		o.CPUDuration = limit
+	}
+	if o.MaxGoroutines <= 0 {
+		o.MaxGoroutines = 10000
+	}
+	if o.MaxProfileBytes <= 0 {
+		o.MaxProfileBytes = 16 << 20
+	}
+	if o.MutexProfileFraction <= 0 {
+		o.MutexProfileFraction = 10
+	}
+	if o.BlockProfileRate <= 0 {
+		o.BlockProfileRate = 10000
+	}
+	if o.MaxTraceIDs <= 0 {
+		o.MaxTraceIDs = 100
+	}
+}
+
+// Stats counts the profiler's work since it was created.
+type Stats struct {
+	Captured uint64
+	Skipped  uint64
+	Errors   uint64
+	Bytes    uint64
+}
+
+// Profiler captures profiles on a schedule. It is safe for concurrent use.
+type Profiler struct {
+	opts Options
+
+	captured atomic.Uint64
+	skipped  atomic.Uint64
+	errors   atomic.Uint64
+	bytes    atomic.Uint64
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+	running bool
+}
+
+// New creates a Profiler. Call Start to begin capturing.
+func New(opts *Options) (*Profiler, error) {
+	if opts == nil || opts.Sink == nil {
+		return nil, ErrNoSink
+	}
+	o := *opts
+	o.Types = append([]Type(nil), o.Types...)
+	o.applyDefaults()
+	for _, t := range o.Types {
+		if !t.valid() {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownType, t)
+		}
+	}
+	return &Profiler{opts: o}, nil
+}
+
+// Start begins capturing every Interval, the first capture one Interval
+// from now. It is a no-op if the profiler is already running.
+func (p *Profiler) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return
+	}
+	p.running = true
+	p.setRates(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go p.loop(ctx, p.done)
+}
+
+// Stop stops capturing, cutting short a CPU profile in progress, and
+// waits for the current capture to be delivered.
+func (p *Profiler) Stop() {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.running = false
+	p.cancel()
+	done := p.done
+	p.mu.Unlock()
+
+	<-done
+	p.setRates(false)
+}
+
+// Stats returns the profiler's counters.
+func (p *Profiler) Stats() Stats {
+	return Stats{
+		Captured: p.captured.Load(),
+		Skipped:  p.skipped.Load(),
+		Errors:   p.errors.Load(),
+		Bytes:    p.bytes.Load(),
+	}
+}
+
+func (p *Profiler) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, t := range p.opts.Types {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := p.CaptureAndSend(ctx, t); err != nil {
+					report.Error(report.ProfileUpload, fmt.Errorf("profile: %s: %w", t, err))
+					if p.opts.OnError != nil {
+						p.opts.OnError(t, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// CaptureAndSend captures one profile of type t and writes it to the sink.
+func (p *Profiler) CaptureAndSend(ctx context.Context, t Type) error {
+	prof, err := p.Capture(ctx, t)
+	if err != nil {
+		return err
+	}
+	if err := p.opts.Sink.Write(ctx, prof); err != nil {
+		p.errors.Add(1)
+		return err
+	}
+	return nil
+}
+
+// Capture captures one profile of type t without sending it. A CPU
+// capture blocks for Options.CPUDuration or until ctx is done.
+func (p *Profiler) Capture(ctx context.Context, t Type) (*Profile, error) {
+	if !t.valid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownType, t)
+	}
+	prof := &Profile{
+		Type:     t,
+		Time:     time.Now(),
+		Resource: p.opts.Resource,
+		TraceIDs: p.activeTraceIDs(),
+	}
+
+	var buf bytes.Buffer
+	switch t {
+	case CPU:
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			p.skipped.Add(1)
+			return nil, ErrCPUBusy
+		}
+		timer := time.NewTimer(p.opts.CPUDuration)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+		pprof.StopCPUProfile()
+		prof.Duration = time.Since(prof.Time)
+	case Goroutine:
+		if n := runtime.NumGoroutine(); n > p.opts.MaxGoroutines {
+			p.skipped.Add(1)
+			return nil, fmt.Errorf("%w: %d goroutines", ErrSkipped, n)
+		}
+		fallthrough
+	default:
+		if err := pprof.Lookup(string(t)).WriteTo(&buf, 0); err != nil {
+			p.errors.Add(1)
+			return nil, err
+		}
+	}
+
+	if buf.Len() > p.opts.MaxProfileBytes {
+		p.skipped.Add(1)
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, buf.Len())
+	}
+	prof.Data = buf.Bytes()
+	p.captured.Add(1)
+	p.bytes.Add(uint64(len(prof.Data)))
+	return prof, nil
+}
+
+// activeTraceIDs returns the distinct trace IDs of the tracer's active
+// spans, oldest first.
+func (p *Profiler) activeTraceIDs() []string {
+	if p.opts.Tracer == nil {
+		return nil
+	}
+	var ids []string
+	seen := make(map[trace.TraceID]bool)
+	for _, s := range p.opts.Tracer.ActiveSpans() {
+		if seen[s.TraceID] {
+			continue
+		}
+		seen[s.TraceID] = true
+		ids = append(ids, s.TraceID.String())
+		if len(ids) == p.opts.MaxTraceIDs {
+			break
+		}
+	}
+	return ids
+}
+
+// setRates turns mutex and block profiling on or off for the types the
+// profiler captures.
+func (p *Profiler) setRates(on bool) {
+	for _, t := range p.opts.Types {
+		switch {
+		case t == Mutex && on:
+			runtime.SetMutexProfileFraction(p.opts.MutexProfileFraction)
+		case t == Mutex:
+			runtime.SetMutexProfileFraction(0)
+		case t == Block && on:
+			runtime.SetBlockProfileRate(p.opts.BlockProfileRate)
+		case t == Block:
+			runtime.SetBlockProfileRate(0)
+		}
+	}
+}
+
+func (t Type) valid() bool {
+	switch t {
+	case CPU, Heap, Allocs, Goroutine, Mutex, Block:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package profile_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/profile"
+	"github.com/kolosys/lumen/resource"
+	"github.com/kolosys/lumen/trace"
+)
+
+func discard() Sink {
+	return SinkFunc(func(context.Context, *Profile) error { return nil })
+}
+
+func TestCaptureSnapshotTaggedWithResourceAndTraces(t *testing.T) {
+	tracer := trace.New(&trace.Options{TrackActiveSpans: true})
+	defer tracer.Close()
+	_, span := tracer.Start(context.Background(), "slow")
+	traceID := span.TraceID().String()
+	defer span.End()
+
+	res := resource.New(&resource.Options{ServiceName: "checkout", DisableDetection: true})
+	p, err := New(&Options{Sink: discard(), Resource: res, Tracer: tracer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prof, err := p.Capture(context.Background(), Heap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prof.Data) == 0 || prof.Data[0] != 0x1f {
+		t.Errorf("expected gzipped pprof data, got %d bytes", len(prof.Data))
+	}
+	if prof.Resource.ServiceName() != "checkout" {
+		t.Errorf("resource = %v", prof.Resource.Map())
+	}
+	if len(prof.TraceIDs) != 1 || prof.TraceIDs[0] != traceID {
+		t.Errorf("trace IDs = %v, want [%s]", prof.TraceIDs, traceID)
+	}
+	if s := p.Stats(); s.Captured != 1 || s.Bytes == 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestCaptureCPUStopsWithContext(t *testing.T) {
+	p, _ := New(&Options{Sink: discard(), CPUDuration: time.Minute, Interval: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	prof, err := p.Capture(ctx, CPU)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("capture took %v", elapsed)
+	}
+	if prof.Duration <= 0 || len(prof.Data) == 0 {
+		t.Errorf("duration = %v, %d bytes", prof.Duration, len(prof.Data))
+	}
+}
+
+func TestOverheadLimits(t *testing.T) {
+	p, _ := New(&Options{Sink: discard(), MaxGoroutines: 1})
+	if _, err := p.Capture(context.Background(), Goroutine); !errors.Is(err, ErrSkipped) {
+		t.Errorf("goroutine capture err = %v, want ErrSkipped", err)
+	}
+
+	p, _ = New(&Options{Sink: discard(), MaxProfileBytes: 1})
+	if _, err := p.Capture(context.Background(), Heap); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("heap capture err = %v, want ErrTooLarge", err)
+	}
+	if s := p.Stats(); s.Skipped != 1 || s.Captured != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+
+	if _, err := New(&Options{Sink: discard(), Types: []Type{"threads"}}); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("New err = %v, want ErrUnknownType", err)
+	}
+}
+
+func TestDirSinkPrunes(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewDirSink(dir, 2)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		err := sink.Write(context.Background(), &Profile{Type: Heap, Time: base.Add(time.Duration(i) * time.Second), Data: []byte{1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 4 {
+		t.Fatalf("files = %v, want 2 profiles with metadata", files)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "20240501T120000.000Z-heap.pb.gz")); !os.IsNotExist(err) {
+		t.Error("oldest profile was not pruned")
+	}
+	var meta Metadata
+	data, _ := os.ReadFile(filepath.Join(dir, "20240501T120002.000Z-heap.json"))
+	if err := json.Unmarshal(data, &meta); err != nil || meta.Type != Heap {
+		t.Errorf("metadata = %+v, %v", meta, err)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		meta Metadata
+		body []byte
+		auth string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auth = r.Header.Get("Authorization")
+		json.Unmarshal([]byte(r.FormValue("metadata")), &meta)
+		f, _, err := r.FormFile("profile")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body, _ = io.ReadAll(f)
+	}))
+	defer srv.Close()
+
+	sink := &HTTPSink{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer t"}}
+	err := sink.Write(context.Background(), &Profile{Type: CPU, Time: time.Now(), Duration: time.Second, Data: []byte("pprof"), TraceIDs: []string{"abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if string(body) != "pprof" || meta.Type != CPU || meta.DurationNS != int64(time.Second) || len(meta.TraceIDs) != 1 || auth != "Bearer t" {
+		t.Errorf("got body %q, metadata %+v, auth %q", body, meta, auth)
+	}
+}
+
+func TestDoSetsTraceLabels(t *testing.T) {
+	tracer := trace.New(nil)
+	defer tracer.Close()
+	ctx, span := tracer.Start(context.Background(), "work")
+	defer span.End()
+
+	Do(ctx, func(ctx context.Context) {
+		if v, _ := pprof.Label(ctx, TraceIDLabel); v != span.TraceID().String() {
+			t.Errorf("trace_id label = %q", v)
+		}
+		if v, _ := pprof.Label(ctx, SpanNameLabel); v != "work" {
+			t.Errorf("span_name label = %q", v)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink stores or forwards captured profiles.
+type Sink interface {
+	Write(ctx context.Context, p *Profile) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, p *Profile) error
+
+// Write calls f(ctx, p).
+func (f SinkFunc) Write(ctx context.Context, p *Profile) error { return f(ctx, p) }
+
+// Metadata describes a profile alongside its data, as written by the
+// built-in sinks.
+type Metadata struct {
+	Type       Type              `json:"type"`
+	Time       time.Time         `json:"time"`
+	DurationNS int64             `json:"duration_ns,omitempty"`
+	Resource   map[string]string `json:"resource,omitempty"`
+	TraceIDs   []string          `json:"trace_ids,omitempty"`
+}
+
+// Metadata returns p's metadata.
+func (p *Profile) Metadata() Metadata {
+	return Metadata{
+		Type:       p.Type,
+		Time:       p.Time,
+		DurationNS: p.Duration.Nanoseconds(),
+		Resource:   p.Resource.Map(),
+		TraceIDs:   p.TraceIDs,
+	}
+}
+
+// DirSink writes each profile to a directory as a .pb.gz file, readable by
+// go tool pprof, next to a .json file holding its Metadata.
+type DirSink struct {
+	dir      string
+	maxFiles int
+
+	mu sync.Mutex
+}
+
+// NewDirSink returns a sink writing to dir, which is created if needed.
+// When maxFiles is positive, the oldest profiles are removed so that at
+// most maxFiles remain.
+func NewDirSink(dir string, maxFiles int) *DirSink {
+	return &DirSink{dir: dir, maxFiles: maxFiles}
+}
+
+// Write implements Sink.
+func (s *DirSink) Write(_ context.Context, p *Profile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	base := filepath.Join(s.dir, fileName(p))
+	meta, err := json.Marshal(p.Metadata())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(base+".pb.gz", p.Data, 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(base+".json", meta, 0o644); err != nil {
+		return err
+	}
+	return s.prune()
+}
+
+// prune removes the oldest profiles beyond maxFiles. File names start
+// with a sortable timestamp, so name order is capture order.
+func (s *DirSink) prune() error {
+	if s.maxFiles <= 0 {
+		return nil
+	}
+	profiles, err := filepath.Glob(filepath.Join(s.dir, "*.pb.gz"))
+	if err != nil || len(profiles) <= s.maxFiles {
+		return err
+	}
+	sort.Strings(profiles)
+	for _, path := range profiles[:len(profiles)-s.maxFiles] {
+		os.Remove(path)
+		os.Remove(strings.TrimSuffix(path, ".pb.gz") + ".json")
+	}
+	return nil
+}
+
+// fileName returns the base name for p, e.g.
+// "20240501T120000.000Z-checkout-cpu".
+func fileName(p *Profile) string {
+	name := p.Time.UTC().Format("20060102T150405.000Z") + "-"
+	if svc := p.Resource.ServiceName(); svc != "" {
+		name += strings.Map(func(r rune) rune {
+			if r == '/' || r == '\\' || r == ' ' {
+				return '_'
+			}
+			return r
+		}, svc) + "-"
+	}
+	return name + string(p.Type)
+}
+
+// HTTPSink posts each profile to an endpoint as a multipart form with a
+// "metadata" part holding the JSON Metadata and a "profile" part holding
+// the pprof data.
+type HTTPSink struct {
+	// URL is the endpoint to post to.
+	URL string
+
+	// Headers are added to each request, e.g. for authentication.
+	Headers map[string]string
+
+	// Client sends the requests. Default: a client with a 30s timeout.
+	Client *http.Client
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// Write implements Sink.
+func (s *HTTPSink) Write(ctx context.Context, p *Profile) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	meta, err := mw.CreateFormField("metadata")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(meta).Encode(p.Metadata()); err != nil {
+		return err
+	}
+	data, err := mw.CreateFormFile("profile", fileName(p)+".pb.gz")
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write(p.Data); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("profile: %s returned %s", s.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package lumen
+
+import (
+	"sync"
+
+	"github.com/kolosys/lumen/trace"
+)
+
+// RecentSpans keeps the most recently ended spans in a fixed-size ring for
+// inspection, e.g. via AdminHandler's /traces/recent. Add it to
+// trace.Options.Processors; it passes spans through unchanged.
+type RecentSpans struct {
+	mu    sync.Mutex
+	spans []*trace.SpanSnapshot
+	next  int
+	full  bool
+}
+
+// NewRecentSpans creates a ring holding up to size spans (minimum 1).
+func NewRecentSpans(size int) *RecentSpans {
+	return &RecentSpans{spans: make([]*trace.SpanSnapshot, max(size, 1))}
+}
+
+// Process implements trace.Processor.
+func (r *RecentSpans) Process(span *trace.SpanSnapshot) *trace.SpanSnapshot {
+	r.mu.Lock()
+	r.spans[r.next] = span
+	r.next = (r.next + 1) % len(r.spans)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mu.Unlock()
+	return span
+}
+
+// Spans returns the retained spans, oldest first.
+func (r *RecentSpans) Spans() []*trace.SpanSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]*trace.SpanSnapshot(nil), r.spans[:r.next]...)
+	}
+	out := make([]*trace.SpanSnapshot, 0, len(r.spans))
+	out = append(out, r.spans[r.next:]...)
+	return append(out, r.spans[:r.next]...)
+}
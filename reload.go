@@ -0,0 +1,238 @@
+package lumen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolosys/lumen/internal/watch"
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/trace"
+)
+
+// ErrInvalidLevel is returned when a configured log level is not known.
+var ErrInvalidLevel = errors.New("lumen: invalid log level")
+
+// LoadConfigFile reads a Config from path. decode parses the file, e.g.
+// yaml.Unmarshal; nil decodes JSON.
+func LoadConfigFile(path string, decode func(data []byte, v any) error) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if decode == nil {
+		decode = json.Unmarshal
+	}
+	if err := decode(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("lumen: parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// CurrentConfig returns the configuration in effect, including changes
+// applied by Reload.
+func (o *Observability) CurrentConfig() Config {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.live
+}
+
+// Reload applies the settings of cfg that can change at runtime: the log
+// level, the trace sample ratio and the OTLP endpoints of exporters
+// created by Setup. cfg is validated first, so either every change is
+// applied or none is. Other differences need a new Setup; they are listed
+// in the audit entry logged for each reload that changes anything.
+func (o *Observability) Reload(cfg Config) error {
+	cfg.Logs.Output = o.Config.Logs.Output
+	cfg.Trace.SpanExporter = o.Config.Trace.SpanExporter
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = o.Resource.ServiceName()
+	}
+	cfg.applyDefaults()
+
+	level, err := parseLevel(cfg.Logs.Level)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.apply(cfg, level)
+	return nil
+}
+
+// setLogLevel changes the log level the way Reload does, keeping the live
+// configuration in step so a later Reload does not revert it.
+func (o *Observability) setLogLevel(level logs.Level) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	cfg := o.live
+	cfg.Logs.Level = level.String()
+	o.apply(cfg, level)
+}
+
+// logLevel returns the level entries outside sampled traces are logged at.
+func (o *Observability) logLevel() logs.Level {
+	if o.logSampler != nil {
+		return o.logSampler.Threshold()
+	}
+	return o.Logger.GetLevel()
+}
+
+// apply makes cfg, already validated, the live configuration and logs an
+// audit entry for what changed. o.mu must be held.
+func (o *Observability) apply(cfg Config, level logs.Level) {
+	old := o.live
+
+	var changes, ignored []string
+	change := func(name, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", name, from, to))
+		}
+	}
+	change("logs.level", old.Logs.Level, cfg.Logs.Level)
+	change("trace.sample_ratio", fmt.Sprint(old.Trace.SampleRatio), fmt.Sprint(cfg.Trace.SampleRatio))
+	if o.traceExporter != nil {
+		change("trace.endpoint", old.Trace.Endpoint, cfg.Trace.Endpoint)
+	}
+	if o.metricExporter != nil {
+		change("metrics.endpoint", old.Metrics.Endpoint, cfg.Metrics.Endpoint)
+	}
+	for name, differs := range map[string]bool{
+		"service_name":     old.ServiceName != cfg.ServiceName,
+		"logs.format":      old.Logs.Format != cfg.Logs.Format,
+		"metrics.exporter": old.Metrics.Exporter != cfg.Metrics.Exporter,
+		"trace.exporter":   old.Trace.Exporter != cfg.Trace.Exporter,
+	} {
+		if differs {
+			ignored = append(ignored, name)
+		}
+	}
+	if len(changes) == 0 && len(ignored) == 0 {
+		return
+	}
+	sort.Strings(changes)
+	sort.Strings(ignored)
+
+	o.Logger.SetLevel(o.loggerLevel(level))
+	o.sampler.set(cfg.sampler())
+	if o.traceExporter != nil && cfg.Trace.Endpoint != "" {
+		o.traceExporter.SetURL(cfg.Trace.Endpoint)
+	}
+	if o.metricExporter != nil && cfg.Metrics.Endpoint != "" {
+		o.metricExporter.SetURL(cfg.Metrics.Endpoint)
+	}
+	o.live = cfg
+
+	fields := []logs.Field{logs.Strings("changes", changes)}
+	if len(ignored) > 0 {
+		fields = append(fields, logs.Strings("restart_required", ignored))
+	}
+	o.Logger.Info("lumen config reloaded", fields...)
+}
+
+func parseLevel(s string) (logs.Level, error) {
+	if s == "" {
+		return logs.InfoLevel, nil
+	}
+	// ParseLevel falls back to info for unknown names.
+	level := logs.ParseLevel(s)
+	if level == logs.InfoLevel && !strings.EqualFold(strings.TrimSpace(s), "info") {
+		return level, fmt.Errorf("%w: %q", ErrInvalidLevel, s)
+	}
+	return level, nil
+}
+
+// WatchOptions configures WatchConfig.
+type WatchOptions struct {
+	// Interval is how often the file's modification time is checked.
+	// Defaults to 2s.
+	Interval time.Duration
+
+	// Decode parses the file; see LoadConfigFile.
+	Decode func(data []byte, v any) error
+
+	// DisableSIGHUP stops SIGHUP from triggering a reload.
+	DisableSIGHUP bool
+
+	// OnError is called when the file cannot be read or applied. Errors
+	// are logged at error level by default.
+	OnError func(err error)
+}
+
+func (o *WatchOptions) applyDefaults() {
+	if o.Interval <= 0 {
+		o.Interval = 2 * time.Second
+	}
+}
+
+// WatchConfig reloads the config file at path whenever its modification
+// time changes, or on SIGHUP, until the returned stop function is called.
+// The file is not applied at start; call Reload first if needed.
+func (o *Observability) WatchConfig(path string, opts *WatchOptions) (stop func()) {
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+	wo := *opts
+	wo.applyDefaults()
+	if wo.OnError == nil {
+		wo.OnError = func(err error) {
+			o.Logger.Error("lumen config reload failed", logs.Err(err))
+		}
+	}
+
+	return watch.File(path, watch.Options{
+		Interval: wo.Interval,
+		SIGHUP:   !wo.DisableSIGHUP,
+		Reload: func() error {
+			cfg, err := LoadConfigFile(path, wo.Decode)
+			if err != nil {
+				return err
+			}
+			return o.Reload(cfg)
+		},
+		OnError: wo.OnError,
+	})
+}
+
+// dynamicSampler delegates to a sampler that Reload can replace, and
+// samples everything in scope while a boost is active.
+type dynamicSampler struct {
+	s     atomic.Pointer[trace.Sampler]
+	boost atomic.Pointer[boostState]
+}
+
+func newDynamicSampler(s trace.Sampler) *dynamicSampler {
+	d := &dynamicSampler{}
+	d.set(s)
+	return d
+}
+
+func (d *dynamicSampler) set(s trace.Sampler) { d.s.Store(&s) }
+
+func (d *dynamicSampler) ShouldSample(params trace.SamplingParams) bool {
+	return d.Sample(params).Sampled
+}
+
+func (d *dynamicSampler) Sample(params trace.SamplingParams) trace.SamplingResult {
+	if b := d.boost.Load(); b != nil && (b.covers(params.TraceID) || b.matches(params.Attributes)) {
+		if len(b.scope) > 0 {
+			b.traces.Store(params.TraceID, struct{}{})
+		}
+		return trace.SamplingResult{
+			Sampled:    true,
+			Attributes: []trace.Attribute{{Key: trace.SamplingRuleKey, Value: BoostRule}},
+		}
+	}
+	s := *d.s.Load()
+	if rs, ok := s.(trace.ResultSampler); ok {
+		return rs.Sample(params)
+	}
+	return trace.SamplingResult{Sampled: s.ShouldSample(params)}
+}
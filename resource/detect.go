@@ -0,0 +1,111 @@
+package resource
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// k8sNamespaceFile holds the pod's namespace when a service account token
+// is mounted.
+const k8sNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Detect returns attributes describing the current process, read from the
+// host and environment variables only; it never makes network calls:
+//
+//   - host.name and process.pid
+//   - service.name, service.version, deployment.environment and
+//     service.instance.id from LUMEN_SERVICE, LUMEN_VERSION, LUMEN_ENV and
+//     LUMEN_INSTANCE or their common alternatives (OTEL_SERVICE_NAME,
+//     SERVICE_VERSION, DEPLOYMENT_ENVIRONMENT, POD_NAME)
+//   - k8s.* when running in Kubernetes, using the downward API variables
+//     POD_NAME, POD_NAMESPACE and NODE_NAME where set
+//   - cloud.* and faas.name on AWS Lambda and ECS, Google Cloud Run and
+//     Azure App Service
+//   - anything in OTEL_RESOURCE_ATTRIBUTES, which overrides the above
+func Detect() map[string]string {
+	attrs := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			attrs[key] = value
+		}
+	}
+
+	host, _ := os.Hostname()
+	set(HostNameKey, host)
+	set(ProcessPIDKey, strconv.Itoa(os.Getpid()))
+
+	set(ServiceNameKey, firstEnv("LUMEN_SERVICE", "OTEL_SERVICE_NAME", "SERVICE_NAME"))
+	set(ServiceVersionKey, firstEnv("LUMEN_VERSION", "SERVICE_VERSION"))
+	set(EnvironmentKey, firstEnv("LUMEN_ENV", "DEPLOYMENT_ENVIRONMENT"))
+	set(ServiceInstanceIDKey, firstEnv("LUMEN_INSTANCE", "POD_NAME"))
+
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		pod := firstEnv("POD_NAME", "HOSTNAME")
+		if pod == "" {
+			pod = host
+		}
+		set(K8sPodNameKey, pod)
+		ns := firstEnv("POD_NAMESPACE")
+		if ns == "" {
+			if b, err := os.ReadFile(k8sNamespaceFile); err == nil {
+				ns = strings.TrimSpace(string(b))
+			}
+		}
+		set(K8sNamespaceKey, ns)
+		set(K8sNodeNameKey, firstEnv("NODE_NAME", "K8S_NODE_NAME"))
+	}
+
+	switch {
+	case os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "":
+		set(CloudProviderKey, "aws")
+		set(CloudPlatformKey, "aws_lambda")
+		set(CloudRegionKey, os.Getenv("AWS_REGION"))
+		set(FaaSNameKey, os.Getenv("AWS_LAMBDA_FUNCTION_NAME"))
+	case os.Getenv("ECS_CONTAINER_METADATA_URI_V4") != "":
+		set(CloudProviderKey, "aws")
+		set(CloudPlatformKey, "aws_ecs")
+		set(CloudRegionKey, os.Getenv("AWS_REGION"))
+	case os.Getenv("K_SERVICE") != "":
+		set(CloudProviderKey, "gcp")
+		set(CloudPlatformKey, "gcp_cloud_run")
+		set(FaaSNameKey, os.Getenv("K_SERVICE"))
+	case os.Getenv("WEBSITE_SITE_NAME") != "":
+		set(CloudProviderKey, "azure")
+		set(CloudPlatformKey, "azure_app_service")
+		set(CloudRegionKey, os.Getenv("REGION_NAME"))
+	}
+
+	for k, v := range parseAttributes(os.Getenv("OTEL_RESOURCE_ATTRIBUTES")) {
+		attrs[k] = v
+	}
+	return attrs
+}
+
+// parseAttributes parses the OTEL_RESOURCE_ATTRIBUTES format: comma
+// separated key=value pairs with percent-encoded values. Malformed pairs
+// are skipped.
+func parseAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for pair := range strings.SplitSeq(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			continue
+		}
+		if dec, err := url.PathUnescape(strings.TrimSpace(v)); err == nil {
+			attrs[k] = dec
+		}
+	}
+	return attrs
+}
+
+func firstEnv(keys ...string) string {
+	for _, k := range keys {
+		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+// Package resource describes the entity producing telemetry: the service,
+// its version, instance and environment, and where it runs. One Resource
+// is shared by the logs, metrics and trace packages so that all three
+// signals identify the service the same way.
+//
+// Basic usage:
+//
+//	res := resource.New(&resource.Options{ServiceName: "checkout", ServiceVersion: "1.4.2"})
+//	logger := logs.New(&logs.Options{Resource: res})
+//	registry := metrics.NewRegistry(&metrics.Options{Resource: res})
+//	tracer := trace.New(&trace.Options{Resource: res})
+package resource
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+	"slices"
+)
+
+// Attribute keys, following the OpenTelemetry semantic conventions.
+const (
+	ServiceNameKey       = "service.name"
+	ServiceVersionKey    = "service.version"
+	ServiceInstanceIDKey = "service.instance.id"
+	EnvironmentKey       = "deployment.environment"
+	HostNameKey          = "host.name"
+	ProcessPIDKey        = "process.pid"
+	K8sNamespaceKey      = "k8s.namespace.name"
+	K8sPodNameKey        = "k8s.pod.name"
+	K8sNodeNameKey       = "k8s.node.name"
+	CloudProviderKey     = "cloud.provider"
+	CloudPlatformKey     = "cloud.platform"
+	CloudRegionKey       = "cloud.region"
+	FaaSNameKey          = "faas.name"
+)
+
+// Short keys used by Identity, matching the labels of
+// metrics.DetectInstanceLabels. The instance is not keyed "instance",
+// which Prometheus sets to the scrape target.
+const (
+	ServiceLabel     = "service"
+	VersionLabel     = "version"
+	EnvironmentLabel = "env"
+	InstanceLabel    = "service_instance"
+)
+
+// Options configures a Resource.
+type Options struct {
+	// ServiceName identifies the service. Defaults to the detected
+	// service name, then "unknown".
+	ServiceName string
+
+	// ServiceVersion is the version of the service.
+	ServiceVersion string
+
+	// Environment is the deployment environment, e.g. "production".
+	Environment string
+
+	// InstanceID identifies this process among instances of the service.
+	// Defaults to the detected instance (e.g. the Kubernetes pod name),
+	// then the host name, then a random ID.
+	InstanceID string
+
+	// Attributes are additional attributes. They override detected ones
+	// and are overridden by the fields above.
+	Attributes map[string]string
+
+	// DisableDetection skips reading the environment; see Detect.
+	DisableDetection bool
+}
+
+// Resource is an immutable set of attributes describing a service.
+type Resource struct {
+	attrs map[string]string
+}
+
+// KeyValue is a resource attribute.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// New creates a Resource from opts and, unless disabled, the attributes
+// returned by Detect. If opts is nil, default options are used.
+func New(opts *Options) *Resource {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	attrs := make(map[string]string)
+	if !opts.DisableDetection {
+		maps.Copy(attrs, Detect())
+	}
+	maps.Copy(attrs, opts.Attributes)
+	set := func(key, value string) {
+		if value != "" {
+			attrs[key] = value
+		}
+	}
+	set(ServiceNameKey, opts.ServiceName)
+	set(ServiceVersionKey, opts.ServiceVersion)
+	set(EnvironmentKey, opts.Environment)
+	set(ServiceInstanceIDKey, opts.InstanceID)
+
+	if attrs[ServiceNameKey] == "" {
+		attrs[ServiceNameKey] = "unknown"
+	}
+	if attrs[ServiceInstanceIDKey] == "" {
+		attrs[ServiceInstanceIDKey] = instanceID(attrs)
+	}
+	return &Resource{attrs: attrs}
+}
+
+// FromMap creates a Resource with exactly the attributes in attrs, e.g.
+// ones received with an OTLP export. No defaults are applied.
+func FromMap(attrs map[string]string) *Resource {
+	return &Resource{attrs: maps.Clone(attrs)}
+}
+
+func instanceID(attrs map[string]string) string {
+	for _, key := range []string{K8sPodNameKey, HostNameKey} {
+		if v := attrs[key]; v != "" {
+			return v
+		}
+	}
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Get returns the value of the attribute key, or "" if it is not set.
+func (r *Resource) Get(key string) string {
+	if r == nil {
+		return ""
+	}
+	return r.attrs[key]
+}
+
+// ServiceName returns the service.name attribute.
+func (r *Resource) ServiceName() string { return r.Get(ServiceNameKey) }
+
+// ServiceVersion returns the service.version attribute.
+func (r *Resource) ServiceVersion() string { return r.Get(ServiceVersionKey) }
+
+// Environment returns the deployment.environment attribute.
+func (r *Resource) Environment() string { return r.Get(EnvironmentKey) }
+
+// InstanceID returns the service.instance.id attribute.
+func (r *Resource) InstanceID() string { return r.Get(ServiceInstanceIDKey) }
+
+// Attributes returns all attributes sorted by key, as attached to spans
+// and OTLP exports.
+func (r *Resource) Attributes() []KeyValue {
+	if r == nil {
+		return nil
+	}
+	out := make([]KeyValue, 0, len(r.attrs))
+	for _, k := range slices.Sorted(maps.Keys(r.attrs)) {
+		out = append(out, KeyValue{Key: k, Value: r.attrs[k]})
+	}
+	return out
+}
+
+// Map returns a copy of the attributes.
+func (r *Resource) Map() map[string]string {
+	if r == nil {
+		return nil
+	}
+	return maps.Clone(r.attrs)
+}
+
+// Identity returns the service, version, env and service_instance under
+// their short keys, omitting unset ones. Logs add them as default fields
+// and metrics as default labels; the full attribute set would add too many
+// fields to every entry and too many labels to every series.
+func (r *Resource) Identity() []KeyValue {
+	var out []KeyValue
+	for _, kv := range [...]KeyValue{
+		{ServiceLabel, r.ServiceName()},
+		{VersionLabel, r.ServiceVersion()},
+		{EnvironmentLabel, r.Environment()},
+		{InstanceLabel, r.InstanceID()},
+	} {
+		if kv.Value != "" {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
+
+// Merge returns a Resource with the attributes of r and other; other's
+// values win.
+func (r *Resource) Merge(other *Resource) *Resource {
+	attrs := r.Map()
+	if attrs == nil {
+		attrs = make(map[string]string)
+	}
+	maps.Copy(attrs, other.Map())
+	return &Resource{attrs: attrs}
+}
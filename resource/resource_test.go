@@ -0,0 +1,58 @@
+package resource_test
+
+import (
+	"slices"
+	"testing"
+
+	. "github.com/kolosys/lumen/resource"
+)
+
+func TestNewPrecedence(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("POD_NAME", "checkout-7d9f")
+	t.Setenv("POD_NAMESPACE", "shop")
+	t.Setenv("OTEL_SERVICE_NAME", "from-env")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "team=payments,deployment.environment=staging,note=a%20b")
+
+	res := New(&Options{ServiceName: "checkout", Attributes: map[string]string{"team": "billing"}})
+
+	for key, want := range map[string]string{
+		ServiceNameKey:       "checkout",
+		ServiceInstanceIDKey: "checkout-7d9f",
+		K8sPodNameKey:        "checkout-7d9f",
+		K8sNamespaceKey:      "shop",
+		EnvironmentKey:       "staging",
+		"team":               "billing",
+		"note":               "a b",
+	} {
+		if got := res.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	want := []KeyValue{
+		{ServiceLabel, "checkout"},
+		{EnvironmentLabel, "staging"},
+		{InstanceLabel, "checkout-7d9f"},
+	}
+	if got := res.Identity(); !slices.Equal(got, want) {
+		t.Errorf("Identity() = %v, want %v", got, want)
+	}
+}
+
+func TestNewWithoutDetection(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "from-env")
+
+	res := New(&Options{DisableDetection: true, InstanceID: "i-1"})
+	if res.ServiceName() != "unknown" || res.InstanceID() != "i-1" {
+		t.Errorf("unexpected resource: %v", res.Attributes())
+	}
+	if res.Get(HostNameKey) != "" {
+		t.Errorf("expected no detected attributes, got %v", res.Attributes())
+	}
+
+	merged := res.Merge(FromMap(map[string]string{ServiceNameKey: "checkout"}))
+	if merged.ServiceName() != "checkout" || merged.InstanceID() != "i-1" {
+		t.Errorf("unexpected merge: %v", merged.Attributes())
+	}
+}
@@ -0,0 +1,71 @@
+package trace
+
+import (
+	"sort"
+	"time"
+)
+
+// ActiveSpan describes a span that has started but not yet ended.
+type ActiveSpan struct {
+	Name      string
+	TraceID   TraceID
+	SpanID    SpanID
+	ParentID  SpanID
+	StartTime time.Time
+	Elapsed   time.Duration
+}
+
+// ActiveSpans returns the spans that are currently running, oldest first.
+// It returns nil unless Options.TrackActiveSpans or HeartbeatInterval is set.
+func (t *Tracer) ActiveSpans() []ActiveSpan {
+	if !t.opts.TrackActiveSpans {
+		return nil
+	}
+
+	now := time.Now()
+	var spans []ActiveSpan
+	t.active.Range(func(key, _ any) bool {
+		s := key.(*Span)
+		spans = append(spans, ActiveSpan{
+			Name:      s.Name(),
+			TraceID:   s.traceID,
+			SpanID:    s.spanID,
+			ParentID:  s.parentID,
+			StartTime: s.startTime,
+			Elapsed:   now.Sub(s.startTime),
+		})
+		return true
+	})
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].StartTime.Before(spans[j].StartTime)
+	})
+	return spans
+}
+
+func (t *Tracer) heartbeatLoop(interval time.Duration) {
+	defer t.bgWg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case now := <-ticker.C:
+			t.heartbeat(now, interval)
+		}
+	}
+}
+
+func (t *Tracer) heartbeat(now time.Time, interval time.Duration) {
+	t.active.Range(func(key, _ any) bool {
+		s := key.(*Span)
+		elapsed := now.Sub(s.startTime)
+		if elapsed >= interval {
+			s.AddEvent("heartbeat", Attribute{Key: "elapsed_ms", Value: elapsed.Milliseconds()})
+		}
+		return true
+	})
+}
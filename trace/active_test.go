@@ -0,0 +1,108 @@
+package trace_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/trace"
+)
+
+func TestActiveSpans(t *testing.T) {
+	tracer := New(&Options{TrackActiveSpans: true})
+
+	ctx, first := tracer.Start(context.Background(), "first", WithStartTime(time.Now().Add(-time.Second)))
+	_, second := tracer.Start(ctx, "second")
+
+	active := tracer.ActiveSpans()
+	if len(active) != 2 {
+		t.Fatalf("expected 2 active spans, got %d", len(active))
+	}
+	if active[0].Name != "first" || active[1].Name != "second" {
+		t.Errorf("expected oldest first, got %s, %s", active[0].Name, active[1].Name)
+	}
+	if active[1].TraceID != first.TraceID() || active[1].SpanID != second.SpanID() || active[1].ParentID != first.SpanID() {
+		t.Errorf("unexpected ids %+v", active[1])
+	}
+	if active[0].Elapsed < time.Second {
+		t.Errorf("elapsed = %v, want at least 1s", active[0].Elapsed)
+	}
+
+	first.End()
+	if active := tracer.ActiveSpans(); len(active) != 1 || active[0].Name != "second" {
+		t.Errorf("after ending first: %+v", active)
+	}
+	second.End()
+	if active := tracer.ActiveSpans(); len(active) != 0 {
+		t.Errorf("after ending both: %+v", active)
+	}
+}
+
+func TestActiveSpansRenamed(t *testing.T) {
+	tracer := New(&Options{TrackActiveSpans: true, RecordDurations: true})
+	_, span := tracer.Start(context.Background(), "GET")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		span.SetName("GET /orders")
+		span.End()
+	}()
+	for range 100 {
+		tracer.ActiveSpans()
+	}
+	<-done
+
+	if st, ok := tracer.DurationStats("GET /orders"); !ok || st.Count != 1 {
+		t.Errorf("expected the duration under the new name, got %+v", st)
+	}
+}
+
+func TestActiveSpansDisabled(t *testing.T) {
+	tracer := New(nil)
+	_, span := tracer.Start(context.Background(), "op")
+	defer span.End()
+	if active := tracer.ActiveSpans(); active != nil {
+		t.Errorf("expected nil without TrackActiveSpans, got %+v", active)
+	}
+}
+
+func heartbeats(span *Span) int {
+	n := 0
+	for _, ev := range span.Events() {
+		if ev.Name == "heartbeat" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestHeartbeat(t *testing.T) {
+	tracer := New(&Options{HeartbeatInterval: 10 * time.Millisecond})
+	defer tracer.Close()
+
+	_, long := tracer.Start(context.Background(), "long", WithStartTime(time.Now().Add(-time.Second)))
+	if len(tracer.ActiveSpans()) != 1 {
+		t.Fatal("expected HeartbeatInterval to enable active span tracking")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for heartbeats(long) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if heartbeats(long) < 2 {
+		t.Fatal("expected heartbeat events on a long-running span")
+	}
+	for _, ev := range long.Events() {
+		if ms, _ := ev.Attributes[0].Value.(int64); ev.Attributes[0].Key != "elapsed_ms" || ms < 1000 {
+			t.Errorf("unexpected heartbeat attributes %v", ev.Attributes)
+		}
+	}
+
+	long.End()
+	n := heartbeats(long)
+	time.Sleep(30 * time.Millisecond)
+	if heartbeats(long) != n {
+		t.Error("heartbeat added to an ended span")
+	}
+}
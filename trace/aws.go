@@ -0,0 +1,177 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"strings"
+)
+
+// AWS X-Ray trace header names.
+const (
+	// XRayTraceHeader is the HTTP header used by ALB, API Gateway and X-Ray.
+	XRayTraceHeader = "X-Amzn-Trace-Id"
+	// AWSTraceHeaderAttribute is the SQS system attribute carrying the header.
+	AWSTraceHeaderAttribute = "AWSTraceHeader"
+	// LambdaTraceEnv is the environment variable Lambda sets per invocation.
+	LambdaTraceEnv = "_X_AMZN_TRACE_ID"
+)
+
+// ParseXRayTraceHeader parses an X-Ray trace header such as
+// "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1".
+// The X-Ray root ID maps directly onto a 128-bit W3C trace ID.
+func ParseXRayTraceHeader(header string) (*TraceContext, error) {
+	var tc TraceContext
+	var hasRoot bool
+
+	for _, part := range strings.Split(header, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Root":
+			fields := strings.Split(value, "-")
+			if len(fields) != 3 || fields[0] != "1" || len(fields[1]) != 8 || len(fields[2]) != 24 {
+				return nil, ErrInvalidTraceID
+			}
+			id, err := hex.DecodeString(fields[1] + fields[2])
+			if err != nil {
+				return nil, ErrInvalidTraceID
+			}
+			copy(tc.TraceID[:], id)
+			hasRoot = true
+		case "Parent":
+			id, err := hex.DecodeString(value)
+			if err != nil || len(id) != 8 {
+				return nil, ErrInvalidSpanID
+			}
+			copy(tc.SpanID[:], id)
+		case "Sampled":
+			tc.SetSampled(value == "1")
+		}
+	}
+
+	if !hasRoot || !tc.TraceID.IsValid() {
+		return nil, ErrInvalidTraceID
+	}
+	return &tc, nil
+}
+
+// FormatXRayTraceHeader formats a TraceContext as an X-Ray trace header.
+func (tc *TraceContext) FormatXRayTraceHeader() string {
+	id := tc.TraceID.String()
+	sampled := "0"
+	if tc.IsSampled() {
+		sampled = "1"
+	}
+	return "Root=1-" + id[:8] + "-" + id[8:] + ";Parent=" + tc.SpanID.String() + ";Sampled=" + sampled
+}
+
+// XRayPropagator implements AWS X-Ray header propagation.
+type XRayPropagator struct{}
+
+func (p *XRayPropagator) Inject(ctx context.Context, carrier Carrier) {
+	span := SpanFromContext(ctx)
+	if span == nil || !span.traceID.IsValid() {
+		return
+	}
+
+	tc := &TraceContext{
+		TraceID: span.traceID,
+		SpanID:  span.spanID,
+	}
+	tc.SetSampled(span.sampled)
+
+	carrier.Set(XRayTraceHeader, tc.FormatXRayTraceHeader())
+}
+
+func (p *XRayPropagator) Extract(ctx context.Context, carrier Carrier) context.Context {
+	header := carrier.Get(XRayTraceHeader)
+	if header == "" {
+		return ctx
+	}
+
+	tc, err := ParseXRayTraceHeader(header)
+	if err != nil {
+		return ctx
+	}
+
+	return ContextWithTraceContext(ctx, tc)
+}
+
+// LambdaContext attaches the trace context of the current Lambda invocation,
+// read from the _X_AMZN_TRACE_ID environment variable, to ctx.
+func LambdaContext(ctx context.Context) context.Context {
+	header := os.Getenv(LambdaTraceEnv)
+	if header == "" {
+		return ctx
+	}
+
+	tc, err := ParseXRayTraceHeader(header)
+	if err != nil {
+		return ctx
+	}
+
+	return ContextWithTraceContext(ctx, tc)
+}
+
+// StartLambda starts a span for a Lambda invocation, parented to the
+// invocation's X-Ray trace context when present.
+func (t *Tracer) StartLambda(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	attrs := []Attribute{{Key: "cloud.provider", Value: "aws"}}
+	if fn := os.Getenv("AWS_LAMBDA_FUNCTION_NAME"); fn != "" {
+		attrs = append(attrs, Attribute{Key: "faas.name", Value: fn})
+	}
+	opts = append([]SpanOption{WithAttributes(attrs...)}, opts...)
+
+	return t.Start(LambdaContext(ctx), name, opts...)
+}
+
+// MessageContext extracts trace context from SQS system attributes or SNS/SQS
+// message attributes. AWSTraceHeader takes precedence over a W3C traceparent.
+// If a context is found, it replaces any span already present in ctx so that
+// spans started from the result join the message's trace.
+func MessageContext(ctx context.Context, attributes map[string]string) context.Context {
+	carrier := MapCarrier(attributes)
+
+	var tc *TraceContext
+	if header := carrier.Get(AWSTraceHeaderAttribute); header != "" {
+		tc, _ = ParseXRayTraceHeader(header)
+	}
+	if tc == nil {
+		if header := carrier.Get(W3CTraceparentHeader); header != "" {
+			tc, _ = ParseW3CTraceparent(header)
+		}
+	}
+	if tc == nil {
+		return ctx
+	}
+
+	return ContextWithTraceContext(ContextWithSpan(ctx, nil), tc)
+}
+
+// StartSQSMessage starts a consumer span for a single SQS message, parented to
+// the trace context carried in its attributes.
+func (t *Tracer) StartSQSMessage(ctx context.Context, queue, messageID string, attributes map[string]string, opts ...SpanOption) (context.Context, *Span) {
+	opts = append([]SpanOption{WithAttributes(
+		Attribute{Key: "messaging.system", Value: "aws_sqs"},
+		Attribute{Key: "messaging.operation", Value: "process"},
+		Attribute{Key: "messaging.destination.name", Value: queue},
+		Attribute{Key: "messaging.message.id", Value: messageID},
+	)}, opts...)
+
+	return t.Start(MessageContext(ctx, attributes), queue+" process", opts...)
+}
+
+// StartSNSMessage starts a consumer span for a single SNS notification.
+func (t *Tracer) StartSNSMessage(ctx context.Context, topic, messageID string, attributes map[string]string, opts ...SpanOption) (context.Context, *Span) {
+	opts = append([]SpanOption{WithAttributes(
+		Attribute{Key: "messaging.system", Value: "aws_sns"},
+		Attribute{Key: "messaging.operation", Value: "process"},
+		Attribute{Key: "messaging.destination.name", Value: topic},
+		Attribute{Key: "messaging.message.id", Value: messageID},
+	)}, opts...)
+
+	return t.Start(MessageContext(ctx, attributes), topic+" process", opts...)
+}
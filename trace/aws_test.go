@@ -0,0 +1,190 @@
+package trace_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/kolosys/lumen/trace"
+)
+
+const (
+	xrayRoot   = "1-5759e988-bd862e3fe1be46a994272793"
+	xrayParent = "53995c3f42cd8ad8"
+)
+
+func TestParseXRayTraceHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		valid   bool
+		span    string
+		sampled bool
+	}{
+		{"sampled", "Root=" + xrayRoot + ";Parent=" + xrayParent + ";Sampled=1", true, xrayParent, true},
+		{"unsampled", "Root=" + xrayRoot + ";Parent=" + xrayParent + ";Sampled=0", true, xrayParent, false},
+		{"sampling deferred", "Root=" + xrayRoot + ";Parent=" + xrayParent + ";Sampled=?", true, xrayParent, false},
+		{"no sampled field", "Root=" + xrayRoot + ";Parent=" + xrayParent, true, xrayParent, false},
+		{"root only", "Root=" + xrayRoot, true, "0000000000000000", false},
+		{"fields reordered", "Sampled=1;Parent=" + xrayParent + ";Root=" + xrayRoot, true, xrayParent, true},
+		{"spaces and extra fields", "Root=" + xrayRoot + "; Parent=" + xrayParent + "; Sampled=1; Lineage=a87bd80c:0", true, xrayParent, true},
+		{"missing root", "Parent=" + xrayParent + ";Sampled=1", false, "", false},
+		{"root version 2", "Root=2-5759e988-bd862e3fe1be46a994272793", false, "", false},
+		{"short epoch", "Root=1-5759e98-bd862e3fe1be46a994272793", false, "", false},
+		{"short root id", "Root=1-5759e988-bd862e3fe1be46a99427279", false, "", false},
+		{"root not hex", "Root=1-5759e988-bd862e3fe1be46a99427279z", false, "", false},
+		{"root missing part", "Root=1-bd862e3fe1be46a994272793", false, "", false},
+		{"zero root", "Root=1-00000000-000000000000000000000000", false, "", false},
+		{"short parent", "Root=" + xrayRoot + ";Parent=53995c3f42cd8a", false, "", false},
+		{"parent not hex", "Root=" + xrayRoot + ";Parent=53995c3f42cd8adz", false, "", false},
+		{"empty", "", false, "", false},
+		{"garbage", "not an x-ray header", false, "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseXRayTraceHeader(tc.header)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("expected %q to be rejected", tc.header)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected %q to parse, got %v", tc.header, err)
+			}
+			if got.TraceID.String() != "5759e988bd862e3fe1be46a994272793" {
+				t.Errorf("trace id = %s", got.TraceID)
+			}
+			if got.SpanID.String() != tc.span {
+				t.Errorf("span id = %s, want %s", got.SpanID, tc.span)
+			}
+			if got.IsSampled() != tc.sampled {
+				t.Errorf("sampled = %v, want %v", got.IsSampled(), tc.sampled)
+			}
+		})
+	}
+}
+
+func TestXRayTraceHeaderRoundTrip(t *testing.T) {
+	headers := []string{
+		"Root=" + xrayRoot + ";Parent=" + xrayParent + ";Sampled=1",
+		"Root=" + xrayRoot + ";Parent=" + xrayParent + ";Sampled=0",
+	}
+
+	for _, header := range headers {
+		tc, err := ParseXRayTraceHeader(header)
+		if err != nil {
+			t.Fatalf("parse %q: %v", header, err)
+		}
+		if got := tc.FormatXRayTraceHeader(); got != header {
+			t.Errorf("round trip mismatch:\n got  %q\n want %q", got, header)
+		}
+	}
+}
+
+func TestXRayPropagatorRoundTrip(t *testing.T) {
+	p := &XRayPropagator{}
+	ctx := p.Extract(context.Background(), MapCarrier{
+		XRayTraceHeader: "Root=" + xrayRoot + ";Parent=" + xrayParent + ";Sampled=1",
+	})
+	if TraceContextFromContext(ctx) == nil {
+		t.Fatal("expected trace context")
+	}
+
+	_, span := New(nil).Start(ctx, "child")
+	if span.TraceID().String() != "5759e988bd862e3fe1be46a994272793" {
+		t.Errorf("child trace id = %s", span.TraceID())
+	}
+	if span.ParentID().String() != xrayParent {
+		t.Errorf("child parent id = %s", span.ParentID())
+	}
+
+	out := MapCarrier{}
+	p.Inject(ContextWithSpan(context.Background(), span), out)
+	want := "Root=" + xrayRoot + ";Parent=" + span.SpanID().String() + ";Sampled=1"
+	if got := out[XRayTraceHeader]; got != want {
+		t.Errorf("injected %q, want %q", got, want)
+	}
+
+	for _, header := range []string{"", "Root=garbage"} {
+		if ctx := p.Extract(context.Background(), MapCarrier{XRayTraceHeader: header}); TraceContextFromContext(ctx) != nil {
+			t.Errorf("expected no trace context from %q", header)
+		}
+	}
+}
+
+func TestLambdaContext(t *testing.T) {
+	t.Setenv(LambdaTraceEnv, "Root="+xrayRoot+";Parent="+xrayParent+";Sampled=1")
+	tc := TraceContextFromContext(LambdaContext(context.Background()))
+	if tc == nil {
+		t.Fatal("expected trace context")
+	}
+	if tc.SpanID.String() != xrayParent || !tc.IsSampled() {
+		t.Errorf("unexpected trace context %s/%s sampled=%v", tc.TraceID, tc.SpanID, tc.IsSampled())
+	}
+
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "checkout")
+	_, span := New(nil).StartLambda(context.Background(), "invoke")
+	if span.ParentID().String() != xrayParent {
+		t.Errorf("lambda span parent = %s", span.ParentID())
+	}
+	if !hasAttribute(span, "faas.name", "checkout") {
+		t.Errorf("missing faas.name in %v", span.Attributes())
+	}
+
+	t.Setenv(LambdaTraceEnv, "")
+	if TraceContextFromContext(LambdaContext(context.Background())) != nil {
+		t.Error("expected no trace context without the environment variable")
+	}
+}
+
+func TestMessageContext(t *testing.T) {
+	traceparent := "00-" + validTraceID + "-" + validSpanID + "-01"
+	xray := "Root=" + xrayRoot + ";Parent=" + xrayParent + ";Sampled=1"
+
+	tests := []struct {
+		name       string
+		attributes map[string]string
+		parent     string
+	}{
+		{"AWSTraceHeader wins", map[string]string{AWSTraceHeaderAttribute: xray, W3CTraceparentHeader: traceparent}, xrayParent},
+		{"traceparent only", map[string]string{W3CTraceparentHeader: traceparent}, validSpanID},
+		{"invalid AWSTraceHeader falls back", map[string]string{AWSTraceHeaderAttribute: "Root=bad", W3CTraceparentHeader: traceparent}, validSpanID},
+		{"none", map[string]string{"other": "x"}, ""},
+	}
+
+	tracer := New(nil)
+	_, current := tracer.Start(context.Background(), "poll")
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := MessageContext(ContextWithSpan(context.Background(), current), tc.attributes)
+			_, span := tracer.StartSQSMessage(ctx, "orders", "m-1", tc.attributes)
+
+			want := tc.parent
+			if want == "" {
+				want = current.SpanID().String()
+			}
+			if span.ParentID().String() != want {
+				t.Errorf("parent = %s, want %s", span.ParentID(), want)
+			}
+			if span.Name() != "orders process" || !hasAttribute(span, "messaging.system", "aws_sqs") ||
+				!hasAttribute(span, "messaging.message.id", "m-1") {
+				t.Errorf("unexpected span %q %v", span.Name(), span.Attributes())
+			}
+		})
+	}
+
+	_, span := tracer.StartSNSMessage(context.Background(), "events", "n-1", map[string]string{AWSTraceHeaderAttribute: xray})
+	if span.ParentID().String() != xrayParent || !hasAttribute(span, "messaging.system", "aws_sns") {
+		t.Errorf("unexpected SNS span parent %s attributes %v", span.ParentID(), span.Attributes())
+	}
+}
+
+func hasAttribute(span *Span, key string, value any) bool {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key && attr.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
-	"regexp"
 	"strings"
 )
 
@@ -33,6 +32,13 @@ type TraceContext struct {
 	SpanID     SpanID
 	TraceFlags byte
 	TraceState string
+
+	// Version is the traceparent version that was parsed (0 for "00").
+	Version byte
+	// Extension holds any data that followed the flags in a traceparent of
+	// a future version, without the leading '-'. It is written back by
+	// FormatW3CTraceparent so unknown fields survive propagation.
+	Extension string
 }
 
 // W3C Trace Context header names.
@@ -47,50 +53,98 @@ const (
 	SpanIDHeader  = "X-Span-ID"
 )
 
-var traceparentRegex = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+// traceparentLen is the length of a version 00 traceparent.
+const traceparentLen = 55
 
 // ParseW3CTraceparent parses a W3C traceparent header.
+//
+// Version 00 headers must match the specification exactly. Headers with a
+// higher version are parsed leniently: the first four fields are read with
+// the version 00 rules and anything after them is kept in Extension.
 func ParseW3CTraceparent(header string) (*TraceContext, error) {
-	header = strings.TrimSpace(strings.ToLower(header))
-	matches := traceparentRegex.FindStringSubmatch(header)
-	if matches == nil {
+	header = strings.TrimSpace(header)
+	if len(header) < traceparentLen {
 		return nil, ErrInvalidContext
 	}
 
-	version := matches[1]
-	if version != "00" {
+	version, ok := parseHexByte(header[0:2])
+	if !ok || version == 0xff || header[2] != '-' {
 		return nil, ErrInvalidContext
 	}
 
 	var tc TraceContext
+	tc.Version = version
 
-	traceIDBytes, err := hex.DecodeString(matches[2])
-	if err != nil || len(traceIDBytes) != 16 {
+	if version == 0 && len(header) != traceparentLen {
+		return nil, ErrInvalidContext
+	}
+	if len(header) > traceparentLen {
+		if header[traceparentLen] != '-' {
+			return nil, ErrInvalidContext
+		}
+		tc.Extension = header[traceparentLen+1:]
+	}
+
+	if header[35] != '-' || header[52] != '-' {
+		return nil, ErrInvalidContext
+	}
+
+	if !isLowerHex(header[3:35]) {
+		return nil, ErrInvalidTraceID
+	}
+	hex.Decode(tc.TraceID[:], []byte(header[3:35]))
+	if !tc.TraceID.IsValid() {
 		return nil, ErrInvalidTraceID
 	}
-	copy(tc.TraceID[:], traceIDBytes)
 
-	spanIDBytes, err := hex.DecodeString(matches[3])
-	if err != nil || len(spanIDBytes) != 8 {
+	if !isLowerHex(header[36:52]) {
+		return nil, ErrInvalidSpanID
+	}
+	hex.Decode(tc.SpanID[:], []byte(header[36:52]))
+	if !tc.SpanID.IsValid() {
 		return nil, ErrInvalidSpanID
 	}
-	copy(tc.SpanID[:], spanIDBytes)
 
-	flags, err := hex.DecodeString(matches[4])
-	if err != nil || len(flags) != 1 {
+	flags, ok := parseHexByte(header[53:55])
+	if !ok {
 		return nil, ErrInvalidContext
 	}
-	tc.TraceFlags = flags[0]
+	tc.TraceFlags = flags
 
 	return &tc, nil
 }
 
+func parseHexByte(s string) (byte, bool) {
+	if len(s) != 2 || !isLowerHex(s) {
+		return 0, false
+	}
+	var b [1]byte
+	hex.Decode(b[:], []byte(s))
+	return b[0], true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
 // FormatW3CTraceparent formats a TraceContext as W3C traceparent.
+// Contexts parsed from a future version keep that version and extension.
 func (tc *TraceContext) FormatW3CTraceparent() string {
-	return fmt.Sprintf("00-%s-%s-%02x",
+	s := fmt.Sprintf("%02x-%s-%s-%02x",
+		tc.Version,
 		tc.TraceID.String(),
 		tc.SpanID.String(),
 		tc.TraceFlags)
+	if tc.Extension != "" {
+		s += "-" + tc.Extension
+	}
+	return s
 }
 
 // ParseHeaders parses X-Trace-ID and X-Span-ID headers.
@@ -1,22 +1,96 @@
 package trace
 
 import (
-	"encoding/json"
+	"context"
 	"io"
+	"strconv"
 	"sync"
+	"time"
+
+	"github.com/kolosys/lumen/internal/enc"
+	"github.com/kolosys/lumen/resource"
 )
 
-// Exporter receives completed spans.
+// Exporter receives batches of completed spans.
 type Exporter interface {
+	// ExportSpans exports a batch of spans. Snapshots are shared and must
+	// not be modified. A returned error is reported via Options.OnExportError.
+	ExportSpans(ctx context.Context, spans []*SpanSnapshot) error
+	Close() error
+}
+
+// SpanExporter is the single-span exporter interface.
+// Wrap implementations with AdaptSpanExporter to use them as an Exporter.
+type SpanExporter interface {
 	Export(span *Span)
 	Close() error
 }
 
+// AdaptSpanExporter returns an Exporter that calls e.Export once per span.
+// The spans passed to e are read-only views of the exported snapshots.
+func AdaptSpanExporter(e SpanExporter) Exporter {
+	return &spanExporterAdapter{exporter: e}
+}
+
+type spanExporterAdapter struct {
+	exporter SpanExporter
+}
+
+func (a *spanExporterAdapter) ExportSpans(_ context.Context, spans []*SpanSnapshot) error {
+	for _, snap := range spans {
+		a.exporter.Export(snap.span())
+	}
+	return nil
+}
+
+func (a *spanExporterAdapter) Close() error { return a.exporter.Close() }
+
+// SpanSnapshot is an immutable copy of a completed span.
+type SpanSnapshot struct {
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentID      SpanID
+	Name          string
+	ServiceName   string
+	StartTime     time.Time
+	EndTime       time.Time
+	Status        SpanStatus
+	StatusMessage string
+	Attributes    []Attribute
+	Events        []Event
+	Sampled       bool
+	Resource      *resource.Resource
+}
+
+// Duration returns the span duration.
+func (s *SpanSnapshot) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// span returns a read-only, already-ended Span view of the snapshot.
+func (s *SpanSnapshot) span() *Span {
+	span := &Span{
+		traceID:    s.TraceID,
+		spanID:     s.SpanID,
+		parentID:   s.ParentID,
+		name:       s.Name,
+		startTime:  s.StartTime,
+		endTime:    s.EndTime,
+		status:     s.Status,
+		statusMsg:  s.StatusMessage,
+		attributes: s.Attributes,
+		events:     s.Events,
+		sampled:    s.Sampled,
+	}
+	span.ended.Store(true)
+	return span
+}
+
 // NopExporter discards all spans.
 type NopExporter struct{}
 
-func (NopExporter) Export(*Span) {}
-func (NopExporter) Close() error { return nil }
+func (NopExporter) ExportSpans(context.Context, []*SpanSnapshot) error { return nil }
+func (NopExporter) Close() error                                       { return nil }
 
 // WriterExporter writes spans as JSON to an io.Writer.
 type WriterExporter struct {
@@ -29,116 +103,117 @@ func NewWriterExporter(w io.Writer) *WriterExporter {
 	return &WriterExporter{writer: w}
 }
 
-type spanData struct {
-	TraceID    string      `json:"trace_id"`
-	SpanID     string      `json:"span_id"`
-	ParentID   string      `json:"parent_id,omitempty"`
-	Name       string      `json:"name"`
-	StartTime  int64       `json:"start_time_ns"`
-	EndTime    int64       `json:"end_time_ns"`
-	Duration   int64       `json:"duration_ns"`
-	Status     string      `json:"status"`
-	StatusMsg  string      `json:"status_message,omitempty"`
-	Attributes []attrData  `json:"attributes,omitempty"`
-	Events     []eventData `json:"events,omitempty"`
-}
-
-type attrData struct {
-	Key   string `json:"key"`
-	Value any    `json:"value"`
-}
-
-type eventData struct {
-	Name       string     `json:"name"`
-	Timestamp  int64      `json:"timestamp_ns"`
-	Attributes []attrData `json:"attributes,omitempty"`
-}
-
-func (e *WriterExporter) Export(span *Span) {
-	data := spanData{
-		TraceID:   span.traceID.String(),
-		SpanID:    span.spanID.String(),
-		Name:      span.name,
-		StartTime: span.startTime.UnixNano(),
-		EndTime:   span.endTime.UnixNano(),
-		Duration:  span.Duration().Nanoseconds(),
-		Status:    span.status.String(),
-		StatusMsg: span.statusMsg,
+// appendSpanJSON appends span as a single-line JSON object.
+func appendSpanJSON(dst []byte, span *SpanSnapshot) []byte {
+	dst = append(dst, `{"trace_id":"`...)
+	dst = append(dst, span.TraceID.String()...)
+	dst = append(dst, `","span_id":"`...)
+	dst = append(dst, span.SpanID.String()...)
+	dst = append(dst, '"')
+	if span.ParentID.IsValid() {
+		dst = append(dst, `,"parent_id":"`...)
+		dst = append(dst, span.ParentID.String()...)
+		dst = append(dst, '"')
 	}
-
-	if span.parentID.IsValid() {
-		data.ParentID = span.parentID.String()
+	dst = append(dst, `,"name":`...)
+	dst = enc.AppendJSONString(dst, span.Name)
+	dst = append(dst, `,"start_time_ns":`...)
+	dst = strconv.AppendInt(dst, span.StartTime.UnixNano(), 10)
+	dst = append(dst, `,"end_time_ns":`...)
+	dst = strconv.AppendInt(dst, span.EndTime.UnixNano(), 10)
+	dst = append(dst, `,"duration_ns":`...)
+	dst = strconv.AppendInt(dst, span.Duration().Nanoseconds(), 10)
+	dst = append(dst, `,"status":`...)
+	dst = enc.AppendJSONString(dst, span.Status.String())
+	if span.StatusMessage != "" {
+		dst = append(dst, `,"status_message":`...)
+		dst = enc.AppendJSONString(dst, span.StatusMessage)
 	}
-
-	for _, attr := range span.attributes {
-		data.Attributes = append(data.Attributes, attrData{
-			Key:   attr.Key,
-			Value: attr.Value,
-		})
+	if len(span.Attributes) > 0 {
+		dst = append(dst, `,"attributes":`...)
+		dst = appendAttributesJSON(dst, span.Attributes)
 	}
-
-	for _, event := range span.events {
-		ev := eventData{
-			Name:      event.Name,
-			Timestamp: event.Timestamp.UnixNano(),
+	if len(span.Events) > 0 {
+		dst = append(dst, `,"events":[`...)
+		for i, event := range span.Events {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, `{"name":`...)
+			dst = enc.AppendJSONString(dst, event.Name)
+			dst = append(dst, `,"timestamp_ns":`...)
+			dst = strconv.AppendInt(dst, event.Timestamp.UnixNano(), 10)
+			if len(event.Attributes) > 0 {
+				dst = append(dst, `,"attributes":`...)
+				dst = appendAttributesJSON(dst, event.Attributes)
+			}
+			dst = append(dst, '}')
 		}
-		for _, attr := range event.Attributes {
-			ev.Attributes = append(ev.Attributes, attrData{
-				Key:   attr.Key,
-				Value: attr.Value,
-			})
+		dst = append(dst, ']')
+	}
+	return append(dst, '}')
+}
+
+// appendAttributesJSON appends attrs as a JSON array of key/value objects.
+func appendAttributesJSON(dst []byte, attrs []Attribute) []byte {
+	dst = append(dst, '[')
+	for i, attr := range attrs {
+		if i > 0 {
+			dst = append(dst, ',')
 		}
-		data.Events = append(data.Events, ev)
+		dst = append(dst, `{"key":`...)
+		dst = enc.AppendJSONString(dst, attr.Key)
+		dst = append(dst, `,"value":`...)
+		dst = enc.AppendJSONValue(dst, enc.Of(attr.Value))
+		dst = append(dst, '}')
 	}
+	return append(dst, ']')
+}
 
+func (e *WriterExporter) ExportSpans(_ context.Context, spans []*SpanSnapshot) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	encoded, _ := json.Marshal(data)
-	e.writer.Write(encoded)
-	e.writer.Write([]byte("\n"))
+	buf := enc.GetBuffer()
+	defer enc.PutBuffer(buf)
+	for _, span := range spans {
+		buf.Reset()
+		buf.Write(appendSpanJSON(buf.AvailableBuffer(), span))
+		buf.WriteByte('\n')
+		if _, err := e.writer.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (e *WriterExporter) Close() error { return nil }
 
 // InMemoryExporter collects spans in memory for testing.
 type InMemoryExporter struct {
-	spans []*spanData
+	spans []*SpanSnapshot
 	mu    sync.Mutex
 }
 
 // NewInMemoryExporter creates an in-memory exporter.
 func NewInMemoryExporter() *InMemoryExporter {
 	return &InMemoryExporter{
-		spans: make([]*spanData, 0),
+		spans: make([]*SpanSnapshot, 0),
 	}
 }
 
-func (e *InMemoryExporter) Export(span *Span) {
-	data := &spanData{
-		TraceID:   span.traceID.String(),
-		SpanID:    span.spanID.String(),
-		Name:      span.name,
-		StartTime: span.startTime.UnixNano(),
-		EndTime:   span.endTime.UnixNano(),
-		Duration:  span.Duration().Nanoseconds(),
-		Status:    span.status.String(),
-	}
-
-	if span.parentID.IsValid() {
-		data.ParentID = span.parentID.String()
-	}
-
+func (e *InMemoryExporter) ExportSpans(_ context.Context, spans []*SpanSnapshot) error {
 	e.mu.Lock()
-	e.spans = append(e.spans, data)
+	e.spans = append(e.spans, spans...)
 	e.mu.Unlock()
+	return nil
 }
 
 // Spans returns collected spans.
-func (e *InMemoryExporter) Spans() []*spanData {
+func (e *InMemoryExporter) Spans() []*SpanSnapshot {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	result := make([]*spanData, len(e.spans))
+	result := make([]*SpanSnapshot, len(e.spans))
 	copy(result, e.spans)
 	return result
 }
@@ -0,0 +1,168 @@
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/trace"
+)
+
+// batchExporter records the size of each exported batch.
+type batchExporter struct {
+	mu      sync.Mutex
+	batches []int
+	err     error
+	ctxErr  error
+}
+
+func (e *batchExporter) ExportSpans(ctx context.Context, spans []*SpanSnapshot) error {
+	if e.err != nil {
+		<-ctx.Done()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.batches = append(e.batches, len(spans))
+	e.ctxErr = ctx.Err()
+	return e.err
+}
+
+func (e *batchExporter) Close() error { return nil }
+
+func (e *batchExporter) sizes() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]int(nil), e.batches...)
+}
+
+func TestAsyncExportBatches(t *testing.T) {
+	exp := &batchExporter{}
+	tracer := New(&Options{
+		Exporter:           exp,
+		AsyncExport:        true,
+		MaxExportBatchSize: 3,
+		BatchTimeout:       time.Hour,
+	})
+	for range 7 {
+		_, span := tracer.Start(context.Background(), "op")
+		span.End()
+	}
+	tracer.Close()
+
+	got := exp.sizes()
+	if len(got) != 3 || got[0] != 3 || got[1] != 3 || got[2] != 1 {
+		t.Errorf("batches = %v, want [3 3 1]", got)
+	}
+}
+
+func TestAsyncExportEndDuringClose(t *testing.T) {
+	exp := &batchExporter{}
+	tracer := New(&Options{Exporter: exp, AsyncExport: true, BatchTimeout: time.Hour})
+
+	const n = 200
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for range n {
+		_, span := tracer.Start(context.Background(), "op")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			span.End()
+		}()
+	}
+	close(start)
+	tracer.Close()
+	wg.Wait()
+
+	total := 0
+	for _, size := range exp.sizes() {
+		total += size
+	}
+	if total != n {
+		t.Errorf("exported %d spans, want %d", total, n)
+	}
+}
+
+func TestAsyncExportBatchTimeout(t *testing.T) {
+	exp := NewInMemoryExporter()
+	tracer := New(&Options{Exporter: exp, AsyncExport: true, BatchTimeout: 10 * time.Millisecond})
+	defer tracer.Close()
+
+	_, span := tracer.Start(context.Background(), "op")
+	span.End()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for exp.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if exp.Len() != 1 {
+		t.Error("expected a partial batch to be exported after BatchTimeout")
+	}
+}
+
+func TestSnapshotsOutliveSpans(t *testing.T) {
+	exp := NewInMemoryExporter()
+	tracer := New(&Options{Exporter: exp})
+
+	_, span := tracer.Start(context.Background(), "first", WithAttributes(Attribute{Key: "n", Value: 1}))
+	span.AddEvent("e1")
+	span.End()
+
+	// Reuse pooled spans; the first snapshot must not change.
+	for range 10 {
+		_, s := tracer.Start(context.Background(), "other", WithAttributes(Attribute{Key: "n", Value: 2}))
+		s.AddEvent("e2")
+		s.End()
+	}
+
+	snap := exp.Spans()[0]
+	if snap.Name != "first" || len(snap.Attributes) != 1 || snap.Attributes[0].Value != 1 ||
+		len(snap.Events) != 1 || snap.Events[0].Name != "e1" {
+		t.Errorf("snapshot changed after span reuse: %+v", snap)
+	}
+	if snap.ServiceName != "unknown" || !snap.Sampled || snap.Duration() < 0 {
+		t.Errorf("unexpected snapshot fields: %+v", snap)
+	}
+}
+
+func TestExportTimeoutAndError(t *testing.T) {
+	exp := &batchExporter{err: errors.New("collector down")}
+	var got error
+	tracer := New(&Options{
+		Exporter:      exp,
+		ExportTimeout: 10 * time.Millisecond,
+		OnExportError: func(err error) { got = err },
+	})
+
+	_, span := tracer.Start(context.Background(), "op")
+	span.End()
+
+	if !errors.Is(got, exp.err) {
+		t.Errorf("OnExportError got %v", got)
+	}
+	if !errors.Is(exp.ctxErr, context.DeadlineExceeded) {
+		t.Errorf("export context error = %v, want deadline exceeded", exp.ctxErr)
+	}
+}
+
+type spanRecorder struct {
+	names []string
+}
+
+func (r *spanRecorder) Export(span *Span) { r.names = append(r.names, span.Name()) }
+func (r *spanRecorder) Close() error      { return nil }
+
+func TestAdaptSpanExporter(t *testing.T) {
+	rec := &spanRecorder{}
+	tracer := New(&Options{Exporter: AdaptSpanExporter(rec)})
+	for _, name := range []string{"a", "b"} {
+		_, span := tracer.Start(context.Background(), name)
+		span.End()
+	}
+	if len(rec.names) != 2 || rec.names[0] != "a" || rec.names[1] != "b" {
+		t.Errorf("exported %v", rec.names)
+	}
+}
@@ -1,16 +1,33 @@
 package trace
 
+import (
+	"time"
+
+	"github.com/kolosys/lumen/resource"
+)
+
 // Options configures a Tracer.
 type Options struct {
-	// ServiceName identifies the service in traces.
+	// ServiceName identifies the service in traces. Defaults to the
+	// Resource's service name.
 	ServiceName string
 
+	// Resource describes the service; its attributes are attached to
+	// every span snapshot for exporters.
+	Resource *resource.Resource
+
 	// Sampler determines which spans to record.
 	Sampler Sampler
 
+	// OnSamplingDecision, if set, is called for every sampling decision.
+	OnSamplingDecision func(params SamplingParams, result SamplingResult)
+
 	// Exporter receives completed spans.
 	Exporter Exporter
 
+	// Processors transform or drop completed spans before export.
+	Processors []Processor
+
 	// MaxSpansPerSecond limits span creation rate (0 = unlimited).
 	MaxSpansPerSecond int
 
@@ -23,9 +40,43 @@ type Options struct {
 
 	// AsyncBufferSize sets the async export buffer size.
 	AsyncBufferSize int
+
+	// MaxExportBatchSize caps the number of spans per async export call.
+	// Default: 512
+	MaxExportBatchSize int
+
+	// BatchTimeout is the longest a span waits in the async batch before
+	// being exported. Default: 1s
+	BatchTimeout time.Duration
+
+	// ExportTimeout bounds each ExportSpans call (0 = no timeout).
+	ExportTimeout time.Duration
+
+	// OnExportError is called when the exporter returns an error.
+	OnExportError func(err error)
+
+	// RecordDurations keeps a per-name latency histogram of ended spans,
+	// queryable via Tracer.DurationStats. Unsampled spans are included.
+	RecordDurations bool
+
+	// DurationObserver, if set, is called with the name and duration of
+	// every ended span, e.g. to feed a metrics histogram.
+	DurationObserver func(name string, d time.Duration)
+
+	// TrackActiveSpans records started spans until they end so they can be
+	// listed via Tracer.ActiveSpans. Spans are not pooled while tracking.
+	TrackActiveSpans bool
+
+	// HeartbeatInterval adds a "heartbeat" event to every span that has been
+	// running for at least this long, once per interval (0 = disabled).
+	// Enabling heartbeats implies TrackActiveSpans.
+	HeartbeatInterval time.Duration
 }
 
 func (o *Options) applyDefaults() {
+	if o.ServiceName == "" {
+		o.ServiceName = o.Resource.ServiceName()
+	}
 	if o.ServiceName == "" {
 		o.ServiceName = "unknown"
 	}
@@ -38,7 +89,16 @@ func (o *Options) applyDefaults() {
 	if o.PropagationFormat == "" {
 		o.PropagationFormat = "both"
 	}
+	if o.HeartbeatInterval > 0 {
+		o.TrackActiveSpans = true
+	}
 	if o.AsyncBufferSize == 0 {
 		o.AsyncBufferSize = 1024
 	}
+	if o.MaxExportBatchSize == 0 {
+		o.MaxExportBatchSize = 512
+	}
+	if o.BatchTimeout == 0 {
+		o.BatchTimeout = time.Second
+	}
 }
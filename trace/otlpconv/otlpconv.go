@@ -0,0 +1,414 @@
+// Package otlpconv converts lumen span snapshots to and from OTLP trace
+// structures. The types mirror the OTLP protobuf messages and encode to the
+// OTLP/JSON wire format with encoding/json, so they can be posted to an
+// OTLP/HTTP collector or stored and replayed without field-by-field mappers.
+//
+// Only OTLP/JSON is supported: there is no protobuf encoding, so send
+// the data with Content-Type application/json.
+//
+// Lumen spans have no kind or links. FromSnapshot reports every span as
+// SpanKindInternal, and ToSnapshot ignores the kind of incoming spans;
+// OTLP links are not decoded.
+package otlpconv
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kolosys/lumen/resource"
+	"github.com/kolosys/lumen/trace"
+)
+
+// ScopeName is the instrumentation scope reported for converted spans.
+const ScopeName = "github.com/kolosys/lumen/trace"
+
+// ServiceNameKey is the resource attribute holding the service name.
+const ServiceNameKey = "service.name"
+
+// ErrInvalidSpan is returned when an OTLP span cannot be converted.
+var ErrInvalidSpan = errors.New("otlpconv: invalid span")
+
+// Span kinds as defined by OTLP.
+const (
+	SpanKindUnspecified = 0
+	SpanKindInternal    = 1
+	SpanKindServer      = 2
+	SpanKindClient      = 3
+	SpanKindProducer    = 4
+	SpanKindConsumer    = 5
+)
+
+// Status codes as defined by OTLP.
+const (
+	StatusCodeUnset = 0
+	StatusCodeOK    = 1
+	StatusCodeError = 2
+)
+
+// TracesData is the top-level OTLP trace message.
+type TracesData struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans groups spans produced by one resource.
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// Resource describes the entity producing telemetry.
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+// ScopeSpans groups spans produced by one instrumentation scope.
+type ScopeSpans struct {
+	Scope InstrumentationScope `json:"scope"`
+	Spans []Span               `json:"spans"`
+}
+
+// InstrumentationScope identifies the instrumentation library.
+type InstrumentationScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Span is an OTLP span. IDs are lowercase hex strings as in OTLP/JSON.
+type Span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	TraceState        string     `json:"traceState,omitempty"`
+	Flags             uint32     `json:"flags,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano   uint64     `json:"endTimeUnixNano,string"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Events            []Event    `json:"events,omitempty"`
+	Status            Status     `json:"status"`
+}
+
+// Event is a timestamped span annotation.
+type Event struct {
+	TimeUnixNano uint64     `json:"timeUnixNano,string"`
+	Name         string     `json:"name"`
+	Attributes   []KeyValue `json:"attributes,omitempty"`
+}
+
+// Status is the span completion status.
+type Status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// KeyValue is an attribute.
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue holds exactly one of its fields.
+type AnyValue struct {
+	StringValue *string     `json:"stringValue,omitempty"`
+	BoolValue   *bool       `json:"boolValue,omitempty"`
+	IntValue    *int64      `json:"intValue,string,omitempty"`
+	DoubleValue *float64    `json:"doubleValue,omitempty"`
+	ArrayValue  *ArrayValue `json:"arrayValue,omitempty"`
+	BytesValue  *string     `json:"bytesValue,omitempty"`
+}
+
+// ArrayValue is a list of values.
+type ArrayValue struct {
+	Values []AnyValue `json:"values"`
+}
+
+// FromSnapshots converts snapshots to OTLP, grouping them into one
+// ResourceSpans per service name. The resource attributes are taken from
+// the first snapshot of each service.
+func FromSnapshots(spans []*trace.SpanSnapshot) *TracesData {
+	td := &TracesData{}
+	index := make(map[string]int)
+
+	for _, snap := range spans {
+		i, ok := index[snap.ServiceName]
+		if !ok {
+			i = len(td.ResourceSpans)
+			index[snap.ServiceName] = i
+			attrs := []KeyValue{{Key: ServiceNameKey, Value: StringValue(snap.ServiceName)}}
+			for _, kv := range snap.Resource.Attributes() {
+				if kv.Key != ServiceNameKey {
+					attrs = append(attrs, KeyValue{Key: kv.Key, Value: StringValue(kv.Value)})
+				}
+			}
+			td.ResourceSpans = append(td.ResourceSpans, ResourceSpans{
+				Resource:   Resource{Attributes: attrs},
+				ScopeSpans: []ScopeSpans{{Scope: InstrumentationScope{Name: ScopeName}}},
+			})
+		}
+		scope := &td.ResourceSpans[i].ScopeSpans[0]
+		scope.Spans = append(scope.Spans, FromSnapshot(snap))
+	}
+
+	return td
+}
+
+// FromSnapshot converts a single snapshot to an OTLP span.
+func FromSnapshot(snap *trace.SpanSnapshot) Span {
+	span := Span{
+		TraceID:           snap.TraceID.String(),
+		SpanID:            snap.SpanID.String(),
+		Name:              snap.Name,
+		Kind:              SpanKindInternal,
+		StartTimeUnixNano: unixNano(snap.StartTime),
+		EndTimeUnixNano:   unixNano(snap.EndTime),
+		Attributes:        FromAttributes(snap.Attributes),
+		Status: Status{
+			Code:    int(snap.Status),
+			Message: snap.StatusMessage,
+		},
+	}
+	if snap.Sampled {
+		span.Flags = 0x01
+	}
+	if snap.ParentID.IsValid() {
+		span.ParentSpanID = snap.ParentID.String()
+	}
+	for _, ev := range snap.Events {
+		span.Events = append(span.Events, Event{
+			TimeUnixNano: unixNano(ev.Timestamp),
+			Name:         ev.Name,
+			Attributes:   FromAttributes(ev.Attributes),
+		})
+	}
+	return span
+}
+
+// ToSnapshots converts OTLP trace data back into snapshots. The service
+// name is taken from each resource's service.name attribute; other string
+// attributes become the snapshots' Resource.
+func ToSnapshots(td *TracesData) ([]*trace.SpanSnapshot, error) {
+	var spans []*trace.SpanSnapshot
+	for _, rs := range td.ResourceSpans {
+		var service string
+		attrs := make(map[string]string)
+		for _, kv := range rs.Resource.Attributes {
+			if kv.Value.StringValue == nil {
+				continue
+			}
+			if kv.Key == ServiceNameKey {
+				service = *kv.Value.StringValue
+			} else {
+				attrs[kv.Key] = *kv.Value.StringValue
+			}
+		}
+		var res *resource.Resource
+		if len(attrs) > 0 {
+			attrs[ServiceNameKey] = service
+			res = resource.FromMap(attrs)
+		}
+		for _, ss := range rs.ScopeSpans {
+			for i := range ss.Spans {
+				snap, err := ToSnapshot(&ss.Spans[i])
+				if err != nil {
+					return nil, err
+				}
+				snap.ServiceName = service
+				snap.Resource = res
+				spans = append(spans, snap)
+			}
+		}
+	}
+	return spans, nil
+}
+
+// ToSnapshot converts a single OTLP span to a snapshot.
+func ToSnapshot(span *Span) (*trace.SpanSnapshot, error) {
+	snap := &trace.SpanSnapshot{
+		Name:          span.Name,
+		StartTime:     fromUnixNano(span.StartTimeUnixNano),
+		EndTime:       fromUnixNano(span.EndTimeUnixNano),
+		Status:        trace.SpanStatus(span.Status.Code),
+		StatusMessage: span.Status.Message,
+		Attributes:    ToAttributes(span.Attributes),
+		Sampled:       span.Flags&0x01 != 0,
+	}
+
+	if err := decodeID(snap.TraceID[:], span.TraceID); err != nil {
+		return nil, fmt.Errorf("%w: trace id: %v", ErrInvalidSpan, err)
+	}
+	if err := decodeID(snap.SpanID[:], span.SpanID); err != nil {
+		return nil, fmt.Errorf("%w: span id: %v", ErrInvalidSpan, err)
+	}
+	if span.ParentSpanID != "" {
+		if err := decodeID(snap.ParentID[:], span.ParentSpanID); err != nil {
+			return nil, fmt.Errorf("%w: parent span id: %v", ErrInvalidSpan, err)
+		}
+	}
+
+	for _, ev := range span.Events {
+		snap.Events = append(snap.Events, trace.Event{
+			Name:       ev.Name,
+			Timestamp:  fromUnixNano(ev.TimeUnixNano),
+			Attributes: ToAttributes(ev.Attributes),
+		})
+	}
+	return snap, nil
+}
+
+// FromAttributes converts span attributes to OTLP key-values.
+func FromAttributes(attrs []trace.Attribute) []KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]KeyValue, len(attrs))
+	for i, attr := range attrs {
+		kvs[i] = KeyValue{Key: attr.Key, Value: FromValue(attr.Value)}
+	}
+	return kvs
+}
+
+// ToAttributes converts OTLP key-values to span attributes.
+func ToAttributes(kvs []KeyValue) []trace.Attribute {
+	if len(kvs) == 0 {
+		return nil
+	}
+	attrs := make([]trace.Attribute, len(kvs))
+	for i, kv := range kvs {
+		attrs[i] = trace.Attribute{Key: kv.Key, Value: ToValue(kv.Value)}
+	}
+	return attrs
+}
+
+// FromValue converts a Go value to an OTLP AnyValue. Unsupported types are
+// encoded as their fmt.Sprint string.
+func FromValue(v any) AnyValue {
+	switch val := v.(type) {
+	case nil:
+		return AnyValue{}
+	case string:
+		return StringValue(val)
+	case bool:
+		return AnyValue{BoolValue: &val}
+	case int:
+		return IntValue(int64(val))
+	case int8:
+		return IntValue(int64(val))
+	case int16:
+		return IntValue(int64(val))
+	case int32:
+		return IntValue(int64(val))
+	case int64:
+		return IntValue(val)
+	case uint:
+		return IntValue(int64(val))
+	case uint8:
+		return IntValue(int64(val))
+	case uint16:
+		return IntValue(int64(val))
+	case uint32:
+		return IntValue(int64(val))
+	case uint64:
+		return IntValue(int64(val))
+	case float32:
+		f := float64(val)
+		return AnyValue{DoubleValue: &f}
+	case float64:
+		return AnyValue{DoubleValue: &val}
+	case time.Duration:
+		return IntValue(int64(val))
+	case []byte:
+		s := base64.StdEncoding.EncodeToString(val)
+		return AnyValue{BytesValue: &s}
+	case []string:
+		arr := &ArrayValue{Values: make([]AnyValue, len(val))}
+		for i, s := range val {
+			arr.Values[i] = StringValue(s)
+		}
+		return AnyValue{ArrayValue: arr}
+	case []int64:
+		arr := &ArrayValue{Values: make([]AnyValue, len(val))}
+		for i, n := range val {
+			arr.Values[i] = IntValue(n)
+		}
+		return AnyValue{ArrayValue: arr}
+	case []any:
+		arr := &ArrayValue{Values: make([]AnyValue, len(val))}
+		for i, e := range val {
+			arr.Values[i] = FromValue(e)
+		}
+		return AnyValue{ArrayValue: arr}
+	case fmt.Stringer:
+		return StringValue(val.String())
+	default:
+		return StringValue(fmt.Sprint(val))
+	}
+}
+
+// ToValue converts an OTLP AnyValue to a Go value.
+func ToValue(v AnyValue) any {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return *v.IntValue
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.BytesValue != nil:
+		b, err := base64.StdEncoding.DecodeString(*v.BytesValue)
+		if err != nil {
+			return *v.BytesValue
+		}
+		return b
+	case v.ArrayValue != nil:
+		values := make([]any, len(v.ArrayValue.Values))
+		for i, e := range v.ArrayValue.Values {
+			values[i] = ToValue(e)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// StringValue returns an AnyValue holding s.
+func StringValue(s string) AnyValue {
+	return AnyValue{StringValue: &s}
+}
+
+// IntValue returns an AnyValue holding n.
+func IntValue(n int64) AnyValue {
+	return AnyValue{IntValue: &n}
+}
+
+func decodeID(dst []byte, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != len(dst) {
+		return errors.New("wrong length " + strconv.Itoa(len(b)))
+	}
+	copy(dst, b)
+	return nil
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func fromUnixNano(n uint64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(n))
+}
@@ -0,0 +1,193 @@
+package otlpconv_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolosys/lumen/resource"
+	"github.com/kolosys/lumen/trace"
+	. "github.com/kolosys/lumen/trace/otlpconv"
+)
+
+const (
+	traceIDHex = "0af7651916cd43dd8448eb211c80319c"
+	spanIDHex  = "b7ad6b7169203331"
+	parentHex  = "00f067aa0ba902b7"
+)
+
+func testSnapshot(t *testing.T) *trace.SpanSnapshot {
+	t.Helper()
+	var traceID trace.TraceID
+	var spanID, parentID trace.SpanID
+	hex.Decode(traceID[:], []byte(traceIDHex))
+	hex.Decode(spanID[:], []byte(spanIDHex))
+	hex.Decode(parentID[:], []byte(parentHex))
+	start := time.Unix(1700000000, 123456789)
+	return &trace.SpanSnapshot{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		ParentID:      parentID,
+		Name:          "charge",
+		ServiceName:   "checkout",
+		StartTime:     start,
+		EndTime:       start.Add(42 * time.Millisecond),
+		Status:        trace.StatusError,
+		StatusMessage: "card declined",
+		Sampled:       true,
+		Resource:      resource.FromMap(map[string]string{"service.name": "checkout", "team": "payments"}),
+		Attributes: []trace.Attribute{
+			{Key: "string", Value: "visa"},
+			{Key: "bool", Value: true},
+			{Key: "int", Value: int64(3)},
+			{Key: "double", Value: 9.99},
+			{Key: "bytes", Value: []byte{0xde, 0xad}},
+			{Key: "array", Value: []any{"a", int64(1), false}},
+		},
+		Events: []trace.Event{{
+			Name:       "retry",
+			Timestamp:  start.Add(10 * time.Millisecond),
+			Attributes: []trace.Attribute{{Key: "attempt", Value: int64(2)}},
+		}},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := testSnapshot(t)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(FromSnapshots([]*trace.SpanSnapshot{want})); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`"traceId":"` + traceIDHex + `"`,
+		`"spanId":"` + spanIDHex + `"`,
+		`"parentSpanId":"` + parentHex + `"`,
+		`"intValue":"3"`,
+		`"startTimeUnixNano":"1700000000123456789"`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %s in %s", s, buf.String())
+		}
+	}
+
+	var td TracesData
+	if err := json.Unmarshal(buf.Bytes(), &td); err != nil {
+		t.Fatal(err)
+	}
+	spans, err := ToSnapshots(&td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	got := spans[0]
+
+	if got.TraceID != want.TraceID || got.SpanID != want.SpanID || got.ParentID != want.ParentID {
+		t.Errorf("IDs %s/%s/%s, want %s/%s/%s", got.TraceID, got.SpanID, got.ParentID, want.TraceID, want.SpanID, want.ParentID)
+	}
+	if got.Name != want.Name || got.ServiceName != want.ServiceName || !got.Sampled {
+		t.Errorf("unexpected span %q service %q sampled %v", got.Name, got.ServiceName, got.Sampled)
+	}
+	if !got.StartTime.Equal(want.StartTime) || !got.EndTime.Equal(want.EndTime) {
+		t.Errorf("times %v-%v, want %v-%v", got.StartTime, got.EndTime, want.StartTime, want.EndTime)
+	}
+	if got.Status != want.Status || got.StatusMessage != want.StatusMessage {
+		t.Errorf("status %v %q, want %v %q", got.Status, got.StatusMessage, want.Status, want.StatusMessage)
+	}
+	if !reflect.DeepEqual(got.Attributes, want.Attributes) {
+		t.Errorf("attributes %#v, want %#v", got.Attributes, want.Attributes)
+	}
+	if len(got.Events) != 1 || got.Events[0].Name != "retry" || !got.Events[0].Timestamp.Equal(want.Events[0].Timestamp) ||
+		!reflect.DeepEqual(got.Events[0].Attributes, want.Events[0].Attributes) {
+		t.Errorf("events %#v, want %#v", got.Events, want.Events)
+	}
+	if got.Resource.Get("team") != "payments" || got.Resource.Get("service.name") != "checkout" {
+		t.Errorf("unexpected resource %v", got.Resource.Attributes())
+	}
+}
+
+func TestFromSnapshotKindAndStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status trace.SpanStatus
+		code   int
+	}{
+		{trace.StatusUnset, StatusCodeUnset},
+		{trace.StatusOK, StatusCodeOK},
+		{trace.StatusError, StatusCodeError},
+	} {
+		snap := testSnapshot(t)
+		snap.Status = tc.status
+		span := FromSnapshot(snap)
+		if span.Kind != SpanKindInternal {
+			t.Errorf("kind = %d, want SpanKindInternal", span.Kind)
+		}
+		if span.Status.Code != tc.code {
+			t.Errorf("status %v: code = %d, want %d", tc.status, span.Status.Code, tc.code)
+		}
+		back, err := ToSnapshot(&span)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back.Status != tc.status {
+			t.Errorf("status %v came back as %v", tc.status, back.Status)
+		}
+	}
+
+	span := FromSnapshot(testSnapshot(t))
+	span.Kind = SpanKindServer
+	if _, err := ToSnapshot(&span); err != nil {
+		t.Errorf("server span: %v", err)
+	}
+}
+
+func TestFromValueTypes(t *testing.T) {
+	tests := []struct {
+		in   any
+		want any
+	}{
+		{"s", "s"},
+		{true, true},
+		{42, int64(42)},
+		{uint16(7), int64(7)},
+		{float32(0.5), 0.5},
+		{2 * time.Second, int64(2 * time.Second)},
+		{[]byte("hi"), []byte("hi")},
+		{[]string{"a", "b"}, []any{"a", "b"}},
+		{[]int64{1, 2}, []any{int64(1), int64(2)}},
+		{nil, nil},
+		{struct{ A int }{1}, "{1}"},
+	}
+	for _, tc := range tests {
+		if got := ToValue(FromValue(tc.in)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%#v: got %#v, want %#v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestToSnapshotInvalidIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(*Span)
+	}{
+		{"bad trace id", func(s *Span) { s.TraceID = "zz" + traceIDHex[2:] }},
+		{"short trace id", func(s *Span) { s.TraceID = traceIDHex[:16] }},
+		{"bad span id", func(s *Span) { s.SpanID = "nothex!!" }},
+		{"long parent id", func(s *Span) { s.ParentSpanID = traceIDHex }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			span := FromSnapshot(testSnapshot(t))
+			tc.edit(&span)
+			if _, err := ToSnapshot(&span); !errors.Is(err, ErrInvalidSpan) {
+				t.Errorf("expected ErrInvalidSpan, got %v", err)
+			}
+		})
+	}
+}
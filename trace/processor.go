@@ -0,0 +1,134 @@
+package trace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/kolosys/lumen/internal/redact"
+)
+
+// Processor transforms completed spans before they are exported.
+// Processors run in order on a snapshot that has not yet been shared, so
+// they may modify it in place. Returning nil drops the span.
+type Processor interface {
+	Process(span *SpanSnapshot) *SpanSnapshot
+}
+
+// ProcessorFunc adapts a function to the Processor interface.
+type ProcessorFunc func(span *SpanSnapshot) *SpanSnapshot
+
+// Process implements Processor.
+func (f ProcessorFunc) Process(span *SpanSnapshot) *SpanSnapshot {
+	return f(span)
+}
+
+// RedactAction selects how a matching attribute is redacted.
+type RedactAction int
+
+const (
+	// RedactDrop removes the attribute.
+	RedactDrop RedactAction = iota
+	// RedactHash replaces the value with a truncated SHA-256 digest, so equal
+	// values remain correlatable without being readable.
+	RedactHash
+	// RedactMask replaces the value with "[REDACTED]".
+	RedactMask
+)
+
+// RedactedValue replaces masked attribute values.
+const RedactedValue = "[REDACTED]"
+
+// RedactionRule matches attribute keys against a case-insensitive glob
+// pattern (e.g. "*password*", "http.request.header.authorization"). The
+// syntax is path.Match, the same as the key patterns of logs.NewRedactor,
+// so one set of patterns can drive both. Unlike logs, which always masks,
+// each rule picks its own action.
+type RedactionRule struct {
+	Pattern string
+	Action  RedactAction
+}
+
+// DefaultRedactionRules returns rules covering common credential and PII
+// keys: attributes matching logs.DefaultRedactedKeys are dropped, and
+// email addresses and social security numbers are hashed.
+func DefaultRedactionRules() []RedactionRule {
+	var rules []RedactionRule
+	for _, pattern := range redact.CredentialKeys() {
+		rules = append(rules, RedactionRule{Pattern: pattern, Action: RedactDrop})
+	}
+	return append(rules,
+		RedactionRule{Pattern: "*email*", Action: RedactHash},
+		RedactionRule{Pattern: "*ssn*", Action: RedactHash},
+	)
+}
+
+// RedactProcessor drops, hashes, or masks span and event attributes whose
+// keys match the configured rules. The first matching rule wins.
+type RedactProcessor struct {
+	rules    []RedactionRule
+	patterns redact.Patterns
+	salt     string
+}
+
+// NewRedactProcessor creates a redaction processor.
+// If no rules are given, DefaultRedactionRules is used.
+func NewRedactProcessor(rules ...RedactionRule) *RedactProcessor {
+	if len(rules) == 0 {
+		rules = DefaultRedactionRules()
+	}
+	patterns := make([]string, len(rules))
+	for i, r := range rules {
+		patterns[i] = r.Pattern
+	}
+	return &RedactProcessor{rules: append([]RedactionRule(nil), rules...), patterns: redact.Compile(patterns)}
+}
+
+// WithSalt sets a salt mixed into hashed values.
+func (p *RedactProcessor) WithSalt(salt string) *RedactProcessor {
+	p.salt = salt
+	return p
+}
+
+// Process implements Processor.
+func (p *RedactProcessor) Process(span *SpanSnapshot) *SpanSnapshot {
+	span.Attributes = p.redact(span.Attributes)
+	for i := range span.Events {
+		span.Events[i].Attributes = p.redact(span.Events[i].Attributes)
+	}
+	return span
+}
+
+func (p *RedactProcessor) redact(attrs []Attribute) []Attribute {
+	if len(attrs) == 0 {
+		return attrs
+	}
+
+	result := attrs[:0:0]
+	for _, attr := range attrs {
+		rule, ok := p.match(attr.Key)
+		if !ok {
+			result = append(result, attr)
+			continue
+		}
+		switch rule.Action {
+		case RedactHash:
+			result = append(result, Attribute{Key: attr.Key, Value: p.hash(attr.Value)})
+		case RedactMask:
+			result = append(result, Attribute{Key: attr.Key, Value: RedactedValue})
+		}
+	}
+	return result
+}
+
+func (p *RedactProcessor) match(key string) (RedactionRule, bool) {
+	if i := p.patterns.Index(key); i >= 0 {
+		return p.rules[i], true
+	}
+	return RedactionRule{}, false
+}
+
+func (p *RedactProcessor) hash(value any) string {
+	sum := sha256.Sum256([]byte(p.salt + fmt.Sprint(value)))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,141 @@
+package trace_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kolosys/lumen/logs"
+	. "github.com/kolosys/lumen/trace"
+)
+
+func TestProcessorsRunInOrder(t *testing.T) {
+	exp := NewInMemoryExporter()
+	tracer := New(&Options{
+		Exporter: exp,
+		Processors: []Processor{
+			ProcessorFunc(func(s *SpanSnapshot) *SpanSnapshot {
+				s.Name = strings.ToUpper(s.Name)
+				return s
+			}),
+			ProcessorFunc(func(s *SpanSnapshot) *SpanSnapshot {
+				if s.Name == "HEALTHZ" {
+					return nil
+				}
+				return s
+			}),
+		},
+	})
+	for _, name := range []string{"checkout", "healthz"} {
+		_, span := tracer.Start(context.Background(), name)
+		span.End()
+	}
+
+	spans := exp.Spans()
+	if len(spans) != 1 || spans[0].Name != "CHECKOUT" {
+		t.Errorf("exported %d spans, first %q", len(spans), spans[0].Name)
+	}
+}
+
+func attrValue(attrs []Attribute, key string) (any, bool) {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return nil, false
+}
+
+func TestRedactProcessorDefaults(t *testing.T) {
+	span := &SpanSnapshot{
+		Attributes: []Attribute{
+			{Key: "http.request.header.authorization", Value: "Bearer x"},
+			{Key: "User.Password", Value: "hunter2"},
+			{Key: "user.email", Value: "ann@example.com"},
+			{Key: "http.route", Value: "/login"},
+		},
+		Events: []Event{{Name: "login", Attributes: []Attribute{
+			{Key: "session_token", Value: "t"},
+			{Key: "attempt", Value: 1},
+		}}},
+	}
+	NewRedactProcessor().Process(span)
+
+	for _, key := range []string{"http.request.header.authorization", "User.Password"} {
+		if _, ok := attrValue(span.Attributes, key); ok {
+			t.Errorf("expected %s to be dropped", key)
+		}
+	}
+	email, _ := attrValue(span.Attributes, "user.email")
+	if s, _ := email.(string); !strings.HasPrefix(s, "sha256:") || len(s) != len("sha256:")+16 {
+		t.Errorf("expected a hashed email, got %v", email)
+	}
+	if route, _ := attrValue(span.Attributes, "http.route"); route != "/login" {
+		t.Errorf("http.route = %v", route)
+	}
+	if _, ok := attrValue(span.Events[0].Attributes, "session_token"); ok {
+		t.Error("expected the event token to be dropped")
+	}
+	if n, _ := attrValue(span.Events[0].Attributes, "attempt"); n != 1 {
+		t.Errorf("event attempt = %v", n)
+	}
+}
+
+func TestRedactProcessorHashAndMask(t *testing.T) {
+	hash := func(p *RedactProcessor, value string) any {
+		span := &SpanSnapshot{Attributes: []Attribute{{Key: "email", Value: value}}}
+		return p.Process(span).Attributes[0].Value
+	}
+
+	p := NewRedactProcessor(RedactionRule{Pattern: "email", Action: RedactHash})
+	if hash(p, "a@x") != hash(p, "a@x") || hash(p, "a@x") == hash(p, "b@x") {
+		t.Error("expected equal values to hash equally and different values differently")
+	}
+	salted := NewRedactProcessor(RedactionRule{Pattern: "email", Action: RedactHash}).WithSalt("pepper")
+	if hash(salted, "a@x") == hash(p, "a@x") {
+		t.Error("expected the salt to change the hash")
+	}
+
+	// The first matching rule wins.
+	p = NewRedactProcessor(
+		RedactionRule{Pattern: "card.*", Action: RedactMask},
+		RedactionRule{Pattern: "card.number", Action: RedactDrop},
+	)
+	span := p.Process(&SpanSnapshot{Attributes: []Attribute{{Key: "CARD.NUMBER", Value: "4111"}}})
+	if len(span.Attributes) != 1 || span.Attributes[0].Value != RedactedValue {
+		t.Errorf("unexpected attributes %v", span.Attributes)
+	}
+}
+
+func TestRedactProcessorMatchesLogs(t *testing.T) {
+	keys := []string{"db.password", "X-API-Key", "service.api_key", "tls.private_key", "passwd", "Cookie", "http.route", "user.id"}
+	patterns := []string{"x-api-*", "*_key", "passwd"}
+
+	check := func(name string, redactor *logs.Redactor, p *RedactProcessor) {
+		for _, key := range keys {
+			masked := redactor.Redact(logs.String(key, "v")).String == logs.RedactedValue
+			span := p.Process(&SpanSnapshot{Attributes: []Attribute{{Key: key, Value: "v"}}})
+			if dropped := len(span.Attributes) == 0; dropped != masked {
+				t.Errorf("%s: %s dropped from span = %v, masked in logs = %v", name, key, dropped, masked)
+			}
+		}
+	}
+	check("defaults", logs.NewRedactor(), NewRedactProcessor())
+
+	rules := make([]RedactionRule, len(patterns))
+	for i, pattern := range patterns {
+		rules[i] = RedactionRule{Pattern: pattern, Action: RedactDrop}
+	}
+	check("custom", logs.NewRedactor(patterns...), NewRedactProcessor(rules...))
+}
+
+func TestRedactProcessorInTracer(t *testing.T) {
+	exp := NewInMemoryExporter()
+	tracer := New(&Options{Exporter: exp, Processors: []Processor{NewRedactProcessor()}})
+	_, span := tracer.Start(context.Background(), "op", WithAttributes(Attribute{Key: "password", Value: "p"}))
+	span.End()
+
+	if _, ok := attrValue(exp.Spans()[0].Attributes, "password"); ok {
+		t.Error("expected password to be dropped from the export")
+	}
+}
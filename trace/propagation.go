@@ -1,6 +1,11 @@
 package trace
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // Carrier is an interface for propagation carriers (e.g., HTTP headers).
 type Carrier interface {
@@ -109,6 +114,181 @@ func (p *HeaderPropagator) Extract(ctx context.Context, carrier Carrier) context
 	return ContextWithTraceContext(ctx, tc)
 }
 
+// Datadog header names.
+const (
+	DatadogTraceIDHeader          = "x-datadog-trace-id"
+	DatadogParentIDHeader         = "x-datadog-parent-id"
+	DatadogSamplingPriorityHeader = "x-datadog-sampling-priority"
+	DatadogTagsHeader             = "x-datadog-tags"
+)
+
+// datadogTraceIDTag carries the upper 64 bits of a 128-bit trace ID.
+const datadogTraceIDTag = "_dd.p.tid"
+
+// DatadogPropagator implements Datadog header propagation.
+//
+// Datadog transmits IDs as unsigned 64-bit decimals. The lower 64 bits of the
+// trace ID go in x-datadog-trace-id and the upper 64 bits, when non-zero, are
+// carried as the _dd.p.tid tag in x-datadog-tags.
+type DatadogPropagator struct{}
+
+func (p *DatadogPropagator) Inject(ctx context.Context, carrier Carrier) {
+	span := SpanFromContext(ctx)
+	if span == nil || !span.traceID.IsValid() {
+		return
+	}
+
+	hi, lo := span.traceID.Uint64s()
+	carrier.Set(DatadogTraceIDHeader, strconv.FormatUint(lo, 10))
+	carrier.Set(DatadogParentIDHeader, strconv.FormatUint(span.spanID.Uint64(), 10))
+	if span.sampled {
+		carrier.Set(DatadogSamplingPriorityHeader, "1")
+	} else {
+		carrier.Set(DatadogSamplingPriorityHeader, "0")
+	}
+	if hi != 0 {
+		carrier.Set(DatadogTagsHeader, fmt.Sprintf("%s=%016x", datadogTraceIDTag, hi))
+	}
+}
+
+func (p *DatadogPropagator) Extract(ctx context.Context, carrier Carrier) context.Context {
+	traceID := carrier.Get(DatadogTraceIDHeader)
+	if traceID == "" {
+		return ctx
+	}
+
+	tc, err := ParseDatadogHeaders(
+		traceID,
+		carrier.Get(DatadogParentIDHeader),
+		carrier.Get(DatadogSamplingPriorityHeader),
+		carrier.Get(DatadogTagsHeader),
+	)
+	if err != nil {
+		return ctx
+	}
+
+	return ContextWithTraceContext(ctx, tc)
+}
+
+// ParseDatadogHeaders parses Datadog trace headers into a TraceContext.
+// The parent ID, sampling priority and tags may be empty.
+func ParseDatadogHeaders(traceID, parentID, priority, tags string) (*TraceContext, error) {
+	lo, err := strconv.ParseUint(strings.TrimSpace(traceID), 10, 64)
+	if err != nil || lo == 0 {
+		return nil, ErrInvalidTraceID
+	}
+
+	var hi uint64
+	for _, tag := range strings.Split(tags, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if !ok || key != datadogTraceIDTag {
+			continue
+		}
+		hi, err = strconv.ParseUint(value, 16, 64)
+		if err != nil || len(value) != 16 {
+			return nil, ErrInvalidTraceID
+		}
+	}
+
+	tc := &TraceContext{TraceID: TraceIDFromUint64s(hi, lo)}
+
+	if parentID != "" {
+		id, err := strconv.ParseUint(strings.TrimSpace(parentID), 10, 64)
+		if err != nil {
+			return nil, ErrInvalidSpanID
+		}
+		tc.SpanID = SpanIDFromUint64(id)
+	}
+
+	if priority != "" {
+		p, err := strconv.Atoi(strings.TrimSpace(priority))
+		if err != nil {
+			return nil, ErrInvalidContext
+		}
+		tc.SetSampled(p > 0)
+	}
+
+	return tc, nil
+}
+
+// CloudTraceHeader is the Google Cloud Trace header name.
+const CloudTraceHeader = "X-Cloud-Trace-Context"
+
+// CloudTracePropagator implements Google Cloud Trace propagation using the
+// X-Cloud-Trace-Context header ("TRACE_ID/SPAN_ID;o=OPTIONS"), where the trace
+// ID is 32 hex characters and the span ID is an unsigned decimal.
+type CloudTracePropagator struct{}
+
+func (p *CloudTracePropagator) Inject(ctx context.Context, carrier Carrier) {
+	span := SpanFromContext(ctx)
+	if span == nil || !span.traceID.IsValid() {
+		return
+	}
+
+	tc := &TraceContext{
+		TraceID: span.traceID,
+		SpanID:  span.spanID,
+	}
+	tc.SetSampled(span.sampled)
+
+	carrier.Set(CloudTraceHeader, tc.FormatCloudTraceContext())
+}
+
+func (p *CloudTracePropagator) Extract(ctx context.Context, carrier Carrier) context.Context {
+	header := carrier.Get(CloudTraceHeader)
+	if header == "" {
+		return ctx
+	}
+
+	tc, err := ParseCloudTraceContext(header)
+	if err != nil {
+		return ctx
+	}
+
+	return ContextWithTraceContext(ctx, tc)
+}
+
+// ParseCloudTraceContext parses an X-Cloud-Trace-Context header value.
+// The span ID and options are optional.
+func ParseCloudTraceContext(header string) (*TraceContext, error) {
+	header = strings.TrimSpace(header)
+
+	rest, options, _ := strings.Cut(header, ";")
+	traceID, spanID, _ := strings.Cut(rest, "/")
+
+	tc, err := ParseHeaders(strings.ToLower(traceID), "")
+	if err != nil || !tc.TraceID.IsValid() {
+		return nil, ErrInvalidTraceID
+	}
+
+	if spanID != "" {
+		id, err := strconv.ParseUint(spanID, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidSpanID
+		}
+		tc.SpanID = SpanIDFromUint64(id)
+	}
+
+	if value, ok := strings.CutPrefix(options, "o="); ok {
+		flags, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return nil, ErrInvalidContext
+		}
+		tc.SetSampled(flags&0x01 != 0)
+	}
+
+	return tc, nil
+}
+
+// FormatCloudTraceContext formats a TraceContext as an X-Cloud-Trace-Context value.
+func (tc *TraceContext) FormatCloudTraceContext() string {
+	sampled := 0
+	if tc.IsSampled() {
+		sampled = 1
+	}
+	return fmt.Sprintf("%s/%d;o=%d", tc.TraceID.String(), tc.SpanID.Uint64(), sampled)
+}
+
 // CompositePropagator combines multiple propagators.
 type CompositePropagator struct {
 	propagators []Propagator
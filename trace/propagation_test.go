@@ -0,0 +1,315 @@
+package trace_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	. "github.com/kolosys/lumen/trace"
+)
+
+const (
+	validTraceID = "0af7651916cd43dd8448eb211c80319c"
+	validSpanID  = "b7ad6b7169203331"
+
+	// validTraceID's lower and upper 64 bits and validSpanID as Datadog
+	// writes them.
+	datadogTraceID = "9532127138774266268"
+	datadogTID     = "0af7651916cd43dd"
+	datadogSpanID  = "13235353014750950193"
+)
+
+func TestParseW3CTraceparent(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		valid  bool
+	}{
+		{"valid", "00-" + validTraceID + "-" + validSpanID + "-01", true},
+		{"valid unsampled", "00-" + validTraceID + "-" + validSpanID + "-00", true},
+		{"valid unknown flags", "00-" + validTraceID + "-" + validSpanID + "-09", true},
+		{"surrounding whitespace", "  00-" + validTraceID + "-" + validSpanID + "-01\t", true},
+		{"future version", "cc-" + validTraceID + "-" + validSpanID + "-01", true},
+		{"future version with extension", "cc-" + validTraceID + "-" + validSpanID + "-01-what-the-future-will-be-like", true},
+		{"future version bad delimiter", "cc-" + validTraceID + "-" + validSpanID + "-01.what-the-future-will-not-be-like", false},
+		{"version ff", "ff-" + validTraceID + "-" + validSpanID + "-01", false},
+		{"version 00 with trailing data", "00-" + validTraceID + "-" + validSpanID + "-01-what-the-future-will-be-like", false},
+		{"version not hex", "0x-" + validTraceID + "-" + validSpanID + "-01", false},
+		{"uppercase version", "0A-" + validTraceID + "-" + validSpanID + "-01", false},
+		{"uppercase trace id", "00-0AF7651916CD43DD8448EB211C80319C-" + validSpanID + "-01", false},
+		{"uppercase span id", "00-" + validTraceID + "-B7AD6B7169203331-01", false},
+		{"uppercase flags", "00-" + validTraceID + "-" + validSpanID + "-0A", false},
+		{"zero trace id", "00-00000000000000000000000000000000-" + validSpanID + "-01", false},
+		{"zero span id", "00-" + validTraceID + "-0000000000000000-01", false},
+		{"short trace id", "00-0af7651916cd43dd8448eb211c8031-" + validSpanID + "-01", false},
+		{"short span id", "00-" + validTraceID + "-b7ad6b716920333-01", false},
+		{"short flags", "00-" + validTraceID + "-" + validSpanID + "-1", false},
+		{"trace id not hex", "00-0af7651916cd43dd8448eb211c80319z-" + validSpanID + "-01", false},
+		{"wrong delimiter", "00_" + validTraceID + "-" + validSpanID + "-01", false},
+		{"empty", "", false},
+		{"garbage", "not a traceparent", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseW3CTraceparent(tc.header)
+			if tc.valid && err != nil {
+				t.Errorf("expected %q to parse, got %v", tc.header, err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("expected %q to be rejected", tc.header)
+			}
+		})
+	}
+}
+
+func TestW3CTraceparentRoundTrip(t *testing.T) {
+	headers := []string{
+		"00-" + validTraceID + "-" + validSpanID + "-01",
+		"00-" + validTraceID + "-" + validSpanID + "-00",
+		"01-" + validTraceID + "-" + validSpanID + "-01",
+		"cc-" + validTraceID + "-" + validSpanID + "-01-what-the-future-will-be-like",
+	}
+
+	for _, header := range headers {
+		tc, err := ParseW3CTraceparent(header)
+		if err != nil {
+			t.Fatalf("parse %q: %v", header, err)
+		}
+		if got := tc.FormatW3CTraceparent(); got != header {
+			t.Errorf("round trip mismatch:\n got  %q\n want %q", got, header)
+		}
+	}
+}
+
+func TestW3CTraceparentFields(t *testing.T) {
+	tc, err := ParseW3CTraceparent("cc-" + validTraceID + "-" + validSpanID + "-01-extra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.TraceID.String() != validTraceID {
+		t.Errorf("trace id = %s", tc.TraceID)
+	}
+	if tc.SpanID.String() != validSpanID {
+		t.Errorf("span id = %s", tc.SpanID)
+	}
+	if !tc.IsSampled() {
+		t.Error("expected sampled")
+	}
+	if tc.Version != 0xcc {
+		t.Errorf("version = %x", tc.Version)
+	}
+	if tc.Extension != "extra" {
+		t.Errorf("extension = %q", tc.Extension)
+	}
+}
+
+func TestW3CPropagatorExtract(t *testing.T) {
+	carrier := MapCarrier{
+		W3CTraceparentHeader: "00-" + validTraceID + "-" + validSpanID + "-01",
+		W3CTracestateHeader:  "congo=t61rcWkgMzE",
+	}
+
+	ctx := (&W3CPropagator{}).Extract(context.Background(), carrier)
+	tc := TraceContextFromContext(ctx)
+	if tc == nil {
+		t.Fatal("expected trace context")
+	}
+	if tc.TraceState != "congo=t61rcWkgMzE" {
+		t.Errorf("tracestate = %q", tc.TraceState)
+	}
+
+	tracer := New(nil)
+	_, span := tracer.Start(ctx, "child")
+	if span.TraceID().String() != validTraceID {
+		t.Errorf("child trace id = %s", span.TraceID())
+	}
+	if span.ParentID().String() != validSpanID {
+		t.Errorf("child parent id = %s", span.ParentID())
+	}
+}
+
+func TestParseDatadogHeaders(t *testing.T) {
+	tests := []struct {
+		name                        string
+		traceID, parent, prio, tags string
+		valid                       bool
+		wantTrace                   string
+		wantSampled                 bool
+	}{
+		{"64-bit", datadogTraceID, datadogSpanID, "1", "", true, "00000000000000008448eb211c80319c", true},
+		{"128-bit", datadogTraceID, datadogSpanID, "1", "_dd.p.dm=-4,_dd.p.tid=" + datadogTID, true, validTraceID, true},
+		{"user keep", datadogTraceID, datadogSpanID, "2", "", true, "00000000000000008448eb211c80319c", true},
+		{"auto reject", datadogTraceID, datadogSpanID, "0", "", true, "00000000000000008448eb211c80319c", false},
+		{"user reject", datadogTraceID, datadogSpanID, "-1", "", true, "00000000000000008448eb211c80319c", false},
+		{"trace id only", datadogTraceID, "", "", "", true, "00000000000000008448eb211c80319c", false},
+		{"zero trace id", "0", datadogSpanID, "1", "", false, "", false},
+		{"negative trace id", "-1", datadogSpanID, "1", "", false, "", false},
+		{"hex trace id", "8448eb211c80319c", datadogSpanID, "1", "", false, "", false},
+		{"trace id overflow", "18446744073709551616", datadogSpanID, "1", "", false, "", false},
+		{"empty trace id", "", datadogSpanID, "1", "", false, "", false},
+		{"parent not decimal", datadogTraceID, validSpanID, "1", "", false, "", false},
+		{"priority not a number", datadogTraceID, datadogSpanID, "keep", "", false, "", false},
+		{"short tid", datadogTraceID, datadogSpanID, "1", "_dd.p.tid=0af7", false, "", false},
+		{"tid not hex", datadogTraceID, datadogSpanID, "1", "_dd.p.tid=0af7651916cd43dz", false, "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseDatadogHeaders(tc.traceID, tc.parent, tc.prio, tc.tags)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("expected %q/%q/%q/%q to be rejected", tc.traceID, tc.parent, tc.prio, tc.tags)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected headers to parse, got %v", err)
+			}
+			if got.TraceID.String() != tc.wantTrace {
+				t.Errorf("trace id = %s, want %s", got.TraceID, tc.wantTrace)
+			}
+			if tc.parent != "" && got.SpanID.String() != validSpanID {
+				t.Errorf("span id = %s", got.SpanID)
+			}
+			if got.IsSampled() != tc.wantSampled {
+				t.Errorf("sampled = %v, want %v", got.IsSampled(), tc.wantSampled)
+			}
+		})
+	}
+}
+
+func TestDatadogPropagatorRoundTrip(t *testing.T) {
+	p := &DatadogPropagator{}
+	tracer := New(nil)
+
+	ctx := p.Extract(context.Background(), MapCarrier{
+		DatadogTraceIDHeader:          datadogTraceID,
+		DatadogParentIDHeader:         datadogSpanID,
+		DatadogSamplingPriorityHeader: "1",
+		DatadogTagsHeader:             "_dd.p.tid=" + datadogTID,
+	})
+	_, span := tracer.Start(ctx, "child")
+	if span.TraceID().String() != validTraceID {
+		t.Errorf("child trace id = %s", span.TraceID())
+	}
+	if span.ParentID().String() != validSpanID {
+		t.Errorf("child parent id = %s", span.ParentID())
+	}
+
+	out := MapCarrier{}
+	p.Inject(ContextWithSpan(context.Background(), span), out)
+	if out[DatadogTraceIDHeader] != datadogTraceID || out[DatadogTagsHeader] != "_dd.p.tid="+datadogTID ||
+		out[DatadogSamplingPriorityHeader] != "1" {
+		t.Errorf("unexpected injected headers %v", out)
+	}
+	if want := strconv.FormatUint(span.SpanID().Uint64(), 10); out[DatadogParentIDHeader] != want {
+		t.Errorf("parent id = %s, want %s", out[DatadogParentIDHeader], want)
+	}
+
+	// A 64-bit trace ID is sent without the tid tag.
+	ctx = p.Extract(context.Background(), MapCarrier{DatadogTraceIDHeader: datadogTraceID, DatadogParentIDHeader: datadogSpanID})
+	_, span = tracer.Start(ctx, "child")
+	out = MapCarrier{}
+	p.Inject(ContextWithSpan(context.Background(), span), out)
+	if _, ok := out[DatadogTagsHeader]; ok || out[DatadogTraceIDHeader] != datadogTraceID {
+		t.Errorf("unexpected injected headers %v", out)
+	}
+
+	for _, carrier := range []MapCarrier{{}, {DatadogTraceIDHeader: "abc"}, {DatadogTraceIDHeader: datadogTraceID, DatadogParentIDHeader: "x"}} {
+		if ctx := p.Extract(context.Background(), carrier); TraceContextFromContext(ctx) != nil {
+			t.Errorf("expected no trace context from %v", carrier)
+		}
+	}
+}
+
+func TestParseCloudTraceContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		valid   bool
+		span    string
+		sampled bool
+	}{
+		{"sampled", validTraceID + "/" + datadogSpanID + ";o=1", true, validSpanID, true},
+		{"unsampled", validTraceID + "/" + datadogSpanID + ";o=0", true, validSpanID, false},
+		{"no options", validTraceID + "/" + datadogSpanID, true, validSpanID, false},
+		{"trace id only", validTraceID, true, "0000000000000000", false},
+		{"uppercase trace id", "0AF7651916CD43DD8448EB211C80319C/" + datadogSpanID + ";o=1", true, validSpanID, true},
+		{"surrounding whitespace", " " + validTraceID + "/" + datadogSpanID + ";o=1 ", true, validSpanID, true},
+		{"short trace id", "0af7651916cd43dd8448eb211c8031/" + datadogSpanID + ";o=1", false, "", false},
+		{"trace id not hex", "0af7651916cd43dd8448eb211c80319z/" + datadogSpanID + ";o=1", false, "", false},
+		{"zero trace id", "00000000000000000000000000000000/" + datadogSpanID + ";o=1", false, "", false},
+		{"hex span id", validTraceID + "/" + validSpanID + ";o=1", false, "", false},
+		{"span id overflow", validTraceID + "/18446744073709551616;o=1", false, "", false},
+		{"options not a number", validTraceID + "/" + datadogSpanID + ";o=x", false, "", false},
+		{"empty", "", false, "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseCloudTraceContext(tc.header)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("expected %q to be rejected", tc.header)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected %q to parse, got %v", tc.header, err)
+			}
+			if got.TraceID.String() != validTraceID {
+				t.Errorf("trace id = %s", got.TraceID)
+			}
+			if got.SpanID.String() != tc.span {
+				t.Errorf("span id = %s, want %s", got.SpanID, tc.span)
+			}
+			if got.IsSampled() != tc.sampled {
+				t.Errorf("sampled = %v, want %v", got.IsSampled(), tc.sampled)
+			}
+		})
+	}
+}
+
+func TestCloudTraceContextRoundTrip(t *testing.T) {
+	headers := []string{
+		validTraceID + "/" + datadogSpanID + ";o=1",
+		validTraceID + "/" + datadogSpanID + ";o=0",
+	}
+
+	for _, header := range headers {
+		tc, err := ParseCloudTraceContext(header)
+		if err != nil {
+			t.Fatalf("parse %q: %v", header, err)
+		}
+		if got := tc.FormatCloudTraceContext(); got != header {
+			t.Errorf("round trip mismatch:\n got  %q\n want %q", got, header)
+		}
+	}
+}
+
+func TestCloudTracePropagatorRoundTrip(t *testing.T) {
+	p := &CloudTracePropagator{}
+	ctx := p.Extract(context.Background(), MapCarrier{CloudTraceHeader: validTraceID + "/" + datadogSpanID + ";o=1"})
+
+	_, span := New(nil).Start(ctx, "child")
+	if span.TraceID().String() != validTraceID {
+		t.Errorf("child trace id = %s", span.TraceID())
+	}
+	if span.ParentID().String() != validSpanID {
+		t.Errorf("child parent id = %s", span.ParentID())
+	}
+
+	out := MapCarrier{}
+	p.Inject(ContextWithSpan(context.Background(), span), out)
+	want := validTraceID + "/" + strconv.FormatUint(span.SpanID().Uint64(), 10) + ";o=1"
+	if got := out[CloudTraceHeader]; got != want {
+		t.Errorf("injected %q, want %q", got, want)
+	}
+
+	for _, header := range []string{"", "garbage", validTraceID + "/" + validSpanID} {
+		if ctx := p.Extract(context.Background(), MapCarrier{CloudTraceHeader: header}); TraceContextFromContext(ctx) != nil {
+			t.Errorf("expected no trace context from %q", header)
+		}
+	}
+}
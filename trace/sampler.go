@@ -1,8 +1,9 @@
 package trace
 
 import (
-	"hash/fnv"
-	"sync/atomic"
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
 )
 
 // SamplingParams provides data for sampling decisions.
@@ -10,6 +11,10 @@ type SamplingParams struct {
 	TraceID  TraceID
 	Name     string
 	ParentID SpanID
+
+	// Attributes are those the span was started with, via WithAttributes.
+	// Samplers must not modify them.
+	Attributes []Attribute
 }
 
 // Sampler determines whether a span should be recorded.
@@ -17,6 +22,45 @@ type Sampler interface {
 	ShouldSample(params SamplingParams) bool
 }
 
+// SamplingResult is the outcome of a sampling decision.
+type SamplingResult struct {
+	Sampled bool
+	// Attributes are attached to the span when it is sampled,
+	// e.g. sampling.rule and sampling.probability.
+	Attributes []Attribute
+}
+
+// ResultSampler is implemented by samplers that report attributes with
+// their decisions. The tracer prefers Sample over ShouldSample when available.
+type ResultSampler interface {
+	Sampler
+	Sample(params SamplingParams) SamplingResult
+}
+
+// Sampling attribute keys.
+const (
+	SamplingRuleKey        = "sampling.rule"
+	SamplingProbabilityKey = "sampling.probability"
+)
+
+func samplingResult(sampled bool, rule string, probability float64) SamplingResult {
+	return SamplingResult{
+		Sampled: sampled,
+		Attributes: []Attribute{
+			{Key: SamplingRuleKey, Value: rule},
+			{Key: SamplingProbabilityKey, Value: probability},
+		},
+	}
+}
+
+// sample runs s, using ResultSampler when implemented.
+func sample(s Sampler, params SamplingParams) SamplingResult {
+	if rs, ok := s.(ResultSampler); ok {
+		return rs.Sample(params)
+	}
+	return SamplingResult{Sampled: s.ShouldSample(params)}
+}
+
 // AlwaysSampler always samples.
 type AlwaysSampler struct{}
 
@@ -33,54 +77,73 @@ func NeverSample() *NeverSampler { return &NeverSampler{} }
 
 func (*NeverSampler) ShouldSample(SamplingParams) bool { return false }
 
-// RatioSampler samples a percentage of traces.
+// RatioSampler samples a fraction of spans independently at random.
+// Each decision draws fresh randomness, so sampling is neither periodic nor
+// correlated across traces. Use TraceIDRatioSampler when all services must
+// agree on the decision for a trace.
 type RatioSampler struct {
 	ratio     float64
-	threshold uint32
-	counter   atomic.Uint64
+	threshold uint64
 }
 
 // RatioSample returns a sampler that samples the given ratio of traces.
 func RatioSample(ratio float64) *RatioSampler {
-	if ratio < 0 {
-		ratio = 0
-	}
-	if ratio > 1 {
-		ratio = 1
-	}
+	ratio = clampRatio(ratio)
 	return &RatioSampler{
 		ratio:     ratio,
-		threshold: uint32(ratio * 100),
+		threshold: ratioThreshold(ratio),
 	}
 }
 
 func (s *RatioSampler) ShouldSample(params SamplingParams) bool {
-	count := s.counter.Add(1)
-	return uint32(count%100) < s.threshold
+	return s.ratio >= 1 || rand.Uint64() < s.threshold
+}
+
+func (s *RatioSampler) Sample(params SamplingParams) SamplingResult {
+	return samplingResult(s.ShouldSample(params), "ratio", s.ratio)
 }
 
 // TraceIDRatioSampler samples based on trace ID for consistency.
+// The decision compares the random low 64 bits of the trace ID against the
+// ratio, so every service using the same ratio makes the same decision.
 type TraceIDRatioSampler struct {
-	threshold uint32
+	ratio     float64
+	threshold uint64
 }
 
 // TraceIDRatioSample returns a sampler based on trace ID.
 func TraceIDRatioSample(ratio float64) *TraceIDRatioSampler {
-	if ratio < 0 {
-		ratio = 0
-	}
-	if ratio > 1 {
-		ratio = 1
-	}
+	ratio = clampRatio(ratio)
 	return &TraceIDRatioSampler{
-		threshold: uint32(ratio * 0xFFFFFFFF),
+		ratio:     ratio,
+		threshold: ratioThreshold(ratio),
 	}
 }
 
 func (s *TraceIDRatioSampler) ShouldSample(params SamplingParams) bool {
-	h := fnv.New32a()
-	h.Write(params.TraceID[:])
-	return h.Sum32() < s.threshold
+	return s.ratio >= 1 || binary.BigEndian.Uint64(params.TraceID[8:]) < s.threshold
+}
+
+func (s *TraceIDRatioSampler) Sample(params SamplingParams) SamplingResult {
+	return samplingResult(s.ShouldSample(params), "trace_id_ratio", s.ratio)
+}
+
+func clampRatio(ratio float64) float64 {
+	if ratio < 0 || math.IsNaN(ratio) {
+		return 0
+	}
+	if ratio > 1 {
+		return 1
+	}
+	return ratio
+}
+
+// ratioThreshold maps a ratio in [0, 1) onto the full uint64 range.
+func ratioThreshold(ratio float64) uint64 {
+	if ratio >= 1 {
+		return math.MaxUint64
+	}
+	return uint64(ratio * (1 << 64))
 }
 
 // ParentBasedSampler follows parent sampling decision.
@@ -99,3 +162,10 @@ func (s *ParentBasedSampler) ShouldSample(params SamplingParams) bool {
 	}
 	return s.root.ShouldSample(params)
 }
+
+func (s *ParentBasedSampler) Sample(params SamplingParams) SamplingResult {
+	if params.ParentID.IsValid() {
+		return SamplingResult{Sampled: true}
+	}
+	return sample(s.root, params)
+}
@@ -0,0 +1,138 @@
+package trace_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	. "github.com/kolosys/lumen/trace"
+)
+
+// plainSampler implements only Sampler.
+type plainSampler bool
+
+func (s plainSampler) ShouldSample(SamplingParams) bool { return bool(s) }
+
+func TestSamplerAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		sampler Sampler
+		sampled bool
+		rule    any
+	}{
+		{"trace id ratio", TraceIDRatioSample(1), true, "trace_id_ratio"},
+		{"ratio", RatioSample(1), true, "ratio"},
+		{"ratio unsampled", RatioSample(0), false, nil},
+		{"parent based root", ParentBasedSample(TraceIDRatioSample(1)), true, "trace_id_ratio"},
+		{"plain sampler", plainSampler(true), true, nil},
+		{"always", AlwaysSample(), true, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, span := New(&Options{Sampler: tc.sampler}).Start(context.Background(), "op")
+			if span.IsSampled() != tc.sampled {
+				t.Fatalf("sampled = %v, want %v", span.IsSampled(), tc.sampled)
+			}
+			rule, _ := attrValue(span.Attributes(), SamplingRuleKey)
+			if rule != tc.rule {
+				t.Errorf("%s = %v, want %v", SamplingRuleKey, rule, tc.rule)
+			}
+			if p, ok := attrValue(span.Attributes(), SamplingProbabilityKey); tc.rule != nil && p != 1.0 || tc.rule == nil && ok {
+				t.Errorf("%s = %v", SamplingProbabilityKey, p)
+			}
+		})
+	}
+}
+
+func TestOnSamplingDecision(t *testing.T) {
+	var params []SamplingParams
+	var results []SamplingResult
+	tracer := New(&Options{
+		Sampler: ParentBasedSample(TraceIDRatioSample(1)),
+		OnSamplingDecision: func(p SamplingParams, r SamplingResult) {
+			params = append(params, p)
+			results = append(results, r)
+		},
+	})
+
+	ctx, root := tracer.Start(context.Background(), "root", WithAttributes(Attribute{Key: "tenant", Value: "a"}))
+	_, child := tracer.Start(ctx, "child")
+
+	if len(params) != 2 {
+		t.Fatalf("expected 2 decisions, got %d", len(params))
+	}
+	if params[0].Name != "root" || params[0].TraceID != root.TraceID() || params[0].ParentID.IsValid() {
+		t.Errorf("unexpected root params %+v", params[0])
+	}
+	if v, _ := attrValue(params[0].Attributes, "tenant"); v != "a" {
+		t.Errorf("expected start attributes in params, got %v", params[0].Attributes)
+	}
+	if !results[0].Sampled || len(results[0].Attributes) != 2 {
+		t.Errorf("unexpected root result %+v", results[0])
+	}
+	if params[1].Name != "child" || params[1].ParentID != root.SpanID() || params[1].TraceID != child.TraceID() {
+		t.Errorf("unexpected child params %+v", params[1])
+	}
+	if !results[1].Sampled || len(results[1].Attributes) != 0 {
+		t.Errorf("expected the child to follow its parent without attributes, got %+v", results[1])
+	}
+}
+
+func TestRatioSamplerRate(t *testing.T) {
+	params := SamplingParams{TraceID: TraceIDFromUint64s(1, 1)}
+	tests := []struct {
+		ratio    float64
+		min, max int
+	}{
+		{0, 0, 0},
+		{math.NaN(), 0, 0},
+		{-1, 0, 0},
+		{0.25, 4500, 5500},
+		{1, 20000, 20000},
+		{2, 20000, 20000},
+	}
+	for _, tc := range tests {
+		s := RatioSample(tc.ratio)
+		n := 0
+		// The same params every time: each decision draws its own randomness.
+		for range 20000 {
+			if s.ShouldSample(params) {
+				n++
+			}
+		}
+		if n < tc.min || n > tc.max {
+			t.Errorf("ratio %v: sampled %d of 20000, want %d-%d", tc.ratio, n, tc.min, tc.max)
+		}
+	}
+}
+
+func TestTraceIDRatioSamplerThreshold(t *testing.T) {
+	tests := []struct {
+		ratio float64
+		lo    uint64
+		want  bool
+	}{
+		{0.5, 1<<63 - 1, true},
+		{0.5, 1 << 63, false},
+		{0.25, 1<<62 - 1, true},
+		{0.25, 1 << 62, false},
+		{1e-10, 0, true},
+		{1e-10, 1 << 40, false},
+		{0, 0, false},
+		{1, math.MaxUint64, true},
+	}
+	for _, tc := range tests {
+		// Only the low 64 bits decide; the high bits must not matter.
+		for _, hi := range []uint64{0, math.MaxUint64} {
+			params := SamplingParams{TraceID: TraceIDFromUint64s(hi, tc.lo)}
+			s := TraceIDRatioSample(tc.ratio)
+			if got := s.ShouldSample(params); got != tc.want {
+				t.Errorf("ratio %v, low bits %#x: sampled = %v, want %v", tc.ratio, tc.lo, got, tc.want)
+			}
+			if got := s.ShouldSample(params); got != tc.want {
+				t.Errorf("ratio %v, low bits %#x: decision changed on a second call", tc.ratio, tc.lo)
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package semconv provides attribute keys and constructors following the
+// OpenTelemetry semantic conventions, so instrumentation across a codebase
+// uses consistent keys that tracing backends recognize.
+//
+//	span.SetAttributes(
+//		semconv.HTTPMethod(r.Method),
+//		semconv.HTTPRoute("/users/{id}"),
+//		semconv.PeerService("billing"),
+//	)
+package semconv
+
+import "github.com/kolosys/lumen/trace"
+
+// Service and deployment attribute keys.
+const (
+	ServiceNameKey           = "service.name"
+	ServiceVersionKey        = "service.version"
+	ServiceNamespaceKey      = "service.namespace"
+	ServiceInstanceIDKey     = "service.instance.id"
+	DeploymentEnvironmentKey = "deployment.environment"
+	PeerServiceKey           = "peer.service"
+)
+
+// HTTP attribute keys.
+const (
+	HTTPMethodKey          = "http.request.method"
+	HTTPRouteKey           = "http.route"
+	HTTPStatusCodeKey      = "http.response.status_code"
+	HTTPRequestSizeKey     = "http.request.body.size"
+	HTTPResponseSizeKey    = "http.response.body.size"
+	URLFullKey             = "url.full"
+	URLPathKey             = "url.path"
+	URLSchemeKey           = "url.scheme"
+	URLQueryKey            = "url.query"
+	UserAgentKey           = "user_agent.original"
+	ClientAddressKey       = "client.address"
+	ServerAddressKey       = "server.address"
+	ServerPortKey          = "server.port"
+	NetworkProtocolVersion = "network.protocol.version"
+)
+
+// Database attribute keys.
+const (
+	DBSystemKey    = "db.system"
+	DBNameKey      = "db.namespace"
+	DBOperationKey = "db.operation.name"
+	DBStatementKey = "db.query.text"
+	DBTableKey     = "db.collection.name"
+)
+
+// Messaging attribute keys.
+const (
+	MessagingSystemKey          = "messaging.system"
+	MessagingOperationKey       = "messaging.operation"
+	MessagingDestinationKey     = "messaging.destination.name"
+	MessagingMessageIDKey       = "messaging.message.id"
+	MessagingBatchMessageCount  = "messaging.batch.message_count"
+	MessagingConsumerGroupKey   = "messaging.consumer.group.name"
+	MessagingMessageBodySizeKey = "messaging.message.body.size"
+)
+
+// RPC attribute keys.
+const (
+	RPCSystemKey         = "rpc.system"
+	RPCServiceKey        = "rpc.service"
+	RPCMethodKey         = "rpc.method"
+	RPCGRPCStatusCodeKey = "rpc.grpc.status_code"
+)
+
+// Error attribute keys.
+const (
+	ErrorTypeKey           = "error.type"
+	ExceptionTypeKey       = "exception.type"
+	ExceptionMessageKey    = "exception.message"
+	ExceptionStacktraceKey = "exception.stacktrace"
+)
+
+// Well-known db.system values.
+const (
+	DBSystemPostgreSQL = "postgresql"
+	DBSystemMySQL      = "mysql"
+	DBSystemSQLite     = "sqlite"
+	DBSystemRedis      = "redis"
+	DBSystemMongoDB    = "mongodb"
+)
+
+// Well-known messaging.system values.
+const (
+	MessagingSystemKafka    = "kafka"
+	MessagingSystemRabbitMQ = "rabbitmq"
+	MessagingSystemSQS      = "aws_sqs"
+	MessagingSystemSNS      = "aws_sns"
+	MessagingSystemNATS     = "nats"
+)
+
+func str(key, value string) trace.Attribute {
+	return trace.Attribute{Key: key, Value: value}
+}
+
+func num(key string, value int) trace.Attribute {
+	return trace.Attribute{Key: key, Value: int64(value)}
+}
+
+// ServiceName returns a service.name attribute.
+func ServiceName(name string) trace.Attribute { return str(ServiceNameKey, name) }
+
+// ServiceVersion returns a service.version attribute.
+func ServiceVersion(version string) trace.Attribute { return str(ServiceVersionKey, version) }
+
+// ServiceInstanceID returns a service.instance.id attribute.
+func ServiceInstanceID(id string) trace.Attribute { return str(ServiceInstanceIDKey, id) }
+
+// DeploymentEnvironment returns a deployment.environment attribute.
+func DeploymentEnvironment(env string) trace.Attribute { return str(DeploymentEnvironmentKey, env) }
+
+// PeerService returns a peer.service attribute naming the remote service.
+func PeerService(name string) trace.Attribute { return str(PeerServiceKey, name) }
+
+// HTTPMethod returns an http.request.method attribute.
+func HTTPMethod(method string) trace.Attribute { return str(HTTPMethodKey, method) }
+
+// HTTPRoute returns an http.route attribute (the route template, not the path).
+func HTTPRoute(route string) trace.Attribute { return str(HTTPRouteKey, route) }
+
+// HTTPStatusCode returns an http.response.status_code attribute.
+func HTTPStatusCode(code int) trace.Attribute { return num(HTTPStatusCodeKey, code) }
+
+// URLFull returns a url.full attribute.
+func URLFull(url string) trace.Attribute { return str(URLFullKey, url) }
+
+// URLPath returns a url.path attribute.
+func URLPath(path string) trace.Attribute { return str(URLPathKey, path) }
+
+// URLScheme returns a url.scheme attribute.
+func URLScheme(scheme string) trace.Attribute { return str(URLSchemeKey, scheme) }
+
+// UserAgent returns a user_agent.original attribute.
+func UserAgent(ua string) trace.Attribute { return str(UserAgentKey, ua) }
+
+// ClientAddress returns a client.address attribute.
+func ClientAddress(addr string) trace.Attribute { return str(ClientAddressKey, addr) }
+
+// ServerAddress returns a server.address attribute.
+func ServerAddress(addr string) trace.Attribute { return str(ServerAddressKey, addr) }
+
+// ServerPort returns a server.port attribute.
+func ServerPort(port int) trace.Attribute { return num(ServerPortKey, port) }
+
+// DBSystem returns a db.system attribute, e.g. DBSystemPostgreSQL.
+func DBSystem(system string) trace.Attribute { return str(DBSystemKey, system) }
+
+// DBName returns the database name attribute.
+func DBName(name string) trace.Attribute { return str(DBNameKey, name) }
+
+// DBOperation returns the database operation attribute, e.g. "SELECT".
+func DBOperation(op string) trace.Attribute { return str(DBOperationKey, op) }
+
+// DBStatement returns the database query text attribute.
+func DBStatement(stmt string) trace.Attribute { return str(DBStatementKey, stmt) }
+
+// DBTable returns the database table or collection attribute.
+func DBTable(table string) trace.Attribute { return str(DBTableKey, table) }
+
+// MessagingSystem returns a messaging.system attribute, e.g. MessagingSystemKafka.
+func MessagingSystem(system string) trace.Attribute { return str(MessagingSystemKey, system) }
+
+// MessagingOperation returns a messaging.operation attribute ("publish", "process", ...).
+func MessagingOperation(op string) trace.Attribute { return str(MessagingOperationKey, op) }
+
+// MessagingDestination returns a messaging.destination.name attribute.
+func MessagingDestination(name string) trace.Attribute { return str(MessagingDestinationKey, name) }
+
+// MessagingMessageID returns a messaging.message.id attribute.
+func MessagingMessageID(id string) trace.Attribute { return str(MessagingMessageIDKey, id) }
+
+// RPCSystem returns an rpc.system attribute, e.g. "grpc".
+func RPCSystem(system string) trace.Attribute { return str(RPCSystemKey, system) }
+
+// RPCService returns an rpc.service attribute.
+func RPCService(service string) trace.Attribute { return str(RPCServiceKey, service) }
+
+// RPCMethod returns an rpc.method attribute.
+func RPCMethod(method string) trace.Attribute { return str(RPCMethodKey, method) }
+
+// RPCGRPCStatusCode returns an rpc.grpc.status_code attribute.
+func RPCGRPCStatusCode(code int) trace.Attribute { return num(RPCGRPCStatusCodeKey, code) }
+
+// ErrorType returns an error.type attribute.
+func ErrorType(typ string) trace.Attribute { return str(ErrorTypeKey, typ) }
+
+// Service returns the standard service identity attributes.
+// Empty values are omitted.
+func Service(name, version, environment string) []trace.Attribute {
+	attrs := make([]trace.Attribute, 0, 3)
+	if name != "" {
+		attrs = append(attrs, ServiceName(name))
+	}
+	if version != "" {
+		attrs = append(attrs, ServiceVersion(version))
+	}
+	if environment != "" {
+		attrs = append(attrs, DeploymentEnvironment(environment))
+	}
+	return attrs
+}
@@ -0,0 +1,47 @@
+package semconv_test
+
+import (
+	"testing"
+
+	"github.com/kolosys/lumen/trace"
+	. "github.com/kolosys/lumen/trace/semconv"
+)
+
+func TestConstructors(t *testing.T) {
+	tests := []struct {
+		got  trace.Attribute
+		key  string
+		want any
+	}{
+		{ServiceName("checkout"), "service.name", "checkout"},
+		{DeploymentEnvironment("prod"), "deployment.environment", "prod"},
+		{PeerService("billing"), "peer.service", "billing"},
+		{HTTPMethod("GET"), "http.request.method", "GET"},
+		{HTTPRoute("/users/{id}"), "http.route", "/users/{id}"},
+		{HTTPStatusCode(404), "http.response.status_code", int64(404)},
+		{URLFull("https://x/y"), "url.full", "https://x/y"},
+		{ServerPort(8443), "server.port", int64(8443)},
+		{DBSystem(DBSystemPostgreSQL), "db.system", "postgresql"},
+		{DBStatement("SELECT 1"), "db.query.text", "SELECT 1"},
+		{DBTable("users"), "db.collection.name", "users"},
+		{MessagingSystem(MessagingSystemSQS), "messaging.system", "aws_sqs"},
+		{MessagingDestination("orders"), "messaging.destination.name", "orders"},
+		{RPCGRPCStatusCode(14), "rpc.grpc.status_code", int64(14)},
+		{ErrorType("timeout"), "error.type", "timeout"},
+	}
+	for _, tc := range tests {
+		if tc.got.Key != tc.key || tc.got.Value != tc.want {
+			t.Errorf("got %s=%#v, want %s=%#v", tc.got.Key, tc.got.Value, tc.key, tc.want)
+		}
+	}
+}
+
+func TestService(t *testing.T) {
+	attrs := Service("checkout", "", "prod")
+	if len(attrs) != 2 || attrs[0] != ServiceName("checkout") || attrs[1] != DeploymentEnvironment("prod") {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+	if attrs := Service("", "", ""); len(attrs) != 0 {
+		t.Errorf("expected no attributes, got %v", attrs)
+	}
+}
@@ -58,6 +58,12 @@ type Span struct {
 	mu         sync.Mutex
 }
 
+// NoopSpan returns a span that records nothing, for callers that need a
+// non-nil span when none is active.
+func NoopSpan() *Span {
+	return &Span{noop: true}
+}
+
 // SpanOption configures span creation.
 type SpanOption func(*Span)
 
@@ -92,9 +98,21 @@ func (s *Span) ParentID() SpanID {
 
 // Name returns the span name.
 func (s *Span) Name() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.name
 }
 
+// SetName renames the span, e.g. once an HTTP route is known.
+func (s *Span) SetName(name string) {
+	if s.noop || s.ended.Load() {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
 // StartTime returns the start time.
 func (s *Span) StartTime() time.Time {
 	return s.startTime
@@ -181,7 +199,13 @@ func (s *Span) End() {
 	}
 	s.endTime = time.Now()
 
-	if !s.sampled || s.tracer == nil {
+	if s.tracer == nil {
+		return
+	}
+	s.tracer.active.Delete(s)
+	s.tracer.recordDuration(s.Name(), s.endTime.Sub(s.startTime))
+
+	if !s.sampled {
 		return
 	}
 
@@ -224,6 +248,37 @@ func (s *Span) Events() []Event {
 	return events
 }
 
+// snapshot copies the span into an immutable SpanSnapshot.
+func (s *Span) snapshot() *SpanSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := &SpanSnapshot{
+		TraceID:       s.traceID,
+		SpanID:        s.spanID,
+		ParentID:      s.parentID,
+		Name:          s.name,
+		StartTime:     s.startTime,
+		EndTime:       s.endTime,
+		Status:        s.status,
+		StatusMessage: s.statusMsg,
+		Sampled:       s.sampled,
+	}
+	if s.tracer != nil {
+		snap.ServiceName = s.tracer.opts.ServiceName
+		snap.Resource = s.tracer.opts.Resource
+	}
+	if len(s.attributes) > 0 {
+		snap.Attributes = make([]Attribute, len(s.attributes))
+		copy(snap.Attributes, s.attributes)
+	}
+	if len(s.events) > 0 {
+		snap.Events = make([]Event, len(s.events))
+		copy(snap.Events, s.events)
+	}
+	return snap
+}
+
 func (s *Span) reset() {
 	s.tracer = nil
 	s.traceID = TraceID{}
@@ -0,0 +1,180 @@
+package trace
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DurationStats summarizes the durations of ended spans with a given name.
+// Quantiles are estimated from log-scale buckets with roughly 5% relative error.
+type DurationStats struct {
+	Count uint64
+	Sum   time.Duration
+	Min   time.Duration
+	Max   time.Duration
+	Mean  time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+}
+
+// Bucket layout: 8 buckets per power of two, starting at 1µs.
+const (
+	durationBucketsPerOctave = 8
+	durationOctaves          = 32
+	durationBuckets          = durationBucketsPerOctave*durationOctaves + 1
+	durationBase             = float64(time.Microsecond)
+)
+
+type durationHistogram struct {
+	counts [durationBuckets]atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Int64
+	min    atomic.Int64
+	max    atomic.Int64
+}
+
+func newDurationHistogram() *durationHistogram {
+	h := &durationHistogram{}
+	h.min.Store(math.MaxInt64)
+	return h
+}
+
+func (h *durationHistogram) observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.counts[durationBucket(d)].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+
+	for {
+		cur := h.min.Load()
+		if int64(d) >= cur || h.min.CompareAndSwap(cur, int64(d)) {
+			break
+		}
+	}
+	for {
+		cur := h.max.Load()
+		if int64(d) <= cur || h.max.CompareAndSwap(cur, int64(d)) {
+			break
+		}
+	}
+}
+
+func (h *durationHistogram) stats() DurationStats {
+	var counts [durationBuckets]uint64
+	var total uint64
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return DurationStats{}
+	}
+
+	st := DurationStats{
+		Count: total,
+		Sum:   time.Duration(h.sum.Load()),
+		Min:   time.Duration(h.min.Load()),
+		Max:   time.Duration(h.max.Load()),
+	}
+	st.Mean = st.Sum / time.Duration(total)
+	st.P50 = quantile(counts[:], total, 0.50, st.Min, st.Max)
+	st.P90 = quantile(counts[:], total, 0.90, st.Min, st.Max)
+	st.P95 = quantile(counts[:], total, 0.95, st.Min, st.Max)
+	st.P99 = quantile(counts[:], total, 0.99, st.Min, st.Max)
+	return st
+}
+
+// durationBucket returns the bucket index for d. Bucket 0 holds everything
+// up to 1µs; bucket i holds (base·2^((i-1)/k), base·2^(i/k)].
+func durationBucket(d time.Duration) int {
+	if float64(d) <= durationBase {
+		return 0
+	}
+	i := int(math.Ceil(math.Log2(float64(d)/durationBase) * durationBucketsPerOctave))
+	if i >= durationBuckets {
+		return durationBuckets - 1
+	}
+	return i
+}
+
+// bucketMidpoint returns the geometric midpoint of bucket i.
+func bucketMidpoint(i int) time.Duration {
+	if i == 0 {
+		return time.Duration(durationBase)
+	}
+	exp := (float64(i) - 0.5) / durationBucketsPerOctave
+	return time.Duration(durationBase * math.Exp2(exp))
+}
+
+func quantile(counts []uint64, total uint64, q float64, lo, hi time.Duration) time.Duration {
+	rank := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			v := bucketMidpoint(i)
+			if v < lo {
+				return lo
+			}
+			if v > hi {
+				return hi
+			}
+			return v
+		}
+	}
+	return hi
+}
+
+// durationRecorder tracks per-name duration histograms.
+type durationRecorder struct {
+	byName sync.Map // name -> *durationHistogram
+}
+
+func (r *durationRecorder) observe(name string, d time.Duration) {
+	val, ok := r.byName.Load(name)
+	if !ok {
+		val, _ = r.byName.LoadOrStore(name, newDurationHistogram())
+	}
+	val.(*durationHistogram).observe(d)
+}
+
+func (r *durationRecorder) stats(name string) (DurationStats, bool) {
+	val, ok := r.byName.Load(name)
+	if !ok {
+		return DurationStats{}, false
+	}
+	return val.(*durationHistogram).stats(), true
+}
+
+func (r *durationRecorder) names() []string {
+	var names []string
+	r.byName.Range(func(key, _ any) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	return names
+}
+
+// DurationStats returns latency statistics for spans with the given name.
+// It reports false if Options.RecordDurations is disabled or no span with
+// that name has ended yet.
+func (t *Tracer) DurationStats(name string) (DurationStats, bool) {
+	if t.durations == nil {
+		return DurationStats{}, false
+	}
+	return t.durations.stats(name)
+}
+
+// DurationNames returns the span names with recorded durations.
+func (t *Tracer) DurationNames() []string {
+	if t.durations == nil {
+		return nil
+	}
+	return t.durations.names()
+}
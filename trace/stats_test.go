@@ -0,0 +1,114 @@
+package trace_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/kolosys/lumen/trace"
+)
+
+// endAfter starts a span that ran for d and ends it.
+func endAfter(tracer *Tracer, name string, d time.Duration) {
+	_, span := tracer.Start(context.Background(), name, WithStartTime(time.Now().Add(-d)))
+	span.End()
+}
+
+// within reports whether got is within rel of want, allowing for the
+// time spent between a span's start and End.
+func within(got, want time.Duration, rel float64) bool {
+	slack := rel*float64(want) + float64(time.Millisecond)
+	diff := float64(got - want)
+	return diff <= slack && -diff <= slack
+}
+
+func TestDurationStats(t *testing.T) {
+	var mu sync.Mutex
+	observed := map[string]int{}
+	tracer := New(&Options{
+		Sampler:         NeverSample(),
+		RecordDurations: true,
+		DurationObserver: func(name string, d time.Duration) {
+			mu.Lock()
+			observed[name]++
+			mu.Unlock()
+		},
+	})
+
+	for i := 1; i <= 100; i++ {
+		endAfter(tracer, "query", time.Duration(i)*time.Millisecond)
+	}
+	endAfter(tracer, "render", time.Second)
+
+	st, ok := tracer.DurationStats("query")
+	if !ok {
+		t.Fatal("expected stats for unsampled spans")
+	}
+	if st.Count != 100 {
+		t.Errorf("count = %d, want 100", st.Count)
+	}
+	if !within(st.Min, time.Millisecond, 0.01) || !within(st.Max, 100*time.Millisecond, 0.01) {
+		t.Errorf("min/max = %v/%v", st.Min, st.Max)
+	}
+	if !within(st.Mean, 50500*time.Microsecond, 0.01) || !within(st.Sum, 5050*time.Millisecond, 0.02) {
+		t.Errorf("mean/sum = %v/%v", st.Mean, st.Sum)
+	}
+	for _, q := range []struct {
+		name      string
+		got, want time.Duration
+	}{
+		{"p50", st.P50, 50 * time.Millisecond},
+		{"p90", st.P90, 90 * time.Millisecond},
+		{"p95", st.P95, 95 * time.Millisecond},
+		{"p99", st.P99, 99 * time.Millisecond},
+	} {
+		if !within(q.got, q.want, 0.05) {
+			t.Errorf("%s = %v, want %v ±5%%", q.name, q.got, q.want)
+		}
+	}
+
+	names := tracer.DurationNames()
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"query", "render"}) {
+		t.Errorf("names = %v", names)
+	}
+	if _, ok := tracer.DurationStats("missing"); ok {
+		t.Error("expected no stats for an unknown name")
+	}
+	if observed["query"] != 100 || observed["render"] != 1 {
+		t.Errorf("observer calls = %v", observed)
+	}
+}
+
+func TestDurationStatsDisabled(t *testing.T) {
+	tracer := New(nil)
+	endAfter(tracer, "query", time.Millisecond)
+	if _, ok := tracer.DurationStats("query"); ok {
+		t.Error("expected no stats without RecordDurations")
+	}
+	if names := tracer.DurationNames(); names != nil {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestDurationStatsConcurrent(t *testing.T) {
+	tracer := New(&Options{RecordDurations: true})
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				endAfter(tracer, "work", time.Millisecond)
+				tracer.DurationStats("work")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if st, _ := tracer.DurationStats("work"); st.Count != 800 {
+		t.Errorf("count = %d, want 800", st.Count)
+	}
+}
@@ -4,10 +4,14 @@ package trace
 import (
 	"context"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/kolosys/lumen/internal/report"
 )
 
 // TraceID is a 16-byte trace identifier.
@@ -21,6 +25,19 @@ func (t TraceID) IsValid() bool {
 	return t != TraceID{}
 }
 
+// Uint64s returns the high and low 64 bits of the trace ID.
+func (t TraceID) Uint64s() (hi, lo uint64) {
+	return binary.BigEndian.Uint64(t[:8]), binary.BigEndian.Uint64(t[8:])
+}
+
+// TraceIDFromUint64s builds a trace ID from its high and low 64 bits.
+func TraceIDFromUint64s(hi, lo uint64) TraceID {
+	var t TraceID
+	binary.BigEndian.PutUint64(t[:8], hi)
+	binary.BigEndian.PutUint64(t[8:], lo)
+	return t
+}
+
 // SpanID is an 8-byte span identifier.
 type SpanID [8]byte
 
@@ -32,14 +49,31 @@ func (s SpanID) IsValid() bool {
 	return s != SpanID{}
 }
 
+// Uint64 returns the span ID as an unsigned 64-bit integer.
+func (s SpanID) Uint64() uint64 {
+	return binary.BigEndian.Uint64(s[:])
+}
+
+// SpanIDFromUint64 builds a span ID from an unsigned 64-bit integer.
+func SpanIDFromUint64(v uint64) SpanID {
+	var s SpanID
+	binary.BigEndian.PutUint64(s[:], v)
+	return s
+}
+
 // Tracer creates and manages spans.
 type Tracer struct {
 	opts      *Options
 	spanPool  *sync.Pool
 	closed    atomic.Bool
-	asyncCh   chan *Span
+	asyncMu   sync.RWMutex // held to send on asyncCh, locked to close it
+	asyncCh   chan *SpanSnapshot
 	asyncWg   sync.WaitGroup
 	closeOnce sync.Once
+	durations *durationRecorder
+	active    sync.Map // *Span -> struct{}
+	stopCh    chan struct{}
+	bgWg      sync.WaitGroup
 }
 
 // New creates a new Tracer.
@@ -61,12 +95,22 @@ func New(opts *Options) *Tracer {
 		},
 	}
 
+	if opts.RecordDurations {
+		t.durations = &durationRecorder{}
+	}
+
 	if opts.AsyncExport {
-		t.asyncCh = make(chan *Span, opts.AsyncBufferSize)
+		t.asyncCh = make(chan *SpanSnapshot, opts.AsyncBufferSize)
 		t.asyncWg.Add(1)
 		go t.asyncWorker()
 	}
 
+	if opts.HeartbeatInterval > 0 {
+		t.stopCh = make(chan struct{})
+		t.bgWg.Add(1)
+		go t.heartbeatLoop(opts.HeartbeatInterval)
+	}
+
 	return t
 }
 
@@ -99,14 +143,23 @@ func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanOption) (co
 		opt(span)
 	}
 
-	if !t.opts.Sampler.ShouldSample(SamplingParams{
-		TraceID:  span.traceID,
-		Name:     name,
-		ParentID: span.parentID,
-	}) {
-		span.sampled = false
-	} else {
-		span.sampled = true
+	params := SamplingParams{
+		TraceID:    span.traceID,
+		Name:       name,
+		ParentID:   span.parentID,
+		Attributes: span.attributes,
+	}
+	result := sample(t.opts.Sampler, params)
+	span.sampled = result.Sampled
+	if result.Sampled {
+		span.attributes = append(span.attributes, result.Attributes...)
+	}
+	if t.opts.OnSamplingDecision != nil {
+		t.opts.OnSamplingDecision(params, result)
+	}
+
+	if t.opts.TrackActiveSpans {
+		t.active.Store(span, struct{}{})
 	}
 
 	return ContextWithSpan(ctx, span), span
@@ -115,7 +168,13 @@ func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanOption) (co
 // Close shuts down the tracer.
 func (t *Tracer) Close() error {
 	t.closeOnce.Do(func() {
+		t.asyncMu.Lock()
 		t.closed.Store(true)
+		t.asyncMu.Unlock()
+		if t.stopCh != nil {
+			close(t.stopCh)
+			t.bgWg.Wait()
+		}
 		if t.asyncCh != nil {
 			close(t.asyncCh)
 			t.asyncWg.Wait()
@@ -124,6 +183,15 @@ func (t *Tracer) Close() error {
 	return nil
 }
 
+func (t *Tracer) recordDuration(name string, d time.Duration) {
+	if t.durations != nil {
+		t.durations.observe(name, d)
+	}
+	if t.opts.DurationObserver != nil {
+		t.opts.DurationObserver(name, d)
+	}
+}
+
 func (t *Tracer) getSpan() *Span {
 	s := t.spanPool.Get().(*Span)
 	s.reset()
@@ -131,28 +199,88 @@ func (t *Tracer) getSpan() *Span {
 }
 
 func (t *Tracer) releaseSpan(s *Span) {
+	if t.opts.TrackActiveSpans {
+		return
+	}
 	s.reset()
 	t.spanPool.Put(s)
 }
 
 func (t *Tracer) asyncWorker() {
 	defer t.asyncWg.Done()
-	for span := range t.asyncCh {
-		t.opts.Exporter.Export(span)
-		t.releaseSpan(span)
+
+	ticker := time.NewTicker(t.opts.BatchTimeout)
+	defer ticker.Stop()
+
+	batch := make([]*SpanSnapshot, 0, t.opts.MaxExportBatchSize)
+	for {
+		select {
+		case snap, ok := <-t.asyncCh:
+			if !ok {
+				t.export(batch)
+				return
+			}
+			batch = append(batch, snap)
+			if len(batch) >= t.opts.MaxExportBatchSize {
+				t.export(batch)
+				batch = make([]*SpanSnapshot, 0, t.opts.MaxExportBatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				t.export(batch)
+				batch = make([]*SpanSnapshot, 0, t.opts.MaxExportBatchSize)
+			}
+		}
 	}
 }
 
 func (t *Tracer) exportSpan(span *Span) {
-	if t.asyncCh != nil && !t.closed.Load() {
-		select {
-		case t.asyncCh <- span:
+	snap := span.snapshot()
+	t.releaseSpan(span)
+
+	for _, p := range t.opts.Processors {
+		if snap = p.Process(snap); snap == nil {
 			return
-		default:
 		}
 	}
-	t.opts.Exporter.Export(span)
-	t.releaseSpan(span)
+
+	if t.asyncCh != nil {
+		// Spans ending during or after Close are exported synchronously.
+		t.asyncMu.RLock()
+		queued := false
+		if !t.closed.Load() {
+			select {
+			case t.asyncCh <- snap:
+				queued = true
+			default:
+			}
+		}
+		t.asyncMu.RUnlock()
+		if queued {
+			return
+		}
+	}
+	t.export([]*SpanSnapshot{snap})
+}
+
+func (t *Tracer) export(spans []*SpanSnapshot) {
+	if len(spans) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	if t.opts.ExportTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.opts.ExportTimeout)
+		defer cancel()
+	}
+
+	if err := t.opts.Exporter.ExportSpans(ctx, spans); err != nil {
+		report.Error(report.TraceExport, fmt.Errorf("trace: export %d spans: %w", len(spans), err))
+		if t.opts.OnExportError != nil {
+			t.opts.OnExportError(err)
+		}
+	}
 }
 
 func generateTraceID() TraceID {
@@ -0,0 +1,415 @@
+// Package ui serves a small web UI for looking at a service's telemetry
+// during development: recent log entries, a waterfall of recent traces
+// and live charts of the metrics registry. It needs no external assets
+// or dependencies; everything is served by one http.Handler.
+//
+// Basic usage:
+//
+//	ring := logs.NewRingHook(1000)
+//	logger.AddHook(ring)
+//	spans := trace.NewInMemoryExporter()
+//	tracer := trace.New(&trace.Options{Exporter: spans})
+//
+//	mux.Handle("/debug/lumen/", http.StripPrefix("/debug/lumen", ui.Handler(&ui.Options{
+//		Logs:     ring,
+//		Spans:    spans,
+//		Registry: registry,
+//	})))
+//
+// The UI shows telemetry contents as they are, including any secrets in
+// log fields, so serve it only locally or behind authentication.
+package ui
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// SpanSource supplies recently ended spans. trace.InMemoryExporter and
+// lumen.RecentSpans implement it.
+type SpanSource interface {
+	Spans() []*trace.SpanSnapshot
+}
+
+// LogSource supplies recent log entries. logs.RingHook implements it.
+type LogSource interface {
+	Entries() []logs.Entry
+}
+
+// Options configures Handler. Each view is empty when its source is nil.
+type Options struct {
+	// Title is shown in the page header. Default: "lumen".
+	Title string
+
+	// Logs supplies the log view.
+	Logs LogSource
+
+	// Spans supplies the trace view.
+	Spans SpanSource
+
+	// Registry supplies the metrics view.
+	Registry *metrics.Registry
+
+	// RefreshInterval is how often the page polls for new data.
+	// Default: 2s.
+	RefreshInterval time.Duration
+}
+
+func (o *Options) applyDefaults() {
+	if o.Title == "" {
+		o.Title = "lumen"
+	}
+	if o.RefreshInterval <= 0 {
+		o.RefreshInterval = 2 * time.Second
+	}
+}
+
+// Handler returns the UI. It serves the page at / and its data under
+// /api/; mount it with http.StripPrefix when serving it below the root.
+func Handler(opts *Options) http.Handler {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	o.applyDefaults()
+
+	h := &handler{opts: o}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", h.index)
+	mux.HandleFunc("GET /api/config", h.config)
+	mux.HandleFunc("GET /api/logs", h.logs)
+	mux.HandleFunc("GET /api/traces", h.traces)
+	mux.HandleFunc("GET /api/traces/{id}", h.trace)
+	mux.HandleFunc("GET /api/metrics", h.metrics)
+	return mux
+}
+
+type handler struct {
+	opts Options
+}
+
+func (h *handler) index(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+func (h *handler) config(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"title":      h.opts.Title,
+		"refresh_ms": h.opts.RefreshInterval.Milliseconds(),
+		"logs":       h.opts.Logs != nil,
+		"traces":     h.opts.Spans != nil,
+		"metrics":    h.opts.Registry != nil,
+	})
+}
+
+// logEntry is a log entry as served to the page.
+type logEntry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Logger  string            `json:"logger,omitempty"`
+	Message string            `json:"msg"`
+	Caller  string            `json:"caller,omitempty"`
+	Stack   string            `json:"stack,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	TraceID string            `json:"trace_id,omitempty"`
+}
+
+// logs serves the retained entries, newest first, filtered by ?level=
+// (minimum level) and ?q= (substring of the message or a field value),
+// up to ?limit= (default 500).
+func (h *handler) logs(w http.ResponseWriter, r *http.Request) {
+	out := []logEntry{}
+	if h.opts.Logs == nil {
+		writeJSON(w, out)
+		return
+	}
+	minLevel := logs.TraceLevel
+	if s := r.URL.Query().Get("level"); s != "" {
+		minLevel = logs.ParseLevel(s)
+	}
+	q := strings.ToLower(r.URL.Query().Get("q"))
+	limit := queryInt(r, "limit", 500)
+
+	entries := h.opts.Logs.Entries()
+	for i := len(entries) - 1; i >= 0 && len(out) < limit; i-- {
+		e := &entries[i]
+		if e.Level > minLevel {
+			continue
+		}
+		le := logEntry{
+			Time:    e.Time,
+			Level:   e.Level.String(),
+			Message: e.Message,
+			Caller:  e.Caller,
+			Stack:   e.Stack,
+		}
+		match := q == "" || strings.Contains(strings.ToLower(e.Message), q)
+		for _, f := range e.Fields {
+			value := f.StringValue()
+			switch f.Key {
+			case "_logger":
+				le.Logger = value
+			case "trace_id":
+				le.TraceID = value
+				fallthrough
+			default:
+				if le.Fields == nil {
+					le.Fields = make(map[string]string, len(e.Fields))
+				}
+				le.Fields[f.Key] = value
+			}
+			match = match || strings.Contains(strings.ToLower(value), q)
+		}
+		if match {
+			out = append(out, le)
+		}
+	}
+	writeJSON(w, out)
+}
+
+// traceSummary is one row of the trace list.
+type traceSummary struct {
+	TraceID    string    `json:"trace_id"`
+	Root       string    `json:"root"`
+	Service    string    `json:"service,omitempty"`
+	Start      time.Time `json:"start"`
+	DurationMS float64   `json:"duration_ms"`
+	Spans      int       `json:"spans"`
+	Errors     int       `json:"errors"`
+}
+
+// traces lists the traces of the retained spans, newest first, up to
+// ?limit= (default 100).
+func (h *handler) traces(w http.ResponseWriter, r *http.Request) {
+	out := []traceSummary{}
+	if h.opts.Spans == nil {
+		writeJSON(w, out)
+		return
+	}
+	byTrace := groupByTrace(h.opts.Spans.Spans())
+	for id, spans := range byTrace {
+		start, end := bounds(spans)
+		root := rootSpan(spans)
+		s := traceSummary{
+			TraceID:    id.String(),
+			Root:       root.Name,
+			Service:    root.ServiceName,
+			Start:      start,
+			DurationMS: ms(end.Sub(start)),
+			Spans:      len(spans),
+		}
+		for _, span := range spans {
+			if span.Status == trace.StatusError {
+				s.Errors++
+			}
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.After(out[j].Start) })
+	if limit := queryInt(r, "limit", 100); len(out) > limit {
+		out = out[:limit]
+	}
+	writeJSON(w, out)
+}
+
+// waterfallSpan is one bar of the waterfall, timed relative to the start
+// of the trace.
+type waterfallSpan struct {
+	SpanID        string            `json:"span_id"`
+	ParentID      string            `json:"parent_id,omitempty"`
+	Name          string            `json:"name"`
+	Service       string            `json:"service,omitempty"`
+	Depth         int               `json:"depth"`
+	OffsetMS      float64           `json:"offset_ms"`
+	DurationMS    float64           `json:"duration_ms"`
+	Status        string            `json:"status"`
+	StatusMessage string            `json:"status_message,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+	Events        []waterfallEvent  `json:"events,omitempty"`
+}
+
+type waterfallEvent struct {
+	Name       string            `json:"name"`
+	OffsetMS   float64           `json:"offset_ms"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// trace serves the spans of one trace in waterfall order: depth first,
+// children by start time.
+func (h *handler) trace(w http.ResponseWriter, r *http.Request) {
+	var spans []*trace.SpanSnapshot
+	if h.opts.Spans != nil {
+		id := r.PathValue("id")
+		for _, s := range h.opts.Spans.Spans() {
+			if s.TraceID.String() == id {
+				spans = append(spans, s)
+			}
+		}
+	}
+	if len(spans) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	start, _ := bounds(spans)
+
+	children := make(map[trace.SpanID][]*trace.SpanSnapshot)
+	known := make(map[trace.SpanID]bool, len(spans))
+	for _, s := range spans {
+		known[s.SpanID] = true
+	}
+	var roots []*trace.SpanSnapshot
+	for _, s := range spans {
+		if s.ParentID.IsValid() && known[s.ParentID] {
+			children[s.ParentID] = append(children[s.ParentID], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+
+	out := make([]waterfallSpan, 0, len(spans))
+	var walk func(list []*trace.SpanSnapshot, depth int)
+	walk = func(list []*trace.SpanSnapshot, depth int) {
+		sort.Slice(list, func(i, j int) bool { return list[i].StartTime.Before(list[j].StartTime) })
+		for _, s := range list {
+			ws := waterfallSpan{
+				SpanID:        s.SpanID.String(),
+				Name:          s.Name,
+				Service:       s.ServiceName,
+				Depth:         depth,
+				OffsetMS:      ms(s.StartTime.Sub(start)),
+				DurationMS:    ms(s.Duration()),
+				Status:        s.Status.String(),
+				StatusMessage: s.StatusMessage,
+				Attributes:    attrMap(s.Attributes),
+			}
+			if s.ParentID.IsValid() {
+				ws.ParentID = s.ParentID.String()
+			}
+			for _, e := range s.Events {
+				ws.Events = append(ws.Events, waterfallEvent{
+					Name:       e.Name,
+					OffsetMS:   ms(e.Timestamp.Sub(start)),
+					Attributes: attrMap(e.Attributes),
+				})
+			}
+			out = append(out, ws)
+			walk(children[s.SpanID], depth+1)
+		}
+	}
+	walk(roots, 0)
+	writeJSON(w, out)
+}
+
+// series is one metric series value.
+type series struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// metrics serves the registry's current samples whose name contains ?q=.
+// Histogram buckets are left out; their _sum and _count are kept.
+func (h *handler) metrics(w http.ResponseWriter, r *http.Request) {
+	resp := struct {
+		Time   time.Time `json:"time"`
+		Series []series  `json:"series"`
+	}{Time: time.Now(), Series: []series{}}
+	if h.opts.Registry == nil {
+		writeJSON(w, resp)
+		return
+	}
+	q := r.URL.Query().Get("q")
+	for _, s := range h.opts.Registry.Collect() {
+		if strings.HasSuffix(s.Name, "_bucket") || !strings.Contains(s.Name, q) {
+			continue
+		}
+		var labels map[string]string
+		if s.Labels.Len() > 0 {
+			labels = make(map[string]string, s.Labels.Len())
+			keys, values := s.Labels.Keys(), s.Labels.Values()
+			for i, k := range keys {
+				labels[k] = values[i]
+			}
+		}
+		resp.Series = append(resp.Series, series{Name: s.Name, Labels: labels, Value: s.Value})
+	}
+	sort.Slice(resp.Series, func(i, j int) bool { return resp.Series[i].Name < resp.Series[j].Name })
+	writeJSON(w, resp)
+}
+
+func groupByTrace(spans []*trace.SpanSnapshot) map[trace.TraceID][]*trace.SpanSnapshot {
+	out := make(map[trace.TraceID][]*trace.SpanSnapshot)
+	for _, s := range spans {
+		out[s.TraceID] = append(out[s.TraceID], s)
+	}
+	return out
+}
+
+// bounds returns the earliest start and latest end of spans.
+func bounds(spans []*trace.SpanSnapshot) (start, end time.Time) {
+	for i, s := range spans {
+		if i == 0 || s.StartTime.Before(start) {
+			start = s.StartTime
+		}
+		if s.EndTime.After(end) {
+			end = s.EndTime
+		}
+	}
+	return start, end
+}
+
+// rootSpan returns the span without a parent among spans, or the earliest
+// span if the root was not retained.
+func rootSpan(spans []*trace.SpanSnapshot) *trace.SpanSnapshot {
+	root := spans[0]
+	for _, s := range spans {
+		if !s.ParentID.IsValid() {
+			return s
+		}
+		if s.StartTime.Before(root.StartTime) {
+			root = s
+		}
+	}
+	return root
+}
+
+func attrMap(attrs []trace.Attribute) map[string]string {
+	if len(attrs) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(attrs))
+	for _, a := range attrs {
+		m[a.Key] = logs.Any(a.Key, a.Value).StringValue()
+	}
+	return m
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func queryInt(r *http.Request, key string, def int) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get(key)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(v)
+}
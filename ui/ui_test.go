@@ -0,0 +1,149 @@
+package ui_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kolosys/lumen/logs"
+	"github.com/kolosys/lumen/metrics"
+	"github.com/kolosys/lumen/trace"
+	. "github.com/kolosys/lumen/ui"
+)
+
+func get(t *testing.T, h http.Handler, path string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: %v: %s", path, err, rec.Body)
+		}
+	}
+	return rec
+}
+
+func TestIndexAndConfig(t *testing.T) {
+	h := Handler(&Options{Title: "checkout", Registry: metrics.NewRegistry(nil)})
+
+	rec := get(t, h, "/", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<script>") {
+		t.Fatalf("index: %d %.80s", rec.Code, rec.Body)
+	}
+
+	var cfg map[string]any
+	get(t, h, "/api/config", &cfg)
+	if cfg["title"] != "checkout" || cfg["refresh_ms"] != 2000.0 || cfg["metrics"] != true || cfg["logs"] != false {
+		t.Errorf("config = %v", cfg)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	ring := logs.NewRingHook(10)
+	log := logs.New(&logs.Options{Output: &bytes.Buffer{}, Level: logs.DebugLevel, Hooks: []logs.Hook{ring}})
+	log.Named("db").Debug("connected", logs.String("host", "primary"))
+	log.Warn("slow query", logs.Int("ms", 900))
+	log.Error("query failed", logs.String("table", "orders"))
+
+	h := Handler(&Options{Logs: ring})
+
+	var all []map[string]any
+	get(t, h, "/api/logs", &all)
+	if len(all) != 3 || all[0]["msg"] != "query failed" || all[2]["logger"] != "db" {
+		t.Fatalf("logs = %v", all)
+	}
+
+	var warn []map[string]any
+	get(t, h, "/api/logs?level=warn", &warn)
+	if len(warn) != 2 {
+		t.Errorf("level=warn returned %d entries", len(warn))
+	}
+
+	var found []map[string]any
+	get(t, h, "/api/logs?q=ORDERS", &found)
+	if len(found) != 1 || found[0]["msg"] != "query failed" {
+		t.Errorf("q=ORDERS returned %v", found)
+	}
+}
+
+func TestTraceWaterfall(t *testing.T) {
+	spans := trace.NewInMemoryExporter()
+	tracer := trace.New(&trace.Options{ServiceName: "api", Exporter: spans})
+	defer tracer.Close()
+
+	ctx, root := tracer.Start(context.Background(), "GET /orders")
+	traceID := root.TraceID().String()
+	_, second := tracer.Start(ctx, "render")
+	_, first := tracer.Start(ctx, "db.query")
+	first.AddEvent("retry", trace.Attribute{Key: "attempt", Value: 2})
+	first.SetStatus(trace.StatusError, "timeout")
+	first.End()
+	second.End()
+	root.End()
+
+	h := Handler(&Options{Spans: spans})
+
+	var list []map[string]any
+	get(t, h, "/api/traces", &list)
+	if len(list) != 1 {
+		t.Fatalf("traces = %v", list)
+	}
+	if list[0]["root"] != "GET /orders" || list[0]["spans"] != 3.0 || list[0]["errors"] != 1.0 || list[0]["service"] != "api" {
+		t.Errorf("summary = %v", list[0])
+	}
+
+	var waterfall []struct {
+		Name     string  `json:"name"`
+		Depth    int     `json:"depth"`
+		OffsetMS float64 `json:"offset_ms"`
+		Status   string  `json:"status"`
+		Events   []struct {
+			Name       string            `json:"name"`
+			Attributes map[string]string `json:"attributes"`
+		} `json:"events"`
+	}
+	get(t, h, "/api/traces/"+traceID, &waterfall)
+	if len(waterfall) != 3 {
+		t.Fatalf("waterfall = %+v", waterfall)
+	}
+	// Children are ordered by start time, not end time.
+	if waterfall[0].Name != "GET /orders" || waterfall[0].Depth != 0 || waterfall[0].OffsetMS != 0 ||
+		waterfall[1].Name != "render" || waterfall[1].Depth != 1 ||
+		waterfall[2].Name != "db.query" || waterfall[2].Depth != 1 {
+		t.Errorf("waterfall = %+v", waterfall)
+	}
+	if db := waterfall[2]; db.Status != "error" || len(db.Events) != 1 || db.Events[0].Attributes["attempt"] != "2" {
+		t.Errorf("db.query = %+v", db)
+	}
+
+	if rec := get(t, h, "/api/traces/00000000000000000000000000000001", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown trace: %d", rec.Code)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry(nil)
+	reg.Counter("orders_total", "Orders.", "region").Add(3, "eu")
+	reg.Gauge("queue_depth", "Queue depth.").Set(7)
+
+	h := Handler(&Options{Registry: reg})
+
+	var resp struct {
+		Series []struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+			Value  float64           `json:"value"`
+		} `json:"series"`
+	}
+	get(t, h, "/api/metrics?q=orders", &resp)
+	if len(resp.Series) != 1 {
+		t.Fatalf("series = %+v", resp.Series)
+	}
+	if s := resp.Series[0]; s.Name != "orders_total" || s.Labels["region"] != "eu" || s.Value != 3 {
+		t.Errorf("series = %+v", s)
+	}
+}